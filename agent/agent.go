@@ -11,7 +11,6 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 
@@ -597,20 +596,29 @@ func (c *configInternal) check() error {
 	return nil
 }
 
-var validAddr = regexp.MustCompile("^.+:[0-9]+$")
-
 func checkAddrs(addrs []string, what string) error {
 	if len(addrs) == 0 {
 		return errors.Trace(requiredError(what))
 	}
 	for _, a := range addrs {
-		if !validAddr.MatchString(a) {
+		if !validAddr(a) {
 			return errors.Errorf("invalid %s %q", what, a)
 		}
 	}
 	return nil
 }
 
+// validAddr reports whether addr is a host:port pair. IPv6 hosts
+// must be enclosed in square brackets, as in "[::1]:17070".
+func validAddr(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	_, err = strconv.ParseUint(port, 10, 16)
+	return err == nil
+}
+
 func (c *configInternal) fileContents() ([]byte, error) {
 	data, err := currentFormat.marshal(c)
 	if err != nil {
@@ -97,6 +97,28 @@ var agentConfigTests = []struct {
 		APIAddresses:      []string{"localhost:8080", "bad-address"},
 	},
 	checkErr: `invalid API server address "bad-address"`,
+}, {
+	about: "unbracketed IPv6 api address",
+	params: agent.AgentConfigParams{
+		DataDir:           "/data/dir",
+		Tag:               names.NewMachineTag("1"),
+		UpgradedToVersion: version.Current.Number,
+		Password:          "sekrit",
+		CACert:            "ca cert",
+		APIAddresses:      []string{"2001:db8::1:17070"},
+	},
+	checkErr: `invalid API server address "2001:db8::1:17070"`,
+}, {
+	about: "good IPv6 addresses",
+	params: agent.AgentConfigParams{
+		DataDir:           "/data/dir",
+		Tag:               names.NewMachineTag("1"),
+		UpgradedToVersion: version.Current.Number,
+		Password:          "sekrit",
+		CACert:            "ca cert",
+		StateAddresses:    []string{"[2001:db8::1]:37017"},
+		APIAddresses:      []string{"[2001:db8::1]:17070", "[::1]:17070"},
+	},
 }, {
 	about: "good state addresses",
 	params: agent.AgentConfigParams{
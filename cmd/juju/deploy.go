@@ -30,8 +30,10 @@ type DeployCommand struct {
 	Config       cmd.FileVar
 	Constraints  constraints.Value
 	Networks     string
+	Bindings     map[string]string
 	BumpRevision bool   // Remove this once the 1.16 support is dropped.
 	RepoPath     string // defaults to JUJU_REPOSITORY
+	bindValue    string
 }
 
 const deployDoc = `
@@ -102,10 +104,23 @@ to configure on it. The --networks argument instructs juju to add all the
 networks specified with it to all new machines deployed to host units of
 the service. Not supported on all providers.
 
+The endpoints of the service can be bound to network spaces with the
+--bind argument, which takes a space-delimited list of endpoint=space
+pairs. A bound endpoint is reached through the unit's address in the
+space: "unit-get private-address" and "network-get <endpoint>" return
+that address in its hooks, and when the service is exposed its ports
+are opened only to the subnets of the bound spaces. See "juju help
+space" for how to create spaces.
+
+   juju deploy mysql --bind "db=internal cluster=replication"
+   (deploy mysql with its "db" endpoint bound to the "internal" space,
+    and its "cluster" endpoint bound to the "replication" space)
+
 See Also:
    juju help constraints
    juju help set-constraints
    juju help get-constraints
+   juju help space
 `
 
 func (c *DeployCommand) Info() *cmd.Info {
@@ -125,6 +140,7 @@ func (c *DeployCommand) SetFlags(f *gnuflag.FlagSet) {
 	f.Var(&c.Config, "config", "path to yaml-formatted service config")
 	f.Var(constraints.ConstraintsValue{Target: &c.Constraints}, "constraints", "set service constraints")
 	f.StringVar(&c.Networks, "networks", "", "bind the service to specific networks")
+	f.StringVar(&c.bindValue, "bind", "", "bind service endpoints to spaces, as endpoint=space pairs")
 	f.StringVar(&c.RepoPath, "repository", os.Getenv(osenv.JujuRepositoryEnvKey), "local charm repository")
 }
 
@@ -146,6 +162,11 @@ func (c *DeployCommand) Init(args []string) error {
 	default:
 		return cmd.CheckEmpty(args[2:])
 	}
+	bindings, err := parseBindings(c.bindValue)
+	if err != nil {
+		return err
+	}
+	c.Bindings = bindings
 	return c.UnitCommandBase.Init(args)
 }
 
@@ -229,6 +250,22 @@ func (c *DeployCommand) Run(ctx *cmd.Context) error {
 			return err
 		}
 	}
+	if len(c.Bindings) > 0 {
+		err = client.ServiceDeployWithBindings(params.ServiceDeploy{
+			ServiceName:      serviceName,
+			CharmUrl:         curl.String(),
+			NumUnits:         numUnits,
+			ConfigYAML:       string(configYAML),
+			Constraints:      c.Constraints,
+			ToMachineSpec:    c.ToMachineSpec,
+			Networks:         requestedNetworks,
+			EndpointBindings: c.Bindings,
+		})
+		if params.IsCodeNotImplemented(err) {
+			return errors.New("cannot use --bind: not supported by the API server")
+		}
+		return err
+	}
 	err = client.ServiceDeployWithNetworks(
 		curl.String(),
 		serviceName,
@@ -301,6 +338,30 @@ func parseNetworks(networksValue string) []string {
 	return networks
 }
 
+// parseBindings returns the endpoint bindings given as the value of
+// the --bind argument: a list of endpoint=space pairs, separated by
+// spaces or commas.
+func parseBindings(value string) (map[string]string, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	bindings := make(map[string]string)
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid binding %q: expected endpoint=space", field)
+		}
+		if _, ok := bindings[parts[0]]; ok {
+			return nil, fmt.Errorf("endpoint %q bound more than once", parts[0])
+		}
+		bindings[parts[0]] = parts[1]
+	}
+	return bindings, nil
+}
+
 // networkNamesToTags returns the given network names converted to
 // tags, or an error.
 func networkNamesToTags(networks []string) ([]string, error) {
@@ -60,6 +60,12 @@ var initErrorTests = []struct {
 	}, {
 		args: []string{"craziness", "burble1", "--constraints", "gibber=plop"},
 		err:  `invalid value "gibber=plop" for flag --constraints: unknown constraint "gibber"`,
+	}, {
+		args: []string{"craziness", "burble1", "--bind", "db"},
+		err:  `invalid binding "db": expected endpoint=space`,
+	}, {
+		args: []string{"craziness", "burble1", "--bind", "db=internal db=public"},
+		err:  `endpoint "db" bound more than once`,
 	},
 }
 
@@ -185,6 +191,38 @@ func (s *DeploySuite) TestNetworks(c *gc.C) {
 	c.Assert(cons, jc.DeepEquals, constraints.MustParse("mem=2G cpu-cores=2 networks=net1,net0,^net3,^net4"))
 }
 
+func (s *DeploySuite) TestBindings(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "192.168.1.0/24", ProviderId: "subnet-2"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("public", []string{"192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	charmtesting.Charms.BundlePath(s.SeriesPath, "wordpress")
+	err = runDeploy(c, "local:wordpress", "--bind", "db=internal, url=public")
+	c.Assert(err, gc.IsNil)
+	service, err := s.State.Service("wordpress")
+	c.Assert(err, gc.IsNil)
+	c.Assert(service.EndpointBindings(), jc.DeepEquals, map[string]string{
+		"db":  "internal",
+		"url": "public",
+	})
+}
+
+func (s *DeploySuite) TestBindingsUnknownEndpoint(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	charmtesting.Charms.BundlePath(s.SeriesPath, "dummy")
+	err = runDeploy(c, "local:dummy", "--bind", "db=internal")
+	c.Assert(err, gc.ErrorMatches, `cannot bind endpoint "db": charm "dummy" has no such endpoint`)
+	_, err = s.State.Service("dummy")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *DeploySuite) TestSubordinateConstraints(c *gc.C) {
 	charmtesting.Charms.BundlePath(s.SeriesPath, "logging")
 	err := runDeploy(c, "local:logging", "--constraints", "mem=1G")
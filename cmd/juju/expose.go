@@ -5,22 +5,38 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
 
 	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state/api/params"
 )
 
 // ExposeCommand is responsible exposing services.
 type ExposeCommand struct {
 	envcmd.EnvCommandBase
 	ServiceName string
+	From        string
+	SourceCIDRs []string
 }
 
 var jujuExposeHelp = `
 Adjusts firewall rules and similar security mechanisms of the provider, to
 allow the service to be accessed on its public address.
 
+By default the service can be accessed from any address. The --from
+argument takes a comma-delimited list of CIDRs, and restricts access
+to addresses within those CIDRs. Exposing the service again without
+--from removes the restriction.
+
+Examples:
+   juju expose wordpress
+   juju expose wordpress --from 10.0.0.0/8,192.168.1.0/24
+
 `
 
 func (c *ExposeCommand) Info() *cmd.Info {
@@ -32,11 +48,30 @@ func (c *ExposeCommand) Info() *cmd.Info {
 	}
 }
 
+func (c *ExposeCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.From, "from", "", "only allow access from the given comma-delimited CIDRs")
+}
+
 func (c *ExposeCommand) Init(args []string) error {
 	if len(args) == 0 {
 		return errors.New("no service name specified")
 	}
 	c.ServiceName = args[0]
+	if c.From != "" {
+		var cidrs []string
+		for _, part := range strings.Split(c.From, ",") {
+			if cidr := strings.TrimSpace(part); cidr != "" {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+		if len(cidrs) == 0 {
+			return errors.New("no CIDRs specified with --from")
+		}
+		var err error
+		if c.SourceCIDRs, err = network.ValidateSourceCIDRs(cidrs); err != nil {
+			return err
+		}
+	}
 	return cmd.CheckEmpty(args[1:])
 }
 
@@ -48,5 +83,13 @@ func (c *ExposeCommand) Run(_ *cmd.Context) error {
 		return err
 	}
 	defer client.Close()
-	return client.ServiceExpose(c.ServiceName)
+	if len(c.SourceCIDRs) == 0 {
+		return client.ServiceExpose(c.ServiceName)
+	}
+	err = client.ServiceExposeFrom(c.ServiceName, c.SourceCIDRs)
+	if params.IsCodeNotImplemented(err) {
+		// Never fall back to exposing the service to any address.
+		return fmt.Errorf("cannot expose %q: the environment does not support --from", c.ServiceName)
+	}
+	return err
 }
@@ -6,6 +6,7 @@ package main
 import (
 	"github.com/juju/charm"
 	charmtesting "github.com/juju/charm/testing"
+	jc "github.com/juju/testing/checkers"
 	gc "launchpad.net/gocheck"
 
 	"github.com/juju/juju/cmd/envcmd"
@@ -45,3 +46,31 @@ func (s *ExposeSuite) TestExpose(c *gc.C) {
 	err = runExpose(c, "nonexistent-service")
 	c.Assert(err, gc.ErrorMatches, `service "nonexistent-service" not found`)
 }
+
+func (s *ExposeSuite) TestExposeFrom(c *gc.C) {
+	charmtesting.Charms.BundlePath(s.SeriesPath, "dummy")
+	err := runDeploy(c, "local:dummy", "some-service-name")
+	c.Assert(err, gc.IsNil)
+
+	err = runExpose(c, "some-service-name", "--from", "10.0.0.0/8, 192.168.1.0/24")
+	c.Assert(err, gc.IsNil)
+	s.assertExposed(c, "some-service-name")
+	svc, err := s.State.Service("some-service-name")
+	c.Assert(err, gc.IsNil)
+	c.Assert(svc.ExposedCIDRs(), jc.DeepEquals, []string{"10.0.0.0/8", "192.168.1.0/24"})
+
+	// Exposing without --from lifts the restriction.
+	err = runExpose(c, "some-service-name")
+	c.Assert(err, gc.IsNil)
+	err = svc.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(svc.ExposedCIDRs(), gc.HasLen, 0)
+}
+
+func (s *ExposeSuite) TestExposeFromInvalid(c *gc.C) {
+	err := runExpose(c, "some-service-name", "--from", "10.0.0.0/8,foo")
+	c.Assert(err, gc.ErrorMatches, `invalid source CIDR "foo"`)
+
+	err = runExpose(c, "some-service-name", "--from", ",")
+	c.Assert(err, gc.ErrorMatches, `no CIDRs specified with --from`)
+}
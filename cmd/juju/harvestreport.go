@@ -0,0 +1,99 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/environs/config"
+)
+
+const harvestReportDoc = `
+Reports the instances the provisioner would terminate, without
+terminating any. Which instances are terminated is determined by the
+environment's provisioner-harvest-mode setting:
+
+    none       no instances are terminated
+    destroyed  only the instances of machines juju has destroyed
+    unknown    only instances juju has never known about
+    all        both of the above
+
+The --mode option reports on the given harvest mode instead, so the
+effect of a change to provisioner-harvest-mode can be checked before
+it is made.
+
+Example:
+
+    juju harvest-report --mode unknown
+`
+
+// HarvestReportCommand reports the instances the provisioner would
+// terminate.
+type HarvestReportCommand struct {
+	envcmd.EnvCommandBase
+	Mode string
+	out  cmd.Output
+}
+
+func (c *HarvestReportCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "harvest-report",
+		Purpose: "show the instances the provisioner would terminate",
+		Doc:     harvestReportDoc,
+	}
+}
+
+func (c *HarvestReportCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.Mode, "mode", "", "harvest mode to report on instead of the environment's")
+	c.out.AddFlags(f, "yaml", map[string]cmd.Formatter{
+		"yaml": cmd.FormatYaml,
+		"json": cmd.FormatJson,
+	})
+}
+
+func (c *HarvestReportCommand) Init(args []string) error {
+	if c.Mode != "" {
+		if _, err := config.ParseHarvestMode(c.Mode); err != nil {
+			return err
+		}
+	}
+	return cmd.CheckEmpty(args)
+}
+
+type harvestedInstance struct {
+	InstanceId string `json:"instance-id" yaml:"instance-id"`
+	Machine    string `json:"machine,omitempty" yaml:"machine,omitempty"`
+	Reason     string `json:"reason" yaml:"reason"`
+}
+
+type harvestReport struct {
+	Mode      string              `json:"mode" yaml:"mode"`
+	Instances []harvestedInstance `json:"instances" yaml:"instances"`
+}
+
+func (c *HarvestReportCommand) Run(ctx *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	results, err := client.HarvestReport(c.Mode)
+	if err != nil {
+		return err
+	}
+	report := harvestReport{
+		Mode:      results.Mode,
+		Instances: make([]harvestedInstance, len(results.Instances)),
+	}
+	for i, inst := range results.Instances {
+		report.Instances[i] = harvestedInstance{
+			InstanceId: string(inst.InstanceId),
+			Machine:    inst.MachineId,
+			Reason:     inst.Reason,
+		}
+	}
+	return c.out.Write(ctx, report)
+}
@@ -0,0 +1,60 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/cmd/envcmd"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/provider/dummy"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+)
+
+type HarvestReportSuite struct {
+	jujutesting.JujuConnSuite
+}
+
+var _ = gc.Suite(&HarvestReportSuite{})
+
+func runHarvestReport(c *gc.C, args ...string) (string, error) {
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&HarvestReportCommand{}), args...)
+	if err != nil {
+		return "", err
+	}
+	return testing.Stdout(ctx), nil
+}
+
+func (s *HarvestReportSuite) TestInit(c *gc.C) {
+	_, err := runHarvestReport(c, "--mode", "everything")
+	c.Assert(err, gc.ErrorMatches, `unknown harvest mode "everything"`)
+	_, err = runHarvestReport(c, "roflcopter")
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["roflcopter"\]`)
+}
+
+func (s *HarvestReportSuite) TestHarvestReport(c *gc.C) {
+	m0, err := s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, gc.IsNil)
+	err = m0.SetProvisioned(dummy.BootstrapInstanceId, "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	m1, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	inst, _ := jujutesting.AssertStartInstance(c, s.Environ, m1.Id())
+	err = m1.SetProvisioned(inst.Id(), "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(m1.EnsureDead(), gc.IsNil)
+	unknown, _ := jujutesting.AssertStartInstance(c, s.Environ, "99")
+
+	out, err := runHarvestReport(c, "--format", "json")
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Equals, `{"mode":"all","instances":[`+
+		`{"instance-id":"`+string(inst.Id())+`","machine":"1","reason":"destroyed"},`+
+		`{"instance-id":"`+string(unknown.Id())+`","reason":"unknown"}`+
+		"]}\n")
+
+	out, err = runHarvestReport(c, "--mode", "none", "--format", "json")
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Equals, `{"mode":"none","instances":[]}`+"\n")
+}
@@ -26,6 +26,9 @@ func (dummyHookContext) PublicAddress() (string, bool) {
 func (dummyHookContext) PrivateAddress() (string, bool) {
 	return "", false
 }
+func (dummyHookContext) EndpointAddresses() map[string]string {
+	return nil
+}
 func (dummyHookContext) OpenPort(protocol string, port int) error {
 	return nil
 }
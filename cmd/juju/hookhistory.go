@@ -0,0 +1,93 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+const hookHistoryDoc = `
+Shows the most recent hook executions of a unit, oldest first, with
+their duration, exit code and the last lines they output.
+
+Example:
+
+    juju hook-history wordpress/0
+`
+
+// HookHistoryCommand shows the hook executions recorded for a unit.
+type HookHistoryCommand struct {
+	envcmd.EnvCommandBase
+	UnitName string
+	out      cmd.Output
+}
+
+func (c *HookHistoryCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "hook-history",
+		Args:    "<unit>",
+		Purpose: "show the hooks recently run by a unit",
+		Doc:     hookHistoryDoc,
+	}
+}
+
+func (c *HookHistoryCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", map[string]cmd.Formatter{
+		"yaml": cmd.FormatYaml,
+		"json": cmd.FormatJson,
+	})
+}
+
+func (c *HookHistoryCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no unit specified")
+	}
+	c.UnitName = args[0]
+	if !names.IsValidUnit(c.UnitName) {
+		return fmt.Errorf("invalid unit name %q", c.UnitName)
+	}
+	return cmd.CheckEmpty(args[1:])
+}
+
+type hookExecution struct {
+	Hook       string   `json:"hook" yaml:"hook"`
+	Relation   string   `json:"relation,omitempty" yaml:"relation,omitempty"`
+	RemoteUnit string   `json:"remote-unit,omitempty" yaml:"remote-unit,omitempty"`
+	Started    string   `json:"started" yaml:"started"`
+	Duration   string   `json:"duration" yaml:"duration"`
+	ExitCode   int      `json:"exit-code" yaml:"exit-code"`
+	Output     []string `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+func (c *HookHistoryCommand) Run(ctx *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	executions, err := client.HookHistory(c.UnitName)
+	if err != nil {
+		return err
+	}
+	result := make([]hookExecution, len(executions))
+	for i, execution := range executions {
+		result[i] = hookExecution{
+			Hook:       execution.Hook,
+			Relation:   execution.Relation,
+			RemoteUnit: execution.RemoteUnit,
+			Started:    execution.Started.UTC().Format(time.RFC3339),
+			Duration:   execution.Duration.String(),
+			ExitCode:   execution.ExitCode,
+			Output:     execution.Output,
+		}
+	}
+	return c.out.Write(ctx, result)
+}
@@ -0,0 +1,76 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"time"
+
+	charmtesting "github.com/juju/charm/testing"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/cmd/envcmd"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+)
+
+type HookHistorySuite struct {
+	jujutesting.RepoSuite
+}
+
+var _ = gc.Suite(&HookHistorySuite{})
+
+func runHookHistory(c *gc.C, args ...string) (string, error) {
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&HookHistoryCommand{}), args...)
+	if err != nil {
+		return "", err
+	}
+	return testing.Stdout(ctx), nil
+}
+
+func (s *HookHistorySuite) TestInit(c *gc.C) {
+	_, err := runHookHistory(c)
+	c.Assert(err, gc.ErrorMatches, "no unit specified")
+	_, err = runHookHistory(c, "jeremy-fisher")
+	c.Assert(err, gc.ErrorMatches, `invalid unit name "jeremy-fisher"`)
+	_, err = runHookHistory(c, "dummy/0", "roflcopter")
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["roflcopter"\]`)
+}
+
+func (s *HookHistorySuite) TestHookHistory(c *gc.C) {
+	charmtesting.Charms.BundlePath(s.SeriesPath, "dummy")
+	err := runDeploy(c, "local:dummy", "dummy")
+	c.Assert(err, gc.IsNil)
+	unit, err := s.State.Unit("dummy/0")
+	c.Assert(err, gc.IsNil)
+
+	started := time.Date(2014, 9, 1, 12, 0, 0, 0, time.UTC)
+	err = unit.AddHookExecution(state.HookExecution{
+		Hook:     "install",
+		Started:  started,
+		Duration: 1500 * time.Millisecond,
+	})
+	c.Assert(err, gc.IsNil)
+	err = unit.AddHookExecution(state.HookExecution{
+		Hook:       "db-relation-changed",
+		Relation:   "dummy:db mysql:server",
+		RemoteUnit: "mysql/0",
+		Started:    started.Add(time.Minute),
+		Duration:   time.Second,
+		ExitCode:   1,
+		Output:     []string{"cannot connect"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	out, err := runHookHistory(c, "dummy/0", "--format", "json")
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Equals, `[`+
+		`{"hook":"install","started":"2014-09-01T12:00:00Z","duration":"1.5s","exit-code":0},`+
+		`{"hook":"db-relation-changed","relation":"dummy:db mysql:server","remote-unit":"mysql/0",`+
+		`"started":"2014-09-01T12:01:00Z","duration":"1s","exit-code":1,"output":["cannot connect"]}`+
+		"]\n")
+
+	_, err = runHookHistory(c, "dummy/1")
+	c.Assert(err, gc.ErrorMatches, `unit "dummy/1" not found`)
+}
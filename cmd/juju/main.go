@@ -114,7 +114,12 @@ func registerCommands(r commandRegistry, ctx *cmd.Context) {
 	r.Register(wrapEnvCommand(&ResolvedCommand{}))
 	r.Register(wrapEnvCommand(&DebugLogCommand{}))
 	r.Register(wrapEnvCommand(&DebugHooksCommand{}))
+	r.Register(wrapEnvCommand(&HookHistoryCommand{}))
+	r.Register(&ReplayHookCommand{})
+	r.Register(wrapEnvCommand(&SetHookRetryCommand{}))
 	r.Register(wrapEnvCommand(&RetryProvisioningCommand{}))
+	r.Register(wrapEnvCommand(&StatusHistoryCommand{}))
+	r.Register(wrapEnvCommand(&HarvestReportCommand{}))
 
 	// Configuration commands.
 	r.Register(&InitCommand{})
@@ -137,6 +142,7 @@ func registerCommands(r commandRegistry, ctx *cmd.Context) {
 
 	// Charm tool commands.
 	r.Register(&HelpToolCommand{})
+	r.Register(&TestCharmCommand{})
 
 	// Manage authorized ssh keys.
 	r.Register(NewAuthorizedKeysCommand())
@@ -144,6 +150,12 @@ func registerCommands(r commandRegistry, ctx *cmd.Context) {
 	// Manage users and access
 	r.Register(NewUserCommand())
 
+	// Manage network spaces.
+	r.Register(NewSpaceCommand())
+
+	// Inspect discovered subnets.
+	r.Register(NewSubnetCommand())
+
 	// Manage state server availability.
 	r.Register(wrapEnvCommand(&EnsureAvailabilityCommand{}))
 }
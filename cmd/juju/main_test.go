@@ -210,14 +210,17 @@ var commandNames = []string{
 	"get-constraints",
 	"get-env", // alias for get-environment
 	"get-environment",
+	"harvest-report",
 	"help",
 	"help-tool",
+	"hook-history",
 	"init",
 	"publish",
 	"remove-machine",  // alias for destroy-machine
 	"remove-relation", // alias for destroy-relation
 	"remove-service",  // alias for destroy-service
 	"remove-unit",     // alias for destroy-unit
+	"replay-hook",
 	"resolved",
 	"retry-provisioning",
 	"run",
@@ -226,11 +229,16 @@ var commandNames = []string{
 	"set-constraints",
 	"set-env", // alias for set-environment
 	"set-environment",
+	"set-hook-retry",
+	"space",
 	"ssh",
 	"stat", // alias for status
 	"status",
+	"status-history",
+	"subnet",
 	"switch",
 	"sync-tools",
+	"test-charm",
 	"terminate-machine", // alias for destroy-machine
 	"unexpose",
 	"unset",
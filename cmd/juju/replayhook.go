@@ -0,0 +1,143 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/worker/uniter/harness"
+	"github.com/juju/juju/worker/uniter/jujuc"
+)
+
+// ReplayHookCommand runs a hook locally against a hook context
+// captured by a unit agent.
+type ReplayHookCommand struct {
+	cmd.CommandBase
+	contextFile string
+	charmDir    string
+	jujud       string
+}
+
+const replayHookDoc = `
+Run a hook on this machine against a hook context captured by a unit.
+
+When the environment's hook-context-capture setting is true, units record
+the context of each hook they run: its environment, the service's config
+settings, the unit's relations and their settings, the remote unit and any
+action parameters. The most recent captured contexts of a unit are kept in
+its agent's state directory, and can be downloaded with juju scp:
+
+    juju scp mysql/0:/var/lib/juju/agents/unit-mysql-0/state/hook-contexts/<file> .
+
+replay-hook runs the captured hook from a local copy of the charm, with hook
+tools such as config-get and relation-get answering from the captured
+context. Changes the hook makes, such as setting relation settings or
+opening ports, are not applied anywhere. The hook tools are provided by
+jujud, which must be found in $PATH or given with --jujud.
+
+Examples:
+
+    juju set-env hook-context-capture=true
+    juju replay-hook --charm-dir ~/charms/trusty/mysql 20140905-101530.000000000-db-relation-changed.json
+`
+
+func (c *ReplayHookCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "replay-hook",
+		Args:    "<context file>",
+		Purpose: "run a hook locally against a captured hook context",
+		Doc:     replayHookDoc,
+	}
+}
+
+func (c *ReplayHookCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.charmDir, "charm-dir", ".", "directory holding the charm to run the hook from")
+	f.StringVar(&c.jujud, "jujud", "", "path to the jujud executable providing the hook tools")
+}
+
+func (c *ReplayHookCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no context file specified")
+	}
+	c.contextFile, args = args[0], args[1:]
+	return cmd.CheckEmpty(args)
+}
+
+func (c *ReplayHookCommand) Run(ctx *cmd.Context) error {
+	data, err := ioutil.ReadFile(ctx.AbsPath(c.contextFile))
+	if err != nil {
+		return err
+	}
+	var captured jujuc.CapturedContext
+	if err := json.Unmarshal(data, &captured); err != nil {
+		return fmt.Errorf("cannot read hook context: %v", err)
+	}
+	if captured.HookName == "" {
+		return fmt.Errorf("cannot read hook context: no hook name found")
+	}
+	charmDir := ctx.AbsPath(c.charmDir)
+	location := "hooks"
+	if captured.Action {
+		location = "actions"
+	}
+	hookPath := filepath.Join(charmDir, location, captured.HookName)
+	if _, err := os.Stat(hookPath); err != nil {
+		return fmt.Errorf("cannot find %q in charm: %v", filepath.Join(location, captured.HookName), err)
+	}
+	jujud, err := jujudPath(ctx, c.jujud)
+	if err != nil {
+		return err
+	}
+
+	tools, err := harness.NewToolServer(captured.Context(), jujud)
+	if err != nil {
+		return err
+	}
+	defer tools.Close()
+
+	ctx.Infof("replaying %q hook of unit %s", captured.HookName, captured.UnitName)
+	ps := exec.Command(hookPath)
+	vars := tools.Environment()
+	vars["CHARM_DIR"] = charmDir
+	ps.Env = harness.MergeEnvironment(captured.Environment, vars)
+	ps.Dir = charmDir
+	ps.Stdin = ctx.Stdin
+	ps.Stdout = ctx.Stdout
+	ps.Stderr = ctx.Stderr
+	err = ps.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(interface {
+			ExitStatus() int
+		}); ok {
+			return cmd.NewRcPassthroughError(status.ExitStatus())
+		}
+	}
+	return err
+}
+
+// jujudPath returns the path of the jujud executable used to provide
+// hook tools run outside a unit agent: the given path if set, and the
+// one found in $PATH otherwise.
+func jujudPath(ctx *cmd.Context, path string) (string, error) {
+	if path != "" {
+		path = ctx.AbsPath(path)
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	path, err := harness.FindJujud()
+	if err != nil {
+		return "", fmt.Errorf("%v; specify its location with --jujud", err)
+	}
+	return path, nil
+}
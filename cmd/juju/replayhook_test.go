@@ -0,0 +1,159 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/uniter/jujuc"
+)
+
+type ReplayHookSuite struct {
+	testing.BaseSuite
+	charmDir    string
+	jujud       string
+	contextFile string
+}
+
+var _ = gc.Suite(&ReplayHookSuite{})
+
+func (s *ReplayHookSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	dir := c.MkDir()
+	s.charmDir = filepath.Join(dir, "charm")
+	err := os.MkdirAll(filepath.Join(s.charmDir, "hooks"), 0755)
+	c.Assert(err, gc.IsNil)
+
+	// The hooks run below do not call any hook tools, so jujud
+	// need only exist.
+	s.jujud = filepath.Join(dir, "jujud")
+	err = ioutil.WriteFile(s.jujud, []byte("#!/bin/sh\nexit 1\n"), 0755)
+	c.Assert(err, gc.IsNil)
+
+	s.contextFile = filepath.Join(dir, "context.json")
+	s.writeContext(c, &jujuc.CapturedContext{
+		HookName: "db-relation-changed",
+		Environment: []string{
+			"CHARM_DIR=/var/lib/juju/agents/unit-mysql-0/charm",
+			"JUJU_CONTEXT_ID=mysql/0:db-relation-changed:1234",
+			"JUJU_UNIT_NAME=mysql/0",
+			"JUJU_REMOTE_UNIT=wordpress/0",
+		},
+		UnitName:       "mysql/0",
+		HookRelationId: -1,
+	})
+}
+
+func (s *ReplayHookSuite) writeContext(c *gc.C, captured *jujuc.CapturedContext) {
+	data, err := json.Marshal(captured)
+	c.Assert(err, gc.IsNil)
+	err = ioutil.WriteFile(s.contextFile, data, 0644)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ReplayHookSuite) writeHook(c *gc.C, name, script string) {
+	err := ioutil.WriteFile(filepath.Join(s.charmDir, "hooks", name), []byte("#!/bin/sh\n"+script), 0755)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ReplayHookSuite) runReplayHook(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, &ReplayHookCommand{}, args...)
+}
+
+func (s *ReplayHookSuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args        []string
+		contextFile string
+		err         string
+	}{{
+		err: "no context file specified",
+	}, {
+		args:        []string{"context.json"},
+		contextFile: "context.json",
+	}, {
+		args: []string{"context.json", "foo"},
+		err:  `unrecognized args: \["foo"\]`,
+	}} {
+		c.Logf("test %d", i)
+		replayCmd := &ReplayHookCommand{}
+		err := testing.InitCommand(replayCmd, test.args)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(replayCmd.contextFile, gc.Equals, test.contextFile)
+		c.Check(replayCmd.charmDir, gc.Equals, ".")
+	}
+}
+
+func (s *ReplayHookSuite) TestReplayHook(c *gc.C) {
+	s.writeHook(c, "db-relation-changed", `
+echo $JUJU_UNIT_NAME $JUJU_REMOTE_UNIT $JUJU_CONTEXT_ID
+[ "$CHARM_DIR" = "$(pwd)" ] && echo in charm dir
+[ -S "$JUJU_AGENT_SOCKET" ] && echo socket exists
+readlink $(command -v relation-get)
+echo oops >&2
+`)
+	ctx, err := s.runReplayHook(c, "--charm-dir", s.charmDir, "--jujud", s.jujud, s.contextFile)
+	c.Assert(err, gc.IsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, ""+
+		"mysql/0 wordpress/0 harness\n"+
+		"in charm dir\n"+
+		"socket exists\n"+
+		s.jujud+"\n")
+	c.Assert(testing.Stderr(ctx), gc.Equals, ""+
+		"replaying \"db-relation-changed\" hook of unit mysql/0\n"+
+		"oops\n")
+}
+
+func (s *ReplayHookSuite) TestReplayAction(c *gc.C) {
+	err := os.Mkdir(filepath.Join(s.charmDir, "actions"), 0755)
+	c.Assert(err, gc.IsNil)
+	err = ioutil.WriteFile(filepath.Join(s.charmDir, "actions", "snapshot"), []byte("#!/bin/sh\necho snapped\n"), 0755)
+	c.Assert(err, gc.IsNil)
+	s.writeContext(c, &jujuc.CapturedContext{
+		HookName:       "snapshot",
+		Action:         true,
+		UnitName:       "mysql/0",
+		HookRelationId: -1,
+	})
+	ctx, err := s.runReplayHook(c, "--charm-dir", s.charmDir, "--jujud", s.jujud, s.contextFile)
+	c.Assert(err, gc.IsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, "snapped\n")
+}
+
+func (s *ReplayHookSuite) TestReplayHookExitCode(c *gc.C) {
+	s.writeHook(c, "db-relation-changed", "exit 3\n")
+	_, err := s.runReplayHook(c, "--charm-dir", s.charmDir, "--jujud", s.jujud, s.contextFile)
+	c.Assert(err, jc.Satisfies, cmd.IsRcPassthroughError)
+	c.Assert(err, gc.ErrorMatches, "subprocess encountered error code 3")
+}
+
+func (s *ReplayHookSuite) TestMissingHook(c *gc.C) {
+	_, err := s.runReplayHook(c, "--charm-dir", s.charmDir, "--jujud", s.jujud, s.contextFile)
+	c.Assert(err, gc.ErrorMatches, `cannot find "hooks/db-relation-changed" in charm: .*`)
+}
+
+func (s *ReplayHookSuite) TestMissingJujud(c *gc.C) {
+	s.writeHook(c, "db-relation-changed", "exit 0\n")
+	s.PatchEnvironment("PATH", "")
+	_, err := s.runReplayHook(c, "--charm-dir", s.charmDir, s.contextFile)
+	c.Assert(err, gc.ErrorMatches, "cannot find jujud to provide the hook tools; specify its location with --jujud")
+}
+
+func (s *ReplayHookSuite) TestBadContextFile(c *gc.C) {
+	err := ioutil.WriteFile(s.contextFile, []byte("not json"), 0644)
+	c.Assert(err, gc.IsNil)
+	_, err = s.runReplayHook(c, "--charm-dir", s.charmDir, s.contextFile)
+	c.Assert(err, gc.ErrorMatches, "cannot read hook context: .*")
+}
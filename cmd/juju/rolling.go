@@ -0,0 +1,190 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/state/api"
+	"github.com/juju/juju/state/api/params"
+)
+
+// rollingPollInterval is how often the status of a batch of units is
+// checked while waiting for it to become healthy.
+var rollingPollInterval = 5 * time.Second
+
+// rollingStatusAPI is the part of the API needed to check the health of
+// units during a rolling operation.
+type rollingStatusAPI interface {
+	Status(patterns []string) (*api.Status, error)
+}
+
+// rollingOptions holds the flags shared by commands that can operate on
+// the units of a service a few at a time.
+type rollingOptions struct {
+	batchSize    int
+	batchPause   time.Duration
+	batchTimeout time.Duration
+}
+
+func (o *rollingOptions) addFlags(f *gnuflag.FlagSet) {
+	f.IntVar(&o.batchSize, "batch-size", 0, "number of units to operate on at a time (default all at once)")
+	f.DurationVar(&o.batchPause, "batch-pause", 0, "how long to wait after each batch before checking it again and moving on")
+	f.DurationVar(&o.batchTimeout, "batch-timeout", 10*time.Minute, "how long to wait for each batch to become healthy")
+}
+
+func (o *rollingOptions) validate() error {
+	if o.batchSize < 0 {
+		return fmt.Errorf("--batch-size must not be negative")
+	}
+	if o.batchSize == 0 && o.batchPause != 0 {
+		return fmt.Errorf("--batch-pause requires --batch-size")
+	}
+	return nil
+}
+
+// rolling returns whether a rolling operation was requested.
+func (o *rollingOptions) rolling() bool {
+	return o.batchSize > 0
+}
+
+// rollingExecutor applies an operation to units a batch at a time. After
+// each batch, it waits for the batch's units to be started and ready,
+// pauses, and checks them again before moving on to the next batch. It
+// stops at the first unit found in an error state.
+type rollingExecutor struct {
+	rollingOptions
+	client rollingStatusAPI
+
+	// ready returns whether the unit has finished applying the
+	// operation. Units must also be started to be considered healthy.
+	ready func(unitName string, status api.UnitStatus) bool
+}
+
+// run applies apply to the given units in batches. It returns the names
+// of the units that were not operated on if it stops early.
+func (e *rollingExecutor) run(ctx *cmd.Context, unitNames []string, apply func(batch []string) error) (remaining []string, err error) {
+	batchCount := (len(unitNames) + e.batchSize - 1) / e.batchSize
+	for i := 0; i < batchCount; i++ {
+		start := i * e.batchSize
+		end := start + e.batchSize
+		if end > len(unitNames) {
+			end = len(unitNames)
+		}
+		batch := unitNames[start:end]
+		ctx.Infof("batch %d of %d: %s", i+1, batchCount, strings.Join(batch, ", "))
+		if err := apply(batch); err != nil {
+			return unitNames[start:], err
+		}
+		if err := e.waitHealthy(batch); err != nil {
+			return unitNames[end:], err
+		}
+		if e.batchPause > 0 {
+			time.Sleep(e.batchPause)
+			if err := e.checkHealthy(batch); err != nil {
+				return unitNames[end:], err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// waitHealthy waits until all the given units are healthy, returning an
+// error if any of them fails or the batch timeout expires.
+func (e *rollingExecutor) waitHealthy(unitNames []string) error {
+	timeout := time.After(e.batchTimeout)
+	for {
+		pending, err := e.unhealthy(unitNames)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for units to become healthy: %s", strings.Join(pending, ", "))
+		case <-time.After(rollingPollInterval):
+		}
+	}
+}
+
+// checkHealthy returns an error if any of the given units is not healthy.
+func (e *rollingExecutor) checkHealthy(unitNames []string) error {
+	pending, err := e.unhealthy(unitNames)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("units no longer healthy: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// unhealthy returns the names of the given units that are not yet
+// healthy, or an error if any of them has failed.
+func (e *rollingExecutor) unhealthy(unitNames []string) ([]string, error) {
+	status, err := e.client.Status(unitNames)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, unitName := range unitNames {
+		service := status.Services[names.UnitService(unitName)]
+		unit, ok := service.Units[unitName]
+		if !ok {
+			return nil, fmt.Errorf("unit %q not found", unitName)
+		}
+		if unit.Err != nil {
+			return nil, fmt.Errorf("unit %q failed: %v", unitName, unit.Err)
+		}
+		if unit.AgentState == params.StatusError {
+			return nil, fmt.Errorf("unit %q failed: %s", unitName, unit.AgentStateInfo)
+		}
+		if unit.AgentState != params.StatusStarted || (e.ready != nil && !e.ready(unitName, unit)) {
+			pending = append(pending, unitName)
+		}
+	}
+	return pending, nil
+}
+
+// serviceUnitNames returns the sorted names of the principal units of
+// the given services, together with the given units.
+func serviceUnitNames(client rollingStatusAPI, services, units []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var unitNames []string
+	for _, unitName := range units {
+		if !seen[unitName] {
+			seen[unitName] = true
+			unitNames = append(unitNames, unitName)
+		}
+	}
+	if len(services) > 0 {
+		status, err := client.Status(services)
+		if err != nil {
+			return nil, err
+		}
+		for _, serviceName := range services {
+			service, ok := status.Services[serviceName]
+			if !ok {
+				return nil, fmt.Errorf("service %q not found", serviceName)
+			}
+			for unitName := range service.Units {
+				if !seen[unitName] {
+					seen[unitName] = true
+					unitNames = append(unitNames, unitName)
+				}
+			}
+		}
+	}
+	sort.Strings(unitNames)
+	return unitNames, nil
+}
@@ -0,0 +1,238 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/state/api"
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/testing"
+)
+
+type RollingSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&RollingSuite{})
+
+func (s *RollingSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.PatchValue(&rollingPollInterval, time.Duration(0))
+}
+
+// fakeRollingStatus reports the status of units held in a map.
+type fakeRollingStatus struct {
+	units map[string]api.UnitStatus
+	calls int
+	// onStatus, if set, is called before each status is returned.
+	onStatus func()
+}
+
+func (f *fakeRollingStatus) Status(patterns []string) (*api.Status, error) {
+	f.calls++
+	if f.onStatus != nil {
+		f.onStatus()
+	}
+	services := make(map[string]api.ServiceStatus)
+	for name, unit := range f.units {
+		service := services[names.UnitService(name)]
+		if service.Units == nil {
+			service.Units = make(map[string]api.UnitStatus)
+		}
+		service.Units[name] = unit
+		services[names.UnitService(name)] = service
+	}
+	return &api.Status{Services: services}, nil
+}
+
+func (f *fakeRollingStatus) set(state params.Status, unitNames ...string) {
+	for _, name := range unitNames {
+		f.units[name] = api.UnitStatus{AgentState: state}
+	}
+}
+
+func newFakeRollingStatus(unitNames ...string) *fakeRollingStatus {
+	f := &fakeRollingStatus{units: make(map[string]api.UnitStatus)}
+	f.set(params.StatusStarted, unitNames...)
+	return f
+}
+
+func (s *RollingSuite) TestRollingOptionsValidate(c *gc.C) {
+	for i, test := range []struct {
+		options rollingOptions
+		err     string
+	}{{
+		options: rollingOptions{},
+	}, {
+		options: rollingOptions{batchSize: 2, batchPause: time.Minute},
+	}, {
+		options: rollingOptions{batchSize: -1},
+		err:     "--batch-size must not be negative",
+	}, {
+		options: rollingOptions{batchPause: time.Minute},
+		err:     "--batch-pause requires --batch-size",
+	}} {
+		c.Logf("test %d", i)
+		err := test.options.validate()
+		if test.err == "" {
+			c.Check(err, gc.IsNil)
+		} else {
+			c.Check(err, gc.ErrorMatches, test.err)
+		}
+	}
+}
+
+func (s *RollingSuite) TestRunBatches(c *gc.C) {
+	unitNames := []string{"mysql/0", "mysql/1", "mysql/2"}
+	client := newFakeRollingStatus(unitNames...)
+	executor := &rollingExecutor{
+		rollingOptions: rollingOptions{batchSize: 2, batchTimeout: testing.LongWait},
+		client:         client,
+	}
+	var batches [][]string
+	ctx := testing.Context(c)
+	remaining, err := executor.run(ctx, unitNames, func(batch []string) error {
+		batches = append(batches, batch)
+		// The units restart, and become started again after
+		// the next status check.
+		client.set(params.StatusPending, batch...)
+		client.onStatus = func() {
+			client.set(params.StatusStarted, batch...)
+		}
+		return nil
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(remaining, gc.HasLen, 0)
+	c.Assert(batches, jc.DeepEquals, [][]string{{"mysql/0", "mysql/1"}, {"mysql/2"}})
+	c.Assert(testing.Stderr(ctx), gc.Equals, ""+
+		"batch 1 of 2: mysql/0, mysql/1\n"+
+		"batch 2 of 2: mysql/2\n")
+}
+
+func (s *RollingSuite) TestRunWaitsForReady(c *gc.C) {
+	unitNames := []string{"mysql/0", "mysql/1"}
+	client := newFakeRollingStatus(unitNames...)
+	ready := make(map[string]bool)
+	executor := &rollingExecutor{
+		rollingOptions: rollingOptions{batchSize: 1, batchTimeout: testing.LongWait},
+		client:         client,
+		ready: func(unitName string, _ api.UnitStatus) bool {
+			return ready[unitName]
+		},
+	}
+	_, err := executor.run(testing.Context(c), unitNames, func(batch []string) error {
+		client.onStatus = func() {
+			if client.calls > 2 {
+				ready[batch[0]] = true
+			}
+		}
+		client.calls = 0
+		return nil
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(ready, jc.DeepEquals, map[string]bool{"mysql/0": true, "mysql/1": true})
+}
+
+func (s *RollingSuite) TestRunAbortsOnError(c *gc.C) {
+	unitNames := []string{"mysql/0", "mysql/1", "mysql/2"}
+	client := newFakeRollingStatus(unitNames...)
+	executor := &rollingExecutor{
+		rollingOptions: rollingOptions{batchSize: 1, batchTimeout: testing.LongWait},
+		client:         client,
+	}
+	var batches [][]string
+	remaining, err := executor.run(testing.Context(c), unitNames, func(batch []string) error {
+		batches = append(batches, batch)
+		if batch[0] == "mysql/1" {
+			client.units["mysql/1"] = api.UnitStatus{
+				AgentState:     params.StatusError,
+				AgentStateInfo: `hook failed: "config-changed"`,
+			}
+		}
+		return nil
+	})
+	c.Assert(err, gc.ErrorMatches, `unit "mysql/1" failed: hook failed: "config-changed"`)
+	c.Assert(remaining, jc.DeepEquals, []string{"mysql/2"})
+	c.Assert(batches, jc.DeepEquals, [][]string{{"mysql/0"}, {"mysql/1"}})
+}
+
+func (s *RollingSuite) TestRunAbortsOnApplyError(c *gc.C) {
+	unitNames := []string{"mysql/0", "mysql/1"}
+	executor := &rollingExecutor{
+		rollingOptions: rollingOptions{batchSize: 1, batchTimeout: testing.LongWait},
+		client:         newFakeRollingStatus(unitNames...),
+	}
+	remaining, err := executor.run(testing.Context(c), unitNames, func(batch []string) error {
+		return fmt.Errorf("boom")
+	})
+	c.Assert(err, gc.ErrorMatches, "boom")
+	c.Assert(remaining, jc.DeepEquals, unitNames)
+}
+
+func (s *RollingSuite) TestRunChecksAgainAfterPause(c *gc.C) {
+	unitNames := []string{"mysql/0", "mysql/1"}
+	client := newFakeRollingStatus(unitNames...)
+	executor := &rollingExecutor{
+		rollingOptions: rollingOptions{
+			batchSize:    1,
+			batchPause:   time.Millisecond,
+			batchTimeout: testing.LongWait,
+		},
+		client: client,
+	}
+	remaining, err := executor.run(testing.Context(c), unitNames, func(batch []string) error {
+		// The unit fails after first appearing healthy.
+		client.onStatus = func() {
+			if client.calls > 1 {
+				client.set(params.StatusError, batch...)
+			}
+		}
+		return nil
+	})
+	c.Assert(err, gc.ErrorMatches, `unit "mysql/0" failed: `)
+	c.Assert(remaining, jc.DeepEquals, []string{"mysql/1"})
+}
+
+func (s *RollingSuite) TestRunTimeout(c *gc.C) {
+	unitNames := []string{"mysql/0", "mysql/1"}
+	client := newFakeRollingStatus(unitNames...)
+	executor := &rollingExecutor{
+		rollingOptions: rollingOptions{batchSize: 2, batchTimeout: testing.ShortWait},
+		client:         client,
+	}
+	s.PatchValue(&rollingPollInterval, time.Millisecond)
+	remaining, err := executor.run(testing.Context(c), unitNames, func(batch []string) error {
+		client.set(params.StatusPending, "mysql/1")
+		return nil
+	})
+	c.Assert(err, gc.ErrorMatches, "timed out waiting for units to become healthy: mysql/1")
+	c.Assert(remaining, gc.HasLen, 0)
+}
+
+func (s *RollingSuite) TestRunMissingUnit(c *gc.C) {
+	executor := &rollingExecutor{
+		rollingOptions: rollingOptions{batchSize: 1, batchTimeout: testing.LongWait},
+		client:         newFakeRollingStatus(),
+	}
+	_, err := executor.run(testing.Context(c), []string{"mysql/0"}, func([]string) error {
+		return nil
+	})
+	c.Assert(err, gc.ErrorMatches, `unit "mysql/0" not found`)
+}
+
+func (s *RollingSuite) TestServiceUnitNames(c *gc.C) {
+	client := newFakeRollingStatus("mysql/1", "mysql/0", "wordpress/0")
+	unitNames, err := serviceUnitNames(client, []string{"mysql"}, []string{"wordpress/0", "mysql/1"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(unitNames, jc.DeepEquals, []string{"mysql/0", "mysql/1", "wordpress/0"})
+
+	_, err = serviceUnitNames(client, []string{"riak"}, nil)
+	c.Assert(err, gc.ErrorMatches, `service "riak" not found`)
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"launchpad.net/gnuflag"
 
 	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/state/api"
 	"github.com/juju/juju/state/api/params"
 )
 
@@ -28,6 +30,9 @@ type RunCommand struct {
 	services []string
 	units    []string
 	commands string
+	async    bool
+	attach   string
+	rollingOptions
 }
 
 const runDoc = `
@@ -56,12 +61,30 @@ the unit.
 in the environment.  If you specify --all you cannot provide additional
 targets.
 
+With --async, the commands are started but not waited for, and the id of
+the job running them is printed.  The output of the job is kept in the
+environment, and
+
+    juju run --attach <job id>
+
+shows it, following any further output as it arrives until the commands
+have finished on all targets, or until --timeout has passed.  With the
+default format, each line of output is prefixed by the unit or machine it
+came from; with other formats, the complete results are shown once the
+job is done.
+
+--batch-size runs the commands on units a few at a time rather than all at
+once.  After each batch, juju waits for its units to be started without
+hook errors, waits for --batch-pause, and checks the units again before
+moving on.  The run stops at the first failed command or unhealthy unit.
+Batches can only be used with --service and --unit targets.
+
 `
 
 func (c *RunCommand) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:    "run",
-		Args:    "<commands>",
+		Args:    "<commands> | --attach <job id>",
 		Purpose: "run the commands on the remote targets specified",
 		Doc:     runDoc,
 	}
@@ -74,9 +97,18 @@ func (c *RunCommand) SetFlags(f *gnuflag.FlagSet) {
 	f.Var(cmd.NewStringsValue(nil, &c.machines), "machine", "one or more machine ids")
 	f.Var(cmd.NewStringsValue(nil, &c.services), "service", "one or more service names")
 	f.Var(cmd.NewStringsValue(nil, &c.units), "unit", "one or more unit ids")
+	f.BoolVar(&c.async, "async", false, "start the commands without waiting for them to finish, and print the job id")
+	f.StringVar(&c.attach, "attach", "", "show the output of the job with the given id, waiting for it to finish")
+	c.rollingOptions.addFlags(f)
 }
 
 func (c *RunCommand) Init(args []string) error {
+	if c.attach != "" {
+		if c.async || c.rolling() || c.all || len(c.machines) != 0 || len(c.services) != 0 || len(c.units) != 0 {
+			return fmt.Errorf("You cannot specify --attach with --async, --batch-size or any targets")
+		}
+		return cmd.CheckEmpty(args)
+	}
 	if len(args) == 0 {
 		return fmt.Errorf("no commands specified")
 	}
@@ -98,6 +130,13 @@ func (c *RunCommand) Init(args []string) error {
 		}
 	}
 
+	if err := c.rollingOptions.validate(); err != nil {
+		return err
+	}
+	if c.rolling() && (c.all || c.async || len(c.machines) != 0) {
+		return fmt.Errorf("--batch-size can only be used with --service and --unit targets")
+	}
+
 	var nameErrors []string
 	for _, machineId := range c.machines {
 		if !names.IsValidMachine(machineId) {
@@ -178,6 +217,16 @@ func (c *RunCommand) Run(ctx *cmd.Context) error {
 	}
 	defer client.Close()
 
+	if c.attach != "" {
+		return c.attachJob(ctx, client, c.attach)
+	}
+	if c.async {
+		return c.startJob(ctx, client)
+	}
+	if c.rolling() {
+		return c.runRolling(ctx, client)
+	}
+
 	var runResults []params.RunResult
 	if c.all {
 		runResults, err = client.RunOnAllMachines(c.commands, c.timeout)
@@ -195,7 +244,202 @@ func (c *RunCommand) Run(ctx *cmd.Context) error {
 	if err != nil {
 		return err
 	}
+	return c.writeResults(ctx, runResults)
+}
+
+// runRolling runs the commands on the target units a batch at a time,
+// stopping at the first failure.
+func (c *RunCommand) runRolling(ctx *cmd.Context, client RunClient) error {
+	unitNames, err := serviceUnitNames(client, c.services, c.units)
+	if err != nil {
+		return err
+	}
+	executor := &rollingExecutor{
+		rollingOptions: c.rollingOptions,
+		client:         client,
+	}
+	var runResults []params.RunResult
+	_, runErr := executor.run(ctx, unitNames, func(batch []string) error {
+		results, err := client.Run(params.RunParams{
+			Commands: c.commands,
+			Timeout:  c.timeout,
+			Units:    batch,
+		})
+		if err != nil {
+			return err
+		}
+		runResults = append(runResults, results...)
+		for _, result := range results {
+			if result.Error != "" {
+				return fmt.Errorf("command failed on unit %q: %s", result.UnitId, result.Error)
+			}
+			if result.Code != 0 {
+				return fmt.Errorf("command failed on unit %q: exit code %d", result.UnitId, result.Code)
+			}
+		}
+		return nil
+	})
+	var writeErr error
+	if len(runResults) > 0 {
+		writeErr = c.out.Write(ctx, ConvertRunResults(runResults))
+	}
+	if runErr != nil {
+		if writeErr != nil {
+			logger.Errorf("cannot write results: %v", writeErr)
+		}
+		return fmt.Errorf("rolling run aborted: %v", runErr)
+	}
+	return writeErr
+}
+
+func (c *RunCommand) startJob(ctx *cmd.Context, client RunClient) error {
+	var jobId string
+	var err error
+	if c.all {
+		jobId, err = client.StartRunOnAllMachines(c.commands, c.timeout)
+	} else {
+		jobId, err = client.StartRun(params.RunParams{
+			Commands: c.commands,
+			Timeout:  c.timeout,
+			Machines: c.machines,
+			Services: c.services,
+			Units:    c.units,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return c.out.Write(ctx, jobId)
+}
+
+// runJobPollInterval is how often the output of an attached job is
+// fetched.
+var runJobPollInterval = time.Second
+
+func (c *RunCommand) attachJob(ctx *cmd.Context, client RunClient, jobId string) error {
+	timeout := time.After(c.timeout)
+	// Only the smart format streams the output; other formats show
+	// the results once the job is done.
+	stream := c.out.Name() == "smart"
+	var lines runOutputLines
+	since := 0
+	for {
+		result, err := client.RunJobOutput(jobId, since)
+		if err != nil {
+			return err
+		}
+		for _, out := range result.Output {
+			if stream {
+				lines.write(ctx, out)
+			}
+			since = out.Seq
+		}
+		if result.Done {
+			if !stream {
+				return c.out.Write(ctx, ConvertRunResults(result.Results))
+			}
+			lines.flush(ctx)
+			return attachedResultsError(ctx, result.Results)
+		}
+		select {
+		case <-timeout:
+			lines.flush(ctx)
+			return fmt.Errorf("timed out waiting for job %s to finish", jobId)
+		case <-time.After(runJobPollInterval):
+		}
+	}
+}
+
+// attachedResultsError reports any commands of an attached job that
+// failed. The output has already been shown.
+func attachedResultsError(ctx *cmd.Context, runResults []params.RunResult) error {
+	if len(runResults) == 1 {
+		result := runResults[0]
+		if result.Error != "" {
+			return fmt.Errorf("%s", result.Error)
+		}
+		if result.Code != 0 {
+			return cmd.NewRcPassthroughError(result.Code)
+		}
+		return nil
+	}
+	failed := false
+	for _, result := range runResults {
+		target := runTargetName(result.MachineId, result.UnitId)
+		if result.Error != "" {
+			fmt.Fprintf(ctx.Stderr, "%s: %s\n", target, result.Error)
+			failed = true
+		} else if result.Code != 0 {
+			fmt.Fprintf(ctx.Stderr, "%s: exit code %d\n", target, result.Code)
+			failed = true
+		}
+	}
+	if failed {
+		return cmd.ErrSilent
+	}
+	return nil
+}
+
+func runTargetName(machineId, unitId string) string {
+	if unitId != "" {
+		return unitId
+	}
+	return "machine-" + machineId
+}
+
+// runOutputLines writes the output of a job's targets line by line,
+// prefixing each line with the target it came from. Partial lines are
+// held back until they are completed or flushed.
+type runOutputLines struct {
+	order   []runOutputKey
+	partial map[runOutputKey][]byte
+}
+
+type runOutputKey struct {
+	target string
+	stderr bool
+}
+
+func (l *runOutputLines) write(ctx *cmd.Context, out params.RunOutput) {
+	if l.partial == nil {
+		l.partial = make(map[runOutputKey][]byte)
+	}
+	key := runOutputKey{runTargetName(out.MachineId, out.UnitId), out.Stderr}
+	data, seen := l.partial[key]
+	if !seen {
+		l.order = append(l.order, key)
+	}
+	data = append(data, out.Data...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		l.writeLine(ctx, key, data[:i+1])
+		data = data[i+1:]
+	}
+	l.partial[key] = data
+}
+
+func (l *runOutputLines) flush(ctx *cmd.Context) {
+	for _, key := range l.order {
+		if data := l.partial[key]; len(data) > 0 {
+			l.writeLine(ctx, key, append(data, '\n'))
+		}
+		delete(l.partial, key)
+	}
+	l.order = nil
+}
+
+func (l *runOutputLines) writeLine(ctx *cmd.Context, key runOutputKey, line []byte) {
+	w := ctx.Stdout
+	if key.stderr {
+		w = ctx.Stderr
+	}
+	fmt.Fprintf(w, "%s: %s", key.target, line)
+}
 
+func (c *RunCommand) writeResults(ctx *cmd.Context, runResults []params.RunResult) error {
 	// If we are just dealing with one result, AND we are using the smart
 	// format, then pretend we were running it locally.
 	if len(runResults) == 1 && c.out.Name() == "smart" {
@@ -223,6 +467,10 @@ type RunClient interface {
 	Close() error
 	RunOnAllMachines(commands string, timeout time.Duration) ([]params.RunResult, error)
 	Run(run params.RunParams) ([]params.RunResult, error)
+	StartRunOnAllMachines(commands string, timeout time.Duration) (string, error)
+	StartRun(run params.RunParams) (string, error)
+	RunJobOutput(jobId string, since int) (params.RunJobOutputResults, error)
+	Status(patterns []string) (*api.Status, error)
 }
 
 // Here we need the signature to be correct for the interface.
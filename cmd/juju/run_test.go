@@ -9,11 +9,13 @@ import (
 	"time"
 
 	"github.com/juju/cmd"
+	"github.com/juju/names"
 	jc "github.com/juju/testing/checkers"
 	"github.com/juju/utils/exec"
 	gc "launchpad.net/gocheck"
 
 	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/state/api"
 	"github.com/juju/juju/state/api/params"
 	"github.com/juju/juju/testing"
 )
@@ -151,6 +153,44 @@ func (*RunSuite) TestTimeoutArgParsing(c *gc.C) {
 	}
 }
 
+func (*RunSuite) TestAsyncArgParsing(c *gc.C) {
+	for i, test := range []struct {
+		message  string
+		args     []string
+		async    bool
+		attach   string
+		errMatch string
+	}{{
+		message: "async",
+		args:    []string{"--async", "--all", "sudo reboot"},
+		async:   true,
+	}, {
+		message: "attach",
+		args:    []string{"--attach", "42"},
+		attach:  "42",
+	}, {
+		message:  "attach with commands",
+		args:     []string{"--attach", "42", "sudo reboot"},
+		errMatch: `unrecognized args: \["sudo reboot"\]`,
+	}, {
+		message:  "attach with targets",
+		args:     []string{"--attach", "42", "--machine=0"},
+		errMatch: "You cannot specify --attach with --async, --batch-size or any targets",
+	}, {
+		message:  "attach with async",
+		args:     []string{"--attach", "42", "--async"},
+		errMatch: "You cannot specify --attach with --async, --batch-size or any targets",
+	}} {
+		c.Log(fmt.Sprintf("%v: %s", i, test.message))
+		runCmd := &RunCommand{}
+		testing.TestInit(c, envcmd.Wrap(runCmd), test.args, test.errMatch)
+		if test.errMatch == "" {
+			c.Check(runCmd.async, gc.Equals, test.async)
+			c.Check(runCmd.attach, gc.Equals, test.attach)
+		}
+	}
+}
+
 func (s *RunSuite) TestConvertRunResults(c *gc.C) {
 	for i, test := range []struct {
 		message  string
@@ -348,6 +388,181 @@ func (s *RunSuite) TestSingleResponse(c *gc.C) {
 	}
 }
 
+func (s *RunSuite) TestAsync(c *gc.C) {
+	mock := s.setupMockAPI()
+	mock.setMachinesAlive("0")
+
+	context, err := testing.RunCommand(c, envcmd.Wrap(&RunCommand{}), "--async", "--all", "hostname")
+	c.Assert(err, gc.IsNil)
+	c.Check(testing.Stdout(context), gc.Equals, "1\n")
+	c.Check(mock.started, jc.DeepEquals, []params.RunParams{{
+		Commands: "hostname",
+		Timeout:  5 * time.Minute,
+	}})
+
+	context, err = testing.RunCommand(c, envcmd.Wrap(&RunCommand{}), "--async", "--unit=unit/0", "hostname")
+	c.Assert(err, gc.IsNil)
+	c.Check(testing.Stdout(context), gc.Equals, "2\n")
+	c.Check(mock.started[1].Units, jc.DeepEquals, []string{"unit/0"})
+}
+
+func (*RunSuite) TestBatchArgParsing(c *gc.C) {
+	for i, test := range []struct {
+		message  string
+		args     []string
+		errMatch string
+	}{{
+		message: "batches of units",
+		args:    []string{"--batch-size=2", "--batch-pause=1m", "--service=mysql", "hostname"},
+	}, {
+		message:  "batches of machines",
+		args:     []string{"--batch-size=2", "--machine=0", "hostname"},
+		errMatch: "--batch-size can only be used with --service and --unit targets",
+	}, {
+		message:  "batches of all machines",
+		args:     []string{"--batch-size=2", "--all", "hostname"},
+		errMatch: "--batch-size can only be used with --service and --unit targets",
+	}, {
+		message:  "async batches",
+		args:     []string{"--batch-size=2", "--async", "--unit=mysql/0", "hostname"},
+		errMatch: "--batch-size can only be used with --service and --unit targets",
+	}, {
+		message:  "pause without batches",
+		args:     []string{"--batch-pause=1m", "--unit=mysql/0", "hostname"},
+		errMatch: "--batch-pause requires --batch-size",
+	}} {
+		c.Log(fmt.Sprintf("%v: %s", i, test.message))
+		runCmd := &RunCommand{}
+		testing.TestInit(c, envcmd.Wrap(runCmd), test.args, test.errMatch)
+	}
+}
+
+func (s *RunSuite) TestRunBatches(c *gc.C) {
+	s.PatchValue(&rollingPollInterval, time.Duration(0))
+	mock := s.setupMockAPI()
+	responses := []mockResponse{
+		{stdout: "zero\n", machineId: "0", unitId: "unit/0"},
+		{stdout: "one\n", machineId: "1", unitId: "unit/1"},
+		{stdout: "two\n", machineId: "2", unitId: "unit/2"},
+	}
+	var results []params.RunResult
+	for _, response := range responses {
+		mock.setResponse(response.unitId, response)
+		results = append(results, makeRunResult(response))
+	}
+	jsonFormatted, err := cmd.FormatJson(ConvertRunResults(results))
+	c.Assert(err, gc.IsNil)
+
+	context, err := testing.RunCommand(c, envcmd.Wrap(&RunCommand{}),
+		"--format=json", "--batch-size=2", "--unit=unit/2,unit/1,unit/0", "hostname",
+	)
+	c.Assert(err, gc.IsNil)
+	c.Check(testing.Stdout(context), gc.Equals, string(jsonFormatted)+"\n")
+	c.Check(testing.Stderr(context), gc.Equals, ""+
+		"batch 1 of 2: unit/0, unit/1\n"+
+		"batch 2 of 2: unit/2\n")
+	c.Check(mock.ran, jc.DeepEquals, [][]string{{"unit/0", "unit/1"}, {"unit/2"}})
+}
+
+func (s *RunSuite) TestRunBatchesAbortOnFailure(c *gc.C) {
+	s.PatchValue(&rollingPollInterval, time.Duration(0))
+	mock := s.setupMockAPI()
+	mock.setResponse("unit/0", mockResponse{machineId: "0", unitId: "unit/0", code: 1})
+	mock.setResponse("unit/1", mockResponse{machineId: "1", unitId: "unit/1"})
+
+	_, err := testing.RunCommand(c, envcmd.Wrap(&RunCommand{}),
+		"--batch-size=1", "--unit=unit/0,unit/1", "hostname",
+	)
+	c.Assert(err, gc.ErrorMatches, `rolling run aborted: command failed on unit "unit/0": exit code 1`)
+	c.Check(mock.ran, jc.DeepEquals, [][]string{{"unit/0"}})
+}
+
+func (s *RunSuite) setupAttachJob(c *gc.C) *mockRunAPI {
+	mock := s.setupMockAPI()
+	s.PatchValue(&runJobPollInterval, time.Duration(0))
+	machineResponse := mockResponse{
+		stdout:    "megatron\nstarscream\n",
+		machineId: "0",
+	}
+	unitResponse := mockResponse{
+		stderr:    "bumblebee",
+		code:      1,
+		machineId: "1",
+		unitId:    "unit/0",
+	}
+	mock.jobOutput = []params.RunJobOutputResults{{
+		Output: []params.RunOutput{
+			{Seq: 1, MachineId: "0", Data: []byte("megatron\nstar")},
+		},
+	}, {
+		Output: []params.RunOutput{
+			{Seq: 2, MachineId: "1", UnitId: "unit/0", Stderr: true, Data: []byte("bumblebee")},
+		},
+	}, {
+		Output: []params.RunOutput{
+			{Seq: 3, MachineId: "0", Data: []byte("scream\n")},
+		},
+		Done: true,
+		Results: []params.RunResult{
+			makeRunResult(machineResponse),
+			makeRunResult(unitResponse),
+		},
+	}}
+	return mock
+}
+
+func (s *RunSuite) TestAttach(c *gc.C) {
+	mock := s.setupAttachJob(c)
+
+	context, err := testing.RunCommand(c, envcmd.Wrap(&RunCommand{}), "--attach", "42")
+	c.Assert(err, gc.Equals, cmd.ErrSilent)
+	c.Check(testing.Stdout(context), gc.Equals, "machine-0: megatron\nmachine-0: starscream\n")
+	c.Check(testing.Stderr(context), gc.Equals, "unit/0: bumblebee\nunit/0: exit code 1\n")
+	c.Check(mock.polled, jc.DeepEquals, []int{0, 1, 2})
+}
+
+func (s *RunSuite) TestAttachFormatted(c *gc.C) {
+	mock := s.setupAttachJob(c)
+	jsonFormatted, err := cmd.FormatJson(ConvertRunResults(mock.jobOutput[2].Results))
+	c.Assert(err, gc.IsNil)
+
+	context, err := testing.RunCommand(c, envcmd.Wrap(&RunCommand{}), "--format=json", "--attach", "42")
+	c.Assert(err, gc.IsNil)
+	c.Check(testing.Stdout(context), gc.Equals, string(jsonFormatted)+"\n")
+}
+
+func (s *RunSuite) TestAttachSingleResult(c *gc.C) {
+	mock := s.setupMockAPI()
+	mock.jobOutput = []params.RunJobOutputResults{{
+		Output: []params.RunOutput{
+			{Seq: 1, MachineId: "0", Data: []byte("stdout\n")},
+		},
+		Done: true,
+		Results: []params.RunResult{
+			makeRunResult(mockResponse{stdout: "stdout\n", code: 42, machineId: "0"}),
+		},
+	}}
+
+	context, err := testing.RunCommand(c, envcmd.Wrap(&RunCommand{}), "--attach", "42")
+	c.Check(err, gc.ErrorMatches, "subprocess encountered error code 42")
+	c.Check(testing.Stdout(context), gc.Equals, "machine-0: stdout\n")
+}
+
+func (s *RunSuite) TestAttachTimeout(c *gc.C) {
+	mock := s.setupMockAPI()
+	s.PatchValue(&runJobPollInterval, testing.LongWait)
+	mock.jobOutput = []params.RunJobOutputResults{{
+		Output: []params.RunOutput{
+			{Seq: 1, MachineId: "0", Data: []byte("still going")},
+		},
+	}}
+
+	context, err := testing.RunCommand(c, envcmd.Wrap(&RunCommand{}), "--timeout=1ms", "--attach", "42")
+	c.Check(err, gc.ErrorMatches, "timed out waiting for job 42 to finish")
+	c.Check(testing.Stdout(context), gc.Equals, "machine-0: still going\n")
+	c.Check(mock.polled, jc.DeepEquals, []int{0})
+}
+
 func (s *RunSuite) setupMockAPI() *mockRunAPI {
 	mock := &mockRunAPI{}
 	s.PatchValue(&getRunAPIClient, func(_ *RunCommand) (RunClient, error) {
@@ -363,6 +578,12 @@ type mockRunAPI struct {
 	// machines, services, units
 	machines  map[string]bool
 	responses map[string]params.RunResult
+	// async jobs
+	started   []params.RunParams
+	jobOutput []params.RunJobOutputResults
+	polled    []int
+	// units run on by each call to Run
+	ran [][]string
 }
 
 type mockResponse struct {
@@ -431,6 +652,7 @@ func (m *mockRunAPI) RunOnAllMachines(commands string, timeout time.Duration) ([
 }
 
 func (m *mockRunAPI) Run(runParams params.RunParams) ([]params.RunResult, error) {
+	m.ran = append(m.ran, runParams.Units)
 	var result []params.RunResult
 	// Just add in ids that match in order.
 	for _, id := range runParams.Machines {
@@ -449,3 +671,40 @@ func (m *mockRunAPI) Run(runParams params.RunParams) ([]params.RunResult, error)
 
 	return result, nil
 }
+
+func (m *mockRunAPI) StartRunOnAllMachines(commands string, timeout time.Duration) (string, error) {
+	return m.StartRun(params.RunParams{Commands: commands, Timeout: timeout})
+}
+
+func (m *mockRunAPI) StartRun(runParams params.RunParams) (string, error) {
+	m.started = append(m.started, runParams)
+	return fmt.Sprint(len(m.started)), nil
+}
+
+func (m *mockRunAPI) RunJobOutput(jobId string, since int) (params.RunJobOutputResults, error) {
+	if len(m.jobOutput) == 0 {
+		return params.RunJobOutputResults{}, fmt.Errorf("run job %q not found", jobId)
+	}
+	m.polled = append(m.polled, since)
+	result := m.jobOutput[0]
+	m.jobOutput = m.jobOutput[1:]
+	return result, nil
+}
+
+func (m *mockRunAPI) Status(patterns []string) (*api.Status, error) {
+	// All units with responses are reported as started.
+	services := make(map[string]api.ServiceStatus)
+	for id, response := range m.responses {
+		if response.UnitId == "" {
+			continue
+		}
+		serviceName := names.UnitService(id)
+		service := services[serviceName]
+		if service.Units == nil {
+			service.Units = make(map[string]api.UnitStatus)
+		}
+		service.Units[id] = api.UnitStatus{AgentState: params.StatusStarted}
+		services[serviceName] = service
+	}
+	return &api.Status{Services: services}, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/state/api/params"
+)
+
+// SetHookRetryCommand sets the policy used to automatically retry the
+// failed hooks of a service's units.
+type SetHookRetryCommand struct {
+	envcmd.EnvCommandBase
+	ServiceName string
+	Attempts    int
+	Delay       int
+	Reset       bool
+}
+
+const setHookRetryDoc = `
+When a hook fails, the unit automatically retries it up to the given
+number of attempts, waiting between attempts; the delay doubles with
+every attempt. Automatic retries stop as soon as the failure is resolved
+by hand with "juju resolved".
+
+By default the policy set by the hook-retry-attempts and hook-retry-delay
+environment settings is used for all services. This command overrides that
+policy for a single service; --reset removes the override again. Setting
+--attempts to 0 disables automatic retries for the service.
+
+Examples:
+   juju set-hook-retry wordpress --attempts 5
+   juju set-hook-retry wordpress --attempts 3 --delay 60
+   juju set-hook-retry wordpress --reset
+
+`
+
+func (c *SetHookRetryCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "set-hook-retry",
+		Args:    "<service>",
+		Purpose: "set the automatic hook retry policy for a service",
+		Doc:     setHookRetryDoc,
+	}
+}
+
+func (c *SetHookRetryCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.IntVar(&c.Attempts, "attempts", -1, "maximum number of automatic retries of a failed hook")
+	f.IntVar(&c.Delay, "delay", config.DefaultHookRetryDelay, "seconds to wait before the first retry")
+	f.BoolVar(&c.Reset, "reset", false, "use the environment's hook retry policy")
+}
+
+func (c *SetHookRetryCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no service name specified")
+	}
+	if !names.IsValidService(args[0]) {
+		return fmt.Errorf("invalid service name %q", args[0])
+	}
+	c.ServiceName = args[0]
+	if c.Reset {
+		if c.Attempts != -1 {
+			return errors.New("cannot specify --attempts with --reset")
+		}
+	} else {
+		if c.Attempts < 0 {
+			return errors.New("no attempts specified")
+		}
+		if c.Delay <= 0 {
+			return fmt.Errorf("invalid delay %d: expected positive number of seconds", c.Delay)
+		}
+	}
+	return cmd.CheckEmpty(args[1:])
+}
+
+func (c *SetHookRetryCommand) Run(_ *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var policy *params.HookRetryPolicy
+	if !c.Reset {
+		policy = &params.HookRetryPolicy{
+			Attempts: c.Attempts,
+			Delay:    time.Duration(c.Delay) * time.Second,
+		}
+	}
+	return client.ServiceSetHookRetryPolicy(c.ServiceName, policy)
+}
@@ -0,0 +1,93 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"time"
+
+	charmtesting "github.com/juju/charm/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/environs/config"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/testing"
+)
+
+type SetHookRetrySuite struct {
+	jujutesting.RepoSuite
+}
+
+var _ = gc.Suite(&SetHookRetrySuite{})
+
+func runSetHookRetry(c *gc.C, args ...string) error {
+	_, err := testing.RunCommand(c, envcmd.Wrap(&SetHookRetryCommand{}), args...)
+	return err
+}
+
+func (s *SetHookRetrySuite) TestInit(c *gc.C) {
+	for i, t := range []struct {
+		args []string
+		err  string
+	}{{
+		err: "no service name specified",
+	}, {
+		args: []string{"Wordpress", "--attempts", "3"},
+		err:  `invalid service name "Wordpress"`,
+	}, {
+		args: []string{"wordpress"},
+		err:  "no attempts specified",
+	}, {
+		args: []string{"wordpress", "--attempts", "3", "--delay", "0"},
+		err:  "invalid delay 0: expected positive number of seconds",
+	}, {
+		args: []string{"wordpress", "--attempts", "3", "--reset"},
+		err:  "cannot specify --attempts with --reset",
+	}, {
+		args: []string{"wordpress", "--attempts", "3", "blah"},
+		err:  `unrecognized args: \["blah"\]`,
+	}} {
+		c.Logf("test %d: %v", i, t.args)
+		err := testing.InitCommand(envcmd.Wrap(&SetHookRetryCommand{}), t.args)
+		c.Check(err, gc.ErrorMatches, t.err)
+	}
+}
+
+func (s *SetHookRetrySuite) TestSetHookRetry(c *gc.C) {
+	charmtesting.Charms.BundlePath(s.SeriesPath, "dummy")
+	err := runDeploy(c, "local:dummy", "dummy")
+	c.Assert(err, gc.IsNil)
+	svc, err := s.State.Service("dummy")
+	c.Assert(err, gc.IsNil)
+
+	err = runSetHookRetry(c, "dummy", "--attempts", "5")
+	c.Assert(err, gc.IsNil)
+	err = svc.Refresh()
+	c.Assert(err, gc.IsNil)
+	policy, ok := svc.HookRetryPolicy()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(policy, gc.Equals, config.HookRetryPolicy{
+		Attempts: 5,
+		Delay:    time.Duration(config.DefaultHookRetryDelay) * time.Second,
+	})
+
+	err = runSetHookRetry(c, "dummy", "--attempts", "2", "--delay", "60")
+	c.Assert(err, gc.IsNil)
+	err = svc.Refresh()
+	c.Assert(err, gc.IsNil)
+	policy, ok = svc.HookRetryPolicy()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(policy, gc.Equals, config.HookRetryPolicy{Attempts: 2, Delay: time.Minute})
+
+	err = runSetHookRetry(c, "dummy", "--reset")
+	c.Assert(err, gc.IsNil)
+	err = svc.Refresh()
+	c.Assert(err, gc.IsNil)
+	_, ok = svc.HookRetryPolicy()
+	c.Assert(ok, jc.IsFalse)
+
+	err = runSetHookRetry(c, "nonexistent-service", "--attempts", "1")
+	c.Assert(err, gc.ErrorMatches, `service "nonexistent-service" not found`)
+}
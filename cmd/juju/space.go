@@ -0,0 +1,39 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+type SpaceCommand struct {
+	*cmd.SuperCommand
+}
+
+const spaceCommandDoc = `
+"juju space" is used to manage the network spaces in the Juju
+environment. A space is a named group of subnets; the endpoints of a
+service can be bound to a space when it is deployed, with
+"juju deploy --bind".
+`
+
+const spaceCommandPurpose = "manage network spaces"
+
+func NewSpaceCommand() cmd.Command {
+	spacecmd := &SpaceCommand{
+		SuperCommand: cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:        "space",
+			Doc:         spaceCommandDoc,
+			UsagePrefix: "juju",
+			Purpose:     spaceCommandPurpose,
+		}),
+	}
+	// Define each subcommand in a separate "space_FOO.go" source file
+	// and wire in here.
+	spacecmd.Register(envcmd.Wrap(&SpaceCreateCommand{}))
+	spacecmd.Register(envcmd.Wrap(&SpaceListCommand{}))
+	return spacecmd
+}
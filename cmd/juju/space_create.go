@@ -0,0 +1,57 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+const spaceCreateCommandDoc = `
+Create a new space holding the given subnets, specified in CIDR format.
+The subnets must be known to Juju (see "juju subnet list"). Each subnet
+may belong to only one space, and may not overlap a subnet in another
+space.
+
+Examples:
+  juju space create internal 10.0.0.0/24 10.0.1.0/24
+`
+
+type SpaceCreateCommand struct {
+	envcmd.EnvCommandBase
+	Name    string
+	Subnets []string
+}
+
+func (c *SpaceCreateCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "create",
+		Args:    "<name> <CIDR> ...",
+		Purpose: "create a new space",
+		Doc:     spaceCreateCommandDoc,
+	}
+}
+
+func (c *SpaceCreateCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no space name specified")
+	}
+	if len(args) == 1 {
+		return fmt.Errorf("no subnets specified")
+	}
+	c.Name, c.Subnets = args[0], args[1:]
+	return nil
+}
+
+func (c *SpaceCreateCommand) Run(_ *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.AddSpace(c.Name, c.Subnets)
+}
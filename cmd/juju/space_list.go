@@ -0,0 +1,53 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+const spaceListCommandDoc = `
+List the spaces in the environment, with the subnets each holds.
+`
+
+type SpaceListCommand struct {
+	envcmd.EnvCommandBase
+	out cmd.Output
+}
+
+func (c *SpaceListCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "list",
+		Purpose: "list spaces",
+		Doc:     spaceListCommandDoc,
+	}
+}
+
+func (c *SpaceListCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", cmd.DefaultFormatters)
+}
+
+func (c *SpaceListCommand) Init(args []string) error {
+	return cmd.CheckEmpty(args)
+}
+
+func (c *SpaceListCommand) Run(ctx *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	spaces, err := client.ListSpaces()
+	if err != nil {
+		return err
+	}
+	result := make(map[string][]string)
+	for _, space := range spaces {
+		result[space.Name] = space.Subnets
+	}
+	return c.out.Write(ctx, result)
+}
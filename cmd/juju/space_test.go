@@ -0,0 +1,72 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/cmd/envcmd"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+)
+
+type SpaceCommandSuite struct {
+	jujutesting.JujuConnSuite
+}
+
+var _ = gc.Suite(&SpaceCommandSuite{})
+
+func (s *SpaceCommandSuite) addSubnets(c *gc.C, cidrs ...string) {
+	for i, cidr := range cidrs {
+		_, err := s.State.AddSubnet(state.SubnetInfo{
+			CIDR:       cidr,
+			ProviderId: network.Id(fmt.Sprintf("subnet-%d", i)),
+		})
+		c.Assert(err, gc.IsNil)
+	}
+}
+
+func (s *SpaceCommandSuite) TestCreate(c *gc.C) {
+	s.addSubnets(c, "10.0.1.0/24", "10.0.2.0/24")
+	_, err := testing.RunCommand(c, envcmd.Wrap(&SpaceCreateCommand{}), "internal", "10.0.1.0/24", "10.0.2.0/24")
+	c.Assert(err, gc.IsNil)
+	space, err := s.State.Space("internal")
+	c.Assert(err, gc.IsNil)
+	c.Assert(space.Subnets(), jc.DeepEquals, []string{"10.0.1.0/24", "10.0.2.0/24"})
+
+	_, err = testing.RunCommand(c, envcmd.Wrap(&SpaceCreateCommand{}), "public", "10.0.1.0/24")
+	c.Assert(err, gc.ErrorMatches, `cannot add space "public": subnet 10.0.1.0/24 is already in space "internal"`)
+
+	_, err = testing.RunCommand(c, envcmd.Wrap(&SpaceCreateCommand{}), "public", "192.168.1.0/24")
+	c.Assert(err, gc.ErrorMatches, `cannot add space "public": subnet "192.168.1.0/24" not found`)
+}
+
+func (s *SpaceCommandSuite) TestCreateInit(c *gc.C) {
+	err := testing.InitCommand(envcmd.Wrap(&SpaceCreateCommand{}), nil)
+	c.Assert(err, gc.ErrorMatches, "no space name specified")
+	err = testing.InitCommand(envcmd.Wrap(&SpaceCreateCommand{}), []string{"internal"})
+	c.Assert(err, gc.ErrorMatches, "no subnets specified")
+}
+
+func (s *SpaceCommandSuite) TestList(c *gc.C) {
+	s.addSubnets(c, "10.0.1.0/24", "10.0.2.0/24", "192.168.1.0/24")
+	_, err := s.State.AddSpace("internal", []string{"10.0.1.0/24", "10.0.2.0/24"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("public", []string{"192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&SpaceListCommand{}))
+	c.Assert(err, gc.IsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, ""+
+		"internal:\n"+
+		"- 10.0.1.0/24\n"+
+		"- 10.0.2.0/24\n"+
+		"public:\n"+
+		"- 192.168.1.0/24\n")
+}
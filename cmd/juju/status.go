@@ -155,6 +155,8 @@ type serviceStatus struct {
 	Charm         string                `json:"charm" yaml:"charm"`
 	CanUpgradeTo  string                `json:"can-upgrade-to,omitempty" yaml:"can-upgrade-to,omitempty"`
 	Exposed       bool                  `json:"exposed" yaml:"exposed"`
+	ExposedFrom   []string              `json:"exposed-from,omitempty" yaml:"exposed-from,omitempty"`
+	IngressFrom   []string              `json:"ingress-from,omitempty" yaml:"ingress-from,omitempty"`
 	Life          string                `json:"life,omitempty" yaml:"life,omitempty"`
 	Relations     map[string][]string   `json:"relations,omitempty" yaml:"relations,omitempty"`
 	Networks      map[string][]string   `json:"networks,omitempty" yaml:"networks,omitempty"`
@@ -332,6 +334,8 @@ func (sf *statusFormatter) formatService(name string, service api.ServiceStatus)
 		Err:           service.Err,
 		Charm:         service.Charm,
 		Exposed:       service.Exposed,
+		ExposedFrom:   service.ExposedCIDRs,
+		IngressFrom:   service.IngressCIDRs,
 		Life:          service.Life,
 		Relations:     service.Relations,
 		Networks:      make(map[string][]string),
@@ -384,6 +388,9 @@ func (sf *statusFormatter) getUnitStatusInfo(unit api.UnitStatus, serviceName st
 				statusInfo = statusInfo + " for " + ep.String()
 			}
 		}
+		if retryInfo := getHookRetryInfoFromData(unit); retryInfo != "" {
+			statusInfo = statusInfo + " " + retryInfo
+		}
 	}
 	return adjustInfoIfAgentDown(unit.AgentState, unit.Agent.Status, statusInfo)
 }
@@ -424,6 +431,21 @@ func getRelationIdFromData(unit api.UnitStatus) int {
 	return -1
 }
 
+// getHookRetryInfoFromData describes the automatic retries of a
+// failed hook recorded in the unit's status data, if any.
+func getHookRetryInfoFromData(unit api.UnitStatus) string {
+	max, ok := unit.Agent.Data["retry-max"].(float64)
+	if !ok {
+		return ""
+	}
+	nextRetry, ok := unit.Agent.Data["next-retry"].(string)
+	if !ok {
+		return "(automatic retries exhausted)"
+	}
+	attempt, _ := unit.Agent.Data["retry-attempt"].(float64)
+	return fmt.Sprintf("(retry %d/%d at %s)", int(attempt), int(max), nextRetry)
+}
+
 // findOtherEndpoint searches the provided endpoints for an endpoint
 // that *doesn't* match serviceName. The returned bool indicates if
 // such an endpoint was found.
@@ -1862,7 +1862,7 @@ type addService struct {
 func (as addService) step(c *gc.C, ctx *context) {
 	ch, ok := ctx.charms[as.charm]
 	c.Assert(ok, gc.Equals, true)
-	svc, err := ctx.st.AddService(as.name, "user-admin", ch, as.networks)
+	svc, err := ctx.st.AddService(as.name, "user-admin", ch, as.networks, nil)
 	c.Assert(err, gc.IsNil)
 	if svc.IsPrincipal() {
 		err = svc.SetConstraints(as.cons)
@@ -2311,3 +2311,38 @@ func (s *StatusSuite) TestStatusWithPreRelationsServer(c *gc.C) {
 	defer s.resetContext(c, ctx)
 	ctx.run(c, []stepper{expected})
 }
+
+func (s *StatusSuite) TestStatusHookRetryInfo(c *gc.C) {
+	sf := newStatusFormatter(&api.Status{})
+	for i, t := range []struct {
+		data     params.StatusData
+		expected string
+	}{{
+		data:     params.StatusData{"hook": "install"},
+		expected: `hook failed: "install"`,
+	}, {
+		data: params.StatusData{
+			"retry-attempt": float64(2),
+			"retry-max":     float64(5),
+			"next-retry":    "2014-07-01T12:00:00Z",
+		},
+		expected: `hook failed: "install" (retry 2/5 at 2014-07-01T12:00:00Z)`,
+	}, {
+		data: params.StatusData{
+			"retry-attempt": float64(5),
+			"retry-max":     float64(5),
+		},
+		expected: `hook failed: "install" (automatic retries exhausted)`,
+	}} {
+		c.Logf("test %d", i)
+		unit := api.UnitStatus{
+			AgentState: params.StatusError,
+			Agent: api.AgentStatus{
+				Status: params.StatusError,
+				Info:   `hook failed: "install"`,
+				Data:   t.data,
+			},
+		}
+		c.Check(sf.getUnitStatusInfo(unit, "wordpress"), gc.Equals, t.expected)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/state/api/params"
+)
+
+const statusHistoryDoc = `
+Shows the most recent statuses of a machine, oldest first, including
+the failed attempts the provisioner made to start its instance.
+
+Example:
+
+    juju status-history 3
+`
+
+// StatusHistoryCommand shows the statuses recorded for a machine.
+type StatusHistoryCommand struct {
+	envcmd.EnvCommandBase
+	MachineId string
+	out       cmd.Output
+}
+
+func (c *StatusHistoryCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "status-history",
+		Args:    "<machine>",
+		Purpose: "show the statuses a machine recently had",
+		Doc:     statusHistoryDoc,
+	}
+}
+
+func (c *StatusHistoryCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", map[string]cmd.Formatter{
+		"yaml": cmd.FormatYaml,
+		"json": cmd.FormatJson,
+	})
+}
+
+func (c *StatusHistoryCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no machine specified")
+	}
+	c.MachineId = args[0]
+	if !names.IsValidMachine(c.MachineId) {
+		return fmt.Errorf("invalid machine id %q", c.MachineId)
+	}
+	return cmd.CheckEmpty(args[1:])
+}
+
+type statusHistoryEntry struct {
+	Status  params.Status     `json:"status" yaml:"status"`
+	Info    string            `json:"info,omitempty" yaml:"info,omitempty"`
+	Data    params.StatusData `json:"data,omitempty" yaml:"data,omitempty"`
+	Updated string            `json:"updated" yaml:"updated"`
+}
+
+func (c *StatusHistoryCommand) Run(ctx *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	entries, err := client.StatusHistory(c.MachineId)
+	if err != nil {
+		return err
+	}
+	result := make([]statusHistoryEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = statusHistoryEntry{
+			Status:  entry.Status,
+			Info:    entry.Info,
+			Data:    entry.Data,
+			Updated: entry.Updated.UTC().Format(time.RFC3339),
+		}
+	}
+	return c.out.Write(ctx, result)
+}
@@ -0,0 +1,56 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/cmd/envcmd"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/testing"
+)
+
+type StatusHistorySuite struct {
+	jujutesting.JujuConnSuite
+}
+
+var _ = gc.Suite(&StatusHistorySuite{})
+
+func runStatusHistory(c *gc.C, args ...string) (string, error) {
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&StatusHistoryCommand{}), args...)
+	if err != nil {
+		return "", err
+	}
+	return testing.Stdout(ctx), nil
+}
+
+func (s *StatusHistorySuite) TestInit(c *gc.C) {
+	_, err := runStatusHistory(c)
+	c.Assert(err, gc.ErrorMatches, "no machine specified")
+	_, err = runStatusHistory(c, "jeremy-fisher")
+	c.Assert(err, gc.ErrorMatches, `invalid machine id "jeremy-fisher"`)
+	_, err = runStatusHistory(c, "0", "roflcopter")
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["roflcopter"\]`)
+}
+
+func (s *StatusHistorySuite) TestStatusHistory(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = machine.SetStatus(params.StatusPending, "attempt 1 of 3 failed", nil)
+	c.Assert(err, gc.IsNil)
+	err = machine.SetStatus(params.StatusError, "no capacity", nil)
+	c.Assert(err, gc.IsNil)
+
+	out, err := runStatusHistory(c, machine.Id(), "--format", "json")
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Matches, `\[`+
+		`\{"status":"pending","info":"attempt 1 of 3 failed","updated":"[^"]+"\},`+
+		`\{"status":"error","info":"no capacity","updated":"[^"]+"\}`+
+		"\\]\n")
+
+	_, err = runStatusHistory(c, "42")
+	c.Assert(err, gc.ErrorMatches, `machine 42 not found`)
+}
@@ -0,0 +1,38 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+type SubnetCommand struct {
+	*cmd.SuperCommand
+}
+
+const subnetCommandDoc = `
+"juju subnet" is used to inspect the subnets discovered in the cloud
+the Juju environment runs in. On providers that support it, the
+subnets are discovered as machines are added and kept up to date with
+the cloud periodically.
+`
+
+const subnetCommandPurpose = "inspect discovered subnets"
+
+func NewSubnetCommand() cmd.Command {
+	subnetcmd := &SubnetCommand{
+		SuperCommand: cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:        "subnet",
+			Doc:         subnetCommandDoc,
+			UsagePrefix: "juju",
+			Purpose:     subnetCommandPurpose,
+		}),
+	}
+	// Define each subcommand in a separate "subnet_FOO.go" source file
+	// and wire in here.
+	subnetcmd.Register(envcmd.Wrap(&SubnetListCommand{}))
+	return subnetcmd
+}
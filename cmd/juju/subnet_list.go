@@ -0,0 +1,66 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+const subnetListCommandDoc = `
+List the subnets discovered in the cloud, keyed by CIDR, with their
+provider ids, VLAN tags, availability zones and the spaces they are in.
+`
+
+type SubnetListCommand struct {
+	envcmd.EnvCommandBase
+	out cmd.Output
+}
+
+type formattedSubnet struct {
+	ProviderId string   `json:"provider-id" yaml:"provider-id"`
+	VLANTag    int      `json:"vlan-tag,omitempty" yaml:"vlan-tag,omitempty"`
+	Zones      []string `json:"zones,omitempty" yaml:"zones,omitempty"`
+	Space      string   `json:"space,omitempty" yaml:"space,omitempty"`
+}
+
+func (c *SubnetListCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "list",
+		Purpose: "list discovered subnets",
+		Doc:     subnetListCommandDoc,
+	}
+}
+
+func (c *SubnetListCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", cmd.DefaultFormatters)
+}
+
+func (c *SubnetListCommand) Init(args []string) error {
+	return cmd.CheckEmpty(args)
+}
+
+func (c *SubnetListCommand) Run(ctx *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	subnets, err := client.ListSubnets()
+	if err != nil {
+		return err
+	}
+	result := make(map[string]formattedSubnet)
+	for _, subnet := range subnets {
+		result[subnet.CIDR] = formattedSubnet{
+			ProviderId: subnet.ProviderId,
+			VLANTag:    subnet.VLANTag,
+			Zones:      subnet.AvailabilityZones,
+			Space:      subnet.Space,
+		}
+	}
+	return c.out.Write(ctx, result)
+}
@@ -0,0 +1,51 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/cmd/envcmd"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+)
+
+type SubnetCommandSuite struct {
+	jujutesting.JujuConnSuite
+}
+
+var _ = gc.Suite(&SubnetCommandSuite{})
+
+func (s *SubnetCommandSuite) TestList(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{
+		CIDR:              "10.0.1.0/24",
+		ProviderId:        "subnet-1",
+		AvailabilityZones: []string{"zone1", "zone2"},
+	})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.2.0/24", ProviderId: "subnet-2", VLANTag: 42})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.2.0/24"})
+	c.Assert(err, gc.IsNil)
+
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&SubnetListCommand{}))
+	c.Assert(err, gc.IsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, ""+
+		"10.0.1.0/24:\n"+
+		"  provider-id: subnet-1\n"+
+		"  zones:\n"+
+		"  - zone1\n"+
+		"  - zone2\n"+
+		"10.0.2.0/24:\n"+
+		"  provider-id: subnet-2\n"+
+		"  vlan-tag: 42\n"+
+		"  space: internal\n")
+}
+
+func (s *SubnetCommandSuite) TestListEmpty(c *gc.C) {
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&SubnetListCommand{}))
+	c.Assert(err, gc.IsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, "{}\n")
+}
@@ -0,0 +1,280 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/juju/charm"
+	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
+	"launchpad.net/goyaml"
+
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/worker/uniter/harness"
+)
+
+// TestCharmCommand runs the hooks of a local charm against an
+// in-memory hook context, as described by a test script.
+type TestCharmCommand struct {
+	cmd.CommandBase
+	charmDir   string
+	scriptFile string
+	jujud      string
+}
+
+const testCharmDoc = `
+Run the hooks of a charm on this machine, without an environment, and check
+the results.
+
+The charm in the given directory is deployed to a temporary directory, and
+its hooks are run against an in-memory hook context, as described by a test
+script in YAML format:
+
+    config:                     # service config settings, over the defaults
+      title: My Blog
+    relations:                  # relations, by endpoint name
+      db:
+        mysql/0:                # remote units and their settings
+          private-address: 10.0.0.2
+    steps:                      # hooks and actions to run, in order
+      - hook: install
+      - hook: config-changed
+      - hook: start
+      - hook: db-relation-joined
+        remote-unit: mysql/0
+      - action: backup
+        params:
+          outfile: /tmp/backup
+    expect:                     # checks made once the steps have run
+      status: started
+      ports: [80/tcp]
+      relation-settings:
+        db:
+          database: wordpress
+      logs:
+        - "connected to mysql"
+
+The relation of a relation hook is found from the hook's name; its remote unit
+defaults to the first unit of the relation. As with a unit agent, no further
+steps are run once a hook fails. Relation settings expected to be empty must
+not be set, and each expected log message must appear in a message logged with
+juju-log.
+
+The hook tools are provided by jujud, which must be found in $PATH or given
+with --jujud.
+`
+
+func (c *TestCharmCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "test-charm",
+		Args:    "<charm dir> <test script>",
+		Purpose: "run a charm's hooks locally against a fake hook context",
+		Doc:     testCharmDoc,
+	}
+}
+
+func (c *TestCharmCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.jujud, "jujud", "", "path to the jujud executable providing the hook tools")
+}
+
+func (c *TestCharmCommand) Init(args []string) error {
+	switch len(args) {
+	case 0:
+		return fmt.Errorf("no charm directory specified")
+	case 1:
+		return fmt.Errorf("no test script specified")
+	}
+	c.charmDir, c.scriptFile = args[0], args[1]
+	return cmd.CheckEmpty(args[2:])
+}
+
+// charmTestScript describes the context to run a charm's hooks in,
+// the hooks to run, and the expected results.
+type charmTestScript struct {
+	Config    map[string]interface{}                        `yaml:"config"`
+	Relations map[string]map[string]params.RelationSettings `yaml:"relations"`
+	Steps     []charmTestStep                               `yaml:"steps"`
+	Expect    charmTestExpect                               `yaml:"expect"`
+}
+
+type charmTestStep struct {
+	Hook       string                 `yaml:"hook"`
+	RemoteUnit string                 `yaml:"remote-unit"`
+	Action     string                 `yaml:"action"`
+	Params     map[string]interface{} `yaml:"params"`
+}
+
+type charmTestExpect struct {
+	Status           string                             `yaml:"status"`
+	Ports            []string                           `yaml:"ports"`
+	RelationSettings map[string]params.RelationSettings `yaml:"relation-settings"`
+	Logs             []string                           `yaml:"logs"`
+}
+
+func (c *TestCharmCommand) Run(ctx *cmd.Context) error {
+	data, err := ioutil.ReadFile(ctx.AbsPath(c.scriptFile))
+	if err != nil {
+		return err
+	}
+	var script charmTestScript
+	if err := goyaml.Unmarshal(data, &script); err != nil {
+		return fmt.Errorf("cannot read test script: %v", err)
+	}
+	jujud, err := jujudPath(ctx, c.jujud)
+	if err != nil {
+		return err
+	}
+	h, err := harness.New(ctx.AbsPath(c.charmDir), jujud)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	h.Context.UpdateConfig(charm.Settings(script.Config))
+	endpoints := make([]string, 0, len(script.Relations))
+	for endpoint := range script.Relations {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		r := h.Context.AddRelation(endpoint)
+		for unitName, settings := range script.Relations[endpoint] {
+			r.SetUnit(unitName, settings)
+		}
+	}
+
+	for _, step := range script.Steps {
+		result, err := runCharmTestStep(h, step)
+		if err != nil {
+			return err
+		}
+		switch {
+		case result.Skipped:
+			fmt.Fprintf(ctx.Stdout, "%s: skipped (not implemented)\n", result.Hook)
+		case result.Code == 0:
+			fmt.Fprintf(ctx.Stdout, "%s: ok\n", result.Hook)
+		default:
+			fmt.Fprintf(ctx.Stdout, "%s: failed with exit code %d\n", result.Hook, result.Code)
+			ctx.Stdout.Write(indent(result.Stdout))
+			ctx.Stdout.Write(indent(result.Stderr))
+		}
+		if result.Code != 0 {
+			break
+		}
+	}
+
+	failures := checkCharmTestExpect(h, script.Expect)
+	for _, failure := range failures {
+		fmt.Fprintf(ctx.Stdout, "FAIL: %s\n", failure)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("charm test failed")
+	}
+	fmt.Fprintln(ctx.Stdout, "PASS")
+	return nil
+}
+
+func runCharmTestStep(h *harness.Harness, step charmTestStep) (*harness.HookResult, error) {
+	switch {
+	case step.Hook != "" && step.Action != "":
+		return nil, fmt.Errorf("step cannot run both hook %q and action %q", step.Hook, step.Action)
+	case step.Action != "":
+		return h.RunAction(step.Action, step.Params)
+	case step.Hook == "":
+		return nil, fmt.Errorf("step has no hook or action")
+	}
+	i := strings.Index(step.Hook, "-relation-")
+	if i == -1 {
+		return h.RunHook(step.Hook)
+	}
+	endpoint := step.Hook[:i]
+	r, found := h.Context.FindRelation(endpoint)
+	if !found {
+		return nil, fmt.Errorf("cannot run %q: relation %q not in test script", step.Hook, endpoint)
+	}
+	remoteUnit := step.RemoteUnit
+	if remoteUnit == "" && !strings.HasSuffix(step.Hook, "-relation-broken") {
+		unitNames := r.UnitNames()
+		if len(unitNames) == 0 {
+			return nil, fmt.Errorf("cannot run %q: relation %q has no units", step.Hook, endpoint)
+		}
+		remoteUnit = unitNames[0]
+	}
+	return h.RunRelationHook(step.Hook, r.Id(), remoteUnit)
+}
+
+// checkCharmTestExpect returns a description of each expectation not
+// met by the results of the hooks run by h.
+func checkCharmTestExpect(h *harness.Harness, expect charmTestExpect) []string {
+	var failures []string
+	status, info := h.Status()
+	if expect.Status != "" && string(status) != expect.Status {
+		if info != "" {
+			status = params.Status(fmt.Sprintf("%s (%s)", status, info))
+		}
+		failures = append(failures, fmt.Sprintf("expected status %s, got %s", expect.Status, status))
+	}
+	if expect.Ports != nil {
+		want := append([]string(nil), expect.Ports...)
+		sort.Strings(want)
+		got := h.Context.OpenedPorts()
+		if strings.Join(want, " ") != strings.Join(got, " ") {
+			failures = append(failures, fmt.Sprintf("expected opened ports [%s], got [%s]",
+				strings.Join(want, " "), strings.Join(got, " ")))
+		}
+	}
+	endpoints := make([]string, 0, len(expect.RelationSettings))
+	for endpoint := range expect.RelationSettings {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		r, found := h.Context.FindRelation(endpoint)
+		if !found {
+			failures = append(failures, fmt.Sprintf("relation %q not in test script", endpoint))
+			continue
+		}
+		settings := r.LocalSettings()
+		expected := expect.RelationSettings[endpoint]
+		keys := make([]string, 0, len(expected))
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if settings[key] != expected[key] {
+				failures = append(failures, fmt.Sprintf("expected %s setting %q to be %q, got %q",
+					endpoint, key, expected[key], settings[key]))
+			}
+		}
+	}
+	logs := h.Logs()
+	for _, want := range expect.Logs {
+		found := false
+		for _, entry := range logs {
+			if strings.Contains(entry.Message, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("expected log message containing %q", want))
+		}
+	}
+	return failures
+}
+
+// indent returns the given output with each line indented, for
+// showing the output of a failed hook.
+func indent(output []byte) []byte {
+	if len(output) == 0 {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	return []byte("    " + strings.Join(lines, "\n    ") + "\n")
+}
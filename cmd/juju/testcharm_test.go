@@ -0,0 +1,171 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/testing"
+)
+
+type TestCharmSuite struct {
+	testing.BaseSuite
+	charmDir string
+	jujud    string
+	script   string
+}
+
+var _ = gc.Suite(&TestCharmSuite{})
+
+func (s *TestCharmSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	dir := c.MkDir()
+	s.charmDir = filepath.Join(dir, "wordpress")
+	s.writeCharmFile(c, "metadata.yaml", `
+name: wordpress
+summary: "Blog engine"
+description: "A pretty popular blog engine"
+requires:
+  db:
+    interface: mysql
+`, 0644)
+	s.writeCharmFile(c, "revision", "1\n", 0644)
+
+	// The hooks run below do not call any hook tools, so jujud
+	// need only exist.
+	s.jujud = filepath.Join(dir, "jujud")
+	err := ioutil.WriteFile(s.jujud, []byte("#!/bin/sh\nexit 1\n"), 0755)
+	c.Assert(err, gc.IsNil)
+	s.script = filepath.Join(dir, "test.yaml")
+}
+
+func (s *TestCharmSuite) writeCharmFile(c *gc.C, name, data string, mode os.FileMode) {
+	path := filepath.Join(s.charmDir, name)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	c.Assert(err, gc.IsNil)
+	err = ioutil.WriteFile(path, []byte(data), mode)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *TestCharmSuite) runTestCharm(c *gc.C, script string) (string, error) {
+	err := ioutil.WriteFile(s.script, []byte(script), 0644)
+	c.Assert(err, gc.IsNil)
+	ctx, err := testing.RunCommand(c, &TestCharmCommand{}, "--jujud", s.jujud, s.charmDir, s.script)
+	return testing.Stdout(ctx), err
+}
+
+func (s *TestCharmSuite) TestInit(c *gc.C) {
+	testing.TestInit(c, &TestCharmCommand{}, nil, "no charm directory specified")
+	testing.TestInit(c, &TestCharmCommand{}, []string{"wordpress"}, "no test script specified")
+	testing.TestInit(c, &TestCharmCommand{}, []string{"wordpress", "test.yaml", "foo"}, `unrecognized args: \["foo"\]`)
+}
+
+func (s *TestCharmSuite) TestPass(c *gc.C) {
+	s.writeCharmFile(c, "hooks/install", "#!/bin/sh\nexit 0\n", 0755)
+	s.writeCharmFile(c, "hooks/start", "#!/bin/sh\nexit 0\n", 0755)
+	s.writeCharmFile(c, "hooks/db-relation-joined", `#!/bin/sh
+[ "$JUJU_REMOTE_UNIT" = mysql/0 ] && [ "$JUJU_RELATION_ID" = db:0 ]
+`, 0755)
+	out, err := s.runTestCharm(c, `
+relations:
+  db:
+    mysql/0:
+      private-address: 10.0.0.2
+steps:
+  - hook: install
+  - hook: config-changed
+  - hook: start
+  - hook: db-relation-joined
+expect:
+  status: started
+  ports: []
+`)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Equals, ""+
+		"install: ok\n"+
+		"config-changed: skipped (not implemented)\n"+
+		"start: ok\n"+
+		"db-relation-joined: ok\n"+
+		"PASS\n")
+}
+
+func (s *TestCharmSuite) TestHookFailure(c *gc.C) {
+	s.writeCharmFile(c, "hooks/install", "#!/bin/sh\necho installing\necho broken >&2\nexit 3\n", 0755)
+	out, err := s.runTestCharm(c, `
+steps:
+  - hook: install
+  - hook: start
+expect:
+  status: started
+  ports: [80/tcp]
+  logs:
+    - ready
+`)
+	c.Assert(err, gc.ErrorMatches, "charm test failed")
+	c.Assert(out, gc.Equals, ""+
+		"install: failed with exit code 3\n"+
+		"    installing\n"+
+		"    broken\n"+
+		`FAIL: expected status started, got error (hook failed: "install")`+"\n"+
+		"FAIL: expected opened ports [80/tcp], got []\n"+
+		`FAIL: expected log message containing "ready"`+"\n")
+}
+
+func (s *TestCharmSuite) TestExpectFailedHook(c *gc.C) {
+	s.writeCharmFile(c, "hooks/install", "#!/bin/sh\nexit 1\n", 0755)
+	out, err := s.runTestCharm(c, `
+steps:
+  - hook: install
+expect:
+  status: error
+`)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Equals, "install: failed with exit code 1\nPASS\n")
+}
+
+func (s *TestCharmSuite) TestRelationSettings(c *gc.C) {
+	s.writeCharmFile(c, "hooks/install", "#!/bin/sh\nexit 0\n", 0755)
+	out, err := s.runTestCharm(c, `
+relations:
+  db:
+    mysql/0: {}
+steps:
+  - hook: install
+expect:
+  relation-settings:
+    db:
+      database: wordpress
+      password: ""
+`)
+	c.Assert(err, gc.ErrorMatches, "charm test failed")
+	c.Assert(out, gc.Equals, ""+
+		"install: ok\n"+
+		`FAIL: expected db setting "database" to be "wordpress", got ""`+"\n")
+}
+
+func (s *TestCharmSuite) TestUnknownRelation(c *gc.C) {
+	_, err := s.runTestCharm(c, `
+steps:
+  - hook: website-relation-joined
+`)
+	c.Assert(err, gc.ErrorMatches, `cannot run "website-relation-joined": relation "website" not in test script`)
+}
+
+func (s *TestCharmSuite) TestBadStep(c *gc.C) {
+	_, err := s.runTestCharm(c, `
+steps:
+  - hook: install
+    action: backup
+`)
+	c.Assert(err, gc.ErrorMatches, `step cannot run both hook "install" and action "backup"`)
+	_, err = s.runTestCharm(c, `
+steps:
+  - remote-unit: mysql/0
+`)
+	c.Assert(err, gc.ErrorMatches, "step has no hook or action")
+}
@@ -6,6 +6,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/juju/charm"
 	"github.com/juju/cmd"
@@ -14,6 +15,8 @@ import (
 
 	"github.com/juju/juju/cmd/envcmd"
 	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/state/api"
+	"github.com/juju/juju/state/api/params"
 )
 
 // UpgradeCharm is responsible for upgrading a service's charm.
@@ -24,6 +27,8 @@ type UpgradeCharmCommand struct {
 	RepoPath    string // defaults to JUJU_REPOSITORY
 	SwitchURL   string
 	Revision    int // defaults to -1 (latest)
+	Rollback    bool
+	rollingOptions
 }
 
 const upgradeCharmDoc = `
@@ -62,6 +67,20 @@ would specify revision number 5 of the wordpress charm.
 Use of the --force flag is not generally recommended; units upgraded while in an
 error state will not have upgrade-charm hooks executed, and may cause unexpected
 behavior.
+
+The --rollback flag returns the service to the charm it used before its last
+upgrade, for example when the upgrade-charm hook of the new charm fails. All
+units are changed back to the previous charm, even if they are in an error
+state, and run the previous charm's upgrade-charm hook. A given upgrade can
+only be rolled back once, and --rollback cannot be combined with any other
+flag.
+
+The --batch-size flag upgrades the service's units a few at a time rather
+than all at once. After each batch is upgraded, juju waits for its units to
+be started on the new charm without hook errors, waits for --batch-pause, and
+checks the units again before moving on to the next batch. The upgrade stops
+at the first unhealthy unit; units not yet upgraded are held on the previous
+charm until the service's charm is changed again, for example with --rollback.
 `
 
 func (c *UpgradeCharmCommand) Info() *cmd.Info {
@@ -78,6 +97,8 @@ func (c *UpgradeCharmCommand) SetFlags(f *gnuflag.FlagSet) {
 	f.StringVar(&c.RepoPath, "repository", os.Getenv("JUJU_REPOSITORY"), "local charm repository path")
 	f.StringVar(&c.SwitchURL, "switch", "", "crossgrade to a different charm")
 	f.IntVar(&c.Revision, "revision", -1, "explicit revision of current charm")
+	f.BoolVar(&c.Rollback, "rollback", false, "roll back to the charm used before the last upgrade")
+	c.rollingOptions.addFlags(f)
 }
 
 func (c *UpgradeCharmCommand) Init(args []string) error {
@@ -95,6 +116,15 @@ func (c *UpgradeCharmCommand) Init(args []string) error {
 	if c.SwitchURL != "" && c.Revision != -1 {
 		return fmt.Errorf("--switch and --revision are mutually exclusive")
 	}
+	if c.Rollback && (c.Force || c.SwitchURL != "" || c.Revision != -1) {
+		return fmt.Errorf("--rollback cannot be combined with --force, --switch or --revision")
+	}
+	if err := c.rollingOptions.validate(); err != nil {
+		return err
+	}
+	if c.Rollback && c.rolling() {
+		return fmt.Errorf("--rollback cannot be combined with --batch-size")
+	}
 	return nil
 }
 
@@ -106,6 +136,9 @@ func (c *UpgradeCharmCommand) Run(ctx *cmd.Context) error {
 		return err
 	}
 	defer client.Close()
+	if c.Rollback {
+		return c.rollback(ctx, client)
+	}
 	oldURL, err := client.ServiceGetCharmURL(c.ServiceName)
 	if err != nil {
 		return err
@@ -164,5 +197,56 @@ func (c *UpgradeCharmCommand) Run(ctx *cmd.Context) error {
 		return err
 	}
 
+	if c.rolling() {
+		return c.upgradeRolling(ctx, client, addedURL)
+	}
 	return client.ServiceSetCharm(c.ServiceName, addedURL.String(), c.Force)
 }
+
+// upgradeRolling changes the service's charm while holding back all its
+// units, then releases the units to upgrade a batch at a time.
+func (c *UpgradeCharmCommand) upgradeRolling(ctx *cmd.Context, client *api.Client, curl *charm.URL) error {
+	unitNames, err := serviceUnitNames(client, []string{c.ServiceName}, nil)
+	if err != nil {
+		return err
+	}
+	err = client.ServiceSetCharmHoldingUnits(c.ServiceName, curl.String(), c.Force, unitNames)
+	if params.IsCodeNotImplemented(err) {
+		return fmt.Errorf("cannot upgrade %q in batches: the environment does not support --batch-size", c.ServiceName)
+	} else if err != nil {
+		return err
+	}
+	executor := &rollingExecutor{
+		rollingOptions: c.rollingOptions,
+		client:         client,
+		ready: func(_ string, status api.UnitStatus) bool {
+			// The charm is only reported for units not
+			// running the service's charm.
+			return status.Charm == ""
+		},
+	}
+	remaining, err := executor.run(ctx, unitNames, func(batch []string) error {
+		return client.ServiceReleaseCharmUpgrades(c.ServiceName, batch...)
+	})
+	if err == nil {
+		return nil
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("rolling upgrade aborted: %v; units held on the previous charm: %s",
+			err, strings.Join(remaining, ", "))
+	}
+	return fmt.Errorf("rolling upgrade aborted: %v", err)
+}
+
+// rollback changes the service's charm back to the one it used before
+// its last upgrade.
+func (c *UpgradeCharmCommand) rollback(ctx *cmd.Context, client *api.Client) error {
+	curl, err := client.ServiceRollbackCharm(c.ServiceName)
+	if params.IsCodeNotImplemented(err) {
+		return fmt.Errorf("cannot roll back %q: the environment does not support --rollback", c.ServiceName)
+	} else if err != nil {
+		return err
+	}
+	ctx.Infof("rolling back %q to charm %q", c.ServiceName, curl)
+	return nil
+}
@@ -8,9 +8,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"time"
 
 	"github.com/juju/charm"
 	charmtesting "github.com/juju/charm/testing"
+	jc "github.com/juju/testing/checkers"
 	gc "launchpad.net/gocheck"
 
 	"github.com/juju/juju/cmd/envcmd"
@@ -88,6 +90,29 @@ func (s *UpgradeCharmErrorsSuite) TestSwitchAndRevisionFails(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "--switch and --revision are mutually exclusive")
 }
 
+func (s *UpgradeCharmErrorsSuite) TestRollbackWithOtherFlagsFails(c *gc.C) {
+	s.deployService(c)
+	for _, flag := range []string{"--force", "--switch=riak", "--revision=2"} {
+		err := runUpgradeCharm(c, "riak", "--rollback", flag)
+		c.Assert(err, gc.ErrorMatches, "--rollback cannot be combined with --force, --switch or --revision")
+	}
+}
+
+func (s *UpgradeCharmErrorsSuite) TestRollbackWithoutUpgradeFails(c *gc.C) {
+	s.deployService(c)
+	err := runUpgradeCharm(c, "riak", "--rollback")
+	c.Assert(err, gc.ErrorMatches, `cannot roll back charm of service "riak": no previous charm`)
+}
+
+func (s *UpgradeCharmErrorsSuite) TestBatchFlags(c *gc.C) {
+	err := runUpgradeCharm(c, "riak", "--rollback", "--batch-size=2")
+	c.Assert(err, gc.ErrorMatches, "--rollback cannot be combined with --batch-size")
+	err = runUpgradeCharm(c, "riak", "--batch-pause=1m")
+	c.Assert(err, gc.ErrorMatches, "--batch-pause requires --batch-size")
+	err = runUpgradeCharm(c, "riak", "--batch-size=-1")
+	c.Assert(err, gc.ErrorMatches, "--batch-size must not be negative")
+}
+
 func (s *UpgradeCharmErrorsSuite) TestInvalidRevision(c *gc.C) {
 	s.deployService(c)
 	err := runUpgradeCharm(c, "riak", "--revision=blah")
@@ -178,6 +203,39 @@ func (s *UpgradeCharmSuccessSuite) TestForcedUpgrade(c *gc.C) {
 	s.assertLocalRevision(c, 7, s.path)
 }
 
+func (s *UpgradeCharmSuccessSuite) TestRollback(c *gc.C) {
+	err := runUpgradeCharm(c, "riak")
+	c.Assert(err, gc.IsNil)
+	s.assertUpgraded(c, 8, false)
+
+	err = runUpgradeCharm(c, "riak", "--rollback")
+	c.Assert(err, gc.IsNil)
+	s.assertUpgraded(c, 7, true)
+
+	err = runUpgradeCharm(c, "riak", "--rollback")
+	c.Assert(err, gc.ErrorMatches, `cannot roll back charm of service "riak": no previous charm`)
+}
+
+func (s *UpgradeCharmSuccessSuite) TestRollingUpgradeAborts(c *gc.C) {
+	s.PatchValue(&rollingPollInterval, time.Millisecond)
+	_, err := s.riak.AddUnit()
+	c.Assert(err, gc.IsNil)
+
+	// The units never start, so the first batch never becomes healthy.
+	err = runUpgradeCharm(c, "riak", "--batch-size=1", "--batch-timeout=10ms")
+	c.Assert(err, gc.ErrorMatches, "rolling upgrade aborted: "+
+		"timed out waiting for units to become healthy: riak/0; "+
+		"units held on the previous charm: riak/1")
+	s.assertUpgraded(c, 8, false)
+	c.Assert(s.riak.HeldUnits(), jc.DeepEquals, []string{"riak/1"})
+
+	// Rolling back releases the held units.
+	err = runUpgradeCharm(c, "riak", "--rollback")
+	c.Assert(err, gc.IsNil)
+	s.assertUpgraded(c, 7, true)
+	c.Assert(s.riak.HeldUnits(), gc.HasLen, 0)
+}
+
 var myriakMeta = []byte(`
 name: myriak
 summary: "K/V storage engine"
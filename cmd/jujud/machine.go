@@ -26,6 +26,7 @@ import (
 	"launchpad.net/tomb"
 
 	"github.com/juju/juju/agent"
+	"github.com/juju/juju/container/docker"
 	"github.com/juju/juju/container/kvm"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/instance"
@@ -48,7 +49,9 @@ import (
 	"github.com/juju/juju/worker/charmrevisionworker"
 	"github.com/juju/juju/worker/cleaner"
 	"github.com/juju/juju/worker/deployer"
+	"github.com/juju/juju/worker/dnsserver"
 	"github.com/juju/juju/worker/firewaller"
+	"github.com/juju/juju/worker/hostfirewaller"
 	"github.com/juju/juju/worker/instancepoller"
 	"github.com/juju/juju/worker/localstorage"
 	workerlogger "github.com/juju/juju/worker/logger"
@@ -58,9 +61,12 @@ import (
 	"github.com/juju/juju/worker/networker"
 	"github.com/juju/juju/worker/peergrouper"
 	"github.com/juju/juju/worker/provisioner"
+	"github.com/juju/juju/worker/resolvconfupdater"
 	"github.com/juju/juju/worker/resumer"
 	"github.com/juju/juju/worker/rsyslog"
 	"github.com/juju/juju/worker/singular"
+	"github.com/juju/juju/worker/sparepool"
+	"github.com/juju/juju/worker/subnetupdater"
 	"github.com/juju/juju/worker/terminationworker"
 	"github.com/juju/juju/worker/upgrader"
 )
@@ -270,8 +276,10 @@ func (a *MachineAgent) APIWorker() (worker.Worker, error) {
 	rsyslogMode := rsyslog.RsyslogModeForwarding
 	runner := newRunner(connectionIsFatal(st), moreImportant)
 	var singularRunner worker.Runner
+	isStateServer := false
 	for _, job := range entity.Jobs() {
 		if job == params.JobManageEnviron {
+			isStateServer = true
 			rsyslogMode = rsyslog.RsyslogModeAccumulate
 			conn := singularAPIConn{st, st.Agent()}
 			singularRunner, err = newSingularRunner(runner, conn)
@@ -299,6 +307,9 @@ func (a *MachineAgent) APIWorker() (worker.Worker, error) {
 	a.startWorkerAfterUpgrade(runner, "apiaddressupdater", func() (worker.Worker, error) {
 		return apiaddressupdater.NewAPIAddressUpdater(st.Machiner(), a), nil
 	})
+	a.startWorkerAfterUpgrade(runner, "resolvconfupdater", func() (worker.Worker, error) {
+		return resolvconfupdater.NewResolvConfUpdater(st.Machiner(), isStateServer), nil
+	})
 	a.startWorkerAfterUpgrade(runner, "logger", func() (worker.Worker, error) {
 		return workerlogger.NewLogger(st.Logger(), agentConfig), nil
 	})
@@ -337,6 +348,9 @@ func (a *MachineAgent) APIWorker() (worker.Worker, error) {
 				context := newDeployContext(apiDeployer, agentConfig)
 				return deployer.NewDeployer(apiDeployer, context), nil
 			})
+			a.startWorkerAfterUpgrade(runner, "hostfirewaller", func() (worker.Worker, error) {
+				return hostfirewaller.NewHostFirewaller(st.HostFirewaller(), agentConfig), nil
+			})
 		case params.JobManageEnviron:
 			a.startWorkerAfterUpgrade(singularRunner, "environ-provisioner", func() (worker.Worker, error) {
 				return provisioner.NewEnvironProvisioner(st.Provisioner(), agentConfig), nil
@@ -376,6 +390,13 @@ func (a *MachineAgent) setupContainerSupport(runner worker.Runner, st *api.State
 	if err == nil && supportsKvm {
 		supportedContainers = append(supportedContainers, instance.KVM)
 	}
+	supportsDocker, err := docker.IsDockerSupported()
+	if err != nil {
+		logger.Warningf("determining docker support: %v\nno docker containers possible", err)
+	}
+	if err == nil && supportsDocker {
+		supportedContainers = append(supportedContainers, instance.DOCKER)
+	}
 	return a.updateSupportedContainers(runner, st, entity.Tag(), supportedContainers, agentConfig)
 }
 
@@ -479,6 +500,25 @@ func (a *MachineAgent) StateWorker() (worker.Worker, error) {
 			a.startWorkerAfterUpgrade(runner, "peergrouper", func() (worker.Worker, error) {
 				return peergrouperNew(st)
 			})
+			a.startWorkerAfterUpgrade(runner, "dnsserver", func() (worker.Worker, error) {
+				envConfig, err := st.EnvironConfig()
+				if err != nil {
+					return nil, err
+				}
+				if !envConfig.DNSService() {
+					return worker.NewNoOpWorker(), nil
+				}
+				// The machine's addresses may have changed since
+				// the worker last started.
+				if err := m.Refresh(); err != nil {
+					return nil, err
+				}
+				conns, err := dnsserver.Listen(m.Addresses())
+				if err != nil {
+					return nil, err
+				}
+				return dnsserver.New(st, conns)
+			})
 			runner.StartWorker("apiserver", func() (worker.Worker, error) {
 				// If the configuration does not have the required information,
 				// it is currently not a recoverable error, so we kill the whole
@@ -524,6 +564,12 @@ func (a *MachineAgent) StateWorker() (worker.Worker, error) {
 			a.startWorkerAfterUpgrade(singularRunner, "minunitsworker", func() (worker.Worker, error) {
 				return minunitsworker.NewMinUnitsWorker(st), nil
 			})
+			a.startWorkerAfterUpgrade(singularRunner, "sparepool", func() (worker.Worker, error) {
+				return sparepool.NewSparePool(st), nil
+			})
+			a.startWorkerAfterUpgrade(singularRunner, "subnetupdater", func() (worker.Worker, error) {
+				return subnetupdater.NewWorker(st), nil
+			})
 		case state.JobManageStateDeprecated:
 			// Legacy environments may set this, but we ignore it.
 		default:
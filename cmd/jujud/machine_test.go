@@ -52,6 +52,7 @@ import (
 	"github.com/juju/juju/worker/deployer"
 	"github.com/juju/juju/worker/instancepoller"
 	"github.com/juju/juju/worker/machineenvironmentworker"
+	"github.com/juju/juju/worker/resolvconfupdater"
 	"github.com/juju/juju/worker/rsyslog"
 	"github.com/juju/juju/worker/singular"
 	"github.com/juju/juju/worker/upgrader"
@@ -82,6 +83,8 @@ func (s *commonMachineSuite) SetUpTest(c *gc.C) {
 	os.Remove(jujuRun) // ignore error; may not exist
 	// Patch ssh user to avoid touching ~ubuntu/.ssh/authorized_keys.
 	s.agentSuite.PatchValue(&authenticationworker.SSHUser, "")
+	// Patch the resolver configuration to avoid touching the host's.
+	s.agentSuite.PatchValue(&resolvconfupdater.HeadFile, filepath.Join(c.MkDir(), "head"))
 
 	testpath := c.MkDir()
 	s.agentSuite.PatchEnvPathPrepend(testpath)
@@ -403,6 +406,8 @@ func (s *MachineSuite) TestManageEnviron(c *gc.C) {
 		"firewaller",
 		"minunitsworker",
 		"resumer",
+		"sparepool",
+		"subnetupdater",
 	})
 }
 
@@ -4,9 +4,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	osexec "os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/juju/cmd"
 	"github.com/juju/names"
@@ -43,7 +46,8 @@ or the unit id:
 If --no-context is specified, the <unit-name> positional
 argument is not needed.
 
-The commands are executed with '/bin/bash -s', and the output returned.
+The commands are executed with '/bin/bash -s', and their output is
+written as it arrives.
 `
 
 // Info returns usage information for the command.
@@ -91,20 +95,17 @@ func (c *RunCommand) Run(ctx *cmd.Context) error {
 		return gnuflag.ErrHelp
 	}
 
-	var result *exec.ExecResponse
+	var code int
 	var err error
 	if c.noContext {
-		result, err = c.executeNoContext()
+		code, err = c.executeNoContext(ctx)
 	} else {
-		result, err = c.executeInUnitContext()
+		code, err = c.executeInUnitContext(ctx)
 	}
 	if err != nil {
 		return err
 	}
-
-	ctx.Stdout.Write(result.Stdout)
-	ctx.Stderr.Write(result.Stderr)
-	return cmd.NewRcPassthroughError(result.Code)
+	return cmd.NewRcPassthroughError(code)
 }
 
 func (c *RunCommand) nixSockPath() string {
@@ -125,27 +126,63 @@ func (c *RunCommand) sockPath() string {
 	}
 }
 
-func (c *RunCommand) executeInUnitContext() (*exec.ExecResponse, error) {
+func (c *RunCommand) executeInUnitContext(ctx *cmd.Context) (int, error) {
 	unitDir := filepath.Join(AgentDir, c.unit)
 	logger.Debugf("looking for unit dir %s", unitDir)
 	// make sure the unit exists
 	_, err := os.Stat(unitDir)
 	if os.IsNotExist(err) {
-		return nil, fmt.Errorf("unit %q not found on this machine", c.unit)
+		return 0, fmt.Errorf("unit %q not found on this machine", c.unit)
 	} else if err != nil {
-		return nil, err
+		return 0, err
 	}
 	// make sure the socket exists
 	socketPath := c.sockPath()
 	client, err := sockets.Dial(socketPath)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer client.Close()
 
-	var result exec.ExecResponse
-	err = client.Call(uniter.JujuRunEndpoint, c.commands, &result)
-	return &result, err
+	var id int
+	err = client.Call(uniter.JujuRunStartEndpoint, c.commands, &id)
+	if isUnknownMethodError(err) {
+		// The unit agent predates streamed output, so the output
+		// can only be written once the commands have finished.
+		var result exec.ExecResponse
+		if err := client.Call(uniter.JujuRunEndpoint, c.commands, &result); err != nil {
+			return 0, err
+		}
+		ctx.Stdout.Write(result.Stdout)
+		ctx.Stderr.Write(result.Stderr)
+		return result.Code, nil
+	} else if err != nil {
+		return 0, err
+	}
+	args := uniter.CommandsOutputArgs{Id: id}
+	for {
+		var output uniter.CommandsOutput
+		if err := client.Call(uniter.JujuRunOutputEndpoint, args, &output); err != nil {
+			return 0, err
+		}
+		ctx.Stdout.Write(output.Stdout)
+		ctx.Stderr.Write(output.Stderr)
+		args.StdoutOffset += len(output.Stdout)
+		args.StderrOffset += len(output.Stderr)
+		if !output.Done {
+			continue
+		}
+		if output.Error != "" {
+			return 0, errors.New(output.Error)
+		}
+		return output.Code, nil
+	}
+}
+
+// isUnknownMethodError returns whether the error was returned by an
+// rpc call for a method the server does not have.
+func isUnknownMethodError(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "rpc: can't find method ")
 }
 
 func getLock() (*fslock.Lock, error) {
@@ -167,23 +204,39 @@ func (c *RunCommand) appendProxyToCommands() string {
 	}
 }
 
-func (c *RunCommand) executeNoContext() (*exec.ExecResponse, error) {
+func (c *RunCommand) executeNoContext(ctx *cmd.Context) (int, error) {
 	// Acquire the uniter hook execution lock to make sure we don't
 	// stomp on each other.
 	lock, err := getLock()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	err = lock.Lock("juju-run")
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer lock.Unlock()
 
 	runCmd := c.appendProxyToCommands()
 
-	return exec.RunCommands(
-		exec.RunParams{
-			Commands: runCmd,
-		})
+	var command *osexec.Cmd
+	switch version.Current.OS {
+	case version.Windows:
+		command = osexec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", "-")
+	default:
+		command = osexec.Command("/bin/bash", "-s")
+	}
+	command.Stdin = strings.NewReader(runCmd + "\n")
+	command.Stdout = ctx.Stdout
+	command.Stderr = ctx.Stderr
+	err = command.Run()
+	if exitErr, ok := err.(*osexec.ExitError); ok {
+		// A non-zero exit code isn't considered an error here.
+		if status, ok := exitErr.Sys().(interface {
+			ExitStatus() int
+		}); ok && status.ExitStatus() >= 0 {
+			return status.ExitStatus(), nil
+		}
+	}
+	return 0, err
 }
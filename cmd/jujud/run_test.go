@@ -5,6 +5,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -194,11 +195,18 @@ type mockRunner struct {
 
 var _ uniter.CommandRunner = (*mockRunner)(nil)
 
-func (r *mockRunner) RunCommands(commands string) (results *exec.ExecResponse, err error) {
+func (r *mockRunner) RunCommands(commands string, stdout, stderr io.Writer, abort <-chan struct{}) (results *exec.ExecResponse, err error) {
 	r.c.Log("mock runner: " + commands)
-	return &exec.ExecResponse{
+	result := &exec.ExecResponse{
 		Code:   42,
 		Stdout: []byte(commands + " stdout"),
 		Stderr: []byte(commands + " stderr"),
-	}, nil
+	}
+	if stdout != nil {
+		stdout.Write(result.Stdout)
+	}
+	if stderr != nil {
+		stderr.Write(result.Stderr)
+	}
+	return result, nil
 }
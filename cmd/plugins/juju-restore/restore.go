@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
 	"strconv"
@@ -284,7 +285,7 @@ func (c *restoreCommand) Run(ctx *cmd.Context) error {
 	for a := attempt.Start(); a.Next(); {
 		st, err = state.Open(&authentication.MongoInfo{
 			Info: mongo.Info{
-				Addrs:  []string{fmt.Sprintf("%s:%d", machine0Addr, cfg.StatePort())},
+				Addrs:  []string{net.JoinHostPort(machine0Addr, strconv.Itoa(cfg.StatePort()))},
 				CACert: caCert,
 			},
 			Tag:      tag,
@@ -314,7 +315,8 @@ func rebootstrap(cfg *config.Config, ctx *cmd.Context, cons constraints.Value) (
 	// Turn on safe mode so that the newly bootstrapped instance
 	// will not destroy all the instances it does not know about.
 	cfg, err := cfg.Apply(map[string]interface{}{
-		"provisioner-safe-mode": true,
+		"provisioner-safe-mode":    true,
+		"provisioner-harvest-mode": config.HarvestDestroyed.String(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("cannot enable provisioner-safe-mode: %v", err)
@@ -570,6 +572,11 @@ func runViaSsh(addr string, script string) error {
 }
 
 func sendViaScp(file, host, destFile string) error {
+	// scp needs IPv6 addresses in brackets to tell them apart
+	// from the path.
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
 	err := ssh.Copy([]string{file, "ubuntu@" + host + ":" + destFile}, nil)
 	if err != nil {
 		return fmt.Errorf("scp command failed: %v", err)
@@ -0,0 +1,92 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseCPUSet parses a list of CPUs in the kernel's cpuset format,
+// such as "0-2,5", and returns the CPUs it holds.
+func ParseCPUSet(s string) ([]int, error) {
+	var cpus []int
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid cpuset %q", s)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpuset %q", s)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// FormatCPUSet returns the given CPUs in the kernel's cpuset format,
+// with runs of consecutive CPUs written as ranges.
+func FormatCPUSet(cpus []int) string {
+	sorted := append([]int(nil), cpus...)
+	sort.Ints(sorted)
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// AllocateCPUs chooses n of the host's CPUs for a container to be
+// pinned to, given the number of containers already pinned to each
+// CPU. The least used CPUs are chosen, the lowest numbered first among
+// those used equally, so that containers are spread across the host's
+// CPUs rather than crowded onto the first ones. The CPUs are returned
+// in ascending order; n must not exceed hostCPUs.
+func AllocateCPUs(n, hostCPUs int, usage map[int]int) []int {
+	cpus := make([]int, hostCPUs)
+	for i := range cpus {
+		cpus[i] = i
+	}
+	sort.Stable(cpusByUsage{cpus, usage})
+	chosen := cpus[:n]
+	sort.Ints(chosen)
+	return chosen
+}
+
+type cpusByUsage struct {
+	cpus  []int
+	usage map[int]int
+}
+
+func (b cpusByUsage) Len() int {
+	return len(b.cpus)
+}
+
+func (b cpusByUsage) Less(i, j int) bool {
+	return b.usage[b.cpus[i]] < b.usage[b.cpus[j]]
+}
+
+func (b cpusByUsage) Swap(i, j int) {
+	b.cpus[i], b.cpus[j] = b.cpus[j], b.cpus[i]
+}
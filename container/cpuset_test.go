@@ -0,0 +1,62 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package container_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/testing"
+)
+
+type CPUSetSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&CPUSetSuite{})
+
+var cpusetTests = []struct {
+	cpuset string
+	cpus   []int
+}{
+	{"", nil},
+	{"0", []int{0}},
+	{"0-2", []int{0, 1, 2}},
+	{"0-1,3,5-6", []int{0, 1, 3, 5, 6}},
+}
+
+func (s *CPUSetSuite) TestParseAndFormatCPUSet(c *gc.C) {
+	for i, test := range cpusetTests {
+		c.Logf("test %d: %q", i, test.cpuset)
+		cpus, err := container.ParseCPUSet(test.cpuset)
+		c.Check(err, gc.IsNil)
+		c.Check(cpus, jc.DeepEquals, test.cpus)
+		c.Check(container.FormatCPUSet(test.cpus), gc.Equals, test.cpuset)
+	}
+}
+
+func (s *CPUSetSuite) TestParseCPUSetInvalid(c *gc.C) {
+	for _, cpuset := range []string{"a", "1-", "3-1", "-1", "1,,2"} {
+		_, err := container.ParseCPUSet(cpuset)
+		c.Check(err, gc.ErrorMatches, `invalid cpuset ".*"`)
+	}
+}
+
+func (s *CPUSetSuite) TestAllocateCPUs(c *gc.C) {
+	usage := make(map[int]int)
+	allocate := func(n int) []int {
+		cpus := container.AllocateCPUs(n, 4, usage)
+		for _, cpu := range cpus {
+			usage[cpu]++
+		}
+		return cpus
+	}
+	// Containers are spread across the CPUs, not crowded onto the
+	// first ones.
+	c.Assert(allocate(2), jc.DeepEquals, []int{0, 1})
+	c.Assert(allocate(1), jc.DeepEquals, []int{2})
+	c.Assert(allocate(2), jc.DeepEquals, []int{0, 3})
+	c.Assert(allocate(4), jc.DeepEquals, []int{0, 1, 2, 3})
+}
@@ -0,0 +1,119 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/container"
+)
+
+// userDataMount is where the user data directory is mounted inside
+// the container.
+const userDataMount = "/var/lib/juju/container-init"
+
+// runDocker runs the docker client with the given arguments and
+// returns its output. It is a variable so it can be overridden in tests.
+var runDocker = func(args ...string) (string, error) {
+	cmd := exec.Command("docker", args...)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		return "", errors.Annotatef(err, "docker %s failed: %s", args[0], output)
+	}
+	return output, nil
+}
+
+type dockerContainer struct {
+	factory *containerFactory
+	name    string
+	// started is a three state boolean, true, false, or unknown
+	// this allows for checking when we don't know, but using a
+	// value if we already know it (like in the list situation).
+	started *bool
+}
+
+var _ Container = (*dockerContainer)(nil)
+
+func (c *dockerContainer) Name() string {
+	return c.name
+}
+
+func (c *dockerContainer) Start(params StartParams) error {
+	if params.Network != nil {
+		if params.Network.NetworkType != container.BridgeNetwork {
+			return errors.LoggedErrorf(logger, "Non-bridge network devices not yet supported")
+		}
+		if params.Network.Device != DefaultDockerBridge {
+			return errors.LoggedErrorf(logger, "docker containers can only use the %q bridge", DefaultDockerBridge)
+		}
+	}
+	args := []string{
+		"run", "--detach",
+		"--name", c.name,
+		"--hostname", c.name,
+		"--restart", "always",
+		// The agent needs to manage upstart jobs and mounts.
+		"--privileged",
+		"--volume", fmt.Sprintf("%s:%s:ro", params.UserDataDir, userDataMount),
+	}
+	if params.LogDir != "" {
+		args = append(args, "--volume", fmt.Sprintf("%s:/var/log/juju", params.LogDir))
+	}
+	if params.Memory > 0 {
+		args = append(args, fmt.Sprintf("--memory=%dm", params.Memory))
+	}
+	if params.CpuShares > 0 {
+		args = append(args, fmt.Sprintf("--cpu-shares=%d", params.CpuShares))
+	}
+	if params.CpuSet != "" {
+		args = append(args, fmt.Sprintf("--cpuset=%s", params.CpuSet))
+	}
+	// Carry out the user data before handing over to init, which
+	// starts the agent's upstart job.
+	script := filepath.Join(userDataMount, params.UserDataScript)
+	args = append(args, params.Image, "/bin/bash", "-c",
+		fmt.Sprintf("/bin/bash %s > /var/log/cloud-init-output.log 2>&1; exec /sbin/init", script))
+
+	logger.Debugf("Create the container %s from %s", c.name, params.Image)
+	if _, err := runDocker(args...); err != nil {
+		return err
+	}
+	c.started = nil
+	return nil
+}
+
+func (c *dockerContainer) Stop() error {
+	// Make started state unknown again.
+	c.started = nil
+	logger.Debugf("Remove %s", c.name)
+	_, err := runDocker("rm", "--force", c.name)
+	return err
+}
+
+func (c *dockerContainer) IsRunning() bool {
+	if c.started != nil {
+		return *c.started
+	}
+	out, err := runDocker("inspect", "--format", "{{.State.Running}}", c.name)
+	if err != nil {
+		return false
+	}
+	running := out == "true"
+	c.started = &running
+	return running
+}
+
+func (c *dockerContainer) CPUSet() (string, error) {
+	return runDocker("inspect", "--format", "{{.Config.Cpuset}}", c.name)
+}
+
+func (c *dockerContainer) String() string {
+	return fmt.Sprintf("<Docker container %v>", *c)
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker
+
+import (
+	"strings"
+)
+
+type containerFactory struct {
+}
+
+var _ ContainerFactory = (*containerFactory)(nil)
+
+func (factory *containerFactory) New(name string) Container {
+	return &dockerContainer{
+		factory: factory,
+		name:    name,
+	}
+}
+
+func (factory *containerFactory) List() (result []Container, err error) {
+	out, err := runDocker("ps", "--all", "--quiet", "--no-trunc")
+	if err != nil {
+		return nil, err
+	}
+	ids := strings.Fields(out)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"inspect", "--format", "{{.Name}} {{.State.Running}}"}, ids...)
+	if out, err = runDocker(args...); err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		running := fields[1] == "true"
+		result = append(result, &dockerContainer{
+			factory: factory,
+			// Docker reports names with a leading slash.
+			name:    strings.TrimPrefix(fields[0], "/"),
+			started: &running,
+		})
+	}
+	return result, nil
+}
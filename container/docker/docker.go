@@ -0,0 +1,262 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/agent"
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/environs/cloudinit"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/version"
+)
+
+var (
+	logger = loggo.GetLogger("juju.container.docker")
+
+	DockerObjectFactory ContainerFactory = &containerFactory{}
+
+	// DefaultDockerBridge is the bridge created by the docker package.
+	DefaultDockerBridge = "docker0"
+
+	// BaseImage is the image containers are built on; the
+	// container's series is used as the tag.
+	BaseImage = "ubuntu"
+)
+
+// hostCPUs returns the number of CPUs on the host; it is a variable
+// so it can be overridden in tests.
+var hostCPUs = runtime.NumCPU
+
+// IsDockerSupported reports whether docker containers can be run on
+// this machine: either the docker client is already available, or the
+// docker package can be installed when the container initialiser
+// runs. It is a variable to allow us to override behaviour in the
+// tests.
+var IsDockerSupported = func() (bool, error) {
+	if _, err := exec.LookPath("docker"); err == nil {
+		return true, nil
+	}
+	for _, pkg := range requiredPackages {
+		candidate, err := packageCandidate(pkg)
+		if err != nil {
+			return false, err
+		}
+		if candidate == "" {
+			return false, errors.NotFoundf("docker executable or installable %s package", pkg)
+		}
+	}
+	return true, nil
+}
+
+// packageCandidate returns the version of the given package that apt
+// would install, or an empty string if the package cannot be
+// installed. It is a variable so it can be overridden in tests.
+var packageCandidate = func(pkg string) (string, error) {
+	out, err := exec.Command("apt-cache", "policy", pkg).CombinedOutput()
+	if err != nil {
+		return "", errors.Annotatef(err, "apt-cache policy %s failed: %s", pkg, strings.TrimSpace(string(out)))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "Candidate:") {
+			continue
+		}
+		candidate := strings.TrimSpace(strings.TrimPrefix(line, "Candidate:"))
+		if candidate == "(none)" {
+			candidate = ""
+		}
+		return candidate, nil
+	}
+	return "", nil
+}
+
+// NewContainerManager returns a manager object that can start and stop
+// docker containers. The containers that are created are namespaced by
+// the name parameter.
+func NewContainerManager(conf container.ManagerConfig) (container.Manager, error) {
+	name := conf.PopValue(container.ConfigName)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	logDir := conf.PopValue(container.ConfigLogDir)
+	if logDir == "" {
+		logDir = agent.DefaultLogDir
+	}
+	conf.WarnAboutUnused()
+	return &containerManager{name: name, logdir: logDir}, nil
+}
+
+// containerManager handles all of the business logic at the juju specific
+// level. It makes sure that the necessary directories are in place, that the
+// user-data is written out in the right place.
+type containerManager struct {
+	name   string
+	logdir string
+}
+
+var _ container.Manager = (*containerManager)(nil)
+
+func (manager *containerManager) CreateContainer(
+	machineConfig *cloudinit.MachineConfig,
+	series string,
+	network *container.NetworkConfig,
+	cons constraints.Value) (instance.Instance, *instance.HardwareCharacteristics, error) {
+
+	name := names.NewMachineTag(machineConfig.MachineId).String()
+	if manager.name != "" {
+		name = fmt.Sprintf("%s-%s", manager.name, name)
+	}
+	// Note here that the DockerObjectFactory only returns a valid
+	// container object, and doesn't actually create the container.
+	dockerContainer := DockerObjectFactory.New(name)
+
+	directory, err := container.NewDirectory(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create container directory: %v", err)
+	}
+	// The base image does not run cloud-init, so the cloud-init is
+	// rendered as a script that is run when the container starts.
+	logger.Tracef("write user data script")
+	scriptFilename, err := container.WriteUserDataScript(machineConfig, network, directory)
+	if err != nil {
+		return nil, nil, errors.LoggedErrorf(logger, "failed to write user data: %v", err)
+	}
+
+	arch := version.Current.Arch
+	hardware := &instance.HardwareCharacteristics{
+		Arch: &arch,
+	}
+	cpuAllocationMutex.Lock()
+	defer cpuAllocationMutex.Unlock()
+	startParams := ParseConstraintsToStartParams(cons, hardware)
+	startParams.Image = fmt.Sprintf("%s:%s", BaseImage, series)
+	startParams.Network = network
+	startParams.UserDataDir = directory
+	startParams.UserDataScript = filepath.Base(scriptFilename)
+	startParams.LogDir = manager.logdir
+
+	logger.Tracef("create the container, constraints: %v", cons)
+	if err := dockerContainer.Start(startParams); err != nil {
+		return nil, nil, errors.LoggedErrorf(logger, "docker container creation failed: %v", err)
+	}
+	logger.Tracef("docker container created")
+	return &dockerInstance{dockerContainer, name}, hardware, nil
+}
+
+func (manager *containerManager) DestroyContainer(id instance.Id) error {
+	name := string(id)
+	dockerContainer := DockerObjectFactory.New(name)
+	if err := dockerContainer.Stop(); err != nil {
+		logger.Errorf("failed to stop docker container: %v", err)
+		return err
+	}
+	return container.RemoveDirectory(name)
+}
+
+func (manager *containerManager) ListContainers() (result []instance.Instance, err error) {
+	containers, err := DockerObjectFactory.List()
+	if err != nil {
+		logger.Errorf("failed getting all instances: %v", err)
+		return
+	}
+	managerPrefix := fmt.Sprintf("%s-", manager.name)
+	for _, container := range containers {
+		// Filter out those not starting with our name.
+		name := container.Name()
+		if !strings.HasPrefix(name, managerPrefix) {
+			continue
+		}
+		if container.IsRunning() {
+			result = append(result, &dockerInstance{container, name})
+		}
+	}
+	return
+}
+
+// cpuAllocationMutex is held while CPUs are chosen for a container
+// until it is started, so that containers created at the same time
+// are not pinned to the same CPUs.
+var cpuAllocationMutex sync.Mutex
+
+// hostCPUUsage returns the number of docker containers on the host
+// pinned to each CPU.
+func hostCPUUsage() map[int]int {
+	usage := make(map[int]int)
+	containers, err := DockerObjectFactory.List()
+	if err != nil {
+		logger.Warningf("cannot list containers to find their CPUs: %v", err)
+		return usage
+	}
+	for _, c := range containers {
+		cpuset, err := c.CPUSet()
+		if err != nil {
+			logger.Warningf("cannot find CPUs of container %q: %v", c.Name(), err)
+			continue
+		}
+		cpus, err := container.ParseCPUSet(cpuset)
+		if err != nil {
+			logger.Warningf("ignoring CPUs of container %q: %v", c.Name(), err)
+			continue
+		}
+		for _, cpu := range cpus {
+			usage[cpu]++
+		}
+	}
+	return usage
+}
+
+// ParseConstraintsToStartParams takes a constraints object and returns
+// a bare StartParams object with the resource limits populated, and
+// records the limits applied in hardware. Constraints that cannot be
+// applied to a docker container cause a message to be logged.
+func ParseConstraintsToStartParams(cons constraints.Value, hardware *instance.HardwareCharacteristics) StartParams {
+	var params StartParams
+	if cons.Mem != nil && *cons.Mem > 0 {
+		mem := *cons.Mem
+		params.Memory = mem
+		hardware.Mem = &mem
+	}
+	if cons.CpuCores != nil && *cons.CpuCores > 0 {
+		cores := *cons.CpuCores
+		if available := uint64(hostCPUs()); cores > available {
+			logger.Warningf("cpu-cores constraint of %d exceeds the %d host CPUs", cores, available)
+			cores = available
+		}
+		cpus := container.AllocateCPUs(int(cores), hostCPUs(), hostCPUUsage())
+		params.CpuSet = container.FormatCPUSet(cpus)
+		params.CpuShares = cores * 1024
+		hardware.CpuCores = &cores
+	}
+	if cons.CpuPower != nil && *cons.CpuPower > 0 {
+		power := *cons.CpuPower
+		// cpu-power is measured in hundredths of a core.
+		params.CpuShares = power * 1024 / 100
+		if params.CpuShares < 2 {
+			params.CpuShares = 2
+		}
+		hardware.CpuPower = &power
+	}
+	if cons.RootDisk != nil {
+		logger.Infof("root-disk constraint of %vM being ignored as not supported", *cons.RootDisk)
+	}
+	if cons.Arch != nil {
+		logger.Infof("arch constraint of %q being ignored as not supported", *cons.Arch)
+	}
+	if cons.Tags != nil {
+		logger.Infof("tags constraint of %q being ignored as not supported", strings.Join(*cons.Tags, ","))
+	}
+	return params
+}
@@ -0,0 +1,233 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker_test
+
+import (
+	"path/filepath"
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/container/docker"
+	dockertesting "github.com/juju/juju/container/docker/testing"
+	containertesting "github.com/juju/juju/container/testing"
+	"github.com/juju/juju/instance"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type DockerSuite struct {
+	dockertesting.TestSuite
+	manager container.Manager
+}
+
+var _ = gc.Suite(&DockerSuite{})
+
+func (s *DockerSuite) SetUpTest(c *gc.C) {
+	s.TestSuite.SetUpTest(c)
+	var err error
+	s.manager, err = docker.NewContainerManager(container.ManagerConfig{container.ConfigName: "test"})
+	c.Assert(err, gc.IsNil)
+}
+
+func (*DockerSuite) TestManagerNameNeeded(c *gc.C) {
+	manager, err := docker.NewContainerManager(container.ManagerConfig{container.ConfigName: ""})
+	c.Assert(err, gc.ErrorMatches, "name is required")
+	c.Assert(manager, gc.IsNil)
+}
+
+func (s *DockerSuite) TestIsDockerSupportedWhenInstallable(c *gc.C) {
+	s.PatchEnvironment("PATH", c.MkDir())
+	s.PatchValue(docker.PackageCandidate, func(pkg string) (string, error) {
+		c.Check(pkg, gc.Equals, "docker.io")
+		return "1.0.1~dfsg1-0ubuntu1~ubuntu0.14.04.1", nil
+	})
+	supported, err := docker.IsDockerSupported()
+	c.Assert(err, gc.IsNil)
+	c.Assert(supported, jc.IsTrue)
+}
+
+func (s *DockerSuite) TestIsDockerSupportedNotInstallable(c *gc.C) {
+	s.PatchEnvironment("PATH", c.MkDir())
+	s.PatchValue(docker.PackageCandidate, func(pkg string) (string, error) {
+		return "", nil
+	})
+	supported, err := docker.IsDockerSupported()
+	c.Assert(err, gc.ErrorMatches, "docker executable or installable docker.io package not found")
+	c.Assert(supported, jc.IsFalse)
+}
+
+func (s *DockerSuite) TestListInitiallyEmpty(c *gc.C) {
+	containers, err := s.manager.ListContainers()
+	c.Assert(err, gc.IsNil)
+	c.Assert(containers, gc.HasLen, 0)
+}
+
+func (s *DockerSuite) TestListMatchesManagerName(c *gc.C) {
+	for _, name := range []string{"test-match1", "test-match2", "testNoMatch", "other"} {
+		err := s.ContainerFactory.New(name).Start(docker.StartParams{})
+		c.Assert(err, gc.IsNil)
+	}
+	containers, err := s.manager.ListContainers()
+	c.Assert(err, gc.IsNil)
+	c.Assert(containers, gc.HasLen, 2)
+	expectedIds := []instance.Id{"test-match1", "test-match2"}
+	ids := []instance.Id{containers[0].Id(), containers[1].Id()}
+	c.Assert(ids, jc.SameContents, expectedIds)
+}
+
+func (s *DockerSuite) TestCreateContainer(c *gc.C) {
+	instance := containertesting.CreateContainer(c, s.manager, "1/docker/0")
+	name := string(instance.Id())
+	c.Assert(name, gc.Equals, "test-machine-1-docker-0")
+	c.Assert(instance.Status(), gc.Equals, "running")
+
+	script := filepath.Join(s.ContainerDir, name, "cloud-init.sh")
+	c.Assert(script, jc.IsNonEmptyFile)
+}
+
+func (s *DockerSuite) TestCreateContainerWithConstraints(c *gc.C) {
+	s.PatchValue(docker.HostCPUs, func() int { return 8 })
+	cons := constraints.MustParse("mem=2G cpu-cores=2")
+	_, hardware := containertesting.CreateContainerWithConstraints(c, s.manager, "1/docker/0", cons)
+	c.Assert(*hardware.Mem, gc.Equals, uint64(2048))
+	c.Assert(*hardware.CpuCores, gc.Equals, uint64(2))
+	c.Assert(hardware.RootDisk, gc.IsNil)
+}
+
+func (s *DockerSuite) TestCreateContainersSpreadAcrossCPUs(c *gc.C) {
+	s.PatchValue(docker.HostCPUs, func() int { return 4 })
+	cpuset := func(machineId, cons string) string {
+		instance, _ := containertesting.CreateContainerWithConstraints(c, s.manager, machineId, constraints.MustParse(cons))
+		cpuset, err := s.ContainerFactory.New(string(instance.Id())).CPUSet()
+		c.Assert(err, gc.IsNil)
+		return cpuset
+	}
+	// Each container is pinned to the CPUs least used by those before it.
+	c.Assert(cpuset("1/docker/0", "cpu-cores=2"), gc.Equals, "0-1")
+	c.Assert(cpuset("1/docker/1", "cpu-cores=1"), gc.Equals, "2")
+	c.Assert(cpuset("1/docker/2", "cpu-cores=2"), gc.Equals, "0,3")
+
+	// The CPUs of a destroyed container are used again.
+	err := s.manager.DestroyContainer("test-machine-1-docker-1")
+	c.Assert(err, gc.IsNil)
+	c.Assert(cpuset("1/docker/3", "cpu-cores=1"), gc.Equals, "2")
+}
+
+func (s *DockerSuite) TestDestroyContainer(c *gc.C) {
+	instance := containertesting.CreateContainer(c, s.manager, "1/docker/0")
+
+	err := s.manager.DestroyContainer(instance.Id())
+	c.Assert(err, gc.IsNil)
+
+	name := string(instance.Id())
+	// Check that the container dir is no longer in the container dir
+	c.Assert(filepath.Join(s.ContainerDir, name), jc.DoesNotExist)
+	// but instead, in the removed container dir
+	c.Assert(filepath.Join(s.RemovedDir, name), jc.IsDirectory)
+}
+
+type ConstraintsSuite struct {
+	dockertesting.TestSuite
+}
+
+var _ = gc.Suite(&ConstraintsSuite{})
+
+func (s *ConstraintsSuite) TestResourceLimits(c *gc.C) {
+	s.PatchValue(docker.HostCPUs, func() int { return 4 })
+	for i, test := range []struct {
+		cons     string
+		expected docker.StartParams
+		hardware string
+	}{{
+		hardware: "arch=amd64",
+	}, {
+		cons:     "mem=512M",
+		expected: docker.StartParams{Memory: 512},
+		hardware: "arch=amd64 mem=512M",
+	}, {
+		cons:     "cpu-cores=1",
+		expected: docker.StartParams{CpuShares: 1024, CpuSet: "0"},
+		hardware: "arch=amd64 cpu-cores=1",
+	}, {
+		cons:     "cpu-cores=16",
+		expected: docker.StartParams{CpuShares: 4096, CpuSet: "0-3"},
+		hardware: "arch=amd64 cpu-cores=4",
+	}, {
+		cons:     "cpu-cores=2 cpu-power=50",
+		expected: docker.StartParams{CpuShares: 512, CpuSet: "0-1"},
+		hardware: "arch=amd64 cpu-cores=2 cpu-power=50",
+	}} {
+		c.Logf("test %d: %s", i, test.cons)
+		arch := "amd64"
+		hardware := &instance.HardwareCharacteristics{Arch: &arch}
+		params := docker.ParseConstraintsToStartParams(constraints.MustParse(test.cons), hardware)
+		c.Check(params, gc.DeepEquals, test.expected)
+		c.Check(hardware.String(), gc.Equals, test.hardware)
+	}
+}
+
+type ContainerSuite struct {
+	coretesting.BaseSuite
+	commands [][]string
+}
+
+var _ = gc.Suite(&ContainerSuite{})
+
+func (s *ContainerSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.commands = nil
+	s.PatchValue(docker.RunDocker, func(args ...string) (string, error) {
+		s.commands = append(s.commands, args)
+		return "", nil
+	})
+}
+
+func (s *ContainerSuite) TestStart(c *gc.C) {
+	err := docker.DockerObjectFactory.New("juju-machine-1-docker-0").Start(docker.StartParams{
+		Image:          "ubuntu:trusty",
+		Network:        container.BridgeNetworkConfig(docker.DefaultDockerBridge),
+		UserDataDir:    "/var/lib/juju/containers/juju-machine-1-docker-0",
+		UserDataScript: "cloud-init.sh",
+		LogDir:         "/var/log/juju",
+		Memory:         1024,
+		CpuShares:      2048,
+		CpuSet:         "0-1",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.commands, gc.HasLen, 1)
+	c.Assert(strings.Join(s.commands[0], " "), gc.Equals, "run --detach"+
+		" --name juju-machine-1-docker-0 --hostname juju-machine-1-docker-0"+
+		" --restart always --privileged"+
+		" --volume /var/lib/juju/containers/juju-machine-1-docker-0:/var/lib/juju/container-init:ro"+
+		" --volume /var/log/juju:/var/log/juju"+
+		" --memory=1024m --cpu-shares=2048 --cpuset=0-1"+
+		" ubuntu:trusty /bin/bash -c"+
+		" /bin/bash /var/lib/juju/container-init/cloud-init.sh > /var/log/cloud-init-output.log 2>&1; exec /sbin/init")
+}
+
+func (s *ContainerSuite) TestStartOtherBridge(c *gc.C) {
+	err := docker.DockerObjectFactory.New("juju-machine-1-docker-0").Start(docker.StartParams{
+		Network: container.BridgeNetworkConfig("lxcbr0"),
+	})
+	c.Assert(err, gc.ErrorMatches, `docker containers can only use the "docker0" bridge`)
+	c.Assert(s.commands, gc.HasLen, 0)
+}
+
+func (s *ContainerSuite) TestCPUSet(c *gc.C) {
+	cpuset, err := docker.DockerObjectFactory.New("juju-machine-1-docker-0").CPUSet()
+	c.Assert(err, gc.IsNil)
+	c.Assert(cpuset, gc.Equals, "")
+	c.Assert(s.commands, gc.DeepEquals, [][]string{
+		{"inspect", "--format", "{{.Config.Cpuset}}", "juju-machine-1-docker-0"},
+	})
+}
+
+func (s *ContainerSuite) TestStop(c *gc.C) {
+	err := docker.DockerObjectFactory.New("juju-machine-1-docker-0").Stop()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.commands, gc.DeepEquals, [][]string{{"rm", "--force", "juju-machine-1-docker-0"}})
+}
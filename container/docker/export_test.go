@@ -0,0 +1,13 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker
+
+// This file exports internal package implementations so that tests
+// can utilize them to mock behavior.
+
+var (
+	HostCPUs         = &hostCPUs
+	RunDocker        = &runDocker
+	PackageCandidate = &packageCandidate
+)
@@ -0,0 +1,30 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker
+
+import (
+	"github.com/juju/utils/apt"
+
+	"github.com/juju/juju/container"
+)
+
+var requiredPackages = []string{
+	"docker.io",
+}
+
+type containerInitialiser struct{}
+
+// containerInitialiser implements container.Initialiser.
+var _ container.Initialiser = (*containerInitialiser)(nil)
+
+// NewContainerInitialiser returns an instance used to perform the steps
+// required to allow a host machine to run a docker container.
+func NewContainerInitialiser() container.Initialiser {
+	return &containerInitialiser{}
+}
+
+// Initialise is specified on the container.Initialiser interface.
+func (ci *containerInitialiser) Initialise() error {
+	return apt.GetInstall(requiredPackages...)
+}
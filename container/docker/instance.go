@@ -0,0 +1,62 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+)
+
+type dockerInstance struct {
+	container Container
+	id        string
+}
+
+var _ instance.Instance = (*dockerInstance)(nil)
+
+// Id implements instance.Instance.Id.
+func (inst *dockerInstance) Id() instance.Id {
+	return instance.Id(inst.id)
+}
+
+// Status implements instance.Instance.Status.
+func (inst *dockerInstance) Status() string {
+	if inst.container.IsRunning() {
+		return "running"
+	}
+	return "stopped"
+}
+
+func (*dockerInstance) Refresh() error {
+	return nil
+}
+
+// Addresses implements instance.Instance.Addresses.
+func (inst *dockerInstance) Addresses() ([]network.Address, error) {
+	return nil, errors.NotImplementedf("dockerInstance.Addresses")
+}
+
+// OpenPorts implements instance.Instance.OpenPorts.
+func (inst *dockerInstance) OpenPorts(machineId string, ports []network.Port) error {
+	return fmt.Errorf("not implemented")
+}
+
+// ClosePorts implements instance.Instance.ClosePorts.
+func (inst *dockerInstance) ClosePorts(machineId string, ports []network.Port) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Ports implements instance.Instance.Ports.
+func (inst *dockerInstance) Ports(machineId string) ([]network.Port, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// Add a string representation of the id.
+func (inst *dockerInstance) String() string {
+	return fmt.Sprintf("docker:%s", inst.id)
+}
@@ -0,0 +1,61 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package docker
+
+import (
+	"github.com/juju/juju/container"
+)
+
+// StartParams is a simple parameter struct for Container.Start.
+type StartParams struct {
+	Image   string
+	Network *container.NetworkConfig
+	// UserDataDir holds the user data script, and is mounted
+	// read-only into the container.
+	UserDataDir    string
+	UserDataScript string
+	LogDir         string
+	Memory         uint64 // MB
+	CpuShares      uint64
+	// CpuSet holds the CPUs the container is pinned to, in the
+	// kernel's cpuset format; if empty it may run on any CPU.
+	CpuSet string
+}
+
+// Container represents a docker container instance and provides
+// operations to create, maintain and destroy the container.
+type Container interface {
+
+	// Name returns the name of the container.
+	Name() string
+
+	// Start creates and runs the container as a daemon.
+	Start(params StartParams) error
+
+	// Stop terminates and removes the container.
+	Stop() error
+
+	// IsRunning returns whether or not the container is running and active.
+	IsRunning() bool
+
+	// CPUSet returns the CPUs the container is pinned to, in the
+	// kernel's cpuset format, or an empty string if it may run on
+	// any CPU.
+	CPUSet() (string, error)
+
+	// String returns information about the container, like the name, state,
+	// and process id.
+	String() string
+}
+
+// ContainerFactory represents the methods used to create Containers.  This
+// wraps the low level docker commands for dealing with the containers.
+type ContainerFactory interface {
+	// New returns a container instance which can then be used for operations
+	// like Start() and Stop()
+	New(string) Container
+
+	// List returns all the existing containers on the system.
+	List() ([]Container, error)
+}
@@ -0,0 +1,158 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package mock
+
+import (
+	"fmt"
+
+	"github.com/juju/juju/container/docker"
+)
+
+// This file provides a mock implementation of the docker interfaces
+// ContainerFactory and Container.
+
+type Action int
+
+const (
+	// A container has been started.
+	Started Action = iota
+	// A container has been stopped.
+	Stopped
+)
+
+func (action Action) String() string {
+	switch action {
+	case Started:
+		return "Started"
+	case Stopped:
+		return "Stopped"
+	}
+	return "unknown"
+}
+
+type Event struct {
+	Action     Action
+	InstanceId string
+}
+
+type ContainerFactory interface {
+	docker.ContainerFactory
+
+	AddListener(chan<- Event)
+	RemoveListener(chan<- Event)
+	HasListener(chan<- Event) bool
+}
+
+type mockFactory struct {
+	instances map[string]docker.Container
+	listeners []chan<- Event
+}
+
+func MockFactory() ContainerFactory {
+	return &mockFactory{
+		instances: make(map[string]docker.Container),
+	}
+}
+
+type mockContainer struct {
+	factory *mockFactory
+	name    string
+	started bool
+	cpuset  string
+}
+
+// Name returns the name of the container.
+func (mock *mockContainer) Name() string {
+	return mock.name
+}
+
+func (mock *mockContainer) Start(params docker.StartParams) error {
+	if mock.started {
+		return fmt.Errorf("container is already running")
+	}
+	mock.started = true
+	mock.cpuset = params.CpuSet
+	mock.factory.notify(Started, mock.name)
+	return nil
+}
+
+// Stop terminates the running container.
+func (mock *mockContainer) Stop() error {
+	if !mock.started {
+		return fmt.Errorf("container is not running")
+	}
+	mock.started = false
+	// Stopping a container removes it, freeing its CPUs.
+	mock.cpuset = ""
+	mock.factory.notify(Stopped, mock.name)
+	return nil
+}
+
+func (mock *mockContainer) IsRunning() bool {
+	return mock.started
+}
+
+// CPUSet returns the CPUs the container was started on.
+func (mock *mockContainer) CPUSet() (string, error) {
+	return mock.cpuset, nil
+}
+
+// String returns information about the container.
+func (mock *mockContainer) String() string {
+	return fmt.Sprintf("<MockContainer %q>", mock.name)
+}
+
+func (mock *mockFactory) String() string {
+	return fmt.Sprintf("<Mock Docker Factory>")
+}
+
+func (mock *mockFactory) New(name string) docker.Container {
+	container, ok := mock.instances[name]
+	if ok {
+		return container
+	}
+	container = &mockContainer{
+		factory: mock,
+		name:    name,
+	}
+	mock.instances[name] = container
+	return container
+}
+
+func (mock *mockFactory) List() (result []docker.Container, err error) {
+	for _, container := range mock.instances {
+		result = append(result, container)
+	}
+	return
+}
+
+func (mock *mockFactory) notify(action Action, instanceId string) {
+	event := Event{action, instanceId}
+	for _, c := range mock.listeners {
+		c <- event
+	}
+}
+
+func (mock *mockFactory) AddListener(listener chan<- Event) {
+	mock.listeners = append(mock.listeners, listener)
+}
+
+func (mock *mockFactory) RemoveListener(listener chan<- Event) {
+	pos := 0
+	for i, c := range mock.listeners {
+		if c == listener {
+			pos = i
+		}
+	}
+	mock.listeners = append(mock.listeners[:pos], mock.listeners[pos+1:]...)
+}
+
+func (mock *mockFactory) HasListener(listener chan<- Event) bool {
+	for _, c := range mock.listeners {
+		if c == listener {
+			return true
+		}
+	}
+	return false
+}
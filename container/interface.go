@@ -4,6 +4,7 @@
 package container
 
 import (
+	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/environs/cloudinit"
 	"github.com/juju/juju/instance"
 )
@@ -21,11 +22,14 @@ type ManagerConfig map[string]string
 // containers that it has started.
 type Manager interface {
 	// CreateContainer creates and starts a new container for the specified
-	// machine.
+	// machine. The constraints are applied as resource limits on the
+	// container, and the returned hardware characteristics reflect the
+	// limits actually applied.
 	CreateContainer(
 		machineConfig *cloudinit.MachineConfig,
 		series string,
-		network *NetworkConfig) (instance.Instance, *instance.HardwareCharacteristics, error)
+		network *NetworkConfig,
+		cons constraints.Value) (instance.Instance, *instance.HardwareCharacteristics, error)
 
 	// DestroyContainer stops and destroyes the container identified by
 	// instance id.
@@ -27,8 +27,9 @@ func (c *kvmContainer) Name() string {
 }
 
 func (c *kvmContainer) Start(params StartParams) error {
-	logger.Debugf("Synchronise images for %s %s", params.Series, params.Arch)
-	if err := SyncImages(params.Series, params.Arch); err != nil {
+	logger.Debugf("Ensure cached image for %s %s", params.Series, params.Arch)
+	image, err := EnsureCachedImage(params.Series, params.Arch, params.ImageMetadataURL)
+	if err != nil {
 		return err
 	}
 	var bridge string
@@ -49,6 +50,7 @@ func (c *kvmContainer) Start(params StartParams) error {
 		Memory:        params.Memory,
 		CpuCores:      params.CpuCores,
 		RootDisk:      params.RootDisk,
+		BackingImage:  image,
 	}); err != nil {
 		return err
 	}
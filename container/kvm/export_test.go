@@ -3,4 +3,8 @@ package kvm
 // This file exports internal package implementations so that tests
 // can utilize them to mock behavior.
 
-var KVMPath = &kvmPath
+var (
+	KVMPath           = &kvmPath
+	BackingFiles      = &backingFiles
+	ParseBackingFiles = parseBackingFiles
+)
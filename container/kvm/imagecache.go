@@ -0,0 +1,464 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package kvm
+
+// This file maintains a per-host cache of Ubuntu cloud images, one
+// for each series and arch, so that new guests can be created as
+// copy-on-write clones of a local image rather than downloading and
+// converting the image every time.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"github.com/juju/utils/fslock"
+)
+
+var (
+	// ImageCacheDir holds the cached images.
+	ImageCacheDir = "/var/lib/juju/kvm/images"
+
+	// ImageStreamURL is the simplestreams product file that lists the
+	// downloadable cloud images. Image paths within it are relative to
+	// the part of the URL before "streams/".
+	ImageStreamURL = "https://cloud-images.ubuntu.com/releases/" + imageStreamPath
+
+	// ImageRefreshInterval is how long a cached image is used before
+	// the image metadata is checked again for a newer one.
+	ImageRefreshInterval = 24 * time.Hour
+
+	// GuestImageDir holds the disk images of the guests, which are
+	// copy-on-write clones of the cached images.
+	GuestImageDir = "/var/lib/uvtool/libvirt/images"
+
+	// PartialDownloadExpiry is how long an image download may go
+	// without being written to before CleanImageCache removes it.
+	PartialDownloadExpiry = time.Hour
+
+	// ImageFetchTimeout bounds connecting to the image server and each
+	// wait for data from it, so a stalled transfer fails rather than
+	// hanging the provisioner.
+	ImageFetchTimeout = time.Minute
+)
+
+// imageStreamPath is the path of the simplestreams product file listing
+// the downloadable cloud images, relative to the image metadata URL.
+const imageStreamPath = "streams/v1/com.ubuntu.cloud:released:download.json"
+
+// imageHTTPClient is used to fetch image metadata and images. Proxies
+// are taken from the environment, which the machine environment worker
+// keeps in line with the environment's proxy settings.
+var imageHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.DialTimeout(network, addr, ImageFetchTimeout)
+			if err != nil {
+				return nil, err
+			}
+			return &timeoutConn{conn}, nil
+		},
+		ResponseHeaderTimeout: ImageFetchTimeout,
+	},
+}
+
+// timeoutConn is a net.Conn whose reads fail if no data arrives within
+// ImageFetchTimeout.
+type timeoutConn struct {
+	net.Conn
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(ImageFetchTimeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// imageFileType is the simplestreams item type of the qcow2 disk image.
+const imageFileType = "disk1.img"
+
+// CachedImage describes an image in the cache.
+type CachedImage struct {
+	Series  string    `json:"series"`
+	Arch    string    `json:"arch"`
+	Version string    `json:"version"`
+	Path    string    `json:"path"`
+	Checked time.Time `json:"checked"`
+}
+
+// Stale reports whether the image metadata should be checked for a
+// newer image.
+func (image *CachedImage) Stale() bool {
+	return time.Since(image.Checked) > ImageRefreshInterval
+}
+
+// imageProducts holds the parts of the simplestreams download product
+// file that are relevant to the cache.
+type imageProducts struct {
+	Products map[string]struct {
+		Release  string `json:"release"`
+		Arch     string `json:"arch"`
+		Versions map[string]struct {
+			Items map[string]struct {
+				FileType string `json:"ftype"`
+				Path     string `json:"path"`
+				Sha256   string `json:"sha256"`
+			} `json:"items"`
+		} `json:"versions"`
+	} `json:"products"`
+}
+
+// imageDownload describes the newest available image for a series
+// and arch.
+type imageDownload struct {
+	version string
+	url     string
+	sha256  string
+}
+
+func cacheKey(series, arch string) string {
+	return fmt.Sprintf("%s-%s", series, arch)
+}
+
+func indexFilename(series, arch string) string {
+	return filepath.Join(ImageCacheDir, cacheKey(series, arch)+".json")
+}
+
+func imageFilename(series, arch, version string) string {
+	return filepath.Join(ImageCacheDir, fmt.Sprintf("%s-%s.img", cacheKey(series, arch), version))
+}
+
+// imageStreamURL returns the URL of the simplestreams product file
+// listing the downloadable cloud images under the given image metadata
+// URL, or ImageStreamURL if it is empty.
+func imageStreamURL(metadataURL string) string {
+	if metadataURL == "" {
+		return ImageStreamURL
+	}
+	return strings.TrimSuffix(metadataURL, "/") + "/" + imageStreamPath
+}
+
+// EnsureCachedImage returns the path of the cached image for the given
+// series and arch, downloading it first if it is not cached, or if the
+// image metadata lists a newer image than the one cached. If
+// metadataURL is not empty, the image metadata is read from the
+// simplestreams data under it rather than from ImageStreamURL.
+//
+// The cache lock is only held while the cache index is read and
+// updated, so a slow download does not hold up guests of the same
+// series and arch whose image is already cached.
+func EnsureCachedImage(series, arch, metadataURL string) (string, error) {
+	if err := os.MkdirAll(ImageCacheDir, 0755); err != nil {
+		return "", errors.Annotate(err, "cannot create image cache directory")
+	}
+	lock, err := fslock.NewLock(filepath.Join(ImageCacheDir, "locks"), cacheKey(series, arch))
+	if err != nil {
+		return "", errors.Annotate(err, "cannot create image cache lock")
+	}
+	if err := lock.Lock("read image cache"); err != nil {
+		return "", errors.Annotate(err, "cannot lock image cache")
+	}
+	cached, err := readCachedImage(series, arch)
+	lock.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if cached != nil && !cached.Stale() {
+		return cached.Path, nil
+	}
+	download, err := findImage(imageStreamURL(metadataURL), series, arch)
+	if err != nil {
+		if cached != nil {
+			// A stale image is better than no image.
+			logger.Warningf("cannot refresh %s image, using cached version %s: %v", cacheKey(series, arch), cached.Version, err)
+			return cached.Path, nil
+		}
+		return "", err
+	}
+	var partial string
+	if cached == nil || cached.Version != download.version {
+		logger.Infof("downloading %s image version %s", cacheKey(series, arch), download.version)
+		if partial, err = downloadImage(download, imageFilename(series, arch, download.version)); err != nil {
+			return "", err
+		}
+		// Once renamed into place, the partial file no longer exists.
+		defer os.Remove(partial)
+	}
+
+	if err := lock.Lock("update image cache"); err != nil {
+		return "", errors.Annotate(err, "cannot lock image cache")
+	}
+	defer lock.Unlock()
+	// The cache may have been updated while the lock was released.
+	current, err := readCachedImage(series, arch)
+	if err != nil {
+		return "", err
+	}
+	if current == nil || current.Version != download.version {
+		if partial == "" {
+			return "", errors.Errorf("%s image version %s was removed from the cache", cacheKey(series, arch), download.version)
+		}
+		path := imageFilename(series, arch, download.version)
+		if err := os.Rename(partial, path); err != nil {
+			return "", err
+		}
+		if current != nil {
+			if err := removeUnusedImage(current.Path); err != nil {
+				logger.Warningf("cannot remove superseded image %s: %v", current.Path, err)
+			}
+		}
+		current = &CachedImage{
+			Series:  series,
+			Arch:    arch,
+			Version: download.version,
+			Path:    path,
+		}
+	}
+	current.Checked = time.Now()
+	if err := writeCachedImage(current); err != nil {
+		return "", err
+	}
+	return current.Path, nil
+}
+
+// ImageCacheStatus returns the images in the cache.
+func ImageCacheStatus() ([]CachedImage, error) {
+	indexes, err := filepath.Glob(filepath.Join(ImageCacheDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var images []CachedImage
+	for _, index := range indexes {
+		image, err := readIndex(index)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+// CleanImageCache removes files in the cache that are not the current
+// image for any series and arch, such as superseded images and
+// abandoned downloads. Images that still back the disk of a guest are
+// kept until the guest is destroyed, as are downloads that are still
+// being written.
+func CleanImageCache() error {
+	images, err := ImageCacheStatus()
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool)
+	for _, image := range images {
+		current[image.Path] = true
+	}
+	files, err := filepath.Glob(filepath.Join(ImageCacheDir, "*.img*"))
+	if err != nil {
+		return err
+	}
+	inUse, err := backingFiles()
+	if err != nil {
+		return errors.Annotate(err, "cannot determine the images used by guests")
+	}
+	for _, file := range files {
+		if current[file] || inUse[file] {
+			continue
+		}
+		if strings.Contains(filepath.Base(file), ".partial") {
+			info, err := os.Stat(file)
+			if err != nil || time.Since(info.ModTime()) < PartialDownloadExpiry {
+				continue
+			}
+		}
+		logger.Infof("removing unused image %s", file)
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUnusedImage removes the image at path unless it backs the
+// disk of a guest, in which case it is left for CleanImageCache.
+func removeUnusedImage(path string) error {
+	inUse, err := backingFiles()
+	if err != nil {
+		return errors.Annotate(err, "cannot determine the images used by guests")
+	}
+	if inUse[path] {
+		logger.Infof("keeping superseded image %s while guests use it", path)
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// backingFiles returns the set of files in the backing chains of the
+// guest disk images. It is a variable so it can be overridden in tests.
+var backingFiles = func() (map[string]bool, error) {
+	disks, err := filepath.Glob(filepath.Join(GuestImageDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, disk := range disks {
+		output, err := run("qemu-img", "info", "--backing-chain", disk)
+		if err != nil {
+			return nil, errors.Annotatef(err, "cannot read backing chain of %s", disk)
+		}
+		for _, file := range parseBackingFiles(output) {
+			files[file] = true
+		}
+	}
+	return files, nil
+}
+
+// parseBackingFiles returns the backing files listed in the output
+// of "qemu-img info --backing-chain".
+func parseBackingFiles(output string) []string {
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "backing file:") {
+			continue
+		}
+		file := strings.TrimSpace(strings.TrimPrefix(line, "backing file:"))
+		// Relative backing files are followed by the path they
+		// resolve to.
+		const actualPath = " (actual path: "
+		if i := strings.Index(file, actualPath); i >= 0 {
+			file = strings.TrimSuffix(file[i+len(actualPath):], ")")
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func readCachedImage(series, arch string) (*CachedImage, error) {
+	image, err := readIndex(indexFilename(series, arch))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(image.Path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return image, nil
+}
+
+func readIndex(filename string) (*CachedImage, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var image CachedImage
+	if err := json.Unmarshal(data, &image); err != nil {
+		return nil, errors.Annotatef(err, "cannot parse image cache index %s", filename)
+	}
+	return &image, nil
+}
+
+func writeCachedImage(image *CachedImage) error {
+	data, err := json.Marshal(image)
+	if err != nil {
+		return err
+	}
+	return utils.AtomicWriteFile(indexFilename(image.Series, image.Arch), data, 0644)
+}
+
+// findImage returns the newest image for the series and arch listed
+// in the image metadata.
+func findImage(streamURL, series, arch string) (*imageDownload, error) {
+	resp, err := imageHTTPClient.Get(streamURL)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot fetch image metadata")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("cannot fetch image metadata: %s", resp.Status)
+	}
+	var products imageProducts
+	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+		return nil, errors.Annotate(err, "cannot parse image metadata")
+	}
+	baseURL := streamURL
+	if i := strings.Index(baseURL, "streams/"); i >= 0 {
+		baseURL = baseURL[:i]
+	}
+	for _, product := range products.Products {
+		if product.Release != series || product.Arch != arch {
+			continue
+		}
+		versions := make([]string, 0, len(product.Versions))
+		for version := range product.Versions {
+			versions = append(versions, version)
+		}
+		// Versions are date serials, so the newest sorts last.
+		sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+		for _, version := range versions {
+			for _, item := range product.Versions[version].Items {
+				if item.FileType == imageFileType {
+					return &imageDownload{
+						version: version,
+						url:     baseURL + item.Path,
+						sha256:  item.Sha256,
+					}, nil
+				}
+			}
+		}
+	}
+	return nil, errors.NotFoundf("%s image for %s", arch, series)
+}
+
+// downloadImage fetches the image to a new partial file alongside
+// path, verifying its checksum, and returns the name of the file.
+func downloadImage(download *imageDownload, path string) (partial string, err error) {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".partial")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+	resp, err := imageHTTPClient.Get(download.url)
+	if err != nil {
+		file.Close()
+		return "", errors.Annotate(err, "cannot download image")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		file.Close()
+		return "", errors.Errorf("cannot download image: %s", resp.Status)
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Annotate(err, "cannot download image")
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); download.sha256 != "" && sum != download.sha256 {
+		return "", errors.Errorf("image checksum mismatch: expected %s, got %s", download.sha256, sum)
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package kvm_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/container/kvm"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type ImageCacheSuite struct {
+	coretesting.BaseSuite
+	server    *httptest.Server
+	version   string
+	images    map[string]string
+	downloads int
+	inUse     map[string]bool
+}
+
+var _ = gc.Suite(&ImageCacheSuite{})
+
+func (s *ImageCacheSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.version = "20140927"
+	s.images = map[string]string{
+		"20140927": "image one",
+		"20141016": "image two",
+	}
+	s.downloads = 0
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.PatchValue(&kvm.ImageCacheDir, c.MkDir())
+	s.PatchValue(&kvm.ImageStreamURL, s.server.URL+"/releases/streams/v1/com.ubuntu.cloud:released:download.json")
+	s.inUse = make(map[string]bool)
+	s.PatchValue(kvm.BackingFiles, func() (map[string]bool, error) {
+		return s.inUse, nil
+	})
+}
+
+func (s *ImageCacheSuite) TearDownTest(c *gc.C) {
+	s.server.Close()
+	s.BaseSuite.TearDownTest(c)
+}
+
+func (s *ImageCacheSuite) serve(w http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "/releases/streams/v1/com.ubuntu.cloud:released:download.json":
+		sum := sha256.Sum256([]byte(s.images[s.version]))
+		fmt.Fprintf(w, `{"products": {"com.ubuntu.cloud:server:14.04:amd64": {
+			"release": "trusty", "arch": "amd64",
+			"versions": {"%s": {"items": {
+				"tar.gz": {"ftype": "tar.gz", "path": "trusty/%s/trusty-server-cloudimg-amd64.tar.gz"},
+				"disk1.img": {"ftype": "disk1.img", "path": "trusty/%s/trusty-server-cloudimg-amd64-disk1.img", "sha256": "%s"}
+			}}}
+		}}}`, s.version, s.version, s.version, hex.EncodeToString(sum[:]))
+	case fmt.Sprintf("/releases/trusty/%s/trusty-server-cloudimg-amd64-disk1.img", s.version):
+		s.downloads++
+		fmt.Fprint(w, s.images[s.version])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (s *ImageCacheSuite) assertImage(c *gc.C, path, content string) {
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(data), gc.Equals, content)
+}
+
+func (s *ImageCacheSuite) TestEnsureCachedImageDownloadsOnce(c *gc.C) {
+	path, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(path, gc.Equals, filepath.Join(kvm.ImageCacheDir, "trusty-amd64-20140927.img"))
+	s.assertImage(c, path, "image one")
+
+	again, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(again, gc.Equals, path)
+	c.Assert(s.downloads, gc.Equals, 1)
+
+	images, err := kvm.ImageCacheStatus()
+	c.Assert(err, gc.IsNil)
+	c.Assert(images, gc.HasLen, 1)
+	c.Assert(images[0].Series, gc.Equals, "trusty")
+	c.Assert(images[0].Arch, gc.Equals, "amd64")
+	c.Assert(images[0].Version, gc.Equals, "20140927")
+}
+
+func (s *ImageCacheSuite) TestEnsureCachedImageRefreshes(c *gc.C) {
+	old, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+
+	// A new image is only noticed once the cached one is stale.
+	s.version = "20141016"
+	path, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(path, gc.Equals, old)
+
+	s.PatchValue(&kvm.ImageRefreshInterval, time.Duration(0))
+	path, err = kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(path, gc.Equals, filepath.Join(kvm.ImageCacheDir, "trusty-amd64-20141016.img"))
+	s.assertImage(c, path, "image two")
+	c.Assert(old, jc.DoesNotExist)
+	c.Assert(s.downloads, gc.Equals, 2)
+}
+
+func (s *ImageCacheSuite) TestEnsureCachedImageKeepsImageInUse(c *gc.C) {
+	old, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	s.inUse[old] = true
+
+	s.version = "20141016"
+	s.PatchValue(&kvm.ImageRefreshInterval, time.Duration(0))
+	path, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(path, gc.Not(gc.Equals), old)
+	s.assertImage(c, old, "image one")
+
+	// The superseded image is only removed once no guest uses it.
+	err = kvm.CleanImageCache()
+	c.Assert(err, gc.IsNil)
+	s.assertImage(c, old, "image one")
+	delete(s.inUse, old)
+	err = kvm.CleanImageCache()
+	c.Assert(err, gc.IsNil)
+	c.Assert(old, jc.DoesNotExist)
+	s.assertImage(c, path, "image two")
+}
+
+func (s *ImageCacheSuite) TestEnsureCachedImageUsesMetadataURL(c *gc.C) {
+	s.PatchValue(&kvm.ImageStreamURL, "http://0.1.2.3/unused")
+	path, err := kvm.EnsureCachedImage("trusty", "amd64", s.server.URL+"/releases/")
+	c.Assert(err, gc.IsNil)
+	s.assertImage(c, path, "image one")
+}
+
+func (s *ImageCacheSuite) TestEnsureCachedImageUsesStaleImageOnError(c *gc.C) {
+	path, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+
+	s.PatchValue(&kvm.ImageRefreshInterval, time.Duration(0))
+	s.server.Close()
+	again, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(again, gc.Equals, path)
+}
+
+func (s *ImageCacheSuite) TestEnsureCachedImageNotFound(c *gc.C) {
+	_, err := kvm.EnsureCachedImage("precise", "amd64", "")
+	c.Assert(err, gc.ErrorMatches, "amd64 image for precise not found")
+}
+
+func (s *ImageCacheSuite) TestEnsureCachedImageChecksumMismatch(c *gc.C) {
+	s.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if filepath.Ext(req.URL.Path) == ".img" {
+			fmt.Fprint(w, "something else")
+			return
+		}
+		s.serve(w, req)
+	})
+	_, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.ErrorMatches, "image checksum mismatch: .*")
+	files, err := filepath.Glob(filepath.Join(kvm.ImageCacheDir, "*.img*"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(files, gc.HasLen, 0)
+}
+
+func (s *ImageCacheSuite) TestCleanImageCache(c *gc.C) {
+	path, err := kvm.EnsureCachedImage("trusty", "amd64", "")
+	c.Assert(err, gc.IsNil)
+	stray := filepath.Join(kvm.ImageCacheDir, "precise-amd64-20140101.img")
+	partial := filepath.Join(kvm.ImageCacheDir, "trusty-amd64-20141016.img.partial123")
+	downloading := filepath.Join(kvm.ImageCacheDir, "trusty-amd64-20141016.img.partial456")
+	for _, file := range []string{stray, partial, downloading} {
+		err := ioutil.WriteFile(file, []byte("junk"), 0644)
+		c.Assert(err, gc.IsNil)
+	}
+	abandoned := time.Now().Add(-2 * kvm.PartialDownloadExpiry)
+	err = os.Chtimes(partial, abandoned, abandoned)
+	c.Assert(err, gc.IsNil)
+
+	err = kvm.CleanImageCache()
+	c.Assert(err, gc.IsNil)
+	c.Assert(path, jc.IsNonEmptyFile)
+	c.Assert(downloading, jc.IsNonEmptyFile)
+	for _, file := range []string{stray, partial} {
+		_, err := os.Stat(file)
+		c.Assert(os.IsNotExist(err), jc.IsTrue)
+	}
+}
+
+func (s *ImageCacheSuite) TestParseBackingFiles(c *gc.C) {
+	output := `image: /var/lib/uvtool/libvirt/images/juju-machine-1-kvm-0.qcow
+file format: qcow2
+virtual size: 8.0G (8589934592 bytes)
+backing file: /var/lib/juju/kvm/images/trusty-amd64-20140927.img
+
+image: /var/lib/juju/kvm/images/trusty-amd64-20140927.img
+file format: qcow2
+backing file: base.img (actual path: /var/lib/juju/kvm/images/base.img)
+
+image: /var/lib/juju/kvm/images/base.img
+file format: qcow2
+`
+	c.Assert(kvm.ParseBackingFiles(output), gc.DeepEquals, []string{
+		"/var/lib/juju/kvm/images/trusty-amd64-20140927.img",
+		"/var/lib/juju/kvm/images/base.img",
+	})
+}
@@ -31,7 +31,23 @@ func NewContainerInitialiser() container.Initialiser {
 
 // Initialise is specified on the container.Initialiser interface.
 func (ci *containerInitialiser) Initialise() error {
-	return ensureDependencies()
+	if err := ensureDependencies(); err != nil {
+		return err
+	}
+	// A problem with the image cache is not fatal; images are
+	// fetched again as guests need them.
+	if err := CleanImageCache(); err != nil {
+		logger.Warningf("cannot clean image cache: %v", err)
+	}
+	images, err := ImageCacheStatus()
+	if err != nil {
+		logger.Warningf("cannot read image cache: %v", err)
+	}
+	for _, image := range images {
+		logger.Infof("cached image for %s %s: version %s, last checked %v",
+			image.Series, image.Arch, image.Version, image.Checked)
+	}
+	return nil
 }
 
 func ensureDependencies() error {
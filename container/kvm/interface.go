@@ -16,6 +16,9 @@ type StartParams struct {
 	Memory       uint64 // MB
 	CpuCores     uint64
 	RootDisk     uint64 // GB
+	// ImageMetadataURL, if set, is where the guest image metadata
+	// is read from instead of the Ubuntu cloud images site.
+	ImageMetadataURL string
 }
 
 // Container represents a virtualized container instance and provides
@@ -41,6 +41,10 @@ var (
 	MinDisk   uint64 = 2 // GB
 )
 
+// ConfigImageMetadataURL is the container manager config key for the
+// image metadata URL guest images are found with.
+const ConfigImageMetadataURL = "image-metadata-url"
+
 // Utilized to provide a hard-coded path to kvm-ok
 var kvmPath = "/usr/sbin"
 
@@ -82,16 +86,22 @@ func NewContainerManager(conf container.ManagerConfig) (container.Manager, error
 	if logDir == "" {
 		logDir = agent.DefaultLogDir
 	}
+	imageMetadataURL := conf.PopValue(ConfigImageMetadataURL)
 	conf.WarnAboutUnused()
-	return &containerManager{name: name, logdir: logDir}, nil
+	return &containerManager{
+		name:             name,
+		logdir:           logDir,
+		imageMetadataURL: imageMetadataURL,
+	}, nil
 }
 
 // containerManager handles all of the business logic at the juju specific
 // level. It makes sure that the necessary directories are in place, that the
 // user-data is written out in the right place.
 type containerManager struct {
-	name   string
-	logdir string
+	name             string
+	logdir           string
+	imageMetadataURL string
 }
 
 var _ container.Manager = (*containerManager)(nil)
@@ -99,7 +109,8 @@ var _ container.Manager = (*containerManager)(nil)
 func (manager *containerManager) CreateContainer(
 	machineConfig *cloudinit.MachineConfig,
 	series string,
-	network *container.NetworkConfig) (instance.Instance, *instance.HardwareCharacteristics, error) {
+	network *container.NetworkConfig,
+	cons constraints.Value) (instance.Instance, *instance.HardwareCharacteristics, error) {
 
 	name := names.NewMachineTag(machineConfig.MachineId).String()
 	if manager.name != "" {
@@ -116,16 +127,17 @@ func (manager *containerManager) CreateContainer(
 		return nil, nil, fmt.Errorf("failed to create container directory: %v", err)
 	}
 	logger.Tracef("write cloud-init")
-	userDataFilename, err := container.WriteUserData(machineConfig, directory)
+	userDataFilename, err := container.WriteUserData(machineConfig, network, directory)
 	if err != nil {
 		return nil, nil, errors.LoggedErrorf(logger, "failed to write user data: %v", err)
 	}
 	// Create the container.
-	startParams := ParseConstraintsToStartParams(machineConfig.Constraints)
+	startParams := ParseConstraintsToStartParams(cons)
 	startParams.Arch = version.Current.Arch
 	startParams.Series = series
 	startParams.Network = network
 	startParams.UserDataFile = userDataFilename
+	startParams.ImageMetadataURL = manager.imageMetadataURL
 
 	var hardware instance.HardwareCharacteristics
 	hardware, err = instance.ParseHardware(
@@ -135,7 +147,7 @@ func (manager *containerManager) CreateContainer(
 		logger.Warningf("failed to parse hardware: %v", err)
 	}
 
-	logger.Tracef("create the container, constraints: %v", machineConfig.Constraints)
+	logger.Tracef("create the container, constraints: %v", cons)
 	if err := kvmContainer.Start(startParams); err != nil {
 		return nil, nil, errors.LoggedErrorf(logger, "kvm container creation failed: %v", err)
 	}
@@ -96,6 +96,14 @@ func (s *KVMSuite) TestCreateContainer(c *gc.C) {
 	containertesting.AssertCloudInit(c, cloudInitFilename)
 }
 
+func (s *KVMSuite) TestCreateContainerWithConstraints(c *gc.C) {
+	cons := constraints.MustParse("mem=2G cpu-cores=2 root-disk=10G")
+	_, hardware := containertesting.CreateContainerWithConstraints(c, s.manager, "1/kvm/0", cons)
+	c.Assert(*hardware.Mem, gc.Equals, uint64(2048))
+	c.Assert(*hardware.CpuCores, gc.Equals, uint64(2))
+	c.Assert(*hardware.RootDisk, gc.Equals, uint64(10*1024))
+}
+
 func (s *KVMSuite) TestDestroyContainer(c *gc.C) {
 	instance := containertesting.CreateContainer(c, s.manager, "1/lxc/0")
 
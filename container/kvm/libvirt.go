@@ -4,7 +4,6 @@
 package kvm
 
 // This file contains wrappers around the following executables:
-//   uvt-simplestreams-libvirt
 //   uvt-kvm
 //   virsh
 // Those executables are found in the following packages:
@@ -38,18 +37,6 @@ func run(command string, args ...string) (output string, err error) {
 	return output, err
 }
 
-// SyncImages updates the local cached images by reading the simplestreams
-// data and downloading the cloud images to the uvtool pool (used by libvirt).
-func SyncImages(series string, arch string) error {
-	args := []string{
-		"sync",
-		fmt.Sprintf("arch=%s", arch),
-		fmt.Sprintf("release=%s", series),
-	}
-	_, err := run("uvt-simplestreams-libvirt", args...)
-	return err
-}
-
 type CreateMachineParams struct {
 	Hostname      string
 	Series        string
@@ -59,6 +46,9 @@ type CreateMachineParams struct {
 	Memory        uint64
 	CpuCores      uint64
 	RootDisk      uint64
+	// BackingImage, if set, is the image the guest's disk is created
+	// as a copy-on-write clone of, instead of one from the uvtool pool.
+	BackingImage string
 }
 
 // CreateMachine creates a virtual machine and starts it.
@@ -85,6 +75,9 @@ func CreateMachine(params CreateMachineParams) error {
 	if params.RootDisk != 0 {
 		args = append(args, "--disk", fmt.Sprint(params.RootDisk))
 	}
+	if params.BackingImage != "" {
+		args = append(args, "--backing-image-file", params.BackingImage)
+	}
 	// TODO add memory, cpu and disk prior to hostname
 	args = append(args, params.Hostname)
 	if params.Series != "" {
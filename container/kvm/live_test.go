@@ -95,7 +95,7 @@ func createContainer(c *gc.C, manager container.Manager, machineId string) insta
 	err := environs.FinishMachineConfig(machineConfig, environConfig, constraints.Value{})
 	c.Assert(err, gc.IsNil)
 
-	inst, hardware, err := manager.CreateContainer(machineConfig, "precise", network)
+	inst, hardware, err := manager.CreateContainer(machineConfig, "precise", network, constraints.Value{})
 	c.Assert(err, gc.IsNil)
 	c.Assert(hardware, gc.NotNil)
 	expected := fmt.Sprintf("arch=%s cpu-cores=1 mem=512M root-disk=8192M", version.Current.Arch)
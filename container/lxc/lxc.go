@@ -6,11 +6,14 @@ package lxc
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/loggo"
@@ -19,6 +22,7 @@ import (
 	"launchpad.net/golxc"
 
 	"github.com/juju/juju/agent"
+	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/environs/cloudinit"
 	"github.com/juju/juju/instance"
@@ -143,6 +147,7 @@ func (manager *containerManager) CreateContainer(
 	machineConfig *cloudinit.MachineConfig,
 	series string,
 	network *container.NetworkConfig,
+	cons constraints.Value,
 ) (instance.Instance, *instance.HardwareCharacteristics, error) {
 	start := time.Now()
 	name := names.NewMachineTag(machineConfig.MachineId).String()
@@ -159,7 +164,7 @@ func (manager *containerManager) CreateContainer(
 		// If we are using clone, disable the apt-get steps
 		machineConfig.DisablePackageCommands = true
 	}
-	userDataFilename, err := container.WriteUserData(machineConfig, directory)
+	userDataFilename, err := container.WriteUserData(machineConfig, network, directory)
 	if err != nil {
 		logger.Errorf("failed to write user data: %v", err)
 		return nil, nil, err
@@ -231,6 +236,14 @@ func (manager *containerManager) CreateContainer(
 	if err := mountHostLogDir(name, manager.logdir); err != nil {
 		return nil, nil, err
 	}
+	arch := version.Current.Arch
+	hardware := &instance.HardwareCharacteristics{
+		Arch: &arch,
+	}
+	if err := applyResourceLimits(name, cons, hardware); err != nil {
+		logger.Errorf("failed to apply resource limits: %v", err)
+		return nil, nil, err
+	}
 	// Start the lxc container with the appropriate settings for grabbing the
 	// console output and a log file.
 	consoleFile := filepath.Join(directory, "console.log")
@@ -244,10 +257,6 @@ func (manager *containerManager) CreateContainer(
 		logger.Errorf("container failed to start: %v", err)
 		return nil, nil, err
 	}
-	arch := version.Current.Arch
-	hardware := &instance.HardwareCharacteristics{
-		Arch: &arch,
-	}
 	logger.Tracef("container %q started: %v", name, time.Now().Sub(start))
 	return &lxcInstance{lxcContainer, name}, hardware, nil
 }
@@ -263,6 +272,115 @@ func appendToContainerConfig(name, line string) error {
 	return err
 }
 
+// hostCPUs returns the number of CPUs on the host; it is a variable
+// so it can be overridden in tests.
+var hostCPUs = runtime.NumCPU
+
+// cpusetKey is the container config key that pins a container to
+// particular CPUs.
+const cpusetKey = "lxc.cgroup.cpuset.cpus"
+
+// cpuAllocationMutex is held while CPUs are chosen for a container
+// until they are recorded in its config, so that containers created
+// at the same time are not pinned to the same CPUs.
+var cpuAllocationMutex sync.Mutex
+
+// applyResourceLimits appends the settings that apply the constraints
+// as resource limits to the named container's config, and records the
+// limits applied in hardware.
+func applyResourceLimits(name string, cons constraints.Value, hardware *instance.HardwareCharacteristics) error {
+	cpuAllocationMutex.Lock()
+	defer cpuAllocationMutex.Unlock()
+	limits := resourceLimits(cons, hardware)
+	if limits == "" {
+		return nil
+	}
+	logger.Tracef("apply resource limits for constraints: %v", cons)
+	return appendToContainerConfig(name, limits)
+}
+
+// hostCPUUsage returns the number of containers on the host pinned to
+// each CPU, from the cpuset settings in their configs.
+func hostCPUUsage() map[int]int {
+	usage := make(map[int]int)
+	dirs, err := ioutil.ReadDir(LxcContainerDir)
+	if err != nil {
+		logger.Warningf("cannot read containers to find their CPUs: %v", err)
+		return usage
+	}
+	for _, dir := range dirs {
+		data, err := ioutil.ReadFile(containerConfigFilename(dir.Name()))
+		if err != nil {
+			// Not every directory holds a container.
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) != cpusetKey {
+				continue
+			}
+			cpus, err := container.ParseCPUSet(strings.TrimSpace(parts[1]))
+			if err != nil {
+				logger.Warningf("ignoring CPUs of container %q: %v", dir.Name(), err)
+				continue
+			}
+			for _, cpu := range cpus {
+				usage[cpu]++
+			}
+		}
+	}
+	return usage
+}
+
+// resourceLimits returns the lxc.cgroup settings that apply the mem,
+// cpu-cores and cpu-power constraints as resource limits on a
+// container, and records the limits applied in hardware. For cpu-cores
+// the container is pinned to as many of the host's CPUs as requested,
+// up to the number it has, choosing those least used by the containers
+// already pinned. The container's CPU shares
+// are scaled from the default of 1024 per core, or from cpu-power
+// where given.
+func resourceLimits(cons constraints.Value, hardware *instance.HardwareCharacteristics) string {
+	var lines []string
+	if cons.Mem != nil && *cons.Mem > 0 {
+		mem := *cons.Mem
+		lines = append(lines, fmt.Sprintf("lxc.cgroup.memory.limit_in_bytes = %dM", mem))
+		hardware.Mem = &mem
+	}
+	var shares uint64
+	if cons.CpuCores != nil && *cons.CpuCores > 0 {
+		cores := *cons.CpuCores
+		if available := uint64(hostCPUs()); cores > available {
+			logger.Warningf("cpu-cores constraint of %d exceeds the %d host CPUs", cores, available)
+			cores = available
+		}
+		cpus := container.AllocateCPUs(int(cores), hostCPUs(), hostCPUUsage())
+		lines = append(lines, fmt.Sprintf("%s = %s", cpusetKey, container.FormatCPUSet(cpus)))
+		hardware.CpuCores = &cores
+		shares = cores * 1024
+	}
+	if cons.CpuPower != nil && *cons.CpuPower > 0 {
+		power := *cons.CpuPower
+		// cpu-power is measured in hundredths of a core.
+		shares = power * 1024 / 100
+		if shares < 2 {
+			// The kernel's minimum.
+			shares = 2
+		}
+		hardware.CpuPower = &power
+	}
+	if shares > 0 {
+		lines = append(lines, fmt.Sprintf("lxc.cgroup.cpu.shares = %d", shares))
+	}
+	if cons.RootDisk != nil {
+		logger.Infof("root-disk constraint of %vM being ignored as not supported", *cons.RootDisk)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
 func autostartContainer(name string) error {
 	// Now symlink the config file into the restart directory, if it exists.
 	// This is for backwards compatiblity. From Trusty onwards, the auto start
@@ -380,10 +498,27 @@ func generateNetworkConfig(network *container.NetworkConfig) string {
 	case container.BridgeNetwork:
 		lxcConfig = networkConfigTemplate("veth", network.Device)
 	}
+	if network.StaticAddress != nil {
+		lxcConfig += staticAddressConfig(network.StaticAddress)
+	}
 
 	return lxcConfig
 }
 
+// staticAddressConfig returns the lxc.network settings that configure
+// the static address on the container's interface.
+func staticAddressConfig(address *container.StaticAddress) string {
+	family := "ipv4"
+	if ip, _, err := net.ParseCIDR(address.Address); err == nil && ip.To4() == nil {
+		family = "ipv6"
+	}
+	config := fmt.Sprintf("lxc.network.%s = %s\n", family, address.Address)
+	if address.Gateway != "" {
+		config += fmt.Sprintf("lxc.network.%s.gateway = %s\n", family, address.Gateway)
+	}
+	return config
+}
+
 func writeLxcConfig(network *container.NetworkConfig, directory string) (string, error) {
 	networkConfig := generateNetworkConfig(network)
 	configFilename := filepath.Join(directory, "lxc.conf")
@@ -21,6 +21,7 @@ import (
 	"launchpad.net/goyaml"
 
 	"github.com/juju/juju/agent"
+	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/container/lxc"
 	"github.com/juju/juju/container/lxc/mock"
@@ -217,6 +218,74 @@ func (s *LxcSuite) TestCreateContainer(c *gc.C) {
 	c.Assert(location, gc.Equals, expectedTarget)
 }
 
+func (s *LxcSuite) TestCreateContainerWithConstraints(c *gc.C) {
+	s.PatchValue(lxc.HostCPUs, func() int { return 2 })
+	manager := s.makeManager(c, "test")
+	cons := constraints.MustParse("mem=1G cpu-cores=4 cpu-power=50")
+	instance, hardware := containertesting.CreateContainerWithConstraints(c, manager, "1/lxc/0", cons)
+
+	config, err := ioutil.ReadFile(lxc.ContainerConfigFilename(string(instance.Id())))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(config), jc.Contains, "lxc.cgroup.memory.limit_in_bytes = 1024M\n")
+	c.Assert(string(config), jc.Contains, "lxc.cgroup.cpuset.cpus = 0-1\n")
+	c.Assert(string(config), jc.Contains, "lxc.cgroup.cpu.shares = 512\n")
+
+	// The hardware reflects the limits applied, not those asked for.
+	c.Assert(*hardware.Mem, gc.Equals, uint64(1024))
+	c.Assert(*hardware.CpuCores, gc.Equals, uint64(2))
+	c.Assert(*hardware.CpuPower, gc.Equals, uint64(50))
+}
+
+func (s *LxcSuite) TestCreateContainerWithCpuCores(c *gc.C) {
+	s.PatchValue(lxc.HostCPUs, func() int { return 4 })
+	manager := s.makeManager(c, "test")
+	cons := constraints.MustParse("cpu-cores=2")
+	instance, hardware := containertesting.CreateContainerWithConstraints(c, manager, "1/lxc/0", cons)
+
+	config, err := ioutil.ReadFile(lxc.ContainerConfigFilename(string(instance.Id())))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(config), jc.Contains, "lxc.cgroup.cpu.shares = 2048\n")
+	c.Assert(string(config), jc.Contains, "lxc.cgroup.cpuset.cpus = 0-1\n")
+	c.Assert(*hardware.CpuCores, gc.Equals, uint64(2))
+}
+
+func (s *LxcSuite) TestCreateContainersSpreadAcrossCPUs(c *gc.C) {
+	s.PatchValue(lxc.HostCPUs, func() int { return 4 })
+	manager := s.makeManager(c, "test")
+	cpuset := func(machineId, cons string) string {
+		instance, _ := containertesting.CreateContainerWithConstraints(c, manager, machineId, constraints.MustParse(cons))
+		config, err := ioutil.ReadFile(lxc.ContainerConfigFilename(string(instance.Id())))
+		c.Assert(err, gc.IsNil)
+		for _, line := range strings.Split(string(config), "\n") {
+			if strings.HasPrefix(line, "lxc.cgroup.cpuset.cpus = ") {
+				return strings.TrimPrefix(line, "lxc.cgroup.cpuset.cpus = ")
+			}
+		}
+		c.Fatalf("container %q is not pinned to any CPUs", instance.Id())
+		return ""
+	}
+	// Each container is pinned to the CPUs least used by those before it.
+	c.Assert(cpuset("1/lxc/0", "cpu-cores=2"), gc.Equals, "0-1")
+	c.Assert(cpuset("1/lxc/1", "cpu-cores=1"), gc.Equals, "2")
+	c.Assert(cpuset("1/lxc/2", "cpu-cores=2"), gc.Equals, "0,3")
+
+	// The CPUs of a destroyed container are used again.
+	err := os.RemoveAll(filepath.Join(s.LxcDir, "test-machine-1-lxc-1"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(cpuset("1/lxc/3", "cpu-cores=1"), gc.Equals, "2")
+}
+
+func (s *LxcSuite) TestCreateContainerWithoutConstraints(c *gc.C) {
+	manager := s.makeManager(c, "test")
+	instance, hardware := containertesting.CreateContainerWithConstraints(c, manager, "1/lxc/0", constraints.Value{})
+
+	config, err := ioutil.ReadFile(lxc.ContainerConfigFilename(string(instance.Id())))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(config), gc.Not(jc.Contains), "lxc.cgroup")
+	c.Assert(hardware.Mem, gc.IsNil)
+	c.Assert(hardware.CpuCores, gc.IsNil)
+}
+
 func (s *LxcSuite) ensureTemplateStopped(name string) <-chan struct{} {
 	ch := make(chan struct{}, 1)
 	go func() {
@@ -14,16 +14,42 @@ const (
 type NetworkConfig struct {
 	NetworkType string
 	Device      string
+
+	// StaticAddress, if set, is configured on the container's network
+	// interface in place of an address obtained by DHCP.
+	StaticAddress *StaticAddress
+}
+
+// StaticAddress holds the settings of a fixed address for a container,
+// such as one allocated by the provider.
+type StaticAddress struct {
+	// Address is the address with the prefix length of its network,
+	// in 123.45.67.89/24 format.
+	Address string
+
+	// Gateway is the address of the network's default gateway, if any.
+	Gateway string
+
+	// DNSServers holds the addresses of the nameservers the container
+	// resolves names with.
+	DNSServers []string
 }
 
 // BridgeNetworkConfig returns a valid NetworkConfig to use the specified
 // device as a network bridge for the container.
 func BridgeNetworkConfig(device string) *NetworkConfig {
-	return &NetworkConfig{BridgeNetwork, device}
+	return &NetworkConfig{NetworkType: BridgeNetwork, Device: device}
+}
+
+// StaticBridgeNetworkConfig returns a valid NetworkConfig to use the
+// specified device as a network bridge for the container, with the
+// given static address configured on the container.
+func StaticBridgeNetworkConfig(device string, address *StaticAddress) *NetworkConfig {
+	return &NetworkConfig{NetworkType: BridgeNetwork, Device: device, StaticAddress: address}
 }
 
 // PhysicalNetworkConfig returns a valid NetworkConfig to use the specified
 // device as the network device for the container.
 func PhysicalNetworkConfig(device string) *NetworkConfig {
-	return &NetworkConfig{PhysicalNetwork, device}
+	return &NetworkConfig{NetworkType: PhysicalNetwork, Device: device}
 }
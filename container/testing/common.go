@@ -9,6 +9,7 @@ import (
 	jc "github.com/juju/testing/checkers"
 	gc "launchpad.net/gocheck"
 
+	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/instance"
@@ -18,6 +19,15 @@ import (
 )
 
 func CreateContainer(c *gc.C, manager container.Manager, machineId string) instance.Instance {
+	inst, _ := CreateContainerWithConstraints(c, manager, machineId, constraints.Value{})
+	return inst
+}
+
+// CreateContainerWithConstraints creates a container with the given
+// constraints, returning the instance and its hardware characteristics.
+func CreateContainerWithConstraints(
+	c *gc.C, manager container.Manager, machineId string, cons constraints.Value,
+) (instance.Instance, *instance.HardwareCharacteristics) {
 	stateInfo := jujutesting.FakeStateInfo(machineId)
 	apiInfo := jujutesting.FakeAPIInfo(machineId)
 	machineConfig := environs.NewMachineConfig(machineId, "fake-nonce", nil, stateInfo, apiInfo)
@@ -28,11 +38,11 @@ func CreateContainer(c *gc.C, manager container.Manager, machineId string) insta
 
 	series := "series"
 	network := container.BridgeNetworkConfig("nic42")
-	inst, hardware, err := manager.CreateContainer(machineConfig, series, network)
+	inst, hardware, err := manager.CreateContainer(machineConfig, series, network, cons)
 	c.Assert(err, gc.IsNil)
 	c.Assert(hardware, gc.NotNil)
 	c.Assert(hardware.String(), gc.Not(gc.Equals), "")
-	return inst
+	return inst, hardware
 }
 
 func AssertCloudInit(c *gc.C, filename string) []byte {
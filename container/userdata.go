@@ -4,12 +4,16 @@
 package container
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/juju/loggo"
+	"github.com/juju/utils"
 
 	coreCloudinit "github.com/juju/juju/cloudinit"
+	"github.com/juju/juju/cloudinit/sshinit"
 	"github.com/juju/juju/environs/cloudinit"
 )
 
@@ -17,11 +21,11 @@ var (
 	logger = loggo.GetLogger("juju.container")
 )
 
-// WriteUserData generates the cloud init for the specified machine config,
-// and writes the serialized form out to a cloud-init file in the directory
-// specified.
-func WriteUserData(machineConfig *cloudinit.MachineConfig, directory string) (string, error) {
-	userData, err := cloudInitUserData(machineConfig)
+// WriteUserData generates the cloud init for the specified machine config
+// and network config, and writes the serialized form out to a cloud-init
+// file in the directory specified.
+func WriteUserData(machineConfig *cloudinit.MachineConfig, networkConfig *NetworkConfig, directory string) (string, error) {
+	userData, err := cloudInitUserData(machineConfig, networkConfig)
 	if err != nil {
 		logger.Errorf("failed to create user data: %v", err)
 		return "", err
@@ -29,6 +33,30 @@ func WriteUserData(machineConfig *cloudinit.MachineConfig, directory string) (st
 	return WriteCloudInitFile(directory, userData)
 }
 
+// WriteUserDataScript generates the cloud init for the specified machine
+// config and network config, renders it as a shell script that carries
+// out the same steps, and writes the script out to the directory
+// specified. It is used for containers whose images do not run
+// cloud-init.
+func WriteUserDataScript(machineConfig *cloudinit.MachineConfig, networkConfig *NetworkConfig, directory string) (string, error) {
+	cloudConfig, err := cloudInitConfig(machineConfig, networkConfig)
+	if err != nil {
+		logger.Errorf("failed to create user data: %v", err)
+		return "", err
+	}
+	script, err := sshinit.ConfigureScript(cloudConfig)
+	if err != nil {
+		logger.Errorf("failed to create user data script: %v", err)
+		return "", err
+	}
+	scriptFilename := filepath.Join(directory, "cloud-init.sh")
+	if err := ioutil.WriteFile(scriptFilename, []byte(script), 0755); err != nil {
+		logger.Errorf("failed to write user data script: %v", err)
+		return "", err
+	}
+	return scriptFilename, nil
+}
+
 // WriteCloudInitFile writes the data out to a cloud-init file in the
 // directory specified, and returns the filename.
 func WriteCloudInitFile(directory string, userData []byte) (string, error) {
@@ -40,8 +68,42 @@ func WriteCloudInitFile(directory string, userData []byte) (string, error) {
 	return userDataFilename, nil
 }
 
-func cloudInitUserData(machineConfig *cloudinit.MachineConfig) ([]byte, error) {
+// staticInterfacesTemplate is the /etc/network/interfaces written for
+// containers with a static address.
+const staticInterfacesTemplate = `auto lo
+iface lo inet loopback
+
+auto eth0
+iface eth0 inet static
+    address %s
+`
+
+// staticNetworkScript returns a script that replaces the container's
+// DHCP configuration of eth0 with the static address.
+func staticNetworkScript(address *StaticAddress) string {
+	interfaces := fmt.Sprintf(staticInterfacesTemplate, address.Address)
+	if address.Gateway != "" {
+		interfaces += fmt.Sprintf("    gateway %s\n", address.Gateway)
+	}
+	// Without DHCP, resolvconf only learns the nameservers from here.
+	if len(address.DNSServers) > 0 {
+		interfaces += fmt.Sprintf("    dns-nameservers %s\n", strings.Join(address.DNSServers, " "))
+	}
+	return strings.Join([]string{
+		"ifdown eth0",
+		"rm -f /etc/network/interfaces.d/eth0.cfg",
+		fmt.Sprintf("printf '%%s' %s > /etc/network/interfaces", utils.ShQuote(interfaces)),
+		"ifup eth0",
+	}, "\n")
+}
+
+func cloudInitConfig(machineConfig *cloudinit.MachineConfig, networkConfig *NetworkConfig) (*coreCloudinit.Config, error) {
 	cloudConfig := coreCloudinit.New()
+	if networkConfig != nil && networkConfig.StaticAddress != nil {
+		// Boot commands run before the agent is installed, so
+		// the agent starts with the static address in place.
+		cloudConfig.AddBootCmd(staticNetworkScript(networkConfig.StaticAddress))
+	}
 	err := cloudinit.Configure(machineConfig, cloudConfig)
 	if err != nil {
 		return nil, err
@@ -50,7 +112,14 @@ func cloudInitUserData(machineConfig *cloudinit.MachineConfig) ([]byte, error) {
 	// Run ifconfig to get the addresses of the internal container at least
 	// logged in the host.
 	cloudConfig.AddRunCmd("ifconfig")
+	return cloudConfig, nil
+}
 
+func cloudInitUserData(machineConfig *cloudinit.MachineConfig, networkConfig *NetworkConfig) ([]byte, error) {
+	cloudConfig, err := cloudInitConfig(machineConfig, networkConfig)
+	if err != nil {
+		return nil, err
+	}
 	data, err := cloudConfig.Render()
 	if err != nil {
 		return nil, err
@@ -30,6 +30,21 @@ type StartInstanceParams struct {
 	// instance should be started.
 	Placement string
 
+	// AvailabilityZone, if non-empty and Placement is empty, holds
+	// the availability zone in which the instance should be started.
+	// It is set when a previous attempt to start the instance failed
+	// for lack of capacity in another zone.
+	//
+	// Only the ec2 and openstack providers honour AvailabilityZone
+	// and ExcludedInstanceTypes, and only they return a CapacityError
+	// that makes the provisioner retry; other providers ignore both.
+	AvailabilityZone string
+
+	// ExcludedInstanceTypes holds the names of instance types that
+	// must not be chosen for the instance, because previous attempts
+	// to start instances of those types failed for lack of capacity.
+	ExcludedInstanceTypes []string
+
 	// DistributionGroup, if non-nil, is a function
 	// that returns a slice of instance.Ids that belong
 	// to the same distribution group as the machine
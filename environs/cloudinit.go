@@ -5,6 +5,7 @@ package environs
 
 import (
 	"fmt"
+	"net"
 	"path"
 
 	"github.com/juju/errors"
@@ -127,6 +128,9 @@ func FinishMachineConfig(mcfg *cloudinit.MachineConfig, cfg *config.Config, cons
 	); err != nil {
 		return err
 	}
+	if cfg.DNSService() {
+		mcfg.DNSServers = dnsServers(mcfg)
+	}
 
 	// The following settings are only appropriate at bootstrap time. At the
 	// moment, the only state server is the bootstrap node, but this
@@ -150,7 +154,9 @@ func FinishMachineConfig(mcfg *cloudinit.MachineConfig, cfg *config.Config, cons
 	mcfg.MongoInfo = &authentication.MongoInfo{Password: passwordHash, Info: mongo.Info{CACert: caCert}}
 
 	// These really are directly relevant to running a state server.
-	cert, key, err := cfg.GenerateStateServerCertAndKey()
+	// The state server's own addresses are not known before its
+	// instance starts, so only the loopback addresses are included.
+	cert, key, err := cfg.GenerateStateServerCertAndKey(nil)
 	if err != nil {
 		return errors.Annotate(err, "cannot generate state server certificate")
 	}
@@ -171,6 +177,33 @@ func FinishMachineConfig(mcfg *cloudinit.MachineConfig, cfg *config.Config, cons
 	return nil
 }
 
+// dnsServers returns the addresses of the DNS servers the machine
+// should use to resolve names within the environment. The bootstrap
+// machine runs its own; other machines use the state servers they
+// connect to.
+func dnsServers(mcfg *cloudinit.MachineConfig) []string {
+	if mcfg.Bootstrap {
+		return []string{"127.0.0.1"}
+	}
+	if mcfg.APIInfo == nil {
+		return nil
+	}
+	var servers []string
+	seen := make(map[string]bool)
+	for _, hostPort := range mcfg.APIInfo.Addrs {
+		host, _, err := net.SplitHostPort(hostPort)
+		if err != nil || net.ParseIP(host) == nil {
+			// The resolver only accepts IP addresses.
+			continue
+		}
+		if !seen[host] {
+			seen[host] = true
+			servers = append(servers, host)
+		}
+	}
+	return servers
+}
+
 func configureCloudinit(mcfg *cloudinit.MachineConfig, cloudcfg *coreCloudinit.Config) error {
 	// When bootstrapping, we only want to apt-get update/upgrade
 	// and setup the SSH keys. The rest we leave to cloudinit/sshinit.
@@ -151,6 +151,12 @@ type MachineConfig struct {
 	// and when set IPv6 addresses for connecting to the API/state
 	// servers will be preferred over IPv4 ones.
 	PreferIPv6 bool
+
+	// DNSServers holds the addresses of the state servers running
+	// the environment's DNS service, which the machine will use to
+	// resolve unit and machine names. It is empty when the
+	// dns-service environment setting is off.
+	DNSServers []string
 }
 
 func base64yaml(m *config.Config) string {
@@ -278,6 +284,10 @@ func ConfigureJuju(cfg *MachineConfig, c *cloudinit.Config) error {
 				shquote(cfg.ProxySettings.AsScriptEnvironment())))
 	}
 
+	if len(cfg.DNSServers) > 0 {
+		c.AddScripts(dnsConfigScripts(cfg.DNSServers)...)
+	}
+
 	// Make the lock dir and change the ownership of the lock dir itself to
 	// ubuntu:ubuntu from root:root so the juju-run command run as the ubuntu
 	// user is able to get access to the hook execution lock (like the uniter
@@ -368,6 +378,52 @@ func ConfigureJuju(cfg *MachineConfig, c *cloudinit.Config) error {
 	return cfg.addMachineAgentToBoot(c, machineTag.String(), cfg.MachineId)
 }
 
+// maxDNSServers limits the number of juju DNS servers written to the
+// resolver configuration. The resolver only uses the first three
+// nameservers, and one must remain for names outside the environment.
+const maxDNSServers = 2
+
+// ResolvconfHeadFile holds nameserver entries that resolvconf places
+// before any others in /etc/resolv.conf.
+const ResolvconfHeadFile = "/etc/resolvconf/resolv.conf.d/head"
+
+// DNSBlockBegin and DNSBlockEnd delimit the nameserver entries juju
+// writes to ResolvconfHeadFile, so that they can be replaced without
+// touching the rest of the file.
+const (
+	DNSBlockBegin = "# begin juju dns-service"
+	DNSBlockEnd   = "# end juju dns-service"
+)
+
+// DNSResolverBlock returns the lines, delimited by DNSBlockBegin and
+// DNSBlockEnd, that make the resolver use the given DNS servers.
+func DNSResolverBlock(servers []string) []string {
+	if len(servers) > maxDNSServers {
+		servers = servers[:maxDNSServers]
+	}
+	lines := []string{DNSBlockBegin}
+	for _, server := range servers {
+		lines = append(lines, "nameserver "+server)
+	}
+	return append(lines, DNSBlockEnd)
+}
+
+// dnsConfigScripts returns the commands that make the machine's
+// resolver use the given DNS servers before any others. Any block
+// written previously is replaced, so the commands may be run again.
+func dnsConfigScripts(servers []string) []string {
+	var quoted []string
+	for _, line := range DNSResolverBlock(servers) {
+		quoted = append(quoted, shquote(line))
+	}
+	return []string{
+		fmt.Sprintf("[ -d %s ] && touch %s && sed -i '/^%s$/,/^%s$/d' %s && printf '%%s\\n' %s >> %s && resolvconf -u",
+			path.Dir(ResolvconfHeadFile), ResolvconfHeadFile,
+			DNSBlockBegin, DNSBlockEnd, ResolvconfHeadFile,
+			strings.Join(quoted, " "), ResolvconfHeadFile),
+	}
+}
+
 func (cfg *MachineConfig) dataFile(name string) string {
 	return path.Join(cfg.DataDir, name)
 }
@@ -883,6 +883,55 @@ export NO_PROXY=localhost,10.0.3.1' > /home/ubuntu/.juju-proxy && chown ubuntu:u
 	c.Assert(found, jc.IsTrue)
 }
 
+func (s *cloudinitSuite) TestDNSServersWritten(c *gc.C) {
+	environConfig := minimalConfig(c)
+	environConfig, err := environConfig.Apply(map[string]interface{}{
+		"dns-service": true,
+	})
+	c.Assert(err, gc.IsNil)
+	machineCfg := s.createMachineConfig(c, environConfig)
+	c.Assert(machineCfg.DNSServers, gc.DeepEquals, []string{"0.1.2.3"})
+	cloudcfg := coreCloudinit.New()
+	err = cloudinit.Configure(machineCfg, cloudcfg)
+	c.Assert(err, gc.IsNil)
+
+	expected := `[ -d /etc/resolvconf/resolv.conf.d ] && touch /etc/resolvconf/resolv.conf.d/head && ` +
+		`sed -i '/^# begin juju dns-service$/,/^# end juju dns-service$/d' /etc/resolvconf/resolv.conf.d/head && ` +
+		`printf '%s\n' '# begin juju dns-service' 'nameserver 0.1.2.3' '# end juju dns-service' >> /etc/resolvconf/resolv.conf.d/head && ` +
+		`resolvconf -u`
+	found := false
+	for _, cmd := range cloudcfg.RunCmds() {
+		if cmd == expected {
+			found = true
+			break
+		}
+	}
+	c.Assert(found, jc.IsTrue)
+}
+
+func (s *cloudinitSuite) TestDNSResolverBlock(c *gc.C) {
+	block := cloudinit.DNSResolverBlock([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+	c.Assert(block, gc.DeepEquals, []string{
+		"# begin juju dns-service",
+		"nameserver 10.0.0.1",
+		"nameserver 10.0.0.2",
+		"# end juju dns-service",
+	})
+}
+
+func (s *cloudinitSuite) TestDNSServersNotWrittenIfNotSet(c *gc.C) {
+	environConfig := minimalConfig(c)
+	machineCfg := s.createMachineConfig(c, environConfig)
+	c.Assert(machineCfg.DNSServers, gc.HasLen, 0)
+	cloudcfg := coreCloudinit.New()
+	err := cloudinit.Configure(machineCfg, cloudcfg)
+	c.Assert(err, gc.IsNil)
+
+	for _, cmd := range cloudcfg.RunCmds() {
+		c.Assert(cmd.(string), gc.Not(jc.Contains), "resolvconf")
+	}
+}
+
 var serverCert = []byte(`
 SERVER CERT
 -----BEGIN CERTIFICATE-----
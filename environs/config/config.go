@@ -6,6 +6,7 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -36,6 +37,11 @@ const (
 	// port opened.
 	FwGlobal = "global"
 
+	// FwHost requests that each machine agent manages the firewall of
+	// its own host with iptables, instead of relying on the provider.
+	// It is intended for providers without security groups.
+	FwHost = "host"
+
 	// DefaultStatePort is the default port the state server is listening on.
 	DefaultStatePort int = 37017
 
@@ -59,6 +65,25 @@ const (
 	// refresh addresses from the provider each time.
 	DefaultBootstrapSSHAddressesDelay int = 10
 
+	// DefaultHookRetryDelay is the amount of time to wait before
+	// automatically retrying a failed hook for the first time, in
+	// seconds. The delay doubles with each attempt.
+	DefaultHookRetryDelay int = 10
+
+	// DefaultProvisionerConcurrency is the number of instances the
+	// provisioner starts at once.
+	DefaultProvisionerConcurrency int = 4
+
+	// DefaultStartInstanceAttempts is the number of times the
+	// provisioner attempts to start an instance for a machine, in other
+	// availability zones or with other instance types, when the
+	// provider lacks the capacity to start it.
+	DefaultStartInstanceAttempts int = 3
+
+	// DefaultSpareMachines is the number of spare machines kept for
+	// each constraints profile.
+	DefaultSpareMachines int = 0
+
 	// fallbackLtsSeries is the latest LTS series we'll use, if we fail to
 	// obtain this information from the system.
 	fallbackLtsSeries string = "precise"
@@ -323,10 +348,39 @@ func Validate(cfg, old *Config) error {
 	}
 
 	// Check firewall mode.
-	if mode := cfg.FirewallMode(); mode != FwInstance && mode != FwGlobal {
+	if mode := cfg.FirewallMode(); mode != FwInstance && mode != FwGlobal && mode != FwHost {
 		return fmt.Errorf("invalid firewall mode in environment configuration: %q", mode)
 	}
 
+	if v, ok := cfg.defined["provisioner-harvest-mode"].(string); ok && v != "" {
+		if _, err := ParseHarvestMode(v); err != nil {
+			return fmt.Errorf("provisioner-harvest-mode: %v", err)
+		}
+	}
+
+	// Check hook retry settings.
+	if v, ok := cfg.defined["hook-retry-attempts"].(int); ok && v < 0 {
+		return fmt.Errorf("hook-retry-attempts: expected non-negative number, got %d", v)
+	}
+	if v, ok := cfg.defined["hook-retry-delay"].(int); ok && v <= 0 {
+		return fmt.Errorf("hook-retry-delay: expected positive number, got %d", v)
+	}
+	if v, ok := cfg.defined["hook-timeout"].(int); ok && v < 0 {
+		return fmt.Errorf("hook-timeout: expected non-negative number, got %d", v)
+	}
+
+	if v, ok := cfg.defined["provisioner-concurrency"].(int); ok && v <= 0 {
+		return fmt.Errorf("provisioner-concurrency: expected positive number, got %d", v)
+	}
+
+	if v, ok := cfg.defined["start-instance-attempts"].(int); ok && v <= 0 {
+		return fmt.Errorf("start-instance-attempts: expected positive number, got %d", v)
+	}
+
+	if v, ok := cfg.defined["spare-machines"].(int); ok && v < 0 {
+		return fmt.Errorf("spare-machines: expected non-negative number, got %d", v)
+	}
+
 	caCert, caCertOK := cfg.CACert()
 	caKey, caKeyOK := cfg.CAPrivateKey()
 	if caCertOK || caKeyOK {
@@ -621,6 +675,56 @@ func (c *Config) BootstrapSSHOpts() SSHTimeoutOpts {
 	return opts
 }
 
+// HookRetryPolicy holds how failed hooks are automatically retried.
+type HookRetryPolicy struct {
+	// Attempts is the maximum number of times a failed hook is
+	// retried. No retries are made if it is zero.
+	Attempts int
+
+	// Delay is the time to wait before the first retry; it
+	// doubles with each attempt.
+	Delay time.Duration
+}
+
+// HookRetryPolicy returns how failed hooks are automatically retried.
+// By default they are not.
+func (c *Config) HookRetryPolicy() HookRetryPolicy {
+	policy := HookRetryPolicy{
+		Delay: time.Duration(DefaultHookRetryDelay) * time.Second,
+	}
+	if v, ok := c.defined["hook-retry-attempts"].(int); ok {
+		policy.Attempts = v
+	}
+	if v, ok := c.defined["hook-retry-delay"].(int); ok && v != 0 {
+		policy.Delay = time.Duration(v) * time.Second
+	}
+	return policy
+}
+
+// HookTimeout returns the maximum time a hook may run for before it
+// is killed and considered failed. Zero means hooks never time out,
+// which is the default.
+func (c *Config) HookTimeout() time.Duration {
+	if v, ok := c.defined["hook-timeout"].(int); ok {
+		return time.Duration(v) * time.Second
+	}
+	return 0
+}
+
+// CharmAutoRollback returns whether a charm upgrade that fails
+// on any unit is automatically rolled back to the previous charm.
+func (c *Config) CharmAutoRollback() bool {
+	v, _ := c.defined["charm-auto-rollback"].(bool)
+	return v
+}
+
+// HookContextCapture returns whether units record the full context of
+// every hook they run, so the hook can be replayed elsewhere.
+func (c *Config) HookContextCapture() bool {
+	v, _ := c.defined["hook-context-capture"].(bool)
+	return v
+}
+
 // CACert returns the certificate of the CA that signed the state server
 // certificate, in PEM format, and whether the setting is available.
 func (c *Config) CACert() (string, bool) {
@@ -649,8 +753,8 @@ func (c *Config) AdminSecret() string {
 }
 
 // FirewallMode returns whether the firewall should
-// manage ports per machine or global
-// (FwInstance or FwGlobal)
+// manage ports per machine, global or on each host
+// (FwInstance, FwGlobal or FwHost)
 func (c *Config) FirewallMode() string {
 	return c.mustString("firewall-mode")
 }
@@ -699,6 +803,14 @@ func (c *Config) PreferIPv6() bool {
 	return v
 }
 
+// DNSService returns whether the state servers run a DNS
+// service resolving unit and machine names within the
+// environment, and machines are configured to use it.
+func (c *Config) DNSService() bool {
+	v, _ := c.defined["dns-service"].(bool)
+	return v
+}
+
 // SSLHostnameVerification returns weather the environment has requested
 // SSL hostname verification to be enabled.
 func (c *Config) SSLHostnameVerification() bool {
@@ -718,11 +830,59 @@ func (c *Config) CharmStoreAuth() (string, bool) {
 
 // ProvisionerSafeMode reports whether the provisioner should not
 // destroy machines it does not know about.
+//
+// Deprecated: provisioner-safe-mode is superseded by
+// provisioner-harvest-mode; see ProvisionerHarvestMode.
 func (c *Config) ProvisionerSafeMode() bool {
 	v, _ := c.defined["provisioner-safe-mode"].(bool)
 	return v
 }
 
+// ProvisionerHarvestMode returns which instances the provisioner
+// terminates. If provisioner-harvest-mode is not set, it is derived
+// from provisioner-safe-mode: in safe mode only the instances of
+// destroyed machines are terminated.
+func (c *Config) ProvisionerHarvestMode() HarvestMode {
+	if v, ok := c.defined["provisioner-harvest-mode"].(string); ok && v != "" {
+		if mode, err := ParseHarvestMode(v); err == nil {
+			return mode
+		}
+	}
+	if c.ProvisionerSafeMode() {
+		return HarvestDestroyed
+	}
+	return HarvestAll
+}
+
+// ProvisionerConcurrency returns the maximum number of instances the
+// provisioner starts at once.
+func (c *Config) ProvisionerConcurrency() int {
+	if v, ok := c.defined["provisioner-concurrency"].(int); ok && v > 0 {
+		return v
+	}
+	return DefaultProvisionerConcurrency
+}
+
+// StartInstanceAttempts returns the number of times the provisioner
+// attempts to start an instance for a machine when the provider lacks
+// the capacity to start it.
+func (c *Config) StartInstanceAttempts() int {
+	if v, ok := c.defined["start-instance-attempts"].(int); ok && v > 0 {
+		return v
+	}
+	return DefaultStartInstanceAttempts
+}
+
+// SpareMachines returns the number of spare machines, provisioned in
+// advance for units to be assigned to, that are kept for each
+// constraints profile in use in the environment.
+func (c *Config) SpareMachines() int {
+	if v, ok := c.defined["spare-machines"].(int); ok {
+		return v
+	}
+	return DefaultSpareMachines
+}
+
 // ImageStream returns the simplestreams stream
 // used to identify which image ids to search
 // when starting an instance.
@@ -820,6 +980,10 @@ var fields = schema.Fields{
 	"logging-config":            schema.String(),
 	"charm-store-auth":          schema.String(),
 	"provisioner-safe-mode":     schema.Bool(),
+	"provisioner-harvest-mode":  schema.String(),
+	"provisioner-concurrency":   schema.ForceInt(),
+	"start-instance-attempts":   schema.ForceInt(),
+	"spare-machines":            schema.ForceInt(),
 	"http-proxy":                schema.String(),
 	"https-proxy":               schema.String(),
 	"ftp-proxy":                 schema.String(),
@@ -830,11 +994,17 @@ var fields = schema.Fields{
 	"bootstrap-timeout":         schema.ForceInt(),
 	"bootstrap-retry-delay":     schema.ForceInt(),
 	"bootstrap-addresses-delay": schema.ForceInt(),
+	"hook-retry-attempts":       schema.ForceInt(),
+	"hook-retry-delay":          schema.ForceInt(),
+	"hook-timeout":              schema.ForceInt(),
+	"charm-auto-rollback":       schema.Bool(),
+	"hook-context-capture":      schema.Bool(),
 	"test-mode":                 schema.Bool(),
 	"proxy-ssh":                 schema.Bool(),
 	"lxc-clone":                 schema.Bool(),
 	"lxc-clone-aufs":            schema.Bool(),
 	"prefer-ipv6":               schema.Bool(),
+	"dns-service":               schema.Bool(),
 
 	// Deprecated fields, retain for backwards compatibility.
 	"tools-url":     schema.String(),
@@ -858,9 +1028,18 @@ var alwaysOptional = schema.Defaults{
 	"ca-private-key-path":       schema.Omit,
 	"logging-config":            schema.Omit,
 	"provisioner-safe-mode":     schema.Omit,
+	"provisioner-harvest-mode":  schema.Omit,
+	"provisioner-concurrency":   schema.Omit,
+	"start-instance-attempts":   schema.Omit,
+	"spare-machines":            schema.Omit,
 	"bootstrap-timeout":         schema.Omit,
 	"bootstrap-retry-delay":     schema.Omit,
 	"bootstrap-addresses-delay": schema.Omit,
+	"hook-retry-attempts":       schema.Omit,
+	"hook-retry-delay":          schema.Omit,
+	"hook-timeout":              schema.Omit,
+	"charm-auto-rollback":       schema.Omit,
+	"hook-context-capture":      schema.Omit,
 	"rsyslog-ca-cert":           schema.Omit,
 	"http-proxy":                schema.Omit,
 	"https-proxy":               schema.Omit,
@@ -900,6 +1079,7 @@ var alwaysOptional = schema.Defaults{
 	"proxy-ssh":      false,
 	"lxc-clone-aufs": false,
 	"prefer-ipv6":    false,
+	"dns-service":    false,
 
 	// uuid may be missing for backwards compatability.
 	"uuid": schema.Omit,
@@ -927,6 +1107,7 @@ func allDefaults() schema.Defaults {
 		"bootstrap-addresses-delay": DefaultBootstrapSSHAddressesDelay,
 		"proxy-ssh":                 true,
 		"prefer-ipv6":               false,
+		"dns-service":               false,
 	}
 	for attr, val := range alwaysOptional {
 		if _, ok := d[attr]; !ok {
@@ -969,6 +1150,7 @@ var immutableAttributes = []string{
 	"lxc-clone-aufs",
 	"syslog-port",
 	"prefer-ipv6",
+	"dns-service",
 }
 
 var (
@@ -1002,8 +1184,11 @@ func (cfg *Config) ValidateUnknownAttrs(fields schema.Fields, defaults schema.De
 }
 
 // GenerateStateServerCertAndKey makes sure that the config has a CACert and
-// CAPrivateKey, generates and retruns new certificate and key.
-func (cfg *Config) GenerateStateServerCertAndKey() (string, string, error) {
+// CAPrivateKey, generates and retruns new certificate and key. The
+// certificate is valid for the loopback addresses and for the given IP
+// addresses of the state server, so that it can be reached over IPv6
+// as well as IPv4.
+func (cfg *Config) GenerateStateServerCertAndKey(hostAddresses []string) (string, string, error) {
 	caCert, hasCACert := cfg.CACert()
 	if !hasCACert {
 		return "", "", fmt.Errorf("environment configuration has no ca-cert")
@@ -1012,8 +1197,15 @@ func (cfg *Config) GenerateStateServerCertAndKey() (string, string, error) {
 	if !hasCAKey {
 		return "", "", fmt.Errorf("environment configuration has no ca-private-key")
 	}
-	var noHostnames []string
-	return cert.NewServer(caCert, caKey, time.Now().UTC().AddDate(10, 0, 0), noHostnames)
+	// Clients verify the certificate against a fixed server name,
+	// so only IP addresses are added as subject alternative names.
+	ipAddresses := []string{"127.0.0.1", "::1"}
+	for _, addr := range hostAddresses {
+		if ip := net.ParseIP(addr); ip != nil && !ip.IsLoopback() {
+			ipAddresses = append(ipAddresses, addr)
+		}
+	}
+	return cert.NewServer(caCert, caKey, time.Now().UTC().AddDate(10, 0, 0), ipAddresses)
 }
 
 type Specializer interface {
@@ -363,6 +363,24 @@ var configTests = []configTest{
 			"name":        "my-name",
 			"prefer-ipv6": true,
 		},
+	}, {
+		about:       "dns-service on",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":        "my-type",
+			"name":        "my-name",
+			"dns-service": true,
+		},
+	}, {
+		about:       "Invalid dns-service flag",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":            "my-type",
+			"name":            "my-name",
+			"authorized-keys": testing.FakeAuthKeys,
+			"dns-service":     "invalid",
+		},
+		err: `dns-service: expected bool, got string\("invalid"\)`,
 	}, {
 		about:       "Invalid agent version",
 		useDefaults: config.UseDefaults,
@@ -450,6 +468,14 @@ var configTests = []configTest{
 			"name":          "my-name",
 			"firewall-mode": config.FwGlobal,
 		},
+	}, {
+		about:       "Host firewall mode",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":          "my-type",
+			"name":          "my-name",
+			"firewall-mode": config.FwHost,
+		},
 	}, {
 		about:       "Illegal firewall mode",
 		useDefaults: config.UseDefaults,
@@ -501,6 +527,32 @@ var configTests = []configTest{
 			"provisioner-safe-mode": "yes please",
 		},
 		err: `provisioner-safe-mode: expected bool, got string\("yes please"\)`,
+	}, {
+		about:       "provisioner-harvest-mode",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                     "my-type",
+			"name":                     "my-name",
+			"provisioner-harvest-mode": "unknown",
+		},
+	}, {
+		about:       "provisioner-harvest-mode overrides provisioner-safe-mode",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                     "my-type",
+			"name":                     "my-name",
+			"provisioner-safe-mode":    true,
+			"provisioner-harvest-mode": "none",
+		},
+	}, {
+		about:       "provisioner-harvest-mode incorrect",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                     "my-type",
+			"name":                     "my-name",
+			"provisioner-harvest-mode": "everything",
+		},
+		err: `provisioner-harvest-mode: unknown harvest mode "everything"`,
 	}, {
 		about:       "default image stream",
 		useDefaults: config.UseDefaults,
@@ -618,6 +670,117 @@ var configTests = []configTest{
 			"bootstrap-addresses-delay": "illegal",
 		},
 		err: `bootstrap-addresses-delay: expected number, got string\("illegal"\)`,
+	}, {
+		about:       "Explicit hook retry policy",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                "my-type",
+			"name":                "my-name",
+			"hook-retry-attempts": 3,
+			"hook-retry-delay":    30,
+		},
+	}, {
+		about:       "Negative hook retry attempts",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                "my-type",
+			"name":                "my-name",
+			"hook-retry-attempts": -1,
+		},
+		err: `hook-retry-attempts: expected non-negative number, got -1`,
+	}, {
+		about:       "Invalid hook retry delay",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":             "my-type",
+			"name":             "my-name",
+			"hook-retry-delay": 0,
+		},
+		err: `hook-retry-delay: expected positive number, got 0`,
+	}, {
+		about:       "Explicit hook timeout",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":         "my-type",
+			"name":         "my-name",
+			"hook-timeout": 600,
+		},
+	}, {
+		about:       "Negative hook timeout",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":         "my-type",
+			"name":         "my-name",
+			"hook-timeout": -5,
+		},
+		err: `hook-timeout: expected non-negative number, got -5`,
+	}, {
+		about:       "Explicit provisioner concurrency",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                    "my-type",
+			"name":                    "my-name",
+			"provisioner-concurrency": 20,
+		},
+	}, {
+		about:       "Invalid provisioner concurrency",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                    "my-type",
+			"name":                    "my-name",
+			"provisioner-concurrency": 0,
+		},
+		err: `provisioner-concurrency: expected positive number, got 0`,
+	}, {
+		about:       "Explicit start instance attempts",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                    "my-type",
+			"name":                    "my-name",
+			"start-instance-attempts": 5,
+		},
+	}, {
+		about:       "Invalid start instance attempts",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                    "my-type",
+			"name":                    "my-name",
+			"start-instance-attempts": -1,
+		},
+		err: `start-instance-attempts: expected positive number, got -1`,
+	}, {
+		about:       "Explicit spare machines",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":           "my-type",
+			"name":           "my-name",
+			"spare-machines": 2,
+		},
+	}, {
+		about:       "Invalid spare machines",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":           "my-type",
+			"name":           "my-name",
+			"spare-machines": -1,
+		},
+		err: `spare-machines: expected non-negative number, got -1`,
+	}, {
+		about:       "Automatic charm rollback",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                "my-type",
+			"name":                "my-name",
+			"charm-auto-rollback": true,
+		},
+	}, {
+		about:       "Hook context capture",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                 "my-type",
+			"name":                 "my-name",
+			"hook-context-capture": true,
+		},
 	}, {
 		about:       "Invalid logging configuration",
 		useDefaults: config.UseDefaults,
@@ -1016,11 +1179,25 @@ func (test configTest) check(c *gc.C, home *gitjujutesting.FakeHome) {
 		c.Assert(cfg.SSLHostnameVerification(), gc.Equals, v)
 	}
 
+	if v, ok := test.attrs["dns-service"]; ok {
+		c.Assert(cfg.DNSService(), gc.Equals, v)
+	} else {
+		c.Assert(cfg.DNSService(), gc.Equals, false)
+	}
+
 	if v, ok := test.attrs["provisioner-safe-mode"]; ok {
 		c.Assert(cfg.ProvisionerSafeMode(), gc.Equals, v)
 	} else {
 		c.Assert(cfg.ProvisionerSafeMode(), gc.Equals, false)
 	}
+
+	if v, ok := test.attrs["provisioner-harvest-mode"]; ok {
+		c.Assert(cfg.ProvisionerHarvestMode().String(), gc.Equals, v)
+	} else if cfg.ProvisionerSafeMode() {
+		c.Assert(cfg.ProvisionerHarvestMode(), gc.Equals, config.HarvestDestroyed)
+	} else {
+		c.Assert(cfg.ProvisionerHarvestMode(), gc.Equals, config.HarvestAll)
+	}
 	sshOpts := cfg.BootstrapSSHOpts()
 	test.assertDuration(
 		c,
@@ -1041,6 +1218,55 @@ func (test configTest) check(c *gc.C, home *gitjujutesting.FakeHome) {
 		config.DefaultBootstrapSSHAddressesDelay,
 	)
 
+	retryPolicy := cfg.HookRetryPolicy()
+	if v, ok := test.attrs["hook-retry-attempts"]; ok {
+		c.Assert(retryPolicy.Attempts, gc.Equals, v)
+	} else {
+		c.Assert(retryPolicy.Attempts, gc.Equals, 0)
+	}
+	test.assertDuration(
+		c,
+		"hook-retry-delay",
+		retryPolicy.Delay,
+		config.DefaultHookRetryDelay,
+	)
+
+	if v, ok := test.attrs["charm-auto-rollback"]; ok {
+		c.Assert(cfg.CharmAutoRollback(), gc.Equals, v)
+	} else {
+		c.Assert(cfg.CharmAutoRollback(), jc.IsFalse)
+	}
+
+	if v, ok := test.attrs["hook-context-capture"]; ok {
+		c.Assert(cfg.HookContextCapture(), gc.Equals, v)
+	} else {
+		c.Assert(cfg.HookContextCapture(), jc.IsFalse)
+	}
+
+	if v, ok := test.attrs["hook-timeout"]; ok {
+		c.Assert(cfg.HookTimeout(), gc.Equals, time.Duration(v.(int))*time.Second)
+	} else {
+		c.Assert(cfg.HookTimeout(), gc.Equals, time.Duration(0))
+	}
+
+	if v, ok := test.attrs["provisioner-concurrency"]; ok {
+		c.Assert(cfg.ProvisionerConcurrency(), gc.Equals, v)
+	} else {
+		c.Assert(cfg.ProvisionerConcurrency(), gc.Equals, config.DefaultProvisionerConcurrency)
+	}
+
+	if v, ok := test.attrs["start-instance-attempts"]; ok {
+		c.Assert(cfg.StartInstanceAttempts(), gc.Equals, v)
+	} else {
+		c.Assert(cfg.StartInstanceAttempts(), gc.Equals, config.DefaultStartInstanceAttempts)
+	}
+
+	if v, ok := test.attrs["spare-machines"]; ok {
+		c.Assert(cfg.SpareMachines(), gc.Equals, v)
+	} else {
+		c.Assert(cfg.SpareMachines(), gc.Equals, config.DefaultSpareMachines)
+	}
+
 	if v, ok := test.attrs["image-stream"]; ok {
 		c.Assert(cfg.ImageStream(), gc.Equals, v)
 	} else {
@@ -1143,6 +1369,7 @@ func (s *ConfigSuite) TestConfigAttrs(c *gc.C) {
 	attrs["proxy-ssh"] = false
 	attrs["lxc-clone-aufs"] = false
 	attrs["prefer-ipv6"] = false
+	attrs["dns-service"] = false
 
 	// Default firewall mode is instance
 	attrs["firewall-mode"] = string(config.FwInstance)
@@ -1246,6 +1473,11 @@ var validationTests = []validationTest{{
 	old:   testing.Attrs{"prefer-ipv6": false},
 	new:   testing.Attrs{"prefer-ipv6": true},
 	err:   `cannot change prefer-ipv6 from false to true`,
+}, {
+	about: "Cannot change dns-service",
+	old:   testing.Attrs{"dns-service": false},
+	new:   testing.Attrs{"dns-service": true},
+	err:   `cannot change dns-service from false to true`,
 }, {
 	about: "Can change uuid from unset to set",
 	new:   testing.Attrs{"uuid": "dcfbdb4a-bca2-49ad-aa7c-f011424e0fe4"},
@@ -1469,12 +1701,18 @@ func (s *ConfigSuite) TestGenerateStateServerCertAndKey(c *gc.C) {
 	}} {
 		cfg, err := config.New(config.UseDefaults, test.configValues)
 		c.Assert(err, gc.IsNil)
-		certPEM, keyPEM, err := cfg.GenerateStateServerCertAndKey()
+		certPEM, keyPEM, err := cfg.GenerateStateServerCertAndKey([]string{"2001:db8::1", "10.0.0.1", "::1", "example.com"})
 		if test.errMatch == "" {
 			c.Assert(err, gc.IsNil)
 
-			_, _, err = cert.ParseCertAndKey(certPEM, keyPEM)
-			c.Check(err, gc.IsNil)
+			srvCert, _, err := cert.ParseCertAndKey(certPEM, keyPEM)
+			c.Assert(err, gc.IsNil)
+			var ipAddresses []string
+			for _, ip := range srvCert.IPAddresses {
+				ipAddresses = append(ipAddresses, ip.String())
+			}
+			c.Check(ipAddresses, jc.SameContents, []string{"127.0.0.1", "::1", "2001:db8::1", "10.0.0.1"})
+			c.Check(srvCert.DNSNames, gc.HasLen, 0)
 
 			err = cert.Verify(certPEM, testing.CACert, time.Now())
 			c.Assert(err, gc.IsNil)
@@ -0,0 +1,65 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package config
+
+import (
+	"fmt"
+)
+
+// HarvestMode describes which instances the provisioner terminates
+// when they are not, or are no longer, associated with a live machine.
+type HarvestMode uint32
+
+const (
+	// HarvestNone terminates no instances.
+	HarvestNone HarvestMode = 0
+
+	// HarvestDestroyed terminates the instances of machines that juju
+	// has destroyed.
+	HarvestDestroyed HarvestMode = 1 << 0
+
+	// HarvestUnknown terminates instances that juju has never known
+	// about.
+	HarvestUnknown HarvestMode = 1 << 1
+
+	// HarvestAll terminates both the instances of destroyed machines
+	// and unknown instances.
+	HarvestAll HarvestMode = HarvestDestroyed | HarvestUnknown
+)
+
+var harvestModeNames = map[HarvestMode]string{
+	HarvestNone:      "none",
+	HarvestDestroyed: "destroyed",
+	HarvestUnknown:   "unknown",
+	HarvestAll:       "all",
+}
+
+// ParseHarvestMode returns the harvest mode with the given name.
+func ParseHarvestMode(name string) (HarvestMode, error) {
+	for mode, modeName := range harvestModeNames {
+		if name == modeName {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown harvest mode %q", name)
+}
+
+// String returns the name of the harvest mode.
+func (mode HarvestMode) String() string {
+	if name, ok := harvestModeNames[mode]; ok {
+		return name
+	}
+	return fmt.Sprintf("HarvestMode(%d)", uint32(mode))
+}
+
+// Destroyed reports whether the instances of destroyed machines are
+// terminated.
+func (mode HarvestMode) Destroyed() bool {
+	return mode&HarvestDestroyed != 0
+}
+
+// Unknown reports whether unknown instances are terminated.
+func (mode HarvestMode) Unknown() bool {
+	return mode&HarvestUnknown != 0
+}
@@ -0,0 +1,48 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package config_test
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/testing"
+)
+
+type HarvestModeSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&HarvestModeSuite{})
+
+var harvestModeTests = []struct {
+	name      string
+	mode      config.HarvestMode
+	destroyed bool
+	unknown   bool
+}{
+	{"none", config.HarvestNone, false, false},
+	{"destroyed", config.HarvestDestroyed, true, false},
+	{"unknown", config.HarvestUnknown, false, true},
+	{"all", config.HarvestAll, true, true},
+}
+
+func (s *HarvestModeSuite) TestParseHarvestMode(c *gc.C) {
+	for i, test := range harvestModeTests {
+		c.Logf("test %d: %s", i, test.name)
+		mode, err := config.ParseHarvestMode(test.name)
+		c.Assert(err, gc.IsNil)
+		c.Check(mode, gc.Equals, test.mode)
+		c.Check(mode.String(), gc.Equals, test.name)
+		c.Check(mode.Destroyed(), gc.Equals, test.destroyed)
+		c.Check(mode.Unknown(), gc.Equals, test.unknown)
+	}
+}
+
+func (s *HarvestModeSuite) TestParseHarvestModeInvalid(c *gc.C) {
+	_, err := config.ParseHarvestMode("some")
+	c.Assert(err, gc.ErrorMatches, `unknown harvest mode "some"`)
+	_, err = config.ParseHarvestMode("")
+	c.Assert(err, gc.ErrorMatches, `unknown harvest mode ""`)
+}
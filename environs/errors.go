@@ -5,6 +5,7 @@ package environs
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
@@ -13,3 +14,51 @@ var (
 	ErrNoInstances         = errors.New("no instances found")
 	ErrPartialInstances    = errors.New("only some instances were found")
 )
+
+// rateLimitedError wraps an error returned by a provider that refused
+// a request because too many requests have been made.
+type rateLimitedError struct {
+	error
+}
+
+// NewRateLimitedError returns an error with the same message as err,
+// that indicates the provider refused the request because it is rate
+// limiting requests. The same request may succeed if made again later.
+func NewRateLimitedError(err error) error {
+	return &rateLimitedError{err}
+}
+
+// IsRateLimited reports whether err was returned by a provider that
+// is rate limiting requests.
+func IsRateLimited(err error) bool {
+	_, ok := err.(*rateLimitedError)
+	return ok
+}
+
+// CapacityError is returned by a provider that failed to start an
+// instance because there is not enough capacity to start it in the
+// chosen availability zone, or to start an instance of the chosen
+// type. The instance may start in another zone, or with another type.
+type CapacityError struct {
+	// Zone holds the availability zone lacking capacity, if known.
+	Zone string
+
+	// InstanceType holds the name of the instance type lacking
+	// capacity, if known.
+	InstanceType string
+
+	// Err holds the error returned by the provider.
+	Err error
+}
+
+func (e *CapacityError) Error() string {
+	switch {
+	case e.Zone != "" && e.InstanceType != "":
+		return fmt.Sprintf("no capacity for instance type %q in availability zone %q: %v", e.InstanceType, e.Zone, e.Err)
+	case e.Zone != "":
+		return fmt.Sprintf("no capacity in availability zone %q: %v", e.Zone, e.Err)
+	case e.InstanceType != "":
+		return fmt.Sprintf("no capacity for instance type %q: %v", e.InstanceType, e.Err)
+	}
+	return fmt.Sprintf("no capacity: %v", e.Err)
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environs
+
+import (
+	"github.com/juju/juju/network"
+)
+
+// GlobalIngressFirewaller is an optional interface that may be
+// implemented by an Environ whose global firewall can restrict
+// opened ports to particular source CIDRs. Its methods must only be
+// used if the environment was setup with the FwGlobal firewall mode.
+//
+// Environs that do not implement it can only open ports to any
+// address; see Environ.OpenPorts.
+type GlobalIngressFirewaller interface {
+	// OpenIngressRules opens the given rules for the whole environment.
+	OpenIngressRules(rules []network.IngressRule) error
+
+	// CloseIngressRules closes the given rules for the whole environment.
+	CloseIngressRules(rules []network.IngressRule) error
+
+	// IngressRules returns the rules opened for the whole environment.
+	IngressRules() ([]network.IngressRule, error)
+}
+
+// InstanceIngressFirewaller is an optional interface that may be
+// implemented by an instance.Instance whose firewall can restrict
+// opened ports to particular source CIDRs. Its methods must only be
+// used if the environment was setup with the FwInstance firewall mode.
+type InstanceIngressFirewaller interface {
+	// OpenIngressRules opens the given rules on the instance, which
+	// should have been started with the given machine id.
+	OpenIngressRules(machineId string, rules []network.IngressRule) error
+
+	// CloseIngressRules closes the given rules on the instance, which
+	// should have been started with the given machine id.
+	CloseIngressRules(machineId string, rules []network.IngressRule) error
+
+	// IngressRules returns the rules opened on the instance, which
+	// should have been started with the given machine id.
+	IngressRules(machineId string) ([]network.IngressRule, error)
+}
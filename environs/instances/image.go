@@ -23,6 +23,8 @@ type InstanceConstraint struct {
 	// by the user as a constraint but rather passed in by the provider implementation to restrict the
 	// choice of available images.
 	Storage *string
+	// ExcludedInstanceTypes holds the names of instance types which must not be chosen.
+	ExcludedInstanceTypes []string
 }
 
 // String returns a human readable form of this InstanceConstaint.
@@ -60,6 +62,9 @@ func FindInstanceSpec(possibleImages []Image, ic *InstanceConstraint, allInstanc
 			ic.Series, ic.Region, ic.Arches)
 	}
 
+	if len(ic.ExcludedInstanceTypes) > 0 {
+		allInstanceTypes = excludeInstanceTypes(allInstanceTypes, ic.ExcludedInstanceTypes)
+	}
 	matchingTypes, err := MatchingInstanceTypes(allInstanceTypes, ic.Region, ic.Constraints)
 	if err != nil {
 		return nil, err
@@ -92,6 +97,22 @@ func FindInstanceSpec(possibleImages []Image, ic *InstanceConstraint, allInstanc
 	return nil, fmt.Errorf("no %q images in %s matching instance types %v", ic.Series, ic.Region, names)
 }
 
+// excludeInstanceTypes returns the instance types whose names are not
+// in excluded.
+func excludeInstanceTypes(instanceTypes []InstanceType, excluded []string) []InstanceType {
+	var result []InstanceType
+outer:
+	for _, itype := range instanceTypes {
+		for _, name := range excluded {
+			if itype.Name == name {
+				continue outer
+			}
+		}
+		result = append(result, itype)
+	}
+	return result
+}
+
 // byArch sorts InstanceSpecs first by descending word-size, then
 // alphabetically by name, and choose the first spec in the sequence.
 type byArch []*InstanceSpec
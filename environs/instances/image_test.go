@@ -208,6 +208,7 @@ type instanceSpecTestParams struct {
 	stream           string
 	constraints      string
 	instanceTypes    []InstanceType
+	excluded         []string
 	imageId          string
 	instanceTypeId   string
 	instanceTypeName string
@@ -325,6 +326,28 @@ var findInstanceSpecTests = []instanceSpecTestParams{
 		},
 		err: `no instance types in test matching constraints "instance-type=it-10"`,
 	},
+	{
+		desc:             "excluded instance type",
+		region:           "test",
+		imageId:          "ami-00000035",
+		excluded:         []string{"it-1"},
+		instanceTypeName: "it-2",
+		instanceTypes: []InstanceType{
+			{Id: "1", Name: "it-1", Arches: []string{"amd64"}, VirtType: &hvm, Mem: 512, CpuCores: 2},
+			{Id: "2", Name: "it-2", Arches: []string{"amd64"}, VirtType: &hvm, Mem: 1024, CpuCores: 2},
+		},
+	},
+	{
+		desc:        "instance type constraint, instance type excluded",
+		region:      "test",
+		constraints: "instance-type=it-1",
+		excluded:    []string{"it-1"},
+		instanceTypes: []InstanceType{
+			{Id: "1", Name: "it-1", Arches: []string{"amd64"}, VirtType: &hvm, Mem: 512, CpuCores: 2},
+			{Id: "2", Name: "it-2", Arches: []string{"amd64"}, VirtType: &hvm, Mem: 1024, CpuCores: 2},
+		},
+		err: `no instance types in test matching constraints "instance-type=it-1"`,
+	},
 	{
 		desc:   "no image exists in metadata",
 		region: "invalid-region",
@@ -369,10 +392,11 @@ func (s *imageSuite) TestFindInstanceSpec(c *gc.C) {
 		}
 		imageCons := constraints.MustParse(t.constraints)
 		spec, err := FindInstanceSpec(images, &InstanceConstraint{
-			Series:      "precise",
-			Region:      t.region,
-			Arches:      t.arches,
-			Constraints: imageCons,
+			Series:                "precise",
+			Region:                t.region,
+			Arches:                t.arches,
+			Constraints:           imageCons,
+			ExcludedInstanceTypes: t.excluded,
 		}, t.instanceTypes)
 		if t.err != "" {
 			c.Check(err, gc.ErrorMatches, t.err)
@@ -388,6 +412,9 @@ func (s *imageSuite) TestFindInstanceSpec(c *gc.C) {
 			if imageCons.HasInstanceType() {
 				c.Assert(spec.InstanceType.Name, gc.Equals, *imageCons.InstanceType)
 			}
+			if t.instanceTypeName != "" {
+				c.Check(spec.InstanceType.Name, gc.Equals, t.instanceTypeName)
+			}
 		}
 	}
 }
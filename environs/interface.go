@@ -112,6 +112,10 @@ type Environ interface {
 	// given instance on the given network.
 	AllocateAddress(instId instance.Id, netId network.Id) (network.Address, error)
 
+	// ReleaseAddress releases an address allocated for the given
+	// instance on the given network by AllocateAddress.
+	ReleaseAddress(instId instance.Id, netId network.Id, addr network.Address) error
+
 	// ListNetworks returns basic information about all networks known
 	// by the provider for the environment. They may be unknown to juju
 	// yet (i.e. when called initially or when a new network was created).
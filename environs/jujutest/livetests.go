@@ -440,7 +440,7 @@ func (t *LiveTests) TestBootstrapAndDeploy(c *gc.C) {
 	url := charmtesting.Charms.ClonedURL(repoDir, mtools0.Version.Series, "dummy")
 	sch, err := testing.PutCharm(st, url, &charm.LocalRepository{Path: repoDir}, false)
 	c.Assert(err, gc.IsNil)
-	svc, err := st.AddService("dummy", "user-admin", sch, nil)
+	svc, err := st.AddService("dummy", "user-admin", sch, nil, nil)
 	c.Assert(err, gc.IsNil)
 	units, err := juju.AddUnits(st, svc, 1, "")
 	c.Assert(err, gc.IsNil)
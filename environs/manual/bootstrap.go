@@ -115,6 +115,9 @@ func Bootstrap(args BootstrapArgs) (err error) {
 	if err != nil {
 		return err
 	}
+	// The host is not ours to manage, and its resolver would be left
+	// pointing at the DNS service once the environment is destroyed.
+	mcfg.DNSServers = nil
 	for k, v := range agentEnv {
 		mcfg.AgentEnvironment[k] = v
 	}
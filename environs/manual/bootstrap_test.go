@@ -161,3 +161,19 @@ func (s *bootstrapSuite) testBootstrapToolsURL(c *gc.C, toolsURL, expectedURL st
 	err := manual.Bootstrap(args)
 	c.Assert(err, gc.IsNil)
 }
+
+func (s *bootstrapSuite) TestBootstrapLeavesResolverAlone(c *gc.C) {
+	cfg, err := s.Environ.Config().Apply(map[string]interface{}{
+		"dns-service": true,
+	})
+	c.Assert(err, gc.IsNil)
+	err = s.Environ.SetConfig(cfg)
+	c.Assert(err, gc.IsNil)
+	s.PatchValue(manual.ProvisionMachineAgent, func(host string, mcfg *cloudinit.MachineConfig, w io.Writer) error {
+		c.Assert(mcfg.DNSServers, gc.HasLen, 0)
+		return nil
+	})
+	defer fakeSSH{SkipDetection: true}.install(c).Restore()
+	err = manual.Bootstrap(s.getArgs(c))
+	c.Assert(err, gc.IsNil)
+}
@@ -0,0 +1,18 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environs
+
+import (
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+)
+
+// SubnetDiscoverer is an optional interface that may be implemented
+// by an Environ that can report the subnets that exist in the cloud.
+type SubnetDiscoverer interface {
+	// Subnets returns information about the subnets the given
+	// instance is connected to or, if instId is empty, about all
+	// the subnets known to the provider for the environment.
+	Subnets(instId instance.Id) ([]network.SubnetInfo, error)
+}
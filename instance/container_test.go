@@ -28,6 +28,10 @@ func (s *InstanceSuite) TestParseContainerType(c *gc.C) {
 	c.Assert(err, gc.IsNil)
 	c.Assert(ctype, gc.Equals, instance.KVM)
 
+	ctype, err = instance.ParseContainerType("docker")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ctype, gc.Equals, instance.DOCKER)
+
 	ctype, err = instance.ParseContainerType("none")
 	c.Assert(err, gc.ErrorMatches, `invalid container type "none"`)
 
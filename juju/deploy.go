@@ -32,6 +32,9 @@ type DeployServiceParams struct {
 	ToMachineSpec string
 	// Networks holds a list of networks to required to start on boot.
 	Networks []string
+	// Bindings holds the names of the spaces to bind the service's
+	// endpoints to, keyed by endpoint name.
+	Bindings map[string]string
 }
 
 // DeployService takes a charm and various parameters and deploys it.
@@ -69,11 +72,15 @@ func DeployService(st *state.State, args DeployServiceParams) (*state.Service, e
 			return nil, fmt.Errorf("cannot deploy with networks: not suppored by the environment")
 		}
 	}
+	if err := validateBindings(st, args.Charm.Meta(), args.Bindings); err != nil {
+		return nil, err
+	}
 	service, err := st.AddService(
 		args.ServiceName,
 		args.ServiceOwner,
 		args.Charm,
 		args.Networks,
+		args.Bindings,
 	)
 	if err != nil {
 		return nil, err
@@ -99,6 +106,24 @@ func DeployService(st *state.State, args DeployServiceParams) (*state.Service, e
 	return service, nil
 }
 
+// validateBindings checks that the endpoints and spaces named in
+// bindings exist, so that a service is not created with bindings that
+// cannot be set.
+func validateBindings(st *state.State, meta *charm.Meta, bindings map[string]string) error {
+	for endpoint, spaceName := range bindings {
+		_, isPeer := meta.Peers[endpoint]
+		_, isProvider := meta.Provides[endpoint]
+		_, isRequirer := meta.Requires[endpoint]
+		if !isPeer && !isProvider && !isRequirer && endpoint != "juju-info" {
+			return fmt.Errorf("cannot bind endpoint %q: charm %q has no such endpoint", endpoint, meta.Name)
+		}
+		if _, err := st.Space(spaceName); err != nil {
+			return fmt.Errorf("cannot bind endpoint %q: %v", endpoint, err)
+		}
+	}
+	return nil
+}
+
 // AddUnits starts n units of the given service and allocates machines
 // to them as necessary.
 func AddUnits(st *state.State, svc *state.Service, n int, machineIdSpec string) ([]*state.Unit, error) {
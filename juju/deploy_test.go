@@ -115,6 +115,35 @@ func (s *DeployLocalSuite) TestDeploySettingsError(c *gc.C) {
 	c.Assert(err, jc.Satisfies, errors.IsNotFound)
 }
 
+func (s *DeployLocalSuite) TestDeployBindings(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	service, err := juju.DeployService(s.State,
+		juju.DeployServiceParams{
+			ServiceName: "bob",
+			Charm:       s.charm,
+			Bindings:    map[string]string{"juju-info": "internal"},
+		})
+	c.Assert(err, gc.IsNil)
+	err = service.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(service.EndpointBindings(), jc.DeepEquals, map[string]string{"juju-info": "internal"})
+}
+
+func (s *DeployLocalSuite) TestDeployBindingsUnknownSpace(c *gc.C) {
+	_, err := juju.DeployService(s.State,
+		juju.DeployServiceParams{
+			ServiceName: "bob",
+			Charm:       s.charm,
+			Bindings:    map[string]string{"juju-info": "internal"},
+		})
+	c.Assert(err, gc.ErrorMatches, `cannot bind endpoint "juju-info": space "internal" not found`)
+	_, err = s.State.Service("bob")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *DeployLocalSuite) TestDeployConstraints(c *gc.C) {
 	err := s.State.SetEnvironConstraints(constraints.MustParse("mem=2G"))
 	c.Assert(err, gc.IsNil)
@@ -0,0 +1,286 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package simulation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/names"
+	"github.com/juju/utils"
+	"github.com/juju/utils/fslock"
+
+	"github.com/juju/juju/agent"
+	"github.com/juju/juju/agent/tools"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api"
+	"github.com/juju/juju/state/api/params"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/version"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/deployer"
+	"github.com/juju/juju/worker/firewaller"
+	"github.com/juju/juju/worker/instancepoller"
+	"github.com/juju/juju/worker/machiner"
+	"github.com/juju/juju/worker/provisioner"
+	"github.com/juju/juju/worker/uniter"
+	"github.com/juju/juju/worker/upgrader"
+)
+
+// isFatal returns whether an error returned by an agent's worker should
+// stop the agent.
+func isFatal(err error) bool {
+	return err == worker.ErrTerminateAgent
+}
+
+func moreImportant(err0, err1 error) bool {
+	return false
+}
+
+// machineAgent runs the workers of a machine agent, and the agents of
+// the units deployed to the machine.
+type machineAgent struct {
+	runner worker.Runner
+	units  *unitContext
+}
+
+// provisionedAttempt governs how long a machine agent waits to log in
+// while the provisioner records the instance it has just started.
+var provisionedAttempt = utils.AttemptStrategy{
+	Total: coretesting.LongWait,
+	Delay: 50 * time.Millisecond,
+}
+
+// startAgent starts the agent of the machine, with its own data
+// directory, logging in with the given password and nonce. As with
+// jujud's machine agent, the workers that use the API are restarted
+// together when the API connection fails.
+func (s *Simulation) startAgent(m *state.Machine, password, nonce string) (*machineAgent, error) {
+	dataDir := filepath.Join(s.params.DataDir, m.Tag().String())
+	agentConfig, err := agent.NewAgentConfig(agent.AgentConfigParams{
+		DataDir:           dataDir,
+		LogDir:            dataDir,
+		Tag:               m.Tag(),
+		UpgradedToVersion: version.Current.Number,
+		Password:          password,
+		Nonce:             nonce,
+		APIAddresses:      s.params.APIInfo.Addrs,
+		CACert:            s.params.APIInfo.CACert,
+	})
+	if err != nil {
+		return nil, err
+	}
+	units := &unitContext{
+		sim:         s,
+		agentConfig: agentConfig,
+		dataDir:     dataDir,
+		units:       make(map[string]*unitAgent),
+	}
+	jobs := m.Jobs()
+	runner := worker.NewRunner(isFatal, moreImportant)
+	runner.StartWorker("api", func() (worker.Worker, error) {
+		return s.apiWorker(agentConfig, jobs, units)
+	})
+	if m.IsManager() {
+		runner.StartWorker("instancepoller", func() (worker.Worker, error) {
+			return instancepoller.NewWorker(s.params.State), nil
+		})
+	}
+	logger.Infof("started agent for machine %s", m.Id())
+	return &machineAgent{
+		runner: runner,
+		units:  units,
+	}, nil
+}
+
+// apiWorker connects to the API as the machine agent, and starts the
+// workers the agent runs for the machine's jobs.
+func (s *Simulation) apiWorker(agentConfig agent.Config, jobs []state.MachineJob, units *unitContext) (worker.Worker, error) {
+	info := agentConfig.APIInfo()
+	var st *api.State
+	var err error
+	for a := provisionedAttempt.Start(); a.Next(); {
+		st, err = s.openAPI(agentConfig.Tag(), info.Password, info.Nonce)
+		if !params.IsCodeNotProvisioned(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	runner := worker.NewRunner(isFatal, moreImportant)
+	runner.StartWorker("upgrader", func() (worker.Worker, error) {
+		return upgrader.NewUpgrader(st.Upgrader(), agentConfig), nil
+	})
+	runner.StartWorker("machiner", func() (worker.Worker, error) {
+		return machiner.NewMachiner(st.Machiner(), agentConfig), nil
+	})
+	for _, job := range jobs {
+		switch job {
+		case state.JobHostUnits:
+			runner.StartWorker("deployer", func() (worker.Worker, error) {
+				return deployer.NewDeployer(st.Deployer(), units), nil
+			})
+		case state.JobManageEnviron:
+			runner.StartWorker("environ-provisioner", func() (worker.Worker, error) {
+				return provisioner.NewEnvironProvisioner(st.Provisioner(), agentConfig), nil
+			})
+			runner.StartWorker("firewaller", func() (worker.Worker, error) {
+				return firewaller.NewFirewaller(st.Firewaller())
+			})
+		}
+	}
+	return &closeWorker{runner, st}, nil
+}
+
+// closeWorker closes the API connection its workers use once they
+// have stopped.
+type closeWorker struct {
+	worker.Runner
+	st *api.State
+}
+
+func (w *closeWorker) Wait() error {
+	err := w.Runner.Wait()
+	if closeErr := w.st.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// stop stops the machine agent and the agents of its units.
+func (a *machineAgent) stop() error {
+	err := worker.Stop(a.runner)
+	if err == worker.ErrTerminateAgent {
+		err = nil
+	}
+	a.units.stop()
+	return err
+}
+
+// unitAgent runs the uniter of a unit deployed by a unitContext.
+type unitAgent struct {
+	runner worker.Runner
+	st     *api.State
+}
+
+// stop stops the unit agent.
+func (a *unitAgent) stop() error {
+	err := worker.Stop(a.runner)
+	if err == worker.ErrTerminateAgent {
+		err = nil
+	}
+	if closeErr := a.st.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// unitContext implements deployer.Context by running the agents of the
+// units deployed to a machine in process, in the machine's data
+// directory.
+type unitContext struct {
+	sim         *Simulation
+	agentConfig agent.Config
+	dataDir     string
+
+	mu    sync.Mutex
+	units map[string]*unitAgent
+}
+
+var _ deployer.Context = (*unitContext)(nil)
+
+// AgentConfig implements deployer.Context.
+func (ctx *unitContext) AgentConfig() agent.Config {
+	return ctx.agentConfig
+}
+
+// DeployUnit implements deployer.Context.
+func (ctx *unitContext) DeployUnit(unitName, initialPassword string) (err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if _, ok := ctx.units[unitName]; ok {
+		return fmt.Errorf("unit %q is already deployed", unitName)
+	}
+	tag := names.NewUnitTag(unitName)
+	toolsDir := tools.ToolsDir(ctx.dataDir, tag.String())
+	if err := os.MkdirAll(toolsDir, 0755); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(toolsDir)
+		}
+	}()
+	// The uniter links the hook tools to jujud in its tools directory.
+	if err := os.Symlink(ctx.sim.params.Jujud, filepath.Join(toolsDir, "jujud")); err != nil {
+		return err
+	}
+	lock, err := fslock.NewLock(filepath.Join(ctx.dataDir, "locks"), "uniter-hook-execution")
+	if err != nil {
+		return err
+	}
+	st, err := ctx.sim.openAPI(tag, initialPassword, "")
+	if err != nil {
+		return fmt.Errorf("cannot connect as unit %q: %v", unitName, err)
+	}
+	runner := worker.NewRunner(isFatal, moreImportant)
+	runner.StartWorker("uniter", func() (worker.Worker, error) {
+		return uniter.NewUniter(st.Uniter(), tag.String(), ctx.dataDir, lock), nil
+	})
+	ctx.units[unitName] = &unitAgent{
+		runner: runner,
+		st:     st,
+	}
+	logger.Infof("deployed unit %q", unitName)
+	return nil
+}
+
+// RecallUnit implements deployer.Context.
+func (ctx *unitContext) RecallUnit(unitName string) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	a, ok := ctx.units[unitName]
+	if !ok {
+		return fmt.Errorf("unit %q is not deployed", unitName)
+	}
+	delete(ctx.units, unitName)
+	if err := a.stop(); err != nil {
+		logger.Errorf("agent for unit %q stopped with error: %v", unitName, err)
+	}
+	tag := names.NewUnitTag(unitName)
+	if err := os.RemoveAll(agent.Dir(ctx.dataDir, tag)); err != nil {
+		return err
+	}
+	return os.RemoveAll(tools.ToolsDir(ctx.dataDir, tag.String()))
+}
+
+// DeployedUnits implements deployer.Context.
+func (ctx *unitContext) DeployedUnits() ([]string, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	unitNames := make([]string, 0, len(ctx.units))
+	for unitName := range ctx.units {
+		unitNames = append(unitNames, unitName)
+	}
+	sort.Strings(unitNames)
+	return unitNames, nil
+}
+
+// stop stops the agents of all deployed units, leaving their data in
+// place.
+func (ctx *unitContext) stop() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for unitName, a := range ctx.units {
+		if err := a.stop(); err != nil {
+			logger.Errorf("agent for unit %q stopped with error: %v", unitName, err)
+		}
+	}
+	ctx.units = make(map[string]*unitAgent)
+}
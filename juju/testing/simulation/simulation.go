@@ -0,0 +1,294 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// The simulation package runs the agents of an environment in process,
+// so that integration tests can deploy charms, add relations and watch
+// hooks execute end to end without starting any real instances.
+//
+// The state server machine is started as an instance of the dummy
+// provider, as bootstrap would, and each machine runs the workers a
+// machine agent runs for its jobs: the upgrader, the machiner and the
+// deployer, and on the state server the provisioner, firewaller and
+// instance poller. The provisioner starts the instances of all other
+// machines, and the simulation starts the agent of each machine with the
+// nonce and password the provisioner gave it. Containers are not
+// provisioned.
+//
+// Each unit deployed to a machine runs a real uniter, in a temporary
+// data directory. The hook tools are provided by the test binary, which
+// runs them with harness.ToolMain.
+package simulation
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/names"
+	"github.com/juju/utils"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/environs"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/provider/dummy"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api"
+	"github.com/juju/juju/state/watcher"
+)
+
+var logger = loggo.GetLogger("juju.testing.simulation")
+
+// bootstrapNonce is the nonce the state server machine is provisioned
+// with, and that its agent logs in with.
+const bootstrapNonce = "fake_nonce"
+
+// Params holds the parameters of a simulation.
+type Params struct {
+	// State is used to watch the environment's machines and to
+	// provision them, as a provisioner would.
+	State *state.State
+
+	// Environ holds the environment that machines are started in. It
+	// must use the dummy provider, whose operations the simulation
+	// listens for while it runs.
+	Environ environs.Environ
+
+	// APIInfo holds the information the agents use to connect to the
+	// API server; its tag, password and nonce are ignored.
+	APIInfo *api.Info
+
+	// DataDir holds the directory under which each machine gets its
+	// own data directory.
+	DataDir string
+
+	// Jujud holds the path of the executable that provides the hook
+	// tools to units: jujud, or a test binary that calls
+	// harness.ToolMain when it starts.
+	Jujud string
+}
+
+// Simulation runs the agents of the machines in an environment, and
+// of the units deployed to them, until it is stopped.
+type Simulation struct {
+	tomb     tomb.Tomb
+	params   Params
+	machines map[string]*machineAgent
+
+	// ops receives the operations of the dummy provider. Instances
+	// started by the provisioner are queued in started, and notified
+	// on startedNotify, so that the provider is never blocked on the
+	// simulation.
+	ops           chan dummy.Operation
+	mu            sync.Mutex
+	started       []dummy.OpStartInstance
+	startedNotify chan struct{}
+}
+
+// New starts a simulation with the given parameters.
+func New(params Params) *Simulation {
+	s := &Simulation{
+		params:        params,
+		machines:      make(map[string]*machineAgent),
+		ops:           make(chan dummy.Operation),
+		startedNotify: make(chan struct{}, 1),
+	}
+	dummy.Listen(s.ops)
+	go s.watchOps()
+	go func() {
+		defer s.tomb.Done()
+		s.tomb.Kill(s.loop())
+	}()
+	return s
+}
+
+// Kill implements worker.Worker.Kill.
+func (s *Simulation) Kill() {
+	s.tomb.Kill(nil)
+}
+
+// Wait implements worker.Worker.Wait.
+func (s *Simulation) Wait() error {
+	return s.tomb.Wait()
+}
+
+// Stop stops the simulation and all the agents it runs.
+func (s *Simulation) Stop() error {
+	s.Kill()
+	return s.Wait()
+}
+
+func (s *Simulation) loop() error {
+	defer func() {
+		// The agents must be stopped while the provider's operations
+		// are still being received, since they may be waiting for the
+		// provider.
+		s.stopAgents()
+		dummy.Listen(nil)
+	}()
+	w := s.params.State.WatchEnvironMachines()
+	defer watcher.Stop(w, &s.tomb)
+	for {
+		select {
+		case <-s.tomb.Dying():
+			return tomb.ErrDying
+		case ids, ok := <-w.Changes():
+			if !ok {
+				return watcher.MustErr(w)
+			}
+			for _, id := range ids {
+				if err := s.machineChanged(id); err != nil {
+					return err
+				}
+			}
+		case <-s.startedNotify:
+			s.mu.Lock()
+			started := s.started
+			s.started = nil
+			s.mu.Unlock()
+			for _, op := range started {
+				if err := s.instanceStarted(op); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// watchOps receives the dummy provider's operations until the
+// simulation stops listening for them, and queues the instances
+// started for the loop.
+func (s *Simulation) watchOps() {
+	for op := range s.ops {
+		if op, ok := op.(dummy.OpStartInstance); ok {
+			s.mu.Lock()
+			s.started = append(s.started, op)
+			s.mu.Unlock()
+			select {
+			case s.startedNotify <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// machineChanged bootstraps the state server machine, and stops the
+// agent of any machine that is dead or removed. The provisioner removes
+// dead machines once their instances are stopped.
+func (s *Simulation) machineChanged(id string) error {
+	m, err := s.params.State.Machine(id)
+	if errors.IsNotFound(err) {
+		s.stopAgent(id)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if m.Life() == state.Dead {
+		s.stopAgent(id)
+		return nil
+	}
+	if _, ok := s.machines[id]; ok || !m.IsManager() {
+		return nil
+	}
+	if err := s.bootstrap(m); err != nil {
+		return errors.Annotatef(err, "cannot bootstrap machine %s", id)
+	}
+	return nil
+}
+
+// instanceStarted starts the agent of the machine whose instance the
+// provisioner has started, with the nonce and password it was given.
+func (s *Simulation) instanceStarted(op dummy.OpStartInstance) error {
+	if _, ok := s.machines[op.MachineId]; ok {
+		return nil
+	}
+	m, err := s.params.State.Machine(op.MachineId)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if m.Life() == state.Dead {
+		return nil
+	}
+	agent, err := s.startAgent(m, op.APIInfo.Password, op.MachineNonce)
+	if err != nil {
+		return errors.Annotatef(err, "cannot start agent for machine %s", op.MachineId)
+	}
+	s.machines[op.MachineId] = agent
+	return nil
+}
+
+// bootstrap provisions the state server machine if necessary, gives it
+// a password, and starts its agent.
+func (s *Simulation) bootstrap(m *state.Machine) error {
+	if err := s.provision(m); err != nil {
+		return err
+	}
+	password, err := utils.RandomPassword()
+	if err != nil {
+		return err
+	}
+	if err := m.SetPassword(password); err != nil {
+		return err
+	}
+	agent, err := s.startAgent(m, password, bootstrapNonce)
+	if err != nil {
+		return err
+	}
+	s.machines[m.Id()] = agent
+	return nil
+}
+
+// provision starts an instance for the state server machine, unless it
+// already has one.
+func (s *Simulation) provision(m *state.Machine) error {
+	if _, err := m.InstanceId(); err == nil {
+		return nil
+	} else if !state.IsNotProvisionedError(err) {
+		return err
+	}
+	cons, err := m.Constraints()
+	if err != nil {
+		return err
+	}
+	inst, hc, _, err := jujutesting.StartInstanceWithConstraints(s.params.Environ, m.Id(), cons)
+	if err != nil {
+		return err
+	}
+	if err := m.SetProvisioned(inst.Id(), bootstrapNonce, hc); err != nil {
+		s.params.Environ.StopInstances(inst.Id())
+		return err
+	}
+	addrs, err := inst.Addresses()
+	if err != nil {
+		return err
+	}
+	logger.Infof("started instance %q for machine %s", inst.Id(), m.Id())
+	return m.SetAddresses(addrs...)
+}
+
+func (s *Simulation) stopAgent(id string) {
+	agent, ok := s.machines[id]
+	if !ok {
+		return
+	}
+	delete(s.machines, id)
+	if err := agent.stop(); err != nil {
+		logger.Errorf("agent for machine %s stopped with error: %v", id, err)
+	}
+}
+
+func (s *Simulation) stopAgents() {
+	for id := range s.machines {
+		s.stopAgent(id)
+	}
+}
+
+// openAPI connects to the API server as the entity with the given tag.
+func (s *Simulation) openAPI(tag names.Tag, password, nonce string) (*api.State, error) {
+	info := *s.params.APIInfo
+	info.Tag = tag
+	info.Password = password
+	info.Nonce = nonce
+	return api.Open(&info, api.DialOpts{})
+}
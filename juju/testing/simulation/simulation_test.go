@@ -0,0 +1,196 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package simulation_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/charm"
+	charmtesting "github.com/juju/charm/testing"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/instance"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/juju/testing/simulation"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api/params"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/version"
+)
+
+type SimulationSuite struct {
+	simulation.Suite
+	repoPath string
+	hookLog  string
+}
+
+var _ = gc.Suite(&SimulationSuite{})
+
+func (s *SimulationSuite) SetUpTest(c *gc.C) {
+	s.Suite.SetUpTest(c)
+	s.repoPath = c.MkDir()
+	s.hookLog = filepath.Join(c.MkDir(), "hooks.log")
+}
+
+// addCharm adds the named testing charm to state, with the given hooks,
+// each of which logs its unit, name and remote unit after running the
+// given script.
+func (s *SimulationSuite) addCharm(c *gc.C, name string, hooks map[string]string) *state.Charm {
+	path := charmtesting.Charms.ClonedDirPath(filepath.Join(s.repoPath, "quantal"), name)
+	err := os.MkdirAll(filepath.Join(path, "hooks"), 0755)
+	c.Assert(err, gc.IsNil)
+	for hook, script := range hooks {
+		data := fmt.Sprintf("#!/bin/sh\nset -e\n%s\necho $JUJU_UNIT_NAME %s $JUJU_REMOTE_UNIT >> %s\n", script, hook, s.hookLog)
+		err = ioutil.WriteFile(filepath.Join(path, "hooks", hook), []byte(data), 0755)
+		c.Assert(err, gc.IsNil)
+	}
+	curl := charm.MustParseURL("local:quantal/" + name)
+	repo := &charm.LocalRepository{Path: s.repoPath}
+	ch, err := jujutesting.PutCharm(s.State, curl, repo, false)
+	c.Assert(err, gc.IsNil)
+	return ch
+}
+
+func (s *SimulationSuite) addUnit(c *gc.C, service *state.Service) *state.Unit {
+	unit, err := service.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = s.State.AssignUnit(unit, state.AssignNew)
+	c.Assert(err, gc.IsNil)
+	return unit
+}
+
+// waitHookLog waits for the hook log to hold the given line.
+func (s *SimulationSuite) waitHookLog(c *gc.C, line string) {
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		data, err := ioutil.ReadFile(s.hookLog)
+		if err == nil && strings.Contains(string(data), line+"\n") {
+			return
+		}
+	}
+	c.Fatalf("timed out waiting for hook log line %q", line)
+}
+
+func (s *SimulationSuite) TestDeploy(c *gc.C) {
+	ch := s.addCharm(c, "wordpress", map[string]string{
+		"install":        "",
+		"config-changed": "",
+		"start":          "",
+	})
+	unit := s.addUnit(c, s.AddTestingService(c, "wordpress", ch))
+	s.WaitUnitStatus(c, unit.Name(), params.StatusStarted)
+
+	data, err := ioutil.ReadFile(s.hookLog)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(data), gc.Equals, ""+
+		"wordpress/0 install\n"+
+		"wordpress/0 config-changed\n"+
+		"wordpress/0 start\n")
+
+	machineId, err := unit.AssignedMachineId()
+	c.Assert(err, gc.IsNil)
+	machine, err := s.State.Machine(machineId)
+	c.Assert(err, gc.IsNil)
+	_, err = machine.InstanceId()
+	c.Assert(err, gc.IsNil)
+
+	// The machine's upgrader reports the version its agent runs.
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		agentTools, err := machine.AgentTools()
+		if err == nil {
+			c.Assert(agentTools.Version, gc.Equals, version.Current)
+			return
+		}
+		c.Assert(err, jc.Satisfies, errors.IsNotFound)
+		err = machine.Refresh()
+		c.Assert(err, gc.IsNil)
+	}
+	c.Fatalf("timed out waiting for machine %s agent version", machineId)
+}
+
+func (s *SimulationSuite) TestExpose(c *gc.C) {
+	ch := s.addCharm(c, "wordpress", map[string]string{
+		"start": "open-port 80",
+	})
+	service := s.AddTestingService(c, "wordpress", ch)
+	unit := s.addUnit(c, service)
+	s.WaitUnitStatus(c, unit.Name(), params.StatusStarted)
+	err := service.SetExposed()
+	c.Assert(err, gc.IsNil)
+
+	machineId, err := unit.AssignedMachineId()
+	c.Assert(err, gc.IsNil)
+	machine, err := s.State.Machine(machineId)
+	c.Assert(err, gc.IsNil)
+	instId, err := machine.InstanceId()
+	c.Assert(err, gc.IsNil)
+	insts, err := s.Environ.Instances([]instance.Id{instId})
+	c.Assert(err, gc.IsNil)
+
+	// The firewaller opens the unit's port on its instance.
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		ports, err := insts[0].Ports(machineId)
+		c.Assert(err, gc.IsNil)
+		if len(ports) > 0 {
+			c.Assert(ports, gc.DeepEquals, []network.Port{{Protocol: "tcp", Number: 80}})
+			return
+		}
+	}
+	c.Fatalf("timed out waiting for port 80 to be opened")
+}
+
+func (s *SimulationSuite) TestRelation(c *gc.C) {
+	mysql := s.AddTestingService(c, "mysql", s.addCharm(c, "mysql", map[string]string{
+		"server-relation-joined": "relation-set database=blog",
+	}))
+	wordpress := s.AddTestingService(c, "wordpress", s.addCharm(c, "wordpress", map[string]string{
+		"db-relation-changed": `[ "$(relation-get database)" = blog ] || exit 0`,
+	}))
+	s.addUnit(c, mysql)
+	s.addUnit(c, wordpress)
+	eps, err := s.State.InferEndpoints([]string{"wordpress", "mysql"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddRelation(eps...)
+	c.Assert(err, gc.IsNil)
+
+	s.waitHookLog(c, "mysql/0 server-relation-joined wordpress/0")
+	s.waitHookLog(c, "wordpress/0 db-relation-changed mysql/0")
+	s.WaitUnitStatus(c, "wordpress/0", params.StatusStarted)
+}
+
+func (s *SimulationSuite) TestDestroy(c *gc.C) {
+	ch := s.addCharm(c, "wordpress", map[string]string{
+		"stop": "",
+	})
+	unit := s.addUnit(c, s.AddTestingService(c, "wordpress", ch))
+	s.WaitUnitStatus(c, unit.Name(), params.StatusStarted)
+	machineId, err := unit.AssignedMachineId()
+	c.Assert(err, gc.IsNil)
+
+	err = unit.Destroy()
+	c.Assert(err, gc.IsNil)
+	s.waitHookLog(c, "wordpress/0 stop")
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		err := unit.Refresh()
+		if errors.IsNotFound(err) {
+			break
+		}
+		c.Assert(err, gc.IsNil)
+		if !a.HasNext() {
+			c.Fatalf("timed out waiting for unit to be removed")
+		}
+	}
+
+	machine, err := s.State.Machine(machineId)
+	c.Assert(err, gc.IsNil)
+	err = machine.Destroy()
+	c.Assert(err, gc.IsNil)
+	s.WaitMachineRemoved(c, machineId)
+}
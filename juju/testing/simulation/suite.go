@@ -0,0 +1,99 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package simulation
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	gc "launchpad.net/gocheck"
+
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state/api/params"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/uniter/harness"
+)
+
+func init() {
+	// The test binary provides the hook tools of the simulated units;
+	// see Suite.
+	if code, ok := harness.ToolMain(os.Args); ok {
+		os.Exit(code)
+	}
+}
+
+// Suite provides a JujuConnSuite whose environment is simulated: for
+// each test, a Simulation runs the agents of the machines added to the
+// environment and of the units deployed to them.
+//
+// The hook tools are links to the test binary, which runs them with
+// harness.ToolMain when it is started through one, so no jujud need be
+// built.
+type Suite struct {
+	jujutesting.JujuConnSuite
+
+	// Simulation holds the simulation running for the current test.
+	Simulation *Simulation
+
+	jujud string
+}
+
+func (s *Suite) SetUpSuite(c *gc.C) {
+	s.JujuConnSuite.SetUpSuite(c)
+	jujud, err := filepath.Abs(os.Args[0])
+	c.Assert(err, gc.IsNil)
+	s.jujud = jujud
+}
+
+func (s *Suite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	jujutesting.AddStateServerMachine(c, s.State)
+	s.Simulation = New(Params{
+		State:   s.State,
+		Environ: s.Environ,
+		APIInfo: s.APIInfo(c),
+		DataDir: c.MkDir(),
+		Jujud:   s.jujud,
+	})
+}
+
+func (s *Suite) TearDownTest(c *gc.C) {
+	if s.Simulation != nil {
+		c.Check(s.Simulation.Stop(), gc.IsNil)
+		s.Simulation = nil
+	}
+	s.JujuConnSuite.TearDownTest(c)
+}
+
+// WaitUnitStatus waits for the named unit to have the given status,
+// failing the test if it does not, or if one of its hooks fails first.
+func (s *Suite) WaitUnitStatus(c *gc.C, unitName string, status params.Status) {
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		unit, err := s.State.Unit(unitName)
+		c.Assert(err, gc.IsNil)
+		got, info, _, err := unit.Status()
+		c.Assert(err, gc.IsNil)
+		if got == status {
+			return
+		}
+		if got == params.StatusError {
+			c.Fatalf("unit %q is in error: %s", unitName, info)
+		}
+	}
+	c.Fatalf("timed out waiting for unit %q to be %s", unitName, status)
+}
+
+// WaitMachineRemoved waits for the machine with the given id to be
+// removed from state, failing the test if it is not.
+func (s *Suite) WaitMachineRemoved(c *gc.C, id string) {
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		_, err := s.State.Machine(id)
+		if errors.IsNotFound(err) {
+			return
+		}
+		c.Assert(err, gc.IsNil)
+	}
+	c.Fatalf("timed out waiting for machine %s to be removed", id)
+}
@@ -60,6 +60,7 @@ func (s *adminSuite) TestEnsureAdminUser(c *gc.C) {
 	s.PatchValue(mongo.ProcessSignal, func(*os.Process, os.Signal) error {
 		return nil
 	})
+	s.PatchValue(mongo.LoopbackIPv6Available, func() bool { return true })
 
 	// First call succeeds, as there are no users yet.
 	added, err := s.ensureAdminUser(c, dialInfo, "whomever", "whatever")
@@ -79,7 +80,8 @@ func (s *adminSuite) TestEnsureAdminUser(c *gc.C) {
 		"--sslOnNormalPorts",
 		"--sslPEMKeyFile", "server.pem",
 		"--sslPEMKeyPassword", "ignored",
-		"--bind_ip", "127.0.0.1",
+		"--bind_ip", "127.0.0.1,::1",
+		"--ipv6",
 		"--port", portString,
 		"--noprealloc",
 		"--syslog",
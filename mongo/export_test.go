@@ -4,10 +4,11 @@
 package mongo
 
 var (
-	MakeJournalDirs = makeJournalDirs
-	MongoConfigPath = &mongoConfigPath
-	NoauthCommand   = noauthCommand
-	ProcessSignal   = &processSignal
+	MakeJournalDirs       = makeJournalDirs
+	MongoConfigPath       = &mongoConfigPath
+	NoauthCommand         = noauthCommand
+	ProcessSignal         = &processSignal
+	LoopbackIPv6Available = &loopbackIPv6Available
 
 	SharedSecretPath = sharedSecretPath
 	SSLKeyPath       = sslKeyPath
@@ -365,18 +365,37 @@ func noauthCommand(dataDir string, port int) (*exec.Cmd, error) {
 	if err != nil {
 		return nil, err
 	}
-	cmd := exec.Command(mongoPath,
+	// mongod refuses to start if it cannot bind to all the given
+	// addresses, so only bind to ::1 when the host has it.
+	bindIP := []string{"--bind_ip", "127.0.0.1"}
+	if loopbackIPv6Available() {
+		bindIP = []string{"--bind_ip", "127.0.0.1,::1", "--ipv6"}
+	}
+	args := []string{
 		"--noauth",
 		"--dbpath", dbDir,
 		"--sslOnNormalPorts",
 		"--sslPEMKeyFile", sslKeyFile,
 		"--sslPEMKeyPassword", "ignored",
-		"--bind_ip", "127.0.0.1",
+	}
+	args = append(args, bindIP...)
+	args = append(args,
 		"--port", fmt.Sprint(port),
 		"--noprealloc",
 		"--syslog",
 		"--smallfiles",
 		"--journal",
 	)
-	return cmd, nil
+	return exec.Command(mongoPath, args...), nil
+}
+
+// loopbackIPv6Available reports whether the IPv6 loopback address
+// can be bound to. It cannot if IPv6 is disabled on the host.
+var loopbackIPv6Available = func() bool {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
 }
@@ -264,6 +264,23 @@ func (s *MongoSuite) TestNoAuthCommandWithJournal(c *gc.C) {
 	c.Assert(isJournalPresent, jc.IsTrue)
 }
 
+func (s *MongoSuite) TestNoAuthCommandBindsIPv6WhenAvailable(c *gc.C) {
+	dataDir := c.MkDir()
+
+	s.PatchValue(mongo.LoopbackIPv6Available, func() bool { return true })
+	cmd, err := mongo.NoauthCommand(dataDir, 1234)
+	c.Assert(err, gc.IsNil)
+	args := strings.Join(cmd.Args, " ")
+	c.Assert(strings.Contains(args, " --bind_ip 127.0.0.1,::1 --ipv6 "), jc.IsTrue)
+
+	s.PatchValue(mongo.LoopbackIPv6Available, func() bool { return false })
+	cmd, err = mongo.NoauthCommand(dataDir, 1234)
+	c.Assert(err, gc.IsNil)
+	args = strings.Join(cmd.Args, " ")
+	c.Assert(strings.Contains(args, " --bind_ip 127.0.0.1 "), jc.IsTrue)
+	c.Assert(strings.Contains(args, "--ipv6"), jc.IsFalse)
+}
+
 func (s *MongoSuite) TestRemoveService(c *gc.C) {
 	err := mongo.RemoveService("namespace")
 	c.Assert(err, gc.IsNil)
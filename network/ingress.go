@@ -0,0 +1,90 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// AnyIPv4CIDR is the source CIDR used for ports that are open to
+// every IPv4 address.
+const AnyIPv4CIDR = "0.0.0.0/0"
+
+// IngressRule allows traffic to reach a port from the addresses
+// within a source CIDR.
+type IngressRule struct {
+	Port
+	SourceCIDR string
+}
+
+// String implements Stringer.
+func (r IngressRule) String() string {
+	return fmt.Sprintf("%v from %s", r.Port, r.SourceCIDR)
+}
+
+// IsUnrestricted returns whether the rule allows traffic from
+// any address.
+func (r IngressRule) IsUnrestricted() bool {
+	return r.SourceCIDR == "" || r.SourceCIDR == AnyIPv4CIDR
+}
+
+// IngressRulesForPorts returns the rules allowing traffic to each of
+// the given ports from each of the given source CIDRs. If no CIDRs
+// are given, the ports are open to any address.
+func IngressRulesForPorts(ports []Port, sourceCIDRs []string) []IngressRule {
+	if len(sourceCIDRs) == 0 {
+		sourceCIDRs = []string{AnyIPv4CIDR}
+	}
+	rules := make([]IngressRule, 0, len(ports)*len(sourceCIDRs))
+	for _, port := range ports {
+		for _, cidr := range sourceCIDRs {
+			rules = append(rules, IngressRule{Port: port, SourceCIDR: cidr})
+		}
+	}
+	return rules
+}
+
+// IsIPv6CIDR returns whether the given CIDR holds IPv6 addresses.
+func IsIPv6CIDR(cidr string) bool {
+	ip, _, err := net.ParseCIDR(cidr)
+	return err == nil && ip.To4() == nil
+}
+
+// ValidateSourceCIDRs checks that all the given values are valid
+// CIDRs and returns them in their canonical form.
+func ValidateSourceCIDRs(cidrs []string) ([]string, error) {
+	result := make([]string, len(cidrs))
+	for i, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid source CIDR %q", cidr)
+		}
+		result[i] = ipNet.String()
+	}
+	return result, nil
+}
+
+type ingressRuleSlice []IngressRule
+
+func (r ingressRuleSlice) Len() int      { return len(r) }
+func (r ingressRuleSlice) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r ingressRuleSlice) Less(i, j int) bool {
+	r1 := r[i]
+	r2 := r[j]
+	if r1.Protocol != r2.Protocol {
+		return r1.Protocol < r2.Protocol
+	}
+	if r1.Number != r2.Number {
+		return r1.Number < r2.Number
+	}
+	return r1.SourceCIDR < r2.SourceCIDR
+}
+
+// SortIngressRules sorts the given rules by port, then by source CIDR.
+func SortIngressRules(rules []IngressRule) {
+	sort.Sort(ingressRuleSlice(rules))
+}
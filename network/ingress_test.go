@@ -0,0 +1,81 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package network_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/testing"
+)
+
+type IngressRuleSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&IngressRuleSuite{})
+
+func (*IngressRuleSuite) TestIngressRulesForPorts(c *gc.C) {
+	ports := []network.Port{{"tcp", 80}, {"udp", 53}}
+	rules := network.IngressRulesForPorts(ports, nil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "0.0.0.0/0"},
+		{network.Port{"udp", 53}, "0.0.0.0/0"},
+	})
+	rules = network.IngressRulesForPorts(ports, []string{"10.0.0.0/8", "192.168.1.0/24"})
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+		{network.Port{"tcp", 80}, "192.168.1.0/24"},
+		{network.Port{"udp", 53}, "10.0.0.0/8"},
+		{network.Port{"udp", 53}, "192.168.1.0/24"},
+	})
+}
+
+func (*IngressRuleSuite) TestIsUnrestricted(c *gc.C) {
+	c.Assert(network.IngressRule{network.Port{"tcp", 80}, ""}.IsUnrestricted(), jc.IsTrue)
+	c.Assert(network.IngressRule{network.Port{"tcp", 80}, "0.0.0.0/0"}.IsUnrestricted(), jc.IsTrue)
+	c.Assert(network.IngressRule{network.Port{"tcp", 80}, "10.0.0.0/8"}.IsUnrestricted(), jc.IsFalse)
+}
+
+func (*IngressRuleSuite) TestIsIPv6CIDR(c *gc.C) {
+	c.Assert(network.IsIPv6CIDR("2001:db8::/32"), jc.IsTrue)
+	c.Assert(network.IsIPv6CIDR("::/0"), jc.IsTrue)
+	c.Assert(network.IsIPv6CIDR("10.0.0.0/8"), jc.IsFalse)
+	c.Assert(network.IsIPv6CIDR("foo"), jc.IsFalse)
+}
+
+func (*IngressRuleSuite) TestValidateSourceCIDRs(c *gc.C) {
+	cidrs, err := network.ValidateSourceCIDRs([]string{"10.1.2.3/8", " 192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(cidrs, jc.DeepEquals, []string{"10.0.0.0/8", "192.168.1.0/24"})
+
+	cidrs, err = network.ValidateSourceCIDRs([]string{"2001:db8::1/32", "::/0"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(cidrs, jc.DeepEquals, []string{"2001:db8::/32", "::/0"})
+
+	_, err = network.ValidateSourceCIDRs([]string{"10.0.0.0/8", "foo"})
+	c.Assert(err, gc.ErrorMatches, `invalid source CIDR "foo"`)
+}
+
+func (*IngressRuleSuite) TestSortIngressRules(c *gc.C) {
+	rules := []network.IngressRule{
+		{network.Port{"udp", 53}, "10.0.0.0/8"},
+		{network.Port{"tcp", 80}, "192.168.1.0/24"},
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+		{network.Port{"tcp", 22}, "0.0.0.0/0"},
+	}
+	network.SortIngressRules(rules)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 22}, "0.0.0.0/0"},
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+		{network.Port{"tcp", 80}, "192.168.1.0/24"},
+		{network.Port{"udp", 53}, "10.0.0.0/8"},
+	})
+}
+
+func (*IngressRuleSuite) TestString(c *gc.C) {
+	rule := network.IngressRule{network.Port{"tcp", 80}, "10.0.0.0/8"}
+	c.Assert(rule.String(), gc.Equals, "80/tcp from 10.0.0.0/8")
+}
@@ -38,6 +38,24 @@ type BasicInfo struct {
 	VLANTag int
 }
 
+// SubnetInfo describes a subnet known to the provider, which juju
+// might not yet know about.
+type SubnetInfo struct {
+	// CIDR of the subnet, in 123.45.67.0/24 format.
+	CIDR string
+
+	// ProviderId is a provider-specific subnet id.
+	ProviderId Id
+
+	// VLANTag needs to be between 1 and 4094 for VLANs and 0 for
+	// normal networks. It's defined by IEEE 802.1Q standard.
+	VLANTag int
+
+	// AvailabilityZones holds the names of the availability zones
+	// the subnet spans, if the provider has such a concept.
+	AvailabilityZones []string
+}
+
 // Info describes a single network interface available on an instance.
 // For providers that support networks, this will be available at
 // StartInstance() time.
@@ -66,6 +84,37 @@ type Info struct {
 	// Disabled is true when the interface needs to be disabled on the
 	// machine, e.g. not to configure it.
 	Disabled bool
+
+	// MTU is the interface's maximum transmission unit, in bytes.
+	// When 0, the default MTU for the device is used.
+	MTU int
+
+	// BondMode is the bonding mode (e.g. "802.3ad" or
+	// "active-backup") of a bonded interface. It's only used when
+	// BondSlaves is not empty.
+	BondMode string
+
+	// BondSlaves holds the raw names of the interfaces aggregated by
+	// a bonded interface (e.g. "eth2" and "eth3" for "bond0"). It's
+	// empty for interfaces that are not bonds.
+	BondSlaves []string
+
+	// Routes holds the static routes to set up through the
+	// interface.
+	Routes []Route
+}
+
+// Route describes a static route to a network.
+type Route struct {
+	// DestinationCIDR is the network the route leads to, in
+	// 123.45.67.0/24 format.
+	DestinationCIDR string
+
+	// GatewayAddress is the address of the next hop.
+	GatewayAddress string
+
+	// Metric is the route's metric; lower values are preferred.
+	Metric int
 }
 
 // ActualInterfaceName returns raw interface name for raw interface (e.g. "eth0") and
@@ -78,9 +127,15 @@ func (i *Info) ActualInterfaceName() string {
 }
 
 // IsVirtual returns true when the interface is a virtual device, as
-// opposed to a physical device (e.g. a VLAN or a network alias)
+// opposed to a physical device (e.g. a VLAN, a bond or a network alias)
 func (i *Info) IsVirtual() bool {
-	return i.VLANTag > 0
+	return i.VLANTag > 0 || i.IsBond()
+}
+
+// IsBond returns true when the interface is a bond of other
+// interfaces.
+func (i *Info) IsBond() bool {
+	return len(i.BondSlaves) > 0
 }
 
 // PreferIPv6Getter will be implemented by both the environment and agent
@@ -22,6 +22,7 @@ func (n *InfoSuite) SetUpTest(c *gc.C) {
 		{VLANTag: 1, InterfaceName: "eth0"},
 		{VLANTag: 0, InterfaceName: "eth1"},
 		{VLANTag: 42, InterfaceName: "br2"},
+		{InterfaceName: "bond0", BondMode: "802.3ad", BondSlaves: []string{"eth2", "eth3"}},
 	}
 }
 
@@ -29,12 +30,20 @@ func (n *InfoSuite) TestActualInterfaceName(c *gc.C) {
 	c.Check(n.info[0].ActualInterfaceName(), gc.Equals, "eth0.1")
 	c.Check(n.info[1].ActualInterfaceName(), gc.Equals, "eth1")
 	c.Check(n.info[2].ActualInterfaceName(), gc.Equals, "br2.42")
+	c.Check(n.info[3].ActualInterfaceName(), gc.Equals, "bond0")
 }
 
 func (n *InfoSuite) TestIsVirtual(c *gc.C) {
 	c.Check(n.info[0].IsVirtual(), jc.IsTrue)
 	c.Check(n.info[1].IsVirtual(), jc.IsFalse)
 	c.Check(n.info[2].IsVirtual(), jc.IsTrue)
+	c.Check(n.info[3].IsVirtual(), jc.IsTrue)
+}
+
+func (n *InfoSuite) TestIsBond(c *gc.C) {
+	c.Check(n.info[0].IsBond(), jc.IsFalse)
+	c.Check(n.info[1].IsBond(), jc.IsFalse)
+	c.Check(n.info[3].IsBond(), jc.IsTrue)
 }
 
 type NetworkSuite struct {
@@ -1031,6 +1031,13 @@ func (*azureEnviron) AllocateAddress(_ instance.Id, _ network.Id) (network.Addre
 	return network.Address{}, errors.NotImplementedf("AllocateAddress")
 }
 
+// ReleaseAddress releases an address allocated for the given instance
+// on the given network. This is not implemented on the Azure provider
+// yet.
+func (*azureEnviron) ReleaseAddress(_ instance.Id, _ network.Id, _ network.Address) error {
+	return errors.NotImplementedf("ReleaseAddress")
+}
+
 // ListNetworks returns basic information about all networks known
 // by the provider for the environment. They may be unknown to juju
 // yet (i.e. when called initially or when a new network was created).
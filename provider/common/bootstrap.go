@@ -78,6 +78,9 @@ func Bootstrap(ctx environs.BootstrapContext, env environs.Environ, args environ
 	fmt.Fprintf(ctx.GetStderr(), " - %s\n", inst.Id())
 	machineConfig.InstanceId = inst.Id()
 	machineConfig.HardwareCharacteristics = hw
+	if err := addStateServerCertAddresses(env.Config(), inst, machineConfig); err != nil {
+		return err
+	}
 
 	err = SaveState(env.Storage(), &BootstrapState{
 		StateInstances: []instance.Id{inst.Id()},
@@ -88,6 +91,39 @@ func Bootstrap(ctx environs.BootstrapContext, env environs.Environ, args environ
 	return FinishBootstrap(ctx, client, inst, machineConfig)
 }
 
+// addStateServerCertAddresses regenerates the bootstrap machine's
+// state server certificate so that it is also valid for the IP
+// addresses of the instance, IPv4 or IPv6, which are not known until
+// it has started. Addresses the instance does not report yet are not
+// included.
+func addStateServerCertAddresses(cfg *config.Config, inst instance.Instance, mcfg *cloudinit.MachineConfig) error {
+	if mcfg.StateServingInfo == nil {
+		return nil
+	}
+	addrs, err := inst.Addresses()
+	if err != nil {
+		// The certificate is still valid for local connections.
+		logger.Warningf("cannot get addresses of bootstrap instance: %v", err)
+		return nil
+	}
+	var ipAddresses []string
+	for _, addr := range addrs {
+		if addr.Type != network.HostName {
+			ipAddresses = append(ipAddresses, addr.Value)
+		}
+	}
+	if len(ipAddresses) == 0 {
+		return nil
+	}
+	cert, key, err := cfg.GenerateStateServerCertAndKey(ipAddresses)
+	if err != nil {
+		return fmt.Errorf("cannot generate state server certificate: %v", err)
+	}
+	mcfg.StateServingInfo.Cert = cert
+	mcfg.StateServingInfo.PrivateKey = key
+	return nil
+}
+
 // GenerateSystemSSHKey creates a new key for the system identity. The
 // authorized_keys in the environment config is updated to include the public
 // key for the generated key.
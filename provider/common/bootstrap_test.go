@@ -13,6 +13,7 @@ import (
 	jc "github.com/juju/testing/checkers"
 	gc "launchpad.net/gocheck"
 
+	"github.com/juju/juju/cert"
 	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/cloudinit"
@@ -22,6 +23,7 @@ import (
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/network"
 	"github.com/juju/juju/provider/common"
+	"github.com/juju/juju/state/api/params"
 	coretesting "github.com/juju/juju/testing"
 	"github.com/juju/juju/tools"
 	"github.com/juju/juju/utils/ssh"
@@ -220,6 +222,48 @@ func (s *BootstrapSuite) TestSuccess(c *gc.C) {
 	c.Assert(authKeys, jc.HasSuffix, "juju-system-key\n")
 }
 
+func (s *BootstrapSuite) TestStateServerCertIncludesInstanceAddresses(c *gc.C) {
+	stor := newStorage(s, c)
+	var machineConfig *cloudinit.MachineConfig
+	startInstance := func(
+		_ string, _ constraints.Value, _ []string, _ tools.List, mcfg *cloudinit.MachineConfig,
+	) (
+		instance.Instance, *instance.HardwareCharacteristics, []network.Info, error,
+	) {
+		mcfg.StateServingInfo = &params.StateServingInfo{}
+		machineConfig = mcfg
+		return &mockInstance{
+			id: "i-success",
+			addresses: []network.Address{
+				network.NewAddress("2001:db8::1", network.ScopeCloudLocal),
+				network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+				network.NewAddress("example.com", network.ScopePublic),
+			},
+		}, nil, nil, nil
+	}
+	restore := envtesting.DisableFinishBootstrap()
+	defer restore()
+
+	env := &mockEnviron{
+		storage:       stor,
+		startInstance: startInstance,
+		config:        configGetter(c),
+	}
+	ctx := coretesting.Context(c)
+	err := common.Bootstrap(ctx, env, environs.BootstrapParams{})
+	c.Assert(err, gc.IsNil)
+
+	info := machineConfig.StateServingInfo
+	srvCert, _, err := cert.ParseCertAndKey(info.Cert, info.PrivateKey)
+	c.Assert(err, gc.IsNil)
+	var ipAddresses []string
+	for _, ip := range srvCert.IPAddresses {
+		ipAddresses = append(ipAddresses, ip.String())
+	}
+	c.Assert(ipAddresses, jc.SameContents, []string{"127.0.0.1", "::1", "2001:db8::1", "10.0.0.1"})
+	c.Assert(srvCert.DNSNames, gc.HasLen, 0)
+}
+
 type neverRefreshes struct {
 }
 
@@ -17,6 +17,9 @@
 // The DNS name of instances is the same as the Id,
 // with ".dns" appended.
 //
+// If the "ipv6-only" property is true, instances and the
+// state server are given IPv6 addresses only.
+//
 // To avoid enumerating all possible series and architectures,
 // any series or architecture with the prefix "unknown" is
 // treated as bad when starting a new instance.
@@ -94,14 +97,17 @@ func SampleConfig() testing.Attrs {
 
 // stateInfo returns a *state.Info which allows clients to connect to the
 // shared dummy state, if it exists. If preferIPv6 is true, an IPv6 endpoint
-// will be added as primary.
-func stateInfo(preferIPv6 bool) *authentication.MongoInfo {
+// will be added as primary. If ipv6Only is true, only the IPv6 endpoint
+// is used.
+func stateInfo(preferIPv6, ipv6Only bool) *authentication.MongoInfo {
 	if gitjujutesting.MgoServer.Addr() == "" {
 		panic("dummy environ state tests must be run with MgoTestPackage")
 	}
 	mongoPort := strconv.Itoa(gitjujutesting.MgoServer.Port())
 	var addrs []string
-	if preferIPv6 {
+	if ipv6Only {
+		addrs = []string{net.JoinHostPort("::1", mongoPort)}
+	} else if preferIPv6 {
 		addrs = []string{
 			net.JoinHostPort("::1", mongoPort),
 			net.JoinHostPort("localhost", mongoPort),
@@ -138,11 +144,24 @@ type OpAllocateAddress struct {
 	Address    network.Address
 }
 
+type OpReleaseAddress struct {
+	Env        string
+	InstanceId instance.Id
+	NetworkId  network.Id
+	Address    network.Address
+}
+
 type OpListNetworks struct {
 	Env  string
 	Info []network.BasicInfo
 }
 
+type OpSubnets struct {
+	Env        string
+	InstanceId instance.Id
+	Info       []network.SubnetInfo
+}
+
 type OpStartInstance struct {
 	Env           string
 	MachineId     string
@@ -168,6 +187,7 @@ type OpOpenPorts struct {
 	MachineId  string
 	InstanceId instance.Id
 	Ports      []network.Port
+	Rules      []network.IngressRule
 }
 
 type OpClosePorts struct {
@@ -175,6 +195,7 @@ type OpClosePorts struct {
 	MachineId  string
 	InstanceId instance.Id
 	Ports      []network.Port
+	Rules      []network.IngressRule
 }
 
 type OpPutFile struct {
@@ -209,7 +230,7 @@ type environState struct {
 	maxId        int // maximum instance id allocated so far.
 	maxAddr      int // maximum allocated address last byte
 	insts        map[instance.Id]*dummyInstance
-	globalPorts  map[network.Port]bool
+	globalRules  map[network.IngressRule]bool
 	bootstrapped bool
 	storageDelay time.Duration
 	storage      *storageServer
@@ -218,6 +239,7 @@ type environState struct {
 	apiServer    *apiserver.Server
 	apiState     *state.State
 	preferIPv6   bool
+	ipv6Only     bool
 }
 
 // environ represents a client's connection to a given environment's
@@ -233,6 +255,9 @@ type environ struct {
 var _ imagemetadata.SupportsCustomSources = (*environ)(nil)
 var _ tools.SupportsCustomSources = (*environ)(nil)
 var _ environs.Environ = (*environ)(nil)
+var _ environs.GlobalIngressFirewaller = (*environ)(nil)
+var _ environs.SubnetDiscoverer = (*environ)(nil)
+var _ environs.InstanceIngressFirewaller = (*dummyInstance)(nil)
 
 // discardOperations discards all Operations written to it.
 var discardOperations chan<- Operation
@@ -326,7 +351,7 @@ func newState(name string, ops chan<- Operation, policy state.Policy) *environSt
 		ops:         ops,
 		statePolicy: policy,
 		insts:       make(map[instance.Id]*dummyInstance),
-		globalPorts: make(map[network.Port]bool),
+		globalRules: make(map[network.IngressRule]bool),
 	}
 	s.storage = newStorageServer(s, "/"+name+"/private")
 	s.listenStorage()
@@ -405,11 +430,13 @@ var configFields = schema.Fields{
 	"broken":       schema.String(),
 	"secret":       schema.String(),
 	"state-id":     schema.String(),
+	"ipv6-only":    schema.Bool(),
 }
 var configDefaults = schema.Defaults{
-	"broken":   "",
-	"secret":   "pork",
-	"state-id": schema.Omit,
+	"broken":    "",
+	"secret":    "pork",
+	"state-id":  schema.Omit,
+	"ipv6-only": false,
 }
 
 type environConfig struct {
@@ -425,6 +452,10 @@ func (c *environConfig) broken() string {
 	return c.attrs["broken"].(string)
 }
 
+func (c *environConfig) ipv6Only() bool {
+	return c.attrs["ipv6-only"].(bool)
+}
+
 func (c *environConfig) secret() string {
 	return c.attrs["secret"].(string)
 }
@@ -648,13 +679,18 @@ func (e *environ) Bootstrap(ctx environs.BootstrapContext, args environs.Bootstr
 		return fmt.Errorf("environment is already bootstrapped")
 	}
 	estate.preferIPv6 = e.Config().PreferIPv6()
+	estate.ipv6Only = e.ecfg().ipv6Only()
 
 	// Create an instance for the bootstrap node.
 	logger.Infof("creating bootstrap instance")
+	bootstrapHost := "localhost"
+	if estate.ipv6Only {
+		bootstrapHost = "::1"
+	}
 	i := &dummyInstance{
 		id:           BootstrapInstanceId,
-		addresses:    network.NewAddresses("localhost"),
-		ports:        make(map[network.Port]bool),
+		addresses:    network.NewAddresses(bootstrapHost),
+		rules:        make(map[network.IngressRule]bool),
 		machineId:    agent.BootstrapMachineId,
 		series:       series,
 		firewallMode: e.Config().FirewallMode(),
@@ -667,7 +703,7 @@ func (e *environ) Bootstrap(ctx environs.BootstrapContext, args environs.Bootstr
 		// TODO(rog) factor out relevant code from cmd/jujud/bootstrap.go
 		// so that we can call it here.
 
-		info := stateInfo(estate.preferIPv6)
+		info := stateInfo(estate.preferIPv6, estate.ipv6Only)
 		st, err := state.Initialize(info, cfg, mongo.DefaultDialOpts(), estate.statePolicy)
 		if err != nil {
 			panic(err)
@@ -801,15 +837,20 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 	series := args.Tools.OneSeries()
 
 	idString := fmt.Sprintf("%s-%d", e.name, estate.maxId)
-	addrs := network.NewAddresses(idString+".dns", "127.0.0.1")
-	if estate.preferIPv6 {
-		addrs = append(addrs, network.NewAddress(fmt.Sprintf("fc00::%x", estate.maxId+1), network.ScopeUnknown))
+	var addrs []network.Address
+	if estate.ipv6Only {
+		addrs = network.NewAddresses(fmt.Sprintf("fc00::%x", estate.maxId+1), "::1")
+	} else {
+		addrs = network.NewAddresses(idString+".dns", "127.0.0.1")
+		if estate.preferIPv6 {
+			addrs = append(addrs, network.NewAddress(fmt.Sprintf("fc00::%x", estate.maxId+1), network.ScopeUnknown))
+		}
 	}
 	logger.Debugf("StartInstance addresses: %v", addrs)
 	i := &dummyInstance{
 		id:           instance.Id(idString),
 		addresses:    addrs,
-		ports:        make(map[network.Port]bool),
+		rules:        make(map[network.IngressRule]bool),
 		machineId:    machineId,
 		series:       series,
 		firewallMode: e.Config().FirewallMode(),
@@ -959,10 +1000,11 @@ func (env *environ) AllocateAddress(instId instance.Id, netId network.Id) (netwo
 	// and addresses, make sure we return a valid address
 	// for the given network, and we also have the network
 	// already registered.
-	newAddress := network.NewAddress(
-		fmt.Sprintf("0.1.2.%d", estate.maxAddr),
-		network.ScopeCloudLocal,
-	)
+	value := fmt.Sprintf("0.1.2.%d", estate.maxAddr)
+	if estate.ipv6Only {
+		value = fmt.Sprintf("fc00:1:2::%x", estate.maxAddr)
+	}
+	newAddress := network.NewAddress(value, network.ScopeCloudLocal)
 	estate.ops <- OpAllocateAddress{
 		Env:        env.name,
 		InstanceId: instId,
@@ -972,6 +1014,28 @@ func (env *environ) AllocateAddress(instId instance.Id, netId network.Id) (netwo
 	return newAddress, nil
 }
 
+// ReleaseAddress releases an address allocated for the given
+// instance on the given network.
+func (env *environ) ReleaseAddress(instId instance.Id, netId network.Id, addr network.Address) error {
+	if err := env.checkBroken("ReleaseAddress"); err != nil {
+		return err
+	}
+
+	estate, err := env.state()
+	if err != nil {
+		return err
+	}
+	estate.mu.Lock()
+	defer estate.mu.Unlock()
+	estate.ops <- OpReleaseAddress{
+		Env:        env.name,
+		InstanceId: instId,
+		NetworkId:  netId,
+		Address:    addr,
+	}
+	return nil
+}
+
 // ListNetworks implements environs.Environ.ListNetworks.
 func (env *environ) ListNetworks() ([]network.BasicInfo, error) {
 	if err := env.checkBroken("ListNetworks"); err != nil {
@@ -996,6 +1060,36 @@ func (env *environ) ListNetworks() ([]network.BasicInfo, error) {
 	return netInfo, nil
 }
 
+// Subnets is specified in the environs.SubnetDiscoverer interface.
+// Every instance is connected to both dummy networks.
+func (env *environ) Subnets(instId instance.Id) ([]network.SubnetInfo, error) {
+	if err := env.checkBroken("Subnets"); err != nil {
+		return nil, err
+	}
+
+	estate, err := env.state()
+	if err != nil {
+		return nil, err
+	}
+	estate.mu.Lock()
+	defer estate.mu.Unlock()
+	if instId != "" {
+		if _, ok := estate.insts[instId]; !ok {
+			return nil, fmt.Errorf("instance %q not found", instId)
+		}
+	}
+	subnets := []network.SubnetInfo{
+		{CIDR: "0.10.0.0/16", ProviderId: "dummy-private"},
+		{CIDR: "0.20.0.0/24", ProviderId: "dummy-public"},
+	}
+	estate.ops <- OpSubnets{
+		Env:        env.name,
+		InstanceId: instId,
+		Info:       subnets,
+	}
+	return subnets, nil
+}
+
 func (e *environ) AllInstances() ([]instance.Instance, error) {
 	defer delay()
 	if err := e.checkBroken("AllInstances"); err != nil {
@@ -1015,6 +1109,24 @@ func (e *environ) AllInstances() ([]instance.Instance, error) {
 }
 
 func (e *environ) OpenPorts(ports []network.Port) error {
+	return e.OpenIngressRules(network.IngressRulesForPorts(ports, nil))
+}
+
+func (e *environ) ClosePorts(ports []network.Port) error {
+	return e.CloseIngressRules(network.IngressRulesForPorts(ports, nil))
+}
+
+func (e *environ) Ports() (ports []network.Port, err error) {
+	rules, err := e.IngressRules()
+	if err != nil {
+		return nil, err
+	}
+	return unrestrictedPorts(rules), nil
+}
+
+// OpenIngressRules is specified in the environs.GlobalIngressFirewaller
+// interface.
+func (e *environ) OpenIngressRules(rules []network.IngressRule) error {
 	if mode := e.ecfg().FirewallMode(); mode != config.FwGlobal {
 		return fmt.Errorf("invalid firewall mode %q for opening ports on environment", mode)
 	}
@@ -1024,13 +1136,15 @@ func (e *environ) OpenPorts(ports []network.Port) error {
 	}
 	estate.mu.Lock()
 	defer estate.mu.Unlock()
-	for _, p := range ports {
-		estate.globalPorts[p] = true
+	for _, r := range rules {
+		estate.globalRules[r] = true
 	}
 	return nil
 }
 
-func (e *environ) ClosePorts(ports []network.Port) error {
+// CloseIngressRules is specified in the environs.GlobalIngressFirewaller
+// interface.
+func (e *environ) CloseIngressRules(rules []network.IngressRule) error {
 	if mode := e.ecfg().FirewallMode(); mode != config.FwGlobal {
 		return fmt.Errorf("invalid firewall mode %q for closing ports on environment", mode)
 	}
@@ -1040,13 +1154,15 @@ func (e *environ) ClosePorts(ports []network.Port) error {
 	}
 	estate.mu.Lock()
 	defer estate.mu.Unlock()
-	for _, p := range ports {
-		delete(estate.globalPorts, p)
+	for _, r := range rules {
+		delete(estate.globalRules, r)
 	}
 	return nil
 }
 
-func (e *environ) Ports() (ports []network.Port, err error) {
+// IngressRules is specified in the environs.GlobalIngressFirewaller
+// interface.
+func (e *environ) IngressRules() (rules []network.IngressRule, err error) {
 	if mode := e.ecfg().FirewallMode(); mode != config.FwGlobal {
 		return nil, fmt.Errorf("invalid firewall mode %q for retrieving ports from environment", mode)
 	}
@@ -1056,20 +1172,46 @@ func (e *environ) Ports() (ports []network.Port, err error) {
 	}
 	estate.mu.Lock()
 	defer estate.mu.Unlock()
-	for p := range estate.globalPorts {
-		ports = append(ports, p)
+	for r := range estate.globalRules {
+		rules = append(rules, r)
 	}
-	network.SortPorts(ports)
+	network.SortIngressRules(rules)
 	return
 }
 
+// unrestrictedPorts returns the ports of those rules that allow
+// access from any address.
+func unrestrictedPorts(rules []network.IngressRule) []network.Port {
+	var ports []network.Port
+	for _, r := range rules {
+		if r.IsUnrestricted() {
+			ports = append(ports, r.Port)
+		}
+	}
+	network.SortPorts(ports)
+	return ports
+}
+
+// rulePorts returns the distinct ports of the given rules.
+func rulePorts(rules []network.IngressRule) []network.Port {
+	seen := make(map[network.Port]bool)
+	var ports []network.Port
+	for _, r := range rules {
+		if !seen[r.Port] {
+			seen[r.Port] = true
+			ports = append(ports, r.Port)
+		}
+	}
+	return ports
+}
+
 func (*environ) Provider() environs.EnvironProvider {
 	return &providerInstance
 }
 
 type dummyInstance struct {
 	state        *environState
-	ports        map[network.Port]bool
+	rules        map[network.IngressRule]bool
 	id           instance.Id
 	status       string
 	machineId    string
@@ -1118,8 +1260,26 @@ func (inst *dummyInstance) Addresses() ([]network.Address, error) {
 }
 
 func (inst *dummyInstance) OpenPorts(machineId string, ports []network.Port) error {
+	return inst.OpenIngressRules(machineId, network.IngressRulesForPorts(ports, nil))
+}
+
+func (inst *dummyInstance) ClosePorts(machineId string, ports []network.Port) error {
+	return inst.CloseIngressRules(machineId, network.IngressRulesForPorts(ports, nil))
+}
+
+func (inst *dummyInstance) Ports(machineId string) (ports []network.Port, err error) {
+	rules, err := inst.IngressRules(machineId)
+	if err != nil {
+		return nil, err
+	}
+	return unrestrictedPorts(rules), nil
+}
+
+// OpenIngressRules is specified in the environs.InstanceIngressFirewaller
+// interface.
+func (inst *dummyInstance) OpenIngressRules(machineId string, rules []network.IngressRule) error {
 	defer delay()
-	logger.Infof("openPorts %s, %#v", machineId, ports)
+	logger.Infof("openIngressRules %s, %#v", machineId, rules)
 	if inst.firewallMode != config.FwInstance {
 		return fmt.Errorf("invalid firewall mode %q for opening ports on instance",
 			inst.firewallMode)
@@ -1133,15 +1293,18 @@ func (inst *dummyInstance) OpenPorts(machineId string, ports []network.Port) err
 		Env:        inst.state.name,
 		MachineId:  machineId,
 		InstanceId: inst.Id(),
-		Ports:      ports,
+		Ports:      rulePorts(rules),
+		Rules:      rules,
 	}
-	for _, p := range ports {
-		inst.ports[p] = true
+	for _, r := range rules {
+		inst.rules[r] = true
 	}
 	return nil
 }
 
-func (inst *dummyInstance) ClosePorts(machineId string, ports []network.Port) error {
+// CloseIngressRules is specified in the environs.InstanceIngressFirewaller
+// interface.
+func (inst *dummyInstance) CloseIngressRules(machineId string, rules []network.IngressRule) error {
 	defer delay()
 	if inst.firewallMode != config.FwInstance {
 		return fmt.Errorf("invalid firewall mode %q for closing ports on instance",
@@ -1156,15 +1319,18 @@ func (inst *dummyInstance) ClosePorts(machineId string, ports []network.Port) er
 		Env:        inst.state.name,
 		MachineId:  machineId,
 		InstanceId: inst.Id(),
-		Ports:      ports,
+		Ports:      rulePorts(rules),
+		Rules:      rules,
 	}
-	for _, p := range ports {
-		delete(inst.ports, p)
+	for _, r := range rules {
+		delete(inst.rules, r)
 	}
 	return nil
 }
 
-func (inst *dummyInstance) Ports(machineId string) (ports []network.Port, err error) {
+// IngressRules is specified in the environs.InstanceIngressFirewaller
+// interface.
+func (inst *dummyInstance) IngressRules(machineId string) (rules []network.IngressRule, err error) {
 	defer delay()
 	if inst.firewallMode != config.FwInstance {
 		return nil, fmt.Errorf("invalid firewall mode %q for retrieving ports from instance",
@@ -1175,10 +1341,10 @@ func (inst *dummyInstance) Ports(machineId string) (ports []network.Port, err er
 	}
 	inst.state.mu.Lock()
 	defer inst.state.mu.Unlock()
-	for p := range inst.ports {
-		ports = append(ports, p)
+	for r := range inst.rules {
+		rules = append(rules, r)
 	}
-	network.SortPorts(ports)
+	network.SortIngressRules(rules)
 	return
 }
 
@@ -124,6 +124,32 @@ func (s *suite) TestAllocateAddress(c *gc.C) {
 	assertAllocateAddress(c, e, opc, inst.Id(), netId, expectAddress)
 }
 
+func (s *suite) TestReleaseAddress(c *gc.C) {
+	e := s.bootstrapTestEnviron(c, false)
+
+	inst, _ := jujutesting.AssertStartInstance(c, e, "0")
+	c.Assert(inst, gc.NotNil)
+	address, err := e.AllocateAddress(inst.Id(), "net1")
+	c.Assert(err, gc.IsNil)
+
+	opc := make(chan dummy.Operation, 200)
+	dummy.Listen(opc)
+
+	err = e.ReleaseAddress(inst.Id(), "net1", address)
+	c.Assert(err, gc.IsNil)
+	select {
+	case op := <-opc:
+		c.Assert(op, gc.DeepEquals, dummy.OpReleaseAddress{
+			Env:        e.Config().Name(),
+			InstanceId: inst.Id(),
+			NetworkId:  "net1",
+			Address:    address,
+		})
+	case <-time.After(testing.ShortWait):
+		c.Fatalf("time out wating for operation")
+	}
+}
+
 func (s *suite) TestListNetworks(c *gc.C) {
 	e := s.bootstrapTestEnviron(c, false)
 
@@ -140,6 +166,32 @@ func (s *suite) TestListNetworks(c *gc.C) {
 	assertListNetworks(c, e, opc, expectInfo)
 }
 
+func (s *suite) TestSubnets(c *gc.C) {
+	e := s.bootstrapTestEnviron(c, false)
+	inst, _ := jujutesting.AssertStartInstance(c, e, "0")
+	c.Assert(inst, gc.NotNil)
+
+	opc := make(chan dummy.Operation, 200)
+	dummy.Listen(opc)
+
+	expectInfo := []network.SubnetInfo{
+		{CIDR: "0.10.0.0/16", ProviderId: "dummy-private"},
+		{CIDR: "0.20.0.0/24", ProviderId: "dummy-public"},
+	}
+	subnets, err := e.(environs.SubnetDiscoverer).Subnets(inst.Id())
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnets, jc.DeepEquals, expectInfo)
+	assertSubnets(c, e, opc, inst.Id(), expectInfo)
+
+	subnets, err = e.(environs.SubnetDiscoverer).Subnets("")
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnets, jc.DeepEquals, expectInfo)
+	assertSubnets(c, e, opc, "", expectInfo)
+
+	_, err = e.(environs.SubnetDiscoverer).Subnets("unknown")
+	c.Assert(err, gc.ErrorMatches, `instance "unknown" not found`)
+}
+
 func (s *suite) TestPreferIPv6On(c *gc.C) {
 	e := s.bootstrapTestEnviron(c, true)
 	inst, _ := jujutesting.AssertStartInstance(c, e, "0")
@@ -168,6 +220,26 @@ func (s *suite) TestPreferIPv6Off(c *gc.C) {
 	c.Assert(toolsURL.Host, gc.Matches, `127\.0\.0\.1:\d+`)
 }
 
+func (s *suite) TestIPv6Only(c *gc.C) {
+	s.TestConfig["ipv6-only"] = true
+	defer delete(s.TestConfig, "ipv6-only")
+	e := s.bootstrapTestEnviron(c, false)
+	inst, _ := jujutesting.AssertStartInstance(c, e, "0")
+	c.Assert(inst, gc.NotNil)
+	addrs, err := inst.Addresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addrs, jc.DeepEquals, network.NewAddresses("fc00::1", "::1"))
+	storageURL, err := e.Storage().URL("tools/releases/juju-" + version.Current.String() + ".tgz")
+	c.Assert(err, gc.IsNil)
+	toolsURL, err := url.Parse(storageURL)
+	c.Assert(err, gc.IsNil)
+	c.Assert(toolsURL.Host, gc.Matches, `\[::1\]:\d+`)
+
+	address, err := e.AllocateAddress(inst.Id(), "net1")
+	c.Assert(err, gc.IsNil)
+	c.Assert(address, jc.DeepEquals, network.NewAddress("fc00:1:2::1", network.ScopeCloudLocal))
+}
+
 func assertAllocateAddress(c *gc.C, e environs.Environ, opc chan dummy.Operation, expectInstId instance.Id, expectNetId network.Id, expectAddress network.Address) {
 	select {
 	case op := <-opc:
@@ -197,3 +269,18 @@ func assertListNetworks(c *gc.C, e environs.Environ, opc chan dummy.Operation, e
 		c.Fatalf("time out wating for operation")
 	}
 }
+
+func assertSubnets(c *gc.C, e environs.Environ, opc chan dummy.Operation, expectInstId instance.Id, expectInfo []network.SubnetInfo) {
+	select {
+	case op := <-opc:
+		subnetsOp, ok := op.(dummy.OpSubnets)
+		if !ok {
+			c.Fatalf("unexpected op: %#v", op)
+		}
+		c.Check(subnetsOp.InstanceId, gc.Equals, expectInstId)
+		c.Check(subnetsOp.Info, jc.DeepEquals, expectInfo)
+		return
+	case <-time.After(testing.ShortWait):
+		c.Fatalf("time out wating for operation")
+	}
+}
@@ -76,6 +76,8 @@ var _ imagemetadata.SupportsCustomSources = (*environ)(nil)
 var _ envtools.SupportsCustomSources = (*environ)(nil)
 var _ state.Prechecker = (*environ)(nil)
 var _ state.InstanceDistributor = (*environ)(nil)
+var _ environs.GlobalIngressFirewaller = (*environ)(nil)
+var _ environs.SubnetDiscoverer = (*environ)(nil)
 
 type ec2Instance struct {
 	e *environ
@@ -89,6 +91,7 @@ func (inst *ec2Instance) String() string {
 }
 
 var _ instance.Instance = (*ec2Instance)(nil)
+var _ environs.InstanceIngressFirewaller = (*ec2Instance)(nil)
 
 func (inst *ec2Instance) getInstance() *ec2.Instance {
 	inst.mu.Lock()
@@ -541,6 +544,8 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 			return nil, nil, nil, fmt.Errorf("availability zone %q is %s", placement.availabilityZone.Name, placement.availabilityZone.State)
 		}
 		availabilityZones = append(availabilityZones, placement.availabilityZone.Name)
+	} else if args.AvailabilityZone != "" {
+		availabilityZones = append(availabilityZones, args.AvailabilityZone)
 	}
 
 	// If no availability zone is specified, then automatically spread across
@@ -579,11 +584,12 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 
 	series := args.Tools.OneSeries()
 	spec, err := findInstanceSpec(sources, e.Config().ImageStream(), &instances.InstanceConstraint{
-		Region:      e.ecfg().region(),
-		Series:      series,
-		Arches:      arches,
-		Constraints: args.Constraints,
-		Storage:     &stor,
+		Region:                e.ecfg().region(),
+		Series:                series,
+		Arches:                arches,
+		Constraints:           args.Constraints,
+		Storage:               &stor,
+		ExcludedInstanceTypes: args.ExcludedInstanceTypes,
 	})
 	if err != nil {
 		return nil, nil, nil, err
@@ -611,7 +617,8 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 	var instResp *ec2.RunInstancesResp
 
 	device, diskSize := getDiskSize(args.Constraints)
-	for _, availZone := range availabilityZones {
+	var availZone string
+	for _, availZone = range availabilityZones {
 		instResp, err = runInstances(e.ec2(), &ec2.RunInstances{
 			AvailZone:           availZone,
 			ImageId:             spec.Image.Id,
@@ -628,6 +635,16 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 			break
 		}
 	}
+	if ec2ErrCode(err) == "RequestLimitExceeded" {
+		return nil, nil, nil, environs.NewRateLimitedError(fmt.Errorf("cannot run instances: %v", err))
+	}
+	if ec2ErrCode(err) == "InsufficientInstanceCapacity" {
+		return nil, nil, nil, &environs.CapacityError{
+			Zone:         availZone,
+			InstanceType: spec.InstanceType.Name,
+			Err:          fmt.Errorf("cannot run instances: %v", err),
+		}
+	}
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("cannot run instances: %v", err)
 	}
@@ -832,6 +849,13 @@ func (*environ) AllocateAddress(_ instance.Id, _ network.Id) (network.Address, e
 	return network.Address{}, errors.NotImplementedf("AllocateAddress")
 }
 
+// ReleaseAddress releases an address allocated for the given instance
+// on the given network. This is not implemented by the EC2 provider
+// yet.
+func (*environ) ReleaseAddress(_ instance.Id, _ network.Id, _ network.Address) error {
+	return errors.NotImplementedf("ReleaseAddress")
+}
+
 // ListNetworks returns basic information about all networks known
 // by the provider for the environment. They may be unknown to juju
 // yet (i.e. when called initially or when a new network was created).
@@ -840,6 +864,68 @@ func (*environ) ListNetworks() ([]network.BasicInfo, error) {
 	return nil, errors.NotImplementedf("ListNetworks")
 }
 
+// Subnets returns information about the VPC subnets the given
+// instance is connected to or, if instId is empty, about all the
+// subnets of the VPCs the environment's instances are in. Other VPCs
+// in the region are not part of the environment, so their subnets are
+// not reported. Instances launched outside a VPC are not connected to
+// any subnet.
+func (e *environ) Subnets(instId instance.Id) ([]network.SubnetInfo, error) {
+	filter := ec2.NewFilter()
+	if instId != "" {
+		insts, err := e.Instances([]instance.Id{instId})
+		if err != nil {
+			return nil, err
+		}
+		subnetId := insts[0].(*ec2Instance).SubnetId
+		if subnetId == "" {
+			return nil, nil
+		}
+		filter.Add("subnet-id", subnetId)
+	} else {
+		vpcIds, err := e.environVPCIds()
+		if err != nil {
+			return nil, err
+		}
+		if len(vpcIds) == 0 {
+			return nil, nil
+		}
+		filter.Add("vpc-id", vpcIds...)
+	}
+	resp, err := e.ec2().Subnets(nil, filter)
+	if err != nil {
+		return nil, err
+	}
+	subnets := make([]network.SubnetInfo, len(resp.Subnets))
+	for i, subnet := range resp.Subnets {
+		subnets[i] = network.SubnetInfo{
+			CIDR:              subnet.CIDRBlock,
+			ProviderId:        network.Id(subnet.Id),
+			AvailabilityZones: []string{subnet.AvailZone},
+		}
+	}
+	return subnets, nil
+}
+
+// environVPCIds returns the ids of the VPCs the environment's
+// instances are in.
+func (e *environ) environVPCIds() ([]string, error) {
+	insts, err := e.AllInstances()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var vpcIds []string
+	for _, inst := range insts {
+		vpcId := inst.(*ec2Instance).VPCId
+		if vpcId != "" && !seen[vpcId] {
+			seen[vpcId] = true
+			vpcIds = append(vpcIds, vpcId)
+		}
+	}
+	return vpcIds, nil
+}
+
 func (e *environ) AllInstances() ([]instance.Instance, error) {
 	filter := ec2.NewFilter()
 	filter.Add("instance-state-name", "pending", "running")
@@ -869,32 +955,43 @@ func (e *environ) Destroy() error {
 	return common.Destroy(e)
 }
 
-func portsToIPPerms(ports []network.Port) []ec2.IPPerm {
-	ipPerms := make([]ec2.IPPerm, len(ports))
-	for i, p := range ports {
-		ipPerms[i] = ec2.IPPerm{
-			Protocol:  p.Protocol,
-			FromPort:  p.Number,
-			ToPort:    p.Number,
-			SourceIPs: []string{"0.0.0.0/0"},
+// rulesToIPPerms returns the IP permissions for the given rules.
+// EC2 security groups only accept IPv4 source CIDRs, so rules for
+// IPv6 CIDRs are skipped.
+func rulesToIPPerms(rules []network.IngressRule) []ec2.IPPerm {
+	ipPerms := make([]ec2.IPPerm, 0, len(rules))
+	for _, r := range rules {
+		if network.IsIPv6CIDR(r.SourceCIDR) {
+			logger.Warningf("ignoring %v: IPv6 source CIDRs are not supported", r)
+			continue
 		}
+		ipPerms = append(ipPerms, ec2.IPPerm{
+			Protocol:  r.Protocol,
+			FromPort:  r.Number,
+			ToPort:    r.Number,
+			SourceIPs: []string{r.SourceCIDR},
+		})
 	}
 	return ipPerms
 }
 
 func (e *environ) openPortsInGroup(name string, ports []network.Port) error {
-	if len(ports) == 0 {
+	return e.openRulesInGroup(name, network.IngressRulesForPorts(ports, nil))
+}
+
+func (e *environ) openRulesInGroup(name string, rules []network.IngressRule) error {
+	ipPerms := rulesToIPPerms(rules)
+	if len(ipPerms) == 0 {
 		return nil
 	}
-	// Give permissions for anyone to access the given ports.
+	// Give permissions for the rules' sources to access the given ports.
 	g, err := e.groupByName(name)
 	if err != nil {
 		return err
 	}
-	ipPerms := portsToIPPerms(ports)
 	_, err = e.ec2().AuthorizeSecurityGroup(g, ipPerms)
 	if err != nil && ec2ErrCode(err) == "InvalidPermission.Duplicate" {
-		if len(ports) == 1 {
+		if len(ipPerms) == 1 {
 			return nil
 		}
 		// If there's more than one port and we get a duplicate error,
@@ -916,42 +1013,67 @@ func (e *environ) openPortsInGroup(name string, ports []network.Port) error {
 }
 
 func (e *environ) closePortsInGroup(name string, ports []network.Port) error {
-	if len(ports) == 0 {
+	return e.closeRulesInGroup(name, network.IngressRulesForPorts(ports, nil))
+}
+
+func (e *environ) closeRulesInGroup(name string, rules []network.IngressRule) error {
+	ipPerms := rulesToIPPerms(rules)
+	if len(ipPerms) == 0 {
 		return nil
 	}
-	// Revoke permissions for anyone to access the given ports.
+	// Revoke permissions for the rules' sources to access the given ports.
 	// Note that ec2 allows the revocation of permissions that aren't
 	// granted, so this is naturally idempotent.
 	g, err := e.groupByName(name)
 	if err != nil {
 		return err
 	}
-	_, err = e.ec2().RevokeSecurityGroup(g, portsToIPPerms(ports))
+	_, err = e.ec2().RevokeSecurityGroup(g, ipPerms)
 	if err != nil {
 		return fmt.Errorf("cannot close ports: %v", err)
 	}
 	return nil
 }
 
-func (e *environ) portsInGroup(name string) (ports []network.Port, err error) {
+func (e *environ) portsInGroup(name string) ([]network.Port, error) {
+	rules, err := e.rulesInGroup(name)
+	if err != nil {
+		return nil, err
+	}
+	var ports []network.Port
+	for _, r := range rules {
+		if r.IsUnrestricted() {
+			ports = append(ports, r.Port)
+		}
+	}
+	network.SortPorts(ports)
+	return ports, nil
+}
+
+func (e *environ) rulesInGroup(name string) (rules []network.IngressRule, err error) {
 	group, err := e.groupInfoByName(name)
 	if err != nil {
 		return nil, err
 	}
 	for _, p := range group.IPPerms {
-		if len(p.SourceIPs) != 1 {
+		if len(p.SourceIPs) == 0 {
 			logger.Warningf("unexpected IP permission found: %v", p)
 			continue
 		}
 		for i := p.FromPort; i <= p.ToPort; i++ {
-			ports = append(ports, network.Port{
-				Protocol: p.Protocol,
-				Number:   i,
-			})
+			for _, sourceIP := range p.SourceIPs {
+				rules = append(rules, network.IngressRule{
+					Port: network.Port{
+						Protocol: p.Protocol,
+						Number:   i,
+					},
+					SourceCIDR: sourceIP,
+				})
+			}
 		}
 	}
-	network.SortPorts(ports)
-	return ports, nil
+	network.SortIngressRules(rules)
+	return rules, nil
 }
 
 func (e *environ) OpenPorts(ports []network.Port) error {
@@ -986,6 +1108,44 @@ func (e *environ) Ports() ([]network.Port, error) {
 	return e.portsInGroup(e.globalGroupName())
 }
 
+// OpenIngressRules is specified in the environs.GlobalIngressFirewaller
+// interface.
+func (e *environ) OpenIngressRules(rules []network.IngressRule) error {
+	if e.Config().FirewallMode() != config.FwGlobal {
+		return fmt.Errorf("invalid firewall mode %q for opening ports on environment",
+			e.Config().FirewallMode())
+	}
+	if err := e.openRulesInGroup(e.globalGroupName(), rules); err != nil {
+		return err
+	}
+	logger.Infof("opened rules in global group: %v", rules)
+	return nil
+}
+
+// CloseIngressRules is specified in the environs.GlobalIngressFirewaller
+// interface.
+func (e *environ) CloseIngressRules(rules []network.IngressRule) error {
+	if e.Config().FirewallMode() != config.FwGlobal {
+		return fmt.Errorf("invalid firewall mode %q for closing ports on environment",
+			e.Config().FirewallMode())
+	}
+	if err := e.closeRulesInGroup(e.globalGroupName(), rules); err != nil {
+		return err
+	}
+	logger.Infof("closed rules in global group: %v", rules)
+	return nil
+}
+
+// IngressRules is specified in the environs.GlobalIngressFirewaller
+// interface.
+func (e *environ) IngressRules() ([]network.IngressRule, error) {
+	if e.Config().FirewallMode() != config.FwGlobal {
+		return nil, fmt.Errorf("invalid firewall mode %q for retrieving ports from environment",
+			e.Config().FirewallMode())
+	}
+	return e.rulesInGroup(e.globalGroupName())
+}
+
 func (*environ) Provider() environs.EnvironProvider {
 	return &providerInstance
 }
@@ -1072,6 +1232,47 @@ func (inst *ec2Instance) Ports(machineId string) ([]network.Port, error) {
 	return inst.e.portsInGroup(name)
 }
 
+// OpenIngressRules is specified in the environs.InstanceIngressFirewaller
+// interface.
+func (inst *ec2Instance) OpenIngressRules(machineId string, rules []network.IngressRule) error {
+	if inst.e.Config().FirewallMode() != config.FwInstance {
+		return fmt.Errorf("invalid firewall mode %q for opening ports on instance",
+			inst.e.Config().FirewallMode())
+	}
+	name := inst.e.machineGroupName(machineId)
+	if err := inst.e.openRulesInGroup(name, rules); err != nil {
+		return err
+	}
+	logger.Infof("opened rules in security group %s: %v", name, rules)
+	return nil
+}
+
+// CloseIngressRules is specified in the environs.InstanceIngressFirewaller
+// interface.
+func (inst *ec2Instance) CloseIngressRules(machineId string, rules []network.IngressRule) error {
+	if inst.e.Config().FirewallMode() != config.FwInstance {
+		return fmt.Errorf("invalid firewall mode %q for closing ports on instance",
+			inst.e.Config().FirewallMode())
+	}
+	name := inst.e.machineGroupName(machineId)
+	if err := inst.e.closeRulesInGroup(name, rules); err != nil {
+		return err
+	}
+	logger.Infof("closed rules in security group %s: %v", name, rules)
+	return nil
+}
+
+// IngressRules is specified in the environs.InstanceIngressFirewaller
+// interface.
+func (inst *ec2Instance) IngressRules(machineId string) ([]network.IngressRule, error) {
+	if inst.e.Config().FirewallMode() != config.FwInstance {
+		return nil, fmt.Errorf("invalid firewall mode %q for retrieving ports from instance",
+			inst.e.Config().FirewallMode())
+	}
+	name := inst.e.machineGroupName(machineId)
+	return inst.e.rulesInGroup(name)
+}
+
 // setUpGroups creates the security groups for the new machine, and
 // returns them.
 //
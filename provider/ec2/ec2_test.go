@@ -8,6 +8,7 @@ import (
 	gc "launchpad.net/gocheck"
 
 	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/network"
 )
 
 type Suite struct{}
@@ -58,6 +59,25 @@ func (*Suite) TestRootDisk(c *gc.C) {
 	}
 }
 
+func (*Suite) TestRulesToIPPermsSkipsIPv6(c *gc.C) {
+	ipPerms := rulesToIPPerms([]network.IngressRule{
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+		{network.Port{"tcp", 80}, "2001:db8::/32"},
+		{network.Port{"udp", 53}, "0.0.0.0/0"},
+	})
+	c.Assert(ipPerms, gc.DeepEquals, []amzec2.IPPerm{{
+		Protocol:  "tcp",
+		FromPort:  80,
+		ToPort:    80,
+		SourceIPs: []string{"10.0.0.0/8"},
+	}, {
+		Protocol:  "udp",
+		FromPort:  53,
+		ToPort:    53,
+		SourceIPs: []string{"0.0.0.0/0"},
+	}})
+}
+
 func pInt(i uint64) *uint64 {
 	return &i
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/joyent/gocommon/client"
+	je "github.com/joyent/gocommon/errors"
 	"github.com/joyent/gosdc/cloudapi"
 	"github.com/juju/errors"
 	"github.com/juju/names"
@@ -127,7 +128,9 @@ func (env *joyentEnviron) StartInstance(args environs.StartInstanceParams) (inst
 		Metadata: map[string]string{"metadata.cloud-init:user-data": string(userData)},
 		Tags:     map[string]string{"tag.group": "juju", "tag.env": env.Config().Name()},
 	})
-	if err != nil {
+	if je.IsRequestThrottled(err) {
+		return nil, nil, nil, environs.NewRateLimitedError(fmt.Errorf("cannot create instances: %v", err))
+	} else if err != nil {
 		return nil, nil, nil, fmt.Errorf("cannot create instances: %v", err)
 	}
 	machineId := machine.Id
@@ -232,6 +235,13 @@ func (*joyentEnviron) AllocateAddress(_ instance.Id, _ network.Id) (network.Addr
 	return network.Address{}, errors.NotImplementedf("AllocateAddress")
 }
 
+// ReleaseAddress releases an address allocated for the given instance
+// on the given network. This is not implemented on the Joyent provider
+// yet.
+func (*joyentEnviron) ReleaseAddress(_ instance.Id, _ network.Id, _ network.Address) error {
+	return errors.NotImplementedf("ReleaseAddress")
+}
+
 // ListNetworks returns basic information about all networks known by
 // the provider for the environment. They may be unknown to juju yet
 // (i.e. when called initially or when a new network was created).
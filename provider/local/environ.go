@@ -157,6 +157,8 @@ func (env *localEnviron) Bootstrap(ctx environs.BootstrapContext, args environs.
 	// don't write proxy settings for local machine
 	mcfg.AptProxySettings = proxy.Settings{}
 	mcfg.ProxySettings = proxy.Settings{}
+	// nor change the resolver of the user's own machine
+	mcfg.DNSServers = nil
 	cloudcfg := coreCloudinit.New()
 	// Since rsyslogd is restricted by apparmor to only write to /var/log/**
 	// we now provide a symlink to the written file in the local log dir.
@@ -342,12 +344,8 @@ func (env *localEnviron) StartInstance(args environs.StartInstanceParams) (insta
 	if err := environs.FinishMachineConfig(args.MachineConfig, env.config.Config, args.Constraints); err != nil {
 		return nil, nil, nil, err
 	}
-	// TODO: evaluate the impact of setting the contstraints on the
-	// machineConfig for all machines rather than just state server nodes.
-	// This limiation is why the constraints are assigned directly here.
-	args.MachineConfig.Constraints = args.Constraints
 	args.MachineConfig.AgentEnvironment[agent.Namespace] = env.config.namespace()
-	inst, hardware, err := env.containerManager.CreateContainer(args.MachineConfig, series, network)
+	inst, hardware, err := env.containerManager.CreateContainer(args.MachineConfig, series, network, args.Constraints)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -405,6 +403,12 @@ func (*localEnviron) AllocateAddress(_ instance.Id, _ network.Id) (network.Addre
 	return network.Address{}, errors.NotSupportedf("AllocateAddress")
 }
 
+// ReleaseAddress releases an address allocated for the given instance
+// on the given network. This is not supported on the local provider.
+func (*localEnviron) ReleaseAddress(_ instance.Id, _ network.Id, _ network.Address) error {
+	return errors.NotSupportedf("ReleaseAddress")
+}
+
 // ListNetworks returns basic information about all networks known
 // by the provider for the environment. They may be unknown to juju
 // yet (i.e. when called initially or when a new network was created).
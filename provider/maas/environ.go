@@ -71,6 +71,7 @@ type maasEnviron struct {
 var _ environs.Environ = (*maasEnviron)(nil)
 var _ imagemetadata.SupportsCustomSources = (*maasEnviron)(nil)
 var _ envtools.SupportsCustomSources = (*maasEnviron)(nil)
+var _ environs.SubnetDiscoverer = (*maasEnviron)(nil)
 
 func NewEnviron(cfg *config.Config) (*maasEnviron, error) {
 	env := new(maasEnviron)
@@ -605,6 +606,13 @@ func (*maasEnviron) AllocateAddress(_ instance.Id, _ network.Id) (network.Addres
 	return network.Address{}, errors.NotImplementedf("AllocateAddress")
 }
 
+// ReleaseAddress releases an address allocated for the given instance
+// on the given network. This is not implemented on the MAAS provider
+// yet.
+func (*maasEnviron) ReleaseAddress(_ instance.Id, _ network.Id, _ network.Address) error {
+	return errors.NotImplementedf("ReleaseAddress")
+}
+
 // ListNetworks returns basic information about all networks known
 // by the provider for the environment. They may be unknown to juju
 // yet (i.e. when called initially or when a new network was created).
@@ -613,6 +621,33 @@ func (*maasEnviron) ListNetworks() ([]network.BasicInfo, error) {
 	return nil, errors.NotImplementedf("ListNetworks")
 }
 
+// Subnets returns information about the MAAS networks the given node
+// is connected to or, if instId is empty, about all MAAS networks.
+func (environ *maasEnviron) Subnets(instId instance.Id) ([]network.SubnetInfo, error) {
+	var networks []networkDetails
+	if instId != "" {
+		insts, err := environ.Instances([]instance.Id{instId})
+		if err != nil {
+			return nil, err
+		}
+		networks, err = environ.getInstanceNetworks(insts[0])
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		networks, err = environ.getNetworks(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	subnets := make([]network.SubnetInfo, len(networks))
+	for i, netw := range networks {
+		subnets[i] = netw.subnetInfo()
+	}
+	return subnets, nil
+}
+
 // AllInstances returns all the instance.Instance in this provider.
 func (environ *maasEnviron) AllInstances() ([]instance.Instance, error) {
 	return environ.instances(nil)
@@ -672,16 +707,39 @@ type networkDetails struct {
 	Description string
 }
 
+// subnetInfo returns the subnet the network is on.
+func (netw networkDetails) subnetInfo() network.SubnetInfo {
+	info := network.SubnetInfo{
+		ProviderId: network.Id(netw.Name),
+		VLANTag:    netw.VLANTag,
+	}
+	ip := net.ParseIP(netw.IP)
+	mask := net.ParseIP(netw.Mask)
+	if ip != nil && mask != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, mask = ip4, mask.To4()
+		}
+		ipNet := &net.IPNet{IP: ip.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+		info.CIDR = ipNet.String()
+	}
+	return info
+}
+
 // getInstanceNetworks returns a list of all MAAS networks for a given node.
 func (environ *maasEnviron) getInstanceNetworks(inst instance.Instance) ([]networkDetails, error) {
 	maasInst := inst.(*maasInstance)
 	maasObj := maasInst.maasObject
-	client := environ.getMAASClient().GetSubObject("networks")
 	nodeId, err := maasObj.GetField("system_id")
 	if err != nil {
 		return nil, err
 	}
-	params := url.Values{"node": {nodeId}}
+	return environ.getNetworks(url.Values{"node": {nodeId}})
+}
+
+// getNetworks returns a list of the MAAS networks matching the given
+// parameters.
+func (environ *maasEnviron) getNetworks(params url.Values) ([]networkDetails, error) {
+	client := environ.getMAASClient().GetSubObject("networks")
 	json, err := client.CallGet("", params)
 	if err != nil {
 		return nil, err
@@ -657,6 +657,17 @@ func (suite *environSuite) TestGetInstanceNetworks(c *gc.C) {
 	})
 }
 
+func (suite *environSuite) TestNetworkDetailsSubnetInfo(c *gc.C) {
+	netw := networkDetails{Name: "test_network", IP: "192.168.123.1", Mask: "255.255.255.0", VLANTag: 321}
+	c.Check(netw.subnetInfo(), gc.DeepEquals, network.SubnetInfo{
+		CIDR: "192.168.123.0/24", ProviderId: "test_network", VLANTag: 321,
+	})
+	netw = networkDetails{Name: "unknown_network", IP: "", Mask: ""}
+	c.Check(netw.subnetInfo(), gc.DeepEquals, network.SubnetInfo{
+		ProviderId: "unknown_network",
+	})
+}
+
 // A typical lshw XML dump with lots of things left out.
 const lshwXMLTestExtractInterfaces = `
 <?xml version="1.0" standalone="yes" ?>
@@ -122,6 +122,11 @@ func (s *configSuite) TestStorageParams(c *gc.C) {
 	testConfig = getEnvironConfig(c, values)
 	c.Assert(testConfig.storageAddr(), gc.Equals, "hostname:1234")
 	c.Assert(testConfig.storageListenAddr(), gc.Equals, "10.0.0.123:1234")
+	values["bootstrap-host"] = "2001:db8::1"
+	values["storage-listen-ip"] = "2001:db8::2"
+	testConfig = getEnvironConfig(c, values)
+	c.Assert(testConfig.storageAddr(), gc.Equals, "[2001:db8::1]:1234")
+	c.Assert(testConfig.storageListenAddr(), gc.Equals, "[2001:db8::2]:1234")
 }
 
 func (s *configSuite) TestStorageCompat(c *gc.C) {
@@ -219,6 +219,12 @@ func (*manualEnviron) AllocateAddress(_ instance.Id, _ network.Id) (network.Addr
 	return network.Address{}, errors.NotSupportedf("AllocateAddress")
 }
 
+// ReleaseAddress releases an address allocated for the given instance
+// on the given network. This is not supported on the manual provider.
+func (*manualEnviron) ReleaseAddress(_ instance.Id, _ network.Id, _ network.Address) error {
+	return errors.NotSupportedf("ReleaseAddress")
+}
+
 // ListNetworks returns basic information about all networks known
 // by the provider for the environment. They may be unknown to juju
 // yet (i.e. when called initially or when a new network was created).
@@ -34,8 +34,10 @@ func init() {
 }
 
 var (
-	ShortAttempt   = &shortAttempt
-	StorageAttempt = &storageAttempt
+	ShortAttempt    = &shortAttempt
+	StorageAttempt  = &storageAttempt
+	IsRateLimited   = isRateLimited
+	IsCapacityError = isCapacityError
 )
 
 // MetadataStorage returns a Storage instance which is used to store simplestreams metadata for tests.
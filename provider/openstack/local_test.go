@@ -1216,6 +1216,38 @@ func (t *localServerSuite) TestStartInstanceAvailZoneUnknown(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `invalid availability zone "test-unknown"`)
 }
 
+func (t *localServerSuite) TestStartInstanceAvailabilityZoneParam(c *gc.C) {
+	env := t.Prepare(c)
+	envtesting.UploadFakeTools(c, env.Storage())
+	err := bootstrap.Bootstrap(coretesting.Context(c), env, environs.BootstrapParams{})
+	c.Assert(err, gc.IsNil)
+
+	// The zone given is used without consulting the zone allocations.
+	mock := mockAvailabilityZoneAllocations{
+		err: fmt.Errorf("AvailabilityZoneAllocations failed"),
+	}
+	t.PatchValue(openstack.AvailabilityZoneAllocations, mock.AvailabilityZoneAllocations)
+	params := environs.StartInstanceParams{AvailabilityZone: "test-available"}
+	inst, _, _, err := testing.StartInstanceWithParams(env, "1", params, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(openstack.InstanceServerDetail(inst).AvailabilityZone, gc.Equals, "test-available")
+}
+
+func (s *localServerSuite) TestStartInstanceExcludedInstanceTypes(c *gc.C) {
+	env := s.Prepare(c)
+	envtesting.UploadFakeTools(c, env.Storage())
+	err := bootstrap.Bootstrap(coretesting.Context(c), env, environs.BootstrapParams{})
+	c.Assert(err, gc.IsNil)
+
+	params := environs.StartInstanceParams{
+		Constraints:           constraints.MustParse("mem=1024"),
+		ExcludedInstanceTypes: []string{"m1.small"},
+	}
+	_, hc, _, err := testing.StartInstanceWithParams(env, "1", params, nil)
+	c.Assert(err, gc.IsNil)
+	c.Check(*hc.Mem, gc.Equals, uint64(4096))
+}
+
 func (t *localServerSuite) testStartInstanceAvailZone(c *gc.C, zone string) (instance.Instance, error) {
 	env := t.Prepare(c)
 	envtesting.UploadFakeTools(c, env.Storage())
@@ -310,6 +310,7 @@ var _ envtools.SupportsCustomSources = (*environ)(nil)
 var _ simplestreams.HasRegion = (*environ)(nil)
 var _ state.Prechecker = (*environ)(nil)
 var _ state.InstanceDistributor = (*environ)(nil)
+var _ environs.SubnetDiscoverer = (*environ)(nil)
 
 type openstackInstance struct {
 	e        *environ
@@ -882,6 +883,8 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 			return nil, nil, nil, fmt.Errorf("availability zone %q is unavailable", placement.availabilityZone.Name)
 		}
 		availabilityZone = placement.availabilityZone.Name
+	} else if args.AvailabilityZone != "" {
+		availabilityZone = args.AvailabilityZone
 	}
 
 	// If no availability zone is specified, then automatically spread across
@@ -914,10 +917,11 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 	series := args.Tools.OneSeries()
 	arches := args.Tools.Arches()
 	spec, err := findInstanceSpec(e, &instances.InstanceConstraint{
-		Region:      e.ecfg().region(),
-		Series:      series,
-		Arches:      arches,
-		Constraints: args.Constraints,
+		Region:                e.ecfg().region(),
+		Series:                series,
+		Arches:                arches,
+		Constraints:           args.Constraints,
+		ExcludedInstanceTypes: args.ExcludedInstanceTypes,
 	})
 	if err != nil {
 		return nil, nil, nil, err
@@ -984,7 +988,17 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 		}
 	}
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("cannot run instance: %v", err)
+		err = fmt.Errorf("cannot run instance: %v", err)
+		if isRateLimited(err) {
+			err = environs.NewRateLimitedError(err)
+		} else if isCapacityError(err) {
+			err = &environs.CapacityError{
+				Zone:         availabilityZone,
+				InstanceType: spec.InstanceType.Name,
+				Err:          err,
+			}
+		}
+		return nil, nil, nil, err
 	}
 	detail, err := e.nova().GetServer(server.Id)
 	if err != nil {
@@ -1010,6 +1024,24 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (instance.Ins
 	return inst, inst.hardwareCharacteristics(), nil, nil
 }
 
+// isRateLimited reports whether err was returned because nova is
+// limiting the rate of requests. Nova reports this with an overLimit
+// fault and status 413, or with status 429 in later releases; quota
+// faults share status 413, but retrying does not clear them.
+func isRateLimited(err error) bool {
+	msg := err.Error()
+	if strings.Contains(msg, "unexpected status: 429") {
+		return true
+	}
+	return strings.Contains(msg, "unexpected status: 413") && strings.Contains(msg, "rate-limited")
+}
+
+// isCapacityError reports whether err was returned because nova could
+// not find a host with the capacity to run the instance.
+func isCapacityError(err error) bool {
+	return strings.Contains(err.Error(), "No valid host was found")
+}
+
 func (e *environ) StopInstances(ids ...instance.Id) error {
 	// If in instance firewall mode, gather the security group names.
 	var securityGroupNames []string
@@ -1117,6 +1149,13 @@ func (*environ) AllocateAddress(_ instance.Id, _ network.Id) (network.Address, e
 	return network.Address{}, jujuerrors.NotImplementedf("AllocateAddress")
 }
 
+// ReleaseAddress releases an address allocated for the given instance
+// on the given network. This is not implemented on the OpenStack
+// provider yet.
+func (*environ) ReleaseAddress(_ instance.Id, _ network.Id, _ network.Address) error {
+	return jujuerrors.NotImplementedf("ReleaseAddress")
+}
+
 // ListNetworks returns basic information about all networks known
 // by the provider for the environment. They may be unknown to juju
 // yet (i.e. when called initially or when a new network was created).
@@ -1125,6 +1164,46 @@ func (*environ) ListNetworks() ([]network.BasicInfo, error) {
 	return nil, jujuerrors.NotImplementedf("ListNetworks")
 }
 
+// Subnets returns information about the networks the given instance
+// is connected to or, if instId is empty, about all the networks
+// available to the tenant. Networks connected to an instance span
+// its availability zone.
+func (e *environ) Subnets(instId instance.Id) ([]network.SubnetInfo, error) {
+	networks, err := e.nova().ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+	var server *nova.ServerDetail
+	if instId != "" {
+		insts, err := e.Instances([]instance.Id{instId})
+		if err != nil {
+			return nil, err
+		}
+		server = insts[0].(*openstackInstance).getServerDetail()
+	}
+	var subnets []network.SubnetInfo
+	for _, netw := range networks {
+		if netw.Cidr == nil {
+			// Networks without an IP range are of no use to us.
+			continue
+		}
+		subnet := network.SubnetInfo{
+			CIDR:       *netw.Cidr,
+			ProviderId: network.Id(netw.Id),
+		}
+		if server != nil {
+			if _, ok := server.Addresses[netw.Label]; !ok {
+				continue
+			}
+			if server.AvailabilityZone != "" {
+				subnet.AvailabilityZones = []string{server.AvailabilityZone}
+			}
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
 func (e *environ) AllInstances() (insts []instance.Instance, err error) {
 	servers, err := e.nova().ListServersDetail(e.machinesFilter())
 	if err != nil {
@@ -4,6 +4,7 @@
 package openstack_test
 
 import (
+	"errors"
 	"flag"
 	"testing"
 
@@ -198,3 +199,34 @@ func (t *localTests) TestGetServerAddresses(c *gc.C) {
 		c.Assert(addr, gc.Equals, t.expected)
 	}
 }
+
+var rateLimitTests = []struct {
+	err     string
+	limited bool
+}{{
+	err:     `request (http://nova/servers) returned unexpected status: 413; error info: {"overLimit": {"message": "This request was rate-limited.", "code": 413}}`,
+	limited: true,
+}, {
+	err:     `request (http://nova/servers) returned unexpected status: 429; error info: Too Many Requests`,
+	limited: true,
+}, {
+	err:     `request (http://nova/servers) returned unexpected status: 413; error info: {"overLimit": {"message": "Quota exceeded for instances", "code": 413}}`,
+	limited: false,
+}, {
+	err:     `request (http://nova/servers) returned unexpected status: 500; error info: internal error`,
+	limited: false,
+}}
+
+func (t *localTests) TestIsRateLimited(c *gc.C) {
+	for i, test := range rateLimitTests {
+		c.Logf("test %d: %s", i, test.err)
+		c.Check(openstack.IsRateLimited(errors.New(test.err)), gc.Equals, test.limited)
+	}
+}
+
+func (t *localTests) TestIsCapacityError(c *gc.C) {
+	err := errors.New(`cannot run instance: request (http://nova/servers) returned unexpected status: 500; error info: {"computeFault": {"message": "No valid host was found. ", "code": 500}}`)
+	c.Check(openstack.IsCapacityError(err), gc.Equals, true)
+	err = errors.New(`cannot run instance: request (http://nova/servers) returned unexpected status: 500; error info: internal error`)
+	c.Check(openstack.IsCapacityError(err), gc.Equals, false)
+}
@@ -72,6 +72,10 @@ type MachineTemplate struct {
 	// with the machine.
 	Placement string
 
+	// Spare signifies whether the new machine is added to the pool
+	// of spare machines.
+	Spare bool
+
 	// principals holds the principal units that will
 	// associated with the machine.
 	principals []string
@@ -407,6 +411,7 @@ func machineDocForTemplate(template MachineTemplate, id string) *machineDoc {
 		Addresses:  instanceAddressesToAddresses(template.Addresses),
 		NoVote:     template.NoVote,
 		Placement:  template.Placement,
+		Spare:      template.Spare,
 	}
 }
 
@@ -87,6 +87,8 @@ type ServiceStatus struct {
 	Err           error
 	Charm         string
 	Exposed       bool
+	ExposedCIDRs  []string
+	IngressCIDRs  []string
 	Life          string
 	Relations     map[string][]string
 	Networks      NetworksSpecification
@@ -210,6 +212,39 @@ func (c *Client) Resolved(unit string, retry bool) error {
 	return c.call("Resolved", p, nil)
 }
 
+// HookHistory returns the recorded hook executions of a unit,
+// oldest first.
+func (c *Client) HookHistory(unit string) ([]params.HookExecution, error) {
+	var results params.HookHistoryResults
+	p := params.HookHistory{UnitName: unit}
+	if err := c.call("HookHistory", p, &results); err != nil {
+		return nil, err
+	}
+	return results.Executions, nil
+}
+
+// StatusHistory returns the recorded statuses of a machine, oldest
+// first.
+func (c *Client) StatusHistory(machineId string) ([]params.StatusHistoryEntry, error) {
+	var results params.StatusHistoryResults
+	p := params.StatusHistory{MachineId: machineId}
+	if err := c.call("StatusHistory", p, &results); err != nil {
+		return nil, err
+	}
+	return results.Statuses, nil
+}
+
+// HarvestReport returns the instances the provisioner would terminate
+// in the named harvest mode, or in the environment's harvest mode if
+// mode is empty, along with the name of the mode reported on. No
+// instances are terminated.
+func (c *Client) HarvestReport(mode string) (params.HarvestReportResults, error) {
+	var results params.HarvestReportResults
+	p := params.HarvestReport{Mode: mode}
+	err := c.call("HarvestReport", p, &results)
+	return results, err
+}
+
 // RetryProvisioning updates the provisioning status of a machine allowing the
 // provisioner to retry.
 func (c *Client) RetryProvisioning(machines ...string) ([]params.ErrorResult, error) {
@@ -333,6 +368,28 @@ func (c *Client) ServiceExpose(service string) error {
 	return c.call("ServiceExpose", params, nil)
 }
 
+// ServiceExposeFrom works like ServiceExpose, but only allows the
+// exposed ports to be accessed from addresses within the given
+// source CIDRs.
+func (c *Client) ServiceExposeFrom(service string, sourceCIDRs []string) error {
+	params := params.ServiceExposeFrom{
+		ServiceName: service,
+		SourceCIDRs: sourceCIDRs,
+	}
+	return c.call("ServiceExposeFrom", params, nil)
+}
+
+// ServiceSetHookRetryPolicy overrides the environment's hook retry
+// policy for a service. If policy is nil, the service uses the
+// environment's policy again.
+func (c *Client) ServiceSetHookRetryPolicy(service string, policy *params.HookRetryPolicy) error {
+	p := params.ServiceSetHookRetryPolicy{
+		ServiceName: service,
+		Policy:      policy,
+	}
+	return c.call("ServiceSetHookRetryPolicy", p, nil)
+}
+
 // ServiceUnexpose changes the juju-managed firewall to unexpose any ports that
 // were also explicitly marked by units as open.
 func (c *Client) ServiceUnexpose(service string) error {
@@ -357,6 +414,39 @@ func (c *Client) ServiceDeployWithNetworks(charmURL string, serviceName string,
 	return c.st.Call("Client", "", "ServiceDeployWithNetworks", params, nil)
 }
 
+// ServiceDeployWithBindings works exactly like ServiceDeploy, but also
+// binds the service's endpoints to the spaces in args.EndpointBindings.
+func (c *Client) ServiceDeployWithBindings(args params.ServiceDeploy) error {
+	return c.call("ServiceDeployWithBindings", args, nil)
+}
+
+// AddSpace creates a new space holding the given subnets.
+func (c *Client) AddSpace(name string, subnets []string) error {
+	args := params.AddSpace{
+		Name:    name,
+		Subnets: subnets,
+	}
+	return c.call("AddSpace", args, nil)
+}
+
+// ListSpaces returns all the spaces in the environment.
+func (c *Client) ListSpaces() ([]params.SpaceInfo, error) {
+	var result params.ListSpacesResults
+	if err := c.call("ListSpaces", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Spaces, nil
+}
+
+// ListSubnets returns all the subnets discovered in the provider.
+func (c *Client) ListSubnets() ([]params.SubnetInfo, error) {
+	var result params.ListSubnetsResults
+	if err := c.call("ListSubnets", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Subnets, nil
+}
+
 // ServiceDeploy obtains the charm, either locally or from the charm store,
 // and deploys it.
 func (c *Client) ServiceDeploy(charmURL string, serviceName string, numUnits int, configYAML string, cons constraints.Value, toMachineSpec string) error {
@@ -388,6 +478,41 @@ func (c *Client) ServiceSetCharm(serviceName string, charmUrl string, force bool
 	return c.call("ServiceSetCharm", args, nil)
 }
 
+// ServiceSetCharmHoldingUnits sets the charm for a given service, but
+// holds back the given units from upgrading to it until they are
+// released with ServiceReleaseCharmUpgrades.
+func (c *Client) ServiceSetCharmHoldingUnits(serviceName string, charmUrl string, force bool, heldUnits []string) error {
+	args := params.ServiceSetCharmHoldingUnits{
+		ServiceName: serviceName,
+		CharmUrl:    charmUrl,
+		Force:       force,
+		HeldUnits:   heldUnits,
+	}
+	return c.call("ServiceSetCharmHoldingUnits", args, nil)
+}
+
+// ServiceReleaseCharmUpgrades allows the given units of a service to
+// upgrade to the service's charm.
+func (c *Client) ServiceReleaseCharmUpgrades(serviceName string, unitNames ...string) error {
+	args := params.ServiceReleaseCharmUpgrades{
+		ServiceName: serviceName,
+		UnitNames:   unitNames,
+	}
+	return c.call("ServiceReleaseCharmUpgrades", args, nil)
+}
+
+// ServiceRollbackCharm changes the charm of the given service back to
+// the one it used before its last charm change, and returns the URL of
+// that charm.
+func (c *Client) ServiceRollbackCharm(serviceName string) (*charm.URL, error) {
+	result := new(params.StringResult)
+	args := params.ServiceRollbackCharm{ServiceName: serviceName}
+	if err := c.call("ServiceRollbackCharm", args, result); err != nil {
+		return nil, err
+	}
+	return charm.ParseURL(result.Result)
+}
+
 // ServiceGetCharmURL returns the charm URL the given service is
 // running at present.
 func (c *Client) ServiceGetCharmURL(serviceName string) (*charm.URL, error) {
@@ -600,6 +725,35 @@ func (c *Client) Run(run params.RunParams) ([]params.RunResult, error) {
 	return results.Results, err
 }
 
+// StartRun starts running the Commands specified on the machines
+// identified through the ids provided in the machines, services and
+// units slices, without waiting for them to finish. It returns the id
+// of a run job whose output can be retrieved with RunJobOutput.
+func (c *Client) StartRun(run params.RunParams) (string, error) {
+	var result params.RunJob
+	err := c.call("StartRun", run, &result)
+	return result.JobId, err
+}
+
+// StartRunOnAllMachines starts running the command on all the machines
+// with the specified timeout, without waiting for it to finish.
+func (c *Client) StartRunOnAllMachines(commands string, timeout time.Duration) (string, error) {
+	var result params.RunJob
+	args := params.RunParams{Commands: commands, Timeout: timeout}
+	err := c.call("StartRunOnAllMachines", args, &result)
+	return result.JobId, err
+}
+
+// RunJobOutput returns the output recorded for the run job with the
+// given id after the since sequence number, and the job's results once
+// it is done.
+func (c *Client) RunJobOutput(jobId string, since int) (params.RunJobOutputResults, error) {
+	var result params.RunJobOutputResults
+	args := params.RunJobOutput{JobId: jobId, Since: since}
+	err := c.call("RunJobOutput", args, &result)
+	return result, err
+}
+
 // DestroyEnvironment puts the environment into a "dying" state,
 // and removes all non-manager machine instances. DestroyEnvironment
 // will fail if there are any manually-provisioned non-manager machines
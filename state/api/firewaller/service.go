@@ -70,3 +70,27 @@ func (s *Service) IsExposed() (bool, error) {
 	}
 	return result.Result, nil
 }
+
+// IngressCIDRs returns the source CIDRs from which the opened ports
+// of the service may be accessed when it is exposed. These are the
+// CIDRs it was exposed to, if any, or else the subnets of the spaces
+// its endpoints are bound to. An empty result means that the ports may
+// be accessed from any address.
+func (s *Service) IngressCIDRs() ([]string, error) {
+	var results params.StringsResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: s.tag.String()}},
+	}
+	err := s.st.call("GetIngressCIDRs", args, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Results) != 1 {
+		return nil, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return result.Result, nil
+}
@@ -90,3 +90,19 @@ func (s *serviceSuite) TestIsExposed(c *gc.C) {
 	c.Assert(err, gc.IsNil)
 	c.Assert(isExposed, jc.IsFalse)
 }
+
+func (s *serviceSuite) TestIngressCIDRs(c *gc.C) {
+	err := s.service.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+
+	cidrs, err := s.apiService.IngressCIDRs()
+	c.Assert(err, gc.IsNil)
+	c.Assert(cidrs, jc.DeepEquals, []string{"10.0.0.0/8"})
+
+	err = s.service.SetExposed()
+	c.Assert(err, gc.IsNil)
+
+	cidrs, err = s.apiService.IngressCIDRs()
+	c.Assert(err, gc.IsNil)
+	c.Assert(cidrs, gc.HasLen, 0)
+}
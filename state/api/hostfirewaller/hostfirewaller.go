@@ -0,0 +1,77 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller
+
+import (
+	"fmt"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state/api/base"
+	"github.com/juju/juju/state/api/common"
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/state/api/watcher"
+)
+
+const hostFirewallerFacade = "HostFirewaller"
+
+// State provides access to a host firewaller worker's view of the state.
+type State struct {
+	*common.EnvironWatcher
+
+	caller base.Caller
+}
+
+func (st *State) call(method string, params, result interface{}) error {
+	return st.caller.Call(hostFirewallerFacade, "", method, params, result)
+}
+
+// NewState creates a new client-side HostFirewaller facade.
+func NewState(caller base.Caller) *State {
+	return &State{
+		EnvironWatcher: common.NewEnvironWatcher(hostFirewallerFacade, caller),
+		caller:         caller,
+	}
+}
+
+// IngressRules returns the rules the host firewall of the given
+// machine should apply, and the ports they apply to.
+func (st *State) IngressRules(machineTag string) ([]network.IngressRule, []network.Port, error) {
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: machineTag}},
+	}
+	var results params.IngressRulesResults
+	err := st.call("IngressRules", args, &results)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results.Results) != 1 {
+		return nil, nil, fmt.Errorf("expected one result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	return result.Rules, result.Ports, nil
+}
+
+// WatchIngressRules returns a NotifyWatcher that notifies of changes
+// to the ingress rules of the given machine.
+func (st *State) WatchIngressRules(machineTag string) (watcher.NotifyWatcher, error) {
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: machineTag}},
+	}
+	var results params.NotifyWatchResults
+	err := st.call("WatchIngressRules", args, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Results) != 1 {
+		return nil, fmt.Errorf("expected one result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return watcher.NewNotifyWatcher(st.caller, result), nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api"
+	"github.com/juju/juju/state/api/hostfirewaller"
+	statetesting "github.com/juju/juju/state/testing"
+)
+
+type hostFirewallerSuite struct {
+	testing.JujuConnSuite
+
+	st             *api.State
+	machine        *state.Machine
+	unit           *state.Unit
+	hostFirewaller *hostfirewaller.State
+}
+
+var _ = gc.Suite(&hostFirewallerSuite{})
+
+func (s *hostFirewallerSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.st, s.machine = s.OpenAPIAsNewMachine(c)
+
+	service := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	var err error
+	s.unit, err = service.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = s.unit.AssignToMachine(s.machine)
+	c.Assert(err, gc.IsNil)
+	err = service.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+
+	s.hostFirewaller = s.st.HostFirewaller()
+	c.Assert(s.hostFirewaller, gc.NotNil)
+}
+
+func (s *hostFirewallerSuite) TestIngressRules(c *gc.C) {
+	err := s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+
+	rules, ports, err := s.hostFirewaller.IngressRules(s.machine.Tag().String())
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+	})
+	c.Assert(ports, jc.DeepEquals, []network.Port{{"tcp", 80}})
+
+	_, _, err = s.hostFirewaller.IngressRules("machine-0")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *hostFirewallerSuite) TestWatchIngressRules(c *gc.C) {
+	w, err := s.hostFirewaller.WatchIngressRules(s.machine.Tag().String())
+	c.Assert(err, gc.IsNil)
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.BackingState, w)
+
+	// Initial event.
+	wc.AssertOneChange()
+
+	err = s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *hostFirewallerSuite) TestWatchForEnvironConfigChanges(c *gc.C) {
+	w, err := s.hostFirewaller.WatchForEnvironConfigChanges()
+	c.Assert(err, gc.IsNil)
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.BackingState, w)
+	wc.AssertOneChange()
+}
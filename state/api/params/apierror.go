@@ -47,6 +47,7 @@ const (
 	CodeTryAgain            = "try again"
 	CodeNotImplemented      = rpc.CodeNotImplemented
 	CodeAlreadyExists       = "already exists"
+	CodeNotSupported        = "not supported"
 )
 
 // ErrCode returns the error code associated with
@@ -140,3 +141,7 @@ func IsCodeNotImplemented(err error) bool {
 func IsCodeAlreadyExists(err error) bool {
 	return ErrCode(err) == CodeAlreadyExists
 }
+
+func IsCodeNotSupported(err error) bool {
+	return ErrCode(err) == CodeNotSupported
+}
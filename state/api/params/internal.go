@@ -70,6 +70,21 @@ type PortsResult struct {
 	Ports []network.Port
 }
 
+// IngressRulesResults holds the bulk operation result of an API call
+// that returns a slice of network.IngressRule.
+type IngressRulesResults struct {
+	Results []IngressRulesResult
+}
+
+// IngressRulesResult holds the result of an API call that returns a
+// slice of network.IngressRule and the ports they apply to, or an
+// error.
+type IngressRulesResult struct {
+	Error *Error
+	Rules []network.IngressRule
+	Ports []network.Port
+}
+
 // StringsResults holds the bulk operation result of an API call
 // that returns a slice of strings or an error.
 type StringsResults struct {
@@ -179,6 +194,20 @@ type ConfigSettingsResults struct {
 	Results []ConfigSettingsResult
 }
 
+// EndpointAddressesResult holds the addresses of a unit to be used
+// by each of its service's endpoints, keyed by endpoint name, or an
+// error.
+type EndpointAddressesResult struct {
+	Error     *Error
+	Addresses map[string]string
+}
+
+// EndpointAddressesResults holds the bulk operation result of an API
+// call that returns the endpoint addresses of units.
+type EndpointAddressesResults struct {
+	Results []EndpointAddressesResult
+}
+
 // EnvironConfig holds an environment configuration.
 type EnvironConfig map[string]interface{}
 
@@ -285,6 +314,31 @@ type EntitiesPorts struct {
 	Entities []EntityPort
 }
 
+// HookRetryPolicyResult holds a hook retry policy or an error.
+type HookRetryPolicyResult struct {
+	Error  *Error
+	Result HookRetryPolicy
+}
+
+// HookRetryPolicyResults holds the results of a HookRetryPolicy
+// API call.
+type HookRetryPolicyResults struct {
+	Results []HookRetryPolicyResult
+}
+
+// EntityHookExecution holds a unit's tag and a hook execution
+// to record for it.
+type EntityHookExecution struct {
+	Tag       string
+	Execution HookExecution
+}
+
+// EntitiesHookExecutions holds the parameters for making a
+// RecordHookExecutions API call.
+type EntitiesHookExecutions struct {
+	Entities []EntityHookExecution
+}
+
 // EntityCharmURL holds an entity's tag and a charm URL.
 type EntityCharmURL struct {
 	Tag      string
@@ -374,6 +428,21 @@ type NetworkInterface struct {
 
 	// Disabled returns whether the interface is disabled.
 	Disabled bool
+
+	// MTU is the interface's maximum transmission unit, in bytes.
+	// When 0, the default MTU for the device is used.
+	MTU int
+
+	// BondMode is the bonding mode of a bonded interface.
+	BondMode string
+
+	// BondSlaves holds the raw names of the interfaces aggregated by
+	// a bonded interface.
+	BondSlaves []string
+
+	// Routes holds the static routes to set up through the
+	// interface.
+	Routes []network.Route
 }
 
 // InstanceInfo holds a machine tag, provider-specific instance id, a
@@ -638,6 +707,37 @@ type RunResults struct {
 	Results []RunResult
 }
 
+// RunJob identifies a run job started with StartRun or
+// StartRunOnAllMachines.
+type RunJob struct {
+	JobId string
+}
+
+// RunJobOutput holds the parameters for a RunJobOutput call. Only
+// output recorded after the Since sequence number is returned.
+type RunJobOutput struct {
+	JobId string
+	Since int
+}
+
+// RunOutput holds a piece of output written by a run job's commands
+// on one of its targets.
+type RunOutput struct {
+	Seq       int
+	MachineId string
+	UnitId    string
+	Stderr    bool
+	Data      []byte
+}
+
+// RunJobOutputResults holds the results of a RunJobOutput call. Once
+// the job is done, Results holds its complete results.
+type RunJobOutputResults struct {
+	Output  []RunOutput
+	Done    bool
+	Results []RunResult
+}
+
 // AgentVersionResult is used to return the current version number of the
 // agent running the API server.
 type AgentVersionResult struct {
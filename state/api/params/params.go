@@ -158,6 +158,43 @@ type ServiceDeploy struct {
 	Constraints   constraints.Value
 	ToMachineSpec string
 	Networks      []string
+
+	// EndpointBindings holds the names of the spaces to bind the
+	// service's endpoints to, keyed by endpoint name.
+	EndpointBindings map[string]string
+}
+
+// AddSpace holds the parameters for making the AddSpace call.
+type AddSpace struct {
+	Name    string
+	Subnets []string
+}
+
+// SpaceInfo describes a space: a named group of subnets.
+type SpaceInfo struct {
+	Name    string
+	Subnets []string
+}
+
+// ListSpacesResults holds the results of the ListSpaces call.
+type ListSpacesResults struct {
+	Spaces []SpaceInfo
+}
+
+// SubnetInfo describes a subnet discovered in the provider.
+type SubnetInfo struct {
+	CIDR              string
+	ProviderId        string
+	VLANTag           int
+	AvailabilityZones []string
+
+	// Space holds the name of the space the subnet is in, if any.
+	Space string
+}
+
+// ListSubnetsResults holds the results of the ListSubnets call.
+type ListSubnetsResults struct {
+	Subnets []SubnetInfo
 }
 
 // ServiceUpdate holds the parameters for making the ServiceUpdate call.
@@ -178,11 +215,115 @@ type ServiceSetCharm struct {
 	Force       bool
 }
 
+// ServiceSetCharmHoldingUnits holds the parameters for making the
+// ServiceSetCharmHoldingUnits call.
+type ServiceSetCharmHoldingUnits struct {
+	ServiceName string
+	CharmUrl    string
+	Force       bool
+	HeldUnits   []string
+}
+
+// ServiceReleaseCharmUpgrades holds the parameters for making the
+// ServiceReleaseCharmUpgrades call.
+type ServiceReleaseCharmUpgrades struct {
+	ServiceName string
+	UnitNames   []string
+}
+
+// ServiceRollbackCharm holds the parameters for making the
+// ServiceRollbackCharm call.
+type ServiceRollbackCharm struct {
+	ServiceName string
+}
+
 // ServiceExpose holds the parameters for making the ServiceExpose call.
 type ServiceExpose struct {
 	ServiceName string
 }
 
+// ServiceExposeFrom holds the parameters for making the
+// ServiceExposeFrom call.
+type ServiceExposeFrom struct {
+	ServiceName string
+	SourceCIDRs []string
+}
+
+// HookRetryPolicy holds how failed hooks are automatically retried.
+type HookRetryPolicy struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// ServiceSetHookRetryPolicy holds the parameters for making a
+// ServiceSetHookRetryPolicy call. A nil Policy makes the service
+// use the environment's policy.
+type ServiceSetHookRetryPolicy struct {
+	ServiceName string
+	Policy      *HookRetryPolicy
+}
+
+// HookExecution describes a single run of a hook by a unit.
+type HookExecution struct {
+	Hook       string
+	Relation   string
+	RemoteUnit string
+	Started    time.Time
+	Duration   time.Duration
+	ExitCode   int
+	Output     []string
+}
+
+// HookHistory holds the parameters for making a HookHistory call.
+type HookHistory struct {
+	UnitName string
+}
+
+// HookHistoryResults holds the results of a HookHistory call.
+type HookHistoryResults struct {
+	Executions []HookExecution
+}
+
+// StatusHistoryEntry describes a status an entity had.
+type StatusHistoryEntry struct {
+	Status  Status
+	Info    string
+	Data    StatusData
+	Updated time.Time
+}
+
+// StatusHistory holds the parameters for making a StatusHistory call.
+type StatusHistory struct {
+	MachineId string
+}
+
+// StatusHistoryResults holds the results of a StatusHistory call.
+type StatusHistoryResults struct {
+	Statuses []StatusHistoryEntry
+}
+
+// HarvestReport holds the parameters for making a HarvestReport call.
+// Mode holds the name of the harvest mode to report on; if empty, the
+// environment's harvest mode is used.
+type HarvestReport struct {
+	Mode string
+}
+
+// HarvestedInstance describes an instance the provisioner would
+// terminate. MachineId holds the id of the destroyed machine the
+// instance belongs to, and is empty for unknown instances.
+type HarvestedInstance struct {
+	InstanceId instance.Id
+	MachineId  string
+	Reason     string
+}
+
+// HarvestReportResults holds the results of a HarvestReport call.
+type HarvestReportResults struct {
+	Mode      string
+	Instances []HarvestedInstance
+}
+
 // ServiceSet holds the parameters for a ServiceSet
 // command. Options contains the configuration data.
 type ServiceSet struct {
@@ -614,6 +755,20 @@ type ContainerConfig struct {
 	PreferIPv6              bool
 }
 
+// ContainerAddressResult holds an address allocated by the provider
+// for a container, with the CIDR of the subnet it is on, or an error.
+type ContainerAddressResult struct {
+	Error   *Error
+	Address string
+	CIDR    string
+}
+
+// ContainerAddressResults holds the results of the
+// AllocateContainerAddresses provisioner API call.
+type ContainerAddressResults struct {
+	Results []ContainerAddressResult
+}
+
 // ProvisioningScriptParams contains the parameters for the
 // ProvisioningScript client API call.
 type ProvisioningScriptParams struct {
@@ -705,6 +860,23 @@ type DistributionGroupResults struct {
 	Results []DistributionGroupResult
 }
 
+// RetainedInstance describes the instance of a dead machine that the
+// provisioner left running because of the environment's harvest mode.
+type RetainedInstance struct {
+	InstanceId instance.Id
+	MachineId  string
+}
+
+// RetainedInstancesResult holds the result of a RetainedInstances call.
+type RetainedInstancesResult struct {
+	Instances []RetainedInstance
+}
+
+// InstanceIds holds the ids of some instances.
+type InstanceIds struct {
+	Ids []instance.Id
+}
+
 // APIHostPortsResult holds the result of an APIHostPorts
 // call. Each element in the top level slice holds
 // the addresses for one API server.
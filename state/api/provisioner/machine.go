@@ -131,6 +131,20 @@ func (m *Machine) Remove() error {
 	return result.OneError()
 }
 
+// RetainInstance records that the machine's instance is left running
+// when the machine is removed. It will fail if the machine is not Dead.
+func (m *Machine) RetainInstance() error {
+	var result params.ErrorResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: m.tag.String()}},
+	}
+	err := m.st.call("RetainInstances", args, &result)
+	if err != nil {
+		return err
+	}
+	return result.OneError()
+}
+
 // Series returns the operating system series running on the machine.
 //
 // NOTE: Unlike state.Machine.Series(), this method returns an error
@@ -8,6 +8,7 @@ import (
 
 	"github.com/juju/names"
 
+	"github.com/juju/juju/instance"
 	"github.com/juju/juju/state/api/base"
 	"github.com/juju/juju/state/api/common"
 	"github.com/juju/juju/state/api/params"
@@ -130,6 +131,49 @@ func (st *State) ContainerConfig() (result params.ContainerConfig, err error) {
 	return result, err
 }
 
+// AllocateContainerAddress allocates an address for the container
+// with the given tag, routable on the provider network of its host.
+// The error satisfies params.IsCodeNotSupported when the environment
+// cannot allocate such addresses.
+func (st *State) AllocateContainerAddress(tag names.MachineTag) (params.ContainerAddressResult, error) {
+	var results params.ContainerAddressResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: tag.String()}},
+	}
+	err := st.call("AllocateContainerAddresses", args, &results)
+	if err != nil {
+		return params.ContainerAddressResult{}, err
+	}
+	if len(results.Results) != 1 {
+		return params.ContainerAddressResult{}, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return params.ContainerAddressResult{}, result.Error
+	}
+	return result, nil
+}
+
+// RetainedInstances returns the instances that were retained when
+// their machines were removed.
+func (st *State) RetainedInstances() ([]params.RetainedInstance, error) {
+	var result params.RetainedInstancesResult
+	if err := st.call("RetainedInstances", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Instances, nil
+}
+
+// ReleaseRetainedInstances forgets the given retained instances, once
+// they have been stopped or have otherwise gone away.
+func (st *State) ReleaseRetainedInstances(ids ...instance.Id) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := params.InstanceIds{Ids: ids}
+	return st.call("ReleaseRetainedInstances", args, nil)
+}
+
 // MachinesWithTransientErrors returns a slice of machines and corresponding status information
 // for those machines which have transient provisioning errors.
 func (st *State) MachinesWithTransientErrors() ([]*Machine, []params.StatusResult, error) {
@@ -180,6 +180,33 @@ func (s *provisionerSuite) TestEnsureDeadAndRemove(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "machine 0 is required by the environment")
 }
 
+func (s *provisionerSuite) TestRetainInstance(c *gc.C) {
+	otherMachine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = otherMachine.SetProvisioned("i-1", "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	apiMachine, err := s.provisioner.Machine(otherMachine.Tag().(names.MachineTag))
+	c.Assert(err, gc.IsNil)
+
+	err = apiMachine.RetainInstance()
+	c.Assert(err, gc.ErrorMatches, "cannot retain instance of machine 1: machine is not dead")
+	err = apiMachine.EnsureDead()
+	c.Assert(err, gc.IsNil)
+	err = apiMachine.RetainInstance()
+	c.Assert(err, gc.IsNil)
+	err = apiMachine.Remove()
+	c.Assert(err, gc.IsNil)
+
+	retained, err := s.provisioner.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained, gc.DeepEquals, []params.RetainedInstance{{InstanceId: "i-1", MachineId: "1"}})
+	err = s.provisioner.ReleaseRetainedInstances("i-1")
+	c.Assert(err, gc.IsNil)
+	retained, err = s.provisioner.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained, gc.HasLen, 0)
+}
+
 func (s *provisionerSuite) TestRefreshAndLife(c *gc.C) {
 	// Create a fresh machine to test the complete scenario.
 	otherMachine, err := s.State.AddMachine("quantal", state.JobHostUnits)
@@ -617,6 +644,31 @@ func (s *provisionerSuite) TestContainerConfig(c *gc.C) {
 	c.Assert(result.PreferIPv6, jc.IsTrue)
 }
 
+func (s *provisionerSuite) TestAllocateContainerAddress(c *gc.C) {
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container, err := s.State.AddMachineInsideMachine(template, s.machine.Id(), instance.LXC)
+	c.Assert(err, gc.IsNil)
+	err = s.machine.SetAddresses(network.NewAddress("0.1.2.250", network.ScopeCloudLocal))
+	c.Assert(err, gc.IsNil)
+
+	// Addresses can only be allocated on known subnets.
+	_, err = s.provisioner.AllocateContainerAddress(container.Tag().(names.MachineTag))
+	c.Assert(err, jc.Satisfies, params.IsCodeNotSupported)
+
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "0.1.2.0/24", ProviderId: "dummy-private"})
+	c.Assert(err, gc.IsNil)
+	result, err := s.provisioner.AllocateContainerAddress(container.Tag().(names.MachineTag))
+	c.Assert(err, gc.IsNil)
+	c.Assert(result.Address, gc.Matches, `0\.1\.2\.[0-9]+`)
+	c.Assert(result.CIDR, gc.Equals, "0.1.2.0/24")
+
+	_, err = s.provisioner.AllocateContainerAddress(s.machine.Tag().(names.MachineTag))
+	c.Assert(err, gc.ErrorMatches, "machine 0 is not a container")
+}
+
 func (s *provisionerSuite) TestToolsWrongMachine(c *gc.C) {
 	tools, err := s.provisioner.Tools(names.NewMachineTag("42"))
 	c.Assert(err, gc.ErrorMatches, "machine 42 not found")
@@ -16,6 +16,7 @@ import (
 	"github.com/juju/juju/state/api/deployer"
 	"github.com/juju/juju/state/api/environment"
 	"github.com/juju/juju/state/api/firewaller"
+	"github.com/juju/juju/state/api/hostfirewaller"
 	"github.com/juju/juju/state/api/keyupdater"
 	apilogger "github.com/juju/juju/state/api/logger"
 	"github.com/juju/juju/state/api/machiner"
@@ -127,6 +128,12 @@ func (st *State) Networker() *networker.State {
 	return networker.NewState(st)
 }
 
+// HostFirewaller returns a version of the state that provides
+// functionality required by the host firewaller worker.
+func (st *State) HostFirewaller() *hostfirewaller.State {
+	return hostfirewaller.NewState(st)
+}
+
 // Provisioner returns a version of the state that provides functionality
 // required by the provisioner worker.
 func (st *State) Provisioner() *provisioner.State {
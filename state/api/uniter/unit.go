@@ -125,6 +125,64 @@ func (u *Unit) ConfigSettings() (charm.Settings, error) {
 	return charm.Settings(result.Settings), nil
 }
 
+// HookRetryPolicy returns the policy used to automatically retry the
+// unit's failed hooks.
+func (u *Unit) HookRetryPolicy() (params.HookRetryPolicy, error) {
+	var results params.HookRetryPolicyResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: u.tag.String()}},
+	}
+	err := u.st.call("HookRetryPolicy", args, &results)
+	if err != nil {
+		return params.HookRetryPolicy{}, err
+	}
+	if len(results.Results) != 1 {
+		return params.HookRetryPolicy{}, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return params.HookRetryPolicy{}, result.Error
+	}
+	return result.Result, nil
+}
+
+// RollbackCharm changes the charm of the unit's service back to the one
+// it used before its last charm change. The environment must allow
+// automatic rollbacks, the service must be being upgraded and the unit
+// must be running the service's current charm.
+func (u *Unit) RollbackCharm() error {
+	var result params.ErrorResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: u.tag.String()}},
+	}
+	err := u.st.call("RollbackCharm", args, &result)
+	if err != nil {
+		return err
+	}
+	return result.OneError()
+}
+
+// CharmUpgradeHeld returns whether the unit is being held back from
+// upgrading to its service's charm.
+func (u *Unit) CharmUpgradeHeld() (bool, error) {
+	var results params.BoolResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: u.tag.String()}},
+	}
+	err := u.st.call("CharmUpgradeHeld", args, &results)
+	if err != nil {
+		return false, err
+	}
+	if len(results.Results) != 1 {
+		return false, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.Result, nil
+}
+
 // ServiceName returns the service name.
 func (u *Unit) ServiceName() string {
 	return names.UnitService(u.Name())
@@ -287,6 +345,44 @@ func (u *Unit) PrivateAddress() (string, error) {
 	return result.Result, nil
 }
 
+// EndpointAddresses returns the addresses of the unit to be used by
+// each of its service's endpoints, keyed by endpoint name. An endpoint
+// bound to a space uses the unit's address in that space; other
+// endpoints use its private address.
+func (u *Unit) EndpointAddresses() (map[string]string, error) {
+	var results params.EndpointAddressesResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: u.tag.String()}},
+	}
+	err := u.st.call("EndpointAddresses", args, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Results) != 1 {
+		return nil, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return result.Addresses, nil
+}
+
+// RecordHookExecution records a run of a hook by the unit.
+func (u *Unit) RecordHookExecution(execution params.HookExecution) error {
+	var result params.ErrorResults
+	args := params.EntitiesHookExecutions{
+		Entities: []params.EntityHookExecution{
+			{Tag: u.tag.String(), Execution: execution},
+		},
+	}
+	err := u.st.call("RecordHookExecutions", args, &result)
+	if err != nil {
+		return err
+	}
+	return result.OneError()
+}
+
 // OpenPort sets the policy of the port with protocol and number to be
 // opened.
 //
@@ -6,6 +6,7 @@ package uniter_test
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/juju/charm"
 	"github.com/juju/errors"
@@ -226,6 +227,89 @@ func (s *unitSuite) TestPrivateAddress(c *gc.C) {
 	c.Assert(address, gc.Equals, "1.2.3.4")
 }
 
+func (s *unitSuite) TestEndpointAddresses(c *gc.C) {
+	err := s.wordpressMachine.SetAddresses(
+		network.NewAddress("1.2.3.4", network.ScopeCloudLocal),
+		network.NewAddress("10.0.1.4", network.ScopeCloudLocal),
+	)
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = s.wordpressService.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+
+	addresses, err := s.apiUnit.EndpointAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses["db"], gc.Equals, "10.0.1.4")
+	c.Assert(addresses["juju-info"], gc.Equals, "1.2.3.4")
+}
+
+func (s *unitSuite) TestRecordHookExecution(c *gc.C) {
+	started := time.Date(2014, 9, 1, 12, 0, 0, 0, time.UTC)
+	err := s.apiUnit.RecordHookExecution(params.HookExecution{
+		Hook:       "db-relation-changed",
+		Relation:   "wordpress:db mysql:server",
+		RemoteUnit: "mysql/0",
+		Started:    started,
+		Duration:   time.Second,
+		ExitCode:   1,
+		Output:     []string{"oops"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	executions, err := s.wordpressUnit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, jc.DeepEquals, []state.HookExecution{{
+		Hook:       "db-relation-changed",
+		Relation:   "wordpress:db mysql:server",
+		RemoteUnit: "mysql/0",
+		Started:    started,
+		Duration:   time.Second,
+		ExitCode:   1,
+		Output:     []string{"oops"},
+	}})
+}
+
+func (s *unitSuite) TestHookRetryPolicy(c *gc.C) {
+	policy, err := s.apiUnit.HookRetryPolicy()
+	c.Assert(err, gc.IsNil)
+	c.Assert(policy, gc.Equals, params.HookRetryPolicy{Delay: 10 * time.Second})
+
+	err = s.BackingState.UpdateEnvironConfig(map[string]interface{}{
+		"hook-retry-attempts": 2,
+	}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	policy, err = s.apiUnit.HookRetryPolicy()
+	c.Assert(err, gc.IsNil)
+	c.Assert(policy, gc.Equals, params.HookRetryPolicy{Attempts: 2, Delay: 10 * time.Second})
+}
+
+func (s *unitSuite) TestRollbackCharm(c *gc.C) {
+	err := s.apiUnit.RollbackCharm()
+	c.Assert(err, gc.ErrorMatches, "automatic charm rollback is disabled")
+
+	err = s.BackingState.UpdateEnvironConfig(map[string]interface{}{
+		"charm-auto-rollback": true,
+	}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	err = s.apiUnit.RollbackCharm()
+	c.Assert(err, gc.ErrorMatches, `service "wordpress" is not being upgraded`)
+}
+
+func (s *unitSuite) TestCharmUpgradeHeld(c *gc.C) {
+	held, err := s.apiUnit.CharmUpgradeHeld()
+	c.Assert(err, gc.IsNil)
+	c.Assert(held, jc.IsFalse)
+
+	err = s.wordpressService.SetCharmHoldingUnits(s.AddTestingCharm(c, "dummy"), false, []string{"wordpress/0"})
+	c.Assert(err, gc.IsNil)
+	held, err = s.apiUnit.CharmUpgradeHeld()
+	c.Assert(err, gc.IsNil)
+	c.Assert(held, jc.IsTrue)
+}
+
 func (s *unitSuite) TestOpenClosePort(c *gc.C) {
 	ports := s.wordpressUnit.OpenedPorts()
 	c.Assert(ports, gc.HasLen, 0)
@@ -13,6 +13,7 @@ import (
 	_ "github.com/juju/juju/state/apiserver/deployer"
 	_ "github.com/juju/juju/state/apiserver/environment"
 	_ "github.com/juju/juju/state/apiserver/firewaller"
+	_ "github.com/juju/juju/state/apiserver/hostfirewaller"
 	_ "github.com/juju/juju/state/apiserver/keymanager"
 	_ "github.com/juju/juju/state/apiserver/keyupdater"
 	_ "github.com/juju/juju/state/apiserver/logger"
@@ -22,6 +22,7 @@ import (
 	"github.com/juju/juju/rpc/jsoncodec"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/state/apiserver/client"
 	"github.com/juju/juju/state/apiserver/common"
 )
 
@@ -41,6 +42,7 @@ type Server struct {
 	logDir    string
 	limiter   utils.Limiter
 	validator LoginValidator
+	runJobs   *client.RunJobRunner
 
 	mu          sync.Mutex // protects the fields that follow
 	environUUID string
@@ -80,6 +82,7 @@ func NewServer(s *state.State, lis net.Listener, cfg ServerConfig) (*Server, err
 		logDir:    cfg.LogDir,
 		limiter:   utils.NewLimiter(loginRateLimit),
 		validator: cfg.Validator,
+		runJobs:   client.NewRunJobRunner(s, cfg.DataDir),
 	}
 	// TODO(rog) check that *srvRoot is a valid type for using
 	// as an RPC server.
@@ -191,6 +194,16 @@ func (srv *Server) run(lis net.Listener) {
 		srv.wg.Done()
 	}()
 	srv.wg.Add(1)
+	go func() {
+		// Run jobs do not outlive the server, so their commands
+		// are stopped when it is.
+		<-srv.tomb.Dying()
+		if err := srv.runJobs.Stop(); err != nil {
+			logger.Errorf("error stopping run jobs: %v", err)
+		}
+		srv.wg.Done()
+	}()
+	srv.wg.Add(1)
 	go func() {
 		err := srv.mongoPinger()
 		srv.tomb.Kill(err)
@@ -147,6 +147,59 @@ func (c *Client) Resolved(p params.Resolved) error {
 	return unit.Resolve(p.Retry)
 }
 
+// HookHistory returns the recorded hook executions of a unit,
+// oldest first.
+func (c *Client) HookHistory(p params.HookHistory) (params.HookHistoryResults, error) {
+	unit, err := c.api.state.Unit(p.UnitName)
+	if err != nil {
+		return params.HookHistoryResults{}, err
+	}
+	executions, err := unit.HookExecutions()
+	if err != nil {
+		return params.HookHistoryResults{}, err
+	}
+	results := params.HookHistoryResults{
+		Executions: make([]params.HookExecution, len(executions)),
+	}
+	for i, execution := range executions {
+		results.Executions[i] = params.HookExecution{
+			Hook:       execution.Hook,
+			Relation:   execution.Relation,
+			RemoteUnit: execution.RemoteUnit,
+			Started:    execution.Started,
+			Duration:   execution.Duration,
+			ExitCode:   execution.ExitCode,
+			Output:     execution.Output,
+		}
+	}
+	return results, nil
+}
+
+// StatusHistory returns the recorded statuses of a machine, oldest
+// first.
+func (c *Client) StatusHistory(p params.StatusHistory) (params.StatusHistoryResults, error) {
+	machine, err := c.api.state.Machine(p.MachineId)
+	if err != nil {
+		return params.StatusHistoryResults{}, err
+	}
+	entries, err := machine.StatusHistory()
+	if err != nil {
+		return params.StatusHistoryResults{}, err
+	}
+	results := params.StatusHistoryResults{
+		Statuses: make([]params.StatusHistoryEntry, len(entries)),
+	}
+	for i, entry := range entries {
+		results.Statuses[i] = params.StatusHistoryEntry{
+			Status:  entry.Status,
+			Info:    entry.Info,
+			Data:    entry.Data,
+			Updated: entry.Updated,
+		}
+	}
+	return results, nil
+}
+
 // PublicAddress implements the server side of Client.PublicAddress.
 func (c *Client) PublicAddress(p params.PublicAddress) (results params.PublicAddressResults, err error) {
 	switch {
@@ -213,6 +266,33 @@ func (c *Client) ServiceExpose(args params.ServiceExpose) error {
 	return svc.SetExposed()
 }
 
+// ServiceExposeFrom works like ServiceExpose, but only allows the
+// exposed ports to be accessed from addresses within the given
+// source CIDRs.
+func (c *Client) ServiceExposeFrom(args params.ServiceExposeFrom) error {
+	svc, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return err
+	}
+	return svc.SetExposedFrom(args.SourceCIDRs)
+}
+
+// ServiceSetHookRetryPolicy overrides the environment's hook retry
+// policy for a service, or clears the override if no policy is given.
+func (c *Client) ServiceSetHookRetryPolicy(args params.ServiceSetHookRetryPolicy) error {
+	svc, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return err
+	}
+	if args.Policy == nil {
+		return svc.SetHookRetryPolicy(nil)
+	}
+	return svc.SetHookRetryPolicy(&config.HookRetryPolicy{
+		Attempts: args.Policy.Attempts,
+		Delay:    args.Policy.Delay,
+	})
+}
+
 // ServiceUnexpose changes the juju-managed firewall to unexpose any ports that
 // were also explicitly marked by units as open.
 func (c *Client) ServiceUnexpose(args params.ServiceUnexpose) error {
@@ -303,6 +383,7 @@ func (c *Client) ServiceDeploy(args params.ServiceDeploy) error {
 			Constraints:    args.Constraints,
 			ToMachineSpec:  args.ToMachineSpec,
 			Networks:       requestedNetworks,
+			Bindings:       args.EndpointBindings,
 		})
 	return err
 }
@@ -315,6 +396,59 @@ func (c *Client) ServiceDeployWithNetworks(args params.ServiceDeploy) error {
 	return c.ServiceDeploy(args)
 }
 
+// ServiceDeployWithBindings works exactly like ServiceDeploy, but
+// allows binding the service's endpoints to spaces with
+// args.EndpointBindings.
+func (c *Client) ServiceDeployWithBindings(args params.ServiceDeploy) error {
+	return c.ServiceDeploy(args)
+}
+
+// AddSpace creates a new space holding the given subnets.
+func (c *Client) AddSpace(args params.AddSpace) error {
+	_, err := c.api.state.AddSpace(args.Name, args.Subnets)
+	return err
+}
+
+// ListSpaces returns all the spaces in the environment.
+func (c *Client) ListSpaces() (params.ListSpacesResults, error) {
+	spaces, err := c.api.state.AllSpaces()
+	if err != nil {
+		return params.ListSpacesResults{}, err
+	}
+	result := params.ListSpacesResults{
+		Spaces: make([]params.SpaceInfo, len(spaces)),
+	}
+	for i, space := range spaces {
+		result.Spaces[i] = params.SpaceInfo{
+			Name:    space.Name(),
+			Subnets: space.Subnets(),
+		}
+	}
+	return result, nil
+}
+
+// ListSubnets returns all the subnets discovered in the provider,
+// along with the spaces they are in.
+func (c *Client) ListSubnets() (params.ListSubnetsResults, error) {
+	subnets, err := c.api.state.AllSubnets()
+	if err != nil {
+		return params.ListSubnetsResults{}, err
+	}
+	result := params.ListSubnetsResults{
+		Subnets: make([]params.SubnetInfo, len(subnets)),
+	}
+	for i, subnet := range subnets {
+		result.Subnets[i] = params.SubnetInfo{
+			CIDR:              subnet.CIDR(),
+			ProviderId:        string(subnet.ProviderId()),
+			VLANTag:           subnet.VLANTag(),
+			AvailabilityZones: subnet.AvailabilityZones(),
+			Space:             subnet.SpaceName(),
+		}
+	}
+	return result, nil
+}
+
 // ServiceUpdate updates the service attributes, including charm URL,
 // minimum number of units, settings and constraints.
 // All parameters in params.ServiceUpdate except the service name are optional.
@@ -449,6 +583,49 @@ func (c *Client) ServiceSetCharm(args params.ServiceSetCharm) error {
 	return c.serviceSetCharm(service, args.CharmUrl, args.Force)
 }
 
+// ServiceSetCharmHoldingUnits sets the charm for a given service, holding
+// back the given units from upgrading to it until they are released with
+// ServiceReleaseCharmUpgrades.
+func (c *Client) ServiceSetCharmHoldingUnits(args params.ServiceSetCharmHoldingUnits) error {
+	service, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return err
+	}
+	curl, err := charm.ParseURL(args.CharmUrl)
+	if err != nil {
+		return err
+	}
+	sch, err := c.api.state.Charm(curl)
+	if err != nil {
+		return err
+	}
+	return service.SetCharmHoldingUnits(sch, args.Force, args.HeldUnits)
+}
+
+// ServiceReleaseCharmUpgrades allows the given units of a service to
+// upgrade to the service's charm.
+func (c *Client) ServiceReleaseCharmUpgrades(args params.ServiceReleaseCharmUpgrades) error {
+	service, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return err
+	}
+	return service.ReleaseCharmUpgrades(args.UnitNames...)
+}
+
+// ServiceRollbackCharm changes the charm of a service back to the one it
+// used before its last charm change, and returns the URL of that charm.
+func (c *Client) ServiceRollbackCharm(args params.ServiceRollbackCharm) (params.StringResult, error) {
+	service, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return params.StringResult{}, err
+	}
+	if err := service.RollbackCharm(); err != nil {
+		return params.StringResult{}, err
+	}
+	curl, _ := service.CharmURL()
+	return params.StringResult{Result: curl.String()}, nil
+}
+
 // addServiceUnits adds a given number of units to a service.
 func addServiceUnits(state *state.State, args params.AddServiceUnits) ([]*state.Unit, error) {
 	service, err := state.Service(args.ServiceName)
@@ -434,6 +434,98 @@ func (s *clientSuite) TestClientServiceExpose(c *gc.C) {
 	}
 }
 
+func (s *clientSuite) TestClientServiceExposeFrom(c *gc.C) {
+	s.AddTestingService(c, "dummy-service", s.AddTestingCharm(c, "dummy"))
+
+	err := s.APIState.Client().ServiceExposeFrom("dummy-service", []string{"10.0.0.0/8", "192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	service, err := s.State.Service("dummy-service")
+	c.Assert(err, gc.IsNil)
+	c.Assert(service.IsExposed(), gc.Equals, true)
+	c.Assert(service.ExposedCIDRs(), jc.DeepEquals, []string{"10.0.0.0/8", "192.168.1.0/24"})
+
+	err = s.APIState.Client().ServiceExposeFrom("dummy-service", []string{"not-a-cidr"})
+	c.Assert(err, gc.ErrorMatches, `cannot set exposed flag for service "dummy-service": invalid source CIDR "not-a-cidr"`)
+
+	err = s.APIState.Client().ServiceExposeFrom("unknown-service", []string{"10.0.0.0/8"})
+	c.Assert(err, gc.ErrorMatches, `service "unknown-service" not found`)
+}
+
+func (s *clientSuite) TestClientServiceSetHookRetryPolicy(c *gc.C) {
+	service := s.AddTestingService(c, "dummy-service", s.AddTestingCharm(c, "dummy"))
+
+	err := s.APIState.Client().ServiceSetHookRetryPolicy("dummy-service", &params.HookRetryPolicy{
+		Attempts: 5,
+		Delay:    time.Minute,
+	})
+	c.Assert(err, gc.IsNil)
+	err = service.Refresh()
+	c.Assert(err, gc.IsNil)
+	policy, ok := service.HookRetryPolicy()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(policy, gc.Equals, config.HookRetryPolicy{Attempts: 5, Delay: time.Minute})
+
+	err = s.APIState.Client().ServiceSetHookRetryPolicy("dummy-service", nil)
+	c.Assert(err, gc.IsNil)
+	err = service.Refresh()
+	c.Assert(err, gc.IsNil)
+	_, ok = service.HookRetryPolicy()
+	c.Assert(ok, jc.IsFalse)
+
+	err = s.APIState.Client().ServiceSetHookRetryPolicy("unknown-service", nil)
+	c.Assert(err, gc.ErrorMatches, `service "unknown-service" not found`)
+}
+
+func (s *clientSuite) TestClientHookHistory(c *gc.C) {
+	service := s.AddTestingService(c, "dummy-service", s.AddTestingCharm(c, "dummy"))
+	unit, err := service.AddUnit()
+	c.Assert(err, gc.IsNil)
+	started := time.Date(2014, 9, 1, 12, 0, 0, 0, time.UTC)
+	err = unit.AddHookExecution(state.HookExecution{
+		Hook:     "config-changed",
+		Started:  started,
+		Duration: 2 * time.Second,
+		ExitCode: 1,
+		Output:   []string{"boom"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	executions, err := s.APIState.Client().HookHistory("dummy-service/0")
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, jc.DeepEquals, []params.HookExecution{{
+		Hook:     "config-changed",
+		Started:  started,
+		Duration: 2 * time.Second,
+		ExitCode: 1,
+		Output:   []string{"boom"},
+	}})
+
+	_, err = s.APIState.Client().HookHistory("dummy-service/1")
+	c.Assert(err, gc.ErrorMatches, `unit "dummy-service/1" not found`)
+}
+
+func (s *clientSuite) TestClientStatusHistory(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = machine.SetStatus(params.StatusPending, "retrying", nil)
+	c.Assert(err, gc.IsNil)
+	err = machine.SetStatus(params.StatusError, "failed", params.StatusData{"attempts": 3})
+	c.Assert(err, gc.IsNil)
+
+	statuses, err := s.APIState.Client().StatusHistory(machine.Id())
+	c.Assert(err, gc.IsNil)
+	c.Assert(statuses, gc.HasLen, 2)
+	c.Assert(statuses[0].Status, gc.Equals, params.StatusPending)
+	c.Assert(statuses[0].Info, gc.Equals, "retrying")
+	c.Assert(statuses[1].Status, gc.Equals, params.StatusError)
+	c.Assert(statuses[1].Info, gc.Equals, "failed")
+	c.Assert(statuses[1].Data, gc.DeepEquals, params.StatusData{"attempts": 3})
+	c.Assert(statuses[1].Updated.Before(statuses[0].Updated), jc.IsFalse)
+
+	_, err = s.APIState.Client().StatusHistory("42")
+	c.Assert(err, gc.ErrorMatches, `machine 42 not found`)
+}
+
 var serviceUnexposeTests = []struct {
 	about    string
 	service  string
@@ -742,6 +834,83 @@ func (s *clientSuite) TestClientServiceDeployWithNetworks(c *gc.C) {
 	c.Assert(serviceCons, gc.DeepEquals, cons)
 }
 
+func (s *clientSuite) TestClientServiceDeployWithBindings(c *gc.C) {
+	store, restore := makeMockCharmStore()
+	defer restore()
+	curl, _ := addCharm(c, store, "wordpress")
+	args := params.ServiceDeploy{
+		ServiceName:      "service",
+		CharmUrl:         curl.String(),
+		NumUnits:         1,
+		EndpointBindings: map[string]string{"db": "internal"},
+	}
+	err := s.APIState.Client().ServiceDeployWithBindings(args)
+	c.Assert(err, gc.ErrorMatches, `cannot bind endpoint "db": space "internal" not found`)
+	_, err = s.State.Service("service")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	err = s.APIState.Client().AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = s.APIState.Client().ServiceDeployWithBindings(args)
+	c.Assert(err, gc.IsNil)
+	service, err := s.State.Service("service")
+	c.Assert(err, gc.IsNil)
+	c.Assert(service.EndpointBindings(), jc.DeepEquals, map[string]string{"db": "internal"})
+}
+
+func (s *clientSuite) TestClientSpaces(c *gc.C) {
+	spaces, err := s.APIState.Client().ListSpaces()
+	c.Assert(err, gc.IsNil)
+	c.Assert(spaces, gc.HasLen, 0)
+
+	for i, cidr := range []string{"10.0.1.0/24", "10.0.2.0/24", "192.168.1.0/24"} {
+		_, err = s.State.AddSubnet(state.SubnetInfo{
+			CIDR:       cidr,
+			ProviderId: network.Id(fmt.Sprintf("subnet-%d", i)),
+		})
+		c.Assert(err, gc.IsNil)
+	}
+	err = s.APIState.Client().AddSpace("public", []string{"192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = s.APIState.Client().AddSpace("internal", []string{"10.0.1.0/24", "10.0.2.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = s.APIState.Client().AddSpace("other", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "other": subnet 10.0.1.0/24 is already in space "internal"`)
+
+	spaces, err = s.APIState.Client().ListSpaces()
+	c.Assert(err, gc.IsNil)
+	c.Assert(spaces, jc.DeepEquals, []params.SpaceInfo{
+		{Name: "internal", Subnets: []string{"10.0.1.0/24", "10.0.2.0/24"}},
+		{Name: "public", Subnets: []string{"192.168.1.0/24"}},
+	})
+}
+
+func (s *clientSuite) TestClientListSubnets(c *gc.C) {
+	subnets, err := s.APIState.Client().ListSubnets()
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnets, gc.HasLen, 0)
+
+	_, err = s.State.AddSubnet(state.SubnetInfo{
+		CIDR:              "10.0.1.0/24",
+		ProviderId:        "subnet-1",
+		AvailabilityZones: []string{"zone1"},
+	})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.2.0/24", ProviderId: "subnet-2", VLANTag: 42})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.2.0/24"})
+	c.Assert(err, gc.IsNil)
+
+	subnets, err = s.APIState.Client().ListSubnets()
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnets, jc.DeepEquals, []params.SubnetInfo{
+		{CIDR: "10.0.1.0/24", ProviderId: "subnet-1", AvailabilityZones: []string{"zone1"}},
+		{CIDR: "10.0.2.0/24", ProviderId: "subnet-2", VLANTag: 42, Space: "internal"},
+	})
+}
+
 func (s *clientSuite) assertPrincipalDeployed(c *gc.C, serviceName string, curl *charm.URL, forced bool, bundle charm.Charm, cons constraints.Value) *state.Service {
 	service, err := s.State.Service(serviceName)
 	c.Assert(err, gc.IsNil)
@@ -1133,6 +1302,70 @@ func (s *clientSuite) TestClientServiceSetCharm(c *gc.C) {
 	c.Assert(force, gc.Equals, false)
 }
 
+func (s *clientSuite) TestClientServiceRollbackCharm(c *gc.C) {
+	store, restore := makeMockCharmStore()
+	defer restore()
+	curl, _ := addCharm(c, store, "dummy")
+	err := s.APIState.Client().ServiceDeploy(
+		curl.String(), "service", 1, "", constraints.Value{}, "",
+	)
+	c.Assert(err, gc.IsNil)
+	_, err = s.APIState.Client().ServiceRollbackCharm("service")
+	c.Assert(err, gc.ErrorMatches, `cannot roll back charm of service "service": no previous charm`)
+
+	addCharm(c, store, "wordpress")
+	err = s.APIState.Client().ServiceSetCharm(
+		"service", "cs:precise/wordpress-3", false,
+	)
+	c.Assert(err, gc.IsNil)
+	rolledBack, err := s.APIState.Client().ServiceRollbackCharm("service")
+	c.Assert(err, gc.IsNil)
+	c.Assert(rolledBack, gc.DeepEquals, curl)
+
+	// Ensure that the previous charm is marked as forced.
+	service, err := s.State.Service("service")
+	c.Assert(err, gc.IsNil)
+	charm, force, err := service.Charm()
+	c.Assert(err, gc.IsNil)
+	c.Assert(charm.URL(), gc.DeepEquals, curl)
+	c.Assert(force, gc.Equals, true)
+
+	_, err = s.APIState.Client().ServiceRollbackCharm("nonexistent")
+	c.Assert(err, gc.ErrorMatches, `service "nonexistent" not found`)
+}
+
+func (s *clientSuite) TestClientServiceSetCharmHoldingUnits(c *gc.C) {
+	store, restore := makeMockCharmStore()
+	defer restore()
+	curl, _ := addCharm(c, store, "dummy")
+	err := s.APIState.Client().ServiceDeploy(
+		curl.String(), "service", 2, "", constraints.Value{}, "",
+	)
+	c.Assert(err, gc.IsNil)
+	newURL, _ := addCharm(c, store, "wordpress")
+	err = s.APIState.Client().AddCharm(newURL)
+	c.Assert(err, gc.IsNil)
+
+	err = s.APIState.Client().ServiceSetCharmHoldingUnits(
+		"service", newURL.String(), false, []string{"service/0", "service/1"},
+	)
+	c.Assert(err, gc.IsNil)
+	service, err := s.State.Service("service")
+	c.Assert(err, gc.IsNil)
+	url, _ := service.CharmURL()
+	c.Assert(url, gc.DeepEquals, newURL)
+	c.Assert(service.HeldUnits(), jc.DeepEquals, []string{"service/0", "service/1"})
+
+	err = s.APIState.Client().ServiceReleaseCharmUpgrades("service", "service/1")
+	c.Assert(err, gc.IsNil)
+	err = service.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(service.HeldUnits(), jc.DeepEquals, []string{"service/0"})
+
+	err = s.APIState.Client().ServiceReleaseCharmUpgrades("nonexistent", "service/0")
+	c.Assert(err, gc.ErrorMatches, `service "nonexistent" not found`)
+}
+
 func (s *clientSuite) TestClientServiceSetCharmForce(c *gc.C) {
 	store, restore := makeMockCharmStore()
 	defer restore()
@@ -0,0 +1,108 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"sort"
+
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api/params"
+)
+
+// HarvestReport reports the instances the environment provisioner
+// would terminate in the given harvest mode, or in the environment's
+// harvest mode if none is given. No instances are terminated.
+func (c *Client) HarvestReport(args params.HarvestReport) (params.HarvestReportResults, error) {
+	envcfg, err := c.api.state.EnvironConfig()
+	if err != nil {
+		return params.HarvestReportResults{}, err
+	}
+	mode := envcfg.ProvisionerHarvestMode()
+	if args.Mode != "" {
+		if mode, err = config.ParseHarvestMode(args.Mode); err != nil {
+			return params.HarvestReportResults{}, err
+		}
+	}
+	env, err := environs.New(envcfg)
+	if err != nil {
+		return params.HarvestReportResults{}, err
+	}
+	instances, err := env.AllInstances()
+	if err != nil {
+		return params.HarvestReportResults{}, err
+	}
+	machines, err := c.api.state.AllMachines()
+	if err != nil {
+		return params.HarvestReportResults{}, err
+	}
+	retained, err := c.api.state.RetainedInstances()
+	if err != nil {
+		return params.HarvestReportResults{}, err
+	}
+	harvested := harvestedInstances(mode, instances, machines, retained)
+	sort.Sort(harvestedById(harvested))
+	return params.HarvestReportResults{
+		Mode:      mode.String(),
+		Instances: harvested,
+	}, nil
+}
+
+// harvestedInstances returns the instances the provisioner terminates
+// in the given harvest mode: those of dead machines, including the
+// removed machines whose instances were retained, and those not
+// associated with any machine.
+func harvestedInstances(
+	mode config.HarvestMode, instances []instance.Instance, machines []*state.Machine, retained []state.RetainedInstance,
+) []params.HarvestedInstance {
+	running := make(map[instance.Id]bool)
+	for _, inst := range instances {
+		running[inst.Id()] = true
+	}
+	harvested := []params.HarvestedInstance{}
+	for _, m := range machines {
+		id, err := m.InstanceId()
+		if err != nil || !running[id] {
+			continue
+		}
+		delete(running, id)
+		if m.Life() == state.Dead && mode.Destroyed() {
+			harvested = append(harvested, params.HarvestedInstance{
+				InstanceId: id,
+				MachineId:  m.Id(),
+				Reason:     "destroyed",
+			})
+		}
+	}
+	for _, r := range retained {
+		if !running[r.InstanceId] {
+			continue
+		}
+		delete(running, r.InstanceId)
+		if mode.Destroyed() {
+			harvested = append(harvested, params.HarvestedInstance{
+				InstanceId: r.InstanceId,
+				MachineId:  r.MachineId,
+				Reason:     "destroyed",
+			})
+		}
+	}
+	if mode.Unknown() {
+		for id := range running {
+			harvested = append(harvested, params.HarvestedInstance{
+				InstanceId: id,
+				Reason:     "unknown",
+			})
+		}
+	}
+	return harvested
+}
+
+type harvestedById []params.HarvestedInstance
+
+func (h harvestedById) Len() int           { return len(h) }
+func (h harvestedById) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h harvestedById) Less(i, j int) bool { return h[i].InstanceId < h[j].InstanceId }
@@ -0,0 +1,130 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/provider/dummy"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api/params"
+)
+
+type harvestReportSuite struct {
+	baseSuite
+}
+
+var _ = gc.Suite(&harvestReportSuite{})
+
+// setUpInstances adds a state server machine backed by the bootstrap
+// instance, a live machine and a dead machine backed by instances, and
+// starts an instance unknown to state.
+func (s *harvestReportSuite) setUpInstances(c *gc.C) (deadId, unknownId instance.Id) {
+	m0, err := s.State.AddMachine("precise", state.JobManageEnviron)
+	c.Assert(err, gc.IsNil)
+	err = m0.SetProvisioned(dummy.BootstrapInstanceId, "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+
+	for i := 0; i < 2; i++ {
+		m, err := s.State.AddMachine("precise", state.JobHostUnits)
+		c.Assert(err, gc.IsNil)
+		inst, _ := testing.AssertStartInstance(c, s.Environ, m.Id())
+		err = m.SetProvisioned(inst.Id(), "fake_nonce", nil)
+		c.Assert(err, gc.IsNil)
+		if i == 1 {
+			c.Assert(m.EnsureDead(), gc.IsNil)
+			deadId = inst.Id()
+		}
+	}
+
+	inst, _ := testing.AssertStartInstance(c, s.Environ, "99")
+	return deadId, inst.Id()
+}
+
+func (s *harvestReportSuite) TestHarvestReport(c *gc.C) {
+	deadId, unknownId := s.setUpInstances(c)
+
+	results, err := s.APIState.Client().HarvestReport("")
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, jc.DeepEquals, params.HarvestReportResults{
+		Mode: "all",
+		Instances: []params.HarvestedInstance{
+			{InstanceId: deadId, MachineId: "2", Reason: "destroyed"},
+			{InstanceId: unknownId, Reason: "unknown"},
+		},
+	})
+}
+
+func (s *harvestReportSuite) TestHarvestReportEnvironMode(c *gc.C) {
+	deadId, _ := s.setUpInstances(c)
+	err := s.State.UpdateEnvironConfig(map[string]interface{}{"provisioner-safe-mode": true}, nil, nil)
+	c.Assert(err, gc.IsNil)
+
+	results, err := s.APIState.Client().HarvestReport("")
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, jc.DeepEquals, params.HarvestReportResults{
+		Mode: "destroyed",
+		Instances: []params.HarvestedInstance{
+			{InstanceId: deadId, MachineId: "2", Reason: "destroyed"},
+		},
+	})
+}
+
+func (s *harvestReportSuite) TestHarvestReportGivenMode(c *gc.C) {
+	_, unknownId := s.setUpInstances(c)
+
+	results, err := s.APIState.Client().HarvestReport("unknown")
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, jc.DeepEquals, params.HarvestReportResults{
+		Mode: "unknown",
+		Instances: []params.HarvestedInstance{
+			{InstanceId: unknownId, Reason: "unknown"},
+		},
+	})
+
+	results, err = s.APIState.Client().HarvestReport("none")
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Instances, gc.HasLen, 0)
+
+	_, err = s.APIState.Client().HarvestReport("everything")
+	c.Assert(err, gc.ErrorMatches, `unknown harvest mode "everything"`)
+}
+
+func (s *harvestReportSuite) TestHarvestReportRetainedInstances(c *gc.C) {
+	deadId, unknownId := s.setUpInstances(c)
+	m2, err := s.State.Machine("2")
+	c.Assert(err, gc.IsNil)
+	err = m2.RetainInstance()
+	c.Assert(err, gc.IsNil)
+	err = m2.Remove()
+	c.Assert(err, gc.IsNil)
+
+	// The instance of the removed machine is still reported as the
+	// instance of a destroyed machine, and never as an unknown one.
+	results, err := s.APIState.Client().HarvestReport("all")
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Instances, jc.DeepEquals, []params.HarvestedInstance{
+		{InstanceId: deadId, MachineId: "2", Reason: "destroyed"},
+		{InstanceId: unknownId, Reason: "unknown"},
+	})
+
+	results, err = s.APIState.Client().HarvestReport("unknown")
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Instances, jc.DeepEquals, []params.HarvestedInstance{
+		{InstanceId: unknownId, Reason: "unknown"},
+	})
+}
+
+func (s *harvestReportSuite) TestHarvestReportDoesNotStopInstances(c *gc.C) {
+	deadId, unknownId := s.setUpInstances(c)
+
+	_, err := s.APIState.Client().HarvestReport("all")
+	c.Assert(err, gc.IsNil)
+	instances, err := s.Environ.Instances([]instance.Id{deadId, unknownId})
+	c.Assert(err, gc.IsNil)
+	c.Assert(instances, gc.HasLen, 2)
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/juju/utils"
+	"github.com/juju/utils/exec"
 	"github.com/juju/utils/set"
 
 	"github.com/juju/juju/agent"
@@ -77,6 +78,14 @@ func getAllUnitNames(st *state.State, units, services []string) (result []*state
 	return result, nil
 }
 
+func (c *Client) getRunJobRunner() (*RunJobRunner, error) {
+	runner, ok := c.api.resources.Get("runJobRunner").(RunJobRunnerResource)
+	if !ok {
+		return nil, fmt.Errorf("run jobs are not available")
+	}
+	return runner.RunJobRunner, nil
+}
+
 func (c *Client) getDataDir() string {
 	dataResource, ok := c.api.resources.Get("dataDir").(common.StringResource)
 	if !ok {
@@ -85,18 +94,18 @@ func (c *Client) getDataDir() string {
 	return dataResource.String()
 }
 
-// Run the commands specified on the machines identified through the
-// list of machines, units and services.
-func (c *Client) Run(run params.RunParams) (results params.RunResults, err error) {
+// runParams returns a RemoteExec for each of the machines and units
+// identified through the list of machines, units and services.
+func (c *Client) runParams(run params.RunParams) ([]*RemoteExec, error) {
 	units, err := getAllUnitNames(c.api.state, run.Units, run.Services)
 	if err != nil {
-		return results, err
+		return nil, err
 	}
 	// We want to create a RemoteExec for each unit and each machine.
 	// If we have both a unit and a machine request, we run it twice,
 	// once for the unit inside the exec context using juju-run, and
 	// the other outside the context just using bash.
-	var params []*RemoteExec
+	var execParams []*RemoteExec
 	var quotedCommands = utils.ShQuote(run.Commands)
 	for _, unit := range units {
 		// We know that the unit is both a principal unit, and that it has an
@@ -104,38 +113,158 @@ func (c *Client) Run(run params.RunParams) (results params.RunResults, err error
 		machineId, _ := unit.AssignedMachineId()
 		machine, err := c.api.state.Machine(machineId)
 		if err != nil {
-			return results, err
+			return nil, err
 		}
 		command := fmt.Sprintf("juju-run %s %s", unit.Name(), quotedCommands)
 		execParam := remoteParamsForMachine(machine, command, run.Timeout)
 		execParam.UnitId = unit.Name()
-		params = append(params, execParam)
+		execParams = append(execParams, execParam)
 	}
 	for _, machineId := range run.Machines {
 		machine, err := c.api.state.Machine(machineId)
 		if err != nil {
-			return results, err
+			return nil, err
 		}
 		command := fmt.Sprintf("juju-run --no-context %s", quotedCommands)
 		execParam := remoteParamsForMachine(machine, command, run.Timeout)
-		params = append(params, execParam)
+		execParams = append(execParams, execParam)
 	}
-	return ParallelExecute(c.getDataDir(), params), nil
+	return execParams, nil
 }
 
-// RunOnAllMachines attempts to run the specified command on all the machines.
-func (c *Client) RunOnAllMachines(run params.RunParams) (params.RunResults, error) {
+// allMachinesRunParams returns a RemoteExec for each machine in the
+// environment.
+func (c *Client) allMachinesRunParams(run params.RunParams) ([]*RemoteExec, error) {
 	machines, err := c.api.state.AllMachines()
 	if err != nil {
-		return params.RunResults{}, err
+		return nil, err
 	}
-	var params []*RemoteExec
+	var execParams []*RemoteExec
 	quotedCommands := utils.ShQuote(run.Commands)
 	command := fmt.Sprintf("juju-run --no-context %s", quotedCommands)
 	for _, machine := range machines {
-		params = append(params, remoteParamsForMachine(machine, command, run.Timeout))
+		execParams = append(execParams, remoteParamsForMachine(machine, command, run.Timeout))
+	}
+	return execParams, nil
+}
+
+// Run the commands specified on the machines identified through the
+// list of machines, units and services.
+func (c *Client) Run(run params.RunParams) (results params.RunResults, err error) {
+	execParams, err := c.runParams(run)
+	if err != nil {
+		return results, err
+	}
+	return ParallelExecute(c.getDataDir(), execParams), nil
+}
+
+// RunOnAllMachines attempts to run the specified command on all the machines.
+func (c *Client) RunOnAllMachines(run params.RunParams) (params.RunResults, error) {
+	execParams, err := c.allMachinesRunParams(run)
+	if err != nil {
+		return params.RunResults{}, err
+	}
+	return ParallelExecute(c.getDataDir(), execParams), nil
+}
+
+// StartRun starts running the commands specified on the machines
+// identified through the list of machines, units and services, without
+// waiting for them to finish. The output and results can be retrieved
+// with RunJobOutput using the returned job id.
+func (c *Client) StartRun(run params.RunParams) (params.RunJob, error) {
+	execParams, err := c.runParams(run)
+	if err != nil {
+		return params.RunJob{}, err
+	}
+	return c.startRunJob(run.Commands, execParams)
+}
+
+// StartRunOnAllMachines starts running the specified command on all
+// the machines, without waiting for it to finish.
+func (c *Client) StartRunOnAllMachines(run params.RunParams) (params.RunJob, error) {
+	execParams, err := c.allMachinesRunParams(run)
+	if err != nil {
+		return params.RunJob{}, err
+	}
+	return c.startRunJob(run.Commands, execParams)
+}
+
+func (c *Client) startRunJob(commands string, execParams []*RemoteExec) (params.RunJob, error) {
+	runner, err := c.getRunJobRunner()
+	if err != nil {
+		return params.RunJob{}, err
 	}
-	return ParallelExecute(c.getDataDir(), params), nil
+	targets := make([]state.RunTarget, len(execParams))
+	for i, execParam := range execParams {
+		targets[i] = state.RunTarget{
+			MachineId: execParam.MachineId,
+			UnitId:    execParam.UnitId,
+		}
+	}
+	owner := c.api.auth.GetAuthTag().String()
+	job, err := c.api.state.AddRunJob(owner, commands, targets)
+	if err != nil {
+		return params.RunJob{}, err
+	}
+	if err := runner.Start(job, execParams); err != nil {
+		return params.RunJob{}, err
+	}
+	return params.RunJob{JobId: job.Id()}, nil
+}
+
+// RunJobOutput returns the output recorded for a run job since the
+// given sequence number, and the job's results once it is done. Only
+// the owner of a job may retrieve its output.
+func (c *Client) RunJobOutput(args params.RunJobOutput) (params.RunJobOutputResults, error) {
+	var result params.RunJobOutputResults
+	job, err := c.api.state.RunJob(args.JobId)
+	if err != nil {
+		return result, err
+	}
+	if job.Owner() != c.api.auth.GetAuthTag().String() {
+		return result, common.ErrPerm
+	}
+	// The job was loaded before its output is read, so all of the
+	// output of any target already done is included.
+	output, err := job.Output(args.Since)
+	if err != nil {
+		return result, err
+	}
+	targets := job.Targets()
+	for _, out := range output {
+		if out.Target < 0 || out.Target >= len(targets) {
+			continue
+		}
+		result.Output = append(result.Output, params.RunOutput{
+			Seq:       out.Seq,
+			MachineId: targets[out.Target].MachineId,
+			UnitId:    targets[out.Target].UnitId,
+			Stderr:    out.Stderr,
+			Data:      out.Data,
+		})
+	}
+	if !job.Done() {
+		return result, nil
+	}
+	results, err := job.Results()
+	if err != nil {
+		return result, err
+	}
+	result.Done = true
+	for _, r := range results {
+		result.Results = append(result.Results, params.RunResult{
+			ExecResponse: exec.ExecResponse{
+				Code:   r.Code,
+				Stdout: r.Stdout,
+				Stderr: r.Stderr,
+			},
+			MachineId: r.MachineId,
+			UnitId:    r.UnitId,
+			Error:     r.Error,
+		})
+	}
+	sort.Sort(MachineOrder(result.Results))
+	return result, nil
 }
 
 // RemoteExec extends the standard ssh.ExecParams by providing the machine and
@@ -183,6 +312,60 @@ func ParallelExecute(dataDir string, runParams []*RemoteExec) params.RunResults
 	return params.RunResults{result}
 }
 
+// ParallelExecuteJob executes all of the requests defined in the params
+// in the same way as ParallelExecute, recording their output in the run
+// job as it arrives, and their results as they finish. The commands
+// are stopped early if the cancel channel is closed.
+func ParallelExecuteJob(dataDir string, job *state.RunJob, runParams []*RemoteExec, cancel <-chan struct{}) {
+	logger.Debugf("exec job %s: %#v", job.Id(), runParams)
+	var outstanding sync.WaitGroup
+	// lock serialises updates to the job.
+	var lock sync.Mutex
+	identity := filepath.Join(dataDir, agent.SystemIdentity)
+	for i, param := range runParams {
+		outstanding.Add(1)
+		param.IdentityFile = identity
+		param.Stdout = &runJobWriter{job: job, target: i, lock: &lock}
+		param.Stderr = &runJobWriter{job: job, target: i, stderr: true, lock: &lock}
+		param.Cancel = cancel
+		go func(target int, param *RemoteExec) {
+			defer outstanding.Done()
+			response, err := ssh.ExecuteCommandOnMachine(param.ExecParams)
+			logger.Debugf("reponse from %s: %v (err:%v)", param.MachineId, response, err)
+			var errMsg string
+			if err != nil {
+				errMsg = fmt.Sprint(err)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if err := job.SetResult(target, response.Code, errMsg); err != nil {
+				logger.Errorf("%v", err)
+			}
+		}(i, param)
+	}
+	outstanding.Wait()
+}
+
+// runJobWriter records everything written to it as output of one of
+// the targets of a run job.
+type runJobWriter struct {
+	job    *state.RunJob
+	target int
+	stderr bool
+	lock   *sync.Mutex
+}
+
+// Write implements io.Writer. Failures to record the output are logged
+// rather than returned, so they do not interrupt the running commands.
+func (w *runJobWriter) Write(data []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.job.AddOutput(w.target, w.stderr, data); err != nil {
+		logger.Errorf("%v", err)
+	}
+	return len(data), nil
+}
+
 // MachineOrder is used to provide the api to sort the results by the machine
 // id.
 type MachineOrder []params.RunResult
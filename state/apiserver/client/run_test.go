@@ -18,6 +18,7 @@ import (
 	"github.com/juju/juju/state/api/params"
 	"github.com/juju/juju/state/apiserver/client"
 	"github.com/juju/juju/testing"
+	"github.com/juju/juju/testing/factory"
 	"github.com/juju/juju/utils/ssh"
 )
 
@@ -76,16 +77,16 @@ func (s *runSuite) addUnit(c *gc.C, service *state.Service) *state.Unit {
 
 func (s *runSuite) TestGetAllUnitNames(c *gc.C) {
 	charm := s.AddTestingCharm(c, "dummy")
-	magic, err := s.State.AddService("magic", "user-admin", charm, nil)
+	magic, err := s.State.AddService("magic", "user-admin", charm, nil, nil)
 	s.addUnit(c, magic)
 	s.addUnit(c, magic)
 
-	notAssigned, err := s.State.AddService("not-assigned", "user-admin", charm, nil)
+	notAssigned, err := s.State.AddService("not-assigned", "user-admin", charm, nil, nil)
 	c.Assert(err, gc.IsNil)
 	_, err = notAssigned.AddUnit()
 	c.Assert(err, gc.IsNil)
 
-	_, err = s.State.AddService("no-units", "user-admin", charm, nil)
+	_, err = s.State.AddService("no-units", "user-admin", charm, nil, nil)
 	c.Assert(err, gc.IsNil)
 
 	for i, test := range []struct {
@@ -241,7 +242,7 @@ func (s *runSuite) TestRunMachineAndService(c *gc.C) {
 	s.addMachineWithAddress(c, "10.3.2.1")
 
 	charm := s.AddTestingCharm(c, "dummy")
-	magic, err := s.State.AddService("magic", "user-admin", charm, nil)
+	magic, err := s.State.AddService("magic", "user-admin", charm, nil, nil)
 	s.addUnit(c, magic)
 	s.addUnit(c, magic)
 
@@ -280,6 +281,195 @@ func (s *runSuite) TestRunMachineAndService(c *gc.C) {
 	c.Assert(results, jc.DeepEquals, expectedResults)
 }
 
+func (s *runSuite) TestParallelExecuteJob(c *gc.C) {
+	s.mockSSH(c, echoInputShowArgs)
+
+	params := []*client.RemoteExec{
+		&client.RemoteExec{
+			ExecParams: ssh.ExecParams{
+				Host:    "localhost",
+				Command: "foo",
+				Timeout: testing.LongWait,
+			},
+			MachineId: "0",
+		},
+		&client.RemoteExec{
+			ExecParams: ssh.ExecParams{
+				Command: "bar",
+				Timeout: testing.LongWait,
+			},
+			MachineId: "1",
+			UnitId:    "magic/0",
+		},
+	}
+	job, err := s.State.AddRunJob("user-admin", "foo", []state.RunTarget{
+		{MachineId: "0"},
+		{MachineId: "1", UnitId: "magic/0"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	client.ParallelExecuteJob("/some/dir", job, params, nil)
+	c.Assert(job.Done(), jc.IsTrue)
+	results, err := job.Results()
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.HasLen, 2)
+	c.Assert(results[0].Error, gc.Equals, "")
+	c.Assert(string(results[0].Stdout), gc.Equals, "foo\n")
+	c.Assert(string(results[0].Stderr), jc.Contains, "-i /some/dir/system-identity")
+	c.Assert(results[1].Error, gc.Equals, "missing host address")
+}
+
+func (s *runSuite) waitRunJob(c *gc.C, jobId string) params.RunJobOutputResults {
+	client := s.APIState.Client()
+	var all params.RunJobOutputResults
+	since := 0
+	for a := testing.LongAttempt.Start(); a.Next(); {
+		result, err := client.RunJobOutput(jobId, since)
+		c.Assert(err, gc.IsNil)
+		for _, out := range result.Output {
+			c.Assert(out.Seq, gc.Equals, since+1)
+			since = out.Seq
+		}
+		all.Output = append(all.Output, result.Output...)
+		if result.Done {
+			all.Done = true
+			all.Results = result.Results
+			return all
+		}
+	}
+	c.Fatalf("run job %s never finished", jobId)
+	panic("unreachable")
+}
+
+func (s *runSuite) TestStartRunOnAllMachines(c *gc.C) {
+	s.addMachineWithAddress(c, "10.3.2.1")
+	s.addMachineWithAddress(c, "10.3.2.2")
+
+	s.mockSSH(c, echoInput)
+
+	client := s.APIState.Client()
+	jobId, err := client.StartRunOnAllMachines("hostname", testing.LongWait)
+	c.Assert(err, gc.IsNil)
+	c.Assert(jobId, gc.Not(gc.Equals), "")
+
+	result := s.waitRunJob(c, jobId)
+	c.Assert(result.Output, gc.HasLen, 2)
+	var expectedResults []params.RunResult
+	for i := 0; i < 2; i++ {
+		expectedResults = append(expectedResults,
+			params.RunResult{
+				ExecResponse: exec.ExecResponse{Stdout: []byte("juju-run --no-context 'hostname'\n")},
+				MachineId:    fmt.Sprint(i),
+			})
+	}
+	c.Assert(result.Results, jc.DeepEquals, expectedResults)
+
+	// The results are kept, and can be fetched again later.
+	again, err := client.RunJobOutput(jobId, 0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(again.Done, jc.IsTrue)
+	c.Assert(again.Output, jc.DeepEquals, result.Output)
+	c.Assert(again.Results, jc.DeepEquals, expectedResults)
+}
+
+func (s *runSuite) TestStartRunService(c *gc.C) {
+	charm := s.AddTestingCharm(c, "dummy")
+	magic, err := s.State.AddService("magic", "user-admin", charm, nil, nil)
+	c.Assert(err, gc.IsNil)
+	s.addUnit(c, magic)
+
+	s.mockSSH(c, echoInput)
+
+	client := s.APIState.Client()
+	jobId, err := client.StartRun(
+		params.RunParams{
+			Commands: "hostname",
+			Timeout:  testing.LongWait,
+			Services: []string{"magic"},
+		})
+	c.Assert(err, gc.IsNil)
+
+	result := s.waitRunJob(c, jobId)
+	c.Assert(result.Output, jc.DeepEquals, []params.RunOutput{{
+		Seq:       1,
+		MachineId: "0",
+		UnitId:    "magic/0",
+		Data:      []byte("juju-run magic/0 'hostname'\n"),
+	}})
+	c.Assert(result.Results, jc.DeepEquals, []params.RunResult{{
+		ExecResponse: exec.ExecResponse{Stdout: []byte("juju-run magic/0 'hostname'\n")},
+		MachineId:    "0",
+		UnitId:       "magic/0",
+	}})
+}
+
+func (s *runSuite) TestParallelExecuteJobCancel(c *gc.C) {
+	s.mockSSH(c, "#!/bin/bash\nsleep 60\n")
+
+	params := []*client.RemoteExec{
+		&client.RemoteExec{
+			ExecParams: ssh.ExecParams{
+				Host:    "localhost",
+				Command: "foo",
+				Timeout: testing.LongWait,
+			},
+			MachineId: "0",
+		},
+	}
+	job, err := s.State.AddRunJob("user-admin", "foo", []state.RunTarget{{MachineId: "0"}})
+	c.Assert(err, gc.IsNil)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	client.ParallelExecuteJob("/some/dir", job, params, cancel)
+	c.Assert(job.Done(), jc.IsTrue)
+	results, err := job.Results()
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].Error, gc.Equals, "command cancelled")
+}
+
+func (s *runSuite) TestRunJobOutputOtherOwner(c *gc.C) {
+	s.addMachineWithAddress(c, "10.3.2.1")
+	s.mockSSH(c, echoInput)
+	jobId, err := s.APIState.Client().StartRunOnAllMachines("hostname", testing.LongWait)
+	c.Assert(err, gc.IsNil)
+
+	user := s.Factory.MakeUser(factory.UserParams{Password: "password"})
+	st := s.OpenAPIAs(c, user.Tag(), "password")
+	defer st.Close()
+	_, err = st.Client().RunJobOutput(jobId, 0)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(params.ErrCode(err), gc.Equals, params.CodeUnauthorized)
+}
+
+func (s *runSuite) TestAbandonedRunJobsFailed(c *gc.C) {
+	s.PatchValue(client.RunJobActiveInterval, testing.ShortWait)
+	s.PatchValue(client.RunJobAbandonedTimeout, time.Duration(0))
+	job, err := s.State.AddRunJob("user-admin", "foo", []state.RunTarget{{MachineId: "0"}})
+	c.Assert(err, gc.IsNil)
+
+	runner := client.NewRunJobRunner(s.State, "/some/dir")
+	defer func() { c.Assert(runner.Stop(), gc.IsNil) }()
+	for a := testing.LongAttempt.Start(); a.Next(); {
+		err := job.Refresh()
+		c.Assert(err, gc.IsNil)
+		if job.Done() {
+			break
+		}
+	}
+	results, err := job.Results()
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].Done, jc.IsTrue)
+	c.Assert(results[0].Error, gc.Matches, "command abandoned: .*")
+}
+
+func (s *runSuite) TestRunJobOutputNotFound(c *gc.C) {
+	_, err := s.APIState.Client().RunJobOutput("42", 0)
+	c.Assert(err, gc.ErrorMatches, `run job "42" not found`)
+}
+
 var echoInputShowArgs = `#!/bin/bash
 # Write the args to stderr
 echo "$*" >&2
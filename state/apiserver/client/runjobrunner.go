@@ -0,0 +1,144 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/state"
+)
+
+var (
+	// runJobActiveInterval is how often the runner records that the
+	// jobs it is running are still active.
+	runJobActiveInterval = time.Minute
+
+	// runJobAbandonedTimeout is how long a job may go without being
+	// recorded as active before its unfinished targets are failed.
+	runJobAbandonedTimeout = 5 * time.Minute
+)
+
+// runJobAbandonedError is recorded as the error of the targets of run
+// jobs whose commands were abandoned before they finished.
+const runJobAbandonedError = "command abandoned: state server stopped before it finished"
+
+// RunJobRunner runs the commands of run jobs in the background on
+// behalf of the API server. The commands of any jobs still running are
+// stopped when the runner is stopped. While it runs, the runner
+// records that its jobs are active, and fails the unfinished targets
+// of jobs that have not been active for a while, so jobs left behind
+// by a state server that went away do not appear to run forever.
+type RunJobRunner struct {
+	tomb    tomb.Tomb
+	st      *state.State
+	dataDir string
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex // protects the fields that follow
+	stopping bool
+	jobs     map[string]*state.RunJob
+}
+
+// NewRunJobRunner returns a runner that runs jobs in the given state,
+// using the system identity stored in the dataDir.
+func NewRunJobRunner(st *state.State, dataDir string) *RunJobRunner {
+	r := &RunJobRunner{
+		st:      st,
+		dataDir: dataDir,
+		jobs:    make(map[string]*state.RunJob),
+	}
+	go func() {
+		defer r.tomb.Done()
+		r.tomb.Kill(r.loop())
+	}()
+	return r
+}
+
+// Kill implements worker.Worker.Kill.
+func (r *RunJobRunner) Kill() {
+	r.tomb.Kill(nil)
+}
+
+// Wait implements worker.Worker.Wait.
+func (r *RunJobRunner) Wait() error {
+	return r.tomb.Wait()
+}
+
+// Stop stops the runner, and the commands of all the jobs it is
+// running, and waits for them to finish.
+func (r *RunJobRunner) Stop() error {
+	r.tomb.Kill(nil)
+	return r.tomb.Wait()
+}
+
+// Start starts running the commands of the given job on its targets.
+func (r *RunJobRunner) Start(job *state.RunJob, execParams []*RemoteExec) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopping {
+		return fmt.Errorf("cannot start run job %q: runner is stopping", job.Id())
+	}
+	r.jobs[job.Id()] = job
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ParallelExecuteJob(r.dataDir, job, execParams, r.tomb.Dying())
+		r.mu.Lock()
+		delete(r.jobs, job.Id())
+		r.mu.Unlock()
+	}()
+	return nil
+}
+
+func (r *RunJobRunner) loop() error {
+	defer r.wg.Wait()
+	defer func() {
+		r.mu.Lock()
+		r.stopping = true
+		r.mu.Unlock()
+	}()
+	for {
+		r.checkJobs()
+		select {
+		case <-r.tomb.Dying():
+			return tomb.ErrDying
+		case <-time.After(runJobActiveInterval):
+		}
+	}
+}
+
+// checkJobs records that the jobs being run are active, and fails any
+// jobs that have been abandoned. Errors are logged rather than
+// returned, so they do not stop the jobs being run.
+func (r *RunJobRunner) checkJobs() {
+	r.mu.Lock()
+	ids := make([]string, 0, len(r.jobs))
+	for id := range r.jobs {
+		ids = append(ids, id)
+	}
+	r.mu.Unlock()
+	if err := r.st.SetRunJobsActive(ids); err != nil {
+		logger.Errorf("%v", err)
+	}
+	since := time.Now().Add(-runJobAbandonedTimeout)
+	if err := r.st.FailInactiveRunJobs(since, runJobAbandonedError); err != nil {
+		logger.Errorf("%v", err)
+	}
+}
+
+// RunJobRunnerResource makes a RunJobRunner available to the API
+// facades of a connection. The runner belongs to the API server, so
+// stopping the resource when the connection closes leaves it running.
+type RunJobRunnerResource struct {
+	*RunJobRunner
+}
+
+// Stop implements common.Resource.
+func (RunJobRunnerResource) Stop() error {
+	return nil
+}
@@ -485,7 +485,18 @@ func (context *statusContext) processService(service *state.Service) (status api
 	serviceCharmURL, _ := service.CharmURL()
 	status.Charm = serviceCharmURL.String()
 	status.Exposed = service.IsExposed()
+	status.ExposedCIDRs = service.ExposedCIDRs()
 	status.Life = processLife(service)
+	if status.Exposed {
+		// Report the sources that are really allowed, which may
+		// come from the spaces the service's endpoints are bound to.
+		cidrs, err := service.IngressCIDRs()
+		if err != nil {
+			status.Err = err
+			return
+		}
+		status.IngressCIDRs = cidrs
+	}
 
 	latestCharm, ok := context.latestCharms[*serviceCharmURL.WithRevision(-1)]
 	if ok && latestCharm != serviceCharmURL.String() {
@@ -664,13 +675,21 @@ func processAgent(entity stateAgent) (
 	return
 }
 
+// statusDataWhitelist holds the names of the agent StatusData
+// entries that are passed over the API.
+var statusDataWhitelist = set.NewStrings(
+	"relation-id",
+	"retry-attempt",
+	"retry-max",
+	"next-retry",
+)
+
 // filterStatusData limits what agent StatusData data is passed over
 // the API. This prevents unintended leakage of internal-only data.
 func filterStatusData(status params.StatusData) params.StatusData {
 	out := make(params.StatusData)
 	for name, value := range status {
-		// use a set here if we end up with a larger whitelist
-		if name == "relation-id" {
+		if statusDataWhitelist.Contains(name) {
 			out[name] = value
 		}
 	}
@@ -4,6 +4,7 @@
 package client_test
 
 import (
+	jc "github.com/juju/testing/checkers"
 	gc "launchpad.net/gocheck"
 
 	"github.com/juju/juju/instance"
@@ -57,3 +58,28 @@ func (s *statusSuite) TestLegacyStatus(c *gc.C) {
 	}
 	c.Check(resultMachine.InstanceId, gc.Equals, instanceId)
 }
+
+func (s *statusSuite) TestServiceIngressCIDRs(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = wordpress.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+
+	client := s.APIState.Client()
+	status, err := client.Status(nil)
+	c.Assert(err, gc.IsNil)
+	c.Check(status.Services["wordpress"].IngressCIDRs, gc.HasLen, 0)
+
+	// Once exposed, the sources allowed are the subnets of the
+	// space the endpoint is bound to.
+	err = wordpress.SetExposed()
+	c.Assert(err, gc.IsNil)
+	status, err = client.Status(nil)
+	c.Assert(err, gc.IsNil)
+	service := status.Services["wordpress"]
+	c.Check(service.ExposedCIDRs, gc.HasLen, 0)
+	c.Check(service.IngressCIDRs, jc.DeepEquals, []string{"10.0.1.0/24"})
+}
@@ -115,6 +115,8 @@ func ServerError(err error) *params.Error {
 		code = params.CodeNotFound
 	case errors.IsAlreadyExists(err):
 		code = params.CodeAlreadyExists
+	case errors.IsNotSupported(err):
+		code = params.CodeNotSupported
 	case state.IsNotAssigned(err):
 		code = params.CodeNotAssigned
 	case state.IsHasAssignedUnitsError(err):
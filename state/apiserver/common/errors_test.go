@@ -79,6 +79,10 @@ var errorTransformTests = []struct {
 	err:        errors.AlreadyExistsf("blah"),
 	code:       params.CodeAlreadyExists,
 	helperFunc: params.IsCodeAlreadyExists,
+}, {
+	err:        errors.NotSupportedf("blah"),
+	code:       params.CodeNotSupported,
+	helperFunc: params.IsCodeNotSupported,
 }, {
 	err:        common.ErrUnknownWatcher,
 	code:       params.CodeNotFound,
@@ -138,6 +138,29 @@ func (f *FirewallerAPI) GetExposed(args params.Entities) (params.BoolResults, er
 	return result, nil
 }
 
+// GetIngressCIDRs returns the source CIDRs an exposed service is
+// restricted to, for each given service: those it was exposed to, or
+// else the subnets of the spaces its endpoints are bound to. An empty
+// result means the service's ports may be accessed from any address.
+func (f *FirewallerAPI) GetIngressCIDRs(args params.Entities) (params.StringsResults, error) {
+	result := params.StringsResults{
+		Results: make([]params.StringsResult, len(args.Entities)),
+	}
+	canAccess, err := f.accessService()
+	if err != nil {
+		return params.StringsResults{}, err
+	}
+	for i, entity := range args.Entities {
+		var service *state.Service
+		service, err = f.getService(canAccess, entity.Tag)
+		if err == nil {
+			result.Results[i].Result, err = service.IngressCIDRs()
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
 // GetAssignedMachine returns the assigned machine tag (if any) for
 // each given unit.
 func (f *FirewallerAPI) GetAssignedMachine(args params.Entities) (params.StringResults, error) {
@@ -325,6 +325,43 @@ func (s *firewallerSuite) TestGetExposed(c *gc.C) {
 	})
 }
 
+func (s *firewallerSuite) TestGetIngressCIDRs(c *gc.C) {
+	err := s.service.SetExposedFrom([]string{"10.0.0.0/8", "192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+
+	args := addFakeEntities(params.Entities{Entities: []params.Entity{
+		{Tag: s.service.Tag().String()},
+	}})
+	result, err := s.firewaller.GetIngressCIDRs(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, jc.DeepEquals, params.StringsResults{
+		Results: []params.StringsResult{
+			{Result: []string{"10.0.0.0/8", "192.168.1.0/24"}},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.NotFoundError(`service "bar"`)},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	// Exposing to any address clears the CIDRs.
+	err = s.service.SetExposed()
+	c.Assert(err, gc.IsNil)
+
+	args = params.Entities{Entities: []params.Entity{
+		{Tag: s.service.Tag().String()},
+	}}
+	result, err = s.firewaller.GetIngressCIDRs(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, jc.DeepEquals, params.StringsResults{
+		Results: []params.StringsResult{
+			{Result: nil},
+		},
+	})
+}
+
 func (s *firewallerSuite) TestOpenedPorts(c *gc.C) {
 	// Open some ports on two of the units.
 	err := s.units[0].OpenPort("tcp", 1234)
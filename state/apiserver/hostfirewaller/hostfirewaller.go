@@ -0,0 +1,118 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller
+
+import (
+	"github.com/juju/names"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/state/apiserver/common"
+	"github.com/juju/juju/state/watcher"
+)
+
+func init() {
+	common.RegisterStandardFacade("HostFirewaller", 0, NewHostFirewallerAPI)
+}
+
+// HostFirewallerAPI provides access to the HostFirewaller API facade,
+// used by machine agents to manage the firewall of their own host when
+// the environment uses the "host" firewall mode.
+type HostFirewallerAPI struct {
+	*common.EnvironWatcher
+
+	st          *state.State
+	resources   *common.Resources
+	authorizer  common.Authorizer
+	getAuthFunc common.GetAuthFunc
+}
+
+// NewHostFirewallerAPI creates a new server-side HostFirewaller API facade.
+func NewHostFirewallerAPI(
+	st *state.State,
+	resources *common.Resources,
+	authorizer common.Authorizer,
+) (*HostFirewallerAPI, error) {
+	if !authorizer.AuthMachineAgent() {
+		return nil, common.ErrPerm
+	}
+	getAuthFunc := func() (common.AuthFunc, error) {
+		authEntityTag := authorizer.GetAuthTag().String()
+		return func(tag string) bool {
+			// A machine agent can only access its own machine.
+			return tag == authEntityTag
+		}, nil
+	}
+	return &HostFirewallerAPI{
+		// Can always watch for environ changes, but
+		// does not get the secrets.
+		EnvironWatcher: common.NewEnvironWatcher(
+			st, resources, common.AuthAlways(true), common.AuthAlways(false),
+		),
+		st:          st,
+		resources:   resources,
+		authorizer:  authorizer,
+		getAuthFunc: getAuthFunc,
+	}, nil
+}
+
+func (h *HostFirewallerAPI) getMachine(canAccess common.AuthFunc, tag string) (*state.Machine, error) {
+	if !canAccess(tag) {
+		return nil, common.ErrPerm
+	}
+	t, err := names.ParseMachineTag(tag)
+	if err != nil {
+		return nil, common.ErrPerm
+	}
+	return h.st.Machine(t.Id())
+}
+
+// IngressRules returns the rules the host firewall of each given
+// machine should apply, and the ports they apply to.
+func (h *HostFirewallerAPI) IngressRules(args params.Entities) (params.IngressRulesResults, error) {
+	result := params.IngressRulesResults{
+		Results: make([]params.IngressRulesResult, len(args.Entities)),
+	}
+	canAccess, err := h.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, entity := range args.Entities {
+		machine, err := h.getMachine(canAccess, entity.Tag)
+		if err == nil {
+			result.Results[i].Rules, result.Results[i].Ports, err = machine.IngressRules()
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (h *HostFirewallerAPI) watchOneMachineIngressRules(machine *state.Machine) (string, error) {
+	watch := machine.WatchIngressRules()
+	// Consume the initial event.
+	if _, ok := <-watch.Changes(); ok {
+		return h.resources.Register(watch), nil
+	}
+	return "", watcher.MustErr(watch)
+}
+
+// WatchIngressRules returns a NotifyWatcher for observing changes to
+// the ingress rules of each given machine.
+func (h *HostFirewallerAPI) WatchIngressRules(args params.Entities) (params.NotifyWatchResults, error) {
+	result := params.NotifyWatchResults{
+		Results: make([]params.NotifyWatchResult, len(args.Entities)),
+	}
+	canAccess, err := h.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, entity := range args.Entities {
+		machine, err := h.getMachine(canAccess, entity.Tag)
+		if err == nil {
+			result.Results[i].NotifyWatcherId, err = h.watchOneMachineIngressRules(machine)
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/state/apiserver/common"
+	"github.com/juju/juju/state/apiserver/hostfirewaller"
+	apiservertesting "github.com/juju/juju/state/apiserver/testing"
+	statetesting "github.com/juju/juju/state/testing"
+)
+
+type hostFirewallerSuite struct {
+	testing.JujuConnSuite
+
+	machine *state.Machine
+	unit    *state.Unit
+
+	authorizer     apiservertesting.FakeAuthorizer
+	resources      *common.Resources
+	hostFirewaller *hostfirewaller.HostFirewallerAPI
+}
+
+var _ = gc.Suite(&hostFirewallerSuite{})
+
+func (s *hostFirewallerSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	service := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	s.unit, err = service.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = s.unit.AssignToMachine(s.machine)
+	c.Assert(err, gc.IsNil)
+	err = service.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+
+	// Create a FakeAuthorizer so we can check permissions,
+	// set up assuming we logged in as a machine agent.
+	s.authorizer = apiservertesting.FakeAuthorizer{
+		LoggedIn:     true,
+		MachineAgent: true,
+		Tag:          s.machine.Tag(),
+	}
+	s.resources = common.NewResources()
+	s.hostFirewaller, err = hostfirewaller.NewHostFirewallerAPI(
+		s.State,
+		s.resources,
+		s.authorizer,
+	)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *hostFirewallerSuite) TestNewHostFirewallerAPINonMachineAgent(c *gc.C) {
+	anAuthorizer := s.authorizer
+	anAuthorizer.MachineAgent = false
+	api, err := hostfirewaller.NewHostFirewallerAPI(s.State, s.resources, anAuthorizer)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(api, gc.IsNil)
+}
+
+func (s *hostFirewallerSuite) TestIngressRules(c *gc.C) {
+	err := s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: s.machine.Tag().String()},
+		{Tag: "machine-0"},
+		{Tag: "unit-wordpress-0"},
+		{Tag: "foo-42"},
+	}}
+	results, err := s.hostFirewaller.IngressRules(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, jc.DeepEquals, params.IngressRulesResults{
+		Results: []params.IngressRulesResult{
+			{
+				Rules: []network.IngressRule{
+					{network.Port{"tcp", 80}, "10.0.0.0/8"},
+				},
+				Ports: []network.Port{{"tcp", 80}},
+			},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+}
+
+func (s *hostFirewallerSuite) TestWatchIngressRules(c *gc.C) {
+	c.Assert(s.resources.Count(), gc.Equals, 0)
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: s.machine.Tag().String()},
+		{Tag: "machine-0"},
+		{Tag: "unit-wordpress-0"},
+	}}
+	result, err := s.hostFirewaller.WatchIngressRules(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.NotifyWatchResults{
+		Results: []params.NotifyWatchResult{
+			{NotifyWatcherId: "1"},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	// Verify the resource was registered and stop when done.
+	c.Assert(s.resources.Count(), gc.Equals, 1)
+	resource := s.resources.Get("1")
+	defer statetesting.AssertStop(c, resource)
+
+	// Check that the Watch call has consumed the initial event.
+	wc := statetesting.NewNotifyWatcherC(c, s.State, resource.(state.NotifyWatcher))
+	wc.AssertNoChange()
+
+	err = s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+}
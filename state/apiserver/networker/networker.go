@@ -101,6 +101,10 @@ func (n *NetworkerAPI) oneMachineInfo(id string) ([]network.Info, error) {
 			VLANTag:       nw.VLANTag(),
 			InterfaceName: iface.RawInterfaceName(),
 			Disabled:      iface.IsDisabled(),
+			MTU:           iface.MTU(),
+			BondMode:      iface.BondMode(),
+			BondSlaves:    iface.BondSlaves(),
+			Routes:        iface.Routes(),
 		}
 	}
 	return info, nil
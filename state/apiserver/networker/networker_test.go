@@ -99,6 +99,17 @@ func (s *networkerSuite) setUpMachine(c *gc.C) {
 		NetworkName:   "net2",
 		IsVirtual:     false,
 		Disabled:      true,
+	}, {
+		MACAddress:    "aa:bb:cc:dd:ee:f3",
+		InterfaceName: "bond0",
+		NetworkName:   "net2",
+		IsVirtual:     true,
+		MTU:           9000,
+		BondMode:      "802.3ad",
+		BondSlaves:    []string{"eth3", "eth4"},
+		Routes: []network.Route{
+			{DestinationCIDR: "0.6.0.0/16", GatewayAddress: "0.5.2.1", Metric: 10},
+		},
 	}}
 	err = s.machine.SetInstanceInfo("i-am", "fake_nonce", &hwChars, s.networks, s.machineIfaces)
 	c.Assert(err, gc.IsNil)
@@ -247,6 +258,19 @@ func (s *networkerSuite) TestMachineNetworkInfo(c *gc.C) {
 		VLANTag:       0,
 		InterfaceName: "eth2",
 		Disabled:      true,
+	}, {
+		MACAddress:    "aa:bb:cc:dd:ee:f3",
+		CIDR:          "0.5.2.0/24",
+		NetworkName:   "net2",
+		ProviderId:    "net2",
+		VLANTag:       0,
+		InterfaceName: "bond0",
+		MTU:           9000,
+		BondMode:      "802.3ad",
+		BondSlaves:    []string{"eth3", "eth4"},
+		Routes: []network.Route{
+			{DestinationCIDR: "0.6.0.0/16", GatewayAddress: "0.5.2.1", Metric: 10},
+		},
 	}}
 	expectedContainerInfo := []network.Info{{
 		MACAddress:    "aa:bb:cc:dd:ee:e0",
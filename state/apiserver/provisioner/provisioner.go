@@ -5,12 +5,18 @@ package provisioner
 
 import (
 	"fmt"
+	"net"
+	"reflect"
+	"sync"
 
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
 	"github.com/juju/names"
 	"github.com/juju/utils/set"
 
 	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/container"
+	"github.com/juju/juju/environs"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/api/params"
@@ -18,6 +24,8 @@ import (
 	"github.com/juju/juju/state/watcher"
 )
 
+var logger = loggo.GetLogger("juju.state.apiserver.provisioner")
+
 func init() {
 	common.RegisterStandardFacade("Provisioner", 0, NewProvisionerAPI)
 }
@@ -41,6 +49,12 @@ type ProvisionerAPI struct {
 	authorizer          common.Authorizer
 	getAuthFunc         common.GetAuthFunc
 	getCanWatchMachines common.GetAuthFunc
+
+	// mu guards env, which is created by environ, and the config
+	// it was last given.
+	mu     sync.Mutex
+	env    environs.Environ
+	envcfg map[string]interface{}
 }
 
 // NewProvisionerAPI creates a new server-side ProvisionerAPI facade.
@@ -214,6 +228,10 @@ func (p *ProvisionerAPI) ContainerManagerConfig(args params.ContainerManagerConf
 		if useLxcCloneAufs, ok := config.LXCUseCloneAUFS(); ok {
 			cfg["use-aufs"] = fmt.Sprint(useLxcCloneAufs)
 		}
+	case instance.KVM:
+		if url, ok := config.ImageMetadataURL(); ok {
+			cfg["image-metadata-url"] = url
+		}
 	}
 	result.ManagerConfig = cfg
 	return result, nil
@@ -236,6 +254,182 @@ func (p *ProvisionerAPI) ContainerConfig() (params.ContainerConfig, error) {
 	return result, nil
 }
 
+// AllocateContainerAddresses allocates, for each given container, an
+// address routable on the provider network of the container's host,
+// and records it in state so it can be released when the container is
+// removed. Each result has a not supported error when the environment
+// cannot allocate such addresses.
+func (p *ProvisionerAPI) AllocateContainerAddresses(args params.Entities) (params.ContainerAddressResults, error) {
+	result := params.ContainerAddressResults{
+		Results: make([]params.ContainerAddressResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	env, err := p.environ()
+	if err != nil {
+		return result, err
+	}
+	for i, entity := range args.Entities {
+		container, err := p.getMachine(canAccess, entity.Tag)
+		if err == nil {
+			result.Results[i], err = allocateContainerAddress(p.st, env, container)
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// allocateContainerAddress allocates an address for the container
+// on the subnet of its host.
+func allocateContainerAddress(
+	st *state.State, env environs.Environ, container *state.Machine,
+) (params.ContainerAddressResult, error) {
+	parentId, ok := container.ParentId()
+	if !ok {
+		return params.ContainerAddressResult{}, fmt.Errorf("machine %s is not a container", container.Id())
+	}
+	host, err := st.Machine(parentId)
+	if err != nil {
+		return params.ContainerAddressResult{}, err
+	}
+	hostId, err := host.InstanceId()
+	if err != nil {
+		return params.ContainerAddressResult{}, err
+	}
+	subnet, err := hostSubnet(st, host)
+	if err != nil {
+		return params.ContainerAddressResult{}, err
+	}
+	addr, err := env.AllocateAddress(hostId, subnet.ProviderId())
+	if errors.IsNotImplemented(err) || errors.IsNotSupported(err) {
+		return params.ContainerAddressResult{}, errors.NotSupportedf("container addresses")
+	} else if err != nil {
+		return params.ContainerAddressResult{}, errors.Annotatef(err, "cannot allocate address for container %s", container.Id())
+	}
+	if err := container.AddContainerAddress(addr, hostId, subnet.ProviderId()); err != nil {
+		// An address that is not recorded would never be released.
+		if err := env.ReleaseAddress(hostId, subnet.ProviderId(), addr); err != nil {
+			logger.Warningf("cannot release unrecorded address %q: %v", addr.Value, err)
+		}
+		return params.ContainerAddressResult{}, err
+	}
+	return params.ContainerAddressResult{
+		Address: addr.Value,
+		CIDR:    subnet.CIDR(),
+	}, nil
+}
+
+// hostSubnet returns the known subnet that one of the host's addresses
+// is on. Without one, no address can be allocated for its containers.
+func hostSubnet(st *state.State, host *state.Machine) (*state.Subnet, error) {
+	subnets, err := st.AllSubnets()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range host.Addresses() {
+		ip := net.ParseIP(addr.Value)
+		if ip == nil {
+			continue
+		}
+		for _, subnet := range subnets {
+			_, ipNet, err := net.ParseCIDR(subnet.CIDR())
+			if err == nil && ipNet.Contains(ip) {
+				return subnet, nil
+			}
+		}
+	}
+	return nil, errors.NewNotSupported(nil, fmt.Sprintf("machine %s is on no known subnet", host.Id()))
+}
+
+// Remove removes each given dead machine from state, first releasing
+// any addresses allocated for it as a container. A machine whose
+// addresses cannot be released is not removed, so the release is
+// retried when the provisioner next tries to remove it.
+func (p *ProvisionerAPI) Remove(args params.Entities) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	var remove params.Entities
+	var indexes []int
+	for i, entity := range args.Entities {
+		// Any other problem with the machine is reported by the
+		// Remover.
+		if machine, err := p.getMachine(canAccess, entity.Tag); err == nil && machine.Life() == state.Dead {
+			if err := p.releaseContainerAddresses(machine); err != nil {
+				result.Results[i].Error = common.ServerError(err)
+				continue
+			}
+		}
+		remove.Entities = append(remove.Entities, entity)
+		indexes = append(indexes, i)
+	}
+	removed, err := p.Remover.Remove(remove)
+	if err != nil {
+		return result, err
+	}
+	for j, i := range indexes {
+		result.Results[i] = removed.Results[j]
+	}
+	return result, nil
+}
+
+// releaseContainerAddresses releases the addresses allocated for the
+// machine, and forgets them.
+func (p *ProvisionerAPI) releaseContainerAddresses(machine *state.Machine) error {
+	addresses, err := machine.ContainerAddresses()
+	if err != nil || len(addresses) == 0 {
+		return err
+	}
+	env, err := p.environ()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		err := env.ReleaseAddress(addr.HostInstanceId, addr.NetworkId, addr.Address)
+		if errors.IsNotImplemented(err) || errors.IsNotSupported(err) {
+			logger.Warningf("cannot release address %q of machine %s: %v", addr.Address.Value, machine.Id(), err)
+		} else if err != nil {
+			return errors.Annotatef(err, "cannot release address %q of machine %s", addr.Address.Value, machine.Id())
+		}
+		if err := p.st.RemoveContainerAddress(addr.Address.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// environ returns the environment. It is created on first use and
+// then kept up to date with the environment config, rather than being
+// created again for each request.
+func (p *ProvisionerAPI) environ() (environs.Environ, error) {
+	cfg, err := p.st.EnvironConfig()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	attrs := cfg.AllAttrs()
+	if p.env == nil {
+		env, err := environs.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.env = env
+	} else if !reflect.DeepEqual(attrs, p.envcfg) {
+		if err := p.env.SetConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	p.envcfg = attrs
+	return p.env, nil
+}
+
 // Status returns the status of each given machine entity.
 func (p *ProvisionerAPI) Status(args params.Entities) (params.StatusResults, error) {
 	result := params.StatusResults{
@@ -296,6 +490,73 @@ func (p *ProvisionerAPI) MachinesWithTransientErrors() (params.StatusResults, er
 	return results, nil
 }
 
+// RetainInstances records that the instances of the given dead
+// machines are left running when the machines are removed.
+func (p *ProvisionerAPI) RetainInstances(args params.Entities) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, entity := range args.Entities {
+		machine, err := p.getMachine(canAccess, entity.Tag)
+		if err == nil {
+			err = machine.RetainInstance()
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// RetainedInstances returns the instances that were retained when
+// the machines they belonged to were removed.
+func (p *ProvisionerAPI) RetainedInstances() (params.RetainedInstancesResult, error) {
+	result := params.RetainedInstancesResult{}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	retained, err := p.st.RetainedInstances()
+	if err != nil {
+		return result, err
+	}
+	for _, r := range retained {
+		if !canAccess(names.NewMachineTag(r.MachineId).String()) {
+			continue
+		}
+		result.Instances = append(result.Instances, params.RetainedInstance{
+			InstanceId: r.InstanceId,
+			MachineId:  r.MachineId,
+		})
+	}
+	return result, nil
+}
+
+// ReleaseRetainedInstances forgets the given retained instances.
+func (p *ProvisionerAPI) ReleaseRetainedInstances(args params.InstanceIds) error {
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return err
+	}
+	retained, err := p.st.RetainedInstances()
+	if err != nil {
+		return err
+	}
+	machineIds := make(map[instance.Id]string)
+	for _, r := range retained {
+		machineIds[r.InstanceId] = r.MachineId
+	}
+	for _, id := range args.Ids {
+		machineId, found := machineIds[id]
+		if found && !canAccess(names.NewMachineTag(machineId).String()) {
+			return common.ErrPerm
+		}
+	}
+	return p.st.ReleaseRetainedInstances(args.Ids...)
+}
+
 // Series returns the deployed series for each given machine entity.
 func (p *ProvisionerAPI) Series(args params.Entities) (params.StringResults, error) {
 	result := params.StringResults{
@@ -493,6 +754,10 @@ func networkParamsToStateParams(networks []params.Network, ifaces []params.Netwo
 			InterfaceName: iface.InterfaceName,
 			IsVirtual:     iface.IsVirtual,
 			Disabled:      iface.Disabled,
+			MTU:           iface.MTU,
+			BondMode:      iface.BondMode,
+			BondSlaves:    iface.BondSlaves,
+			Routes:        iface.Routes,
 		}
 	}
 	return stateNetworks, stateInterfaces, nil
@@ -6,6 +6,7 @@ package provisioner_test
 import (
 	"fmt"
 	stdtesting "testing"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/names"
@@ -18,6 +19,7 @@ import (
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/network"
+	"github.com/juju/juju/provider/dummy"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/api/params"
 	"github.com/juju/juju/state/apiserver/common"
@@ -315,6 +317,65 @@ func (s *withoutStateServerSuite) TestRemove(c *gc.C) {
 	s.assertLife(c, 2, state.Alive)
 }
 
+func (s *withoutStateServerSuite) TestRetainInstances(c *gc.C) {
+	err := s.machines[1].SetProvisioned("i-1", "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	err = s.machines[1].EnsureDead()
+	c.Assert(err, gc.IsNil)
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: s.machines[0].Tag().String()},
+		{Tag: s.machines[1].Tag().String()},
+		{Tag: "machine-42"},
+		{Tag: "unit-foo-0"},
+	}}
+	result, err := s.provisioner.RetainInstances(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{&params.Error{Message: `cannot retain instance of machine 0: machine is not dead`}},
+			{nil},
+			{apiservertesting.NotFoundError("machine 42")},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	retained, err := s.provisioner.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained, gc.DeepEquals, params.RetainedInstancesResult{
+		Instances: []params.RetainedInstance{{InstanceId: "i-1", MachineId: "1"}},
+	})
+
+	err = s.provisioner.ReleaseRetainedInstances(params.InstanceIds{Ids: []instance.Id{"i-1"}})
+	c.Assert(err, gc.IsNil)
+	retained, err = s.provisioner.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained.Instances, gc.HasLen, 0)
+}
+
+func (s *withoutStateServerSuite) TestRetainedInstancesAccess(c *gc.C) {
+	err := s.machines[1].SetProvisioned("i-1", "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	err = s.machines[1].EnsureDead()
+	c.Assert(err, gc.IsNil)
+	err = s.machines[1].RetainInstance()
+	c.Assert(err, gc.IsNil)
+
+	// A machine agent sees only the retained instances of its own
+	// containers, and cannot release those of other machines.
+	anAuthorizer := s.authorizer
+	anAuthorizer.EnvironManager = false
+	anAuthorizer.MachineAgent = true
+	anAuthorizer.Tag = s.machines[0].Tag()
+	aProvisioner, err := provisioner.NewProvisionerAPI(s.State, s.resources, anAuthorizer)
+	c.Assert(err, gc.IsNil)
+	retained, err := aProvisioner.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained.Instances, gc.HasLen, 0)
+	err = aProvisioner.ReleaseRetainedInstances(params.InstanceIds{Ids: []instance.Id{"i-1"}})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
 func (s *withoutStateServerSuite) TestSetStatus(c *gc.C) {
 	err := s.machines[0].SetStatus(params.StatusStarted, "blah", nil)
 	c.Assert(err, gc.IsNil)
@@ -1153,6 +1214,20 @@ func (s *withoutStateServerSuite) TestContainerManagerConfig(c *gc.C) {
 	})
 }
 
+func (s *withoutStateServerSuite) TestContainerManagerConfigImageMetadataURL(c *gc.C) {
+	err := s.State.UpdateEnvironConfig(map[string]interface{}{
+		"image-metadata-url": "http://images.example.com/",
+	}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	args := params.ContainerManagerConfigParams{Type: instance.KVM}
+	results, err := s.provisioner.ContainerManagerConfig(args)
+	c.Check(err, gc.IsNil)
+	c.Assert(results.ManagerConfig, gc.DeepEquals, map[string]string{
+		container.ConfigName: "juju",
+		"image-metadata-url": "http://images.example.com/",
+	})
+}
+
 func (s *withoutStateServerSuite) TestContainerConfig(c *gc.C) {
 	attrs := map[string]interface{}{
 		"http-proxy": "http://proxy.example.com:9000",
@@ -1173,6 +1248,104 @@ func (s *withoutStateServerSuite) TestContainerConfig(c *gc.C) {
 	c.Check(results.PreferIPv6, jc.IsTrue)
 }
 
+func (s *withoutStateServerSuite) addProvisionedHost(c *gc.C, host *state.Machine, address string) {
+	inst, _ := testing.AssertStartInstance(c, s.Environ, host.Id())
+	err := host.SetProvisioned(inst.Id(), "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	err = host.SetAddresses(network.NewAddress(address, network.ScopeCloudLocal))
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *withoutStateServerSuite) addContainer(c *gc.C, host *state.Machine) *state.Machine {
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container, err := s.State.AddMachineInsideMachine(template, host.Id(), instance.LXC)
+	c.Assert(err, gc.IsNil)
+	return container
+}
+
+func (s *withoutStateServerSuite) TestAllocateContainerAddresses(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "0.1.2.0/24", ProviderId: "dummy-private"})
+	c.Assert(err, gc.IsNil)
+	s.addProvisionedHost(c, s.machines[0], "0.1.2.250")
+	s.addProvisionedHost(c, s.machines[2], "0.3.2.250")
+	provisionedHost := s.addContainer(c, s.machines[0])
+	unprovisionedHost := s.addContainer(c, s.machines[1])
+	unknownSubnet := s.addContainer(c, s.machines[2])
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: provisionedHost.Tag().String()},
+		{Tag: unprovisionedHost.Tag().String()},
+		{Tag: unknownSubnet.Tag().String()},
+		{Tag: s.machines[2].Tag().String()},
+		{Tag: "machine-42"},
+	}}
+	results, err := s.provisioner.AllocateContainerAddresses(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Results, gc.HasLen, 5)
+
+	// The dummy provider allocates addresses on its private network.
+	result := results.Results[0]
+	c.Assert(result.Error, gc.IsNil)
+	c.Assert(result.Address, gc.Matches, `0\.1\.2\.[0-9]+`)
+	c.Assert(result.CIDR, gc.Equals, "0.1.2.0/24")
+	addresses, err := provisionedHost.ContainerAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses, gc.HasLen, 1)
+	c.Assert(addresses[0].Address.Value, gc.Equals, result.Address)
+	c.Assert(addresses[0].NetworkId, gc.Equals, network.Id("dummy-private"))
+
+	c.Assert(results.Results[1].Error, jc.Satisfies, params.IsCodeNotProvisioned)
+	c.Assert(results.Results[2].Error, jc.Satisfies, params.IsCodeNotSupported)
+	c.Assert(results.Results[2].Error, gc.ErrorMatches, "machine 2 is on no known subnet")
+	c.Assert(results.Results[3].Error, gc.ErrorMatches, "machine 2 is not a container")
+	c.Assert(results.Results[4].Error, gc.DeepEquals, apiservertesting.NotFoundError("machine 42"))
+}
+
+func (s *withoutStateServerSuite) TestRemoveReleasesContainerAddresses(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "0.1.2.0/24", ProviderId: "dummy-private"})
+	c.Assert(err, gc.IsNil)
+	s.addProvisionedHost(c, s.machines[0], "0.1.2.250")
+	container := s.addContainer(c, s.machines[0])
+	args := params.Entities{Entities: []params.Entity{{Tag: container.Tag().String()}}}
+	results, err := s.provisioner.AllocateContainerAddresses(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	err = container.EnsureDead()
+	c.Assert(err, gc.IsNil)
+
+	opc := make(chan dummy.Operation, 200)
+	dummy.Listen(opc)
+	defer dummy.Listen(nil)
+	removed, err := s.provisioner.Remove(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(removed, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{{nil}},
+	})
+	addresses, err := container.ContainerAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses, gc.HasLen, 0)
+	err = container.Refresh()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	for {
+		select {
+		case op := <-opc:
+			release, ok := op.(dummy.OpReleaseAddress)
+			if !ok {
+				continue
+			}
+			c.Assert(release.Address.Value, gc.Equals, results.Results[0].Address)
+			c.Assert(release.NetworkId, gc.Equals, network.Id("dummy-private"))
+			return
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("address not released")
+		}
+	}
+}
+
 func (s *withoutStateServerSuite) TestToolsRefusesWrongAgent(c *gc.C) {
 	anAuthorizer := s.authorizer
 	anAuthorizer.Tag = names.NewMachineTag("12354")
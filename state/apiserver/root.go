@@ -15,6 +15,7 @@ import (
 	"github.com/juju/juju/rpc/rpcreflect"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/state/apiserver/client"
 	"github.com/juju/juju/state/apiserver/common"
 )
 
@@ -63,6 +64,7 @@ func newSrvRoot(root *initialRoot, entity state.Entity) *srvRoot {
 		objectCache: make(map[objectKey]reflect.Value),
 	}
 	r.resources.RegisterNamed("dataDir", common.StringResource(root.srv.dataDir))
+	r.resources.RegisterNamed("runJobRunner", client.RunJobRunnerResource{RunJobRunner: root.srv.runJobs})
 	return r
 }
 
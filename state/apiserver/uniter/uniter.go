@@ -12,6 +12,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/juju/names"
 
+	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/api/params"
 	"github.com/juju/juju/state/apiserver/common"
@@ -148,6 +149,32 @@ func (u *UniterAPI) PrivateAddress(args params.Entities) (params.StringResults,
 	return result, nil
 }
 
+// EndpointAddresses returns, for each given unit, the addresses it
+// should use for each of its service's endpoints: its address in the
+// space the endpoint is bound to, or its private address if the
+// endpoint is not bound.
+func (u *UniterAPI) EndpointAddresses(args params.Entities) (params.EndpointAddressesResults, error) {
+	result := params.EndpointAddressesResults{
+		Results: make([]params.EndpointAddressesResult, len(args.Entities)),
+	}
+	canAccess, err := u.accessUnit()
+	if err != nil {
+		return params.EndpointAddressesResults{}, err
+	}
+	for i, entity := range args.Entities {
+		err := common.ErrPerm
+		if canAccess(entity.Tag) {
+			var unit *state.Unit
+			unit, err = u.getUnit(entity.Tag)
+			if err == nil {
+				result.Results[i].Addresses, err = unit.EndpointAddresses()
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
 // Resolved returns the current resolved setting for each given unit.
 func (u *UniterAPI) Resolved(args params.Entities) (params.ResolvedModeResults, error) {
 	result := params.ResolvedModeResults{
@@ -390,6 +417,38 @@ func (u *UniterAPI) OpenPort(args params.EntitiesPorts) (params.ErrorResults, er
 	return result, nil
 }
 
+// RecordHookExecutions records a run of a hook for each given unit.
+func (u *UniterAPI) RecordHookExecutions(args params.EntitiesHookExecutions) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Entities)),
+	}
+	canAccess, err := u.accessUnit()
+	if err != nil {
+		return params.ErrorResults{}, err
+	}
+	for i, entity := range args.Entities {
+		err := common.ErrPerm
+		if canAccess(entity.Tag) {
+			var unit *state.Unit
+			unit, err = u.getUnit(entity.Tag)
+			if err == nil {
+				execution := entity.Execution
+				err = unit.AddHookExecution(state.HookExecution{
+					Hook:       execution.Hook,
+					Relation:   execution.Relation,
+					RemoteUnit: execution.RemoteUnit,
+					Started:    execution.Started,
+					Duration:   execution.Duration,
+					ExitCode:   execution.ExitCode,
+					Output:     execution.Output,
+				})
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
 // ClosePort sets the policy of the port with protocol and number to
 // be closed, for all given units.
 func (u *UniterAPI) ClosePort(args params.EntitiesPorts) (params.ErrorResults, error) {
@@ -528,6 +587,133 @@ func (u *UniterAPI) ConfigSettings(args params.Entities) (params.ConfigSettingsR
 	return result, nil
 }
 
+// HookRetryPolicy returns the policy used to automatically retry the
+// failed hooks of each given unit: its service's, if it overrides the
+// environment's, or the environment's otherwise.
+func (u *UniterAPI) HookRetryPolicy(args params.Entities) (params.HookRetryPolicyResults, error) {
+	result := params.HookRetryPolicyResults{
+		Results: make([]params.HookRetryPolicyResult, len(args.Entities)),
+	}
+	canAccess, err := u.accessUnit()
+	if err != nil {
+		return params.HookRetryPolicyResults{}, err
+	}
+	for i, entity := range args.Entities {
+		err := common.ErrPerm
+		if canAccess(entity.Tag) {
+			var policy config.HookRetryPolicy
+			policy, err = u.hookRetryPolicy(entity.Tag)
+			if err == nil {
+				result.Results[i].Result = params.HookRetryPolicy{
+					Attempts: policy.Attempts,
+					Delay:    policy.Delay,
+				}
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (u *UniterAPI) hookRetryPolicy(tag string) (config.HookRetryPolicy, error) {
+	unit, err := u.getUnit(tag)
+	if err != nil {
+		return config.HookRetryPolicy{}, err
+	}
+	service, err := unit.Service()
+	if err != nil {
+		return config.HookRetryPolicy{}, err
+	}
+	if policy, ok := service.HookRetryPolicy(); ok {
+		return policy, nil
+	}
+	envConfig, err := u.st.EnvironConfig()
+	if err != nil {
+		return config.HookRetryPolicy{}, err
+	}
+	return envConfig.HookRetryPolicy(), nil
+}
+
+// RollbackCharm changes the charm of each given unit's service back to
+// the one it used before its last charm change, provided the unit is
+// running the service's current charm. Units may only do so when the
+// environment's charm-auto-rollback setting is on, and while their
+// service is being upgraded.
+func (u *UniterAPI) RollbackCharm(args params.Entities) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Entities)),
+	}
+	canAccess, err := u.accessUnit()
+	if err != nil {
+		return params.ErrorResults{}, err
+	}
+	envConfig, err := u.st.EnvironConfig()
+	if err != nil {
+		return params.ErrorResults{}, err
+	}
+	for i, entity := range args.Entities {
+		err := common.ErrPerm
+		if canAccess(entity.Tag) {
+			if envConfig.CharmAutoRollback() {
+				err = u.rollbackCharm(entity.Tag)
+			} else {
+				err = fmt.Errorf("automatic charm rollback is disabled")
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (u *UniterAPI) rollbackCharm(tag string) error {
+	unit, err := u.getUnit(tag)
+	if err != nil {
+		return err
+	}
+	service, err := unit.Service()
+	if err != nil {
+		return err
+	}
+	serviceURL, _ := service.CharmURL()
+	previousURL, ok := service.PreviousCharmURL()
+	if !ok || *previousURL == *serviceURL {
+		return fmt.Errorf("service %q is not being upgraded", service)
+	}
+	unitURL, _ := unit.CharmURL()
+	if unitURL == nil || *unitURL != *serviceURL {
+		return fmt.Errorf("unit %q is not running the charm of service %q", unit, service)
+	}
+	return service.RollbackCharm()
+}
+
+// CharmUpgradeHeld returns whether each given unit is being held back
+// from upgrading to its service's charm.
+func (u *UniterAPI) CharmUpgradeHeld(args params.Entities) (params.BoolResults, error) {
+	result := params.BoolResults{
+		Results: make([]params.BoolResult, len(args.Entities)),
+	}
+	canAccess, err := u.accessUnit()
+	if err != nil {
+		return params.BoolResults{}, err
+	}
+	for i, entity := range args.Entities {
+		err := common.ErrPerm
+		if canAccess(entity.Tag) {
+			var unit *state.Unit
+			unit, err = u.getUnit(entity.Tag)
+			if err == nil {
+				var service *state.Service
+				service, err = unit.Service()
+				if err == nil {
+					result.Results[i].Result = service.CharmUpgradeHeld(unit.Name())
+				}
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
 func (u *UniterAPI) watchOneServiceRelations(tag string) (params.StringsWatchResult, error) {
 	nothing := params.StringsWatchResult{}
 	service, err := u.getService(tag)
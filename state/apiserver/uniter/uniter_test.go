@@ -5,6 +5,7 @@ package uniter_test
 
 import (
 	stdtesting "testing"
+	"time"
 
 	"github.com/juju/charm"
 	"github.com/juju/errors"
@@ -13,6 +14,7 @@ import (
 	jc "github.com/juju/testing/checkers"
 	gc "launchpad.net/gocheck"
 
+	"github.com/juju/juju/environs/config"
 	envtesting "github.com/juju/juju/environs/testing"
 	"github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/network"
@@ -386,6 +388,36 @@ func (s *uniterSuite) TestPrivateAddress(c *gc.C) {
 	})
 }
 
+func (s *uniterSuite) TestEndpointAddresses(c *gc.C) {
+	err := s.machine0.SetAddresses(
+		network.NewAddress("1.2.3.4", network.ScopeCloudLocal),
+		network.NewAddress("10.0.1.4", network.ScopeCloudLocal),
+	)
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = s.wordpress.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: "unit-mysql-0"},
+		{Tag: "unit-wordpress-0"},
+		{Tag: "unit-foo-42"},
+	}}
+	result, err := s.uniter.EndpointAddresses(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result.Results, gc.HasLen, 3)
+	c.Assert(result.Results[0].Error, gc.DeepEquals, apiservertesting.ErrUnauthorized)
+	c.Assert(result.Results[2].Error, gc.DeepEquals, apiservertesting.ErrUnauthorized)
+	c.Assert(result.Results[1].Error, gc.IsNil)
+	addresses := result.Results[1].Addresses
+	c.Assert(addresses["db"], gc.Equals, "10.0.1.4")
+	c.Assert(addresses["url"], gc.Equals, "1.2.3.4")
+	c.Assert(addresses["juju-info"], gc.Equals, "1.2.3.4")
+}
+
 func (s *uniterSuite) TestResolved(c *gc.C) {
 	err := s.wordpressUnit.SetResolved(state.ResolvedRetryHooks)
 	c.Assert(err, gc.IsNil)
@@ -684,6 +716,39 @@ func (s *uniterSuite) TestOpenPort(c *gc.C) {
 	})
 }
 
+func (s *uniterSuite) TestRecordHookExecutions(c *gc.C) {
+	started := time.Date(2014, 9, 1, 12, 0, 0, 0, time.UTC)
+	execution := params.HookExecution{
+		Hook:     "install",
+		Started:  started,
+		Duration: time.Second,
+		Output:   []string{"installed"},
+	}
+	args := params.EntitiesHookExecutions{Entities: []params.EntityHookExecution{
+		{Tag: "unit-mysql-0", Execution: execution},
+		{Tag: "unit-wordpress-0", Execution: execution},
+		{Tag: "unit-foo-42", Execution: execution},
+	}}
+	result, err := s.uniter.RecordHookExecutions(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{apiservertesting.ErrUnauthorized},
+			{nil},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	executions, err := s.wordpressUnit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, jc.DeepEquals, []state.HookExecution{{
+		Hook:     "install",
+		Started:  started,
+		Duration: time.Second,
+		Output:   []string{"installed"},
+	}})
+}
+
 func (s *uniterSuite) TestClosePort(c *gc.C) {
 	// Open port udp:4321 in advance on wordpressUnit.
 	err := s.wordpressUnit.OpenPort("udp", 4321)
@@ -891,6 +956,145 @@ func (s *uniterSuite) TestConfigSettings(c *gc.C) {
 	})
 }
 
+func (s *uniterSuite) TestHookRetryPolicy(c *gc.C) {
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: "unit-mysql-0"},
+		{Tag: "unit-wordpress-0"},
+		{Tag: "unit-foo-42"},
+	}}
+	assertPolicy := func(expected params.HookRetryPolicy) {
+		result, err := s.uniter.HookRetryPolicy(args)
+		c.Assert(err, gc.IsNil)
+		c.Assert(result, gc.DeepEquals, params.HookRetryPolicyResults{
+			Results: []params.HookRetryPolicyResult{
+				{Error: apiservertesting.ErrUnauthorized},
+				{Result: expected},
+				{Error: apiservertesting.ErrUnauthorized},
+			},
+		})
+	}
+
+	// By default, hooks are not retried.
+	assertPolicy(params.HookRetryPolicy{Delay: 10 * time.Second})
+
+	err := s.State.UpdateEnvironConfig(map[string]interface{}{
+		"hook-retry-attempts": 3,
+		"hook-retry-delay":    5,
+	}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	assertPolicy(params.HookRetryPolicy{Attempts: 3, Delay: 5 * time.Second})
+
+	// The service's policy overrides the environment's.
+	err = s.wordpress.SetHookRetryPolicy(&config.HookRetryPolicy{Attempts: 1, Delay: time.Minute})
+	c.Assert(err, gc.IsNil)
+	assertPolicy(params.HookRetryPolicy{Attempts: 1, Delay: time.Minute})
+}
+
+func (s *uniterSuite) TestRollbackCharm(c *gc.C) {
+	err := s.wordpressUnit.SetCharmURL(s.wpCharm.URL())
+	c.Assert(err, gc.IsNil)
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: "unit-mysql-0"},
+		{Tag: "unit-wordpress-0"},
+		{Tag: "unit-foo-42"},
+	}}
+	// Units may only roll back when the environment allows it.
+	result, err := s.uniter.RollbackCharm(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{apiservertesting.ErrUnauthorized},
+			{&params.Error{Message: "automatic charm rollback is disabled"}},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	// And only while their service is being upgraded.
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{
+		"charm-auto-rollback": true,
+	}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	result, err = s.uniter.RollbackCharm(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{apiservertesting.ErrUnauthorized},
+			{&params.Error{Message: `service "wordpress" is not being upgraded`}},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	err = s.wordpress.SetCharm(s.AddTestingCharm(c, "dummy"), false)
+	c.Assert(err, gc.IsNil)
+	// The unit must be running the service's current charm.
+	result, err = s.uniter.RollbackCharm(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{apiservertesting.ErrUnauthorized},
+			{&params.Error{Message: `unit "wordpress/0" is not running the charm of service "wordpress"`}},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	newURL, _ := s.wordpress.CharmURL()
+	err = s.wordpressUnit.SetCharmURL(newURL)
+	c.Assert(err, gc.IsNil)
+	result, err = s.uniter.RollbackCharm(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{apiservertesting.ErrUnauthorized},
+			{nil},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+	err = s.wordpress.Refresh()
+	c.Assert(err, gc.IsNil)
+	curl, force := s.wordpress.CharmURL()
+	c.Assert(curl, gc.DeepEquals, s.wpCharm.URL())
+	c.Assert(force, jc.IsTrue)
+
+	// Once rolled back, the service is no longer being upgraded.
+	err = s.wordpressUnit.SetCharmURL(s.wpCharm.URL())
+	c.Assert(err, gc.IsNil)
+	result, err = s.uniter.RollbackCharm(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result.Results[1].Error, gc.DeepEquals, &params.Error{
+		Message: `service "wordpress" is not being upgraded`,
+	})
+}
+
+func (s *uniterSuite) TestCharmUpgradeHeld(c *gc.C) {
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: "unit-mysql-0"},
+		{Tag: "unit-wordpress-0"},
+		{Tag: "unit-foo-42"},
+	}}
+	result, err := s.uniter.CharmUpgradeHeld(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.BoolResults{
+		Results: []params.BoolResult{
+			{Error: apiservertesting.ErrUnauthorized},
+			{Result: false},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	err = s.wordpress.SetCharmHoldingUnits(s.AddTestingCharm(c, "dummy"), false, []string{"wordpress/0"})
+	c.Assert(err, gc.IsNil)
+	result, err = s.uniter.CharmUpgradeHeld(args)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result, gc.DeepEquals, params.BoolResults{
+		Results: []params.BoolResult{
+			{Error: apiservertesting.ErrUnauthorized},
+			{Result: true},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+}
+
 func (s *uniterSuite) TestWatchServiceRelations(c *gc.C) {
 	c.Assert(s.resources.Count(), gc.Equals, 0)
 
@@ -206,7 +206,7 @@ func (st *State) cleanupRemovedUnit(unitId string) error {
 			return err
 		}
 	}
-	return nil
+	return st.removeHookExecutions(unitId)
 }
 
 // cleanupForceDestroyedMachine systematically destroys and removes all entities
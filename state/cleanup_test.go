@@ -5,6 +5,7 @@ package state_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/juju/charm"
 	"github.com/juju/errors"
@@ -349,6 +350,30 @@ func (s *CleanupSuite) TestCleanupActions(c *gc.C) {
 	s.assertDoesNotNeedCleanup(c)
 }
 
+func (s *CleanupSuite) TestCleanupHookExecutions(c *gc.C) {
+	s.assertDoesNotNeedCleanup(c)
+
+	mysql := s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	unit, err := mysql.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = unit.AddHookExecution(state.HookExecution{Hook: "install", Started: time.Now()})
+	c.Assert(err, gc.IsNil)
+
+	// Remove the unit; its hook executions are removed by the cleanup.
+	err = unit.EnsureDead()
+	c.Assert(err, gc.IsNil)
+	err = unit.Remove()
+	c.Assert(err, gc.IsNil)
+	executions, err := unit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, gc.HasLen, 1)
+	s.assertCleanupRuns(c)
+	executions, err = unit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, gc.HasLen, 0)
+	s.assertDoesNotNeedCleanup(c)
+}
+
 func (s *CleanupSuite) TestNothingToCleanup(c *gc.C) {
 	s.assertDoesNotNeedCleanup(c)
 	s.assertCleanupRuns(c)
@@ -78,7 +78,7 @@ func (s *compatSuite) TestGetServiceWithoutNetworksIsOK(c *gc.C) {
 	_, err := s.state.AddAdminUser("pass")
 	c.Assert(err, gc.IsNil)
 	charm := addCharm(c, s.state, "quantal", charmtesting.Charms.Dir("mysql"))
-	service, err := s.state.AddService("mysql", "user-admin", charm, nil)
+	service, err := s.state.AddService("mysql", "user-admin", charm, nil, nil)
 	c.Assert(err, gc.IsNil)
 	// In 1.17.7+ all services have associated document in the
 	// requested networks collection. We remove it here to test
@@ -114,7 +114,7 @@ func (s *compatSuite) TestShowUnitPorts(c *gc.C) {
 	_, err := s.state.AddAdminUser("pass")
 	c.Assert(err, gc.IsNil)
 	charm := addCharm(c, s.state, "quantal", charmtesting.Charms.Dir("mysql"))
-	service, err := s.state.AddService("mysql", "user-admin", charm, nil)
+	service, err := s.state.AddService("mysql", "user-admin", charm, nil, nil)
 	c.Assert(err, gc.IsNil)
 	unit, err := service.AddUnit()
 	c.Assert(err, gc.IsNil)
@@ -144,7 +144,7 @@ func (s *compatSuite) TestMigratePortsOnOpen(c *gc.C) {
 	_, err := s.state.AddAdminUser("pass")
 	c.Assert(err, gc.IsNil)
 	charm := addCharm(c, s.state, "quantal", charmtesting.Charms.Dir("mysql"))
-	service, err := s.state.AddService("mysql", "user-admin", charm, nil)
+	service, err := s.state.AddService("mysql", "user-admin", charm, nil, nil)
 	c.Assert(err, gc.IsNil)
 	unit, err := service.AddUnit()
 	c.Assert(err, gc.IsNil)
@@ -181,7 +181,7 @@ func (s *compatSuite) TestMigratePortsOnClose(c *gc.C) {
 	_, err := s.state.AddAdminUser("pass")
 	c.Assert(err, gc.IsNil)
 	charm := addCharm(c, s.state, "quantal", charmtesting.Charms.Dir("mysql"))
-	service, err := s.state.AddService("mysql", "user-admin", charm, nil)
+	service, err := s.state.AddService("mysql", "user-admin", charm, nil, nil)
 	c.Assert(err, gc.IsNil)
 	unit, err := service.AddUnit()
 	c.Assert(err, gc.IsNil)
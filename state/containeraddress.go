@@ -0,0 +1,127 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+)
+
+// ContainerAddress describes an address the provider allocated for a
+// container on the network of the container's host, which must be
+// released when the container is removed.
+type ContainerAddress struct {
+	// Address is the allocated address.
+	Address network.Address
+
+	// MachineId is the id of the container's machine.
+	MachineId string
+
+	// HostInstanceId is the instance the address was allocated on.
+	HostInstanceId instance.Id
+
+	// NetworkId is the provider id of the network the address is on.
+	NetworkId network.Id
+}
+
+// containerAddressDoc records an allocated address, keyed by its value.
+type containerAddressDoc struct {
+	Value          string `bson:"_id"`
+	Type           network.AddressType
+	NetworkName    string        `bson:",omitempty"`
+	Scope          network.Scope `bson:",omitempty"`
+	MachineId      string
+	HostInstanceId instance.Id
+	NetworkId      network.Id
+}
+
+func (doc *containerAddressDoc) containerAddress() ContainerAddress {
+	return ContainerAddress{
+		Address: network.Address{
+			Value:       doc.Value,
+			Type:        doc.Type,
+			NetworkName: doc.NetworkName,
+			Scope:       doc.Scope,
+		},
+		MachineId:      doc.MachineId,
+		HostInstanceId: doc.HostInstanceId,
+		NetworkId:      doc.NetworkId,
+	}
+}
+
+// AddContainerAddress records an address the provider allocated for
+// the machine, which must be a live container, on the given network
+// of its host's instance.
+func (m *Machine) AddContainerAddress(addr network.Address, hostId instance.Id, netId network.Id) (err error) {
+	defer errors.Maskf(&err, "cannot add address %q to machine %s", addr.Value, m.doc.Id)
+	if _, ok := m.ParentId(); !ok {
+		return fmt.Errorf("machine is not a container")
+	}
+	ops := []txn.Op{{
+		C:      machinesC,
+		Id:     m.doc.Id,
+		Assert: isAliveDoc,
+	}, {
+		C:      ipAddressesC,
+		Id:     addr.Value,
+		Assert: txn.DocMissing,
+		Insert: &containerAddressDoc{
+			Value:          addr.Value,
+			Type:           addr.Type,
+			NetworkName:    addr.NetworkName,
+			Scope:          addr.Scope,
+			MachineId:      m.doc.Id,
+			HostInstanceId: hostId,
+			NetworkId:      netId,
+		},
+	}}
+	if err := m.st.runTransaction(ops); err != txn.ErrAborted {
+		return err
+	}
+	if err := m.Refresh(); err != nil {
+		return err
+	}
+	if m.doc.Life != Alive {
+		return fmt.Errorf("machine is not alive")
+	}
+	return fmt.Errorf("address already allocated")
+}
+
+// ContainerAddresses returns the addresses the provider allocated for
+// the machine.
+func (m *Machine) ContainerAddresses() ([]ContainerAddress, error) {
+	ipAddresses, closer := m.st.getCollection(ipAddressesC)
+	defer closer()
+
+	var docs []containerAddressDoc
+	err := ipAddresses.Find(bson.D{{"machineid", m.doc.Id}}).Sort("_id").All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get addresses of machine %s: %v", m.doc.Id, err)
+	}
+	addresses := make([]ContainerAddress, len(docs))
+	for i := range docs {
+		addresses[i] = docs[i].containerAddress()
+	}
+	return addresses, nil
+}
+
+// RemoveContainerAddress forgets an allocated address, once the
+// provider has released it.
+func (st *State) RemoveContainerAddress(value string) error {
+	ops := []txn.Op{{
+		C:      ipAddressesC,
+		Id:     value,
+		Remove: true,
+	}}
+	if err := st.runTransaction(ops); err != nil {
+		return fmt.Errorf("cannot remove address %q: %v", value, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+)
+
+type ContainerAddressSuite struct {
+	ConnSuite
+	container *state.Machine
+}
+
+var _ = gc.Suite(&ContainerAddressSuite{})
+
+func (s *ContainerAddressSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	host, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	s.container, err = s.State.AddMachineInsideMachine(template, host.Id(), instance.LXC)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ContainerAddressSuite) TestAddContainerAddress(c *gc.C) {
+	addr0 := network.NewAddress("0.1.2.3", network.ScopeCloudLocal)
+	addr1 := network.NewAddress("0.1.2.4", network.ScopeCloudLocal)
+	err := s.container.AddContainerAddress(addr0, "i-host", "net1")
+	c.Assert(err, gc.IsNil)
+	err = s.container.AddContainerAddress(addr1, "i-host", "net1")
+	c.Assert(err, gc.IsNil)
+
+	addresses, err := s.container.ContainerAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses, gc.DeepEquals, []state.ContainerAddress{{
+		Address:        addr0,
+		MachineId:      s.container.Id(),
+		HostInstanceId: "i-host",
+		NetworkId:      "net1",
+	}, {
+		Address:        addr1,
+		MachineId:      s.container.Id(),
+		HostInstanceId: "i-host",
+		NetworkId:      "net1",
+	}})
+
+	err = s.State.RemoveContainerAddress(addr0.Value)
+	c.Assert(err, gc.IsNil)
+	// Removing an address again has no effect.
+	err = s.State.RemoveContainerAddress(addr0.Value)
+	c.Assert(err, gc.IsNil)
+	addresses, err = s.container.ContainerAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses, gc.HasLen, 1)
+	c.Assert(addresses[0].Address, gc.DeepEquals, addr1)
+}
+
+func (s *ContainerAddressSuite) TestAddContainerAddressTwice(c *gc.C) {
+	addr := network.NewAddress("0.1.2.3", network.ScopeCloudLocal)
+	err := s.container.AddContainerAddress(addr, "i-host", "net1")
+	c.Assert(err, gc.IsNil)
+	err = s.container.AddContainerAddress(addr, "i-host", "net1")
+	c.Assert(err, gc.ErrorMatches, `cannot add address "0.1.2.3" to machine 0/lxc/0: address already allocated`)
+}
+
+func (s *ContainerAddressSuite) TestAddContainerAddressDeadMachine(c *gc.C) {
+	err := s.container.EnsureDead()
+	c.Assert(err, gc.IsNil)
+	addr := network.NewAddress("0.1.2.3", network.ScopeCloudLocal)
+	err = s.container.AddContainerAddress(addr, "i-host", "net1")
+	c.Assert(err, gc.ErrorMatches, `cannot add address "0.1.2.3" to machine 0/lxc/0: machine is not alive`)
+}
+
+func (s *ContainerAddressSuite) TestAddContainerAddressNotContainer(c *gc.C) {
+	host, err := s.State.Machine("0")
+	c.Assert(err, gc.IsNil)
+	addr := network.NewAddress("0.1.2.3", network.ScopeCloudLocal)
+	err = host.AddContainerAddress(addr, "i-host", "net1")
+	c.Assert(err, gc.ErrorMatches, `cannot add address "0.1.2.3" to machine 0: machine is not a container`)
+}
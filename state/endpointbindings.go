@@ -0,0 +1,195 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+// EndpointBindings returns the names of the spaces the service's
+// endpoints are bound to, keyed by endpoint name. Endpoints that are
+// not bound to a space are not included.
+func (s *Service) EndpointBindings() map[string]string {
+	bindings := make(map[string]string)
+	for endpoint, space := range s.doc.EndpointBindings {
+		bindings[endpoint] = space
+	}
+	return bindings
+}
+
+// SetEndpointBindings binds the service's endpoints to spaces,
+// replacing any existing bindings. The map holds space names keyed by
+// endpoint name; every endpoint and space must exist.
+func (s *Service) SetEndpointBindings(bindings map[string]string) (err error) {
+	defer errors.Maskf(&err, "cannot set endpoint bindings for service %q", s)
+	var update bson.D
+	if len(bindings) > 0 {
+		update = bson.D{{"$set", bson.D{{"endpointbindings", bindings}}}}
+	} else {
+		update = bson.D{{"$unset", bson.D{{"endpointbindings", nil}}}}
+	}
+	svc := &Service{st: s.st, doc: s.doc}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := svc.Refresh(); errors.IsNotFound(err) {
+				return nil, errNotAlive
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if svc.doc.Life != Alive {
+			return nil, errNotAlive
+		}
+		ops, err := svc.endpointBindingsOps(bindings)
+		if err != nil {
+			return nil, err
+		}
+		return append(ops, txn.Op{
+			C:      servicesC,
+			Id:     svc.doc.Name,
+			Assert: isAliveDoc,
+			Update: update,
+		}), nil
+	}
+	if err := s.st.run(buildTxn); err != nil {
+		return err
+	}
+	s.doc.EndpointBindings = nil
+	if len(bindings) > 0 {
+		s.doc.EndpointBindings = make(map[string]string)
+		for endpoint, space := range bindings {
+			s.doc.EndpointBindings[endpoint] = space
+		}
+	}
+	return nil
+}
+
+// endpointBindingsOps checks that the service has every endpoint and
+// that every space in bindings exists, and returns the operations that
+// assert the spaces still exist when the bindings are written.
+func (s *Service) endpointBindingsOps(bindings map[string]string) ([]txn.Op, error) {
+	var spaceNames []string
+	seen := make(map[string]bool)
+	for endpoint, spaceName := range bindings {
+		if _, err := s.Endpoint(endpoint); err != nil {
+			return nil, err
+		}
+		if _, err := s.st.Space(spaceName); err != nil {
+			return nil, err
+		}
+		if !seen[spaceName] {
+			seen[spaceName] = true
+			spaceNames = append(spaceNames, spaceName)
+		}
+	}
+	sort.Strings(spaceNames)
+	ops := make([]txn.Op, len(spaceNames))
+	for i, spaceName := range spaceNames {
+		ops[i] = txn.Op{
+			C:      spacesC,
+			Id:     spaceName,
+			Assert: txn.DocExists,
+		}
+	}
+	return ops, nil
+}
+
+// IngressCIDRs returns the source CIDRs from which the opened ports
+// of the service may be accessed when it is exposed. These are the
+// CIDRs it was exposed to, if any, or else the subnets of the spaces
+// its endpoints are bound to. An empty result means that the ports may
+// be accessed from any address.
+func (s *Service) IngressCIDRs() ([]string, error) {
+	if len(s.doc.ExposedCIDRs) > 0 {
+		return s.ExposedCIDRs(), nil
+	}
+	seen := make(map[string]bool)
+	var cidrs []string
+	for _, spaceName := range s.doc.EndpointBindings {
+		space, err := s.st.Space(spaceName)
+		if err != nil {
+			return nil, err
+		}
+		for _, cidr := range space.Subnets() {
+			if !seen[cidr] {
+				seen[cidr] = true
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	sort.Strings(cidrs)
+	return cidrs, nil
+}
+
+// EndpointAddress returns the address of the unit to be used by the
+// named endpoint of its service. This is the unit's address in the
+// space the endpoint is bound to or, if the endpoint is not bound,
+// its private address. An error satisfying errors.IsNotFound is
+// returned if the unit has no such address.
+func (u *Unit) EndpointAddress(endpoint string) (string, error) {
+	svc, err := u.Service()
+	if err != nil {
+		return "", err
+	}
+	if _, err := svc.Endpoint(endpoint); err != nil {
+		return "", errors.NotFoundf("endpoint %q", endpoint)
+	}
+	return u.endpointAddress(svc, endpoint)
+}
+
+func (u *Unit) endpointAddress(svc *Service, endpoint string) (string, error) {
+	spaceName, bound := svc.doc.EndpointBindings[endpoint]
+	if !bound {
+		if addr, ok := u.PrivateAddress(); ok {
+			return addr, nil
+		}
+		return "", errors.NotFoundf("private address of unit %q", u)
+	}
+	space, err := u.st.Space(spaceName)
+	if err != nil {
+		return "", err
+	}
+	var addresses []network.Address
+	for _, addr := range u.addressesOfMachine() {
+		if space.Contains(addr) {
+			addresses = append(addresses, addr)
+		}
+	}
+	if addr := network.SelectInternalAddress(addresses, false); addr != "" {
+		return addr, nil
+	}
+	return "", errors.NotFoundf("address of unit %q in space %q", u, spaceName)
+}
+
+// EndpointAddresses returns the addresses of the unit to be used by
+// each of its service's endpoints, keyed by endpoint name. Endpoints
+// for which the unit has no address are omitted. See EndpointAddress.
+func (u *Unit) EndpointAddresses() (map[string]string, error) {
+	svc, err := u.Service()
+	if err != nil {
+		return nil, err
+	}
+	eps, err := svc.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make(map[string]string)
+	for _, ep := range eps {
+		addr, err := u.endpointAddress(svc, ep.Name)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot get address for endpoint %q: %v", ep.Name, err)
+		}
+		addresses[ep.Name] = addr
+	}
+	return addresses, nil
+}
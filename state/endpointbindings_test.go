@@ -0,0 +1,229 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+)
+
+type EndpointBindingsSuite struct {
+	ConnSuite
+	wordpress *state.Service
+}
+
+var _ = gc.Suite(&EndpointBindingsSuite{})
+
+func (s *EndpointBindingsSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	s.wordpress = s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	addSubnets(c, s.State, "10.0.1.0/24", "192.168.1.0/24", "192.168.2.0/24")
+	_, err := s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("public", []string{"192.168.1.0/24", "192.168.2.0/24"})
+	c.Assert(err, gc.IsNil)
+}
+
+// addUnit adds a unit of wordpress on a machine with the given
+// addresses.
+func (s *EndpointBindingsSuite) addUnit(c *gc.C, addresses ...string) *state.Unit {
+	unit, err := s.wordpress.AddUnit()
+	c.Assert(err, gc.IsNil)
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = unit.AssignToMachine(machine)
+	c.Assert(err, gc.IsNil)
+	var addrs []network.Address
+	for _, addr := range addresses {
+		addrs = append(addrs, network.NewAddress(addr, network.ScopeCloudLocal))
+	}
+	err = machine.SetAddresses(addrs...)
+	c.Assert(err, gc.IsNil)
+	return unit
+}
+
+func (s *EndpointBindingsSuite) TestSetEndpointBindings(c *gc.C) {
+	c.Assert(s.wordpress.EndpointBindings(), gc.HasLen, 0)
+
+	bindings := map[string]string{"db": "internal", "url": "public"}
+	err := s.wordpress.SetEndpointBindings(bindings)
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.wordpress.EndpointBindings(), jc.DeepEquals, bindings)
+	err = s.wordpress.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.wordpress.EndpointBindings(), jc.DeepEquals, bindings)
+
+	err = s.wordpress.SetEndpointBindings(nil)
+	c.Assert(err, gc.IsNil)
+	err = s.wordpress.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.wordpress.EndpointBindings(), gc.HasLen, 0)
+}
+
+func (s *EndpointBindingsSuite) TestSetEndpointBindingsInvalid(c *gc.C) {
+	err := s.wordpress.SetEndpointBindings(map[string]string{"foo": "internal"})
+	c.Assert(err, gc.ErrorMatches, `cannot set endpoint bindings for service "wordpress": service "wordpress" has no "foo" relation`)
+	err = s.wordpress.SetEndpointBindings(map[string]string{"db": "storage"})
+	c.Assert(err, gc.ErrorMatches, `cannot set endpoint bindings for service "wordpress": space "storage" not found`)
+	c.Assert(s.wordpress.EndpointBindings(), gc.HasLen, 0)
+}
+
+// removeSpace removes the named space directly from the database, as
+// state does not yet support removing spaces.
+func (s *EndpointBindingsSuite) removeSpace(c *gc.C, name string) {
+	err := s.MgoSuite.Session.DB("juju").C("spaces").RemoveId(name)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *EndpointBindingsSuite) TestSetEndpointBindingsSpaceRemoved(c *gc.C) {
+	defer state.SetBeforeHooks(c, s.State, func() {
+		s.removeSpace(c, "internal")
+	}).Check()
+
+	err := s.wordpress.SetEndpointBindings(map[string]string{"db": "internal", "url": "public"})
+	c.Assert(err, gc.ErrorMatches, `cannot set endpoint bindings for service "wordpress": space "internal" not found`)
+	err = s.wordpress.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.wordpress.EndpointBindings(), gc.HasLen, 0)
+}
+
+func (s *EndpointBindingsSuite) TestAddServiceWithBindings(c *gc.C) {
+	ch, _, err := s.wordpress.Charm()
+	c.Assert(err, gc.IsNil)
+	bindings := map[string]string{"db": "internal", "url": "public"}
+	blog, err := s.State.AddService("blog", "user-admin", ch, nil, bindings)
+	c.Assert(err, gc.IsNil)
+	c.Assert(blog.EndpointBindings(), jc.DeepEquals, bindings)
+	blog, err = s.State.Service("blog")
+	c.Assert(err, gc.IsNil)
+	c.Assert(blog.EndpointBindings(), jc.DeepEquals, bindings)
+}
+
+func (s *EndpointBindingsSuite) TestAddServiceWithBindingsInvalid(c *gc.C) {
+	ch, _, err := s.wordpress.Charm()
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddService("blog", "user-admin", ch, nil, map[string]string{"foo": "internal"})
+	c.Assert(err, gc.ErrorMatches, `cannot add service "blog": service "blog" has no "foo" relation`)
+	_, err = s.State.AddService("blog", "user-admin", ch, nil, map[string]string{"db": "storage"})
+	c.Assert(err, gc.ErrorMatches, `cannot add service "blog": space "storage" not found`)
+	_, err = s.State.Service("blog")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *EndpointBindingsSuite) TestAddServiceWithBindingsSpaceRemoved(c *gc.C) {
+	ch, _, err := s.wordpress.Charm()
+	c.Assert(err, gc.IsNil)
+	defer state.SetBeforeHooks(c, s.State, func() {
+		s.removeSpace(c, "internal")
+	}).Check()
+
+	_, err = s.State.AddService("blog", "user-admin", ch, nil, map[string]string{"db": "internal"})
+	c.Assert(err, gc.ErrorMatches, `cannot add service "blog": space "internal" not found`)
+	_, err = s.State.Service("blog")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *EndpointBindingsSuite) TestIngressCIDRs(c *gc.C) {
+	cidrs, err := s.wordpress.IngressCIDRs()
+	c.Assert(err, gc.IsNil)
+	c.Assert(cidrs, gc.HasLen, 0)
+
+	err = s.wordpress.SetEndpointBindings(map[string]string{"db": "internal", "url": "public"})
+	c.Assert(err, gc.IsNil)
+	cidrs, err = s.wordpress.IngressCIDRs()
+	c.Assert(err, gc.IsNil)
+	c.Assert(cidrs, jc.DeepEquals, []string{"10.0.1.0/24", "192.168.1.0/24", "192.168.2.0/24"})
+
+	// Explicitly exposed CIDRs take precedence.
+	err = s.wordpress.SetExposedFrom([]string{"172.16.0.0/12"})
+	c.Assert(err, gc.IsNil)
+	cidrs, err = s.wordpress.IngressCIDRs()
+	c.Assert(err, gc.IsNil)
+	c.Assert(cidrs, jc.DeepEquals, []string{"172.16.0.0/12"})
+}
+
+func (s *EndpointBindingsSuite) TestEndpointAddress(c *gc.C) {
+	unit := s.addUnit(c, "192.168.1.5", "10.0.1.5")
+	err := s.wordpress.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+
+	addr, err := unit.EndpointAddress("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(addr, gc.Equals, "10.0.1.5")
+
+	private, ok := unit.PrivateAddress()
+	c.Assert(ok, jc.IsTrue)
+	addr, err = unit.EndpointAddress("url")
+	c.Assert(err, gc.IsNil)
+	c.Assert(addr, gc.Equals, private)
+
+	_, err = unit.EndpointAddress("foo")
+	c.Assert(err, gc.ErrorMatches, `endpoint "foo" not found`)
+
+	addresses, err := unit.EndpointAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses["db"], gc.Equals, "10.0.1.5")
+	c.Assert(addresses["url"], gc.Equals, private)
+	c.Assert(addresses["juju-info"], gc.Equals, private)
+}
+
+func (s *EndpointBindingsSuite) TestEndpointAddressNotInSpace(c *gc.C) {
+	unit := s.addUnit(c, "192.168.1.5")
+	err := s.wordpress.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+
+	_, err = unit.EndpointAddress("db")
+	c.Assert(err, gc.ErrorMatches, `address of unit "wordpress/0" in space "internal" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	addresses, err := unit.EndpointAddresses()
+	c.Assert(err, gc.IsNil)
+	_, found := addresses["db"]
+	c.Assert(found, jc.IsFalse)
+}
+
+func (s *EndpointBindingsSuite) TestRelationUnitPrivateAddress(c *gc.C) {
+	unit := s.addUnit(c, "192.168.1.5", "10.0.1.5")
+	err := s.wordpress.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+	s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	eps, err := s.State.InferEndpoints([]string{"wordpress", "mysql"})
+	c.Assert(err, gc.IsNil)
+	rel, err := s.State.AddRelation(eps...)
+	c.Assert(err, gc.IsNil)
+	ru, err := rel.Unit(unit)
+	c.Assert(err, gc.IsNil)
+
+	addr, ok := ru.PrivateAddress()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(addr, gc.Equals, "10.0.1.5")
+}
+
+func (s *EndpointBindingsSuite) TestIngressRules(c *gc.C) {
+	unit := s.addUnit(c, "10.0.1.5")
+	err := s.wordpress.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+	err = s.wordpress.SetExposed()
+	c.Assert(err, gc.IsNil)
+	err = unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	machineId, err := unit.AssignedMachineId()
+	c.Assert(err, gc.IsNil)
+	machine, err := s.State.Machine(machineId)
+	c.Assert(err, gc.IsNil)
+
+	rules, _, err := machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{{
+		Port:       network.Port{Protocol: "tcp", Number: 80},
+		SourceCIDR: "10.0.1.0/24",
+	}, {
+		Port:       network.Port{Protocol: "tcp", Number: 80},
+		SourceCIDR: "10.0.1.5/32",
+	}})
+}
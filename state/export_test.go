@@ -89,7 +89,7 @@ func AddTestingService(c *gc.C, st *State, name string, ch *Charm) *Service {
 }
 
 func AddTestingServiceWithNetworks(c *gc.C, st *State, name string, ch *Charm, networks []string) *Service {
-	service, err := st.AddService(name, "user-admin", ch, networks)
+	service, err := st.AddService(name, "user-admin", ch, networks, nil)
 	c.Assert(err, gc.IsNil)
 	return service
 }
@@ -225,6 +225,12 @@ func CheckUserExists(st *State, name string) (bool, error) {
 
 var StateServerAvailable = &stateServerAvailable
 
+var MaxHookExecutions = &maxHookExecutions
+
+var MaxStatusHistory = &maxStatusHistory
+
+var MaxRunJobs = &maxRunJobs
+
 func EnsureActionMarker(prefix string) string {
 	return ensureActionMarker(prefix)
 }
@@ -0,0 +1,160 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// maxHookExecutions is the number of hook executions kept for each
+// unit; older ones are discarded as new ones are recorded.
+var maxHookExecutions = 50
+
+// HookExecution describes a single run of a hook by a unit.
+type HookExecution struct {
+	// Hook is the name of the hook that was run.
+	Hook string
+
+	// Relation identifies the relation the hook was run for,
+	// if it is a relation hook.
+	Relation string
+
+	// RemoteUnit is the name of the remote unit the hook was run
+	// for, if any.
+	RemoteUnit string
+
+	// Started is the time at which the hook was started.
+	Started time.Time
+
+	// Duration is the time the hook took to run.
+	Duration time.Duration
+
+	// ExitCode is the exit code of the hook's process.
+	ExitCode int
+
+	// Output holds the last lines output by the hook.
+	Output []string
+}
+
+// hookExecutionDoc records a HookExecution for a unit.
+type hookExecutionDoc struct {
+	Id         bson.ObjectId `bson:"_id"`
+	Unit       string
+	Hook       string
+	Relation   string `bson:",omitempty"`
+	RemoteUnit string `bson:",omitempty"`
+	Started    time.Time
+	Duration   time.Duration
+	ExitCode   int
+	Output     []string `bson:",omitempty"`
+}
+
+func (doc *hookExecutionDoc) execution() HookExecution {
+	return HookExecution{
+		Hook:       doc.Hook,
+		Relation:   doc.Relation,
+		RemoteUnit: doc.RemoteUnit,
+		Started:    doc.Started.UTC(),
+		Duration:   doc.Duration,
+		ExitCode:   doc.ExitCode,
+		Output:     doc.Output,
+	}
+}
+
+// AddHookExecution records a run of a hook by the unit. Only the
+// most recent executions are kept.
+func (u *Unit) AddHookExecution(execution HookExecution) error {
+	if execution.Hook == "" {
+		return fmt.Errorf("cannot record hook execution for unit %q: missing hook name", u)
+	}
+	doc := &hookExecutionDoc{
+		Id:         bson.NewObjectId(),
+		Unit:       u.doc.Name,
+		Hook:       execution.Hook,
+		Relation:   execution.Relation,
+		RemoteUnit: execution.RemoteUnit,
+		Started:    execution.Started.UTC(),
+		Duration:   execution.Duration,
+		ExitCode:   execution.ExitCode,
+		Output:     execution.Output,
+	}
+	ops := []txn.Op{{
+		C:      hookExecutionsC,
+		Id:     doc.Id,
+		Assert: txn.DocMissing,
+		Insert: doc,
+	}}
+	if err := u.st.runTransaction(ops); err != nil {
+		return fmt.Errorf("cannot record hook execution for unit %q: %v", u, err)
+	}
+	return u.st.pruneHookExecutions(u.doc.Name)
+}
+
+// HookExecutions returns the recorded hook executions of the unit,
+// oldest first.
+func (u *Unit) HookExecutions() ([]HookExecution, error) {
+	hookExecutions, closer := u.st.getCollection(hookExecutionsC)
+	defer closer()
+
+	var docs []hookExecutionDoc
+	err := hookExecutions.Find(bson.D{{"unit", u.doc.Name}}).Sort("started", "_id").All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get hook executions for unit %q: %v", u, err)
+	}
+	executions := make([]HookExecution, len(docs))
+	for i, doc := range docs {
+		executions[i] = doc.execution()
+	}
+	return executions, nil
+}
+
+// pruneHookExecutions removes all but the most recent hook executions
+// recorded for the named unit.
+func (st *State) pruneHookExecutions(unitName string) error {
+	hookExecutions, closer := st.getCollection(hookExecutionsC)
+	defer closer()
+
+	// Hook execution documents are not otherwise referenced in the
+	// system, and are not under watch, and are therefore safe to
+	// delete directly.
+	var docs []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}
+	err := hookExecutions.Find(bson.D{{"unit", unitName}}).
+		Sort("-started", "-_id").
+		Skip(maxHookExecutions).
+		Select(bson.D{{"_id", 1}}).
+		All(&docs)
+	if err != nil {
+		return fmt.Errorf("cannot prune hook executions for unit %q: %v", unitName, err)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	ids := make([]bson.ObjectId, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Id
+	}
+	sel := bson.D{{"_id", bson.D{{"$in", ids}}}}
+	if _, err := hookExecutions.RemoveAll(sel); err != nil {
+		return fmt.Errorf("cannot prune hook executions for unit %q: %v", unitName, err)
+	}
+	return nil
+}
+
+// removeHookExecutions removes all the hook executions recorded for
+// the named unit.
+func (st *State) removeHookExecutions(unitName string) error {
+	hookExecutions, closer := st.getCollection(hookExecutionsC)
+	defer closer()
+
+	if _, err := hookExecutions.RemoveAll(bson.D{{"unit", unitName}}); err != nil {
+		return fmt.Errorf("cannot remove hook executions for unit %q: %v", unitName, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/state"
+)
+
+type HookExecutionSuite struct {
+	ConnSuite
+	unit *state.Unit
+}
+
+var _ = gc.Suite(&HookExecutionSuite{})
+
+func (s *HookExecutionSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	service := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	var err error
+	s.unit, err = service.AddUnit()
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *HookExecutionSuite) TestAddHookExecution(c *gc.C) {
+	executions, err := s.unit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, gc.HasLen, 0)
+
+	started := time.Date(2014, 9, 1, 12, 0, 0, 0, time.UTC)
+	first := state.HookExecution{
+		Hook:     "install",
+		Started:  started,
+		Duration: 3 * time.Second,
+		Output:   []string{"installing", "done"},
+	}
+	second := state.HookExecution{
+		Hook:       "db-relation-changed",
+		Relation:   "wordpress:db mysql:server",
+		RemoteUnit: "mysql/0",
+		Started:    started.Add(time.Minute),
+		Duration:   time.Second,
+		ExitCode:   1,
+	}
+	// Executions are listed in the order they were started.
+	err = s.unit.AddHookExecution(second)
+	c.Assert(err, gc.IsNil)
+	err = s.unit.AddHookExecution(first)
+	c.Assert(err, gc.IsNil)
+
+	executions, err = s.unit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, jc.DeepEquals, []state.HookExecution{first, second})
+}
+
+func (s *HookExecutionSuite) TestAddHookExecutionMissingHook(c *gc.C) {
+	err := s.unit.AddHookExecution(state.HookExecution{Started: time.Now()})
+	c.Assert(err, gc.ErrorMatches, `cannot record hook execution for unit "wordpress/0": missing hook name`)
+}
+
+func (s *HookExecutionSuite) TestAddHookExecutionPrunes(c *gc.C) {
+	s.PatchValue(state.MaxHookExecutions, 2)
+	started := time.Date(2014, 9, 1, 12, 0, 0, 0, time.UTC)
+	for i, hook := range []string{"install", "config-changed", "start"} {
+		err := s.unit.AddHookExecution(state.HookExecution{
+			Hook:    hook,
+			Started: started.Add(time.Duration(i) * time.Minute),
+		})
+		c.Assert(err, gc.IsNil)
+	}
+	executions, err := s.unit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, gc.HasLen, 2)
+	c.Assert(executions[0].Hook, gc.Equals, "config-changed")
+	c.Assert(executions[1].Hook, gc.Equals, "start")
+}
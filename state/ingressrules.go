@@ -0,0 +1,131 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/network"
+)
+
+// IngressRules returns the rules a host-based firewall on the machine
+// should apply, and the ports they apply to. Ports opened by units of
+// exposed services may be accessed from their services' ingress CIDRs,
+// or from any address if the service has none; see
+// Service.IngressCIDRs. Traffic to the returned ports from any other
+// source should be dropped.
+//
+// Every opened port, whether or not its service is exposed and
+// whatever CIDRs it is exposed to, may also be accessed from every
+// address of every machine in the environment, other than machine-local
+// and link-local ones. This includes public addresses, and machines
+// hosting unrelated services. It is needed because relations are not
+// tied to ports, so any unit may need to reach any other.
+func (m *Machine) IngressRules() (rules []network.IngressRule, ports []network.Port, err error) {
+	defer errors.Maskf(&err, "cannot get ingress rules for machine %v", m)
+	sourceCIDRs, err := m.st.machineSourceCIDRs()
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, ports, _, err = m.ingressRules(sourceCIDRs)
+	return rules, ports, err
+}
+
+// ingressRules returns the machine's ingress rules and the ports they
+// apply to, given the source CIDRs of the environment's machines. It
+// also returns the names of the machine's units and their services,
+// which the rules depend on.
+func (m *Machine) ingressRules(sourceCIDRs []string) (
+	rules []network.IngressRule, ports []network.Port, deps map[string]bool, err error,
+) {
+	units, err := m.Units()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	deps = make(map[string]bool)
+	services := make(map[string]*Service)
+	seen := make(map[network.IngressRule]bool)
+	add := func(newRules []network.IngressRule) {
+		for _, rule := range newRules {
+			if !seen[rule] {
+				seen[rule] = true
+				rules = append(rules, rule)
+			}
+		}
+	}
+	seenPorts := make(map[network.Port]bool)
+	for _, u := range units {
+		deps[u.Name()] = true
+		deps[u.ServiceName()] = true
+		unitPorts := u.OpenedPorts()
+		if len(unitPorts) == 0 {
+			continue
+		}
+		for _, port := range unitPorts {
+			if !seenPorts[port] {
+				seenPorts[port] = true
+				ports = append(ports, port)
+			}
+		}
+		svc, ok := services[u.ServiceName()]
+		if !ok {
+			if svc, err = u.Service(); errors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return nil, nil, nil, err
+			}
+			services[u.ServiceName()] = svc
+		}
+		if svc.IsExposed() {
+			cidrs, err := svc.IngressCIDRs()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			add(network.IngressRulesForPorts(unitPorts, cidrs))
+		}
+		if len(sourceCIDRs) > 0 {
+			add(network.IngressRulesForPorts(unitPorts, sourceCIDRs))
+		}
+	}
+	network.SortIngressRules(rules)
+	network.SortPorts(ports)
+	return rules, ports, deps, nil
+}
+
+// machineSourceCIDRs returns single-address CIDRs for all the IP
+// addresses of the environment's machines.
+func (st *State) machineSourceCIDRs() ([]string, error) {
+	machines, err := st.AllMachines()
+	if err != nil {
+		return nil, err
+	}
+	var cidrs []string
+	seen := make(map[string]bool)
+	for _, m := range machines {
+		for _, cidr := range addressSourceCIDRs(m.Addresses()) {
+			if !seen[cidr] {
+				seen[cidr] = true
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	return cidrs, nil
+}
+
+// addressSourceCIDRs returns single-address CIDRs for the given IP
+// addresses, leaving out machine-local and link-local ones.
+func addressSourceCIDRs(addrs []network.Address) []string {
+	var cidrs []string
+	for _, addr := range addrs {
+		switch {
+		case addr.Scope == network.ScopeMachineLocal || addr.Scope == network.ScopeLinkLocal:
+			continue
+		case addr.Type == network.IPv4Address:
+			cidrs = append(cidrs, addr.Value+"/32")
+		case addr.Type == network.IPv6Address:
+			cidrs = append(cidrs, addr.Value+"/128")
+		}
+	}
+	return cidrs
+}
@@ -0,0 +1,191 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/testing"
+)
+
+type IngressRulesSuite struct {
+	ConnSuite
+	machine *state.Machine
+	service *state.Service
+	unit    *state.Unit
+}
+
+var _ = gc.Suite(&IngressRulesSuite{})
+
+func (s *IngressRulesSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	s.service = s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	s.unit, err = s.service.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = s.unit.AssignToMachine(s.machine)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *IngressRulesSuite) TestIngressRules(c *gc.C) {
+	rules, ports, err := s.machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, gc.HasLen, 0)
+	c.Assert(ports, gc.HasLen, 0)
+
+	err = s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+
+	// Not exposed and no machine addresses, so nothing is allowed,
+	// but the port is still managed so that it can be dropped.
+	rules, ports, err = s.machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, gc.HasLen, 0)
+	c.Assert(ports, jc.DeepEquals, []network.Port{{"tcp", 80}})
+
+	// Machine addresses are always allowed, except machine-local ones.
+	err = s.machine.SetAddresses(
+		network.NewAddress("10.0.0.2", network.ScopeCloudLocal),
+		network.NewAddress("127.0.0.1", network.ScopeMachineLocal),
+	)
+	c.Assert(err, gc.IsNil)
+	rules, ports, err = s.machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "10.0.0.2/32"},
+	})
+	c.Assert(ports, jc.DeepEquals, []network.Port{{"tcp", 80}})
+
+	err = s.service.SetExposedFrom([]string{"192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	rules, _, err = s.machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "10.0.0.2/32"},
+		{network.Port{"tcp", 80}, "192.168.1.0/24"},
+	})
+
+	err = s.service.SetExposed()
+	c.Assert(err, gc.IsNil)
+	err = s.unit.OpenPort("udp", 53)
+	c.Assert(err, gc.IsNil)
+	rules, ports, err = s.machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "0.0.0.0/0"},
+		{network.Port{"tcp", 80}, "10.0.0.2/32"},
+		{network.Port{"udp", 53}, "0.0.0.0/0"},
+		{network.Port{"udp", 53}, "10.0.0.2/32"},
+	})
+	c.Assert(ports, jc.DeepEquals, []network.Port{{"tcp", 80}, {"udp", 53}})
+}
+
+func (s *IngressRulesSuite) TestIngressRulesAllowEnvironmentMachines(c *gc.C) {
+	err := s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	err = s.service.SetExposedFrom([]string{"192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+
+	// A machine hosting an unrelated service, with public and
+	// link-local addresses.
+	other, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = other.SetAddresses(
+		network.NewAddress("54.0.0.1", network.ScopePublic),
+		network.NewAddress("2001:db8::1", network.ScopePublic),
+		network.NewAddress("169.254.0.1", network.ScopeLinkLocal),
+	)
+	c.Assert(err, gc.IsNil)
+	otherService := s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	otherUnit, err := otherService.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = otherUnit.AssignToMachine(other)
+	c.Assert(err, gc.IsNil)
+
+	// All its addresses but the link-local one may reach the port,
+	// although they are outside the CIDRs the service is exposed to.
+	rules, _, err := s.machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "192.168.1.0/24"},
+		{network.Port{"tcp", 80}, "2001:db8::1/128"},
+		{network.Port{"tcp", 80}, "54.0.0.1/32"},
+	})
+
+	// They still may when the service is not exposed.
+	err = s.service.ClearExposed()
+	c.Assert(err, gc.IsNil)
+	rules, _, err = s.machine.IngressRules()
+	c.Assert(err, gc.IsNil)
+	c.Assert(rules, jc.DeepEquals, []network.IngressRule{
+		{network.Port{"tcp", 80}, "2001:db8::1/128"},
+		{network.Port{"tcp", 80}, "54.0.0.1/32"},
+	})
+}
+
+func (s *IngressRulesSuite) TestWatchIngressRules(c *gc.C) {
+	w := s.machine.WatchIngressRules()
+	defer testing.AssertStop(c, w)
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	// Opening a port of an unexposed service makes it managed.
+	err := s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	// Exposing the service does.
+	err = s.service.SetExposed()
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	// Restricting it to some CIDRs does.
+	err = s.service.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	// So does opening another port.
+	err = s.unit.OpenPort("tcp", 443)
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	// And adding an address to another machine.
+	other, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	wc.AssertNoChange()
+	err = other.SetAddresses(network.NewAddress("10.0.0.3", network.ScopeCloudLocal))
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	// Changes to other machines that leave their addresses alone
+	// do not.
+	err = other.SetAddresses(network.NewAddress("10.0.0.3", network.ScopeCloudLocal))
+	c.Assert(err, gc.IsNil)
+	wc.AssertNoChange()
+
+	// Nor do changes to other services and their units.
+	otherService := s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	err = otherService.SetExposed()
+	c.Assert(err, gc.IsNil)
+	otherUnit, err := otherService.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = otherUnit.AssignToMachine(other)
+	c.Assert(err, gc.IsNil)
+	err = otherUnit.OpenPort("tcp", 3306)
+	c.Assert(err, gc.IsNil)
+	wc.AssertNoChange()
+
+	// Removing an address from another machine does.
+	err = other.SetAddresses()
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	testing.AssertStop(c, w)
+	wc.AssertClosed()
+}
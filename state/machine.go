@@ -120,6 +120,9 @@ type machineDoc struct {
 	// Placement is the placement directive that should be used when provisioning
 	// an instance for the machine.
 	Placement string `bson:",omitempty"`
+	// Spare is set for machines added to the pool of spare machines,
+	// which are provisioned in advance for units to be assigned to.
+	Spare bool `bson:",omitempty"`
 	// Deprecated. InstanceId, now lives on instanceData.
 	// This attribute is retained so that data from existing machines can be read.
 	// SCHEMACHANGE
@@ -656,7 +659,10 @@ func (m *Machine) Remove() (err error) {
 	ops = append(ops, removeContainerRefOps(m.st, m.Id())...)
 	// The only abort conditions in play indicate that the machine has already
 	// been removed.
-	return onAbort(m.st.runTransaction(ops), nil)
+	if err := onAbort(m.st.runTransaction(ops), nil); err != nil {
+		return err
+	}
+	return m.st.removeStatusHistory(m.globalKey())
 }
 
 // Refresh refreshes the contents of the machine from the underlying
@@ -1100,6 +1106,9 @@ func (m *Machine) AddNetworkInterface(args NetworkInterfaceInfo) (iface *Network
 	if args.InterfaceName == "" {
 		return nil, fmt.Errorf("interface name must be not empty")
 	}
+	if err = validateInterfaceSettings(args); err != nil {
+		return nil, err
+	}
 	doc := newNetworkInterfaceDoc(args)
 	doc.MachineId = m.doc.Id
 	doc.Id = bson.NewObjectId()
@@ -1176,6 +1185,12 @@ func (m *Machine) Placement() string {
 	return m.doc.Placement
 }
 
+// IsSpare returns whether the machine was added to the pool of spare
+// machines, and has not since been taken from it.
+func (m *Machine) IsSpare() bool {
+	return m.doc.Spare
+}
+
 // Constraints returns the exact constraints that should apply when provisioning
 // an instance for the machine.
 func (m *Machine) Constraints() (constraints.Value, error) {
@@ -1263,9 +1278,20 @@ func (m *Machine) SetStatus(status params.Status, info string, data params.Statu
 	if err := m.st.runTransaction(ops); err != nil {
 		return fmt.Errorf("cannot set status of machine %q: %v", m, onAbort(err, errNotAlive))
 	}
+	// The status history is informational only, so failing to record
+	// it does not fail the status change.
+	if err := m.st.addStatusHistory(m.globalKey(), doc); err != nil {
+		logger.Warningf("%v", err)
+	}
 	return nil
 }
 
+// StatusHistory returns the statuses most recently set on the machine,
+// oldest first.
+func (m *Machine) StatusHistory() ([]StatusHistoryEntry, error) {
+	return m.st.statusHistory(m.globalKey())
+}
+
 // Clean returns true if the machine does not have any deployed units or containers.
 func (m *Machine) Clean() bool {
 	return m.doc.Clean
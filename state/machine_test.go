@@ -524,37 +524,57 @@ var addNetworkInterfaceErrorsTests = []struct {
 	beforeAdding func(*gc.C, *state.Machine)
 	expectErr    string
 }{{
-	state.NetworkInterfaceInfo{"", "eth1", "net1", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "", InterfaceName: "eth1", NetworkName: "net1"},
 	nil,
 	`cannot add network interface "eth1" to machine "2": MAC address must be not empty`,
 }, {
-	state.NetworkInterfaceInfo{"invalid", "eth1", "net1", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "invalid", InterfaceName: "eth1", NetworkName: "net1"},
 	nil,
 	`cannot add network interface "eth1" to machine "2": invalid MAC address: invalid`,
 }, {
-	state.NetworkInterfaceInfo{"aa:bb:cc:dd:ee:f0", "eth1", "net1", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:f0", InterfaceName: "eth1", NetworkName: "net1"},
 	nil,
 	`cannot add network interface "eth1" to machine "2": MAC address "aa:bb:cc:dd:ee:f0" on network "net1" already exists`,
 }, {
-	state.NetworkInterfaceInfo{"aa:bb:cc:dd:ee:ff", "", "net1", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "", NetworkName: "net1"},
 	nil,
 	`cannot add network interface "" to machine "2": interface name must be not empty`,
 }, {
-	state.NetworkInterfaceInfo{"aa:bb:cc:dd:ee:ff", "eth0", "net1", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "eth0", NetworkName: "net1"},
 	nil,
 	`cannot add network interface "eth0" to machine "2": "eth0" on machine "2" already exists`,
 }, {
-	state.NetworkInterfaceInfo{"aa:bb:cc:dd:ee:ff", "eth1", "missing", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "eth1", NetworkName: "missing"},
 	nil,
 	`cannot add network interface "eth1" to machine "2": network "missing" not found`,
 }, {
-	state.NetworkInterfaceInfo{"aa:bb:cc:dd:ee:f1", "eth1", "net1", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "eth1", NetworkName: "net1", MTU: -1},
+	nil,
+	`cannot add network interface "eth1" to machine "2": invalid MTU -1`,
+}, {
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "bond0", NetworkName: "net1", BondSlaves: []string{"eth1"}},
+	nil,
+	`cannot add network interface "bond0" to machine "2": bond mode must be not empty`,
+}, {
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "bond0", NetworkName: "net1", BondMode: "802.3ad", BondSlaves: []string{"bond0"}},
+	nil,
+	`cannot add network interface "bond0" to machine "2": invalid bond slave "bond0"`,
+}, {
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "eth1", NetworkName: "net1", Routes: []network.Route{{DestinationCIDR: "0.5.0.0", GatewayAddress: "0.1.2.1"}}},
+	nil,
+	`cannot add network interface "eth1" to machine "2": invalid route destination "0.5.0.0"`,
+}, {
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:ff", InterfaceName: "eth1", NetworkName: "net1", Routes: []network.Route{{DestinationCIDR: "0.5.0.0/16", GatewayAddress: "gateway"}}},
+	nil,
+	`cannot add network interface "eth1" to machine "2": invalid route gateway "gateway"`,
+}, {
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:f1", InterfaceName: "eth1", NetworkName: "net1"},
 	func(c *gc.C, m *state.Machine) {
 		c.Check(m.EnsureDead(), gc.IsNil)
 	},
 	`cannot add network interface "eth1" to machine "2": machine is not alive`,
 }, {
-	state.NetworkInterfaceInfo{"aa:bb:cc:dd:ee:f1", "eth1", "net1", false, false},
+	state.NetworkInterfaceInfo{MACAddress: "aa:bb:cc:dd:ee:f1", InterfaceName: "eth1", NetworkName: "net1"},
 	func(c *gc.C, m *state.Machine) {
 		c.Check(m.Remove(), gc.IsNil)
 	},
@@ -1372,6 +1392,56 @@ func (s *MachineSuite) TestSetStatusPending(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `cannot set status "pending"`)
 }
 
+func (s *MachineSuite) TestStatusHistory(c *gc.C) {
+	history, err := s.machine.StatusHistory()
+	c.Assert(err, gc.IsNil)
+	c.Assert(history, gc.HasLen, 0)
+
+	err = s.machine.SetStatus(params.StatusError, "no capacity", params.StatusData{"transient": true})
+	c.Assert(err, gc.IsNil)
+	err = s.machine.SetStatus(params.StatusPending, "retrying", nil)
+	c.Assert(err, gc.IsNil)
+	// Failed status changes are not recorded.
+	err = s.machine.SetStatus(params.Status("vliegkat"), "orville", nil)
+	c.Assert(err, gc.NotNil)
+
+	history, err = s.machine.StatusHistory()
+	c.Assert(err, gc.IsNil)
+	c.Assert(history, gc.HasLen, 2)
+	c.Assert(history[0].Status, gc.Equals, params.StatusError)
+	c.Assert(history[0].Info, gc.Equals, "no capacity")
+	c.Assert(history[0].Data, gc.DeepEquals, params.StatusData{"transient": true})
+	c.Assert(history[0].Updated.IsZero(), jc.IsFalse)
+	c.Assert(history[1].Status, gc.Equals, params.StatusPending)
+	c.Assert(history[1].Info, gc.Equals, "retrying")
+	c.Assert(history[1].Data, gc.HasLen, 0)
+}
+
+func (s *MachineSuite) TestStatusHistoryPruned(c *gc.C) {
+	s.PatchValue(state.MaxStatusHistory, 2)
+	for _, info := range []string{"one", "two", "three"} {
+		err := s.machine.SetStatus(params.StatusError, info, nil)
+		c.Assert(err, gc.IsNil)
+	}
+	history, err := s.machine.StatusHistory()
+	c.Assert(err, gc.IsNil)
+	c.Assert(history, gc.HasLen, 2)
+	c.Assert(history[0].Info, gc.Equals, "two")
+	c.Assert(history[1].Info, gc.Equals, "three")
+}
+
+func (s *MachineSuite) TestStatusHistoryRemovedWithMachine(c *gc.C) {
+	err := s.machine.SetStatus(params.StatusError, "failed", nil)
+	c.Assert(err, gc.IsNil)
+	err = s.machine.EnsureDead()
+	c.Assert(err, gc.IsNil)
+	err = s.machine.Remove()
+	c.Assert(err, gc.IsNil)
+	history, err := s.machine.StatusHistory()
+	c.Assert(err, gc.IsNil)
+	c.Assert(history, gc.HasLen, 0)
+}
+
 func (s *MachineSuite) TestGetSetStatusWhileNotAlive(c *gc.C) {
 	// When Dying set/get should work.
 	err := s.machine.Destroy()
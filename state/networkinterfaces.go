@@ -5,6 +5,7 @@ package state
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/juju/errors"
@@ -12,6 +13,8 @@ import (
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
 )
 
 // NetworkInterface represents the state of a machine network
@@ -42,6 +45,22 @@ type NetworkInterfaceInfo struct {
 
 	// Disabled returns whether the interface is disabled.
 	Disabled bool
+
+	// MTU is the interface's maximum transmission unit, in bytes.
+	// When 0, the default MTU for the device is used.
+	MTU int
+
+	// BondMode is the bonding mode of a bonded interface.
+	BondMode string
+
+	// BondSlaves holds the raw names of the interfaces aggregated by
+	// a bonded interface. It's empty for interfaces that are not
+	// bonds.
+	BondSlaves []string
+
+	// Routes holds the static routes to set up through the
+	// interface.
+	Routes []network.Route
 }
 
 // networkInterfaceDoc represents a network interface for a machine on
@@ -54,6 +73,10 @@ type networkInterfaceDoc struct {
 	MachineId     string
 	IsVirtual     bool
 	IsDisabled    bool
+	MTU           int             `bson:",omitempty"`
+	BondMode      string          `bson:",omitempty"`
+	BondSlaves    []string        `bson:",omitempty"`
+	Routes        []network.Route `bson:",omitempty"`
 }
 
 func newNetworkInterface(st *State, doc *networkInterfaceDoc) *NetworkInterface {
@@ -68,6 +91,10 @@ func newNetworkInterfaceDoc(args NetworkInterfaceInfo) *networkInterfaceDoc {
 		NetworkName:   args.NetworkName,
 		IsVirtual:     args.IsVirtual,
 		IsDisabled:    args.Disabled,
+		MTU:           args.MTU,
+		BondMode:      args.BondMode,
+		BondSlaves:    args.BondSlaves,
+		Routes:        args.Routes,
 	}
 }
 
@@ -139,6 +166,33 @@ func (ni *NetworkInterface) IsDisabled() bool {
 	return ni.doc.IsDisabled
 }
 
+// MTU returns the interface's maximum transmission unit, or 0 if the
+// device's default is used.
+func (ni *NetworkInterface) MTU() int {
+	return ni.doc.MTU
+}
+
+// BondMode returns the bonding mode of a bonded interface.
+func (ni *NetworkInterface) BondMode() string {
+	return ni.doc.BondMode
+}
+
+// BondSlaves returns the raw names of the interfaces aggregated by a
+// bonded interface.
+func (ni *NetworkInterface) BondSlaves() []string {
+	return append([]string(nil), ni.doc.BondSlaves...)
+}
+
+// IsBond returns whether the interface is a bond of other interfaces.
+func (ni *NetworkInterface) IsBond() bool {
+	return len(ni.doc.BondSlaves) > 0
+}
+
+// Routes returns the static routes to set up through the interface.
+func (ni *NetworkInterface) Routes() []network.Route {
+	return append([]network.Route(nil), ni.doc.Routes...)
+}
+
 // Remove removes the network interface from state.
 func (ni *NetworkInterface) Remove() (err error) {
 	defer errors.Maskf(&err, "cannot remove network interface %q", ni)
@@ -189,3 +243,28 @@ func (ni *NetworkInterface) Refresh() error {
 	ni.doc = doc
 	return nil
 }
+
+// validateInterfaceSettings checks the MTU, bond and route settings
+// of a network interface to be added.
+func validateInterfaceSettings(args NetworkInterfaceInfo) error {
+	if args.MTU < 0 {
+		return fmt.Errorf("invalid MTU %d", args.MTU)
+	}
+	if len(args.BondSlaves) > 0 && args.BondMode == "" {
+		return fmt.Errorf("bond mode must be not empty")
+	}
+	for _, slave := range args.BondSlaves {
+		if slave == "" || slave == args.InterfaceName {
+			return fmt.Errorf("invalid bond slave %q", slave)
+		}
+	}
+	for _, route := range args.Routes {
+		if _, _, err := net.ParseCIDR(route.DestinationCIDR); err != nil {
+			return fmt.Errorf("invalid route destination %q", route.DestinationCIDR)
+		}
+		if net.ParseIP(route.GatewayAddress) == nil {
+			return fmt.Errorf("invalid route gateway %q", route.GatewayAddress)
+		}
+	}
+	return nil
+}
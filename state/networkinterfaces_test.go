@@ -8,6 +8,7 @@ import (
 	jc "github.com/juju/testing/checkers"
 	gc "launchpad.net/gocheck"
 
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
 )
 
@@ -47,6 +48,38 @@ func (s *NetworkInterfaceSuite) TestGetterMethods(c *gc.C) {
 	c.Assert(s.iface.IsVirtual(), jc.IsTrue)
 	c.Assert(s.iface.IsPhysical(), jc.IsFalse)
 	c.Assert(s.iface.IsDisabled(), jc.IsFalse)
+	c.Assert(s.iface.MTU(), gc.Equals, 0)
+	c.Assert(s.iface.IsBond(), jc.IsFalse)
+	c.Assert(s.iface.BondSlaves(), gc.HasLen, 0)
+	c.Assert(s.iface.Routes(), gc.HasLen, 0)
+}
+
+func (s *NetworkInterfaceSuite) TestBondMTUAndRoutes(c *gc.C) {
+	routes := []network.Route{
+		{DestinationCIDR: "0.5.0.0/16", GatewayAddress: "0.1.2.1", Metric: 10},
+	}
+	iface, err := s.machine.AddNetworkInterface(state.NetworkInterfaceInfo{
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		InterfaceName: "bond0",
+		NetworkName:   "net1",
+		IsVirtual:     true,
+		MTU:           9000,
+		BondMode:      "802.3ad",
+		BondSlaves:    []string{"eth2", "eth3"},
+		Routes:        routes,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(iface.MTU(), gc.Equals, 9000)
+	c.Assert(iface.IsBond(), jc.IsTrue)
+	c.Assert(iface.BondMode(), gc.Equals, "802.3ad")
+	c.Assert(iface.BondSlaves(), jc.DeepEquals, []string{"eth2", "eth3"})
+	c.Assert(iface.Routes(), jc.DeepEquals, routes)
+
+	err = iface.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(iface.MTU(), gc.Equals, 9000)
+	c.Assert(iface.BondSlaves(), jc.DeepEquals, []string{"eth2", "eth3"})
+	c.Assert(iface.Routes(), jc.DeepEquals, routes)
 }
 
 func (s *NetworkInterfaceSuite) TestSetAndIsDisabled(c *gc.C) {
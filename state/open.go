@@ -135,6 +135,9 @@ var indexes = []struct {
 	{networkInterfacesC, []string{"macaddress", "networkname"}, true},
 	{networkInterfacesC, []string{"networkname"}, false},
 	{networkInterfacesC, []string{"machineid"}, false},
+	{hookExecutionsC, []string{"unit", "started"}, false},
+	{statusHistoryC, []string{"globalkey", "updated"}, false},
+	{runOutputC, []string{"jobid", "seq"}, true},
 }
 
 // The capped collection used for transaction logs defaults to 10MB.
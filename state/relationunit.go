@@ -39,7 +39,12 @@ func (ru *RelationUnit) Endpoint() Endpoint {
 }
 
 // PrivateAddress returns the private address of the unit and whether it is valid.
+// If the relation's endpoint is bound to a space, the unit's address in that
+// space is returned instead.
 func (ru *RelationUnit) PrivateAddress() (string, bool) {
+	if addr, err := ru.unit.EndpointAddress(ru.endpoint.Name); err == nil {
+		return addr, true
+	}
 	return ru.unit.PrivateAddress()
 }
 
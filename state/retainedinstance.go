@@ -0,0 +1,94 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/instance"
+)
+
+// RetainedInstance describes the instance of a dead machine that the
+// provisioner left running because of the environment's harvest mode.
+type RetainedInstance struct {
+	InstanceId instance.Id
+	MachineId  string
+}
+
+// retainedInstanceDoc records a retained instance, keyed by its id.
+type retainedInstanceDoc struct {
+	InstanceId instance.Id `bson:"_id"`
+	MachineId  string
+}
+
+// RetainInstance records that the machine's instance is left running
+// when the machine is removed, so it is not later taken for an instance
+// that juju never knew about. The machine must be dead.
+func (m *Machine) RetainInstance() (err error) {
+	defer errors.Maskf(&err, "cannot retain instance of machine %s", m.doc.Id)
+	if m.doc.Life != Dead {
+		return fmt.Errorf("machine is not dead")
+	}
+	instId, err := m.InstanceId()
+	if err != nil {
+		return err
+	}
+	ops := []txn.Op{{
+		C:      retainedInstancesC,
+		Id:     instId,
+		Assert: txn.DocMissing,
+		Insert: &retainedInstanceDoc{
+			InstanceId: instId,
+			MachineId:  m.doc.Id,
+		},
+	}}
+	if err := m.st.runTransaction(ops); err != txn.ErrAborted {
+		return err
+	}
+	// The instance has been retained already.
+	return nil
+}
+
+// RetainedInstances returns the instances that were retained when
+// their machines were removed.
+func (st *State) RetainedInstances() ([]RetainedInstance, error) {
+	retainedInstances, closer := st.getCollection(retainedInstancesC)
+	defer closer()
+
+	var docs []retainedInstanceDoc
+	if err := retainedInstances.Find(nil).All(&docs); err != nil {
+		return nil, fmt.Errorf("cannot get retained instances: %v", err)
+	}
+	retained := make([]RetainedInstance, len(docs))
+	for i, doc := range docs {
+		retained[i] = RetainedInstance{
+			InstanceId: doc.InstanceId,
+			MachineId:  doc.MachineId,
+		}
+	}
+	return retained, nil
+}
+
+// ReleaseRetainedInstances forgets the given retained instances, once
+// they have been stopped or have otherwise gone away.
+func (st *State) ReleaseRetainedInstances(ids ...instance.Id) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	ops := make([]txn.Op, len(ids))
+	for i, id := range ids {
+		ops[i] = txn.Op{
+			C:      retainedInstancesC,
+			Id:     id,
+			Remove: true,
+		}
+	}
+	if err := st.runTransaction(ops); err != nil {
+		return fmt.Errorf("cannot release retained instances: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+)
+
+type RetainedInstanceSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&RetainedInstanceSuite{})
+
+func (s *RetainedInstanceSuite) addDeadMachine(c *gc.C, instId instance.Id) *state.Machine {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = m.SetProvisioned(instId, "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	err = m.EnsureDead()
+	c.Assert(err, gc.IsNil)
+	return m
+}
+
+func (s *RetainedInstanceSuite) TestRetainInstance(c *gc.C) {
+	m0 := s.addDeadMachine(c, "i-0")
+	m1 := s.addDeadMachine(c, "i-1")
+	err := m0.RetainInstance()
+	c.Assert(err, gc.IsNil)
+	err = m1.RetainInstance()
+	c.Assert(err, gc.IsNil)
+	// Retaining an instance again has no effect.
+	err = m0.RetainInstance()
+	c.Assert(err, gc.IsNil)
+	err = m0.Remove()
+	c.Assert(err, gc.IsNil)
+
+	retained, err := s.State.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained, jc.SameContents, []state.RetainedInstance{
+		{InstanceId: "i-0", MachineId: m0.Id()},
+		{InstanceId: "i-1", MachineId: m1.Id()},
+	})
+
+	err = s.State.ReleaseRetainedInstances("i-0", "i-2")
+	c.Assert(err, gc.IsNil)
+	retained, err = s.State.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained, gc.DeepEquals, []state.RetainedInstance{
+		{InstanceId: "i-1", MachineId: m1.Id()},
+	})
+}
+
+func (s *RetainedInstanceSuite) TestRetainInstanceOfLiveMachine(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = m.RetainInstance()
+	c.Assert(err, gc.ErrorMatches, `cannot retain instance of machine 0: machine is not dead`)
+}
+
+func (s *RetainedInstanceSuite) TestRetainInstanceNotProvisioned(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = m.EnsureDead()
+	c.Assert(err, gc.IsNil)
+	err = m.RetainInstance()
+	c.Assert(err, gc.ErrorMatches, `cannot retain instance of machine 0: machine 0 is not provisioned`)
+}
@@ -0,0 +1,436 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Run job and run output documents are not referenced from anywhere
+// else in the system and are not under watch, so they are written
+// directly rather than through the transaction runner. This keeps
+// the cost of recording output as it arrives low.
+
+// maxRunJobs is the number of run jobs kept in state; older jobs
+// and their output are discarded as new ones are added.
+var maxRunJobs = 100
+
+// RunTarget identifies a machine, and optionally a unit on that
+// machine, that a run job executes commands on.
+type RunTarget struct {
+	MachineId string
+	UnitId    string
+}
+
+// String returns the unit name if the target is a unit, and the
+// machine tag otherwise.
+func (t RunTarget) String() string {
+	if t.UnitId != "" {
+		return t.UnitId
+	}
+	return "machine-" + t.MachineId
+}
+
+// RunTargetResult holds the outcome of running a job's commands on
+// one of its targets.
+type RunTargetResult struct {
+	RunTarget
+
+	// Done is true once the commands have finished on the target.
+	Done bool
+
+	// Code is the exit code of the commands.
+	Code int
+
+	// Error holds any error encountered running the commands.
+	Error string
+
+	// Stdout and Stderr hold all output recorded for the target.
+	Stdout []byte
+	Stderr []byte
+}
+
+// RunOutput is a piece of output recorded for a target of a run job.
+type RunOutput struct {
+	// Seq orders the output of a job; it increases by one for
+	// each piece of output recorded.
+	Seq int
+
+	// Target is the index of the target that produced the output.
+	Target int
+
+	// Stderr is true if the output was written to stderr rather
+	// than stdout.
+	Stderr bool
+
+	Data []byte
+}
+
+// RunJob represents a set of commands run asynchronously on a
+// number of machines and units.
+type RunJob struct {
+	st  *State
+	doc runJobDoc
+}
+
+type runTargetDoc struct {
+	MachineId string
+	UnitId    string `bson:",omitempty"`
+	Done      bool
+	Code      int
+	Error     string `bson:",omitempty"`
+}
+
+type runJobDoc struct {
+	Id         string `bson:"_id"`
+	Seq        int
+	Owner      string
+	Commands   string
+	Created    time.Time
+	LastActive time.Time
+	Targets    []runTargetDoc
+	OutputSeq  int
+}
+
+type runOutputDoc struct {
+	Id     bson.ObjectId `bson:"_id"`
+	JobId  string
+	Seq    int
+	Target int
+	Stderr bool `bson:",omitempty"`
+	Data   []byte
+}
+
+// AddRunJob records a new run job, owned by the entity with the
+// given tag, for the given commands and targets. Only the most recent
+// jobs are kept.
+func (st *State) AddRunJob(owner, commands string, targets []RunTarget) (*RunJob, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("cannot add run job: no owner specified")
+	}
+	if commands == "" {
+		return nil, fmt.Errorf("cannot add run job: no commands specified")
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("cannot add run job: no targets specified")
+	}
+	seq, err := st.sequence("runjob")
+	if err != nil {
+		return nil, fmt.Errorf("cannot add run job: %v", err)
+	}
+	now := nowToTheSecond()
+	doc := runJobDoc{
+		Id:         strconv.Itoa(seq),
+		Seq:        seq,
+		Owner:      owner,
+		Commands:   commands,
+		Created:    now,
+		LastActive: now,
+		Targets:    make([]runTargetDoc, len(targets)),
+	}
+	for i, target := range targets {
+		doc.Targets[i] = runTargetDoc{
+			MachineId: target.MachineId,
+			UnitId:    target.UnitId,
+		}
+	}
+	runJobs, closer := st.getCollection(runJobsC)
+	defer closer()
+	if err := runJobs.Insert(&doc); err != nil {
+		return nil, fmt.Errorf("cannot add run job: %v", err)
+	}
+	if err := st.pruneRunJobs(); err != nil {
+		return nil, err
+	}
+	return &RunJob{st: st, doc: doc}, nil
+}
+
+// RunJob returns the run job with the given id.
+func (st *State) RunJob(id string) (*RunJob, error) {
+	job := &RunJob{st: st}
+	if err := job.load(id); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (j *RunJob) load(id string) error {
+	runJobs, closer := j.st.getCollection(runJobsC)
+	defer closer()
+
+	var doc runJobDoc
+	err := runJobs.FindId(id).One(&doc)
+	if err == mgo.ErrNotFound {
+		return errors.NotFoundf("run job %q", id)
+	} else if err != nil {
+		return fmt.Errorf("cannot get run job %q: %v", id, err)
+	}
+	j.doc = doc
+	return nil
+}
+
+// Refresh refreshes the contents of the job from the underlying
+// state.
+func (j *RunJob) Refresh() error {
+	return j.load(j.doc.Id)
+}
+
+// Id returns the job's id.
+func (j *RunJob) Id() string {
+	return j.doc.Id
+}
+
+// Owner returns the tag of the entity that added the job.
+func (j *RunJob) Owner() string {
+	return j.doc.Owner
+}
+
+// Commands returns the commands run by the job.
+func (j *RunJob) Commands() string {
+	return j.doc.Commands
+}
+
+// Created returns the time the job was added.
+func (j *RunJob) Created() time.Time {
+	return j.doc.Created.UTC()
+}
+
+// Targets returns the machines and units the job runs on.
+func (j *RunJob) Targets() []RunTarget {
+	targets := make([]RunTarget, len(j.doc.Targets))
+	for i, target := range j.doc.Targets {
+		targets[i] = RunTarget{
+			MachineId: target.MachineId,
+			UnitId:    target.UnitId,
+		}
+	}
+	return targets
+}
+
+// Done returns whether the commands have finished on all targets.
+func (j *RunJob) Done() bool {
+	for _, target := range j.doc.Targets {
+		if !target.Done {
+			return false
+		}
+	}
+	return true
+}
+
+func (j *RunJob) checkTarget(target int) error {
+	if target < 0 || target >= len(j.doc.Targets) {
+		return fmt.Errorf("run job %q has no target %d", j.doc.Id, target)
+	}
+	return nil
+}
+
+// AddOutput records output written by the job's commands on the
+// target with the given index. Output for a job must not be added
+// concurrently, or readers may miss some of it.
+func (j *RunJob) AddOutput(target int, stderr bool, data []byte) error {
+	if err := j.checkTarget(target); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	runJobs, closer := j.st.getCollection(runJobsC)
+	defer closer()
+
+	change := mgo.Change{
+		Update:    bson.D{{"$inc", bson.D{{"outputseq", 1}}}},
+		ReturnNew: true,
+	}
+	var doc runJobDoc
+	if _, err := runJobs.FindId(j.doc.Id).Apply(change, &doc); err != nil {
+		return fmt.Errorf("cannot add output to run job %q: %v", j.doc.Id, err)
+	}
+	runOutput, closer := j.st.getCollection(runOutputC)
+	defer closer()
+	err := runOutput.Insert(&runOutputDoc{
+		Id:     bson.NewObjectId(),
+		JobId:  j.doc.Id,
+		Seq:    doc.OutputSeq,
+		Target: target,
+		Stderr: stderr,
+		Data:   data,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot add output to run job %q: %v", j.doc.Id, err)
+	}
+	return nil
+}
+
+// Output returns the output recorded for the job after the given
+// sequence number, in the order it was recorded.
+func (j *RunJob) Output(since int) ([]RunOutput, error) {
+	runOutput, closer := j.st.getCollection(runOutputC)
+	defer closer()
+
+	var docs []runOutputDoc
+	sel := bson.D{{"jobid", j.doc.Id}, {"seq", bson.D{{"$gt", since}}}}
+	if err := runOutput.Find(sel).Sort("seq").All(&docs); err != nil {
+		return nil, fmt.Errorf("cannot get output of run job %q: %v", j.doc.Id, err)
+	}
+	output := make([]RunOutput, len(docs))
+	for i, doc := range docs {
+		output[i] = RunOutput{
+			Seq:    doc.Seq,
+			Target: doc.Target,
+			Stderr: doc.Stderr,
+			Data:   doc.Data,
+		}
+	}
+	return output, nil
+}
+
+// SetResult records that the commands have finished on the target
+// with the given index.
+func (j *RunJob) SetResult(target int, code int, errMsg string) error {
+	if err := j.checkTarget(target); err != nil {
+		return err
+	}
+	runJobs, closer := j.st.getCollection(runJobsC)
+	defer closer()
+
+	prefix := fmt.Sprintf("targets.%d.", target)
+	update := bson.D{{"$set", bson.D{
+		{prefix + "done", true},
+		{prefix + "code", code},
+		{prefix + "error", errMsg},
+	}}}
+	if err := runJobs.UpdateId(j.doc.Id, update); err != nil {
+		return fmt.Errorf("cannot set result of run job %q: %v", j.doc.Id, err)
+	}
+	j.doc.Targets[target].Done = true
+	j.doc.Targets[target].Code = code
+	j.doc.Targets[target].Error = errMsg
+	return nil
+}
+
+// Results returns the outcome of the job on each of its targets,
+// including all the output recorded so far.
+func (j *RunJob) Results() ([]RunTargetResult, error) {
+	output, err := j.Output(0)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]RunTargetResult, len(j.doc.Targets))
+	for i, target := range j.doc.Targets {
+		results[i] = RunTargetResult{
+			RunTarget: RunTarget{
+				MachineId: target.MachineId,
+				UnitId:    target.UnitId,
+			},
+			Done:  target.Done,
+			Code:  target.Code,
+			Error: target.Error,
+		}
+	}
+	for _, out := range output {
+		if out.Target < 0 || out.Target >= len(results) {
+			continue
+		}
+		result := &results[out.Target]
+		if out.Stderr {
+			result.Stderr = append(result.Stderr, out.Data...)
+		} else {
+			result.Stdout = append(result.Stdout, out.Data...)
+		}
+	}
+	return results, nil
+}
+
+// SetRunJobsActive records that the commands of the run jobs with the
+// given ids are still being run.
+func (st *State) SetRunJobsActive(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	runJobs, closer := st.getCollection(runJobsC)
+	defer closer()
+
+	sel := bson.D{{"_id", bson.D{{"$in", ids}}}}
+	update := bson.D{{"$set", bson.D{{"lastactive", nowToTheSecond()}}}}
+	if _, err := runJobs.UpdateAll(sel, update); err != nil {
+		return fmt.Errorf("cannot set run jobs active: %v", err)
+	}
+	return nil
+}
+
+// FailInactiveRunJobs records the given error as the result of every
+// unfinished target of the run jobs that have not been set active
+// since the given time. It is used to finish jobs whose commands were
+// abandoned, for example because the state server running them was
+// stopped.
+func (st *State) FailInactiveRunJobs(since time.Time, errMsg string) error {
+	runJobs, closer := st.getCollection(runJobsC)
+	defer closer()
+
+	sel := bson.D{
+		{"targets.done", false},
+		{"$or", []bson.D{
+			{{"lastactive", bson.D{{"$lt", since}}}},
+			{{"lastactive", bson.D{{"$exists", false}}}},
+		}},
+	}
+	var docs []runJobDoc
+	if err := runJobs.Find(sel).All(&docs); err != nil {
+		return fmt.Errorf("cannot fail inactive run jobs: %v", err)
+	}
+	for _, doc := range docs {
+		job := &RunJob{st: st, doc: doc}
+		for i, target := range doc.Targets {
+			if target.Done {
+				continue
+			}
+			logger.Warningf("run job %q on %s was abandoned", doc.Id, job.Targets()[i])
+			if err := job.SetResult(i, 0, errMsg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pruneRunJobs removes all but the most recent run jobs, together
+// with their output.
+func (st *State) pruneRunJobs() error {
+	runJobs, closer := st.getCollection(runJobsC)
+	defer closer()
+
+	var docs []struct {
+		Id string `bson:"_id"`
+	}
+	err := runJobs.Find(nil).
+		Sort("-seq").
+		Skip(maxRunJobs).
+		Select(bson.D{{"_id", 1}}).
+		All(&docs)
+	if err != nil {
+		return fmt.Errorf("cannot prune run jobs: %v", err)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Id
+	}
+	runOutput, closer := st.getCollection(runOutputC)
+	defer closer()
+	if _, err := runOutput.RemoveAll(bson.D{{"jobid", bson.D{{"$in", ids}}}}); err != nil {
+		return fmt.Errorf("cannot prune run jobs: %v", err)
+	}
+	if _, err := runJobs.RemoveAll(bson.D{{"_id", bson.D{{"$in", ids}}}}); err != nil {
+		return fmt.Errorf("cannot prune run jobs: %v", err)
+	}
+	return nil
+}
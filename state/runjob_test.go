@@ -0,0 +1,184 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/state"
+)
+
+type RunJobSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&RunJobSuite{})
+
+var runJobTargets = []state.RunTarget{
+	{MachineId: "0"},
+	{MachineId: "1", UnitId: "wordpress/0"},
+}
+
+func (s *RunJobSuite) TestAddRunJob(c *gc.C) {
+	job, err := s.State.AddRunJob("user-admin", "hostname", runJobTargets)
+	c.Assert(err, gc.IsNil)
+	c.Assert(job.Owner(), gc.Equals, "user-admin")
+	c.Assert(job.Commands(), gc.Equals, "hostname")
+	c.Assert(job.Targets(), jc.DeepEquals, runJobTargets)
+	c.Assert(job.Done(), jc.IsFalse)
+
+	other, err := s.State.RunJob(job.Id())
+	c.Assert(err, gc.IsNil)
+	c.Assert(other.Id(), gc.Equals, job.Id())
+	c.Assert(other.Owner(), gc.Equals, "user-admin")
+	c.Assert(other.Commands(), gc.Equals, "hostname")
+	c.Assert(other.Created(), gc.DeepEquals, job.Created())
+	c.Assert(other.Targets(), jc.DeepEquals, runJobTargets)
+
+	next, err := s.State.AddRunJob("user-admin", "uptime", runJobTargets)
+	c.Assert(err, gc.IsNil)
+	c.Assert(next.Id(), gc.Not(gc.Equals), job.Id())
+}
+
+func (s *RunJobSuite) TestAddRunJobInvalid(c *gc.C) {
+	_, err := s.State.AddRunJob("", "hostname", runJobTargets)
+	c.Assert(err, gc.ErrorMatches, "cannot add run job: no owner specified")
+	_, err = s.State.AddRunJob("user-admin", "", runJobTargets)
+	c.Assert(err, gc.ErrorMatches, "cannot add run job: no commands specified")
+	_, err = s.State.AddRunJob("user-admin", "hostname", nil)
+	c.Assert(err, gc.ErrorMatches, "cannot add run job: no targets specified")
+}
+
+func (s *RunJobSuite) TestRunJobNotFound(c *gc.C) {
+	_, err := s.State.RunJob("42")
+	c.Assert(err, gc.ErrorMatches, `run job "42" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *RunJobSuite) TestOutput(c *gc.C) {
+	job, err := s.State.AddRunJob("user-admin", "hostname", runJobTargets)
+	c.Assert(err, gc.IsNil)
+
+	err = job.AddOutput(0, false, []byte("one\n"))
+	c.Assert(err, gc.IsNil)
+	err = job.AddOutput(1, true, []byte("two\n"))
+	c.Assert(err, gc.IsNil)
+	err = job.AddOutput(0, false, nil)
+	c.Assert(err, gc.IsNil)
+	err = job.AddOutput(0, false, []byte("three\n"))
+	c.Assert(err, gc.IsNil)
+	err = job.AddOutput(2, false, []byte("bad"))
+	c.Assert(err, gc.ErrorMatches, `run job ".*" has no target 2`)
+
+	output, err := job.Output(0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(output, jc.DeepEquals, []state.RunOutput{
+		{Seq: 1, Target: 0, Data: []byte("one\n")},
+		{Seq: 2, Target: 1, Stderr: true, Data: []byte("two\n")},
+		{Seq: 3, Target: 0, Data: []byte("three\n")},
+	})
+
+	output, err = job.Output(2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(output, jc.DeepEquals, []state.RunOutput{
+		{Seq: 3, Target: 0, Data: []byte("three\n")},
+	})
+}
+
+func (s *RunJobSuite) TestResults(c *gc.C) {
+	job, err := s.State.AddRunJob("user-admin", "hostname", runJobTargets)
+	c.Assert(err, gc.IsNil)
+	err = job.AddOutput(0, false, []byte("out"))
+	c.Assert(err, gc.IsNil)
+	err = job.AddOutput(1, true, []byte("err"))
+	c.Assert(err, gc.IsNil)
+	err = job.AddOutput(0, false, []byte("put"))
+	c.Assert(err, gc.IsNil)
+
+	err = job.SetResult(0, 0, "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(job.Done(), jc.IsFalse)
+	err = job.SetResult(1, 2, "command timed out")
+	c.Assert(err, gc.IsNil)
+	c.Assert(job.Done(), jc.IsTrue)
+
+	job, err = s.State.RunJob(job.Id())
+	c.Assert(err, gc.IsNil)
+	c.Assert(job.Done(), jc.IsTrue)
+	results, err := job.Results()
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, jc.DeepEquals, []state.RunTargetResult{{
+		RunTarget: runJobTargets[0],
+		Done:      true,
+		Stdout:    []byte("output"),
+	}, {
+		RunTarget: runJobTargets[1],
+		Done:      true,
+		Code:      2,
+		Error:     "command timed out",
+		Stderr:    []byte("err"),
+	}})
+}
+
+func (s *RunJobSuite) TestFailInactiveRunJobs(c *gc.C) {
+	job, err := s.State.AddRunJob("user-admin", "hostname", runJobTargets)
+	c.Assert(err, gc.IsNil)
+	err = job.SetResult(0, 1, "")
+	c.Assert(err, gc.IsNil)
+	err = s.State.SetRunJobsActive([]string{job.Id(), "42"})
+	c.Assert(err, gc.IsNil)
+
+	// The job has been active recently, so it is left alone.
+	err = s.State.FailInactiveRunJobs(time.Now().Add(-time.Hour), "abandoned")
+	c.Assert(err, gc.IsNil)
+	err = job.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(job.Done(), jc.IsFalse)
+
+	err = s.State.FailInactiveRunJobs(time.Now().Add(time.Hour), "abandoned")
+	c.Assert(err, gc.IsNil)
+	err = job.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(job.Done(), jc.IsTrue)
+	results, err := job.Results()
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, jc.DeepEquals, []state.RunTargetResult{{
+		RunTarget: runJobTargets[0],
+		Done:      true,
+		Code:      1,
+	}, {
+		RunTarget: runJobTargets[1],
+		Done:      true,
+		Error:     "abandoned",
+	}})
+}
+
+func (s *RunJobSuite) TestPruneRunJobs(c *gc.C) {
+	s.PatchValue(state.MaxRunJobs, 2)
+	first, err := s.State.AddRunJob("user-admin", "one", runJobTargets)
+	c.Assert(err, gc.IsNil)
+	err = first.AddOutput(0, false, []byte("one"))
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddRunJob("user-admin", "two", runJobTargets)
+	c.Assert(err, gc.IsNil)
+	third, err := s.State.AddRunJob("user-admin", "three", runJobTargets)
+	c.Assert(err, gc.IsNil)
+
+	_, err = s.State.RunJob(first.Id())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	output, err := first.Output(0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(output, gc.HasLen, 0)
+	_, err = s.State.RunJob(third.Id())
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *RunJobSuite) TestRunTargetString(c *gc.C) {
+	c.Assert(runJobTargets[0].String(), gc.Equals, "machine-0")
+	c.Assert(runJobTargets[1].String(), gc.Equals, "wordpress/0")
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/juju/charm"
 	"github.com/juju/errors"
@@ -19,6 +20,8 @@ import (
 	"gopkg.in/mgo.v2/txn"
 
 	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/state/api/params"
 )
 
@@ -32,19 +35,24 @@ type Service struct {
 // serviceDoc represents the internal state of a service in MongoDB.
 // Note the correspondence with ServiceInfo in state/api/params.
 type serviceDoc struct {
-	Name          string `bson:"_id"`
-	Series        string
-	Subordinate   bool
-	CharmURL      *charm.URL
-	ForceCharm    bool
-	Life          Life
-	UnitSeq       int
-	UnitCount     int
-	RelationCount int
-	Exposed       bool
-	MinUnits      int
-	OwnerTag      string
-	TxnRevno      int64 `bson:"txn-revno"`
+	Name             string `bson:"_id"`
+	Series           string
+	Subordinate      bool
+	CharmURL         *charm.URL
+	ForceCharm       bool
+	PreviousCharmURL *charm.URL `bson:",omitempty"`
+	HeldUnits        []string   `bson:",omitempty"`
+	Life             Life
+	UnitSeq          int
+	UnitCount        int
+	RelationCount    int
+	Exposed          bool
+	ExposedCIDRs     []string          `bson:",omitempty"`
+	EndpointBindings map[string]string `bson:",omitempty"`
+	MinUnits         int
+	HookRetry        *hookRetryDoc `bson:",omitempty"`
+	OwnerTag         string
+	TxnRevno         int64 `bson:"txn-revno"`
 }
 
 func newService(st *State, doc *serviceDoc) *Service {
@@ -238,29 +246,111 @@ func (s *Service) IsExposed() bool {
 	return s.doc.Exposed
 }
 
-// SetExposed marks the service as exposed.
+// ExposedCIDRs returns the source CIDRs from which the opened ports
+// of an exposed service may be accessed. An empty result means that
+// they may be accessed from any address. See SetExposedFrom.
+func (s *Service) ExposedCIDRs() []string {
+	return append([]string(nil), s.doc.ExposedCIDRs...)
+}
+
+// SetExposed marks the service as exposed to any address.
 // See ClearExposed and IsExposed.
 func (s *Service) SetExposed() error {
-	return s.setExposed(true)
+	return s.setExposed(true, nil)
+}
+
+// SetExposedFrom marks the service as exposed only to the given source
+// CIDRs. If no CIDRs are given, the service is exposed to any address.
+// See SetExposed and ExposedCIDRs.
+func (s *Service) SetExposedFrom(cidrs []string) error {
+	cidrs, err := network.ValidateSourceCIDRs(cidrs)
+	if err != nil {
+		return fmt.Errorf("cannot set exposed flag for service %q: %v", s, err)
+	}
+	return s.setExposed(true, cidrs)
 }
 
 // ClearExposed removes the exposed flag from the service.
 // See SetExposed and IsExposed.
 func (s *Service) ClearExposed() error {
-	return s.setExposed(false)
+	return s.setExposed(false, nil)
 }
 
-func (s *Service) setExposed(exposed bool) (err error) {
+func (s *Service) setExposed(exposed bool, cidrs []string) (err error) {
+	var update bson.D
+	if len(cidrs) > 0 {
+		update = bson.D{{"$set", bson.D{{"exposed", exposed}, {"exposedcidrs", cidrs}}}}
+	} else {
+		update = bson.D{
+			{"$set", bson.D{{"exposed", exposed}}},
+			{"$unset", bson.D{{"exposedcidrs", nil}}},
+		}
+	}
 	ops := []txn.Op{{
 		C:      servicesC,
 		Id:     s.doc.Name,
 		Assert: isAliveDoc,
-		Update: bson.D{{"$set", bson.D{{"exposed", exposed}}}},
+		Update: update,
 	}}
 	if err := s.st.runTransaction(ops); err != nil {
 		return fmt.Errorf("cannot set exposed flag for service %q to %v: %v", s, exposed, onAbort(err, errNotAlive))
 	}
 	s.doc.Exposed = exposed
+	s.doc.ExposedCIDRs = cidrs
+	return nil
+}
+
+// hookRetryDoc holds a service's override of the environment's
+// hook retry policy.
+type hookRetryDoc struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// HookRetryPolicy returns the policy used to automatically retry the
+// service's failed hooks, if the service overrides the environment's.
+func (s *Service) HookRetryPolicy() (config.HookRetryPolicy, bool) {
+	if s.doc.HookRetry == nil {
+		return config.HookRetryPolicy{}, false
+	}
+	return config.HookRetryPolicy{
+		Attempts: s.doc.HookRetry.Attempts,
+		Delay:    s.doc.HookRetry.Delay,
+	}, true
+}
+
+// SetHookRetryPolicy overrides the environment's hook retry policy for
+// the service. If policy is nil, the service uses the environment's
+// policy again.
+func (s *Service) SetHookRetryPolicy(policy *config.HookRetryPolicy) (err error) {
+	defer errors.Maskf(&err, "cannot set hook retry policy for service %q", s)
+	var doc *hookRetryDoc
+	var update bson.D
+	if policy != nil {
+		if policy.Attempts < 0 {
+			return fmt.Errorf("expected non-negative attempts, got %d", policy.Attempts)
+		}
+		if policy.Delay <= 0 {
+			return fmt.Errorf("expected positive delay, got %v", policy.Delay)
+		}
+		doc = &hookRetryDoc{
+			Attempts: policy.Attempts,
+			Delay:    policy.Delay,
+		}
+		update = bson.D{{"$set", bson.D{{"hookretry", doc}}}}
+	} else {
+		update = bson.D{{"$unset", bson.D{{"hookretry", nil}}}}
+	}
+	ops := []txn.Op{{
+		C:      servicesC,
+		Id:     s.doc.Name,
+		Assert: isAliveDoc,
+		Update: update,
+	}}
+	if err := s.st.runTransaction(ops); err != nil {
+		return onAbort(err, errNotAlive)
+	}
+	s.doc.HookRetry = doc
 	return nil
 }
 
@@ -371,8 +461,9 @@ func (s *Service) checkRelationsOps(ch *Charm, relations []*Relation) ([]txn.Op,
 }
 
 // changeCharmOps returns the operations necessary to set a service's
-// charm URL to a new value.
-func (s *Service) changeCharmOps(ch *Charm, force bool) ([]txn.Op, error) {
+// charm URL to a new value, recording the given previous charm URL
+// (if any) to allow the change to be rolled back.
+func (s *Service) changeCharmOps(ch *Charm, force bool, previous *charm.URL, heldUnits []string) ([]txn.Op, error) {
 	settings, closer := s.st.getCollection(settingsC)
 	defer closer()
 
@@ -413,6 +504,22 @@ func (s *Service) changeCharmOps(ch *Charm, force bool) ([]txn.Op, error) {
 
 	// Build the transaction.
 	differentCharm := bson.D{{"charmurl", bson.D{{"$ne", ch.URL()}}}}
+	set := bson.D{{"charmurl", ch.URL()}, {"forcecharm", force}}
+	var unset bson.D
+	if previous != nil {
+		set = append(set, bson.DocElem{"previouscharmurl", previous})
+	} else {
+		unset = append(unset, bson.DocElem{"previouscharmurl", nil})
+	}
+	if len(heldUnits) > 0 {
+		set = append(set, bson.DocElem{"heldunits", heldUnits})
+	} else {
+		unset = append(unset, bson.DocElem{"heldunits", nil})
+	}
+	update := bson.D{{"$set", set}}
+	if len(unset) > 0 {
+		update = append(update, bson.DocElem{"$unset", unset})
+	}
 	ops := []txn.Op{
 		// Old settings shouldn't change
 		oldSettings.assertUnchangedOp(),
@@ -425,7 +532,7 @@ func (s *Service) changeCharmOps(ch *Charm, force bool) ([]txn.Op, error) {
 			C:      servicesC,
 			Id:     s.doc.Name,
 			Assert: append(isAliveDoc, differentCharm...),
-			Update: bson.D{{"$set", bson.D{{"charmurl", ch.URL()}, {"forcecharm", force}}}},
+			Update: update,
 		},
 	}
 	// Add any extra peer relations that need creation.
@@ -465,7 +572,19 @@ func (s *Service) changeCharmOps(ch *Charm, force bool) ([]txn.Op, error) {
 // SetCharm changes the charm for the service. New units will be started with
 // this charm, and existing units will be upgraded to use it. If force is true,
 // units will be upgraded even if they are in an error state.
-func (s *Service) SetCharm(ch *Charm, force bool) (err error) {
+func (s *Service) SetCharm(ch *Charm, force bool) error {
+	return s.setCharm(ch, force, nil)
+}
+
+// SetCharmHoldingUnits changes the charm for the service as SetCharm
+// does, except that the named units are not upgraded to the new charm
+// until they are released with ReleaseCharmUpgrades. This allows the
+// units of a service to be upgraded a few at a time.
+func (s *Service) SetCharmHoldingUnits(ch *Charm, force bool, unitNames []string) error {
+	return s.setCharm(ch, force, unitNames)
+}
+
+func (s *Service) setCharm(ch *Charm, force bool, heldUnits []string) (err error) {
 	services, closer := s.st.getCollection(servicesC)
 	defer closer()
 	settings := services.Database.C(settingsC)
@@ -492,17 +611,24 @@ func (s *Service) SetCharm(ch *Charm, force bool) (err error) {
 		if count, err := services.Find(sel).Count(); err != nil {
 			return nil, err
 		} else if count == 1 {
-			// Charm URL already set; just update the force flag.
+			// Charm URL already set; just update the force flag
+			// and the held units.
 			sameCharm := bson.D{{"charmurl", ch.URL()}}
+			update := bson.D{{"$set", bson.D{{"forcecharm", force}}}}
+			if len(heldUnits) > 0 {
+				update = bson.D{{"$set", bson.D{{"forcecharm", force}, {"heldunits", heldUnits}}}}
+			} else {
+				update = append(update, bson.DocElem{"$unset", bson.D{{"heldunits", nil}}})
+			}
 			ops = []txn.Op{{
 				C:      servicesC,
 				Id:     s.doc.Name,
 				Assert: append(isAliveDoc, sameCharm...),
-				Update: bson.D{{"$set", bson.D{{"forcecharm", force}}}},
+				Update: update,
 			}}
 		} else {
 			// Change the charm URL.
-			ops, err = s.changeCharmOps(ch, force)
+			ops, err = s.changeCharmOps(ch, force, s.doc.CharmURL, heldUnits)
 			if err != nil {
 				return nil, err
 			}
@@ -510,13 +636,120 @@ func (s *Service) SetCharm(ch *Charm, force bool) (err error) {
 		return ops, nil
 	}
 	if err = s.st.run(buildTxn); err == nil {
+		if *s.doc.CharmURL != *ch.URL() {
+			s.doc.PreviousCharmURL = s.doc.CharmURL
+		}
 		s.doc.CharmURL = ch.URL()
 		s.doc.ForceCharm = force
+		s.doc.HeldUnits = heldUnits
 		return nil
 	}
 	return err
 }
 
+// HeldUnits returns the names of the units that are not to be upgraded
+// to the service's charm until released.
+func (s *Service) HeldUnits() []string {
+	return s.doc.HeldUnits
+}
+
+// CharmUpgradeHeld returns whether the named unit is not to be upgraded
+// to the service's charm until released.
+func (s *Service) CharmUpgradeHeld(unitName string) bool {
+	for _, name := range s.doc.HeldUnits {
+		if name == unitName {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseCharmUpgrades allows the named units, previously held with
+// SetCharmHoldingUnits, to upgrade to the service's charm.
+func (s *Service) ReleaseCharmUpgrades(unitNames ...string) (err error) {
+	defer errors.Maskf(&err, "cannot release charm upgrades of service %q", s)
+	ops := []txn.Op{{
+		C:      servicesC,
+		Id:     s.doc.Name,
+		Assert: isAliveDoc,
+		Update: bson.D{{"$pullAll", bson.D{{"heldunits", unitNames}}}},
+	}}
+	if err := s.st.runTransaction(ops); err == txn.ErrAborted {
+		return errNotAlive
+	} else if err != nil {
+		return err
+	}
+	var held []string
+	for _, name := range s.doc.HeldUnits {
+		released := false
+		for _, unitName := range unitNames {
+			if name == unitName {
+				released = true
+				break
+			}
+		}
+		if !released {
+			held = append(held, name)
+		}
+	}
+	s.doc.HeldUnits = held
+	return nil
+}
+
+// PreviousCharmURL returns the URL of the charm the service used before
+// its charm was last changed, and whether the change can be rolled back.
+func (s *Service) PreviousCharmURL() (*charm.URL, bool) {
+	return s.doc.PreviousCharmURL, s.doc.PreviousCharmURL != nil
+}
+
+// RollbackCharm changes the charm for the service back to the one it
+// used before its charm was last changed. Units are upgraded to the
+// previous charm even if they are in an error state. A change can only
+// be rolled back once.
+func (s *Service) RollbackCharm() (err error) {
+	defer errors.Maskf(&err, "cannot roll back charm of service %q", s)
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := s.Refresh(); errors.IsNotFound(err) {
+				return nil, errNotAlive
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if s.doc.Life != Alive {
+			return nil, errNotAlive
+		}
+		if s.doc.PreviousCharmURL == nil {
+			return nil, fmt.Errorf("no previous charm")
+		}
+		ch, err := s.st.Charm(s.doc.PreviousCharmURL)
+		if err != nil {
+			return nil, err
+		}
+		ops, err := s.changeCharmOps(ch, true, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		// Make sure we roll back the charm we think we're rolling back.
+		return append(ops, txn.Op{
+			C:  servicesC,
+			Id: s.doc.Name,
+			Assert: bson.D{
+				{"charmurl", s.doc.CharmURL},
+				{"previouscharmurl", ch.URL()},
+			},
+		}), nil
+	}
+	if err := s.st.run(buildTxn); err != nil {
+		return err
+	}
+	s.doc.CharmURL = s.doc.PreviousCharmURL
+	s.doc.PreviousCharmURL = nil
+	s.doc.ForceCharm = true
+	s.doc.HeldUnits = nil
+	return nil
+}
+
 // String returns the service name.
 func (s *Service) String() string {
 	return s.doc.Name
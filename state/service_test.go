@@ -6,6 +6,7 @@ package state_test
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/juju/charm"
 	"github.com/juju/errors"
@@ -70,6 +71,126 @@ func (s *ServiceSuite) TestSetCharm(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `service "mysql" is not alive`)
 }
 
+func (s *ServiceSuite) TestRollbackCharm(c *gc.C) {
+	_, ok := s.mysql.PreviousCharmURL()
+	c.Assert(ok, jc.IsFalse)
+	err := s.mysql.RollbackCharm()
+	c.Assert(err, gc.ErrorMatches, `cannot roll back charm of service "mysql": no previous charm`)
+
+	sch := s.AddMetaCharm(c, "mysql", metaBase, 2)
+	err = s.mysql.SetCharm(sch, false)
+	c.Assert(err, gc.IsNil)
+	previous, ok := s.mysql.PreviousCharmURL()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(previous, gc.DeepEquals, s.charm.URL())
+
+	// Setting the same charm again doesn't lose the previous one.
+	err = s.mysql.SetCharm(sch, false)
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.Refresh()
+	c.Assert(err, gc.IsNil)
+	previous, ok = s.mysql.PreviousCharmURL()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(previous, gc.DeepEquals, s.charm.URL())
+
+	// Rolling back forces units back to the previous charm.
+	err = s.mysql.RollbackCharm()
+	c.Assert(err, gc.IsNil)
+	reloaded, err := s.State.Service("mysql")
+	c.Assert(err, gc.IsNil)
+	for _, svc := range []*state.Service{s.mysql, reloaded} {
+		url, force := svc.CharmURL()
+		c.Assert(url, gc.DeepEquals, s.charm.URL())
+		c.Assert(force, jc.IsTrue)
+		_, ok = svc.PreviousCharmURL()
+		c.Assert(ok, jc.IsFalse)
+	}
+
+	// A change can only be rolled back once.
+	err = s.mysql.RollbackCharm()
+	c.Assert(err, gc.ErrorMatches, `cannot roll back charm of service "mysql": no previous charm`)
+}
+
+func (s *ServiceSuite) TestSetCharmHoldingUnits(c *gc.C) {
+	c.Assert(s.mysql.HeldUnits(), gc.HasLen, 0)
+	sch := s.AddMetaCharm(c, "mysql", metaBase, 2)
+	err := s.mysql.SetCharmHoldingUnits(sch, false, []string{"mysql/0", "mysql/1"})
+	c.Assert(err, gc.IsNil)
+	reloaded, err := s.State.Service("mysql")
+	c.Assert(err, gc.IsNil)
+	for _, svc := range []*state.Service{s.mysql, reloaded} {
+		url, _ := svc.CharmURL()
+		c.Assert(url, gc.DeepEquals, sch.URL())
+		c.Assert(svc.HeldUnits(), jc.DeepEquals, []string{"mysql/0", "mysql/1"})
+		c.Assert(svc.CharmUpgradeHeld("mysql/0"), jc.IsTrue)
+		c.Assert(svc.CharmUpgradeHeld("mysql/2"), jc.IsFalse)
+	}
+
+	err = s.mysql.ReleaseCharmUpgrades("mysql/0", "mysql/2")
+	c.Assert(err, gc.IsNil)
+	err = reloaded.Refresh()
+	c.Assert(err, gc.IsNil)
+	for _, svc := range []*state.Service{s.mysql, reloaded} {
+		c.Assert(svc.HeldUnits(), jc.DeepEquals, []string{"mysql/1"})
+		c.Assert(svc.CharmUpgradeHeld("mysql/0"), jc.IsFalse)
+		c.Assert(svc.CharmUpgradeHeld("mysql/1"), jc.IsTrue)
+	}
+
+	// Any other change of charm releases all held units.
+	err = s.mysql.RollbackCharm()
+	c.Assert(err, gc.IsNil)
+	err = reloaded.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.mysql.HeldUnits(), gc.HasLen, 0)
+	c.Assert(reloaded.HeldUnits(), gc.HasLen, 0)
+
+	err = s.mysql.SetCharmHoldingUnits(sch, false, []string{"mysql/1"})
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.SetCharm(sch, true)
+	c.Assert(err, gc.IsNil)
+	err = reloaded.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.mysql.HeldUnits(), gc.HasLen, 0)
+	c.Assert(reloaded.HeldUnits(), gc.HasLen, 0)
+}
+
+func (s *ServiceSuite) TestReleaseCharmUpgradesDeadService(c *gc.C) {
+	err := s.mysql.Destroy()
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.ReleaseCharmUpgrades("mysql/0")
+	c.Assert(err, gc.ErrorMatches, `cannot release charm upgrades of service "mysql": not found or not alive`)
+}
+
+func (s *ServiceSuite) TestRollbackCharmSettings(c *gc.C) {
+	oldCharm := s.AddConfigCharm(c, "mysql", stringConfig, 2)
+	newCharm := s.AddConfigCharm(c, "mysql", newStringConfig, 3)
+	svc := s.AddTestingService(c, "configured", oldCharm)
+	err := svc.UpdateConfigSettings(charm.Settings{"key": "value"})
+	c.Assert(err, gc.IsNil)
+	err = svc.SetCharm(newCharm, false)
+	c.Assert(err, gc.IsNil)
+	err = svc.UpdateConfigSettings(charm.Settings{"other": "thing"})
+	c.Assert(err, gc.IsNil)
+
+	// Settings are carried back to the previous charm as far as its
+	// configuration allows.
+	err = svc.RollbackCharm()
+	c.Assert(err, gc.IsNil)
+	settings, err := svc.ConfigSettings()
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, gc.DeepEquals, charm.Settings{"key": "value"})
+}
+
+func (s *ServiceSuite) TestRollbackCharmDeadService(c *gc.C) {
+	sch := s.AddMetaCharm(c, "mysql", metaBase, 2)
+	err := s.mysql.SetCharm(sch, false)
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.Destroy()
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.RollbackCharm()
+	c.Assert(err, gc.ErrorMatches, `cannot roll back charm of service "mysql": not found or not alive`)
+}
+
 func (s *ServiceSuite) TestSetCharmErrors(c *gc.C) {
 	logging := s.AddTestingCharm(c, "logging")
 	err := s.mysql.SetCharm(logging, false)
@@ -767,6 +888,71 @@ func (s *ServiceSuite) TestServiceExposed(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, notAliveErr)
 }
 
+func (s *ServiceSuite) TestServiceExposedFrom(c *gc.C) {
+	c.Assert(s.mysql.ExposedCIDRs(), gc.HasLen, 0)
+
+	err := s.mysql.SetExposedFrom([]string{"10.1.2.3/8", "192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.mysql.IsExposed(), gc.Equals, true)
+	c.Assert(s.mysql.ExposedCIDRs(), jc.DeepEquals, []string{"10.0.0.0/8", "192.168.1.0/24"})
+
+	err = s.mysql.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.mysql.IsExposed(), gc.Equals, true)
+	c.Assert(s.mysql.ExposedCIDRs(), jc.DeepEquals, []string{"10.0.0.0/8", "192.168.1.0/24"})
+
+	// Invalid CIDRs are rejected and leave the service untouched.
+	err = s.mysql.SetExposedFrom([]string{"foo"})
+	c.Assert(err, gc.ErrorMatches, `cannot set exposed flag for service "mysql": invalid source CIDR "foo"`)
+	err = s.mysql.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.mysql.ExposedCIDRs(), jc.DeepEquals, []string{"10.0.0.0/8", "192.168.1.0/24"})
+
+	// Exposing without CIDRs opens the service to any address.
+	err = s.mysql.SetExposed()
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.mysql.IsExposed(), gc.Equals, true)
+	c.Assert(s.mysql.ExposedCIDRs(), gc.HasLen, 0)
+
+	// Clearing the exposed flag also forgets the CIDRs.
+	err = s.mysql.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.ClearExposed()
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.mysql.IsExposed(), gc.Equals, false)
+	c.Assert(s.mysql.ExposedCIDRs(), gc.HasLen, 0)
+}
+
+func (s *ServiceSuite) TestServiceHookRetryPolicy(c *gc.C) {
+	_, ok := s.mysql.HookRetryPolicy()
+	c.Assert(ok, jc.IsFalse)
+
+	policy := config.HookRetryPolicy{Attempts: 3, Delay: time.Minute}
+	err := s.mysql.SetHookRetryPolicy(&policy)
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.Refresh()
+	c.Assert(err, gc.IsNil)
+	obtained, ok := s.mysql.HookRetryPolicy()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(obtained, gc.Equals, policy)
+
+	err = s.mysql.SetHookRetryPolicy(&config.HookRetryPolicy{Attempts: -1, Delay: time.Minute})
+	c.Assert(err, gc.ErrorMatches, `cannot set hook retry policy for service "mysql": expected non-negative attempts, got -1`)
+	err = s.mysql.SetHookRetryPolicy(&config.HookRetryPolicy{Attempts: 1})
+	c.Assert(err, gc.ErrorMatches, `cannot set hook retry policy for service "mysql": expected positive delay, got 0`)
+
+	err = s.mysql.SetHookRetryPolicy(nil)
+	c.Assert(err, gc.IsNil)
+	err = s.mysql.Refresh()
+	c.Assert(err, gc.IsNil)
+	_, ok = s.mysql.HookRetryPolicy()
+	c.Assert(ok, jc.IsFalse)
+}
+
 func (s *ServiceSuite) TestAddUnit(c *gc.C) {
 	// Check that principal units can be added on their own.
 	unitZero, err := s.mysql.AddUnit()
@@ -0,0 +1,239 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+// Space represents a named group of subnets. Service endpoints may be
+// bound to a space, so that their units are reached through addresses
+// on the space's subnets.
+type Space struct {
+	st  *State
+	doc spaceDoc
+}
+
+// spaceDoc represents a space in MongoDB.
+type spaceDoc struct {
+	Name string `bson:"_id"`
+
+	// Subnets holds the CIDRs of the space's subnets, in
+	// 123.45.67.0/24 format. Each of those subnets records the
+	// space's name in its own document.
+	Subnets []string
+}
+
+var validSpaceName = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+
+// IsValidSpaceName reports whether name is a valid space name.
+func IsValidSpaceName(name string) bool {
+	return validSpaceName.MatchString(name)
+}
+
+func newSpace(st *State, doc *spaceDoc) *Space {
+	return &Space{st, *doc}
+}
+
+// Name returns the name of the space.
+func (s *Space) Name() string {
+	return s.doc.Name
+}
+
+// String returns the name of the space.
+func (s *Space) String() string {
+	return s.doc.Name
+}
+
+// Subnets returns the CIDRs of the subnets in the space.
+func (s *Space) Subnets() []string {
+	return append([]string(nil), s.doc.Subnets...)
+}
+
+// Contains reports whether the address is on one of the space's
+// subnets.
+func (s *Space) Contains(addr network.Address) bool {
+	ip := net.ParseIP(addr.Value)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range s.doc.Subnets {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSpace creates a new space holding the given subnets, which must
+// already have been added with AddSubnet. Each subnet may belong to
+// only one space, and may not overlap a subnet in another space. If a
+// space with the same name already exists, an error satisfying
+// errors.IsAlreadyExists is returned.
+func (st *State) AddSpace(name string, subnets []string) (space *Space, err error) {
+	defer errors.Contextf(&err, "cannot add space %q", name)
+	if !IsValidSpaceName(name) {
+		return nil, fmt.Errorf("invalid name")
+	}
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("no subnets specified")
+	}
+	var cidrs []string
+	seen := make(map[string]bool)
+	for _, subnet := range subnets {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(subnet))
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q", subnet)
+		}
+		if cidr := ipNet.String(); !seen[cidr] {
+			seen[cidr] = true
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	doc := &spaceDoc{
+		Name:    name,
+		Subnets: cidrs,
+	}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if _, err := st.Space(name); err == nil {
+			return nil, errors.AlreadyExistsf("space %q", name)
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		subnetOps, err := st.spaceSubnetsOps(name, cidrs)
+		if err != nil {
+			return nil, err
+		}
+		ops := []txn.Op{{
+			C:      spacesC,
+			Id:     name,
+			Assert: txn.DocMissing,
+			Insert: doc,
+		}}
+		return append(ops, subnetOps...), nil
+	}
+	if err := st.run(buildTxn); err != nil {
+		return nil, err
+	}
+	return newSpace(st, doc), nil
+}
+
+// spaceSubnetsOps returns the operations needed to put the subnets with
+// the given CIDRs into the named space. The operations assert that
+// neither those subnets nor any known subnet overlapping them is in a
+// space yet, so that concurrent changes cannot leave overlapping
+// subnets in different spaces.
+func (st *State) spaceSubnetsOps(spaceName string, cidrs []string) ([]txn.Op, error) {
+	known, err := st.AllSubnets()
+	if err != nil {
+		return nil, err
+	}
+	requested := make(map[string]bool)
+	for _, cidr := range cidrs {
+		requested[cidr] = true
+	}
+	var ops []txn.Op
+	for _, cidr := range cidrs {
+		var subnet *Subnet
+		for _, other := range known {
+			if other.CIDR() == cidr {
+				subnet = other
+			}
+		}
+		if subnet == nil {
+			return nil, errors.NotFoundf("subnet %q", cidr)
+		}
+		if subnet.SpaceName() != "" {
+			return nil, fmt.Errorf("subnet %s is already in space %q", cidr, subnet.SpaceName())
+		}
+		ops = append(ops, txn.Op{
+			C:      subnetsC,
+			Id:     cidr,
+			Assert: noSpaceDoc,
+			Update: bson.D{{"$set", bson.D{{"spacename", spaceName}}}},
+		})
+	}
+	asserted := make(map[string]bool)
+	for _, other := range known {
+		if requested[other.CIDR()] {
+			continue
+		}
+		for _, cidr := range cidrs {
+			if !subnetsOverlap(cidr, other.CIDR()) {
+				continue
+			}
+			if other.SpaceName() != "" {
+				return nil, fmt.Errorf("subnet %s overlaps subnet %s in space %q", cidr, other.CIDR(), other.SpaceName())
+			}
+			if !asserted[other.CIDR()] {
+				asserted[other.CIDR()] = true
+				ops = append(ops, txn.Op{
+					C:      subnetsC,
+					Id:     other.CIDR(),
+					Assert: noSpaceDoc,
+				})
+			}
+		}
+	}
+	return ops, nil
+}
+
+// subnetsOverlap reports whether the two CIDRs have any address in
+// common.
+func subnetsOverlap(cidr1, cidr2 string) bool {
+	_, ipNet1, err := net.ParseCIDR(cidr1)
+	if err != nil {
+		return false
+	}
+	_, ipNet2, err := net.ParseCIDR(cidr2)
+	if err != nil {
+		return false
+	}
+	return ipNet1.Contains(ipNet2.IP) || ipNet2.Contains(ipNet1.IP)
+}
+
+// Space returns the space with the given name.
+func (st *State) Space(name string) (*Space, error) {
+	spaces, closer := st.getCollection(spacesC)
+	defer closer()
+
+	doc := &spaceDoc{}
+	err := spaces.FindId(name).One(doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("space %q", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get space %q: %v", name, err)
+	}
+	return newSpace(st, doc), nil
+}
+
+// AllSpaces returns all the spaces in the environment, sorted by
+// name.
+func (st *State) AllSpaces() ([]*Space, error) {
+	spacesCollection, closer := st.getCollection(spacesC)
+	defer closer()
+
+	docs := []spaceDoc{}
+	err := spacesCollection.Find(nil).Sort("_id").All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get all spaces: %v", err)
+	}
+	spaces := make([]*Space, len(docs))
+	for i := range docs {
+		spaces[i] = newSpace(st, &docs[i])
+	}
+	return spaces, nil
+}
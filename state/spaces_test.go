@@ -0,0 +1,158 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+)
+
+type SpaceSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&SpaceSuite{})
+
+// addSubnets records subnets with the given CIDRs, so that they can be
+// put into spaces.
+func addSubnets(c *gc.C, st *state.State, cidrs ...string) {
+	for _, cidr := range cidrs {
+		_, err := st.AddSubnet(state.SubnetInfo{
+			CIDR:       cidr,
+			ProviderId: network.Id("subnet-" + cidr),
+		})
+		c.Assert(err, gc.IsNil)
+	}
+}
+
+func (s *SpaceSuite) TestAddSpace(c *gc.C) {
+	addSubnets(c, s.State, "10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24")
+	space, err := s.State.AddSpace("internal", []string{"10.0.1.7/24", "10.0.2.0/24"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(space.Name(), gc.Equals, "internal")
+	c.Assert(space.Subnets(), jc.DeepEquals, []string{"10.0.1.0/24", "10.0.2.0/24"})
+
+	space, err = s.State.Space("internal")
+	c.Assert(err, gc.IsNil)
+	c.Assert(space.Subnets(), jc.DeepEquals, []string{"10.0.1.0/24", "10.0.2.0/24"})
+
+	subnet, err := s.State.Subnet("10.0.2.0/24")
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.SpaceName(), gc.Equals, "internal")
+	subnet, err = s.State.Subnet("10.0.3.0/24")
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.SpaceName(), gc.Equals, "")
+
+	_, err = s.State.AddSpace("internal", []string{"10.0.3.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "internal": space "internal" already exists`)
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *SpaceSuite) TestAddSpaceInvalid(c *gc.C) {
+	for i, test := range []struct {
+		name    string
+		subnets []string
+		err     string
+	}{{
+		name:    "Internal",
+		subnets: []string{"10.0.1.0/24"},
+		err:     `cannot add space "Internal": invalid name`,
+	}, {
+		name: "internal",
+		err:  `cannot add space "internal": no subnets specified`,
+	}, {
+		name:    "internal",
+		subnets: []string{"10.0.1.0"},
+		err:     `cannot add space "internal": invalid subnet "10.0.1.0"`,
+	}} {
+		c.Logf("test %d: %q %v", i, test.name, test.subnets)
+		_, err := s.State.AddSpace(test.name, test.subnets)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *SpaceSuite) TestAddSpaceUnknownSubnet(c *gc.C) {
+	addSubnets(c, s.State, "10.0.1.0/24")
+	_, err := s.State.AddSpace("internal", []string{"10.0.1.0/24", "10.0.2.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "internal": subnet "10.0.2.0/24" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	subnet, err := s.State.Subnet("10.0.1.0/24")
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.SpaceName(), gc.Equals, "")
+}
+
+func (s *SpaceSuite) TestSubnetInOneSpaceOnly(c *gc.C) {
+	addSubnets(c, s.State, "10.0.1.0/24", "10.0.2.0/24")
+	_, err := s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("public", []string{"10.0.2.0/24", "10.0.1.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "public": subnet 10.0.1.0/24 is already in space "internal"`)
+}
+
+func (s *SpaceSuite) TestAddSpaceOverlapping(c *gc.C) {
+	addSubnets(c, s.State, "10.0.0.0/16", "10.0.1.0/24", "10.1.0.0/16")
+	_, err := s.State.AddSpace("internal", []string{"10.0.0.0/16"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("public", []string{"10.1.0.0/16", "10.0.1.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "public": subnet 10.0.1.0/24 overlaps subnet 10.0.0.0/16 in space "internal"`)
+
+	_, err = s.State.Space("public")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *SpaceSuite) TestAddSpaceOverlappingConcurrently(c *gc.C) {
+	addSubnets(c, s.State, "10.0.0.0/16", "10.0.1.0/24")
+	defer state.SetBeforeHooks(c, s.State, func() {
+		_, err := s.State.AddSpace("internal", []string{"10.0.0.0/16"})
+		c.Assert(err, gc.IsNil)
+	}).Check()
+
+	_, err := s.State.AddSpace("public", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "public": subnet 10.0.1.0/24 overlaps subnet 10.0.0.0/16 in space "internal"`)
+}
+
+func (s *SpaceSuite) TestSpaceNotFound(c *gc.C) {
+	_, err := s.State.Space("internal")
+	c.Assert(err, gc.ErrorMatches, `space "internal" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *SpaceSuite) TestAllSpaces(c *gc.C) {
+	spaces, err := s.State.AllSpaces()
+	c.Assert(err, gc.IsNil)
+	c.Assert(spaces, gc.HasLen, 0)
+
+	addSubnets(c, s.State, "10.0.1.0/24", "10.0.2.0/24")
+	_, err = s.State.AddSpace("public", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.2.0/24"})
+	c.Assert(err, gc.IsNil)
+	spaces, err = s.State.AllSpaces()
+	c.Assert(err, gc.IsNil)
+	c.Assert(spaces, gc.HasLen, 2)
+	c.Assert(spaces[0].Name(), gc.Equals, "internal")
+	c.Assert(spaces[1].Name(), gc.Equals, "public")
+}
+
+func (s *SpaceSuite) TestContains(c *gc.C) {
+	addSubnets(c, s.State, "10.0.1.0/24", "fc00::/64")
+	space, err := s.State.AddSpace("internal", []string{"10.0.1.0/24", "fc00::/64"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(space.Contains(network.NewAddress("10.0.1.5", network.ScopeCloudLocal)), jc.IsTrue)
+	c.Assert(space.Contains(network.NewAddress("fc00::5", network.ScopeCloudLocal)), jc.IsTrue)
+	c.Assert(space.Contains(network.NewAddress("10.0.2.5", network.ScopeCloudLocal)), jc.IsFalse)
+	c.Assert(space.Contains(network.NewAddress("example.com", network.ScopePublic)), jc.IsFalse)
+}
+
+func (s *SpaceSuite) TestIsValidSpaceName(c *gc.C) {
+	c.Assert(state.IsValidSpaceName("db-1"), jc.IsTrue)
+	c.Assert(state.IsValidSpaceName("db_1"), jc.IsFalse)
+	c.Assert(state.IsValidSpaceName("-db"), jc.IsFalse)
+	c.Assert(state.IsValidSpaceName(""), jc.IsFalse)
+}
@@ -0,0 +1,221 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/instance"
+)
+
+// spareProfile describes the kind of machine kept in the spare pool:
+// every distinct series and set of resolved constraints in use in the
+// environment gets its own share of spares.
+type spareProfile struct {
+	series      string
+	constraints constraints.Value
+}
+
+func (p spareProfile) key() string {
+	return p.series + " " + p.constraints.String()
+}
+
+// EnsureSpareMachines brings the pool of spare machines in line with
+// the environment's spare-machines setting. Spares that have since
+// been used to host units or containers leave the pool, each profile
+// in use is topped up to the configured number of spares, and spares
+// that are no longer needed are destroyed.
+func (st *State) EnsureSpareMachines() error {
+	cfg, err := st.EnvironConfig()
+	if err != nil {
+		return err
+	}
+	count := cfg.SpareMachines()
+	profiles, err := st.spareProfiles(cfg)
+	if err != nil {
+		return err
+	}
+
+	machines, err := st.spareMachines()
+	if err != nil {
+		return err
+	}
+	pool := make(map[string][]*Machine)
+	for _, m := range machines {
+		if !m.doc.Clean || len(m.doc.Principals) > 0 {
+			if err := m.clearSpare(); err != nil {
+				return errors.Annotatef(err, "cannot remove machine %s from spare pool", m.Id())
+			}
+			continue
+		}
+		if m.Life() != Alive {
+			continue
+		}
+		cons, err := m.Constraints()
+		if err != nil {
+			return err
+		}
+		key := spareProfile{m.Series(), cons}.key()
+		pool[key] = append(pool[key], m)
+	}
+
+	var surplus []*Machine
+	for key, spares := range pool {
+		if _, ok := profiles[key]; !ok {
+			surplus = append(surplus, spares...)
+		}
+	}
+	keys := make([]string, 0, len(profiles))
+	for key := range profiles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		spares := pool[key]
+		if len(spares) > count {
+			// Give up unprovisioned spares first; they are
+			// the furthest from being ready for a unit.
+			sort.Stable(byProvisioned(spares))
+			surplus = append(surplus, spares[count:]...)
+			continue
+		}
+		profile := profiles[key]
+		for i := len(spares); i < count; i++ {
+			m, err := st.AddOneMachine(MachineTemplate{
+				Series:      profile.series,
+				Constraints: profile.constraints,
+				Jobs:        []MachineJob{JobHostUnits},
+				Spare:       true,
+			})
+			if err != nil {
+				return errors.Annotatef(err, "cannot add spare machine for %q", key)
+			}
+			logger.Infof("added spare machine %s (%s)", m.Id(), key)
+		}
+	}
+	for _, m := range surplus {
+		if err := m.Destroy(); err != nil {
+			logger.Warningf("cannot destroy surplus spare machine %s: %v", m.Id(), err)
+			continue
+		}
+		logger.Infof("destroyed surplus spare machine %s", m.Id())
+	}
+	return nil
+}
+
+// spareProfiles returns the profiles for which spare machines should
+// be kept, keyed by profile key. These are the environment's default
+// series and constraints, and those of every alive principal service
+// not deployed into containers. No profiles are returned when the
+// spare pool is disabled.
+func (st *State) spareProfiles(cfg *config.Config) (map[string]spareProfile, error) {
+	profiles := make(map[string]spareProfile)
+	if cfg.SpareMachines() == 0 {
+		return profiles, nil
+	}
+	add := func(series string, cons constraints.Value) error {
+		cons, err := st.resolveConstraints(cons)
+		if err != nil {
+			return err
+		}
+		if cons.HasContainer() {
+			return nil
+		}
+		// Machine constraints never carry a container value; see
+		// effectiveMachineTemplate.
+		cons.Container = nil
+		profile := spareProfile{series, cons}
+		profiles[profile.key()] = profile
+		return nil
+	}
+	if err := add(config.PreferredSeries(cfg), constraints.Value{}); err != nil {
+		return nil, err
+	}
+	services, err := st.AllServices()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		if s.Life() != Alive || !s.IsPrincipal() {
+			continue
+		}
+		cons, err := s.Constraints()
+		if err != nil {
+			return nil, err
+		}
+		if err := add(s.doc.Series, cons); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+// spareMachines returns all machines in the spare pool.
+func (st *State) spareMachines() ([]*Machine, error) {
+	machinesCollection, closer := st.getCollection(machinesC)
+	defer closer()
+
+	var docs []machineDoc
+	if err := machinesCollection.Find(bson.D{{"spare", true}}).All(&docs); err != nil {
+		return nil, errors.Annotate(err, "cannot get spare machines")
+	}
+	machines := make([]*Machine, len(docs))
+	for i := range docs {
+		machines[i] = newMachine(st, &docs[i])
+	}
+	return machines, nil
+}
+
+// clearSpare removes the machine from the spare pool.
+func (m *Machine) clearSpare() error {
+	ops := []txn.Op{{
+		C:      machinesC,
+		Id:     m.doc.Id,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$unset", bson.D{{"spare", nil}}}},
+	}}
+	if err := m.st.runTransaction(ops); err != nil && err != txn.ErrAborted {
+		return err
+	}
+	m.doc.Spare = false
+	return nil
+}
+
+// sparesFirst reorders machines so that spare machines come before
+// all others, otherwise preserving their order.
+func sparesFirst(machines []*Machine) []*Machine {
+	result := make([]*Machine, 0, len(machines))
+	for _, m := range machines {
+		if m.doc.Spare {
+			result = append(result, m)
+		}
+	}
+	for _, m := range machines {
+		if !m.doc.Spare {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// byProvisioned sorts machines so that provisioned machines come
+// before unprovisioned ones.
+type byProvisioned []*Machine
+
+func (b byProvisioned) Len() int      { return len(b) }
+func (b byProvisioned) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byProvisioned) Less(i, j int) bool {
+	return isProvisioned(b[i]) && !isProvisioned(b[j])
+}
+
+func isProvisioned(m *Machine) bool {
+	id, err := m.InstanceId()
+	return err == nil && id != instance.Id("")
+}
@@ -0,0 +1,172 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/testing"
+)
+
+type SpareMachinesSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&SpareMachinesSuite{})
+
+func (s *SpareMachinesSuite) setSpareMachines(c *gc.C, n int) {
+	err := s.State.UpdateEnvironConfig(map[string]interface{}{"spare-machines": n}, nil, nil)
+	c.Assert(err, gc.IsNil)
+}
+
+// spares returns the alive spare machines, grouped by series.
+func (s *SpareMachinesSuite) spares(c *gc.C) map[string][]*state.Machine {
+	machines, err := s.State.AllMachines()
+	c.Assert(err, gc.IsNil)
+	result := make(map[string][]*state.Machine)
+	for _, m := range machines {
+		if m.IsSpare() && m.Life() == state.Alive {
+			result[m.Series()] = append(result[m.Series()], m)
+		}
+	}
+	return result
+}
+
+func (s *SpareMachinesSuite) TestDisabledByDefault(c *gc.C) {
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	err := s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	machines, err := s.State.AllMachines()
+	c.Assert(err, gc.IsNil)
+	c.Assert(machines, gc.HasLen, 0)
+}
+
+func (s *SpareMachinesSuite) TestEnsureSpareMachines(c *gc.C) {
+	s.setSpareMachines(c, 2)
+	err := s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	spares := s.spares(c)
+	c.Assert(spares, gc.HasLen, 1)
+	c.Assert(spares["precise"], gc.HasLen, 2)
+	for _, m := range spares["precise"] {
+		c.Assert(m.Jobs(), gc.DeepEquals, []state.MachineJob{state.JobHostUnits})
+	}
+
+	// A service with its own series gets its own spares.
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	err = s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	spares = s.spares(c)
+	c.Assert(spares, gc.HasLen, 2)
+	c.Assert(spares["precise"], gc.HasLen, 2)
+	c.Assert(spares["quantal"], gc.HasLen, 2)
+
+	// Running again changes nothing.
+	err = s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	machines, err := s.State.AllMachines()
+	c.Assert(err, gc.IsNil)
+	c.Assert(machines, gc.HasLen, 4)
+}
+
+func (s *SpareMachinesSuite) TestAssignPrefersSpares(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	_, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	s.setSpareMachines(c, 1)
+	err = s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	spare := s.spares(c)["quantal"][0]
+
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, gc.IsNil)
+	m, err := unit.AssignToCleanEmptyMachine()
+	c.Assert(err, gc.IsNil)
+	c.Assert(m.Id(), gc.Equals, spare.Id())
+	c.Assert(m.IsSpare(), gc.Equals, false)
+
+	// The used spare leaves the pool as it is assigned, and is
+	// replaced.
+	err = spare.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(spare.IsSpare(), gc.Equals, false)
+	err = s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	err = spare.Refresh()
+	c.Assert(err, gc.IsNil)
+	c.Assert(spare.IsSpare(), gc.Equals, false)
+	spares := s.spares(c)["quantal"]
+	c.Assert(spares, gc.HasLen, 1)
+	c.Assert(spares[0].Id(), gc.Not(gc.Equals), spare.Id())
+}
+
+func (s *SpareMachinesSuite) TestSurplusSparesDestroyed(c *gc.C) {
+	s.setSpareMachines(c, 3)
+	err := s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.spares(c)["precise"], gc.HasLen, 3)
+
+	s.setSpareMachines(c, 1)
+	err = s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.spares(c)["precise"], gc.HasLen, 1)
+
+	s.setSpareMachines(c, 0)
+	err = s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.spares(c), gc.HasLen, 0)
+}
+
+func (s *SpareMachinesSuite) TestWatchSpareMachines(c *gc.C) {
+	s.setSpareMachines(c, 1)
+	err := s.State.EnsureSpareMachines()
+	c.Assert(err, gc.IsNil)
+	spare := s.spares(c)["precise"][0]
+
+	w := s.State.WatchSpareMachines()
+	defer testing.AssertStop(c, w)
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	// Changes to machines outside the pool are ignored.
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = m.SetConstraints(constraints.MustParse("mem=4G"))
+	c.Assert(err, gc.IsNil)
+	err = m.SetProvisioned("i-0", "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	wc.AssertNoChange()
+
+	// As are changes to spare machines that do not affect the pool.
+	err = spare.SetProvisioned("i-1", "fake_nonce", nil)
+	c.Assert(err, gc.IsNil)
+	wc.AssertNoChange()
+
+	// Adding a principal service is noticed, but adding its units
+	// is not.
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	wc.AssertOneChange()
+	_, err = wordpress.AddUnit()
+	c.Assert(err, gc.IsNil)
+	wc.AssertNoChange()
+
+	// Changing the service's constraints is noticed.
+	err = wordpress.SetConstraints(constraints.MustParse("mem=4G"))
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	// A spare machine leaving the pool is noticed.
+	err = spare.Destroy()
+	c.Assert(err, gc.IsNil)
+	wc.AssertOneChange()
+
+	// Environment settings are only noticed when they affect the pool.
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{"logging-config": "<root>=DEBUG"}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	wc.AssertNoChange()
+	s.setSpareMachines(c, 2)
+	wc.AssertOneChange()
+}
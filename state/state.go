@@ -52,6 +52,8 @@ const (
 	requestedNetworksC = "requestednetworks"
 	networksC          = "networks"
 	networkInterfacesC = "networkinterfaces"
+	spacesC            = "spaces"
+	subnetsC           = "subnets"
 	minUnitsC          = "minunits"
 	settingsC          = "settings"
 	settingsrefsC      = "settingsrefs"
@@ -64,8 +66,14 @@ const (
 	cleanupsC          = "cleanups"
 	annotationsC       = "annotations"
 	statusesC          = "statuses"
+	statusHistoryC     = "statuseshistory"
 	stateServersC      = "stateServers"
 	openedPortsC       = "openedPorts"
+	hookExecutionsC    = "hookexecutions"
+	runJobsC           = "runjobs"
+	runOutputC         = "runoutput"
+	retainedInstancesC = "retainedinstances"
+	ipAddressesC       = "ipaddresses"
 
 	// These collections are used by the mgo transaction runner.
 	txnLogC = "txns.log"
@@ -996,8 +1004,10 @@ func (st *State) addPeerRelationsOps(serviceName string, peers map[string]charm.
 
 // AddService creates a new service, running the supplied charm, with the
 // supplied name (which must be unique). If the charm defines peer relations,
-// they will be created automatically.
-func (st *State) AddService(name, ownerTag string, ch *Charm, networks []string) (service *Service, err error) {
+// they will be created automatically. The service's endpoints are bound
+// to spaces as given by bindings, which holds space names keyed by
+// endpoint name; every endpoint and space must exist.
+func (st *State) AddService(name, ownerTag string, ch *Charm, networks []string, bindings map[string]string) (service *Service, err error) {
 	defer errors.Maskf(&err, "cannot add service %q", name)
 	tag, err := names.ParseUserTag(ownerTag)
 	if err != nil {
@@ -1038,7 +1048,17 @@ func (st *State) AddService(name, ownerTag string, ch *Charm, networks []string)
 		Life:          Alive,
 		OwnerTag:      ownerTag,
 	}
+	if len(bindings) > 0 {
+		svcDoc.EndpointBindings = make(map[string]string)
+		for endpoint, space := range bindings {
+			svcDoc.EndpointBindings[endpoint] = space
+		}
+	}
 	svc := newService(st, svcDoc)
+	bindingsOps, err := svc.endpointBindingsOps(bindings)
+	if err != nil {
+		return nil, err
+	}
 	ops := []txn.Op{
 		env.assertAliveOp(),
 		createConstraintsOp(st, svc.globalKey(), constraints.Value{}),
@@ -1071,6 +1091,7 @@ func (st *State) AddService(name, ownerTag string, ch *Charm, networks []string)
 		return nil, err
 	}
 	ops = append(ops, peerOps...)
+	ops = append(ops, bindingsOps...)
 
 	if err := st.runTransaction(ops); err == txn.ErrAborted {
 		err := env.Refresh()
@@ -1086,6 +1107,10 @@ func (st *State) AddService(name, ownerTag string, ch *Charm, networks []string)
 			return nil, fmt.Errorf("unknown user %q", ownerId)
 		}
 
+		if _, err := svc.endpointBindingsOps(bindings); err != nil {
+			return nil, err
+		}
+
 		return nil, fmt.Errorf("service already exists")
 	} else if err != nil {
 		return nil, err
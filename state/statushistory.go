@@ -0,0 +1,116 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/state/api/params"
+)
+
+// maxStatusHistory is the number of statuses kept in the history of
+// each entity; older ones are discarded as new ones are recorded.
+var maxStatusHistory = 100
+
+// StatusHistoryEntry describes a status an entity had.
+type StatusHistoryEntry struct {
+	Status  params.Status
+	Info    string
+	Data    params.StatusData
+	Updated time.Time
+}
+
+// statusHistoryDoc records a status of the entity with the given
+// global key.
+type statusHistoryDoc struct {
+	Id         bson.ObjectId `bson:"_id"`
+	GlobalKey  string
+	Status     params.Status
+	StatusInfo string
+	StatusData params.StatusData `bson:",omitempty"`
+	Updated    time.Time
+}
+
+// addStatusHistory records the given status in the history of the
+// entity with the given global key. Only the most recent statuses
+// are kept.
+func (st *State) addStatusHistory(globalKey string, doc statusDoc) error {
+	statusHistory, closer := st.getCollection(statusHistoryC)
+	defer closer()
+
+	// Status history documents are not otherwise referenced in the
+	// system, and are not under watch, and are therefore safe to
+	// write and delete directly.
+	err := statusHistory.Insert(&statusHistoryDoc{
+		Id:         bson.NewObjectId(),
+		GlobalKey:  globalKey,
+		Status:     doc.Status,
+		StatusInfo: doc.StatusInfo,
+		StatusData: doc.StatusData,
+		Updated:    time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot record status history for %q: %v", globalKey, err)
+	}
+	var docs []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}
+	err = statusHistory.Find(bson.D{{"globalkey", globalKey}}).
+		Sort("-updated", "-_id").
+		Skip(maxStatusHistory).
+		Select(bson.D{{"_id", 1}}).
+		All(&docs)
+	if err != nil {
+		return fmt.Errorf("cannot prune status history for %q: %v", globalKey, err)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	ids := make([]bson.ObjectId, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Id
+	}
+	if _, err := statusHistory.RemoveAll(bson.D{{"_id", bson.D{{"$in", ids}}}}); err != nil {
+		return fmt.Errorf("cannot prune status history for %q: %v", globalKey, err)
+	}
+	return nil
+}
+
+// statusHistory returns the recorded statuses of the entity with the
+// given global key, oldest first.
+func (st *State) statusHistory(globalKey string) ([]StatusHistoryEntry, error) {
+	statusHistory, closer := st.getCollection(statusHistoryC)
+	defer closer()
+
+	var docs []statusHistoryDoc
+	err := statusHistory.Find(bson.D{{"globalkey", globalKey}}).Sort("updated", "_id").All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get status history for %q: %v", globalKey, err)
+	}
+	entries := make([]StatusHistoryEntry, len(docs))
+	for i, doc := range docs {
+		entries[i] = StatusHistoryEntry{
+			Status:  doc.Status,
+			Info:    doc.StatusInfo,
+			Data:    doc.StatusData,
+			Updated: doc.Updated.UTC(),
+		}
+	}
+	return entries, nil
+}
+
+// removeStatusHistory removes the status history of the entity with
+// the given global key.
+func (st *State) removeStatusHistory(globalKey string) error {
+	statusHistory, closer := st.getCollection(statusHistoryC)
+	defer closer()
+
+	if _, err := statusHistory.RemoveAll(bson.D{{"globalkey", globalKey}}); err != nil {
+		return fmt.Errorf("cannot remove status history for %q: %v", globalKey, err)
+	}
+	return nil
+}
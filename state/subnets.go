@@ -0,0 +1,224 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+// Subnet represents a subnet discovered in the provider.
+type Subnet struct {
+	st  *State
+	doc subnetDoc
+}
+
+// SubnetInfo describes a single subnet.
+type SubnetInfo struct {
+	// CIDR of the subnet, in 123.45.67.0/24 format.
+	CIDR string
+
+	// ProviderId is a provider-specific subnet id.
+	ProviderId network.Id
+
+	// VLANTag needs to be between 1 and 4094 for VLANs and 0 for
+	// normal networks. It's defined by IEEE 802.1Q standard.
+	VLANTag int
+
+	// AvailabilityZones holds the names of the availability zones
+	// the subnet spans, if any.
+	AvailabilityZones []string
+}
+
+// noSpaceDoc asserts that a subnet is in no space.
+var noSpaceDoc = bson.D{{"spacename", ""}}
+
+// subnetDoc represents a subnet in MongoDB.
+type subnetDoc struct {
+	CIDR              string `bson:"_id"`
+	ProviderId        network.Id
+	VLANTag           int
+	AvailabilityZones []string `bson:",omitempty"`
+
+	// SpaceName holds the name of the space the subnet is in, or
+	// is empty if the subnet is in no space.
+	SpaceName string
+}
+
+func newSubnet(st *State, doc *subnetDoc) *Subnet {
+	return &Subnet{st, *doc}
+}
+
+// CIDR returns the subnet's CIDR, in 123.45.67.0/24 format.
+func (s *Subnet) CIDR() string {
+	return s.doc.CIDR
+}
+
+// String returns the subnet's CIDR.
+func (s *Subnet) String() string {
+	return s.doc.CIDR
+}
+
+// ProviderId returns the provider-specific id of the subnet.
+func (s *Subnet) ProviderId() network.Id {
+	return s.doc.ProviderId
+}
+
+// VLANTag returns the subnet's VLAN tag. It's 0 if the subnet is not
+// a VLAN.
+func (s *Subnet) VLANTag() int {
+	return s.doc.VLANTag
+}
+
+// AvailabilityZones returns the names of the availability zones the
+// subnet spans.
+func (s *Subnet) AvailabilityZones() []string {
+	return append([]string(nil), s.doc.AvailabilityZones...)
+}
+
+// SpaceName returns the name of the space the subnet is in, or an
+// empty string if it is in none.
+func (s *Subnet) SpaceName() string {
+	return s.doc.SpaceName
+}
+
+// AddSubnet records a subnet discovered in the provider. The subnet is
+// identified by its CIDR; if a subnet with the same CIDR already
+// exists, an error satisfying errors.IsAlreadyExists is returned.
+func (st *State) AddSubnet(args SubnetInfo) (subnet *Subnet, err error) {
+	defer errors.Contextf(&err, "cannot add subnet %q", args.CIDR)
+	_, ipNet, err := net.ParseCIDR(args.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	args.CIDR = ipNet.String()
+	if err := validateSubnetInfo(args); err != nil {
+		return nil, err
+	}
+	doc := &subnetDoc{
+		CIDR:              args.CIDR,
+		ProviderId:        args.ProviderId,
+		VLANTag:           args.VLANTag,
+		AvailabilityZones: args.AvailabilityZones,
+	}
+	ops := []txn.Op{{
+		C:      subnetsC,
+		Id:     args.CIDR,
+		Assert: txn.DocMissing,
+		Insert: doc,
+	}}
+	err = st.runTransaction(ops)
+	if err == txn.ErrAborted {
+		return nil, errors.AlreadyExistsf("subnet %q", args.CIDR)
+	} else if err != nil {
+		return nil, err
+	}
+	return newSubnet(st, doc), nil
+}
+
+// validateSubnetInfo checks the provider details of a subnet.
+func validateSubnetInfo(args SubnetInfo) error {
+	if args.ProviderId == "" {
+		return fmt.Errorf("provider id must be not empty")
+	}
+	if args.VLANTag < 0 || args.VLANTag > 4094 {
+		return fmt.Errorf("invalid VLAN tag %d: must be between 0 and 4094", args.VLANTag)
+	}
+	return nil
+}
+
+// Update replaces the provider details of the subnet with those in
+// args, as the provider's view of a subnet may change over time. The
+// CIDR in args is ignored, and the space the subnet is in is left
+// unchanged.
+func (s *Subnet) Update(args SubnetInfo) (err error) {
+	defer errors.Contextf(&err, "cannot update subnet %q", s.doc.CIDR)
+	if err := validateSubnetInfo(args); err != nil {
+		return err
+	}
+	ops := []txn.Op{{
+		C:      subnetsC,
+		Id:     s.doc.CIDR,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{
+			{"providerid", args.ProviderId},
+			{"vlantag", args.VLANTag},
+			{"availabilityzones", args.AvailabilityZones},
+		}}},
+	}}
+	err = s.st.runTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.NotFoundf("subnet %q", s.doc.CIDR)
+	} else if err != nil {
+		return err
+	}
+	s.doc.ProviderId = args.ProviderId
+	s.doc.VLANTag = args.VLANTag
+	s.doc.AvailabilityZones = args.AvailabilityZones
+	return nil
+}
+
+// Remove removes the subnet, which must not be in a space. Removing a
+// subnet that has already been removed is not an error.
+func (s *Subnet) Remove() (err error) {
+	defer errors.Contextf(&err, "cannot remove subnet %q", s.doc.CIDR)
+	ops := []txn.Op{{
+		C:      subnetsC,
+		Id:     s.doc.CIDR,
+		Assert: noSpaceDoc,
+		Remove: true,
+	}}
+	err = s.st.runTransaction(ops)
+	if err != txn.ErrAborted {
+		return err
+	}
+	subnet, err := s.st.Subnet(s.doc.CIDR)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return fmt.Errorf("subnet is in space %q", subnet.SpaceName())
+}
+
+// Subnet returns the subnet with the given CIDR.
+func (st *State) Subnet(cidr string) (*Subnet, error) {
+	subnets, closer := st.getCollection(subnetsC)
+	defer closer()
+
+	doc := &subnetDoc{}
+	err := subnets.FindId(cidr).One(doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("subnet %q", cidr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get subnet %q: %v", cidr, err)
+	}
+	return newSubnet(st, doc), nil
+}
+
+// AllSubnets returns all the subnets in the environment, sorted by
+// CIDR.
+func (st *State) AllSubnets() ([]*Subnet, error) {
+	subnetsCollection, closer := st.getCollection(subnetsC)
+	defer closer()
+
+	docs := []subnetDoc{}
+	err := subnetsCollection.Find(nil).Sort("_id").All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get all subnets: %v", err)
+	}
+	subnets := make([]*Subnet, len(docs))
+	for i := range docs {
+		subnets[i] = newSubnet(st, &docs[i])
+	}
+	return subnets, nil
+}
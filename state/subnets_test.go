@@ -0,0 +1,147 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+)
+
+type SubnetSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&SubnetSuite{})
+
+func (s *SubnetSuite) TestAddSubnet(c *gc.C) {
+	subnet, err := s.State.AddSubnet(state.SubnetInfo{
+		CIDR:              "10.0.1.7/24",
+		ProviderId:        "subnet-1",
+		VLANTag:           42,
+		AvailabilityZones: []string{"zone1"},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.CIDR(), gc.Equals, "10.0.1.0/24")
+	c.Assert(subnet.ProviderId(), gc.Equals, network.Id("subnet-1"))
+	c.Assert(subnet.VLANTag(), gc.Equals, 42)
+	c.Assert(subnet.AvailabilityZones(), jc.DeepEquals, []string{"zone1"})
+
+	subnet, err = s.State.Subnet("10.0.1.0/24")
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.ProviderId(), gc.Equals, network.Id("subnet-1"))
+	c.Assert(subnet.VLANTag(), gc.Equals, 42)
+	c.Assert(subnet.AvailabilityZones(), jc.DeepEquals, []string{"zone1"})
+
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-2"})
+	c.Assert(err, gc.ErrorMatches, `cannot add subnet "10.0.1.0/24": subnet "10.0.1.0/24" already exists`)
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *SubnetSuite) TestAddSubnetInvalid(c *gc.C) {
+	for i, test := range []struct {
+		args state.SubnetInfo
+		err  string
+	}{{
+		args: state.SubnetInfo{CIDR: "10.0.1.0", ProviderId: "subnet-1"},
+		err:  `cannot add subnet "10.0.1.0": invalid CIDR address: 10.0.1.0`,
+	}, {
+		args: state.SubnetInfo{CIDR: "10.0.1.0/24"},
+		err:  `cannot add subnet "10.0.1.0/24": provider id must be not empty`,
+	}, {
+		args: state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1", VLANTag: 4095},
+		err:  `cannot add subnet "10.0.1.0/24": invalid VLAN tag 4095: must be between 0 and 4094`,
+	}} {
+		c.Logf("test %d: %+v", i, test.args)
+		_, err := s.State.AddSubnet(test.args)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *SubnetSuite) TestSubnetNotFound(c *gc.C) {
+	_, err := s.State.Subnet("10.0.1.0/24")
+	c.Assert(err, gc.ErrorMatches, `subnet "10.0.1.0/24" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *SubnetSuite) TestAllSubnets(c *gc.C) {
+	subnets, err := s.State.AllSubnets()
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnets, gc.HasLen, 0)
+
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.2.0/24", ProviderId: "subnet-2"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+
+	subnets, err = s.State.AllSubnets()
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnets, gc.HasLen, 2)
+	c.Assert(subnets[0].CIDR(), gc.Equals, "10.0.1.0/24")
+	c.Assert(subnets[1].CIDR(), gc.Equals, "10.0.2.0/24")
+}
+
+func (s *SubnetSuite) TestUpdate(c *gc.C) {
+	subnet, err := s.State.AddSubnet(state.SubnetInfo{
+		CIDR:              "10.0.1.0/24",
+		ProviderId:        "subnet-1",
+		AvailabilityZones: []string{"zone1"},
+	})
+	c.Assert(err, gc.IsNil)
+	err = subnet.Update(state.SubnetInfo{
+		ProviderId:        "subnet-2",
+		VLANTag:           42,
+		AvailabilityZones: []string{"zone1", "zone2"},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.ProviderId(), gc.Equals, network.Id("subnet-2"))
+
+	subnet, err = s.State.Subnet("10.0.1.0/24")
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.ProviderId(), gc.Equals, network.Id("subnet-2"))
+	c.Assert(subnet.VLANTag(), gc.Equals, 42)
+	c.Assert(subnet.AvailabilityZones(), jc.DeepEquals, []string{"zone1", "zone2"})
+
+	err = subnet.Update(state.SubnetInfo{ProviderId: "subnet-2", VLANTag: 4095})
+	c.Assert(err, gc.ErrorMatches, `cannot update subnet "10.0.1.0/24": invalid VLAN tag 4095: must be between 0 and 4094`)
+}
+
+func (s *SubnetSuite) TestUpdateKeepsSpace(c *gc.C) {
+	subnet, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = subnet.Update(state.SubnetInfo{ProviderId: "subnet-2"})
+	c.Assert(err, gc.IsNil)
+
+	subnet, err = s.State.Subnet("10.0.1.0/24")
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.SpaceName(), gc.Equals, "internal")
+}
+
+func (s *SubnetSuite) TestRemove(c *gc.C) {
+	subnet, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	err = subnet.Remove()
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.Subnet("10.0.1.0/24")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	err = subnet.Remove()
+	c.Assert(err, gc.IsNil)
+	err = subnet.Update(state.SubnetInfo{ProviderId: "subnet-1"})
+	c.Assert(err, gc.ErrorMatches, `cannot update subnet "10.0.1.0/24": subnet "10.0.1.0/24" not found`)
+}
+
+func (s *SubnetSuite) TestRemoveInSpace(c *gc.C) {
+	subnet, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = subnet.Remove()
+	c.Assert(err, gc.ErrorMatches, `cannot remove subnet "10.0.1.0/24": subnet is in space "internal"`)
+}
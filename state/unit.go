@@ -1125,12 +1125,18 @@ func (u *Unit) assignToMachine(m *Machine, unused bool) (err error) {
 		C:      machinesC,
 		Id:     m.doc.Id,
 		Assert: massert,
-		Update: bson.D{{"$addToSet", bson.D{{"principals", u.doc.Name}}}, {"$set", bson.D{{"clean", false}}}},
+		// A machine hosting a unit is no longer spare.
+		Update: bson.D{
+			{"$addToSet", bson.D{{"principals", u.doc.Name}}},
+			{"$set", bson.D{{"clean", false}}},
+			{"$unset", bson.D{{"spare", nil}}},
+		},
 	}}
 	err = u.st.runTransaction(ops)
 	if err == nil {
 		u.doc.MachineId = m.doc.Id
 		m.doc.Clean = false
+		m.doc.Spare = false
 		return nil
 	}
 	if err != txn.ErrAborted {
@@ -1567,7 +1573,9 @@ func (u *Unit) assignToCleanMaybeEmptyMachine(requireEmpty bool) (m *Machine, er
 		}
 		machines[i] = m
 	}
-	machines = append(machines, unprovisioned...)
+	// Spare machines were provisioned for exactly this purpose,
+	// so take them ahead of others in the same partition.
+	machines = append(sparesFirst(machines), sparesFirst(unprovisioned)...)
 
 	// TODO(axw) 2014-05-30 #1253704
 	// We should not select a machine that is in the process
@@ -6,6 +6,7 @@ package state
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/mongo"
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/state/api/params"
 	"github.com/juju/juju/state/watcher"
 )
@@ -1571,6 +1573,265 @@ func (w *cleanupWatcher) loop() (err error) {
 	}
 }
 
+// spareMachinesWatcher notifies of changes that may affect the pool
+// of spare machines. Changes to machines and services that do not
+// affect the pool, such as those to machines outside it, or to the
+// units and relations of services, are ignored.
+type spareMachinesWatcher struct {
+	commonWatcher
+	out chan struct{}
+
+	// machines holds the state of each spare machine, and services
+	// the series and life of each principal service, as last seen.
+	machines map[string]string
+	services map[string]string
+
+	// environ holds the environment settings that affect the pool,
+	// as last seen.
+	environ string
+}
+
+var _ Watcher = (*spareMachinesWatcher)(nil)
+
+// WatchSpareMachines returns a NotifyWatcher that notifies when spare
+// machines, principal services, their constraints or the environment
+// configuration change in ways that may require the spare machine pool
+// to be brought up to date.
+func (st *State) WatchSpareMachines() NotifyWatcher {
+	return newSpareMachinesWatcher(st)
+}
+
+func newSpareMachinesWatcher(st *State) NotifyWatcher {
+	w := &spareMachinesWatcher{
+		commonWatcher: commonWatcher{st: st},
+		out:           make(chan struct{}),
+		machines:      make(map[string]string),
+		services:      make(map[string]string),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *spareMachinesWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+// spareMachineDoc holds the fields of a machine that affect the pool
+// of spare machines.
+type spareMachineDoc struct {
+	Id         string `bson:"_id"`
+	Spare      bool
+	Clean      bool
+	Life       Life
+	Principals []string
+}
+
+// spareServiceDoc holds the fields of a service that affect the pool
+// of spare machines.
+type spareServiceDoc struct {
+	Name        string `bson:"_id"`
+	Series      string
+	Subordinate bool
+	Life        Life
+}
+
+// updateMachines records the state of the given spare machines, or of
+// all of them if ids is nil, and reports whether it has changed.
+func (w *spareMachinesWatcher) updateMachines(ids map[interface{}]bool) (bool, error) {
+	machines, closer := w.st.getCollection(machinesC)
+	defer closer()
+
+	query := bson.D{{"spare", true}}
+	if ids != nil {
+		query = bson.D{{"_id", bson.D{{"$in", changedIds(ids)}}}}
+	}
+	var docs []spareMachineDoc
+	err := machines.Find(query).Select(bson.D{
+		{"spare", 1}, {"clean", 1}, {"life", 1}, {"principals", 1},
+	}).All(&docs)
+	if err != nil {
+		return false, err
+	}
+	current := make(map[string]string)
+	for _, doc := range docs {
+		if doc.Spare {
+			current[doc.Id] = fmt.Sprintf("%v %v %d", doc.Clean, doc.Life, len(doc.Principals))
+		}
+	}
+	return updateSnapshot(w.machines, current, ids), nil
+}
+
+// updateServices records the series and life of the given principal
+// services, or of all of them if ids is nil, and reports whether they
+// have changed.
+func (w *spareMachinesWatcher) updateServices(ids map[interface{}]bool) (bool, error) {
+	services, closer := w.st.getCollection(servicesC)
+	defer closer()
+
+	var query bson.D
+	if ids != nil {
+		query = bson.D{{"_id", bson.D{{"$in", changedIds(ids)}}}}
+	}
+	var docs []spareServiceDoc
+	err := services.Find(query).Select(bson.D{
+		{"series", 1}, {"subordinate", 1}, {"life", 1},
+	}).All(&docs)
+	if err != nil {
+		return false, err
+	}
+	current := make(map[string]string)
+	for _, doc := range docs {
+		if !doc.Subordinate {
+			current[doc.Name] = fmt.Sprintf("%s %v", doc.Series, doc.Life)
+		}
+	}
+	return updateSnapshot(w.services, current, ids), nil
+}
+
+// updateEnviron records the environment settings that affect the pool
+// of spare machines, and reports whether they have changed.
+func (w *spareMachinesWatcher) updateEnviron() (bool, error) {
+	cfg, err := w.st.EnvironConfig()
+	if err != nil {
+		return false, err
+	}
+	current := fmt.Sprintf("%d %s", cfg.SpareMachines(), config.PreferredSeries(cfg))
+	changed := current != w.environ
+	w.environ = current
+	return changed, nil
+}
+
+// updateSnapshot brings the entries of snapshot for the given ids, or
+// all of its entries if ids is nil, in line with current, and reports
+// whether any of them changed.
+func updateSnapshot(snapshot, current map[string]string, ids map[interface{}]bool) bool {
+	changed := false
+	if ids == nil {
+		for id := range snapshot {
+			if _, ok := current[id]; !ok {
+				delete(snapshot, id)
+				changed = true
+			}
+		}
+		for id, state := range current {
+			if old, ok := snapshot[id]; !ok || old != state {
+				snapshot[id] = state
+				changed = true
+			}
+		}
+		return changed
+	}
+	for id := range ids {
+		id, ok := id.(string)
+		if !ok {
+			continue
+		}
+		old, wasKnown := snapshot[id]
+		state, isKnown := current[id]
+		if wasKnown != isKnown || old != state {
+			changed = true
+		}
+		if isKnown {
+			snapshot[id] = state
+		} else {
+			delete(snapshot, id)
+		}
+	}
+	return changed
+}
+
+// changedIds returns the ids of the changed documents.
+func changedIds(ids map[interface{}]bool) []interface{} {
+	result := make([]interface{}, 0, len(ids))
+	for id := range ids {
+		result = append(result, id)
+	}
+	return result
+}
+
+func (w *spareMachinesWatcher) loop() (err error) {
+	machinesIn := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(machinesC, machinesIn)
+	defer w.st.watcher.UnwatchCollection(machinesC, machinesIn)
+	servicesIn := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(servicesC, servicesIn)
+	defer w.st.watcher.UnwatchCollection(servicesC, servicesIn)
+	constraintsIn := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(constraintsC, constraintsIn)
+	defer w.st.watcher.UnwatchCollection(constraintsC, constraintsIn)
+	settingsIn := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(settingsC, settingsIn)
+	defer w.st.watcher.UnwatchCollection(settingsC, settingsIn)
+
+	if _, err := w.updateMachines(nil); err != nil {
+		return err
+	}
+	if _, err := w.updateServices(nil); err != nil {
+		return err
+	}
+	if _, err := w.updateEnviron(); err != nil {
+		return err
+	}
+	out := w.out
+	for {
+		var changed bool
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.st.watcher.Dead():
+			return stateWatcherDeadError(w.st.watcher.Err())
+		case ch := <-machinesIn:
+			ids, ok := collect(ch, machinesIn, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			if changed, err = w.updateMachines(ids); err != nil {
+				return err
+			}
+		case ch := <-servicesIn:
+			ids, ok := collect(ch, servicesIn, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			if changed, err = w.updateServices(ids); err != nil {
+				return err
+			}
+		case ch := <-constraintsIn:
+			ids, ok := collect(ch, constraintsIn, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			// Only the constraints of the environment and of
+			// services affect the pool; those of machines do not.
+			for id := range ids {
+				if id, ok := id.(string); ok && (id == environGlobalKey || strings.HasPrefix(id, "s#")) {
+					changed = true
+				}
+			}
+		case ch := <-settingsIn:
+			ids, ok := collect(ch, settingsIn, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			if _, ok := ids[environGlobalKey]; ok {
+				if changed, err = w.updateEnviron(); err != nil {
+					return err
+				}
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+		if changed {
+			out = w.out
+		}
+	}
+}
+
 // idPrefixWatcher is a StringsWatcher that watches for changes on the
 // specified collection that match common prefixes
 type idPrefixWatcher struct {
@@ -1891,3 +2152,227 @@ func (w *machineInterfacesWatcher) loop() error {
 		}
 	}
 }
+
+// machineIngressRulesWatcher notifies about changes to the ingress
+// rules a host-based firewall on a machine should apply. See
+// Machine.IngressRules.
+type machineIngressRulesWatcher struct {
+	commonWatcher
+	machine *Machine
+	out     chan struct{}
+}
+
+var _ NotifyWatcher = (*machineIngressRulesWatcher)(nil)
+
+// WatchIngressRules returns a new NotifyWatcher watching the ingress
+// rules of m.
+func (m *Machine) WatchIngressRules() NotifyWatcher {
+	w := &machineIngressRulesWatcher{
+		commonWatcher: commonWatcher{st: m.st},
+		machine:       m,
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *machineIngressRulesWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+// sameIngressRules returns whether old and new contain the same rules.
+// Both old and new must be sorted.
+func sameIngressRules(old, new []network.IngressRule) bool {
+	if len(old) != len(new) {
+		return false
+	}
+	for i, rule := range old {
+		if new[i] != rule {
+			return false
+		}
+	}
+	return true
+}
+
+// samePorts returns whether old and new contain the same ports.
+// Both old and new must be sorted.
+func samePorts(old, new []network.Port) bool {
+	if len(old) != len(new) {
+		return false
+	}
+	for i, port := range old {
+		if new[i] != port {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *machineIngressRulesWatcher) loop() error {
+	// The rules depend on the machine's units, their opened ports,
+	// their services' exposure and the addresses of all machines.
+	// Changes to other units, services and machines' ports are
+	// ignored, and the addresses of all machines are cached so that
+	// a change to another machine only requires reading that machine.
+	unitsCh := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(unitsC, unitsCh)
+	defer w.st.watcher.UnwatchCollection(unitsC, unitsCh)
+	servicesCh := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(servicesC, servicesCh)
+	defer w.st.watcher.UnwatchCollection(servicesC, servicesCh)
+	portsCh := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(openedPortsC, portsCh)
+	defer w.st.watcher.UnwatchCollection(openedPortsC, portsCh)
+	machinesCh := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(machinesC, machinesCh)
+	defer w.st.watcher.UnwatchCollection(machinesC, machinesCh)
+
+	machines, err := w.st.AllMachines()
+	if err != nil {
+		return err
+	}
+	machineCIDRs := make(map[string][]string)
+	for _, m := range machines {
+		machineCIDRs[m.Id()] = addressSourceCIDRs(m.Addresses())
+	}
+	rules, ports, deps, err := w.machine.ingressRules(flattenSourceCIDRs(machineCIDRs))
+	if err != nil {
+		return err
+	}
+	out := w.out
+	for {
+		var changed bool
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.st.watcher.Dead():
+			return stateWatcherDeadError(w.st.watcher.Err())
+		case ch := <-unitsCh:
+			ids, ok := collect(ch, unitsCh, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			changed = hasDependency(ids, deps)
+		case ch := <-servicesCh:
+			ids, ok := collect(ch, servicesCh, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			changed = hasDependency(ids, deps)
+		case ch := <-portsCh:
+			ids, ok := collect(ch, portsCh, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			for id := range ids {
+				parts := portsIdRe.FindStringSubmatch(id.(string))
+				if len(parts) == 3 && parts[machineIdPart] == w.machine.Id() {
+					changed = true
+				}
+			}
+		case ch := <-machinesCh:
+			ids, ok := collect(ch, machinesCh, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			if changed, err = w.updateMachineCIDRs(machineCIDRs, ids); err != nil {
+				return err
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+		if !changed {
+			continue
+		}
+		latestRules, latestPorts, latestDeps, err := w.machine.ingressRules(flattenSourceCIDRs(machineCIDRs))
+		if err != nil {
+			return err
+		}
+		deps = latestDeps
+		if !sameIngressRules(rules, latestRules) || !samePorts(ports, latestPorts) {
+			rules, ports = latestRules, latestPorts
+			out = w.out
+		}
+	}
+}
+
+// updateMachineCIDRs updates the cached source CIDRs of the machines
+// with the given ids, and returns whether the rules may have changed
+// as a result.
+func (w *machineIngressRulesWatcher) updateMachineCIDRs(machineCIDRs map[string][]string, ids map[interface{}]bool) (bool, error) {
+	changed := false
+	for id, exists := range ids {
+		id := id.(string)
+		if id == w.machine.Id() {
+			// The units assigned to the machine may have changed.
+			changed = true
+		}
+		var latest []string
+		if exists {
+			m, err := w.st.Machine(id)
+			if errors.IsNotFound(err) {
+				exists = false
+			} else if err != nil {
+				return false, err
+			} else {
+				latest = addressSourceCIDRs(m.Addresses())
+			}
+		}
+		old, known := machineCIDRs[id]
+		if !exists {
+			if known {
+				delete(machineCIDRs, id)
+				changed = true
+			}
+			continue
+		}
+		machineCIDRs[id] = latest
+		if !known || len(old) != len(latest) {
+			changed = true
+			continue
+		}
+		for i, cidr := range old {
+			if latest[i] != cidr {
+				changed = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// flattenSourceCIDRs returns the unique CIDRs of all machines, in
+// machine id order.
+func flattenSourceCIDRs(machineCIDRs map[string][]string) []string {
+	ids := make([]string, 0, len(machineCIDRs))
+	for id := range machineCIDRs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var cidrs []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		for _, cidr := range machineCIDRs[id] {
+			if !seen[cidr] {
+				seen[cidr] = true
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	return cidrs
+}
+
+// hasDependency returns whether any of the changed document ids is
+// one of the given dependencies.
+func hasDependency(ids map[interface{}]bool, deps map[string]bool) bool {
+	for id := range ids {
+		if name, ok := id.(string); ok && deps[name] {
+			return true
+		}
+	}
+	return false
+}
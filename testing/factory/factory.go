@@ -244,7 +244,7 @@ func (factory *Factory) MakeService(vParams ...ServiceParams) *state.Service {
 		creator := factory.MakeUser()
 		params.Creator = creator.Tag().String()
 	}
-	service, err := factory.st.AddService(params.Name, params.Creator, params.Charm, nil)
+	service, err := factory.st.AddService(params.Name, params.Creator, params.Charm, nil, nil)
 	factory.c.Assert(err, gc.IsNil)
 	return service
 }
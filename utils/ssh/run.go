@@ -6,6 +6,7 @@ package ssh
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 	"syscall"
@@ -20,13 +21,22 @@ type ExecParams struct {
 	Host         string
 	Command      string
 	Timeout      time.Duration
+
+	// Stdout and Stderr, if set, are written to as output
+	// arrives from the remote command, as well as being
+	// captured in the response.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Cancel, if set, stops the command early when it is closed.
+	Cancel <-chan struct{}
 }
 
 // ExecuteCommandOnMachine will execute the command passed through on
 // the host specified. This is done using ssh, and passing the commands
 // through /bin/bash.  If the command is not finished within the timeout
-// specified, an error is returned.  Any output captured during that time
-// is also returned in the remote response.
+// specified, or is cancelled, an error is returned.  Any output captured
+// during that time is also returned in the remote response.
 func ExecuteCommandOnMachine(params ExecParams) (result utilexec.ExecResponse, err error) {
 	// execute bash accepting commands on stdin
 	if params.Host == "" {
@@ -41,7 +51,13 @@ func ExecuteCommandOnMachine(params ExecParams) (result utilexec.ExecResponse, e
 	// start a go routine to do the actual execution
 	var stdout, stderr bytes.Buffer
 	command.Stdout = &stdout
+	if params.Stdout != nil {
+		command.Stdout = io.MultiWriter(&stdout, params.Stdout)
+	}
 	command.Stderr = &stderr
+	if params.Stderr != nil {
+		command.Stderr = io.MultiWriter(&stderr, params.Stderr)
+	}
 	command.Stdin = strings.NewReader(params.Command + "\n")
 
 	if err = command.Start(); err != nil {
@@ -72,6 +88,11 @@ func ExecuteCommandOnMachine(params ExecParams) (result utilexec.ExecResponse, e
 		logger.Infof("killing the command due to timeout")
 		err = fmt.Errorf("command timed out")
 		command.Kill()
+
+	case <-params.Cancel:
+		logger.Infof("killing the command due to cancellation")
+		err = fmt.Errorf("command cancelled")
+		command.Kill()
 	}
 	// In either case, gather as much as we have from stdout and stderr
 	command.Wait()
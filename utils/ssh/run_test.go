@@ -4,6 +4,7 @@
 package ssh_test
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -63,6 +64,25 @@ func (s *ExecuteSSHCommandSuite) TestCaptureOutput(c *gc.C) {
 		"-o StrictHostKeyChecking no -o PasswordAuthentication no -o ServerAliveInterval 30 hostname /bin/bash -s\n")
 }
 
+func (s *ExecuteSSHCommandSuite) TestStreamOutput(c *gc.C) {
+	s.fakeSSH(c, echoSSH)
+
+	var stdout, stderr bytes.Buffer
+	response, err := ssh.ExecuteCommandOnMachine(ssh.ExecParams{
+		Host:    "hostname",
+		Command: "hello",
+		Timeout: shortWait,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+
+	c.Assert(err, gc.IsNil)
+	c.Assert(stdout.String(), gc.Equals, "hello\n")
+	c.Assert(stderr.String(), jc.Contains, "hostname /bin/bash -s")
+	c.Assert(response.Stdout, gc.DeepEquals, stdout.Bytes())
+	c.Assert(response.Stderr, gc.DeepEquals, stderr.Bytes())
+}
+
 func (s *ExecuteSSHCommandSuite) TestIdentityFile(c *gc.C) {
 	s.fakeSSH(c, echoSSH)
 
@@ -92,6 +112,23 @@ func (s *ExecuteSSHCommandSuite) TestTimoutCaptureOutput(c *gc.C) {
 	c.Assert(string(response.Stderr), gc.Equals, "stderr\n")
 }
 
+func (s *ExecuteSSHCommandSuite) TestCancelCaptureOutput(c *gc.C) {
+	s.fakeSSH(c, slowSSH)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	response, err := ssh.ExecuteCommandOnMachine(ssh.ExecParams{
+		IdentityFile: "identity-file",
+		Host:         "hostname",
+		Command:      "ignored",
+		Timeout:      time.Minute,
+		Cancel:       cancel,
+	})
+
+	c.Check(err, gc.ErrorMatches, "command cancelled")
+	c.Assert(response.Code, gc.Equals, 0)
+}
+
 func (s *ExecuteSSHCommandSuite) TestCapturesReturnCode(c *gc.C) {
 	s.fakeSSH(c, passthroughSSH)
 
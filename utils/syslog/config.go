@@ -7,8 +7,9 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"path/filepath"
-	"strings"
+	"strconv"
 	"text/template"
 )
 
@@ -62,7 +63,7 @@ $DefaultNetstreamDriverCAFile {{tlsCACertPath}}
 $ActionSendStreamDriverAuthMode anon
 $ActionSendStreamDriverMode 1 # run driver in TLS-only mode
 
-:syslogtag, startswith, "juju{{namespace}}-" @@{{$stateServerIP}}:{{portNumber}};LongTagForwardFormat
+:syslogtag, startswith, "juju{{namespace}}-" @@{{forwardAddress $stateServerIP}};LongTagForwardFormat
 # end: Forwarding rule for {{$stateServerIP}}
 {{end}}
 :syslogtag, startswith, "juju{{namespace}}-" ~
@@ -125,7 +126,7 @@ $ActionSendStreamDriverAuthMode anon
 $ActionSendStreamDriverMode 1 # run driver in TLS-only mode
 
 $template LongTagForwardFormat,"<%PRI%>%TIMESTAMP:::date-rfc3339% %HOSTNAME% %syslogtag%%msg:::sp-if-no-1st-sp%%msg%"
-:syslogtag, startswith, "juju{{namespace}}-" @@{{$stateServerIP}}:{{portNumber}};LongTagForwardFormat
+:syslogtag, startswith, "juju{{namespace}}-" @@{{forwardAddress $stateServerIP}};LongTagForwardFormat
 # end: Forwarding rule for {{$stateServerIP}}
 {{end}}
 & ~
@@ -240,12 +241,21 @@ func (slConfig *SyslogConfig) Render() ([]byte, error) {
 	var stateServerHosts = func() []string {
 		var hosts []string
 		for _, addr := range slConfig.StateServerAddresses {
-			parts := strings.Split(addr, ":")
-			hosts = append(hosts, parts[0])
+			// Addresses may or may not have a port; IPv6
+			// addresses with a port are in [host]:port form.
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			hosts = append(hosts, host)
 		}
 		return hosts
 	}
 
+	var forwardAddress = func(host string) string {
+		return net.JoinHostPort(host, strconv.Itoa(slConfig.Port))
+	}
+
 	var logFilePath = func() string {
 		return fmt.Sprintf("%s/%s.log", slConfig.LogDir, slConfig.LogFileName)
 	}
@@ -254,6 +264,7 @@ func (slConfig *SyslogConfig) Render() ([]byte, error) {
 	t.Funcs(template.FuncMap{
 		"logfileName":      func() string { return slConfig.LogFileName },
 		"stateServerHosts": stateServerHosts,
+		"forwardAddress":   forwardAddress,
 		"logfilePath":      logFilePath,
 		"portNumber":       func() int { return slConfig.Port },
 		"logDir":           func() string { return slConfig.LogDir },
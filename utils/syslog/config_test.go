@@ -97,6 +97,17 @@ func (s *syslogConfigSuite) TestForwardConfigRenderWithNamespace(c *gc.C) {
 	)
 }
 
+func (s *syslogConfigSuite) TestForwardConfigRenderIPv6(c *gc.C) {
+	syslogConfigRenderer := syslog.NewForwardConfig(
+		"some-machine", agent.DefaultLogDir, 999, "", []string{"[2001:db8::1]:37017"},
+	)
+	s.assertRsyslogConfigContents(
+		c, syslogConfigRenderer, syslogtesting.ExpectedForwardSyslogConf(
+			c, "some-machine", agent.DefaultLogDir, "", "2001:db8::1", 999,
+		),
+	)
+}
+
 func (s *syslogConfigSuite) TestForwardConfigWrite(c *gc.C) {
 	syslogConfigRenderer := syslog.NewForwardConfig(
 		"some-machine", agent.DefaultLogDir, 999, "", []string{"server"},
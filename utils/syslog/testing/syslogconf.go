@@ -5,6 +5,8 @@ package testing
 
 import (
 	"bytes"
+	"net"
+	"strconv"
 	"text/template"
 
 	gc "launchpad.net/gocheck"
@@ -73,6 +75,10 @@ type templateArgs struct {
 	BootstrapIP string
 	Port        int
 	Offset      int
+
+	// ForwardAddress holds the bracketed host:port
+	// of BootstrapIP and Port.
+	ForwardAddress string
 }
 
 // ExpectedAccumulateSyslogConf returns the expected content for a rsyslog file on a state server.
@@ -103,7 +109,7 @@ $InputFileTag juju{{.Namespace}}-{{.MachineTag}}:
 $InputFileStateFile {{.MachineTag}}{{.Namespace}}
 $InputRunFileMonitor
 
-# start: Forwarding rule for server
+# start: Forwarding rule for {{.BootstrapIP}}
 $ActionQueueType LinkedList
 $ActionQueueFileName {{.MachineTag}}{{.Namespace}}_0
 $ActionResumeRetryCount -1
@@ -114,8 +120,8 @@ $ActionSendStreamDriverAuthMode anon
 $ActionSendStreamDriverMode 1 # run driver in TLS-only mode
 
 $template LongTagForwardFormat,"<%PRI%>%TIMESTAMP:::date-rfc3339% %HOSTNAME% %syslogtag%%msg:::sp-if-no-1st-sp%%msg%"
-:syslogtag, startswith, "juju{{.Namespace}}-" @@{{.BootstrapIP}}:{{.Port}};LongTagForwardFormat
-# end: Forwarding rule for server
+:syslogtag, startswith, "juju{{.Namespace}}-" @@{{.ForwardAddress}};LongTagForwardFormat
+# end: Forwarding rule for {{.BootstrapIP}}
 
 & ~
 `
@@ -128,11 +134,12 @@ func ExpectedForwardSyslogConf(c *gc.C, machineTag, logDir, namespace, bootstrap
 	t := template.Must(template.New("").Parse(expectedForwardSyslogConfTemplate))
 	var conf bytes.Buffer
 	err := t.Execute(&conf, templateArgs{
-		MachineTag:  machineTag,
-		LogDir:      logDir,
-		Namespace:   namespace,
-		BootstrapIP: bootstrapIP,
-		Port:        port,
+		MachineTag:     machineTag,
+		LogDir:         logDir,
+		Namespace:      namespace,
+		BootstrapIP:    bootstrapIP,
+		Port:           port,
+		ForwardAddress: net.JoinHostPort(bootstrapIP, strconv.Itoa(port)),
 	})
 	c.Assert(err, gc.IsNil)
 	return conf.String()
@@ -0,0 +1,176 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package dnsserver implements a worker that serves an authoritative
+// DNS zone for the environment, resolving unit and machine names to
+// their current addresses.
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/juju/loggo"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/multiwatcher"
+	"github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.dnsserver")
+
+// Port is the port the DNS service listens on.
+const Port = 53
+
+var listenPacket = net.ListenPacket
+
+// Listen opens a UDP connection on the DNS port for the loopback
+// address and for each of the given cloud-local addresses of the
+// machine. It does not listen on every interface, as that would
+// conflict with DNS servers, such as dnsmasq, that serve other
+// interfaces of the machine.
+func Listen(addrs []network.Address) ([]net.PacketConn, error) {
+	hosts := []string{"127.0.0.1"}
+	for _, addr := range addrs {
+		if addr.Type != network.HostName && addr.Scope == network.ScopeCloudLocal {
+			hosts = append(hosts, addr.Value)
+		}
+	}
+	var conns []net.PacketConn
+	for _, host := range hosts {
+		endpoint := net.JoinHostPort(host, strconv.Itoa(Port))
+		conn, err := listenPacket("udp", endpoint)
+		if err != nil {
+			closeAll(conns)
+			if isAddrInUse(err) {
+				return nil, fmt.Errorf("cannot listen on %s: the port is used by another DNS server; stop it or disable dns-service", endpoint)
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// isAddrInUse reports whether err is the error returned when
+// listening on an address that is already in use.
+func isAddrInUse(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EADDRINUSE
+}
+
+func closeAll(conns []net.PacketConn) {
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+type dnsServer struct {
+	tomb  tomb.Tomb
+	st    *state.State
+	conns []net.PacketConn
+	zone  *zone
+}
+
+// New returns a worker that answers DNS queries received on conns for
+// the names of the environment's units and machines. The names are
+// kept up to date by watching the state. The worker closes conns when
+// it stops.
+func New(st *state.State, conns []net.PacketConn) (worker.Worker, error) {
+	cfg, err := st.EnvironConfig()
+	if err != nil {
+		closeAll(conns)
+		return nil, err
+	}
+	s := &dnsServer{
+		st:    st,
+		conns: conns,
+		zone:  newZone(cfg.Name()),
+	}
+	go func() {
+		defer s.tomb.Done()
+		s.tomb.Kill(s.loop())
+	}()
+	return s, nil
+}
+
+func (s *dnsServer) Kill() {
+	s.tomb.Kill(nil)
+}
+
+func (s *dnsServer) Wait() error {
+	return s.tomb.Wait()
+}
+
+func (s *dnsServer) loop() error {
+	w := s.st.Watch()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.watch(w)
+	}()
+	for _, conn := range s.conns {
+		logger.Infof("serving zone %q on %v", s.zone.domain, conn.LocalAddr())
+		wg.Add(1)
+		go func(conn net.PacketConn) {
+			defer wg.Done()
+			s.serve(conn)
+		}(conn)
+	}
+	<-s.tomb.Dying()
+	w.Stop()
+	closeAll(s.conns)
+	wg.Wait()
+	return tomb.ErrDying
+}
+
+// watch updates the zone with the changes reported by w until it
+// is stopped.
+func (s *dnsServer) watch(w *multiwatcher.Watcher) {
+	for {
+		deltas, err := w.Next()
+		if err != nil {
+			if err != multiwatcher.ErrWatcherStopped {
+				s.tomb.Kill(err)
+			}
+			return
+		}
+		s.zone.update(deltas)
+	}
+}
+
+// serve answers the queries received on conn until it is closed.
+func (s *dnsServer) serve(conn net.PacketConn) {
+	buf := make([]byte, maxUDPMessageLen)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.tomb.Dying():
+			default:
+				s.tomb.Kill(err)
+			}
+			return
+		}
+		resp, err := s.zone.answer(buf[:n])
+		if err != nil {
+			logger.Debugf("ignoring message from %v: %v", addr, err)
+			continue
+		}
+		if _, err := conn.WriteTo(resp, addr); err != nil {
+			logger.Warningf("cannot send response to %v: %v", addr, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package dnsserver
+
+import (
+	"net"
+	"reflect"
+	"time"
+
+	gc "launchpad.net/gocheck"
+
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+)
+
+type workerSuite struct {
+	jujutesting.JujuConnSuite
+	envName string
+	addr    net.Addr
+	worker  worker.Worker
+}
+
+var _ = gc.Suite(&workerSuite{})
+
+func (s *workerSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, gc.IsNil)
+	s.envName = cfg.Name()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, gc.IsNil)
+	s.addr = conn.LocalAddr()
+	s.worker, err = New(s.State, []net.PacketConn{conn})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *workerSuite) TearDownTest(c *gc.C) {
+	if s.worker != nil {
+		c.Assert(worker.Stop(s.worker), gc.IsNil)
+	}
+	s.JujuConnSuite.TearDownTest(c)
+}
+
+// query sends a query for the given name to the worker and returns
+// the response.
+func (s *workerSuite) query(c *gc.C, name string, qtype uint16) response {
+	conn, err := net.Dial("udp", s.addr.String())
+	c.Assert(err, gc.IsNil)
+	defer conn.Close()
+	_, err = conn.Write(newQuery(42, name, qtype))
+	c.Assert(err, gc.IsNil)
+	err = conn.SetReadDeadline(time.Now().Add(coretesting.LongWait))
+	c.Assert(err, gc.IsNil)
+	buf := make([]byte, maxUDPMessageLen)
+	n, err := conn.Read(buf)
+	c.Assert(err, gc.IsNil)
+	return parseResponse(c, buf[:n])
+}
+
+// waitAnswers waits until querying the worker for the given name
+// gives the expected answers.
+func (s *workerSuite) waitAnswers(c *gc.C, name string, qtype uint16, expect []string) {
+	var resp response
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		resp = s.query(c, name, qtype)
+		if resp.rcode == rcodeSuccess && reflect.DeepEqual(resp.answers, expect) {
+			break
+		}
+	}
+	c.Assert(resp.rcode, gc.Equals, uint16(rcodeSuccess))
+	c.Assert(resp.answers, gc.DeepEquals, expect)
+}
+
+func (s *workerSuite) TestServesMachinesAndUnits(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+	err = machine.SetAddresses(
+		network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+		network.NewAddress("2001:db8::1", network.ScopeCloudLocal),
+	)
+	c.Assert(err, gc.IsNil)
+	service := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := service.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = unit.AssignToMachine(machine)
+	c.Assert(err, gc.IsNil)
+
+	machineName := MachineName(machine.Id(), s.envName)
+	unitName := UnitName(unit.Name(), s.envName)
+	s.waitAnswers(c, machineName, typeA, []string{"10.0.0.1"})
+	s.waitAnswers(c, unitName, typeAAAA, []string{"2001:db8::1"})
+
+	// Address changes are picked up.
+	err = machine.SetAddresses(network.NewAddress("10.0.0.2", network.ScopeCloudLocal))
+	c.Assert(err, gc.IsNil)
+	s.waitAnswers(c, unitName, typeA, []string{"10.0.0.2"})
+
+	resp := s.query(c, "wordpress-1.wordpress."+s.envName, typeA)
+	c.Assert(resp.rcode, gc.Equals, uint16(rcodeNameError))
+}
+
+func (s *workerSuite) TestStopClosesConnection(c *gc.C) {
+	c.Assert(worker.Stop(s.worker), gc.IsNil)
+	s.worker = nil
+	// The port is free to be bound again.
+	conn, err := net.ListenPacket("udp", s.addr.String())
+	c.Assert(err, gc.IsNil)
+	conn.Close()
+}
+
+type listenSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&listenSuite{})
+
+func (s *listenSuite) TestListenLoopbackAndCloudLocal(c *gc.C) {
+	var endpoints []string
+	s.PatchValue(&listenPacket, func(network, endpoint string) (net.PacketConn, error) {
+		endpoints = append(endpoints, endpoint)
+		return net.ListenPacket(network, "127.0.0.1:0")
+	})
+	conns, err := Listen([]network.Address{
+		network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+		network.NewAddress("54.0.0.1", network.ScopePublic),
+		network.NewAddress("fc00::1", network.ScopeCloudLocal),
+		network.NewAddress("example.com", network.ScopeCloudLocal),
+	})
+	c.Assert(err, gc.IsNil)
+	closeAll(conns)
+	c.Assert(conns, gc.HasLen, 3)
+	c.Assert(endpoints, gc.DeepEquals, []string{"127.0.0.1:53", "10.0.0.1:53", "[fc00::1]:53"})
+}
+
+func (s *listenSuite) TestListenAddressInUse(c *gc.C) {
+	used, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, gc.IsNil)
+	defer used.Close()
+	var opened []net.PacketConn
+	s.PatchValue(&listenPacket, func(network, endpoint string) (net.PacketConn, error) {
+		if len(opened) == 0 {
+			conn, err := net.ListenPacket(network, "127.0.0.1:0")
+			opened = append(opened, conn)
+			return conn, err
+		}
+		return net.ListenPacket(network, used.LocalAddr().String())
+	})
+	conns, err := Listen(network.NewAddresses("10.0.0.1"))
+	c.Assert(err, gc.ErrorMatches, `cannot listen on 10.0.0.1:53: the port is used by another DNS server; stop it or disable dns-service`)
+	c.Assert(conns, gc.IsNil)
+	// The connections opened before the failure are closed.
+	c.Assert(opened, gc.HasLen, 1)
+	conn, err := net.ListenPacket("udp", opened[0].LocalAddr().String())
+	c.Assert(err, gc.IsNil)
+	conn.Close()
+}
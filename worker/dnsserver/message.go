@@ -0,0 +1,185 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package dnsserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// This file implements just enough of the DNS wire format (RFC 1035)
+// to answer single-question A and AAAA queries over UDP.
+
+const (
+	headerLen = 12
+
+	// maxUDPMessageLen is the largest response sent over UDP;
+	// longer responses are truncated.
+	maxUDPMessageLen = 512
+
+	// answerTTL is the time to live, in seconds, of the answers.
+	// It is kept short because addresses may change at any time.
+	answerTTL = 30
+)
+
+// Record types and classes.
+const (
+	typeA    = 1
+	typeAAAA = 28
+	typeANY  = 255
+
+	classIN  = 1
+	classANY = 255
+)
+
+// Response codes.
+const (
+	rcodeSuccess        = 0
+	rcodeFormatError    = 1
+	rcodeNameError      = 3
+	rcodeNotImplemented = 4
+	rcodeRefused        = 5
+)
+
+// Header flags.
+const (
+	flagResponse         = 1 << 15
+	flagAuthoritative    = 1 << 10
+	flagTruncated        = 1 << 9
+	flagRecursionDesired = 1 << 8
+
+	opcodeShift = 11
+	opcodeMask  = 0xf
+)
+
+var errShortMessage = errors.New("message too short")
+
+// query holds a parsed DNS query.
+type query struct {
+	id    uint16
+	flags uint16
+
+	// name holds the queried name in lower case, with a
+	// trailing dot.
+	name   string
+	qtype  uint16
+	qclass uint16
+
+	// question holds the raw question section, which is
+	// repeated in the response.
+	question []byte
+}
+
+// queryError is returned by parseQuery when a query was received
+// that cannot be answered, but to which a response with the given
+// code should still be sent.
+type queryError struct {
+	rcode uint16
+	msg   string
+}
+
+func (e *queryError) Error() string {
+	return e.msg
+}
+
+// parseQuery parses the given DNS query message. If the header can
+// be parsed but the rest of the message cannot be answered, the
+// returned query is valid and the error is a *queryError.
+func parseQuery(msg []byte) (*query, error) {
+	if len(msg) < headerLen {
+		return nil, errShortMessage
+	}
+	q := &query{
+		id:    binary.BigEndian.Uint16(msg[0:]),
+		flags: binary.BigEndian.Uint16(msg[2:]),
+	}
+	if q.flags&flagResponse != 0 {
+		return nil, errors.New("message is not a query")
+	}
+	if opcode := (q.flags >> opcodeShift) & opcodeMask; opcode != 0 {
+		return q, &queryError{rcodeNotImplemented, "unsupported opcode"}
+	}
+	if qdcount := binary.BigEndian.Uint16(msg[4:]); qdcount != 1 {
+		return q, &queryError{rcodeFormatError, "expected exactly one question"}
+	}
+	var labels []string
+	offset := headerLen
+	for {
+		if offset >= len(msg) {
+			return q, &queryError{rcodeFormatError, "truncated question"}
+		}
+		n := int(msg[offset])
+		offset++
+		if n == 0 {
+			break
+		}
+		if n > 63 {
+			// Compression pointers are never needed in a
+			// single question, and other label types are
+			// obsolete.
+			return q, &queryError{rcodeFormatError, "unsupported label type"}
+		}
+		if offset+n > len(msg) {
+			return q, &queryError{rcodeFormatError, "truncated question"}
+		}
+		labels = append(labels, string(msg[offset:offset+n]))
+		offset += n
+	}
+	if offset+4 > len(msg) {
+		return q, &queryError{rcodeFormatError, "truncated question"}
+	}
+	q.qtype = binary.BigEndian.Uint16(msg[offset:])
+	q.qclass = binary.BigEndian.Uint16(msg[offset+2:])
+	offset += 4
+	q.name = strings.ToLower(strings.Join(labels, ".")) + "."
+	q.question = msg[headerLen:offset]
+	return q, nil
+}
+
+// wantsType reports whether the query asks for records of the
+// given type.
+func (q *query) wantsType(rtype uint16) bool {
+	return q.qtype == rtype || q.qtype == typeANY
+}
+
+// response builds the response to the query with the given response
+// code and answers. Answers that do not fit in a UDP message are
+// dropped, and the response is marked as truncated.
+func (q *query) response(rcode uint16, answers []net.IP) []byte {
+	flags := uint16(flagResponse|flagAuthoritative) | q.flags&flagRecursionDesired | rcode
+	msg := make([]byte, headerLen, maxUDPMessageLen)
+	binary.BigEndian.PutUint16(msg[0:], q.id)
+	if q.question == nil {
+		binary.BigEndian.PutUint16(msg[2:], flags)
+		return msg
+	}
+	msg = append(msg, q.question...)
+	ancount := 0
+	for _, ip := range answers {
+		rtype, rdata := uint16(typeA), ip.To4()
+		if rdata == nil {
+			rtype, rdata = typeAAAA, ip.To16()
+		}
+		if len(msg)+12+len(rdata) > maxUDPMessageLen {
+			flags |= flagTruncated
+			break
+		}
+		var rr [12]byte
+		// The name is a pointer to the one in the question.
+		binary.BigEndian.PutUint16(rr[0:], 0xc000|headerLen)
+		binary.BigEndian.PutUint16(rr[2:], rtype)
+		binary.BigEndian.PutUint16(rr[4:], classIN)
+		binary.BigEndian.PutUint32(rr[6:], answerTTL)
+		binary.BigEndian.PutUint16(rr[10:], uint16(len(rdata)))
+		msg = append(msg, rr[:]...)
+		msg = append(msg, rdata...)
+		ancount++
+	}
+	binary.BigEndian.PutUint16(msg[2:], flags)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	binary.BigEndian.PutUint16(msg[6:], uint16(ancount))
+	return msg
+}
@@ -0,0 +1,189 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package dnsserver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	coretesting "github.com/juju/juju/testing"
+)
+
+type messageSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&messageSuite{})
+
+// newQuery returns a query message for the given name and type.
+func newQuery(id uint16, name string, qtype uint16) []byte {
+	msg := make([]byte, headerLen)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], flagRecursionDesired)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0, byte(qtype>>8), byte(qtype), 0, classIN)
+}
+
+// response holds the interesting parts of a response message.
+type response struct {
+	id      uint16
+	flags   uint16
+	rcode   uint16
+	answers []string
+}
+
+// parseResponse parses a response message built by query.response.
+func parseResponse(c *gc.C, msg []byte) response {
+	c.Assert(len(msg) >= headerLen, jc.IsTrue)
+	flags := binary.BigEndian.Uint16(msg[2:])
+	resp := response{
+		id:    binary.BigEndian.Uint16(msg[0:]),
+		flags: flags &^ 0xf,
+		rcode: flags & 0xf,
+	}
+	offset := headerLen
+	if binary.BigEndian.Uint16(msg[4:]) == 1 {
+		for msg[offset] != 0 {
+			offset += int(msg[offset]) + 1
+		}
+		offset += 5
+	}
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+	for i := 0; i < ancount; i++ {
+		c.Assert(binary.BigEndian.Uint16(msg[offset:]), gc.Equals, uint16(0xc000|headerLen))
+		c.Assert(binary.BigEndian.Uint16(msg[offset+4:]), gc.Equals, uint16(classIN))
+		c.Assert(binary.BigEndian.Uint32(msg[offset+6:]), gc.Equals, uint32(answerTTL))
+		rdlen := int(binary.BigEndian.Uint16(msg[offset+10:]))
+		offset += 12
+		resp.answers = append(resp.answers, net.IP(msg[offset:offset+rdlen]).String())
+		offset += rdlen
+	}
+	c.Assert(offset, gc.Equals, len(msg))
+	return resp
+}
+
+func (s *messageSuite) TestParseQuery(c *gc.C) {
+	q, err := parseQuery(newQuery(42, "Foo.Example", typeAAAA))
+	c.Assert(err, gc.IsNil)
+	c.Assert(q.id, gc.Equals, uint16(42))
+	c.Assert(q.name, gc.Equals, "foo.example.")
+	c.Assert(q.qtype, gc.Equals, uint16(typeAAAA))
+	c.Assert(q.qclass, gc.Equals, uint16(classIN))
+	c.Assert(q.wantsType(typeAAAA), jc.IsTrue)
+	c.Assert(q.wantsType(typeA), jc.IsFalse)
+}
+
+func (s *messageSuite) TestParseQueryErrors(c *gc.C) {
+	valid := newQuery(42, "foo.example", typeA)
+	for i, test := range []struct {
+		about  string
+		mutate func([]byte) []byte
+		err    string
+		rcode  uint16
+	}{{
+		about:  "short header",
+		mutate: func(msg []byte) []byte { return msg[:headerLen-1] },
+		err:    "message too short",
+	}, {
+		about: "response",
+		mutate: func(msg []byte) []byte {
+			msg[2] |= flagResponse >> 8
+			return msg
+		},
+		err: "message is not a query",
+	}, {
+		about: "unsupported opcode",
+		mutate: func(msg []byte) []byte {
+			msg[2] |= 2 << (opcodeShift - 8)
+			return msg
+		},
+		err:   "unsupported opcode",
+		rcode: rcodeNotImplemented,
+	}, {
+		about: "no questions",
+		mutate: func(msg []byte) []byte {
+			msg[5] = 0
+			return msg
+		},
+		err:   "expected exactly one question",
+		rcode: rcodeFormatError,
+	}, {
+		about:  "truncated name",
+		mutate: func(msg []byte) []byte { return msg[:headerLen+2] },
+		err:    "truncated question",
+		rcode:  rcodeFormatError,
+	}, {
+		about:  "truncated type",
+		mutate: func(msg []byte) []byte { return msg[:len(msg)-3] },
+		err:    "truncated question",
+		rcode:  rcodeFormatError,
+	}, {
+		about: "compressed name",
+		mutate: func(msg []byte) []byte {
+			msg[headerLen] = 0xc0
+			return msg
+		},
+		err:   "unsupported label type",
+		rcode: rcodeFormatError,
+	}} {
+		c.Logf("test %d: %s", i, test.about)
+		msg := test.mutate(append([]byte(nil), valid...))
+		q, err := parseQuery(msg)
+		c.Check(err, gc.ErrorMatches, test.err)
+		if test.rcode == 0 {
+			c.Check(err, gc.Not(jc.Satisfies), isQueryError)
+			continue
+		}
+		c.Assert(err, jc.Satisfies, isQueryError)
+		c.Check(err.(*queryError).rcode, gc.Equals, test.rcode)
+		resp := parseResponse(c, q.response(test.rcode, nil))
+		c.Check(resp.id, gc.Equals, uint16(42))
+		c.Check(resp.rcode, gc.Equals, test.rcode)
+		c.Check(resp.answers, gc.HasLen, 0)
+	}
+}
+
+func isQueryError(err error) bool {
+	_, ok := err.(*queryError)
+	return ok
+}
+
+func (s *messageSuite) TestResponse(c *gc.C) {
+	q, err := parseQuery(newQuery(42, "foo.example", typeANY))
+	c.Assert(err, gc.IsNil)
+	msg := q.response(rcodeSuccess, []net.IP{
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("2001:db8::1"),
+	})
+	c.Assert(parseResponse(c, msg), jc.DeepEquals, response{
+		id:      42,
+		flags:   flagResponse | flagAuthoritative | flagRecursionDesired,
+		rcode:   rcodeSuccess,
+		answers: []string{"10.0.0.1", "2001:db8::1"},
+	})
+}
+
+func (s *messageSuite) TestResponseTruncated(c *gc.C) {
+	q, err := parseQuery(newQuery(42, "foo.example", typeAAAA))
+	c.Assert(err, gc.IsNil)
+	var answers []net.IP
+	for i := 0; i < 40; i++ {
+		answers = append(answers, net.ParseIP(fmt.Sprintf("2001:db8::%x", i)))
+	}
+	msg := q.response(rcodeSuccess, answers)
+	c.Assert(len(msg) <= maxUDPMessageLen, jc.IsTrue)
+	resp := parseResponse(c, msg)
+	c.Assert(resp.flags&flagTruncated, gc.Not(gc.Equals), uint16(0))
+	c.Assert(len(resp.answers) < len(answers), jc.IsTrue)
+	c.Assert(resp.answers[0], gc.Equals, "2001:db8::")
+}
@@ -0,0 +1,164 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package dnsserver
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/juju/names"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state/api/params"
+)
+
+// zone holds the names of an environment's units and machines,
+// and answers queries for them.
+//
+// Machines are named after their ids, with slashes replaced by
+// dashes (e.g. "0.envname." and "0-lxc-1.envname."). Units are named
+// after the unit and its service (e.g. "wordpress-0.wordpress.envname.").
+// Both resolve to the addresses of the machine.
+type zone struct {
+	// domain holds the zone's domain, in lower case with a
+	// trailing dot.
+	domain string
+
+	mu sync.Mutex
+	// machines holds the addresses of each machine by id.
+	machines map[string][]network.Address
+	// units holds the id of the machine assigned to each unit,
+	// by unit name. The id is empty for unassigned units.
+	units map[string]string
+}
+
+func newZone(envName string) *zone {
+	return &zone{
+		domain:   strings.ToLower(envName) + ".",
+		machines: make(map[string][]network.Address),
+		units:    make(map[string]string),
+	}
+}
+
+// MachineName returns the DNS name of the machine with the given id
+// in the named environment.
+func MachineName(machineId, envName string) string {
+	return strings.Replace(machineId, "/", "-", -1) + "." + envName
+}
+
+// UnitName returns the DNS name of the unit with the given name in
+// the named environment.
+func UnitName(unitName, envName string) string {
+	service := names.UnitService(unitName)
+	return strings.Replace(unitName, "/", "-", -1) + "." + service + "." + envName
+}
+
+// update records the changes to machines and units in the given
+// deltas.
+func (z *zone) update(deltas []params.Delta) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for _, delta := range deltas {
+		switch info := delta.Entity.(type) {
+		case *params.MachineInfo:
+			if delta.Removed {
+				delete(z.machines, info.Id)
+			} else {
+				z.machines[info.Id] = info.Addresses
+			}
+		case *params.UnitInfo:
+			if delta.Removed {
+				delete(z.units, info.Name)
+			} else {
+				z.units[info.Name] = info.MachineId
+			}
+		}
+	}
+}
+
+// answer returns the response to the given query message, or an
+// error if no response should be sent.
+func (z *zone) answer(msg []byte) ([]byte, error) {
+	q, err := parseQuery(msg)
+	if qerr, ok := err.(*queryError); ok {
+		return q.response(qerr.rcode, nil), nil
+	} else if err != nil {
+		return nil, err
+	}
+	if q.qclass != classIN && q.qclass != classANY {
+		return q.response(rcodeRefused, nil), nil
+	}
+	if !strings.HasSuffix(q.name, "."+z.domain) {
+		if q.name == z.domain {
+			return q.response(rcodeSuccess, nil), nil
+		}
+		// We are only authoritative for the environment's zone.
+		return q.response(rcodeRefused, nil), nil
+	}
+	addrs, ok := z.lookup(strings.TrimSuffix(q.name, "."+z.domain))
+	if !ok {
+		return q.response(rcodeNameError, nil), nil
+	}
+	var answers []net.IP
+	for _, addr := range addrs {
+		switch {
+		case addr.Type == network.IPv4Address && q.wantsType(typeA),
+			addr.Type == network.IPv6Address && q.wantsType(typeAAAA):
+			if ip := net.ParseIP(addr.Value); ip != nil {
+				answers = append(answers, ip)
+			}
+		}
+	}
+	return q.response(rcodeSuccess, answers), nil
+}
+
+// lookup returns the addresses of the unit or machine with the given
+// name relative to the zone's domain, and whether the name exists.
+func (z *zone) lookup(name string) ([]network.Address, bool) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	labels := strings.Split(name, ".")
+	var machineId string
+	switch len(labels) {
+	case 1:
+		machineId = strings.Replace(labels[0], "-", "/", -1)
+		if _, ok := z.machines[machineId]; !ok {
+			return nil, false
+		}
+	case 2:
+		service := labels[1]
+		if !strings.HasPrefix(labels[0], service+"-") {
+			return nil, false
+		}
+		unitName := service + "/" + labels[0][len(service)+1:]
+		id, ok := z.units[unitName]
+		if !ok {
+			return nil, false
+		}
+		machineId = id
+	default:
+		return nil, false
+	}
+	return internalAddresses(z.machines[machineId]), true
+}
+
+// internalAddresses returns the addresses that other machines in the
+// environment should use to reach a machine with the given addresses:
+// the cloud-local ones, or the public ones if there are none.
+func internalAddresses(addrs []network.Address) []network.Address {
+	var internal, public []network.Address
+	for _, addr := range addrs {
+		switch addr.Scope {
+		case network.ScopeCloudLocal, network.ScopeUnknown:
+			internal = append(internal, addr)
+		case network.ScopePublic:
+			public = append(public, addr)
+		}
+	}
+	if len(internal) > 0 {
+		return internal
+	}
+	return public
+}
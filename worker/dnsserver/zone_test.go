@@ -0,0 +1,174 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package dnsserver
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state/api/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type zoneSuite struct {
+	coretesting.BaseSuite
+	zone *zone
+}
+
+var _ = gc.Suite(&zoneSuite{})
+
+func (s *zoneSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.zone = newZone("TestEnv")
+	s.zone.update([]params.Delta{{
+		Entity: &params.MachineInfo{
+			Id: "0",
+			Addresses: []network.Address{
+				network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+				network.NewAddress("2001:db8::1", network.ScopeCloudLocal),
+				network.NewAddress("54.0.0.1", network.ScopePublic),
+				network.NewAddress("127.0.0.1", network.ScopeMachineLocal),
+			},
+		},
+	}, {
+		Entity: &params.MachineInfo{
+			Id: "0/lxc/1",
+			Addresses: []network.Address{
+				network.NewAddress("54.0.0.2", network.ScopePublic),
+			},
+		},
+	}, {
+		Entity: &params.UnitInfo{Name: "wordpress/0", Service: "wordpress", MachineId: "0/lxc/1"},
+	}, {
+		Entity: &params.UnitInfo{Name: "mysql-server/10", Service: "mysql-server", MachineId: "0"},
+	}, {
+		Entity: &params.UnitInfo{Name: "mysql-server/11", Service: "mysql-server"},
+	}})
+}
+
+func (s *zoneSuite) TestNames(c *gc.C) {
+	c.Assert(MachineName("0", "testenv"), gc.Equals, "0.testenv")
+	c.Assert(MachineName("0/lxc/1", "testenv"), gc.Equals, "0-lxc-1.testenv")
+	c.Assert(UnitName("wordpress/0", "testenv"), gc.Equals, "wordpress-0.wordpress.testenv")
+	c.Assert(UnitName("mysql-server/10", "testenv"), gc.Equals, "mysql-server-10.mysql-server.testenv")
+}
+
+func (s *zoneSuite) TestAnswer(c *gc.C) {
+	for i, test := range []struct {
+		name    string
+		qtype   uint16
+		rcode   uint16
+		answers []string
+	}{{
+		name:    "0.testenv",
+		qtype:   typeA,
+		answers: []string{"10.0.0.1"},
+	}, {
+		name:    "0.TestEnv.",
+		qtype:   typeAAAA,
+		answers: []string{"2001:db8::1"},
+	}, {
+		name:    "0.testenv",
+		qtype:   typeANY,
+		answers: []string{"10.0.0.1", "2001:db8::1"},
+	}, {
+		name:    "0-lxc-1.testenv",
+		qtype:   typeA,
+		answers: []string{"54.0.0.2"},
+	}, {
+		name:  "0-lxc-1.testenv",
+		qtype: typeAAAA,
+	}, {
+		name:    "wordpress-0.wordpress.testenv",
+		qtype:   typeA,
+		answers: []string{"54.0.0.2"},
+	}, {
+		name:    "mysql-server-10.mysql-server.testenv",
+		qtype:   typeANY,
+		answers: []string{"10.0.0.1", "2001:db8::1"},
+	}, {
+		// Unassigned units exist, but have no addresses.
+		name:  "mysql-server-11.mysql-server.testenv",
+		qtype: typeA,
+	}, {
+		name:  "testenv",
+		qtype: typeA,
+	}, {
+		name:  "1.testenv",
+		qtype: typeA,
+		rcode: rcodeNameError,
+	}, {
+		name:  "wordpress-1.wordpress.testenv",
+		qtype: typeA,
+		rcode: rcodeNameError,
+	}, {
+		name:  "wordpress-0.mysql-server.testenv",
+		qtype: typeA,
+		rcode: rcodeNameError,
+	}, {
+		name:  "foo.wordpress-0.wordpress.testenv",
+		qtype: typeA,
+		rcode: rcodeNameError,
+	}, {
+		name:  "0.otherenv",
+		qtype: typeA,
+		rcode: rcodeRefused,
+	}, {
+		name:  "example.com",
+		qtype: typeA,
+		rcode: rcodeRefused,
+	}} {
+		c.Logf("test %d: %s type %d", i, test.name, test.qtype)
+		msg, err := s.zone.answer(newQuery(uint16(i), test.name, test.qtype))
+		c.Assert(err, gc.IsNil)
+		resp := parseResponse(c, msg)
+		c.Check(resp.id, gc.Equals, uint16(i))
+		c.Check(resp.rcode, gc.Equals, test.rcode)
+		c.Check(resp.answers, gc.DeepEquals, test.answers)
+	}
+}
+
+func (s *zoneSuite) TestAnswerRefusesOtherClasses(c *gc.C) {
+	msg := newQuery(42, "0.testenv", typeA)
+	msg[len(msg)-1] = 3 // CHAOS
+	resp, err := s.zone.answer(msg)
+	c.Assert(err, gc.IsNil)
+	c.Assert(parseResponse(c, resp).rcode, gc.Equals, uint16(rcodeRefused))
+}
+
+func (s *zoneSuite) TestAnswerFormatError(c *gc.C) {
+	msg := newQuery(42, "0.testenv", typeA)
+	resp, err := s.zone.answer(msg[:len(msg)-1])
+	c.Assert(err, gc.IsNil)
+	c.Assert(parseResponse(c, resp).rcode, gc.Equals, uint16(rcodeFormatError))
+}
+
+func (s *zoneSuite) TestAnswerIgnoresResponses(c *gc.C) {
+	msg := newQuery(42, "0.testenv", typeA)
+	msg[2] |= flagResponse >> 8
+	resp, err := s.zone.answer(msg)
+	c.Assert(err, gc.ErrorMatches, "message is not a query")
+	c.Assert(resp, gc.IsNil)
+}
+
+func (s *zoneSuite) TestUpdateRemoved(c *gc.C) {
+	s.zone.update([]params.Delta{{
+		Removed: true,
+		Entity:  &params.UnitInfo{Name: "wordpress/0"},
+	}, {
+		Removed: true,
+		Entity:  &params.MachineInfo{Id: "0"},
+	}})
+	for _, name := range []string{"0.testenv", "wordpress-0.wordpress.testenv"} {
+		msg, err := s.zone.answer(newQuery(42, name, typeA))
+		c.Assert(err, gc.IsNil)
+		c.Check(parseResponse(c, msg).rcode, gc.Equals, uint16(rcodeNameError))
+	}
+	// The unit's machine has gone, but the unit itself remains.
+	msg, err := s.zone.answer(newQuery(42, "mysql-server-10.mysql-server.testenv", typeA))
+	c.Assert(err, gc.IsNil)
+	resp := parseResponse(c, msg)
+	c.Check(resp.rcode, gc.Equals, uint16(rcodeSuccess))
+	c.Check(resp.answers, gc.HasLen, 0)
+}
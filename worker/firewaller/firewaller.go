@@ -37,7 +37,7 @@ type Firewaller struct {
 	serviceds       map[string]*serviceData
 	exposedChange   chan *exposedChange
 	globalMode      bool
-	globalPortRef   map[network.Port]int
+	globalRuleRef   map[network.IngressRule]int
 }
 
 // NewFirewaller returns a new Firewaller.
@@ -78,9 +78,16 @@ func (fw *Firewaller) loop() error {
 	if err != nil {
 		return err
 	}
-	if fw.environ.Config().FirewallMode() == config.FwGlobal {
+	switch fw.environ.Config().FirewallMode() {
+	case config.FwGlobal:
 		fw.globalMode = true
-		fw.globalPortRef = make(map[network.Port]int)
+		fw.globalRuleRef = make(map[network.IngressRule]int)
+	case config.FwHost:
+		// Each machine agent manages its own host's firewall, so
+		// there is nothing to do here.
+		logger.Infof("firewall mode is %q, not managing provider firewalls", config.FwHost)
+		<-fw.tomb.Dying()
+		return tomb.ErrDying
 	}
 	for {
 		select {
@@ -127,6 +134,7 @@ func (fw *Firewaller) loop() error {
 			}
 		case change := <-fw.exposedChange:
 			change.serviced.exposed = change.exposed
+			change.serviced.cidrs = change.cidrs
 			unitds := []*unitData{}
 			for _, unitd := range change.serviced.unitds {
 				unitds = append(unitds, unitd)
@@ -157,7 +165,7 @@ func (fw *Firewaller) startMachine(machineTag string) error {
 		fw:     fw,
 		tag:    tag,
 		unitds: make(map[string]*unitData),
-		ports:  make([]network.Port, 0),
+		rules:  make([]network.IngressRule, 0),
 	}
 	m, err := machined.machine()
 	if params.IsCodeNotFound(err) {
@@ -238,14 +246,19 @@ func (fw *Firewaller) startService(service *apifirewaller.Service) error {
 	if err != nil {
 		return err
 	}
+	cidrs, err := service.IngressCIDRs()
+	if err != nil {
+		return err
+	}
 	serviced := &serviceData{
 		fw:      fw,
 		service: service,
 		exposed: exposed,
+		cidrs:   cidrs,
 		unitds:  make(map[string]*unitData),
 	}
 	fw.serviceds[service.Name()] = serviced
-	go serviced.watchLoop(serviced.exposed)
+	go serviced.watchLoop(serviced.exposed, serviced.cidrs)
 	return nil
 }
 
@@ -253,38 +266,36 @@ func (fw *Firewaller) startService(service *apifirewaller.Service) error {
 // units and services with the opened and closed ports globally and
 // opens and closes the appropriate ports for the whole environment.
 func (fw *Firewaller) reconcileGlobal() error {
-	initialPorts, err := fw.environ.Ports()
+	initialRules, err := fw.globalRules()
 	if err != nil {
 		return err
 	}
-	collector := make(map[network.Port]bool)
+	collector := make(map[network.IngressRule]bool)
 	for _, unitd := range fw.unitds {
-		if unitd.serviced.exposed {
-			for _, port := range unitd.ports {
-				collector[port] = true
-			}
+		for _, rule := range unitd.wantedRules() {
+			collector[rule] = true
 		}
 	}
-	wantedPorts := []network.Port{}
-	for port := range collector {
-		wantedPorts = append(wantedPorts, port)
+	wantedRules := []network.IngressRule{}
+	for rule := range collector {
+		wantedRules = append(wantedRules, rule)
 	}
-	// Check which ports to open or to close.
-	toOpen := Diff(wantedPorts, initialPorts)
-	toClose := Diff(initialPorts, wantedPorts)
+	// Check which rules to open or to close.
+	toOpen := diffRules(wantedRules, initialRules)
+	toClose := diffRules(initialRules, wantedRules)
 	if len(toOpen) > 0 {
-		logger.Infof("opening global ports %v", toOpen)
-		if err := fw.environ.OpenPorts(toOpen); err != nil {
+		logger.Infof("opening global rules %v", toOpen)
+		if err := fw.openGlobalRules(toOpen); err != nil {
 			return err
 		}
-		network.SortPorts(toOpen)
+		network.SortIngressRules(toOpen)
 	}
 	if len(toClose) > 0 {
-		logger.Infof("closing global ports %v", toClose)
-		if err := fw.environ.ClosePorts(toClose); err != nil {
+		logger.Infof("closing global rules %v", toClose)
+		if err := fw.closeGlobalRules(toClose); err != nil {
 			return err
 		}
-		network.SortPorts(toClose)
+		network.SortIngressRules(toClose)
 	}
 	return nil
 }
@@ -314,30 +325,30 @@ func (fw *Firewaller) reconcileInstances() error {
 			return err
 		}
 		machineId := machined.tag.Id()
-		initialPorts, err := instances[0].Ports(machineId)
+		initialRules, err := instanceRules(instances[0], machineId)
 		if err != nil {
 			return err
 		}
-		// Check which ports to open or to close.
-		toOpen := Diff(machined.ports, initialPorts)
-		toClose := Diff(initialPorts, machined.ports)
+		// Check which rules to open or to close.
+		toOpen := diffRules(machined.rules, initialRules)
+		toClose := diffRules(initialRules, machined.rules)
 		if len(toOpen) > 0 {
-			logger.Infof("opening instance ports %v for %q",
+			logger.Infof("opening instance rules %v for %q",
 				toOpen, machined.tag)
-			if err := instances[0].OpenPorts(machineId, toOpen); err != nil {
+			if err := openInstanceRules(instances[0], machineId, toOpen); err != nil {
 				// TODO(mue) Add local retry logic.
 				return err
 			}
-			network.SortPorts(toOpen)
+			network.SortIngressRules(toOpen)
 		}
 		if len(toClose) > 0 {
-			logger.Infof("closing instance ports %v for %q",
+			logger.Infof("closing instance rules %v for %q",
 				toClose, machined.tag)
-			if err := instances[0].ClosePorts(machineId, toClose); err != nil {
+			if err := closeInstanceRules(instances[0], machineId, toClose); err != nil {
 				// TODO(mue) Add local retry logic.
 				return err
 			}
-			network.SortPorts(toClose)
+			network.SortIngressRules(toClose)
 		}
 	}
 	return nil
@@ -399,69 +410,67 @@ func (fw *Firewaller) flushUnits(unitds []*unitData) error {
 
 // flushMachine opens and closes ports for the passed machine.
 func (fw *Firewaller) flushMachine(machined *machineData) error {
-	// Gather ports to open and close.
-	ports := map[network.Port]bool{}
+	// Gather rules to open and close.
+	rules := map[network.IngressRule]bool{}
 	for _, unitd := range machined.unitds {
-		if unitd.serviced.exposed {
-			for _, port := range unitd.ports {
-				ports[port] = true
-			}
+		for _, rule := range unitd.wantedRules() {
+			rules[rule] = true
 		}
 	}
-	want := []network.Port{}
-	for port := range ports {
-		want = append(want, port)
+	want := []network.IngressRule{}
+	for rule := range rules {
+		want = append(want, rule)
 	}
-	toOpen := Diff(want, machined.ports)
-	toClose := Diff(machined.ports, want)
-	machined.ports = want
+	toOpen := diffRules(want, machined.rules)
+	toClose := diffRules(machined.rules, want)
+	machined.rules = want
 	if fw.globalMode {
-		return fw.flushGlobalPorts(toOpen, toClose)
+		return fw.flushGlobalRules(toOpen, toClose)
 	}
-	return fw.flushInstancePorts(machined, toOpen, toClose)
+	return fw.flushInstanceRules(machined, toOpen, toClose)
 }
 
-// flushGlobalPorts opens and closes global ports in the environment.
-// It keeps a reference count for ports so that only 0-to-1 and 1-to-0 events
+// flushGlobalRules opens and closes global rules in the environment.
+// It keeps a reference count for rules so that only 0-to-1 and 1-to-0 events
 // modify the environment.
-func (fw *Firewaller) flushGlobalPorts(rawOpen, rawClose []network.Port) error {
-	// Filter which ports are really to open or close.
-	var toOpen, toClose []network.Port
-	for _, port := range rawOpen {
-		if fw.globalPortRef[port] == 0 {
-			toOpen = append(toOpen, port)
+func (fw *Firewaller) flushGlobalRules(rawOpen, rawClose []network.IngressRule) error {
+	// Filter which rules are really to open or close.
+	var toOpen, toClose []network.IngressRule
+	for _, rule := range rawOpen {
+		if fw.globalRuleRef[rule] == 0 {
+			toOpen = append(toOpen, rule)
 		}
-		fw.globalPortRef[port]++
+		fw.globalRuleRef[rule]++
 	}
-	for _, port := range rawClose {
-		fw.globalPortRef[port]--
-		if fw.globalPortRef[port] == 0 {
-			toClose = append(toClose, port)
-			delete(fw.globalPortRef, port)
+	for _, rule := range rawClose {
+		fw.globalRuleRef[rule]--
+		if fw.globalRuleRef[rule] == 0 {
+			toClose = append(toClose, rule)
+			delete(fw.globalRuleRef, rule)
 		}
 	}
-	// Open and close the ports.
+	// Open and close the rules.
 	if len(toOpen) > 0 {
-		if err := fw.environ.OpenPorts(toOpen); err != nil {
+		if err := fw.openGlobalRules(toOpen); err != nil {
 			// TODO(mue) Add local retry logic.
 			return err
 		}
-		network.SortPorts(toOpen)
-		logger.Infof("opened ports %v in environment", toOpen)
+		network.SortIngressRules(toOpen)
+		logger.Infof("opened rules %v in environment", toOpen)
 	}
 	if len(toClose) > 0 {
-		if err := fw.environ.ClosePorts(toClose); err != nil {
+		if err := fw.closeGlobalRules(toClose); err != nil {
 			// TODO(mue) Add local retry logic.
 			return err
 		}
-		network.SortPorts(toClose)
-		logger.Infof("closed ports %v in environment", toClose)
+		network.SortIngressRules(toClose)
+		logger.Infof("closed rules %v in environment", toClose)
 	}
 	return nil
 }
 
-// flushInstancePorts opens and closes ports global on the machine.
-func (fw *Firewaller) flushInstancePorts(machined *machineData, toOpen, toClose []network.Port) error {
+// flushInstanceRules opens and closes rules global on the machine.
+func (fw *Firewaller) flushInstanceRules(machined *machineData, toOpen, toClose []network.IngressRule) error {
 	// If there's nothing to do, do nothing.
 	// This is important because when a machine is first created,
 	// it will have no instance id but also no open ports -
@@ -485,26 +494,115 @@ func (fw *Firewaller) flushInstancePorts(machined *machineData, toOpen, toClose
 	if err != nil {
 		return err
 	}
-	// Open and close the ports.
+	// Open and close the rules.
 	if len(toOpen) > 0 {
-		if err := instances[0].OpenPorts(machineId, toOpen); err != nil {
+		if err := openInstanceRules(instances[0], machineId, toOpen); err != nil {
 			// TODO(mue) Add local retry logic.
 			return err
 		}
-		network.SortPorts(toOpen)
-		logger.Infof("opened ports %v on %q", toOpen, machined.tag)
+		network.SortIngressRules(toOpen)
+		logger.Infof("opened rules %v on %q", toOpen, machined.tag)
 	}
 	if len(toClose) > 0 {
-		if err := instances[0].ClosePorts(machineId, toClose); err != nil {
+		if err := closeInstanceRules(instances[0], machineId, toClose); err != nil {
 			// TODO(mue) Add local retry logic.
 			return err
 		}
-		network.SortPorts(toClose)
-		logger.Infof("closed ports %v on %q", toClose, machined.tag)
+		network.SortIngressRules(toClose)
+		logger.Infof("closed rules %v on %q", toClose, machined.tag)
+	}
+	return nil
+}
+
+// globalRules returns the rules opened for the whole environment.
+// If the environment cannot restrict ports to source CIDRs, the
+// opened ports are reported as unrestricted rules.
+func (fw *Firewaller) globalRules() ([]network.IngressRule, error) {
+	if ingress, ok := fw.environ.(environs.GlobalIngressFirewaller); ok {
+		return ingress.IngressRules()
+	}
+	ports, err := fw.environ.Ports()
+	if err != nil {
+		return nil, err
+	}
+	return network.IngressRulesForPorts(ports, nil), nil
+}
+
+// openGlobalRules opens the given rules for the whole environment.
+func (fw *Firewaller) openGlobalRules(rules []network.IngressRule) error {
+	if ingress, ok := fw.environ.(environs.GlobalIngressFirewaller); ok {
+		return ingress.OpenIngressRules(rules)
+	}
+	if ports := unrestrictedPorts(rules, true); len(ports) > 0 {
+		return fw.environ.OpenPorts(ports)
+	}
+	return nil
+}
+
+// closeGlobalRules closes the given rules for the whole environment.
+func (fw *Firewaller) closeGlobalRules(rules []network.IngressRule) error {
+	if ingress, ok := fw.environ.(environs.GlobalIngressFirewaller); ok {
+		return ingress.CloseIngressRules(rules)
+	}
+	if ports := unrestrictedPorts(rules, false); len(ports) > 0 {
+		return fw.environ.ClosePorts(ports)
 	}
 	return nil
 }
 
+// instanceRules returns the rules opened on the given instance.
+// If the instance cannot restrict ports to source CIDRs, the opened
+// ports are reported as unrestricted rules.
+func instanceRules(inst instance.Instance, machineId string) ([]network.IngressRule, error) {
+	if ingress, ok := inst.(environs.InstanceIngressFirewaller); ok {
+		return ingress.IngressRules(machineId)
+	}
+	ports, err := inst.Ports(machineId)
+	if err != nil {
+		return nil, err
+	}
+	return network.IngressRulesForPorts(ports, nil), nil
+}
+
+// openInstanceRules opens the given rules on the given instance.
+func openInstanceRules(inst instance.Instance, machineId string, rules []network.IngressRule) error {
+	if ingress, ok := inst.(environs.InstanceIngressFirewaller); ok {
+		return ingress.OpenIngressRules(machineId, rules)
+	}
+	if ports := unrestrictedPorts(rules, true); len(ports) > 0 {
+		return inst.OpenPorts(machineId, ports)
+	}
+	return nil
+}
+
+// closeInstanceRules closes the given rules on the given instance.
+func closeInstanceRules(inst instance.Instance, machineId string, rules []network.IngressRule) error {
+	if ingress, ok := inst.(environs.InstanceIngressFirewaller); ok {
+		return ingress.CloseIngressRules(machineId, rules)
+	}
+	if ports := unrestrictedPorts(rules, false); len(ports) > 0 {
+		return inst.ClosePorts(machineId, ports)
+	}
+	return nil
+}
+
+// unrestrictedPorts returns the ports of the given rules that allow
+// access from any address. It is used for providers that cannot
+// restrict ports to source CIDRs; restricted rules are never widened
+// to allow access from any address, and are dropped instead. If warn
+// is true, a warning is logged for each dropped rule.
+func unrestrictedPorts(rules []network.IngressRule, warn bool) []network.Port {
+	var ports []network.Port
+	for _, rule := range rules {
+		if rule.IsUnrestricted() {
+			ports = append(ports, rule.Port)
+		} else if warn {
+			logger.Warningf("cannot open %v: the provider does not support source CIDRs", rule)
+		}
+	}
+	return ports
+}
+
 // machineLifeChanged starts watching new machines when the firewaller
 // is starting, or when new machines come to life, and stops watching
 // machines that are dying.
@@ -619,7 +717,7 @@ type machineData struct {
 	fw     *Firewaller
 	tag    names.MachineTag
 	unitds map[string]*unitData
-	ports  []network.Port
+	rules  []network.IngressRule
 }
 
 func (md *machineData) machine() (*apifirewaller.Machine, error) {
@@ -673,6 +771,15 @@ type unitData struct {
 	ports    []network.Port
 }
 
+// wantedRules returns the ingress rules that should be open for the
+// unit, given its opened ports and its service's exposure.
+func (ud *unitData) wantedRules() []network.IngressRule {
+	if !ud.serviced.exposed {
+		return nil
+	}
+	return network.IngressRulesForPorts(ud.ports, ud.serviced.cidrs)
+}
+
 // watchLoop watches the unit for port changes.
 func (ud *unitData) watchLoop(latestPorts []network.Port) {
 	defer ud.tomb.Done()
@@ -735,10 +842,12 @@ func (ud *unitData) Stop() error {
 	return ud.tomb.Wait()
 }
 
-// exposedChange contains the changed exposed flag for one specific service.
+// exposedChange contains the changed exposed flag and source CIDRs
+// for one specific service.
 type exposedChange struct {
 	serviced *serviceData
 	exposed  bool
+	cidrs    []string
 }
 
 // serviceData holds service details and watches exposure changes.
@@ -747,11 +856,13 @@ type serviceData struct {
 	fw      *Firewaller
 	service *apifirewaller.Service
 	exposed bool
+	cidrs   []string
 	unitds  map[string]*unitData
 }
 
-// watchLoop watches the service's exposed flag for changes.
-func (sd *serviceData) watchLoop(exposed bool) {
+// watchLoop watches the service's exposed flag and source CIDRs
+// for changes.
+func (sd *serviceData) watchLoop(exposed bool, cidrs []string) {
 	defer sd.tomb.Done()
 	w, err := sd.service.Watch()
 	if err != nil {
@@ -779,12 +890,18 @@ func (sd *serviceData) watchLoop(exposed bool) {
 				sd.fw.tomb.Kill(err)
 				return
 			}
-			if change == exposed {
+			changeCIDRs, err := sd.service.IngressCIDRs()
+			if err != nil {
+				sd.fw.tomb.Kill(err)
+				return
+			}
+			if change == exposed && sameStrings(changeCIDRs, cidrs) {
 				continue
 			}
 			exposed = change
+			cidrs = changeCIDRs
 			select {
-			case sd.fw.exposedChange <- &exposedChange{sd, change}:
+			case sd.fw.exposedChange <- &exposedChange{sd, change, changeCIDRs}:
 			case <-sd.tomb.Dying():
 				return
 			}
@@ -792,14 +909,28 @@ func (sd *serviceData) watchLoop(exposed bool) {
 	}
 }
 
+// sameStrings returns whether old and new contain the same strings
+// in the same order.
+func sameStrings(old, new []string) bool {
+	if len(old) != len(new) {
+		return false
+	}
+	for i, s := range old {
+		if new[i] != s {
+			return false
+		}
+	}
+	return true
+}
+
 // Stop stops the service watching.
 func (sd *serviceData) Stop() error {
 	sd.tomb.Kill(nil)
 	return sd.tomb.Wait()
 }
 
-// Diff returns all the ports that exist in A but not B.
-func Diff(A, B []network.Port) (missing []network.Port) {
+// diffRules returns all the rules that exist in A but not B.
+func diffRules(A, B []network.IngressRule) (missing []network.IngressRule) {
 next:
 	for _, a := range A {
 		for _, b := range B {
@@ -11,6 +11,7 @@ import (
 	"github.com/juju/utils"
 	gc "launchpad.net/gocheck"
 
+	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/juju"
@@ -94,6 +95,56 @@ func (s *FirewallerSuite) assertEnvironPorts(c *gc.C, expected []network.Port) {
 	}
 }
 
+// assertRules retrieves the open ingress rules of the instance and
+// compares them to the expected.
+func (s *FirewallerSuite) assertRules(c *gc.C, inst instance.Instance, machineId string, expected []network.IngressRule) {
+	s.BackingState.StartSync()
+	start := time.Now()
+	for {
+		got, err := inst.(environs.InstanceIngressFirewaller).IngressRules(machineId)
+		if err != nil {
+			c.Fatal(err)
+			return
+		}
+		network.SortIngressRules(got)
+		network.SortIngressRules(expected)
+		if reflect.DeepEqual(got, expected) {
+			c.Succeed()
+			return
+		}
+		if time.Since(start) > coretesting.LongWait {
+			c.Fatalf("timed out: expected %q; got %q", expected, got)
+			return
+		}
+		time.Sleep(coretesting.ShortWait)
+	}
+}
+
+// assertEnvironRules retrieves the open ingress rules of the environment
+// and compares them to the expected.
+func (s *FirewallerSuite) assertEnvironRules(c *gc.C, expected []network.IngressRule) {
+	s.BackingState.StartSync()
+	start := time.Now()
+	for {
+		got, err := s.Environ.(environs.GlobalIngressFirewaller).IngressRules()
+		if err != nil {
+			c.Fatal(err)
+			return
+		}
+		network.SortIngressRules(got)
+		network.SortIngressRules(expected)
+		if reflect.DeepEqual(got, expected) {
+			c.Succeed()
+			return
+		}
+		if time.Since(start) > coretesting.LongWait {
+			c.Fatalf("timed out: expected %q; got %q", expected, got)
+			return
+		}
+		time.Sleep(coretesting.ShortWait)
+	}
+}
+
 var _ = gc.Suite(&FirewallerSuite{})
 
 func (s FirewallerGlobalModeSuite) SetUpTest(c *gc.C) {
@@ -399,6 +450,81 @@ func (s *FirewallerSuite) TestSetClearExposedService(c *gc.C) {
 	s.assertPorts(c, inst, m.Id(), nil)
 }
 
+func (s *FirewallerSuite) TestExposedFromCIDRs(c *gc.C) {
+	fw, err := firewaller.NewFirewaller(s.firewaller)
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(fw.Stop(), gc.IsNil) }()
+
+	svc := s.AddTestingService(c, "wordpress", s.charm)
+	err = svc.SetExposedFrom([]string{"10.0.0.0/8", "192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+
+	u, m := s.addUnit(c, svc)
+	inst := s.startInstance(c, m)
+	err = u.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+
+	// The port is only opened to the given CIDRs.
+	s.assertRules(c, inst, m.Id(), []network.IngressRule{
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+		{network.Port{"tcp", 80}, "192.168.1.0/24"},
+	})
+	s.assertPorts(c, inst, m.Id(), nil)
+
+	// Changing the CIDRs replaces the rules.
+	err = svc.SetExposedFrom([]string{"172.16.0.0/12"})
+	c.Assert(err, gc.IsNil)
+	s.assertRules(c, inst, m.Id(), []network.IngressRule{
+		{network.Port{"tcp", 80}, "172.16.0.0/12"},
+	})
+
+	// IPv6 CIDRs are handled like IPv4 ones.
+	err = svc.SetExposedFrom([]string{"2001:db8::/32", "172.16.0.0/12"})
+	c.Assert(err, gc.IsNil)
+	s.assertRules(c, inst, m.Id(), []network.IngressRule{
+		{network.Port{"tcp", 80}, "172.16.0.0/12"},
+		{network.Port{"tcp", 80}, "2001:db8::/32"},
+	})
+
+	// Exposing to any address opens the port to everyone.
+	err = svc.SetExposed()
+	c.Assert(err, gc.IsNil)
+	s.assertRules(c, inst, m.Id(), []network.IngressRule{
+		{network.Port{"tcp", 80}, "0.0.0.0/0"},
+	})
+	s.assertPorts(c, inst, m.Id(), []network.Port{{"tcp", 80}})
+
+	// Unexposing closes everything.
+	err = svc.ClearExposed()
+	c.Assert(err, gc.IsNil)
+	s.assertRules(c, inst, m.Id(), nil)
+}
+
+func (s *FirewallerSuite) TestStartWithExposedFromCIDRs(c *gc.C) {
+	svc := s.AddTestingService(c, "wordpress", s.charm)
+	err := svc.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+
+	u, m := s.addUnit(c, svc)
+	inst := s.startInstance(c, m)
+	err = u.OpenPort("tcp", 443)
+	c.Assert(err, gc.IsNil)
+
+	// Open a rule that should not be there.
+	err = inst.(environs.InstanceIngressFirewaller).OpenIngressRules(m.Id(), []network.IngressRule{
+		{network.Port{"tcp", 443}, "0.0.0.0/0"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	fw, err := firewaller.NewFirewaller(s.firewaller)
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(fw.Stop(), gc.IsNil) }()
+
+	s.assertRules(c, inst, m.Id(), []network.IngressRule{
+		{network.Port{"tcp", 443}, "10.0.0.0/8"},
+	})
+}
+
 func (s *FirewallerSuite) TestRemoveUnit(c *gc.C) {
 	fw, err := firewaller.NewFirewaller(s.firewaller)
 	c.Assert(err, gc.IsNil)
@@ -613,6 +739,43 @@ func (s *FirewallerGlobalModeSuite) TestGlobalMode(c *gc.C) {
 	s.assertEnvironPorts(c, nil)
 }
 
+func (s *FirewallerGlobalModeSuite) TestGlobalModeExposedFromCIDRs(c *gc.C) {
+	fw, err := firewaller.NewFirewaller(s.firewaller)
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(fw.Stop(), gc.IsNil) }()
+
+	svc1 := s.AddTestingService(c, "wordpress", s.charm)
+	err = svc1.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+
+	u1, m1 := s.addUnit(c, svc1)
+	s.startInstance(c, m1)
+	err = u1.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+
+	svc2 := s.AddTestingService(c, "moinmoin", s.charm)
+	err = svc2.SetExposed()
+	c.Assert(err, gc.IsNil)
+
+	u2, m2 := s.addUnit(c, svc2)
+	s.startInstance(c, m2)
+	err = u2.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+
+	s.assertEnvironRules(c, []network.IngressRule{
+		{network.Port{"tcp", 80}, "0.0.0.0/0"},
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+	})
+
+	// Unexposing the unrestricted service leaves the restricted rule.
+	err = svc2.ClearExposed()
+	c.Assert(err, gc.IsNil)
+	s.assertEnvironRules(c, []network.IngressRule{
+		{network.Port{"tcp", 80}, "10.0.0.0/8"},
+	})
+	s.assertEnvironPorts(c, nil)
+}
+
 func (s *FirewallerGlobalModeSuite) TestGlobalModeStartWithUnexposedService(c *gc.C) {
 	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
 	c.Assert(err, gc.IsNil)
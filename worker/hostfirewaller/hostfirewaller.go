@@ -0,0 +1,104 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller
+
+import (
+	"reflect"
+
+	"github.com/juju/loggo"
+
+	"github.com/juju/juju/agent"
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state/api/hostfirewaller"
+	"github.com/juju/juju/state/api/watcher"
+	"github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.hostfirewaller")
+
+// HostFirewaller manages the iptables rules of the machine it runs on
+// when the environment uses the "host" firewall mode, so that the
+// ports opened by its units are only reachable from the source CIDRs
+// their services were exposed to.
+type HostFirewaller struct {
+	st      *hostfirewaller.State
+	tag     string
+	enabled bool
+
+	// appliedRules and appliedPorts hold the rules and ports last
+	// applied to the host; appliedRules is nil until rules have been
+	// applied.
+	appliedRules []network.IngressRule
+	appliedPorts []network.Port
+}
+
+var _ worker.NotifyWatchHandler = (*HostFirewaller)(nil)
+
+// NewHostFirewaller returns a worker that keeps the host firewall of
+// the agent's machine in sync with the machine's ingress rules.
+func NewHostFirewaller(st *hostfirewaller.State, agentConfig agent.Config) worker.Worker {
+	return worker.NewNotifyWorker(&HostFirewaller{
+		st:  st,
+		tag: agentConfig.Tag().String(),
+	})
+}
+
+// SetUp is defined on the worker.NotifyWatchHandler interface.
+func (fw *HostFirewaller) SetUp() (watcher.NotifyWatcher, error) {
+	cfg, err := fw.st.EnvironConfig()
+	if err != nil {
+		return nil, err
+	}
+	if mode := cfg.FirewallMode(); mode != config.FwHost {
+		// The firewall mode cannot change, so there is nothing for
+		// the worker to do; it still watches the environment
+		// configuration so it can be stopped like any other.
+		logger.Debugf("firewall mode is %q, not managing host firewall", mode)
+		return fw.st.WatchForEnvironConfigChanges()
+	}
+	fw.enabled = true
+	return fw.st.WatchIngressRules(fw.tag)
+}
+
+// Handle is defined on the worker.NotifyWatchHandler interface.
+func (fw *HostFirewaller) Handle() error {
+	if !fw.enabled {
+		return nil
+	}
+	rules, ports, err := fw.st.IngressRules(fw.tag)
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		rules = []network.IngressRule{}
+	}
+	if fw.appliedRules != nil &&
+		reflect.DeepEqual(rules, fw.appliedRules) &&
+		reflect.DeepEqual(ports, fw.appliedPorts) {
+		return nil
+	}
+	logger.Infof("applying host firewall rules %v to ports %v", rules, ports)
+	var commands []string
+	if fw.appliedRules == nil {
+		// The chain may hold anything, so replace it entirely.
+		commands = iptablesCommands(rules, ports)
+	} else {
+		commands = iptablesDeltaCommands(fw.appliedRules, fw.appliedPorts, rules, ports)
+	}
+	if err := ExecuteCommands(commands); err != nil {
+		// Some of the commands may have been applied; the worker
+		// is restarted and so replaces the chain.
+		return err
+	}
+	fw.appliedRules, fw.appliedPorts = rules, ports
+	return nil
+}
+
+// TearDown is defined on the worker.NotifyWatchHandler interface.
+func (fw *HostFirewaller) TearDown() error {
+	// The rules are left in place, so that ports are not opened
+	// to everyone while the agent is restarting.
+	return nil
+}
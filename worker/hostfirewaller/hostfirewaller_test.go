@@ -0,0 +1,173 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/agent"
+	"github.com/juju/juju/environs/config"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/provider/dummy"
+	"github.com/juju/juju/state"
+	apihostfirewaller "github.com/juju/juju/state/api/hostfirewaller"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/hostfirewaller"
+)
+
+type HostFirewallerSuite struct {
+	jujutesting.JujuConnSuite
+
+	machine  *state.Machine
+	unit     *state.Unit
+	api      *apihostfirewaller.State
+	commands chan []string
+}
+
+var _ = gc.Suite(&HostFirewallerSuite{})
+
+type HostFirewallerInstanceModeSuite struct {
+	HostFirewallerSuite
+}
+
+var _ = gc.Suite(&HostFirewallerInstanceModeSuite{})
+
+func (s *HostFirewallerSuite) SetUpTest(c *gc.C) {
+	if s.DummyConfig == nil {
+		add := map[string]interface{}{"firewall-mode": config.FwHost}
+		s.DummyConfig = dummy.SampleConfig().Merge(add).Delete("admin-secret", "ca-private-key")
+	}
+	s.JujuConnSuite.SetUpTest(c)
+
+	st, machine := s.OpenAPIAsNewMachine(c)
+	s.machine = machine
+	s.api = st.HostFirewaller()
+	c.Assert(s.api, gc.NotNil)
+
+	service := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	var err error
+	s.unit, err = service.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = s.unit.AssignToMachine(s.machine)
+	c.Assert(err, gc.IsNil)
+	err = service.SetExposedFrom([]string{"10.0.0.0/8"})
+	c.Assert(err, gc.IsNil)
+
+	s.commands = make(chan []string, 10)
+	s.PatchValue(&hostfirewaller.ExecuteCommands, func(commands []string) error {
+		s.commands <- commands
+		return nil
+	})
+}
+
+func (s *HostFirewallerInstanceModeSuite) SetUpTest(c *gc.C) {
+	s.DummyConfig = dummy.SampleConfig().Delete("admin-secret", "ca-private-key")
+	s.HostFirewallerSuite.SetUpTest(c)
+}
+
+func (s *HostFirewallerSuite) makeWorker(c *gc.C) worker.Worker {
+	return hostfirewaller.NewHostFirewaller(s.api, &mockConfig{tag: s.machine.Tag()})
+}
+
+func (s *HostFirewallerSuite) assertCommands(c *gc.C, expected []string) {
+	select {
+	case commands := <-s.commands:
+		c.Assert(commands, jc.DeepEquals, expected)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for commands")
+	}
+}
+
+func (s *HostFirewallerSuite) assertNoCommands(c *gc.C) {
+	select {
+	case commands := <-s.commands:
+		c.Fatalf("unexpected commands: %v", commands)
+	case <-time.After(coretesting.ShortWait):
+	}
+}
+
+func (s *HostFirewallerSuite) TestAppliesRules(c *gc.C) {
+	fw := s.makeWorker(c)
+	defer func() { c.Assert(worker.Stop(fw), gc.IsNil) }()
+
+	// The initial rules are always applied.
+	s.assertCommands(c, hostfirewaller.IptablesCommands(nil, nil))
+
+	// Later changes are applied as deltas.
+	err := s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	s.assertCommands(c, []string{
+		"iptables -I juju-ingress 1 -p tcp --dport 80 -s 10.0.0.0/8 -j ACCEPT",
+		"iptables -A juju-ingress -p tcp --dport 80 -j DROP",
+		"ip6tables -A juju-ingress -p tcp --dport 80 -j DROP",
+	})
+	s.assertNoCommands(c)
+
+	service, err := s.unit.Service()
+	c.Assert(err, gc.IsNil)
+	err = service.SetExposedFrom([]string{"192.168.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	s.assertCommands(c, []string{
+		"iptables -I juju-ingress 1 -p tcp --dport 80 -s 192.168.1.0/24 -j ACCEPT",
+		"iptables -D juju-ingress -p tcp --dport 80 -s 10.0.0.0/8 -j ACCEPT",
+	})
+	s.assertNoCommands(c)
+}
+
+func (s *HostFirewallerSuite) TestReplacesRulesAfterFailure(c *gc.C) {
+	failed := false
+	s.PatchValue(&hostfirewaller.ExecuteCommands, func(commands []string) error {
+		s.commands <- commands
+		if !failed && len(commands) > 0 && commands[0] != hostfirewaller.IptablesCommands(nil, nil)[0] {
+			failed = true
+			return fmt.Errorf("boom")
+		}
+		return nil
+	})
+	fw := s.makeWorker(c)
+	defer func() { c.Assert(worker.Stop(fw), gc.IsNil) }()
+	s.assertCommands(c, hostfirewaller.IptablesCommands(nil, nil))
+
+	err := s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	s.assertCommands(c, []string{
+		"iptables -I juju-ingress 1 -p tcp --dport 80 -s 10.0.0.0/8 -j ACCEPT",
+		"iptables -A juju-ingress -p tcp --dport 80 -j DROP",
+		"ip6tables -A juju-ingress -p tcp --dport 80 -j DROP",
+	})
+
+	// The failed delta may have been partly applied, so the
+	// whole chain is replaced when the worker is restarted.
+	c.Assert(fw.Wait(), gc.ErrorMatches, "boom")
+	fw = s.makeWorker(c)
+	s.assertCommands(c, hostfirewaller.IptablesCommands(
+		[]network.IngressRule{{network.Port{"tcp", 80}, "10.0.0.0/8"}},
+		[]network.Port{{"tcp", 80}},
+	))
+}
+
+func (s *HostFirewallerInstanceModeSuite) TestAppliesRules(c *gc.C) {
+	fw := s.makeWorker(c)
+	defer func() { c.Assert(worker.Stop(fw), gc.IsNil) }()
+
+	err := s.unit.OpenPort("tcp", 80)
+	c.Assert(err, gc.IsNil)
+	s.assertNoCommands(c)
+}
+
+type mockConfig struct {
+	agent.Config
+	tag names.Tag
+}
+
+func (mock *mockConfig) Tag() names.Tag {
+	return mock.tag
+}
@@ -0,0 +1,142 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/utils/exec"
+
+	"github.com/juju/juju/network"
+)
+
+// chainName is the name of the iptables chain managed by the worker.
+// It is jumped to from the INPUT chain.
+const chainName = "juju-ingress"
+
+// ExecuteCommands is defined here for easier patching when testing.
+var ExecuteCommands = executeCommands
+
+// executeCommands executes a batch of commands one by one, stopping
+// at the first failure.
+func executeCommands(commands []string) error {
+	for _, command := range commands {
+		result, err := exec.RunCommands(exec.RunParams{
+			Commands:   command,
+			WorkingDir: "/",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute %q: %v", command, err)
+		}
+		if result.Code != 0 {
+			return fmt.Errorf("command %q failed (code: %d, stdout: %s, stderr: %s)",
+				command, result.Code, result.Stdout, result.Stderr)
+		}
+		logger.Debugf("command %q (code: %d, stdout: %s, stderr: %s)",
+			command, result.Code, result.Stdout, result.Stderr)
+	}
+	return nil
+}
+
+// iptablesCommands returns the commands that atomically replace the
+// contents of the managed chain with the given rules, for both IPv4 and
+// IPv6, and make sure the chain is jumped to from the INPUT chain. They
+// are used when the chain's contents are not known, as when the worker
+// starts.
+//
+// Traffic to the given ports is accepted from the rules' source CIDRs
+// and dropped from anywhere else. Traffic to other ports is left alone,
+// so that the rest of the host's firewall configuration (e.g. for SSH
+// and the juju agents) is unaffected.
+func iptablesCommands(rules []network.IngressRule, ports []network.Port) []string {
+	var commands []string
+	for _, cmd := range []string{"iptables", "ip6tables"} {
+		// Declaring the chain in the restore input flushes it even with
+		// --noflush, and the new contents are committed in one go, so
+		// the chain is never seen empty or partially filled.
+		lines := []string{
+			"*filter",
+			fmt.Sprintf(":%s - [0:0]", chainName),
+		}
+		accepts, drops := ruleSpecs(cmd, rules, ports)
+		for _, spec := range append(accepts, drops...) {
+			lines = append(lines, fmt.Sprintf("-A %s %s", chainName, spec))
+		}
+		lines = append(lines, "COMMIT")
+		commands = append(commands,
+			fmt.Sprintf("%s-restore --noflush <<'EOF'\n%s\nEOF", cmd, strings.Join(lines, "\n")),
+			fmt.Sprintf("%s -C INPUT -j %s 2>/dev/null || %s -I INPUT -j %s", cmd, chainName, cmd, chainName),
+		)
+	}
+	return commands
+}
+
+// iptablesDeltaCommands returns the commands that change the contents
+// of the managed chain from the old rules and ports to the new ones,
+// for both IPv4 and IPv6, leaving the rules common to both in place.
+//
+// New ACCEPT rules are inserted at the head of the chain and new DROP
+// rules appended to it, so that every ACCEPT rule precedes the DROP
+// rules. Rules are added before old ones are deleted, so traffic that
+// is allowed before and after the change is never dropped.
+func iptablesDeltaCommands(oldRules []network.IngressRule, oldPorts []network.Port, rules []network.IngressRule, ports []network.Port) []string {
+	var commands []string
+	for _, cmd := range []string{"iptables", "ip6tables"} {
+		oldAccepts, oldDrops := ruleSpecs(cmd, oldRules, oldPorts)
+		accepts, drops := ruleSpecs(cmd, rules, ports)
+		for _, spec := range difference(accepts, oldAccepts) {
+			commands = append(commands, fmt.Sprintf("%s -I %s 1 %s", cmd, chainName, spec))
+		}
+		for _, spec := range difference(drops, oldDrops) {
+			commands = append(commands, fmt.Sprintf("%s -A %s %s", cmd, chainName, spec))
+		}
+		for _, spec := range difference(oldAccepts, accepts) {
+			commands = append(commands, fmt.Sprintf("%s -D %s %s", cmd, chainName, spec))
+		}
+		for _, spec := range difference(oldDrops, drops) {
+			commands = append(commands, fmt.Sprintf("%s -D %s %s", cmd, chainName, spec))
+		}
+	}
+	return commands
+}
+
+// ruleSpecs returns the specifications of the ACCEPT rules for the
+// given ingress rules, and of the DROP rules for the given ports, that
+// apply to the address family of the given iptables command.
+func ruleSpecs(cmd string, rules []network.IngressRule, ports []network.Port) (accepts, drops []string) {
+	for _, rule := range rules {
+		source := ""
+		if !rule.IsUnrestricted() {
+			if network.IsIPv6CIDR(rule.SourceCIDR) != (cmd == "ip6tables") {
+				continue
+			}
+			source = " -s " + rule.SourceCIDR
+		}
+		accepts = append(accepts, fmt.Sprintf(
+			"-p %s --dport %d%s -j ACCEPT", rule.Protocol, rule.Number, source,
+		))
+	}
+	for _, port := range ports {
+		drops = append(drops, fmt.Sprintf(
+			"-p %s --dport %d -j DROP", port.Protocol, port.Number,
+		))
+	}
+	return accepts, drops
+}
+
+// difference returns the elements of a that are not in b.
+func difference(a, b []string) []string {
+	inB := make(map[string]bool)
+	for _, s := range b {
+		inB[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if !inB[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package hostfirewaller_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/hostfirewaller"
+)
+
+type iptablesSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&iptablesSuite{})
+
+func (*iptablesSuite) TestIptablesCommandsNoRules(c *gc.C) {
+	c.Assert(hostfirewaller.IptablesCommands(nil, nil), jc.DeepEquals, []string{
+		"iptables-restore --noflush <<'EOF'\n" +
+			"*filter\n" +
+			":juju-ingress - [0:0]\n" +
+			"COMMIT\n" +
+			"EOF",
+		"iptables -C INPUT -j juju-ingress 2>/dev/null || iptables -I INPUT -j juju-ingress",
+		"ip6tables-restore --noflush <<'EOF'\n" +
+			"*filter\n" +
+			":juju-ingress - [0:0]\n" +
+			"COMMIT\n" +
+			"EOF",
+		"ip6tables -C INPUT -j juju-ingress 2>/dev/null || ip6tables -I INPUT -j juju-ingress",
+	})
+}
+
+func (*iptablesSuite) TestIptablesCommands(c *gc.C) {
+	rules := []network.IngressRule{
+		{network.Port{"tcp", 80}, "0.0.0.0/0"},
+		{network.Port{"tcp", 443}, "10.0.0.0/8"},
+		{network.Port{"tcp", 443}, "2001:db8::/32"},
+		{network.Port{"udp", 53}, "192.168.1.0/24"},
+	}
+	ports := []network.Port{{"tcp", 80}, {"tcp", 443}, {"tcp", 8080}, {"udp", 53}}
+	c.Assert(hostfirewaller.IptablesCommands(rules, ports), jc.DeepEquals, []string{
+		"iptables-restore --noflush <<'EOF'\n" +
+			"*filter\n" +
+			":juju-ingress - [0:0]\n" +
+			"-A juju-ingress -p tcp --dport 80 -j ACCEPT\n" +
+			"-A juju-ingress -p tcp --dport 443 -s 10.0.0.0/8 -j ACCEPT\n" +
+			"-A juju-ingress -p udp --dport 53 -s 192.168.1.0/24 -j ACCEPT\n" +
+			"-A juju-ingress -p tcp --dport 80 -j DROP\n" +
+			"-A juju-ingress -p tcp --dport 443 -j DROP\n" +
+			"-A juju-ingress -p tcp --dport 8080 -j DROP\n" +
+			"-A juju-ingress -p udp --dport 53 -j DROP\n" +
+			"COMMIT\n" +
+			"EOF",
+		"iptables -C INPUT -j juju-ingress 2>/dev/null || iptables -I INPUT -j juju-ingress",
+		"ip6tables-restore --noflush <<'EOF'\n" +
+			"*filter\n" +
+			":juju-ingress - [0:0]\n" +
+			"-A juju-ingress -p tcp --dport 80 -j ACCEPT\n" +
+			"-A juju-ingress -p tcp --dport 443 -s 2001:db8::/32 -j ACCEPT\n" +
+			"-A juju-ingress -p tcp --dport 80 -j DROP\n" +
+			"-A juju-ingress -p tcp --dport 443 -j DROP\n" +
+			"-A juju-ingress -p tcp --dport 8080 -j DROP\n" +
+			"-A juju-ingress -p udp --dport 53 -j DROP\n" +
+			"COMMIT\n" +
+			"EOF",
+		"ip6tables -C INPUT -j juju-ingress 2>/dev/null || ip6tables -I INPUT -j juju-ingress",
+	})
+}
+
+func (*iptablesSuite) TestIptablesDeltaCommands(c *gc.C) {
+	oldRules := []network.IngressRule{
+		{network.Port{"tcp", 80}, "0.0.0.0/0"},
+		{network.Port{"tcp", 443}, "10.0.0.0/8"},
+		{network.Port{"tcp", 443}, "2001:db8::/32"},
+	}
+	oldPorts := []network.Port{{"tcp", 80}, {"tcp", 443}}
+	rules := []network.IngressRule{
+		{network.Port{"tcp", 80}, "192.168.1.0/24"},
+		{network.Port{"tcp", 443}, "10.0.0.0/8"},
+		{network.Port{"tcp", 443}, "2001:db8::/32"},
+		{network.Port{"udp", 53}, "10.0.0.0/8"},
+	}
+	ports := []network.Port{{"tcp", 80}, {"tcp", 443}, {"udp", 53}}
+	c.Assert(hostfirewaller.IptablesDeltaCommands(oldRules, oldPorts, rules, ports), jc.DeepEquals, []string{
+		"iptables -I juju-ingress 1 -p tcp --dport 80 -s 192.168.1.0/24 -j ACCEPT",
+		"iptables -I juju-ingress 1 -p udp --dport 53 -s 10.0.0.0/8 -j ACCEPT",
+		"iptables -A juju-ingress -p udp --dport 53 -j DROP",
+		"iptables -D juju-ingress -p tcp --dport 80 -j ACCEPT",
+		"ip6tables -A juju-ingress -p udp --dport 53 -j DROP",
+		"ip6tables -D juju-ingress -p tcp --dport 80 -j ACCEPT",
+	})
+
+	// Closing ports removes their rules.
+	c.Assert(hostfirewaller.IptablesDeltaCommands(rules, ports, nil, nil), jc.DeepEquals, []string{
+		"iptables -D juju-ingress -p tcp --dport 80 -s 192.168.1.0/24 -j ACCEPT",
+		"iptables -D juju-ingress -p tcp --dport 443 -s 10.0.0.0/8 -j ACCEPT",
+		"iptables -D juju-ingress -p udp --dport 53 -s 10.0.0.0/8 -j ACCEPT",
+		"iptables -D juju-ingress -p tcp --dport 80 -j DROP",
+		"iptables -D juju-ingress -p tcp --dport 443 -j DROP",
+		"iptables -D juju-ingress -p udp --dport 53 -j DROP",
+		"ip6tables -D juju-ingress -p tcp --dport 443 -s 2001:db8::/32 -j ACCEPT",
+		"ip6tables -D juju-ingress -p tcp --dport 80 -j DROP",
+		"ip6tables -D juju-ingress -p tcp --dport 443 -j DROP",
+		"ip6tables -D juju-ingress -p udp --dport 53 -j DROP",
+	})
+
+	// Nothing is done when nothing changes.
+	c.Assert(hostfirewaller.IptablesDeltaCommands(rules, ports, rules, ports), gc.HasLen, 0)
+}
@@ -115,6 +115,12 @@ func ifaceConfigFileName(ifaceName string) string {
 	return filepath.Join(configSubDirName, ifaceName+".cfg")
 }
 
+// ifaceNameFromConfigFileName returns the name of the interface whose
+// configuration is stored in fileName.
+func ifaceNameFromConfigFileName(fileName string) string {
+	return strings.TrimSuffix(filepath.Base(fileName), ".cfg")
+}
+
 // managedPrefix is the prefix that always presents in configuration file for interfaces managed by juju.
 const managedPrefix = "# Managed by Juju, don't change.\n"
 
@@ -128,9 +134,10 @@ func (cf ConfigFiles) addManaged(ifaceName, configText string) {
 
 // removeManaged marks ifaceName configuration to be removed.
 func (cf ConfigFiles) removeManaged(ifaceName string) {
-	if cf[ifaceConfigFileName(ifaceName)] != nil {
-		cf[ifaceName].Data = ""
-		cf[ifaceName].Op = doRemove
+	fileName := ifaceConfigFileName(ifaceName)
+	if cf[fileName] != nil {
+		cf[fileName].Data = ""
+		cf[fileName].Op = doRemove
 	}
 }
 
@@ -146,9 +153,10 @@ func (cf ConfigFiles) isManaged(ifaceName string) bool {
 
 // isChanged checks whether the configuration text for ifaceName has changed.
 func (cf ConfigFiles) isChanged(ifaceName, configText string) bool {
+	fileName := ifaceConfigFileName(ifaceName)
 	return ifaceName != privateInterface &&
 		ifaceName != privateBridge &&
-		(cf[ifaceName] == nil || cf[ifaceName].Data != managedPrefix+configText)
+		(cf[fileName] == nil || cf[fileName].Data != managedPrefix+configText)
 }
 
 // filterManaged filters out interfaces that are not managed by juju.
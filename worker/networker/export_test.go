@@ -5,6 +5,8 @@ package networker
 
 import (
 	"path/filepath"
+
+	"github.com/juju/juju/network"
 )
 
 const (
@@ -33,3 +35,26 @@ func ChangeConfigDirName(dirName string) {
 	ConfigFileName = configFileName
 	ConfigSubDirName = configSubDirName
 }
+
+// SetPrivateInterfaces sets the interface and bridge of the juju
+// internal network, which are never managed by the networker.
+func SetPrivateInterfaces(iface, bridge string) {
+	privateInterface = iface
+	privateBridge = bridge
+}
+
+// BringUpInterfaces returns the commands generated to bring up the
+// interfaces described by info, updating files accordingly.
+func BringUpInterfaces(files ConfigFiles, info []network.Info) []string {
+	s := &configState{configFiles: files, networkInfo: info}
+	s.bringUpInterfaces()
+	return s.commands
+}
+
+// BringDownInterfaces returns the commands generated to bring down the
+// interfaces that are no longer needed or need reconfiguring.
+func BringDownInterfaces(files ConfigFiles, info []network.Info) []string {
+	s := &configState{configFiles: files, networkInfo: info}
+	s.bringDownInterfaces()
+	return s.commands
+}
@@ -23,9 +23,10 @@ var privateBridge string
 
 // networker configures network interfaces on the machine, as needed.
 type networker struct {
-	st                     *apinetworker.State
-	tag                    string
-	isVLANSupportInstalled bool
+	st                        *apinetworker.State
+	tag                       string
+	isVLANSupportInstalled    bool
+	isBondingSupportInstalled bool
 }
 
 // NewNetworker returns a Worker that handles machine networking
@@ -101,6 +102,12 @@ func (nw *networker) Handle() error {
 		nw.isVLANSupportInstalled = true
 	}
 
+	// Add commands to install bonding support, if required.
+	if !nw.isBondingSupportInstalled && s.hasBonds() {
+		s.ensureBondingModule()
+		nw.isBondingSupportInstalled = true
+	}
+
 	// Up configured interfaces.
 	s.bringUpInterfaces()
 	if err = s.apply(); err != nil {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/juju/utils/set"
+
 	"github.com/juju/juju/network"
 )
 
@@ -39,59 +41,85 @@ func (s *configState) resetCommands() {
 	s.commands = []string{}
 }
 
+// desiredConfigs returns the configuration text of each interface
+// that needs to be configured, keyed by the actual interface name.
+// Besides the enabled interfaces from the state, this includes the
+// interfaces enslaved by enabled bonded interfaces.
+func (s *configState) desiredConfigs() (configs map[string]string, slaves []string) {
+	configs = make(map[string]string)
+	for _, info := range s.networkInfo {
+		if info.Disabled {
+			continue
+		}
+		ifaceName := info.ActualInterfaceName()
+		configs[ifaceName] = s.configText(ifaceName, &info)
+		if info.IsBond() && info.VLANTag == 0 {
+			for _, slave := range info.BondSlaves {
+				configs[slave] = slaveConfigText(slave, ifaceName, info.MTU)
+				slaves = append(slaves, slave)
+			}
+		}
+	}
+	return configs, slaves
+}
+
 // bringUpInterfaces generates a set of ifup commands to bring up required interfaces.
 func (s *configState) bringUpInterfaces() {
-	upIfaces := []string{}
+	configs, slaves := s.desiredConfigs()
 
-	// Iterate by state networks infos.
-	for _, info := range s.networkInfo {
-		ifaceName := info.ActualInterfaceName()
+	// Remove configurations of interfaces that are no longer needed.
+	for fileName, _ := range s.configFiles {
+		ifaceName := ifaceNameFromConfigFileName(fileName)
+		if _, ok := configs[ifaceName]; !ok {
+			s.configFiles.removeManaged(ifaceName)
+		}
+	}
+
+	upIfaces := []string{}
+	for ifaceName, configText := range configs {
 		if ifaceName != privateInterface && ifaceName != privateBridge {
-			if info.Disabled {
-				s.configFiles.removeManaged(ifaceName)
-			} else {
-				configText := s.configText(ifaceName, &info)
-				if s.configFiles.isChanged(ifaceName, configText) {
-					s.configFiles.addManaged(ifaceName, configText)
-					upIfaces = append(upIfaces, ifaceName)
-				} else if !InterfaceIsUp(ifaceName) {
-					upIfaces = append(upIfaces, ifaceName)
-				}
+			if s.configFiles.isChanged(ifaceName, configText) {
+				s.configFiles.addManaged(ifaceName, configText)
+				upIfaces = append(upIfaces, ifaceName)
+			} else if !InterfaceIsUp(ifaceName) {
+				upIfaces = append(upIfaces, ifaceName)
 			}
 		}
 	}
 
 	// Sort the interfaces to ensure that raw interface goes up before his virtual descendants.
-	// E.g. eth1 go up before eth1.42 and eth1:2.
+	// E.g. eth1 go up before eth1.42 and eth1:2. Bond slaves go up
+	// before any other interface, so bonds find them ready.
 	sort.Sort(sort.StringSlice(upIfaces))
+	isSlave := set.NewStrings(slaves...)
+	var slavesUp, othersUp []string
 	for _, ifaceName := range upIfaces {
-		s.commands = append(s.commands, "ifup "+ifaceName)
-	}
-}
-
-// lookupNetworkInfo lookups for in network.Info slice for interface by its actual name.
-func (s *configState) lookupNetworkInfo(ifaceName string) *network.Info {
-	for _, info := range s.networkInfo {
-		if ifaceName == info.ActualInterfaceName() {
-			return &info
+		if isSlave.Contains(ifaceName) {
+			slavesUp = append(slavesUp, ifaceName)
+		} else {
+			othersUp = append(othersUp, ifaceName)
 		}
 	}
-	return nil
+	for _, ifaceName := range append(slavesUp, othersUp...) {
+		s.commands = append(s.commands, "ifup "+ifaceName)
+	}
 }
 
 // bringDownInterfaces generates a set of commands to down unneeded interfaces.
 // Changes to config files are done by bringUpInterfaces.
 func (s *configState) bringDownInterfaces() {
+	configs, _ := s.desiredConfigs()
 	downIfaces := []string{}
 
 	// Iterate by existing config files.
-	for ifaceName, _ := range s.configFiles {
+	for fileName, _ := range s.configFiles {
+		ifaceName := ifaceNameFromConfigFileName(fileName)
 		if ifaceName != privateInterface && ifaceName != privateBridge {
-			// Interface goes down if it was disabled or its config was changed
-			info := s.lookupNetworkInfo(ifaceName)
-			if (info == nil || info.Disabled) && InterfaceIsUp(ifaceName) {
+			// Interface goes down if it is not needed anymore or its config was changed
+			configText, ok := configs[ifaceName]
+			if !ok && InterfaceIsUp(ifaceName) {
 				downIfaces = append(downIfaces, ifaceName)
-			} else if s.configFiles.isChanged(ifaceName, s.configText(ifaceName, info)) {
+			} else if ok && s.configFiles.isChanged(ifaceName, configText) {
 				downIfaces = append(downIfaces, ifaceName)
 			}
 		}
@@ -103,6 +131,16 @@ func (s *configState) bringDownInterfaces() {
 	}
 }
 
+// hasBonds reports whether any enabled interface is a bond.
+func (s *configState) hasBonds() bool {
+	for _, info := range s.networkInfo {
+		if !info.Disabled && info.IsBond() {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *configState) ensureVLANModule() {
 	commands := []string{
 		`dpkg-query -s vlan || apt-get --option Dpkg::Options::=--force-confold --assume-yes install vlan`,
@@ -113,6 +151,15 @@ func (s *configState) ensureVLANModule() {
 	s.commands = append(s.commands, commands...)
 }
 
+func (s *configState) ensureBondingModule() {
+	commands := []string{
+		`dpkg-query -s ifenslave || apt-get --option Dpkg::Options::=--force-confold --assume-yes install ifenslave`,
+		`lsmod | grep -q bonding || modprobe bonding`,
+		`grep -q bonding /etc/modules || echo bonding >> /etc/modules`,
+	}
+	s.commands = append(s.commands, commands...)
+}
+
 // configText generate configuration text for interface based on its configuration.
 func (s *configState) configText(interfaceName string, info *network.Info) string {
 	if info == nil {
@@ -127,5 +174,31 @@ func (s *configState) configText(interfaceName string, info *network.Info) strin
 			text += fmt.Sprintf("\tvlan-raw-device %s\n", interfaceName[:len(interfaceName)-len(suffix)])
 		}
 	}
+
+	// Add bonding options for bonded interfaces. The slaves are
+	// enslaved by their own stanzas, see slaveConfigText.
+	if info.IsBond() && info.VLANTag == 0 {
+		text += fmt.Sprintf("\tbond-mode %s\n\tbond-miimon 100\n\tbond-slaves none\n", info.BondMode)
+	}
+	if info.MTU > 0 {
+		text += fmt.Sprintf("\tmtu %d\n", info.MTU)
+	}
+	for _, route := range info.Routes {
+		spec := fmt.Sprintf("%s via %s dev %s", route.DestinationCIDR, route.GatewayAddress, interfaceName)
+		if route.Metric > 0 {
+			spec += fmt.Sprintf(" metric %d", route.Metric)
+		}
+		text += fmt.Sprintf("\tpost-up ip route add %s\n\tpre-down ip route del %s\n", spec, spec)
+	}
+	return text
+}
+
+// slaveConfigText generates configuration text for an interface
+// enslaved by the given bonded interface.
+func slaveConfigText(interfaceName, bondName string, mtu int) string {
+	text := fmt.Sprintf("auto %s\niface %s inet manual\n\tbond-master %s\n", interfaceName, interfaceName, bondName)
+	if mtu > 0 {
+		text += fmt.Sprintf("\tmtu %d\n", mtu)
+	}
 	return text
 }
@@ -0,0 +1,144 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package networker_test
+
+import (
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/networker"
+)
+
+type stateSuite struct {
+	testing.BaseSuite
+	up map[string]bool
+}
+
+var _ = gc.Suite(&stateSuite{})
+
+func (s *stateSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	networker.ChangeConfigDirName(c.MkDir())
+	networker.SetPrivateInterfaces("eth0", "br0")
+	s.up = make(map[string]bool)
+	s.PatchValue(&networker.InterfaceIsUp, func(name string) bool {
+		return s.up[name]
+	})
+}
+
+func bondInfo(mtu int) []network.Info {
+	return []network.Info{{
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		InterfaceName: "eth0",
+		NetworkName:   "net1",
+	}, {
+		MACAddress:    "aa:bb:cc:dd:ee:f2",
+		InterfaceName: "bond0",
+		NetworkName:   "net2",
+		MTU:           mtu,
+		BondMode:      "802.3ad",
+		BondSlaves:    []string{"eth2", "eth3"},
+		Routes: []network.Route{
+			{DestinationCIDR: "0.6.0.0/16", GatewayAddress: "0.5.2.1", Metric: 10},
+		},
+	}, {
+		MACAddress:    "aa:bb:cc:dd:ee:f2",
+		InterfaceName: "bond0",
+		NetworkName:   "vlan42",
+		VLANTag:       42,
+	}, {
+		MACAddress:    "aa:bb:cc:dd:ee:f1",
+		InterfaceName: "eth1",
+		NetworkName:   "net1",
+		Disabled:      true,
+	}}
+}
+
+const managedPrefix = "# Managed by Juju, don't change.\n"
+
+func (s *stateSuite) TestBondsMTUAndRoutes(c *gc.C) {
+	files := networker.ConfigFiles{}
+	commands := networker.BringUpInterfaces(files, bondInfo(9000))
+	c.Assert(commands, gc.DeepEquals, []string{
+		"ifup eth2",
+		"ifup eth3",
+		"ifup bond0",
+		"ifup bond0.42",
+	})
+	c.Assert(files, gc.DeepEquals, networker.ConfigFiles{
+		networker.IfaceConfigFileName("bond0"): {
+			Data: managedPrefix + "auto bond0\niface bond0 inet dhcp\n" +
+				"\tbond-mode 802.3ad\n\tbond-miimon 100\n\tbond-slaves none\n" +
+				"\tmtu 9000\n" +
+				"\tpost-up ip route add 0.6.0.0/16 via 0.5.2.1 dev bond0 metric 10\n" +
+				"\tpre-down ip route del 0.6.0.0/16 via 0.5.2.1 dev bond0 metric 10\n",
+			Op: networker.DoWrite,
+		},
+		networker.IfaceConfigFileName("bond0.42"): {
+			Data: managedPrefix + "auto bond0.42\niface bond0.42 inet dhcp\n\tvlan-raw-device bond0\n",
+			Op:   networker.DoWrite,
+		},
+		networker.IfaceConfigFileName("eth2"): {
+			Data: managedPrefix + "auto eth2\niface eth2 inet manual\n\tbond-master bond0\n\tmtu 9000\n",
+			Op:   networker.DoWrite,
+		},
+		networker.IfaceConfigFileName("eth3"): {
+			Data: managedPrefix + "auto eth3\niface eth3 inet manual\n\tbond-master bond0\n\tmtu 9000\n",
+			Op:   networker.DoWrite,
+		},
+	})
+	for _, name := range []string{"eth2", "eth3", "bond0", "bond0.42"} {
+		s.up[name] = true
+	}
+
+	// Nothing changes when the configuration is applied again.
+	c.Assert(networker.BringDownInterfaces(files, bondInfo(9000)), gc.HasLen, 0)
+	c.Assert(networker.BringUpInterfaces(files, bondInfo(9000)), gc.HasLen, 0)
+
+	// Changing the MTU reconfigures the bond and its slaves only.
+	commands = networker.BringDownInterfaces(files, bondInfo(1500))
+	c.Assert(commands, gc.DeepEquals, []string{
+		"ifdown eth3",
+		"ifdown eth2",
+		"ifdown bond0",
+	})
+	for _, name := range []string{"eth2", "eth3", "bond0"} {
+		s.up[name] = false
+	}
+	commands = networker.BringUpInterfaces(files, bondInfo(1500))
+	c.Assert(commands, gc.DeepEquals, []string{
+		"ifup eth2",
+		"ifup eth3",
+		"ifup bond0",
+	})
+	c.Assert(files[networker.IfaceConfigFileName("eth2")].Data, gc.Equals,
+		managedPrefix+"auto eth2\niface eth2 inet manual\n\tbond-master bond0\n\tmtu 1500\n")
+}
+
+func (s *stateSuite) TestDisabledBondIsRemoved(c *gc.C) {
+	files := networker.ConfigFiles{}
+	networker.BringUpInterfaces(files, bondInfo(0))
+	for _, name := range []string{"eth2", "eth3", "bond0", "bond0.42"} {
+		s.up[name] = true
+	}
+
+	info := bondInfo(0)
+	info[1].Disabled = true
+	info[2].Disabled = true
+	commands := networker.BringDownInterfaces(files, info)
+	c.Assert(commands, gc.DeepEquals, []string{
+		"ifdown eth3",
+		"ifdown eth2",
+		"ifdown bond0.42",
+		"ifdown bond0",
+	})
+	commands = networker.BringUpInterfaces(files, info)
+	c.Assert(commands, gc.HasLen, 0)
+	for _, name := range []string{"eth2", "eth3", "bond0", "bond0.42"} {
+		file := files[networker.IfaceConfigFileName(name)]
+		c.Check(file.Op, gc.Equals, networker.DoRemove)
+		c.Check(file.Data, gc.Equals, "")
+	}
+}
@@ -156,7 +156,6 @@ func updateAddresses(members map[*machine]*replicaset.Member, machines map[strin
 		if hp == "" {
 			continue
 		}
-		// TODO ensure that replicaset works correctly with IPv6 [host]:port addresses.
 		if hp != members[m].Address {
 			members[m].Address = hp
 			changed = true
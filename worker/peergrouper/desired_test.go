@@ -5,6 +5,7 @@ package peergrouper
 
 import (
 	"fmt"
+	"net"
 	"sort"
 	"strconv"
 	"strings"
@@ -452,3 +453,39 @@ func (h hostPortSliceByHostPort) Less(i, j int) bool {
 	}
 	return false
 }
+
+func (*desiredPeerGroupSuite) TestDesiredPeerGroupIPv6Only(c *gc.C) {
+	// Machines in an IPv6-only environment have no IPv4 addresses at
+	// all, only machine-local, link-local and cloud-local IPv6 ones.
+	machines := mkMachines("11v 12v", testIPv6)
+	m13 := &machine{
+		id:        "13",
+		wantsVote: true,
+		mongoHostPorts: network.AddressesWithPort([]network.Address{
+			network.NewAddress("::1", network.ScopeMachineLocal),
+			network.NewAddress("fe80::13", network.ScopeLinkLocal),
+			network.NewAddress("2001:DB8::13", network.ScopeCloudLocal),
+		}, mongoPort),
+	}
+	machines = append(machines, m13)
+	machineMap := make(map[string]*machine)
+	for _, m := range machines {
+		machineMap[m.id] = m
+	}
+	info := &peerGroupInfo{
+		machines: machineMap,
+		statuses: mkStatuses("1s 2p", testIPv6),
+		members:  mkMembers("1v 2v", testIPv6),
+	}
+	members, voting, err := desiredPeerGroup(info)
+	c.Assert(err, gc.IsNil)
+	sort.Sort(membersById(members))
+	c.Assert(members, jc.DeepEquals, mkMembers("1v 2v 3", testIPv6))
+	c.Assert(voting[m13], jc.IsFalse)
+
+	// The member address must be usable as a mongo host:port.
+	host, port, err := net.SplitHostPort(members[2].Address)
+	c.Assert(err, gc.IsNil)
+	c.Assert(host, gc.Equals, "2001:DB8::13")
+	c.Assert(port, gc.Equals, strconv.Itoa(mongoPort))
+}
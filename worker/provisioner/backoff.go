@@ -0,0 +1,74 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package provisioner
+
+import (
+	"sync"
+	"time"
+
+	"launchpad.net/tomb"
+)
+
+var (
+	// rateLimitInitialDelay holds how long instance starts are
+	// delayed after the provider first reports it is rate limiting
+	// requests. The delay doubles each time a start is rate limited
+	// again, up to rateLimitMaxDelay.
+	rateLimitInitialDelay = 5 * time.Second
+	rateLimitMaxDelay     = 2 * time.Minute
+
+	// rateLimitAttempts holds how many times the start of an instance
+	// is attempted while the provider is rate limiting requests.
+	rateLimitAttempts = 8
+)
+
+// startBackoff coordinates the instance starts made concurrently by a
+// provisioner task, so that they all back off when the provider reports
+// that it is rate limiting requests.
+type startBackoff struct {
+	mu    sync.Mutex
+	delay time.Duration
+	until time.Time
+}
+
+// wait waits until instance starts may be attempted again. It returns
+// tomb.ErrDying if dying is closed first.
+func (b *startBackoff) wait(dying <-chan struct{}) error {
+	for {
+		b.mu.Lock()
+		remaining := b.until.Sub(time.Now())
+		b.mu.Unlock()
+		if remaining <= 0 {
+			return nil
+		}
+		select {
+		case <-dying:
+			return tomb.ErrDying
+		case <-time.After(remaining):
+		}
+	}
+}
+
+// rateLimited records that an instance start was rate limited, delaying
+// further starts, and returns the delay.
+func (b *startBackoff) rateLimited() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.delay *= 2
+	if b.delay == 0 {
+		b.delay = rateLimitInitialDelay
+	} else if b.delay > rateLimitMaxDelay {
+		b.delay = rateLimitMaxDelay
+	}
+	b.until = time.Now().Add(b.delay)
+	return b.delay
+}
+
+// succeeded records that an instance start succeeded, so that the next
+// rate limited start is delayed by the initial delay only.
+func (b *startBackoff) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.delay = 0
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/juju/juju/agent"
 	"github.com/juju/juju/container"
+	"github.com/juju/juju/container/docker"
 	"github.com/juju/juju/container/kvm"
 	"github.com/juju/juju/container/lxc"
 	"github.com/juju/juju/environs"
@@ -190,6 +191,13 @@ func (cs *ContainerSetup) getContainerArtifacts(containerType instance.Container
 			logger.Errorf("failed to create new kvm broker")
 			return nil, nil, err
 		}
+	case instance.DOCKER:
+		initialiser = docker.NewContainerInitialiser()
+		broker, err = NewDockerBroker(cs.provisioner, tools, cs.config, managerConfig)
+		if err != nil {
+			logger.Errorf("failed to create new docker broker")
+			return nil, nil, err
+		}
 	default:
 		return nil, nil, fmt.Errorf("unknown container type: %v", containerType)
 	}
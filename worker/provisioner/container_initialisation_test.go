@@ -146,7 +146,7 @@ func (s *ContainerSetupSuite) TestContainerProvisionerStarted(c *gc.C) {
 			Constraints: s.defaultConstraints,
 		})
 		c.Assert(err, gc.IsNil)
-		err = m.SetSupportedContainers([]instance.ContainerType{instance.LXC, instance.KVM})
+		err = m.SetSupportedContainers(instance.ContainerTypes...)
 		c.Assert(err, gc.IsNil)
 		err = m.SetAgentVersion(version.Current)
 		c.Assert(err, gc.IsNil)
@@ -181,7 +181,7 @@ func (s *ContainerSetupSuite) assertContainerInitialised(c *gc.C, ctype instance
 		Constraints: s.defaultConstraints,
 	})
 	c.Assert(err, gc.IsNil)
-	err = m.SetSupportedContainers([]instance.ContainerType{instance.LXC, instance.KVM})
+	err = m.SetSupportedContainers(instance.ContainerTypes...)
 	c.Assert(err, gc.IsNil)
 	err = m.SetAgentVersion(version.Current)
 	c.Assert(err, gc.IsNil)
@@ -204,6 +204,7 @@ func (s *ContainerSetupSuite) TestContainerInitialised(c *gc.C) {
 	}{
 		{instance.LXC, []string{"--target-release", "precise-updates/cloud-tools", "lxc", "cloud-image-utils"}},
 		{instance.KVM, []string{"uvtool-libvirt", "uvtool"}},
+		{instance.DOCKER, []string{"docker.io"}},
 	} {
 		s.assertContainerInitialised(c, test.ctype, test.packages)
 	}
@@ -0,0 +1,269 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package provisioner_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/agent"
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/container/docker/mock"
+	dockertesting "github.com/juju/juju/container/docker/testing"
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/instance"
+	instancetest "github.com/juju/juju/instance/testing"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	coretools "github.com/juju/juju/tools"
+	"github.com/juju/juju/version"
+	"github.com/juju/juju/worker/provisioner"
+)
+
+type dockerSuite struct {
+	dockertesting.TestSuite
+	events     chan mock.Event
+	eventsDone chan struct{}
+}
+
+type dockerBrokerSuite struct {
+	dockerSuite
+	broker      environs.InstanceBroker
+	agentConfig agent.Config
+}
+
+var _ = gc.Suite(&dockerBrokerSuite{})
+
+func (s *dockerSuite) SetUpTest(c *gc.C) {
+	s.TestSuite.SetUpTest(c)
+	s.events = make(chan mock.Event)
+	s.eventsDone = make(chan struct{})
+	go func() {
+		defer close(s.eventsDone)
+		for event := range s.events {
+			c.Output(3, fmt.Sprintf("docker event: <%s, %s>", event.Action, event.InstanceId))
+		}
+	}()
+	s.TestSuite.ContainerFactory.AddListener(s.events)
+}
+
+func (s *dockerSuite) TearDownTest(c *gc.C) {
+	close(s.events)
+	<-s.eventsDone
+	s.TestSuite.TearDownTest(c)
+}
+
+func (s *dockerBrokerSuite) SetUpTest(c *gc.C) {
+	s.dockerSuite.SetUpTest(c)
+	tools := &coretools.Tools{
+		Version: version.MustParseBinary("2.3.4-quantal-amd64"),
+		URL:     "http://tools.testing.invalid/2.3.4-quantal-amd64.tgz",
+	}
+	var err error
+	s.agentConfig, err = agent.NewAgentConfig(
+		agent.AgentConfigParams{
+			DataDir:           "/not/used/here",
+			Tag:               names.NewUnitTag("ubuntu/1"),
+			UpgradedToVersion: version.Current.Number,
+			Password:          "dummy-secret",
+			Nonce:             "nonce",
+			APIAddresses:      []string{"10.0.0.1:1234"},
+			CACert:            coretesting.CACert,
+		})
+	c.Assert(err, gc.IsNil)
+	managerConfig := container.ManagerConfig{container.ConfigName: "juju"}
+	s.broker, err = provisioner.NewDockerBroker(&fakeAPI{}, tools, s.agentConfig, managerConfig)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *dockerBrokerSuite) startInstance(c *gc.C, machineId string) instance.Instance {
+	machineNonce := "fake-nonce"
+	stateInfo := jujutesting.FakeStateInfo(machineId)
+	apiInfo := jujutesting.FakeAPIInfo(machineId)
+	machineConfig := environs.NewMachineConfig(machineId, machineNonce, nil, stateInfo, apiInfo)
+	cons := constraints.Value{}
+	possibleTools := s.broker.(coretools.HasTools).Tools("precise")
+	docker, _, _, err := s.broker.StartInstance(environs.StartInstanceParams{
+		Constraints:   cons,
+		Tools:         possibleTools,
+		MachineConfig: machineConfig,
+	})
+	c.Assert(err, gc.IsNil)
+	return docker
+}
+
+func (s *dockerBrokerSuite) TestStopInstance(c *gc.C) {
+	docker0 := s.startInstance(c, "1/docker/0")
+	docker1 := s.startInstance(c, "1/docker/1")
+	docker2 := s.startInstance(c, "1/docker/2")
+
+	err := s.broker.StopInstances(docker0.Id())
+	c.Assert(err, gc.IsNil)
+	s.assertInstances(c, docker1, docker2)
+	c.Assert(s.dockerContainerDir(docker0), jc.DoesNotExist)
+	c.Assert(s.dockerRemovedContainerDir(docker0), jc.IsDirectory)
+
+	err = s.broker.StopInstances(docker1.Id(), docker2.Id())
+	c.Assert(err, gc.IsNil)
+	s.assertInstances(c)
+}
+
+func (s *dockerBrokerSuite) TestAllInstances(c *gc.C) {
+	docker0 := s.startInstance(c, "1/docker/0")
+	docker1 := s.startInstance(c, "1/docker/1")
+	s.assertInstances(c, docker0, docker1)
+
+	err := s.broker.StopInstances(docker1.Id())
+	c.Assert(err, gc.IsNil)
+	docker2 := s.startInstance(c, "1/docker/2")
+	s.assertInstances(c, docker0, docker2)
+}
+
+func (s *dockerBrokerSuite) assertInstances(c *gc.C, inst ...instance.Instance) {
+	results, err := s.broker.AllInstances()
+	c.Assert(err, gc.IsNil)
+	instancetest.MatchInstances(c, results, inst...)
+}
+
+func (s *dockerBrokerSuite) dockerContainerDir(inst instance.Instance) string {
+	return filepath.Join(s.ContainerDir, string(inst.Id()))
+}
+
+func (s *dockerBrokerSuite) dockerRemovedContainerDir(inst instance.Instance) string {
+	return filepath.Join(s.RemovedDir, string(inst.Id()))
+}
+
+type dockerProvisionerSuite struct {
+	CommonProvisionerSuite
+	dockerSuite
+	events chan mock.Event
+}
+
+var _ = gc.Suite(&dockerProvisionerSuite{})
+
+func (s *dockerProvisionerSuite) SetUpSuite(c *gc.C) {
+	s.CommonProvisionerSuite.SetUpSuite(c)
+	s.dockerSuite.SetUpSuite(c)
+}
+
+func (s *dockerProvisionerSuite) TearDownSuite(c *gc.C) {
+	s.dockerSuite.TearDownSuite(c)
+	s.CommonProvisionerSuite.TearDownSuite(c)
+}
+
+func (s *dockerProvisionerSuite) SetUpTest(c *gc.C) {
+	s.CommonProvisionerSuite.SetUpTest(c)
+	s.dockerSuite.SetUpTest(c)
+
+	hostPorts := [][]network.HostPort{{{
+		Address: network.NewAddress("0.1.2.3", network.ScopeUnknown),
+		Port:    1234,
+	}}}
+	err := s.State.SetAPIHostPorts(hostPorts)
+	c.Assert(err, gc.IsNil)
+
+	s.events = make(chan mock.Event, 25)
+	s.ContainerFactory.AddListener(s.events)
+}
+
+func (s *dockerProvisionerSuite) expectStarted(c *gc.C, machine *state.Machine) string {
+	s.State.StartSync()
+	event := <-s.events
+	c.Assert(event.Action, gc.Equals, mock.Started)
+	err := machine.Refresh()
+	c.Assert(err, gc.IsNil)
+	s.waitInstanceId(c, machine, instance.Id(event.InstanceId))
+	return event.InstanceId
+}
+
+func (s *dockerProvisionerSuite) expectStopped(c *gc.C, instId string) {
+	s.State.StartSync()
+	event := <-s.events
+	c.Assert(event.Action, gc.Equals, mock.Stopped)
+	c.Assert(event.InstanceId, gc.Equals, instId)
+}
+
+func (s *dockerProvisionerSuite) expectNoEvents(c *gc.C) {
+	select {
+	case event := <-s.events:
+		c.Fatalf("unexpected event %#v", event)
+	case <-time.After(coretesting.ShortWait):
+		return
+	}
+}
+
+func (s *dockerProvisionerSuite) TearDownTest(c *gc.C) {
+	close(s.events)
+	s.dockerSuite.TearDownTest(c)
+	s.CommonProvisionerSuite.TearDownTest(c)
+}
+
+func (s *dockerProvisionerSuite) newDockerProvisioner(c *gc.C) provisioner.Provisioner {
+	machineTag := names.NewMachineTag("0")
+	agentConfig := s.AgentConfigForTag(c, machineTag)
+	tools, err := s.provisioner.Tools(agentConfig.Tag().(names.MachineTag))
+	c.Assert(err, gc.IsNil)
+	managerConfig := container.ManagerConfig{container.ConfigName: "juju"}
+	broker, err := provisioner.NewDockerBroker(s.provisioner, tools, agentConfig, managerConfig)
+	c.Assert(err, gc.IsNil)
+	return provisioner.NewContainerProvisioner(instance.DOCKER, s.provisioner, agentConfig, broker)
+}
+
+func (s *dockerProvisionerSuite) TestProvisionerStartStop(c *gc.C) {
+	p := s.newDockerProvisioner(c)
+	c.Assert(p.Stop(), gc.IsNil)
+}
+
+func (s *dockerProvisionerSuite) TestDoesNotStartEnvironMachines(c *gc.C) {
+	p := s.newDockerProvisioner(c)
+	defer stop(c, p)
+
+	// Check that an instance is not provisioned when the machine is created.
+	_, err := s.State.AddMachine(coretesting.FakeDefaultSeries, state.JobHostUnits)
+	c.Assert(err, gc.IsNil)
+
+	s.expectNoEvents(c)
+}
+
+func (s *dockerProvisionerSuite) TestDoesNotHaveRetryWatcher(c *gc.C) {
+	p := s.newDockerProvisioner(c)
+	defer stop(c, p)
+
+	w, err := provisioner.GetRetryWatcher(p)
+	c.Assert(w, gc.IsNil)
+	c.Assert(err, jc.Satisfies, errors.IsNotImplemented)
+}
+
+func (s *dockerProvisionerSuite) addContainer(c *gc.C) *state.Machine {
+	template := state.MachineTemplate{
+		Series: coretesting.FakeDefaultSeries,
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container, err := s.State.AddMachineInsideMachine(template, "0", instance.DOCKER)
+	c.Assert(err, gc.IsNil)
+	return container
+}
+
+func (s *dockerProvisionerSuite) TestContainerStartedAndStopped(c *gc.C) {
+	p := s.newDockerProvisioner(c)
+	defer stop(c, p)
+
+	container := s.addContainer(c)
+
+	instId := s.expectStarted(c, container)
+
+	// ...and removed, along with the machine, when the machine is Dead.
+	c.Assert(container.EnsureDead(), gc.IsNil)
+	s.expectStopped(c, instId)
+	s.waitRemoved(c, container)
+}
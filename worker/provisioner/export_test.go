@@ -20,3 +20,11 @@ func GetRetryWatcher(p Provisioner) (watcher.NotifyWatcher, error) {
 }
 
 var ContainerManagerConfig = containerManagerConfig
+
+var (
+	RateLimitInitialDelay = &rateLimitInitialDelay
+	RateLimitAttempts     = &rateLimitAttempts
+	HostGateway           = &hostGateway
+	HostNameservers       = &hostNameservers
+	ResolvConfPath        = &resolvConfPath
+)
@@ -64,14 +64,13 @@ func (broker *kvmBroker) StartInstance(args environs.StartInstanceParams) (insta
 	machineId := args.MachineConfig.MachineId
 	kvmLogger.Infof("starting kvm container for machineId: %s", machineId)
 
-	// TODO: Default to using the host network until we can configure.  Yes,
-	// this is using the LxcBridge value, we should put it in the api call for
-	// container config.
-	bridgeDevice := broker.agentConfig.Value(agent.LxcBridge)
-	if bridgeDevice == "" {
-		bridgeDevice = kvm.DefaultKvmBridge
+	// TODO: Yes, this is using the LxcBridge value, we should put it in
+	// the api call for container config.
+	network, err := containerNetworkConfig(broker.api, broker.agentConfig, machineId, kvm.DefaultKvmBridge)
+	if err != nil {
+		kvmLogger.Errorf("failed to configure container network: %v", err)
+		return nil, nil, nil, err
 	}
-	network := container.BridgeNetworkConfig(bridgeDevice)
 
 	// TODO: series doesn't necessarily need to be the same as the host.
 	series := args.Tools.OneSeries()
@@ -96,7 +95,7 @@ func (broker *kvmBroker) StartInstance(args environs.StartInstanceParams) (insta
 		return nil, nil, nil, err
 	}
 
-	inst, hardware, err := broker.manager.CreateContainer(args.MachineConfig, series, network)
+	inst, hardware, err := broker.manager.CreateContainer(args.MachineConfig, series, network, args.Constraints)
 	if err != nil {
 		kvmLogger.Errorf("failed to start container: %v", err)
 		return nil, nil, nil, err
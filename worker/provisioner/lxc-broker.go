@@ -5,8 +5,13 @@ package provisioner
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"strings"
 
 	"github.com/juju/loggo"
+	"github.com/juju/names"
 
 	"github.com/juju/juju/agent"
 	"github.com/juju/juju/container"
@@ -25,6 +30,89 @@ var _ tools.HasTools = (*lxcBroker)(nil)
 
 type APICalls interface {
 	ContainerConfig() (params.ContainerConfig, error)
+	AllocateContainerAddress(tag names.MachineTag) (params.ContainerAddressResult, error)
+}
+
+// containerNetworkConfig returns the network config for a new container
+// attached to the host's bridge device. Containers on the default,
+// NATed, bridges get their addresses from the bridge. When the host
+// has been configured with a bridge onto the provider network, and
+// the environment can allocate addresses routable on that network,
+// the container is given one instead. It shares the host's gateway
+// and nameservers, which are on the same network.
+func containerNetworkConfig(api APICalls, agentConfig agent.Config, machineId, defaultBridge string) (*container.NetworkConfig, error) {
+	bridgeDevice := agentConfig.Value(agent.LxcBridge)
+	if bridgeDevice == "" {
+		return container.BridgeNetworkConfig(defaultBridge), nil
+	}
+	result, err := api.AllocateContainerAddress(names.NewMachineTag(machineId))
+	if params.IsCodeNotSupported(err) || params.IsCodeNotImplemented(err) {
+		logger.Debugf("container addresses not supported, using DHCP on %s: %v", bridgeDevice, err)
+		return container.BridgeNetworkConfig(bridgeDevice), nil
+	} else if err != nil {
+		return nil, err
+	}
+	_, ipNet, err := net.ParseCIDR(result.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network for container address %s: %v", result.Address, err)
+	}
+	prefix, _ := ipNet.Mask.Size()
+	logger.Infof("allocated address %s on %s for container %s", result.Address, result.CIDR, machineId)
+	gateway, err := hostGateway(bridgeDevice)
+	if err != nil {
+		logger.Warningf("cannot find the default gateway on %s: %v", bridgeDevice, err)
+	}
+	nameservers, err := hostNameservers()
+	if err != nil {
+		logger.Warningf("cannot find the host's nameservers: %v", err)
+	}
+	return container.StaticBridgeNetworkConfig(bridgeDevice, &container.StaticAddress{
+		Address:    fmt.Sprintf("%s/%d", result.Address, prefix),
+		Gateway:    gateway,
+		DNSServers: nameservers,
+	}), nil
+}
+
+// hostGateway returns the host's default gateway on the given bridge,
+// or an empty string if it has none there. It is a variable so it can
+// be overridden in tests.
+var hostGateway = func(bridge string) (string, error) {
+	out, err := exec.Command("ip", "route", "show", "default", "dev", bridge).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	// The output looks like "default via 10.0.0.1".
+	fields := strings.Fields(string(out))
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "via" {
+			return fields[i+1], nil
+		}
+	}
+	return "", nil
+}
+
+// resolvConfPath is the file the host's nameservers are read from.
+var resolvConfPath = "/etc/resolv.conf"
+
+// hostNameservers returns the nameservers the host uses, leaving out
+// loopback addresses, which containers cannot reach. It is a variable
+// so it can be overridden in tests.
+var hostNameservers = func() ([]string, error) {
+	data, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+	var nameservers []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if ip := net.ParseIP(fields[1]); ip != nil && !ip.IsLoopback() {
+			nameservers = append(nameservers, fields[1])
+		}
+	}
+	return nameservers, nil
 }
 
 func NewLxcBroker(api APICalls, tools *tools.Tools, agentConfig agent.Config, managerConfig container.ManagerConfig) (environs.InstanceBroker, error) {
@@ -64,12 +152,11 @@ func (broker *lxcBroker) StartInstance(args environs.StartInstanceParams) (insta
 	machineId := args.MachineConfig.MachineId
 	lxcLogger.Infof("starting lxc container for machineId: %s", machineId)
 
-	// Default to using the host network until we can configure.
-	bridgeDevice := broker.agentConfig.Value(agent.LxcBridge)
-	if bridgeDevice == "" {
-		bridgeDevice = lxc.DefaultLxcBridge
+	network, err := containerNetworkConfig(broker.api, broker.agentConfig, machineId, lxc.DefaultLxcBridge)
+	if err != nil {
+		lxcLogger.Errorf("failed to configure container network: %v", err)
+		return nil, nil, nil, err
 	}
-	network := container.BridgeNetworkConfig(bridgeDevice)
 
 	series := args.Tools.OneSeries()
 	args.MachineConfig.MachineContainerType = instance.LXC
@@ -93,7 +180,7 @@ func (broker *lxcBroker) StartInstance(args environs.StartInstanceParams) (insta
 		return nil, nil, nil, err
 	}
 
-	inst, hardware, err := broker.manager.CreateContainer(args.MachineConfig, series, network)
+	inst, hardware, err := broker.manager.CreateContainer(args.MachineConfig, series, network, args.Constraints)
 	if err != nil {
 		lxcLogger.Errorf("failed to start container: %v", err)
 		return nil, nil, nil, err
@@ -131,6 +131,53 @@ func (s *lxcBrokerSuite) TestStartInstanceWithBridgeEnviron(c *gc.C) {
 	c.Assert(string(lxcConfContents), jc.Contains, "lxc.network.link = br0")
 }
 
+func (s *lxcBrokerSuite) TestStartInstanceWithContainerAddress(c *gc.C) {
+	s.agentConfig.SetValue(agent.LxcBridge, "br0")
+	s.PatchValue(provisioner.HostGateway, func(bridge string) (string, error) {
+		c.Check(bridge, gc.Equals, "br0")
+		return "10.1.2.1", nil
+	})
+	s.PatchValue(provisioner.HostNameservers, func() ([]string, error) {
+		return []string{"10.1.2.2", "10.1.2.3"}, nil
+	})
+	api := &fakeAPI{containerAddress: &params.ContainerAddressResult{
+		Address: "10.1.2.3",
+		CIDR:    "10.1.2.0/24",
+	}}
+	managerConfig := container.ManagerConfig{container.ConfigName: "juju", "use-clone": "false"}
+	var err error
+	s.broker, err = provisioner.NewLxcBroker(api, s.broker.(coretools.HasTools).Tools("precise")[0], s.agentConfig, managerConfig)
+	c.Assert(err, gc.IsNil)
+
+	lxc := s.startInstance(c, "1/lxc/0")
+	lxcConfContents, err := ioutil.ReadFile(filepath.Join(s.ContainerDir, string(lxc.Id()), "lxc.conf"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(lxcConfContents), jc.Contains, "lxc.network.link = br0\n")
+	c.Assert(string(lxcConfContents), jc.Contains, "lxc.network.ipv4 = 10.1.2.3/24\n")
+	c.Assert(string(lxcConfContents), jc.Contains, "lxc.network.ipv4.gateway = 10.1.2.1\n")
+	cloudInit, err := ioutil.ReadFile(filepath.Join(s.ContainerDir, string(lxc.Id()), "cloud-init"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(cloudInit), jc.Contains, "address 10.1.2.3/24")
+	c.Assert(string(cloudInit), jc.Contains, "gateway 10.1.2.1")
+	c.Assert(string(cloudInit), jc.Contains, "dns-nameservers 10.1.2.2 10.1.2.3")
+}
+
+func (s *lxcBrokerSuite) TestHostNameservers(c *gc.C) {
+	resolvConf := filepath.Join(c.MkDir(), "resolv.conf")
+	err := ioutil.WriteFile(resolvConf, []byte(`
+# Dynamic resolv.conf(5) file for glibc resolver(3) generated by resolvconf(8)
+nameserver 127.0.1.1
+nameserver 10.0.0.2
+search example.com
+nameserver fd00::2
+`), 0644)
+	c.Assert(err, gc.IsNil)
+	s.PatchValue(provisioner.ResolvConfPath, resolvConf)
+	nameservers, err := (*provisioner.HostNameservers)()
+	c.Assert(err, gc.IsNil)
+	c.Assert(nameservers, gc.DeepEquals, []string{"10.0.0.2", "fd00::2"})
+}
+
 func (s *lxcBrokerSuite) TestStopInstance(c *gc.C) {
 	lxc0 := s.startInstance(c, "1/lxc/0")
 	lxc1 := s.startInstance(c, "1/lxc/1")
@@ -300,7 +347,56 @@ func (s *lxcProvisionerSuite) TestContainerStartedAndStopped(c *gc.C) {
 	s.waitRemoved(c, container)
 }
 
-type fakeAPI struct{}
+func (s *lxcProvisionerSuite) TestContainerStartedWithProviderAddress(c *gc.C) {
+	s.PatchValue(provisioner.HostGateway, func(string) (string, error) {
+		return "0.1.2.1", nil
+	})
+	s.PatchValue(provisioner.HostNameservers, func() ([]string, error) {
+		return []string{"0.1.2.2"}, nil
+	})
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "0.1.2.0/24", ProviderId: "dummy-private"})
+	c.Assert(err, gc.IsNil)
+	host, err := s.State.Machine("0")
+	c.Assert(err, gc.IsNil)
+	err = host.SetAddresses(network.NewAddress("0.1.2.250", network.ScopeCloudLocal))
+	c.Assert(err, gc.IsNil)
+
+	parentMachineTag := names.NewMachineTag("0")
+	agentConfig := s.AgentConfigForTag(c, parentMachineTag)
+	agentConfig.SetValue(agent.LxcBridge, "br0")
+	tools, err := s.provisioner.Tools(parentMachineTag)
+	c.Assert(err, gc.IsNil)
+	managerConfig := container.ManagerConfig{container.ConfigName: "juju", "use-clone": "false"}
+	broker, err := provisioner.NewLxcBroker(s.provisioner, tools, agentConfig, managerConfig)
+	c.Assert(err, gc.IsNil)
+	p := provisioner.NewContainerProvisioner(instance.LXC, s.provisioner, agentConfig, broker)
+	defer stop(c, p)
+
+	container := s.addContainer(c)
+	instId := s.expectStarted(c, container)
+
+	// The dummy provider allocates addresses on its private network.
+	lxcConf, err := ioutil.ReadFile(filepath.Join(s.lxcSuite.ContainerDir, instId, "lxc.conf"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(lxcConf), jc.Contains, "lxc.network.link = br0\n")
+	c.Assert(string(lxcConf), gc.Matches, "(.|\n)*lxc.network.ipv4 = 0.1.2.[0-9]+/24\n(.|\n)*")
+	c.Assert(string(lxcConf), jc.Contains, "lxc.network.ipv4.gateway = 0.1.2.1\n")
+	addresses, err := container.ContainerAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses, gc.HasLen, 1)
+
+	// The address is released when the container is removed.
+	c.Assert(container.EnsureDead(), gc.IsNil)
+	s.expectStopped(c, instId)
+	s.waitRemoved(c, container)
+	addresses, err = container.ContainerAddresses()
+	c.Assert(err, gc.IsNil)
+	c.Assert(addresses, gc.HasLen, 0)
+}
+
+type fakeAPI struct {
+	containerAddress *params.ContainerAddressResult
+}
 
 func (*fakeAPI) ContainerConfig() (params.ContainerConfig, error) {
 	return params.ContainerConfig{
@@ -308,3 +404,13 @@ func (*fakeAPI) ContainerConfig() (params.ContainerConfig, error) {
 		AuthorizedKeys:          coretesting.FakeAuthKeys,
 		SSLHostnameVerification: true}, nil
 }
+
+func (f *fakeAPI) AllocateContainerAddress(tag names.MachineTag) (params.ContainerAddressResult, error) {
+	if f.containerAddress == nil {
+		return params.ContainerAddressResult{}, &params.Error{
+			Message: "container addresses not supported",
+			Code:    params.CodeNotSupported,
+		}
+	}
+	return *f.containerAddress, nil
+}
@@ -101,7 +101,7 @@ func (p *provisioner) Stop() error {
 }
 
 // getStartTask creates a new worker for the provisioner,
-func (p *provisioner) getStartTask(safeMode bool) (ProvisionerTask, error) {
+func (p *provisioner) getStartTask(harvestMode config.HarvestMode) (ProvisionerTask, error) {
 	auth, err := authentication.NewAPIAuthenticator(p.st)
 	if err != nil {
 		return nil, err
@@ -122,7 +122,7 @@ func (p *provisioner) getStartTask(safeMode bool) (ProvisionerTask, error) {
 		errors.Errorf("expacted names.MachineTag, got %T", tag)
 	}
 	task := NewProvisionerTask(
-		machineTag, safeMode, p.st,
+		machineTag, harvestMode, p.st,
 		machineWatcher, retryWatcher, p.broker, auth)
 	return task, nil
 }
@@ -161,8 +161,8 @@ func (p *environProvisioner) loop() error {
 	}
 	p.broker = p.environ
 
-	safeMode := p.environ.Config().ProvisionerSafeMode()
-	task, err := p.getStartTask(safeMode)
+	harvestMode := p.environ.Config().ProvisionerHarvestMode()
+	task, err := p.getStartTask(harvestMode)
 	if err != nil {
 		return err
 	}
@@ -188,7 +188,7 @@ func (p *environProvisioner) loop() error {
 			if err := p.setConfig(environConfig); err != nil {
 				logger.Errorf("loaded invalid environment configuration: %v", err)
 			}
-			task.SetSafeMode(environConfig.ProvisionerSafeMode())
+			task.SetHarvestMode(environConfig.ProvisionerHarvestMode())
 		}
 	}
 }
@@ -235,7 +235,7 @@ func NewContainerProvisioner(containerType instance.ContainerType, st *apiprovis
 }
 
 func (p *containerProvisioner) loop() error {
-	task, err := p.getStartTask(false)
+	task, err := p.getStartTask(config.HarvestAll)
 	if err != nil {
 		return err
 	}
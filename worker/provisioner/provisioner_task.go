@@ -5,6 +5,9 @@ package provisioner
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -17,9 +20,11 @@ import (
 	"github.com/juju/juju/environmentserver/authentication"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/cloudinit"
+	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/environs/tools"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/network"
+	"github.com/juju/juju/provider/common"
 	"github.com/juju/juju/state/api/params"
 	apiprovisioner "github.com/juju/juju/state/api/provisioner"
 	apiwatcher "github.com/juju/juju/state/api/watcher"
@@ -35,23 +40,24 @@ type ProvisionerTask interface {
 	Dying() <-chan struct{}
 	Err() error
 
-	// SetSafeMode sets a flag to indicate whether the provisioner task
-	// runs in safe mode or not. In safe mode, any running instances
-	// which do no exist in state are allowed to keep running rather than
-	// being shut down.
-	SetSafeMode(safeMode bool)
+	// SetHarvestMode sets which instances the provisioner task
+	// terminates: those of machines that have been destroyed, those
+	// which do not exist in state, both, or neither.
+	SetHarvestMode(harvestMode config.HarvestMode)
 }
 
 type MachineGetter interface {
 	Machine(names.MachineTag) (*apiprovisioner.Machine, error)
 	MachinesWithTransientErrors() ([]*apiprovisioner.Machine, []params.StatusResult, error)
+	RetainedInstances() ([]params.RetainedInstance, error)
+	ReleaseRetainedInstances(ids ...instance.Id) error
 }
 
 var _ MachineGetter = (*apiprovisioner.State)(nil)
 
 func NewProvisionerTask(
 	machineTag names.MachineTag,
-	safeMode bool,
+	harvestMode config.HarvestMode,
 	machineGetter MachineGetter,
 	machineWatcher apiwatcher.StringsWatcher,
 	retryWatcher apiwatcher.NotifyWatcher,
@@ -59,15 +65,15 @@ func NewProvisionerTask(
 	auth authentication.AuthenticationProvider,
 ) ProvisionerTask {
 	task := &provisionerTask{
-		machineTag:     machineTag,
-		machineGetter:  machineGetter,
-		machineWatcher: machineWatcher,
-		retryWatcher:   retryWatcher,
-		broker:         broker,
-		auth:           auth,
-		safeMode:       safeMode,
-		safeModeChan:   make(chan bool, 1),
-		machines:       make(map[string]*apiprovisioner.Machine),
+		machineTag:      machineTag,
+		machineGetter:   machineGetter,
+		machineWatcher:  machineWatcher,
+		retryWatcher:    retryWatcher,
+		broker:          broker,
+		auth:            auth,
+		harvestMode:     harvestMode,
+		harvestModeChan: make(chan config.HarvestMode, 1),
+		machines:        make(map[string]*apiprovisioner.Machine),
 	}
 	go func() {
 		defer task.tomb.Done()
@@ -85,8 +91,12 @@ type provisionerTask struct {
 	tomb           tomb.Tomb
 	auth           authentication.AuthenticationProvider
 
-	safeMode     bool
-	safeModeChan chan bool
+	harvestMode     config.HarvestMode
+	harvestModeChan chan config.HarvestMode
+
+	// backoff delays instance starts while the provider is rate
+	// limiting requests.
+	backoff startBackoff
 
 	// instance id -> instance
 	instances map[instance.Id]instance.Instance
@@ -121,11 +131,11 @@ func (task *provisionerTask) loop() error {
 	logger.Infof("Starting up provisioner task %s", task.machineTag)
 	defer watcher.Stop(task.machineWatcher, &task.tomb)
 
-	// Don't allow the safe mode to change until we have
+	// Don't allow the harvest mode to change until we have
 	// read at least one set of changes, which will populate
 	// the task.machines map. Otherwise we will potentially
 	// see all legitimate instances as unknown.
-	var safeModeChan chan bool
+	var harvestModeChan chan config.HarvestMode
 
 	// Not all provisioners have a retry channel.
 	var retryChan <-chan struct{}
@@ -148,19 +158,20 @@ func (task *provisionerTask) loop() error {
 			if err := task.processMachines(ids); err != nil {
 				return errors.Annotate(err, "failed to process updated machines")
 			}
-			// We've seen a set of changes. Enable safe mode change.
-			safeModeChan = task.safeModeChan
-		case safeMode := <-safeModeChan:
-			if safeMode == task.safeMode {
+			// We've seen a set of changes. Enable harvest mode change.
+			harvestModeChan = task.harvestModeChan
+		case harvestMode := <-harvestModeChan:
+			if harvestMode == task.harvestMode {
 				break
 			}
-			logger.Infof("safe mode changed to %v", safeMode)
-			task.safeMode = safeMode
-			if !safeMode {
-				// Safe mode has been disabled, so process current machines
-				// so that unknown machines will be immediately dealt with.
+			logger.Infof("harvest mode changed to %v", harvestMode)
+			task.harvestMode = harvestMode
+			if harvestMode != config.HarvestNone {
+				// Process current machines so that unknown machines,
+				// and those of dead machines, will be immediately
+				// dealt with.
 				if err := task.processMachines(nil); err != nil {
-					return errors.Annotate(err, "failed to process machines after safe mode disabled")
+					return errors.Annotate(err, "failed to process machines after harvest mode changed")
 				}
 			}
 		case <-retryChan:
@@ -171,10 +182,10 @@ func (task *provisionerTask) loop() error {
 	}
 }
 
-// SetSafeMode implements ProvisionerTask.SetSafeMode().
-func (task *provisionerTask) SetSafeMode(safeMode bool) {
+// SetHarvestMode implements ProvisionerTask.SetHarvestMode().
+func (task *provisionerTask) SetHarvestMode(harvestMode config.HarvestMode) {
 	select {
-	case task.safeModeChan <- safeMode:
+	case task.harvestModeChan <- harvestMode:
 	case <-task.Dying():
 	}
 }
@@ -219,13 +230,33 @@ func (task *provisionerTask) processMachines(ids []string) error {
 	// Stop all machines that are dead
 	stopping := task.instancesForMachines(dead)
 
+	// Find the running instances of removed machines that were left
+	// running because of the harvest mode.
+	retained, err := task.retainedInstances()
+	if err != nil {
+		return err
+	}
+
 	// Find running instances that have no machines associated
-	unknown, err := task.findUnknownInstances(stopping)
+	unknown, err := task.findUnknownInstances(stopping, retained)
 	if err != nil {
 		return err
 	}
-	if task.safeMode {
-		logger.Infof("running in safe mode, unknown instances not stopped %v", instanceIds(unknown))
+	var released []instance.Id
+	if task.harvestMode.Destroyed() {
+		for _, inst := range retained {
+			stopping = append(stopping, inst)
+			released = append(released, inst.Id())
+		}
+	} else if len(stopping) > 0 {
+		logger.Infof("harvest mode is %v, instances of dead machines not stopped %v", task.harvestMode, instanceIds(stopping))
+		if err := task.retainInstances(dead); err != nil {
+			return err
+		}
+		stopping = nil
+	}
+	if !task.harvestMode.Unknown() && len(unknown) > 0 {
+		logger.Infof("harvest mode is %v, unknown instances not stopped %v", task.harvestMode, instanceIds(unknown))
 		unknown = nil
 	}
 	if len(stopping) > 0 {
@@ -241,6 +272,9 @@ func (task *provisionerTask) processMachines(ids []string) error {
 	if err := task.stopInstances(append(stopping, unknown...)); err != nil {
 		return err
 	}
+	if err := task.machineGetter.ReleaseRetainedInstances(released...); err != nil {
+		return errors.Annotate(err, "cannot release retained instances")
+	}
 
 	// Remove any dead machines from state.
 	for _, machine := range dead {
@@ -343,7 +377,7 @@ func (task *provisionerTask) pendingOrDead(ids []string) (pending, dead []*apipr
 }
 
 // findUnknownInstances finds instances which are not associated with a machine.
-func (task *provisionerTask) findUnknownInstances(stopping []instance.Instance) ([]instance.Instance, error) {
+func (task *provisionerTask) findUnknownInstances(stopping []instance.Instance, retained map[instance.Id]instance.Instance) ([]instance.Instance, error) {
 	// Make a copy of the instances we know about.
 	instances := make(map[instance.Id]instance.Instance)
 	for k, v := range task.instances {
@@ -366,6 +400,10 @@ func (task *provisionerTask) findUnknownInstances(stopping []instance.Instance)
 	for _, inst := range stopping {
 		delete(instances, inst.Id())
 	}
+	// Likewise the instances of removed machines that were retained.
+	for id := range retained {
+		delete(instances, id)
+	}
 	var unknown []instance.Instance
 	for _, inst := range instances {
 		unknown = append(unknown, inst)
@@ -373,6 +411,48 @@ func (task *provisionerTask) findUnknownInstances(stopping []instance.Instance)
 	return unknown, nil
 }
 
+// retainedInstances returns the running instances of removed machines
+// that were retained because of the harvest mode. Retained instances
+// that are no longer running are forgotten.
+func (task *provisionerTask) retainedInstances() (map[instance.Id]instance.Instance, error) {
+	retained, err := task.machineGetter.RetainedInstances()
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot get retained instances")
+	}
+	instances := make(map[instance.Id]instance.Instance)
+	var gone []instance.Id
+	for _, r := range retained {
+		if inst, found := task.instances[r.InstanceId]; found {
+			instances[r.InstanceId] = inst
+		} else {
+			gone = append(gone, r.InstanceId)
+		}
+	}
+	if err := task.machineGetter.ReleaseRetainedInstances(gone...); err != nil {
+		return nil, errors.Annotate(err, "cannot release retained instances")
+	}
+	return instances, nil
+}
+
+// retainInstances records that the running instances of the dead
+// machines are left running when the machines are removed, so they
+// are not later taken for unknown instances.
+func (task *provisionerTask) retainInstances(dead []*apiprovisioner.Machine) error {
+	for _, machine := range dead {
+		instId, err := machine.InstanceId()
+		if err != nil {
+			continue
+		}
+		if _, found := task.instances[instId]; !found {
+			continue
+		}
+		if err := machine.RetainInstance(); err != nil {
+			return errors.Annotatef(err, "cannot retain instance %q of machine %q", instId, machine)
+		}
+	}
+	return nil
+}
+
 // instancesForMachines returns a list of instance.Instance that represent
 // the list of machines running in the provider. Missing machines are
 // omitted from the list.
@@ -407,13 +487,102 @@ func (task *provisionerTask) stopInstances(instances []instance.Instance) error
 	return nil
 }
 
+// startMachines starts instances for the given machines, starting up to
+// the broker's concurrency limit at once. Failures to start instances
+// are reported in the status of each machine; if any other error is
+// encountered, the first is returned once all the starts have finished.
 func (task *provisionerTask) startMachines(machines []*apiprovisioner.Machine) error {
+	if len(machines) == 0 {
+		return nil
+	}
+	zones := task.assignZones(machines)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, task.startConcurrency())
+	errs := make(chan error, len(machines))
 	for _, m := range machines {
-		if err := task.startMachine(m); err != nil {
-			return errors.Annotatef(err, "cannot start machine %v", m)
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(m *apiprovisioner.Machine) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := task.startMachine(m, zones[m]); err != nil {
+				if err != tomb.ErrDying {
+					err = errors.Annotatef(err, "cannot start machine %v", m)
+				}
+				errs <- err
+			}
+		}(m)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// assignZones chooses the availability zone of each of the machines,
+// when the broker starts instances in zones. Instances started at once
+// cannot see each other in their zones' populations, so the zones are
+// chosen here, one machine after another, counting the machines already
+// assigned to each zone along with the instances in it. Machines whose
+// zone cannot be chosen are left for the broker to place.
+func (task *provisionerTask) assignZones(machines []*apiprovisioner.Machine) map[*apiprovisioner.Machine]string {
+	env, ok := task.broker.(common.ZonedEnviron)
+	if !ok {
+		return nil
+	}
+	zones := make(map[*apiprovisioner.Machine]string)
+	assigned := make(map[string]map[string]int)
+	for _, m := range machines {
+		group, err := m.DistributionGroup()
+		if err != nil {
+			logger.Warningf("cannot get distribution group of machine %q: %v", m, err)
+			continue
+		}
+		allocations, err := common.AvailabilityZoneAllocations(env, group)
+		if err != nil {
+			logger.Warningf("cannot choose availability zone for machine %q: %v", m, err)
+			continue
+		}
+		key := distributionGroupKey(group)
+		counts := assigned[key]
+		if counts == nil {
+			counts = make(map[string]int)
+			assigned[key] = counts
+		}
+		var zone string
+		var population int
+		for _, allocation := range allocations {
+			n := len(allocation.Instances) + counts[allocation.ZoneName]
+			if zone == "" || n < population || n == population && allocation.ZoneName < zone {
+				zone, population = allocation.ZoneName, n
+			}
+		}
+		if zone != "" {
+			counts[zone]++
+			zones[m] = zone
 		}
 	}
-	return nil
+	return zones
+}
+
+// distributionGroupKey returns a key identifying the given distribution
+// group, whatever the order of its instances.
+func distributionGroupKey(group []instance.Id) string {
+	ids := make([]string, len(group))
+	for i, id := range group {
+		ids[i] = string(id)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, " ")
+}
+
+// startConcurrency returns the number of instances that may be started
+// at once. Only environment provisioners start instances concurrently;
+// containers are started one at a time.
+func (task *provisionerTask) startConcurrency() int {
+	if env, ok := task.broker.(environs.Environ); ok {
+		return env.Config().ProvisionerConcurrency()
+	}
+	return 1
 }
 
 func (task *provisionerTask) setErrorStatus(message string, machine *apiprovisioner.Machine, err error) error {
@@ -448,12 +617,19 @@ func (task *provisionerTask) prepareNetworkAndInterfaces(networkInfo []network.I
 			NetworkTag:    networkTag,
 			IsVirtual:     info.IsVirtual(),
 			Disabled:      info.Disabled,
+			MTU:           info.MTU,
+			BondMode:      info.BondMode,
+			BondSlaves:    info.BondSlaves,
+			Routes:        info.Routes,
 		})
 	}
 	return networks, ifaces
 }
 
-func (task *provisionerTask) startMachine(machine *apiprovisioner.Machine) error {
+// startMachine starts an instance for the machine, in the given
+// availability zone if it is not empty and the machine has no
+// placement directive.
+func (task *provisionerTask) startMachine(machine *apiprovisioner.Machine, zone string) error {
 	provisioningInfo, err := task.provisioningInfo(machine)
 	if err != nil {
 		return err
@@ -462,14 +638,20 @@ func (task *provisionerTask) startMachine(machine *apiprovisioner.Machine) error
 	if err != nil {
 		return task.setErrorStatus("cannot find tools for machine %q: %v", machine, err)
 	}
-	inst, metadata, networkInfo, err := task.broker.StartInstance(environs.StartInstanceParams{
+	args := environs.StartInstanceParams{
 		Constraints:       provisioningInfo.Constraints,
 		Tools:             possibleTools,
 		MachineConfig:     provisioningInfo.MachineConfig,
 		Placement:         provisioningInfo.Placement,
 		DistributionGroup: machine.DistributionGroup,
-	})
-	if err != nil {
+	}
+	if args.Placement == "" {
+		args.AvailabilityZone = zone
+	}
+	inst, metadata, networkInfo, err := task.startInstanceWithFallback(machine, args)
+	if err == tomb.ErrDying {
+		return err
+	} else if err != nil {
 		// Set the state to error, so the machine will be skipped next
 		// time until the error is resolved, but don't return an
 		// error; just keep going with the other machines.
@@ -494,6 +676,125 @@ func (task *provisionerTask) startMachine(machine *apiprovisioner.Machine) error
 	return nil
 }
 
+// zonedBroker is implemented by brokers that start instances in
+// availability zones.
+type zonedBroker interface {
+	AvailabilityZones() ([]common.AvailabilityZone, error)
+}
+
+// startInstanceWithFallback starts an instance for the machine. When the
+// broker lacks the capacity to start it, the start is attempted again in
+// the other available zones, then with the next-best instance types, up
+// to the configured number of attempts. Each failed attempt is recorded
+// in the machine's status. Only brokers that return a CapacityError are
+// retried, which are currently those of the ec2 and openstack providers;
+// see environs.StartInstanceParams.
+func (task *provisionerTask) startInstanceWithFallback(machine *apiprovisioner.Machine, args environs.StartInstanceParams) (
+	instance.Instance, *instance.HardwareCharacteristics, []network.Info, error,
+) {
+	maxAttempts := task.startAttempts()
+	var zones []string
+	triedZones := set.NewStrings()
+	for attempt := 1; ; attempt++ {
+		inst, metadata, networkInfo, err := task.startInstance(machine, args)
+		capacityErr, ok := err.(*environs.CapacityError)
+		if !ok || attempt == maxAttempts {
+			return inst, metadata, networkInfo, err
+		}
+		if zones == nil {
+			if zones, err = task.availabilityZones(args); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if capacityErr.Zone != "" {
+			triedZones.Add(capacityErr.Zone)
+		}
+		var retry string
+		if zone := nextZone(zones, triedZones); zone != "" {
+			args.AvailabilityZone = zone
+			retry = fmt.Sprintf("in availability zone %q", zone)
+		} else if capacityErr.InstanceType != "" {
+			// Every zone lacks capacity for the instance type, so
+			// try them all again with the next-best type.
+			args.AvailabilityZone = ""
+			args.ExcludedInstanceTypes = append(args.ExcludedInstanceTypes, capacityErr.InstanceType)
+			triedZones = set.NewStrings()
+			retry = "with another instance type"
+		} else {
+			return nil, nil, nil, capacityErr
+		}
+		info := fmt.Sprintf("attempt %d of %d failed: %v; retrying %s", attempt, maxAttempts, capacityErr, retry)
+		logger.Warningf("cannot start instance for machine %q: %s", machine, info)
+		if err := machine.SetStatus(params.StatusPending, info, nil); err != nil {
+			return nil, nil, nil, errors.Annotatef(err, "cannot set status of machine %q", machine)
+		}
+	}
+}
+
+// availabilityZones returns the names of the availability zones in
+// which an instance may be started with the given parameters.
+func (task *provisionerTask) availabilityZones(args environs.StartInstanceParams) ([]string, error) {
+	// A placement directive chooses the zone itself.
+	zoned, ok := task.broker.(zonedBroker)
+	if !ok || args.Placement != "" {
+		return []string{}, nil
+	}
+	availabilityZones, err := zoned.AvailabilityZones()
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot get availability zones")
+	}
+	zones := []string{}
+	for _, zone := range availabilityZones {
+		if zone.Available() {
+			zones = append(zones, zone.Name())
+		}
+	}
+	return zones, nil
+}
+
+// nextZone returns the first of zones not in tried, or "" if all have
+// been tried.
+func nextZone(zones []string, tried set.Strings) string {
+	for _, zone := range zones {
+		if !tried.Contains(zone) {
+			return zone
+		}
+	}
+	return ""
+}
+
+// startAttempts returns the number of times the start of an instance
+// is attempted when the broker lacks the capacity to start it.
+func (task *provisionerTask) startAttempts() int {
+	if env, ok := task.broker.(environs.Environ); ok {
+		return env.Config().StartInstanceAttempts()
+	}
+	return config.DefaultStartInstanceAttempts
+}
+
+// startInstance starts an instance for the machine with the broker.
+// While the broker reports that it is rate limiting requests, the start
+// is retried after a delay shared by all the task's starts.
+func (task *provisionerTask) startInstance(machine *apiprovisioner.Machine, args environs.StartInstanceParams) (
+	instance.Instance, *instance.HardwareCharacteristics, []network.Info, error,
+) {
+	for attempt := 1; ; attempt++ {
+		if err := task.backoff.wait(task.tomb.Dying()); err != nil {
+			return nil, nil, nil, err
+		}
+		inst, metadata, networkInfo, err := task.broker.StartInstance(args)
+		if err == nil {
+			task.backoff.succeeded()
+			return inst, metadata, networkInfo, nil
+		}
+		if !environs.IsRateLimited(err) || attempt == rateLimitAttempts {
+			return nil, nil, nil, err
+		}
+		delay := task.backoff.rateLimited()
+		logger.Warningf("provider is rate limiting requests; retrying start of machine %q in %v", machine, delay)
+	}
+}
+
 func (task *provisionerTask) possibleTools(series string, cons constraints.Value) (coretools.List, error) {
 	if env, ok := task.broker.(environs.Environ); ok {
 		return tools.FindInstanceTools(env, version.Current.Number, series, cons.Arch)
@@ -6,6 +6,7 @@ package provisioner_test
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -27,6 +28,7 @@ import (
 	"github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/network"
+	"github.com/juju/juju/provider/common"
 	"github.com/juju/juju/provider/dummy"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/api"
@@ -902,8 +904,16 @@ func (*mockMachineGetter) MachinesWithTransientErrors() ([]*apiprovisioner.Machi
 	return nil, nil, fmt.Errorf("error")
 }
 
+func (*mockMachineGetter) RetainedInstances() ([]params.RetainedInstance, error) {
+	return nil, fmt.Errorf("error")
+}
+
+func (*mockMachineGetter) ReleaseRetainedInstances(ids ...instance.Id) error {
+	return fmt.Errorf("error")
+}
+
 func (s *ProvisionerSuite) TestMachineErrorsRetainInstances(c *gc.C) {
-	task := s.newProvisionerTask(c, false, s.Environ, s.provisioner)
+	task := s.newProvisionerTask(c, config.HarvestAll, s.Environ, s.provisioner)
 	defer stop(c, task)
 
 	// create a machine
@@ -915,7 +925,7 @@ func (s *ProvisionerSuite) TestMachineErrorsRetainInstances(c *gc.C) {
 	s.startUnknownInstance(c, "999")
 
 	// start the provisioner and ensure it doesn't kill any instances if there are error getting machines
-	task = s.newProvisionerTask(c, false, s.Environ, &mockMachineGetter{})
+	task = s.newProvisionerTask(c, config.HarvestAll, s.Environ, &mockMachineGetter{})
 	defer func() {
 		err := task.Stop()
 		c.Assert(err, gc.ErrorMatches, ".*failed to get machine.*")
@@ -986,7 +996,7 @@ func (s *ProvisionerSuite) TestProvisioningSafeModeChange(c *gc.C) {
 }
 
 func (s *ProvisionerSuite) newProvisionerTask(
-	c *gc.C, safeMode bool, broker environs.InstanceBroker, machineGetter provisioner.MachineGetter,
+	c *gc.C, harvestMode config.HarvestMode, broker environs.InstanceBroker, machineGetter provisioner.MachineGetter,
 ) provisioner.ProvisionerTask {
 
 	machineWatcher, err := s.provisioner.WatchEnvironMachines()
@@ -996,12 +1006,12 @@ func (s *ProvisionerSuite) newProvisionerTask(
 	auth, err := authentication.NewAPIAuthenticator(s.provisioner)
 	c.Assert(err, gc.IsNil)
 	return provisioner.NewProvisionerTask(
-		names.NewMachineTag("0"), safeMode, machineGetter,
+		names.NewMachineTag("0"), harvestMode, machineGetter,
 		machineWatcher, retryWatcher, broker, auth)
 }
 
 func (s *ProvisionerSuite) TestTurningOffSafeModeReapsUnknownInstances(c *gc.C) {
-	task := s.newProvisionerTask(c, true, s.Environ, s.provisioner)
+	task := s.newProvisionerTask(c, config.HarvestDestroyed, s.Environ, s.provisioner)
 	defer stop(c, task)
 
 	// Initially create a machine, and an unknown instance, with safe mode on.
@@ -1018,14 +1028,71 @@ func (s *ProvisionerSuite) TestTurningOffSafeModeReapsUnknownInstances(c *gc.C)
 	s.waitRemoved(c, m0)
 
 	// turn off safe mode and check that the other machine is now stopped also.
-	task.SetSafeMode(false)
+	task.SetHarvestMode(config.HarvestAll)
 	s.checkStopInstances(c, i1)
 }
 
+func (s *ProvisionerSuite) TestHarvestModeUnknownKeepsInstancesOfDeadMachines(c *gc.C) {
+	task := s.newProvisionerTask(c, config.HarvestUnknown, s.Environ, s.provisioner)
+	defer func() { stop(c, task) }()
+
+	m0, err := s.addMachine()
+	c.Assert(err, gc.IsNil)
+	i0 := s.checkStartInstance(c, m0)
+	i1 := s.startUnknownInstance(c, "999")
+
+	// Only the unknown instance is stopped; the dead machine is removed
+	// from state but its instance is left running.
+	c.Assert(m0.EnsureDead(), gc.IsNil)
+	s.checkStopSomeInstances(c, []instance.Instance{i1}, []instance.Instance{i0})
+	s.waitRemoved(c, m0)
+
+	// The retained instance is recorded in state, so it is not taken
+	// for an unknown one once its machine is removed, even by another
+	// provisioner; but it is stopped once instances of destroyed
+	// machines may be harvested.
+	retained, err := s.State.RetainedInstances()
+	c.Assert(err, gc.IsNil)
+	c.Assert(retained, gc.DeepEquals, []state.RetainedInstance{{InstanceId: i0.Id(), MachineId: m0.Id()}})
+	s.checkNoOperations(c)
+	stop(c, task)
+	task = s.newProvisionerTask(c, config.HarvestUnknown, s.Environ, s.provisioner)
+	s.checkNoOperations(c)
+	task.SetHarvestMode(config.HarvestAll)
+	s.checkStopInstances(c, i0)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		retained, err = s.State.RetainedInstances()
+		c.Assert(err, gc.IsNil)
+		if len(retained) == 0 {
+			return
+		}
+	}
+	c.Fatalf("retained instance not released")
+}
+
+func (s *ProvisionerSuite) TestHarvestModeNoneKeepsAllInstances(c *gc.C) {
+	task := s.newProvisionerTask(c, config.HarvestNone, s.Environ, s.provisioner)
+	defer stop(c, task)
+
+	m0, err := s.addMachine()
+	c.Assert(err, gc.IsNil)
+	i0 := s.checkStartInstance(c, m0)
+	i1 := s.startUnknownInstance(c, "999")
+
+	c.Assert(m0.EnsureDead(), gc.IsNil)
+	s.waitRemoved(c, m0)
+	s.checkNoOperations(c)
+
+	// Once unknown instances may be harvested, only the unknown
+	// instance is stopped.
+	task.SetHarvestMode(config.HarvestUnknown)
+	s.checkStopSomeInstances(c, []instance.Instance{i1}, []instance.Instance{i0})
+}
+
 func (s *ProvisionerSuite) TestProvisionerRetriesTransientErrors(c *gc.C) {
 	s.PatchValue(&apiserverprovisioner.ErrorRetryWaitDelay, 5*time.Millisecond)
 	var e environs.Environ = &mockBroker{Environ: s.Environ, retryCount: make(map[string]int)}
-	task := s.newProvisionerTask(c, false, e, s.provisioner)
+	task := s.newProvisionerTask(c, config.HarvestAll, e, s.provisioner)
 	defer stop(c, task)
 
 	// Provision some machines, some will be started first time,
@@ -1070,7 +1137,7 @@ func (s *ProvisionerSuite) TestProvisionerRetriesTransientErrors(c *gc.C) {
 func (s *ProvisionerSuite) TestProvisionerObservesMachineJobs(c *gc.C) {
 	s.PatchValue(&apiserverprovisioner.ErrorRetryWaitDelay, 5*time.Millisecond)
 	broker := &mockBroker{Environ: s.Environ, retryCount: make(map[string]int)}
-	task := s.newProvisionerTask(c, false, broker, s.provisioner)
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
 	defer stop(c, task)
 
 	added := s.ensureAvailability(c, 3)
@@ -1084,13 +1151,354 @@ func (s *ProvisionerSuite) TestProvisionerObservesMachineJobs(c *gc.C) {
 	}
 }
 
+func (s *ProvisionerSuite) setProvisionerConcurrency(c *gc.C, n int) {
+	cfg, err := s.Environ.Config().Apply(map[string]interface{}{"provisioner-concurrency": n})
+	c.Assert(err, gc.IsNil)
+	err = s.Environ.SetConfig(cfg)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ProvisionerSuite) TestProvisionerStartsInstancesConcurrently(c *gc.C) {
+	s.setProvisionerConcurrency(c, 3)
+	var machines []*state.Machine
+	for i := 0; i < 6; i++ {
+		m, err := s.addMachine()
+		c.Assert(err, gc.IsNil)
+		machines = append(machines, m)
+	}
+	broker := &blockingBroker{Environ: s.Environ, release: make(chan struct{})}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
+	defer stop(c, task)
+
+	// Only three instances are started at once.
+	for a := coretesting.LongAttempt.Start(); broker.running() < 3; {
+		if !a.Next() {
+			c.Fatalf("instances not started concurrently")
+		}
+	}
+	time.Sleep(coretesting.ShortWait)
+	c.Assert(broker.running(), gc.Equals, 3)
+	close(broker.release)
+	for _, m := range machines {
+		s.waitMachine(c, m, func() bool {
+			c.Assert(m.Refresh(), gc.IsNil)
+			_, err := m.InstanceId()
+			return err == nil
+		})
+	}
+	c.Assert(broker.maxRunning, gc.Equals, 3)
+}
+
+func (s *ProvisionerSuite) TestProvisionerBacksOffWhenRateLimited(c *gc.C) {
+	s.PatchValue(provisioner.RateLimitInitialDelay, time.Millisecond)
+	broker := &rateLimitedBroker{Environ: s.Environ, limited: 2}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, gc.IsNil)
+	s.checkStartInstance(c, m)
+	c.Assert(broker.attempts, gc.Equals, 3)
+}
+
+func (s *ProvisionerSuite) TestProvisionerGivesUpWhenRateLimited(c *gc.C) {
+	s.PatchValue(provisioner.RateLimitInitialDelay, time.Millisecond)
+	s.PatchValue(provisioner.RateLimitAttempts, 3)
+	broker := &rateLimitedBroker{Environ: s.Environ, limited: 5}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, gc.IsNil)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		status, info, _, err := m.Status()
+		c.Assert(err, gc.IsNil)
+		if status == params.StatusPending {
+			continue
+		}
+		c.Assert(status, gc.Equals, params.StatusError)
+		c.Assert(info, gc.Equals, "too many requests")
+		c.Assert(broker.attempts, gc.Equals, 3)
+		return
+	}
+	c.Fatalf("machine status not set to error")
+}
+
+func (s *ProvisionerSuite) setStartInstanceAttempts(c *gc.C, n int) {
+	cfg, err := s.Environ.Config().Apply(map[string]interface{}{"start-instance-attempts": n})
+	c.Assert(err, gc.IsNil)
+	err = s.Environ.SetConfig(cfg)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ProvisionerSuite) TestProvisionerFallsBackToOtherZones(c *gc.C) {
+	broker := newCapacityBroker(s.Environ, "zone-a/big")
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, gc.IsNil)
+	s.checkStartInstance(c, m)
+	c.Assert(broker.starts(), gc.DeepEquals, []string{"zone-a/big", "zone-b/big"})
+
+	history, err := m.StatusHistory()
+	c.Assert(err, gc.IsNil)
+	c.Assert(history, gc.HasLen, 1)
+	c.Assert(history[0].Status, gc.Equals, params.StatusPending)
+	c.Assert(history[0].Info, gc.Equals, `attempt 1 of 3 failed: no capacity for instance type "big" in availability zone "zone-a": insufficient capacity; retrying in availability zone "zone-b"`)
+}
+
+func (s *ProvisionerSuite) TestProvisionerFallsBackToOtherInstanceTypes(c *gc.C) {
+	broker := newCapacityBroker(s.Environ, "zone-a/big", "zone-b/big")
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, gc.IsNil)
+	s.checkStartInstance(c, m)
+	c.Assert(broker.starts(), gc.DeepEquals, []string{"zone-a/big", "zone-b/big", "zone-a/small"})
+
+	history, err := m.StatusHistory()
+	c.Assert(err, gc.IsNil)
+	c.Assert(history, gc.HasLen, 2)
+	c.Assert(history[1].Info, gc.Equals, `attempt 2 of 3 failed: no capacity for instance type "big" in availability zone "zone-b": insufficient capacity; retrying with another instance type`)
+}
+
+func (s *ProvisionerSuite) TestProvisionerGivesUpAfterStartAttempts(c *gc.C) {
+	s.setStartInstanceAttempts(c, 2)
+	broker := newCapacityBroker(s.Environ, "zone-a/big", "zone-b/big")
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, gc.IsNil)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		status, info, _, err := m.Status()
+		c.Assert(err, gc.IsNil)
+		if status == params.StatusPending {
+			continue
+		}
+		c.Assert(status, gc.Equals, params.StatusError)
+		c.Assert(info, gc.Equals, `no capacity for instance type "big" in availability zone "zone-b": insufficient capacity`)
+		c.Assert(broker.starts(), gc.DeepEquals, []string{"zone-a/big", "zone-b/big"})
+		return
+	}
+	c.Fatalf("machine status not set to error")
+}
+
+func (s *ProvisionerSuite) TestProvisionerSpreadsConcurrentStartsAcrossZones(c *gc.C) {
+	s.setProvisionerConcurrency(c, 4)
+	for i := 0; i < 4; i++ {
+		_, err := s.addMachine()
+		c.Assert(err, gc.IsNil)
+	}
+	broker := &zonedBroker{Environ: s.Environ, zones: make(map[instance.Id]string)}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner)
+	defer stop(c, task)
+
+	for a := coretesting.LongAttempt.Start(); broker.started() < 4; {
+		if !a.Next() {
+			c.Fatalf("instances not started")
+		}
+	}
+	c.Assert(broker.zoneCounts(), gc.DeepEquals, map[string]int{"zone-a": 2, "zone-b": 2})
+}
+
+// blockingBroker starts instances only once release is closed,
+// recording how many starts are in progress at once.
+type blockingBroker struct {
+	environs.Environ
+	release    chan struct{}
+	mu         sync.Mutex
+	current    int
+	maxRunning int
+}
+
+func (b *blockingBroker) running() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current
+}
+
+func (b *blockingBroker) StartInstance(args environs.StartInstanceParams) (instance.Instance, *instance.HardwareCharacteristics, []network.Info, error) {
+	b.mu.Lock()
+	b.current++
+	if b.current > b.maxRunning {
+		b.maxRunning = b.current
+	}
+	b.mu.Unlock()
+	<-b.release
+	defer func() {
+		b.mu.Lock()
+		b.current--
+		b.mu.Unlock()
+	}()
+	return b.Environ.StartInstance(args)
+}
+
+func (b *blockingBroker) GetToolsSources() ([]simplestreams.DataSource, error) {
+	return b.Environ.(tools.SupportsCustomSources).GetToolsSources()
+}
+
+// rateLimitedBroker reports that it is rate limiting the given number
+// of instance starts before starting any.
+type rateLimitedBroker struct {
+	environs.Environ
+	limited  int
+	attempts int
+}
+
+func (b *rateLimitedBroker) StartInstance(args environs.StartInstanceParams) (instance.Instance, *instance.HardwareCharacteristics, []network.Info, error) {
+	b.attempts++
+	if b.attempts <= b.limited {
+		return nil, nil, nil, environs.NewRateLimitedError(fmt.Errorf("too many requests"))
+	}
+	return b.Environ.StartInstance(args)
+}
+
+func (b *rateLimitedBroker) GetToolsSources() ([]simplestreams.DataSource, error) {
+	return b.Environ.(tools.SupportsCustomSources).GetToolsSources()
+}
+
+// capacityBroker starts instances in two availability zones, with two
+// instance types, reporting that it lacks the capacity to start
+// instances in the given zone/type combinations.
+type capacityBroker struct {
+	environs.Environ
+	full map[string]bool
+
+	mu       sync.Mutex
+	attempts []string
+}
+
+func newCapacityBroker(env environs.Environ, full ...string) *capacityBroker {
+	b := &capacityBroker{Environ: env, full: make(map[string]bool)}
+	for _, f := range full {
+		b.full[f] = true
+	}
+	return b
+}
+
+func (b *capacityBroker) StartInstance(args environs.StartInstanceParams) (instance.Instance, *instance.HardwareCharacteristics, []network.Info, error) {
+	zone := args.AvailabilityZone
+	if zone == "" {
+		zone = "zone-a"
+	}
+	instanceType := "big"
+	for _, excluded := range args.ExcludedInstanceTypes {
+		if excluded == "big" {
+			instanceType = "small"
+		}
+	}
+	b.mu.Lock()
+	b.attempts = append(b.attempts, zone+"/"+instanceType)
+	b.mu.Unlock()
+	if b.full[zone+"/"+instanceType] {
+		return nil, nil, nil, &environs.CapacityError{
+			Zone:         zone,
+			InstanceType: instanceType,
+			Err:          fmt.Errorf("insufficient capacity"),
+		}
+	}
+	return b.Environ.StartInstance(args)
+}
+
+func (b *capacityBroker) AvailabilityZones() ([]common.AvailabilityZone, error) {
+	return []common.AvailabilityZone{
+		capacityZone("zone-a"),
+		capacityZone("zone-b"),
+	}, nil
+}
+
+func (b *capacityBroker) starts() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.attempts...)
+}
+
+func (b *capacityBroker) GetToolsSources() ([]simplestreams.DataSource, error) {
+	return b.Environ.(tools.SupportsCustomSources).GetToolsSources()
+}
+
+type capacityZone string
+
+func (z capacityZone) Name() string {
+	return string(z)
+}
+
+func (z capacityZone) Available() bool {
+	return true
+}
+
+// zonedBroker starts instances in the availability zones they are
+// started with, recording the zone of each.
+type zonedBroker struct {
+	environs.Environ
+
+	mu    sync.Mutex
+	zones map[instance.Id]string
+}
+
+func (b *zonedBroker) StartInstance(args environs.StartInstanceParams) (instance.Instance, *instance.HardwareCharacteristics, []network.Info, error) {
+	inst, hc, networkInfo, err := b.Environ.StartInstance(args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	b.mu.Lock()
+	b.zones[inst.Id()] = args.AvailabilityZone
+	b.mu.Unlock()
+	return inst, hc, networkInfo, nil
+}
+
+func (b *zonedBroker) AvailabilityZones() ([]common.AvailabilityZone, error) {
+	return []common.AvailabilityZone{
+		capacityZone("zone-a"),
+		capacityZone("zone-b"),
+	}, nil
+}
+
+func (b *zonedBroker) InstanceAvailabilityZoneNames(ids []instance.Id) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = b.zones[id]
+	}
+	return names, nil
+}
+
+func (b *zonedBroker) started() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.zones)
+}
+
+func (b *zonedBroker) zoneCounts() map[string]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	counts := make(map[string]int)
+	for _, zone := range b.zones {
+		counts[zone]++
+	}
+	return counts
+}
+
+func (b *zonedBroker) GetToolsSources() ([]simplestreams.DataSource, error) {
+	return b.Environ.(tools.SupportsCustomSources).GetToolsSources()
+}
+
 type mockBroker struct {
 	environs.Environ
+	mu         sync.Mutex
 	retryCount map[string]int
 	ids        []string
 }
 
 func (b *mockBroker) StartInstance(args environs.StartInstanceParams) (instance.Instance, *instance.HardwareCharacteristics, []network.Info, error) {
+	// Instances may be started concurrently; starting them one at a
+	// time keeps the ids in the order the instances are started.
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	// All machines except machines 3, 4 are provisioned successfully the first time.
 	// Machines 3 is provisioned after some attempts have been made.
 	// Machine 4 is never provisioned.
@@ -0,0 +1,145 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package resolvconfupdater
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/juju/loggo"
+	"github.com/juju/utils"
+
+	"github.com/juju/juju/environs/cloudinit"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state/api/watcher"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/apiaddressupdater"
+)
+
+var (
+	logger = loggo.GetLogger("juju.worker.resolvconfupdater")
+
+	// HeadFile holds the nameserver block written when the machine
+	// was provisioned.
+	HeadFile = cloudinit.ResolvconfHeadFile
+
+	// updateResolvconf regenerates /etc/resolv.conf from HeadFile.
+	updateResolvconf = func() error {
+		_, err := utils.RunCommand("resolvconf", "-u")
+		return err
+	}
+)
+
+// ResolvConfUpdater keeps the nameservers a machine uses for the
+// environment's DNS service in step with the state servers.
+//
+// The nameservers are only rewritten on machines whose resolver was
+// configured for the DNS service when they were provisioned, so hosts
+// not managed by juju are left alone.
+type ResolvConfUpdater struct {
+	addresser     apiaddressupdater.APIAddresser
+	isStateServer bool
+}
+
+// NewResolvConfUpdater returns a worker.Worker that watches for changes
+// to API addresses and rewrites the machine's nameservers to match.
+// A state server runs its own DNS server, which it uses first.
+func NewResolvConfUpdater(addresser apiaddressupdater.APIAddresser, isStateServer bool) worker.Worker {
+	return worker.NewNotifyWorker(&ResolvConfUpdater{
+		addresser:     addresser,
+		isStateServer: isStateServer,
+	})
+}
+
+func (u *ResolvConfUpdater) SetUp() (watcher.NotifyWatcher, error) {
+	return u.addresser.WatchAPIHostPorts()
+}
+
+func (u *ResolvConfUpdater) Handle() error {
+	hostPorts, err := u.addresser.APIHostPorts()
+	if err != nil {
+		return fmt.Errorf("error getting addresses: %v", err)
+	}
+	servers := u.dnsServers(hostPorts)
+	if len(servers) == 0 {
+		logger.Warningf("no state server has an IP address usable as a nameserver")
+		return nil
+	}
+	if err := updateResolver(servers); err != nil {
+		return fmt.Errorf("cannot update nameservers: %v", err)
+	}
+	return nil
+}
+
+func (u *ResolvConfUpdater) TearDown() error {
+	return nil
+}
+
+// dnsServers returns the addresses of the DNS servers the machine
+// should use, given the API addresses of the state servers.
+func (u *ResolvConfUpdater) dnsServers(hostPorts [][]network.HostPort) []string {
+	var servers []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		if !seen[host] {
+			seen[host] = true
+			servers = append(servers, host)
+		}
+	}
+	if u.isStateServer {
+		add("127.0.0.1")
+	}
+	for _, hps := range hostPorts {
+		host, _, err := net.SplitHostPort(network.SelectInternalHostPort(hps, false))
+		if err != nil || net.ParseIP(host) == nil {
+			// The resolver only accepts IP addresses.
+			continue
+		}
+		add(host)
+	}
+	return servers
+}
+
+// updateResolver replaces the nameserver block in HeadFile with one
+// for the given servers. It does nothing if HeadFile has no block.
+func updateResolver(servers []string) error {
+	data, err := ioutil.ReadFile(HeadFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var kept []string
+	found, inBlock := false, false
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case line == cloudinit.DNSBlockBegin:
+			found, inBlock = true, true
+		case inBlock:
+			inBlock = line != cloudinit.DNSBlockEnd
+		default:
+			kept = append(kept, line)
+		}
+	}
+	if !found {
+		return nil
+	}
+	content := strings.Join(kept, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += strings.Join(cloudinit.DNSResolverBlock(servers), "\n") + "\n"
+	if content == string(data) {
+		return nil
+	}
+	logger.Infof("nameservers updated to %q", servers)
+	if err := ioutil.WriteFile(HeadFile, []byte(content), 0644); err != nil {
+		return err
+	}
+	return updateResolvconf()
+}
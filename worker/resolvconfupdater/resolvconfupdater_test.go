@@ -0,0 +1,110 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package resolvconfupdater_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	stdtesting "testing"
+	"time"
+
+	gc "launchpad.net/gocheck"
+
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/resolvconfupdater"
+)
+
+func TestPackage(t *stdtesting.T) {
+	coretesting.MgoTestPackage(t)
+}
+
+type ResolvConfUpdaterSuite struct {
+	jujutesting.JujuConnSuite
+	headFile string
+	updated  chan struct{}
+}
+
+var _ = gc.Suite(&ResolvConfUpdaterSuite{})
+
+func (s *ResolvConfUpdaterSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.headFile = filepath.Join(c.MkDir(), "head")
+	s.PatchValue(&resolvconfupdater.HeadFile, s.headFile)
+	s.updated = make(chan struct{}, 1)
+	s.PatchValue(resolvconfupdater.UpdateResolvconf, func() error {
+		s.updated <- struct{}{}
+		return nil
+	})
+	err := s.State.SetAPIHostPorts([][]network.HostPort{
+		network.AddressesWithPort(network.NewAddresses("10.0.0.1", "54.0.0.1"), 17070),
+		network.AddressesWithPort(network.NewAddresses("10.0.0.2"), 17070),
+	})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ResolvConfUpdaterSuite) writeHeadFile(c *gc.C, content string) {
+	err := ioutil.WriteFile(s.headFile, []byte(content), 0644)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ResolvConfUpdaterSuite) startWorker(c *gc.C, isStateServer bool) worker.Worker {
+	st, _ := s.OpenAPIAsNewMachine(c, state.JobHostUnits)
+	return resolvconfupdater.NewResolvConfUpdater(st.Machiner(), isStateServer)
+}
+
+func (s *ResolvConfUpdaterSuite) waitUpdated(c *gc.C) {
+	select {
+	case <-s.updated:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for the nameservers to be updated")
+	}
+}
+
+func (s *ResolvConfUpdaterSuite) assertHeadFile(c *gc.C, expected string) {
+	data, err := ioutil.ReadFile(s.headFile)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(data), gc.Equals, expected)
+}
+
+func (s *ResolvConfUpdaterSuite) TestReplacesBlock(c *gc.C) {
+	s.writeHeadFile(c, "# other\n# begin juju dns-service\nnameserver 10.0.0.9\n# end juju dns-service\n")
+	w := s.startWorker(c, false)
+	defer func() { c.Assert(worker.Stop(w), gc.IsNil) }()
+
+	s.waitUpdated(c)
+	s.assertHeadFile(c, "# other\n# begin juju dns-service\nnameserver 10.0.0.1\nnameserver 10.0.0.2\n# end juju dns-service\n")
+
+	err := s.State.SetAPIHostPorts([][]network.HostPort{
+		network.AddressesWithPort(network.NewAddresses("10.0.0.3"), 17070),
+	})
+	c.Assert(err, gc.IsNil)
+	s.waitUpdated(c)
+	s.assertHeadFile(c, "# other\n# begin juju dns-service\nnameserver 10.0.0.3\n# end juju dns-service\n")
+}
+
+func (s *ResolvConfUpdaterSuite) TestStateServerUsesOwnServerFirst(c *gc.C) {
+	s.writeHeadFile(c, "# begin juju dns-service\nnameserver 127.0.0.1\n# end juju dns-service\n")
+	w := s.startWorker(c, true)
+	defer func() { c.Assert(worker.Stop(w), gc.IsNil) }()
+
+	s.waitUpdated(c)
+	s.assertHeadFile(c, "# begin juju dns-service\nnameserver 127.0.0.1\nnameserver 10.0.0.1\n# end juju dns-service\n")
+}
+
+func (s *ResolvConfUpdaterSuite) TestLeavesFileWithoutBlockAlone(c *gc.C) {
+	s.writeHeadFile(c, "nameserver 8.8.8.8\n")
+	w := s.startWorker(c, false)
+	defer func() { c.Assert(worker.Stop(w), gc.IsNil) }()
+
+	select {
+	case <-s.updated:
+		c.Fatalf("unexpected nameserver update")
+	case <-time.After(coretesting.ShortWait):
+	}
+	s.assertHeadFile(c, "nameserver 8.8.8.8\n")
+}
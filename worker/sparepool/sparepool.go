@@ -0,0 +1,44 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package sparepool
+
+import (
+	"github.com/juju/loggo"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/api/watcher"
+	"github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.sparepool")
+
+// SparePool keeps the environment's pool of spare machines topped up.
+type SparePool struct {
+	st *state.State
+}
+
+// NewSparePool returns a worker.Worker that runs
+// state.EnsureSpareMachines() whenever machines, services,
+// constraints or the environment configuration change.
+func NewSparePool(st *state.State) worker.Worker {
+	return worker.NewNotifyWorker(&SparePool{st: st})
+}
+
+func (p *SparePool) SetUp() (watcher.NotifyWatcher, error) {
+	return p.st.WatchSpareMachines(), nil
+}
+
+func (p *SparePool) Handle() error {
+	if err := p.st.EnsureSpareMachines(); err != nil {
+		logger.Errorf("cannot maintain spare machines: %v", err)
+	}
+	// As with the cleaner, a failure here should not stop the
+	// worker; the next change will try again.
+	return nil
+}
+
+func (p *SparePool) TearDown() error {
+	// Nothing to do here.
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package sparepool_test
+
+import (
+	stdtesting "testing"
+	"time"
+
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/sparepool"
+)
+
+func TestPackage(t *stdtesting.T) {
+	coretesting.MgoTestPackage(t)
+}
+
+type sparePoolSuite struct {
+	testing.JujuConnSuite
+}
+
+var _ = gc.Suite(&sparePoolSuite{})
+
+var _ worker.NotifyWatchHandler = (*sparepool.SparePool)(nil)
+
+func (s *sparePoolSuite) countSpares(c *gc.C) int {
+	machines, err := s.State.AllMachines()
+	c.Assert(err, gc.IsNil)
+	count := 0
+	for _, m := range machines {
+		if m.IsSpare() && m.Life() == state.Alive {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *sparePoolSuite) waitSpares(c *gc.C, expect int) {
+	timeout := time.After(coretesting.LongWait)
+	for {
+		s.State.StartSync()
+		select {
+		case <-time.After(coretesting.ShortWait):
+			if s.countSpares(c) == expect {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("timed out waiting for %d spare machines", expect)
+		}
+	}
+}
+
+func (s *sparePoolSuite) TestSparePool(c *gc.C) {
+	p := sparepool.NewSparePool(s.State)
+	defer func() { c.Assert(worker.Stop(p), gc.IsNil) }()
+
+	err := s.State.UpdateEnvironConfig(map[string]interface{}{"spare-machines": 2}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	s.waitSpares(c, 2)
+
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{"spare-machines": 1}, nil, nil)
+	c.Assert(err, gc.IsNil)
+	s.waitSpares(c, 1)
+}
@@ -0,0 +1,178 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnetupdater
+
+import (
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/juju/loggo"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
+	"github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.subnetupdater")
+
+// PollInterval is how often the subnets reported by the provider are
+// checked for changes.
+var PollInterval = 5 * time.Minute
+
+type subnetUpdater struct {
+	st   *state.State
+	tomb tomb.Tomb
+}
+
+// NewWorker returns a worker that keeps the subnets recorded in state
+// in step with those the provider reports for the environment. It
+// checks them periodically, and whenever the environment's machines
+// change, as new instances may be connected to new subnets.
+func NewWorker(st *state.State) worker.Worker {
+	u := &subnetUpdater{
+		st: st,
+	}
+	go func() {
+		defer u.tomb.Done()
+		u.tomb.Kill(u.loop())
+	}()
+	return u
+}
+
+func (u *subnetUpdater) Kill() {
+	u.tomb.Kill(nil)
+}
+
+func (u *subnetUpdater) Wait() error {
+	return u.tomb.Wait()
+}
+
+func (u *subnetUpdater) loop() (err error) {
+	observer, err := worker.NewEnvironObserver(u.st)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		obsErr := worker.Stop(observer)
+		if err == nil {
+			err = obsErr
+		}
+	}()
+	if _, ok := observer.Environ().(environs.SubnetDiscoverer); !ok {
+		logger.Debugf("provider cannot discover subnets")
+		<-u.tomb.Dying()
+		return tomb.ErrDying
+	}
+	machines := u.st.WatchEnvironMachines()
+	defer watcher.Stop(machines, &u.tomb)
+	for {
+		select {
+		case <-u.tomb.Dying():
+			return tomb.ErrDying
+		case _, ok := <-machines.Changes():
+			if !ok {
+				return watcher.MustErr(machines)
+			}
+		case <-time.After(PollInterval):
+		}
+		discoverer := observer.Environ().(environs.SubnetDiscoverer)
+		if err := updateSubnets(u.st, discoverer); err != nil {
+			// The provider may be briefly unavailable; the next
+			// check will try again.
+			logger.Errorf("cannot update subnets: %v", err)
+		}
+	}
+}
+
+// updateSubnets records the subnets the provider reports for the
+// environment, refreshes the details of those already known and
+// removes those the provider no longer reports. Subnets that are in a
+// space are never removed, as that would silently change the space.
+func updateSubnets(st *state.State, discoverer environs.SubnetDiscoverer) error {
+	infos, err := discoverer.Subnets("")
+	if err != nil {
+		return err
+	}
+	reported := make(map[string]network.SubnetInfo)
+	ambiguous := make(map[string]bool)
+	for _, info := range infos {
+		_, ipNet, err := net.ParseCIDR(info.CIDR)
+		if err != nil {
+			logger.Warningf("ignoring subnet %q with invalid CIDR", info.ProviderId)
+			continue
+		}
+		cidr := ipNet.String()
+		if other, ok := reported[cidr]; ok && other.ProviderId != info.ProviderId {
+			// Subnets are identified by CIDR, so we cannot tell
+			// which of the two an address belongs to.
+			logger.Warningf("ignoring subnets %q and %q with the same CIDR %s", other.ProviderId, info.ProviderId, cidr)
+			ambiguous[cidr] = true
+		}
+		info.CIDR = cidr
+		reported[cidr] = info
+	}
+	known, err := st.AllSubnets()
+	if err != nil {
+		return err
+	}
+	for _, subnet := range known {
+		if ambiguous[subnet.CIDR()] {
+			continue
+		}
+		info, ok := reported[subnet.CIDR()]
+		if !ok {
+			if subnet.SpaceName() != "" {
+				logger.Warningf("subnet %s in space %q is no longer reported by the provider", subnet.CIDR(), subnet.SpaceName())
+				continue
+			}
+			logger.Infof("removing subnet %s", subnet.CIDR())
+			if err := subnet.Remove(); err != nil {
+				return err
+			}
+			continue
+		}
+		delete(reported, subnet.CIDR())
+		if subnetChanged(subnet, info) {
+			logger.Infof("updating subnet %s", subnet.CIDR())
+			if err := subnet.Update(stateSubnetInfo(info)); err != nil {
+				return err
+			}
+		}
+	}
+	for cidr, info := range reported {
+		if ambiguous[cidr] {
+			continue
+		}
+		logger.Infof("adding subnet %s", cidr)
+		if _, err := st.AddSubnet(stateSubnetInfo(info)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subnetChanged reports whether the provider details of the subnet
+// differ from info.
+func subnetChanged(subnet *state.Subnet, info network.SubnetInfo) bool {
+	zones := subnet.AvailabilityZones()
+	if len(zones) == 0 && len(info.AvailabilityZones) == 0 {
+		zones = info.AvailabilityZones
+	}
+	return subnet.ProviderId() != info.ProviderId ||
+		subnet.VLANTag() != info.VLANTag ||
+		!reflect.DeepEqual(zones, info.AvailabilityZones)
+}
+
+func stateSubnetInfo(info network.SubnetInfo) state.SubnetInfo {
+	return state.SubnetInfo{
+		CIDR:              info.CIDR,
+		ProviderId:        info.ProviderId,
+		VLANTag:           info.VLANTag,
+		AvailabilityZones: info.AvailabilityZones,
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnetupdater_test
+
+import (
+	"reflect"
+	stdtesting "testing"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/subnetupdater"
+)
+
+func TestPackage(t *stdtesting.T) {
+	coretesting.MgoTestPackage(t)
+}
+
+type subnetUpdaterSuite struct {
+	testing.JujuConnSuite
+}
+
+var _ = gc.Suite(&subnetUpdaterSuite{})
+
+// subnetIds returns the provider ids of the subnets in state, keyed
+// by CIDR.
+func (s *subnetUpdaterSuite) subnetIds(c *gc.C) map[string]network.Id {
+	subnets, err := s.State.AllSubnets()
+	c.Assert(err, gc.IsNil)
+	ids := make(map[string]network.Id)
+	for _, subnet := range subnets {
+		ids[subnet.CIDR()] = subnet.ProviderId()
+	}
+	return ids
+}
+
+func (s *subnetUpdaterSuite) TestUpdatesSubnets(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "0.10.0.0/16", ProviderId: "old-id"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "gone"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.2.0/24", ProviderId: "gone-but-used"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.2.0/24"})
+	c.Assert(err, gc.IsNil)
+
+	w := subnetupdater.NewWorker(s.State)
+	defer func() { c.Assert(worker.Stop(w), gc.IsNil) }()
+
+	expect := map[string]network.Id{
+		"0.10.0.0/16": "dummy-private",
+		"0.20.0.0/24": "dummy-public",
+		"10.0.2.0/24": "gone-but-used",
+	}
+	timeout := time.After(coretesting.LongWait)
+	for {
+		s.State.StartSync()
+		select {
+		case <-time.After(coretesting.ShortWait):
+			if reflect.DeepEqual(s.subnetIds(c), expect) {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("timed out waiting for subnets; got %v", s.subnetIds(c))
+		}
+	}
+}
+
+type mockDiscoverer []network.SubnetInfo
+
+func (d mockDiscoverer) Subnets(instId instance.Id) ([]network.SubnetInfo, error) {
+	return d, nil
+}
+
+func (s *subnetUpdaterSuite) TestUpdateSubnetsIgnoresAmbiguousCIDRs(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+
+	err = subnetupdater.UpdateSubnets(s.State, mockDiscoverer{
+		{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"},
+		{CIDR: "10.0.1.0/24", ProviderId: "subnet-2"},
+		{CIDR: "10.0.2.7/24", ProviderId: "subnet-3"},
+		{CIDR: "10.0.3.0/24", ProviderId: "subnet-4"},
+		{CIDR: "10.0.3.0/24", ProviderId: "subnet-5"},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.subnetIds(c), jc.DeepEquals, map[string]network.Id{
+		"10.0.1.0/24": "subnet-1",
+		"10.0.2.0/24": "subnet-3",
+	})
+}
@@ -250,3 +250,30 @@ func (s *ManifestDeployerSuite) TestUpgradeConflictRevertRetryDifferentCharm(c *
 	ft.Removed{"old-file"}.Check(c, s.targetPath)
 	ft.Removed{"bad-file"}.Check(c, s.targetPath)
 }
+
+func (s *ManifestDeployerSuite) TestRollbackAfterUpgrade(c *gc.C) {
+	// Create base install and add a user file.
+	originalContent := ft.Entries{
+		ft.File{"shared-file", "old", 0755},
+		ft.File{"old-file", "old", 0644},
+	}
+	info := s.deployCharm(c, 1, originalContent...)
+	userFile := ft.File{"user-file", "user", 0644}.Create(c, s.targetPath)
+
+	// Upgrade to a charm that replaces and adds files.
+	s.deployCharm(c, 2,
+		ft.File{"shared-file", "new", 0644},
+		ft.File{"new-file", "new", 0644},
+	)
+	ft.Removed{"old-file"}.Check(c, s.targetPath)
+
+	// Deploy the original charm again; check its content is restored,
+	// the upgrade's files are gone, and user files are preserved.
+	err := s.deployer.Stage(info, nil)
+	c.Assert(err, gc.IsNil)
+	err = s.deployer.Deploy()
+	c.Assert(err, gc.IsNil)
+	s.assertCharm(c, 1, originalContent...)
+	ft.Removed{"new-file"}.Check(c, s.targetPath)
+	userFile.Check(c, s.targetPath)
+}
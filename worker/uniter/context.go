@@ -5,6 +5,7 @@ package uniter
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -49,6 +50,24 @@ func IsMissingHookError(err error) bool {
 	return ok
 }
 
+// hookTimeoutError is returned when a hook is killed for running
+// for longer than it is allowed to.
+type hookTimeoutError struct {
+	hookName string
+	timeout  time.Duration
+}
+
+func (e *hookTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v", e.hookName, e.timeout)
+}
+
+// IsHookTimeoutError returns whether the given error was returned
+// because a hook ran for too long.
+func IsHookTimeoutError(err error) bool {
+	_, ok := err.(*hookTimeoutError)
+	return ok
+}
+
 // HookContext is the implementation of jujuc.Context.
 type HookContext struct {
 	unit *uniter.Unit
@@ -61,6 +80,10 @@ type HookContext struct {
 	// address.
 	publicAddress string
 
+	// endpointAddresses holds the cached addresses of the unit for
+	// each endpoint of its service.
+	endpointAddresses map[string]string
+
 	// configSettings holds the service configuration.
 	configSettings charm.Settings
 
@@ -98,6 +121,14 @@ type HookContext struct {
 
 	// proxySettings are the current proxy settings that the uniter knows about
 	proxySettings proxy.Settings
+
+	// hookOutput holds the last lines output by the most recently
+	// run hook.
+	hookOutput []string
+
+	// hookTimeout holds the maximum time a hook may run for before
+	// it is killed; if zero, hooks may run indefinitely.
+	hookTimeout time.Duration
 }
 
 func NewHookContext(
@@ -136,6 +167,15 @@ func NewHookContext(
 	if err != nil && !params.IsCodeNoAddressSet(err) {
 		return nil, err
 	}
+	ctx.endpointAddresses, err = unit.EndpointAddresses()
+	if params.IsCodeNotImplemented(err) {
+		// The API server predates endpoint bindings; hook tools
+		// fall back to the private address where they can.
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
 	return ctx, nil
 }
 
@@ -151,6 +191,14 @@ func (ctx *HookContext) PrivateAddress() (string, bool) {
 	return ctx.privateAddress, ctx.privateAddress != ""
 }
 
+func (ctx *HookContext) EndpointAddresses() map[string]string {
+	addresses := make(map[string]string)
+	for endpoint, addr := range ctx.endpointAddresses {
+		addresses[endpoint] = addr
+	}
+	return addresses
+}
+
 func (ctx *HookContext) OpenPort(protocol string, port int) error {
 	return ctx.unit.OpenPort(protocol, port)
 }
@@ -310,17 +358,91 @@ func (ctx *HookContext) finalizeContext(process string, err error) error {
 }
 
 // RunCommands executes the commands in an environment which allows it to to
-// call back into the hook context to execute jujuc tools.
-func (ctx *HookContext) RunCommands(commands, charmDir, toolsDir, socketPath string) (*utilexec.ExecResponse, error) {
+// call back into the hook context to execute jujuc tools. If stdout and
+// stderr are not nil, the output is written to them as it arrives, as well
+// as being returned. If abort is closed before the commands finish, they
+// are killed.
+func (ctx *HookContext) RunCommands(commands, charmDir, toolsDir, socketPath string, stdout, stderr io.Writer, abort <-chan struct{}) (*utilexec.ExecResponse, error) {
 	env := ctx.hookVars(charmDir, toolsDir, socketPath)
-	result, err := utilexec.RunCommands(
-		utilexec.RunParams{
-			Commands:    commands,
-			WorkingDir:  charmDir,
-			Environment: env})
+	result, err := runCommands(commands, charmDir, env, stdout, stderr, abort)
 	return result, ctx.finalizeContext("run commands", err)
 }
 
+// runCommandsCommand returns the command that RunCommands uses to run
+// the commands given on its standard input.
+func runCommandsCommand() []string {
+	if version.Current.OS == version.Windows {
+		return []string{"powershell.exe", "-NoProfile", "-NonInteractive", "-Command", "-"}
+	}
+	return []string{"/bin/bash", "-s"}
+}
+
+// errCommandsAborted is returned by runCommands when the commands are
+// killed because the abort channel was closed.
+var errCommandsAborted = fmt.Errorf("commands aborted")
+
+// runCommands runs the commands in dir with the given environment. If
+// abort is closed before they finish, they are killed along with any
+// processes they started.
+func runCommands(commands, dir string, env []string, stdout, stderr io.Writer, abort <-chan struct{}) (*utilexec.ExecResponse, error) {
+	runCmd := runCommandsCommand()
+	ps := exec.Command(runCmd[0], runCmd[1:]...)
+	ps.Env = env
+	ps.Dir = dir
+	ps.Stdin = strings.NewReader(commands + "\n")
+	var outBuf, errBuf bytes.Buffer
+	ps.Stdout = &outBuf
+	if stdout != nil {
+		ps.Stdout = io.MultiWriter(&outBuf, stdout)
+	}
+	ps.Stderr = &errBuf
+	if stderr != nil {
+		ps.Stderr = io.MultiWriter(&errBuf, stderr)
+	}
+	setHookProcessGroup(ps)
+	err := ps.Start()
+	if err == nil {
+		err = waitCommands(ps, abort)
+	}
+	result := &utilexec.ExecResponse{
+		Stdout: outBuf.Bytes(),
+		Stderr: errBuf.Bytes(),
+	}
+	// A non-zero exit code isn't considered an error here.
+	if err == errCommandsAborted {
+		result.Code = -1
+	} else if code := hookExitCode(err); code >= 0 {
+		result.Code = code
+		err = nil
+	}
+	return result, err
+}
+
+// waitCommands waits for the process running commands to exit,
+// killing it if abort is closed first.
+func waitCommands(ps *exec.Cmd, abort <-chan struct{}) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- ps.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-abort:
+	}
+	if err := killHookProcess(ps.Process); err != nil {
+		logger.Errorf("cannot kill aborted commands: %v", err)
+	}
+	<-done
+	return errCommandsAborted
+}
+
+// HookOutput returns the last lines output by the most recently run
+// hook, if any.
+func (ctx *HookContext) HookOutput() []string {
+	return ctx.hookOutput
+}
+
 func (ctx *HookContext) GetLogger(hookName string) loggo.Logger {
 	return loggo.GetLogger(fmt.Sprintf("unit.%s.%s", ctx.UnitName(), hookName))
 }
@@ -339,6 +461,7 @@ func (ctx *HookContext) RunHook(hookName, charmDir, toolsDir, socketPath string)
 
 func (ctx *HookContext) runCharmHookWithLocation(hookName, charmLocation, charmDir, toolsDir, socketPath string) error {
 	var err error
+	ctx.hookOutput = nil
 	env := ctx.hookVars(charmDir, toolsDir, socketPath)
 	debugctx := unitdebug.NewHooksContext(ctx.unit.Name())
 	if session, _ := debugctx.FindSession(); session != nil && session.MatchHook(hookName) {
@@ -435,21 +558,69 @@ func (ctx *HookContext) runCharmHook(hookName, charmDir string, env []string, ch
 		logger: ctx.GetLogger(hookName),
 	}
 	go hookLogger.run()
+	setHookProcessGroup(ps)
 	err = ps.Start()
 	outWriter.Close()
 	if err == nil {
-		err = ps.Wait()
+		err = ctx.waitHook(hookName, ps)
 	}
 	hookLogger.stop()
+	ctx.hookOutput = hookLogger.output()
 	return err
 }
 
+// waitHook waits for the hook's process to exit. If the hook runs
+// for longer than the context's hook timeout, it is killed along with
+// any processes it started.
+func (ctx *HookContext) waitHook(hookName string, ps *exec.Cmd) error {
+	if ctx.hookTimeout <= 0 {
+		return ps.Wait()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- ps.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(ctx.hookTimeout):
+	}
+	logger.Errorf("%s timed out after %v; killing it", hookName, ctx.hookTimeout)
+	if err := killHookProcess(ps.Process); err != nil {
+		logger.Errorf("cannot kill %s: %v", hookName, err)
+	}
+	<-done
+	return &hookTimeoutError{hookName, ctx.hookTimeout}
+}
+
+// hookExitCode returns the exit code of a hook's process, given the
+// error returned when running it, or -1 if the process did not exit
+// normally.
+func hookExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(interface {
+			ExitStatus() int
+		}); ok {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
+
+// maxHookOutputLines is the number of lines of a hook's output that
+// are kept to be recorded with its execution.
+const maxHookOutputLines = 20
+
 type hookLogger struct {
 	r       io.ReadCloser
 	done    chan struct{}
 	mu      sync.Mutex
 	stopped bool
 	logger  loggo.Logger
+	tail    []string
 }
 
 func (l *hookLogger) run() {
@@ -470,6 +641,10 @@ func (l *hookLogger) run() {
 			return
 		}
 		l.logger.Infof("%s", line)
+		if len(l.tail) == maxHookOutputLines {
+			l.tail = l.tail[1:]
+		}
+		l.tail = append(l.tail, string(line))
 		l.mu.Unlock()
 	}
 }
@@ -491,6 +666,13 @@ func (l *hookLogger) stop() {
 	l.mu.Unlock()
 }
 
+// output returns the last lines logged.
+func (l *hookLogger) output() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.tail...)
+}
+
 // SettingsMap is a map from unit name to relation settings.
 type SettingsMap map[string]params.RelationSettings
 
@@ -584,6 +766,19 @@ func (ctx *ContextRelation) Settings() (jujuc.Settings, error) {
 	return ctx.settings, nil
 }
 
+func (ctx *ContextRelation) LocalSettings() (params.RelationSettings, error) {
+	// Settings fetched here are not kept, since any settings the
+	// context has fetched are written when the hook completes.
+	node := ctx.settings
+	if node == nil {
+		var err error
+		if node, err = ctx.ru.Settings(); err != nil {
+			return nil, err
+		}
+	}
+	return node.Map(), nil
+}
+
 func (ctx *ContextRelation) ReadSettings(unit string) (settings params.RelationSettings, err error) {
 	settings, member := ctx.members[unit]
 	if settings == nil {
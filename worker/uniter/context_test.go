@@ -4,11 +4,15 @@
 package uniter_test
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/juju/charm"
@@ -25,6 +29,7 @@ import (
 	"github.com/juju/juju/state/api"
 	"github.com/juju/juju/state/api/params"
 	apiuniter "github.com/juju/juju/state/api/uniter"
+	coretesting "github.com/juju/juju/testing"
 	"github.com/juju/juju/worker/uniter"
 	"github.com/juju/juju/worker/uniter/jujuc"
 )
@@ -72,6 +77,10 @@ type hookSpec struct {
 	stderr string
 	// background holds a string to print in the background after 0.2s.
 	background string
+	// hang holds whether the hook should start a background process,
+	// writing its pid to the output path with a ".pid" suffix, and wait
+	// for it forever.
+	hang bool
 }
 
 // makeCharm constructs a fake charm dir containing a single named hook
@@ -110,6 +119,11 @@ func makeCharm(c *gc.C, spec hookSpec) (charmDir, outPath string) {
 		// expected.
 		printf("(sleep 0.2; echo %s; sleep 10) &", spec.background)
 	}
+	if spec.hang {
+		printf("sleep 3600 &")
+		printf("echo $! > %s.pid", outPath)
+		printf("wait")
+	}
 	printf("exit %d", spec.code)
 	return charmDir, outPath
 }
@@ -285,6 +299,59 @@ func (s *RunHookSuite) TestRunHook(c *gc.C) {
 	}
 }
 
+func (s *RunHookSuite) TestRunHookOutput(c *gc.C) {
+	uuid, err := utils.NewUUID()
+	c.Assert(err, gc.IsNil)
+	ctx := s.getHookContext(c, uuid.String(), -1, "", noProxies)
+	charmDir, _ := makeCharm(c, hookSpec{
+		name:   "something-happened",
+		perm:   0700,
+		code:   3,
+		stdout: "to stdout",
+		stderr: "to stderr",
+	})
+	err = ctx.RunHook("something-happened", charmDir, c.MkDir(), "/path/to/socket")
+	c.Assert(err, gc.ErrorMatches, "exit status 3")
+	c.Assert(uniter.HookExitCode(err), gc.Equals, 3)
+	c.Assert(ctx.HookOutput(), jc.DeepEquals, []string{"to stdout", "to stderr"})
+
+	c.Assert(uniter.HookExitCode(nil), gc.Equals, 0)
+	c.Assert(uniter.HookExitCode(fmt.Errorf("cannot start hook")), gc.Equals, -1)
+}
+
+func (s *RunHookSuite) TestRunHookTimeout(c *gc.C) {
+	uuid, err := utils.NewUUID()
+	c.Assert(err, gc.IsNil)
+	ctx := s.getHookContext(c, uuid.String(), -1, "", noProxies)
+	uniter.SetHookTimeout(ctx, 500*time.Millisecond)
+	charmDir, outPath := makeCharm(c, hookSpec{
+		name:   "something-happened",
+		perm:   0700,
+		stdout: "hanging",
+		hang:   true,
+	})
+	t0 := time.Now()
+	err = ctx.RunHook("something-happened", charmDir, c.MkDir(), "/path/to/socket")
+	c.Assert(err, gc.ErrorMatches, "something-happened timed out after 500ms")
+	c.Assert(uniter.IsHookTimeoutError(err), jc.IsTrue)
+	c.Assert(uniter.HookExitCode(err), gc.Equals, -1)
+	c.Assert(time.Since(t0) < coretesting.LongWait, jc.IsTrue)
+	c.Assert(ctx.HookOutput(), jc.DeepEquals, []string{"hanging"})
+
+	// The process started by the hook has been killed too.
+	data, err := ioutil.ReadFile(outPath + ".pid")
+	c.Assert(err, gc.IsNil)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	c.Assert(err, gc.IsNil)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		p, err := os.FindProcess(pid)
+		if err != nil || p.Signal(syscall.Signal(0)) != nil {
+			return
+		}
+	}
+	c.Fatalf("process %d started by the hook is still running", pid)
+}
+
 // split the line into buffer-sized lengths.
 func splitLine(s string) []string {
 	var ss []string
@@ -582,6 +649,36 @@ func (s *ContextRelationSuite) TestSettings(c *gc.C) {
 	c.Assert(settings, gc.DeepEquals, expectMap)
 }
 
+func (s *ContextRelationSuite) TestLocalSettings(c *gc.C) {
+	ctx := uniter.NewContextRelation(s.apiRelUnit, nil)
+	expectSettings, err := s.ru.ReadSettings("u/0")
+	c.Assert(err, gc.IsNil)
+	settings, err := ctx.LocalSettings()
+	c.Assert(err, gc.IsNil)
+	c.Assert(convertSettings(settings), gc.DeepEquals, expectSettings)
+
+	// The settings read are not written back, so they do not
+	// overwrite changes made elsewhere.
+	node, err := s.ru.Settings()
+	c.Assert(err, gc.IsNil)
+	node.Set("change", "elsewhere")
+	_, err = node.Write()
+	c.Assert(err, gc.IsNil)
+	err = ctx.WriteSettings()
+	c.Assert(err, gc.IsNil)
+	stored, err := s.ru.ReadSettings("u/0")
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored["change"], gc.Equals, "elsewhere")
+
+	// Unwritten changes made through the context are included.
+	ctxNode, err := ctx.Settings()
+	c.Assert(err, gc.IsNil)
+	ctxNode.Set("change", "exciting")
+	settings, err = ctx.LocalSettings()
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings["change"], gc.Equals, "exciting")
+}
+
 type InterfaceSuite struct {
 	HookContextSuite
 }
@@ -650,6 +747,30 @@ func (s *InterfaceSuite) TestUnitCaching(c *gc.C) {
 	c.Assert(pr, gc.Equals, pa)
 }
 
+func (s *InterfaceSuite) TestEndpointAddresses(c *gc.C) {
+	err := s.machine.SetAddresses(
+		network.NewAddress("u-0.testing.invalid", network.ScopeCloudLocal),
+		network.NewAddress("10.0.1.5", network.ScopeCloudLocal),
+	)
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24", ProviderId: "subnet-1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.State.AddSpace("internal", []string{"10.0.1.0/24"})
+	c.Assert(err, gc.IsNil)
+	err = s.service.SetEndpointBindings(map[string]string{"db": "internal"})
+	c.Assert(err, gc.IsNil)
+
+	ctx := s.GetContext(c, -1, "")
+	addresses := ctx.EndpointAddresses()
+	c.Assert(addresses["db"], gc.Equals, "10.0.1.5")
+	c.Assert(addresses["url"], gc.Equals, "u-0.testing.invalid")
+
+	// The addresses are cached, like the unit's other addresses.
+	err = s.service.SetEndpointBindings(nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(ctx.EndpointAddresses()["db"], gc.Equals, "10.0.1.5")
+}
+
 func (s *InterfaceSuite) TestConfigCaching(c *gc.C) {
 	ctx := s.GetContext(c, -1, "")
 	settings, err := ctx.ConfigSettings()
@@ -787,7 +908,7 @@ func (s *RunCommandSuite) getHookContext(c *gc.C) *uniter.HookContext {
 func (s *RunCommandSuite) TestRunCommandsHasEnvironSet(c *gc.C) {
 	context := s.getHookContext(c)
 	charmDir := c.MkDir()
-	result, err := context.RunCommands("env | sort", charmDir, "/path/to/tools", "/path/to/socket")
+	result, err := context.RunCommands("env | sort", charmDir, "/path/to/tools", "/path/to/socket", nil, nil, nil)
 	c.Assert(err, gc.IsNil)
 
 	executionEnvironment := map[string]string{}
@@ -819,10 +940,74 @@ echo this is standard out
 echo this is standard err >&2
 exit 42
 `
-	result, err := context.RunCommands(commands, charmDir, "/path/to/tools", "/path/to/socket")
+	result, err := context.RunCommands(commands, charmDir, "/path/to/tools", "/path/to/socket", nil, nil, nil)
 	c.Assert(err, gc.IsNil)
 
 	c.Assert(result.Code, gc.Equals, 42)
 	c.Assert(string(result.Stdout), gc.Equals, "this is standard out\n")
 	c.Assert(string(result.Stderr), gc.Equals, "this is standard err\n")
 }
+
+func (s *RunCommandSuite) TestRunCommandsStreamsOutput(c *gc.C) {
+	context := s.getHookContext(c)
+	charmDir := c.MkDir()
+	commands := `
+echo this is standard out
+echo this is standard err >&2
+`
+	var stdout, stderr bytes.Buffer
+	result, err := context.RunCommands(commands, charmDir, "/path/to/tools", "/path/to/socket", &stdout, &stderr, nil)
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(result.Code, gc.Equals, 0)
+	c.Assert(stdout.String(), gc.Equals, "this is standard out\n")
+	c.Assert(stderr.String(), gc.Equals, "this is standard err\n")
+	c.Assert(result.Stdout, gc.DeepEquals, stdout.Bytes())
+	c.Assert(result.Stderr, gc.DeepEquals, stderr.Bytes())
+}
+
+func (s *RunCommandSuite) TestRunCommandsAborted(c *gc.C) {
+	context := s.getHookContext(c)
+	charmDir := c.MkDir()
+	commands := `
+echo started
+sleep 3600
+`
+	var stdout bytes.Buffer
+	writer := &notifyWriter{Writer: &stdout, written: make(chan struct{}, 1)}
+	abort := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, err := context.RunCommands(commands, charmDir, "/path/to/tools", "/path/to/socket", writer, nil, abort)
+		c.Check(err, gc.ErrorMatches, "commands aborted")
+		c.Check(result.Code, gc.Equals, -1)
+		c.Check(string(result.Stdout), gc.Equals, "started\n")
+	}()
+	select {
+	case <-writer.written:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("commands not started")
+	}
+	close(abort)
+	select {
+	case <-done:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("aborted commands not killed")
+	}
+}
+
+// notifyWriter sends on written whenever it is written to.
+type notifyWriter struct {
+	io.Writer
+	written chan struct{}
+}
+
+func (w *notifyWriter) Write(data []byte) (int, error) {
+	n, err := w.Writer.Write(data)
+	select {
+	case w.written <- struct{}{}:
+	default:
+	}
+	return n, err
+}
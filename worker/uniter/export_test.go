@@ -4,6 +4,8 @@
 package uniter
 
 import (
+	"time"
+
 	"github.com/juju/utils/proxy"
 )
 
@@ -18,3 +20,18 @@ func (u *Uniter) GetProxyValues() proxy.Settings {
 }
 
 var MergeEnvironment = mergeEnvironment
+
+var HookExitCode = hookExitCode
+
+var (
+	HookRetryAfter = &hookRetryAfter
+	HookRetryDelay = hookRetryDelay
+)
+
+var CharmHookTimeout = charmHookTimeout
+
+var RequestRollback = &requestRollback
+
+func SetHookTimeout(ctx *HookContext, timeout time.Duration) {
+	ctx.hookTimeout = timeout
+}
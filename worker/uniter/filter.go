@@ -88,6 +88,8 @@ type filter struct {
 	service          *uniter.Service
 	upgradeFrom      serviceCharm
 	upgradeAvailable serviceCharm
+	upgradeHeld      bool
+	upgradeHeldFor   *charm.URL
 	upgrade          *charm.URL
 	relations        []int
 	actionsPending   []string
@@ -544,6 +546,11 @@ func (f *filter) serviceChanged() error {
 		return err
 	}
 	f.upgradeAvailable = serviceCharm{url, force}
+	if f.upgradeHeld {
+		// The unit may have been released; check again when the
+		// upgrade is next considered.
+		f.upgradeHeldFor = nil
+	}
 	switch f.service.Life() {
 	case params.Dying:
 		if err := f.unit.Destroy(); err != nil {
@@ -571,6 +578,13 @@ func (f *filter) upgradeChanged() (err error) {
 		return nil
 	}
 	if *f.upgradeAvailable.url != *f.upgradeFrom.url {
+		if held, err := f.charmUpgradeHeld(); err != nil {
+			return err
+		} else if held {
+			filterLogger.Debugf("charm check skipped, upgrade held")
+			f.outUpgrade = nil
+			return nil
+		}
 		if f.upgradeAvailable.force || !f.upgradeFrom.force {
 			filterLogger.Debugf("preparing new upgrade event")
 			if f.upgrade == nil || *f.upgrade != *f.upgradeAvailable.url {
@@ -585,6 +599,23 @@ func (f *filter) upgradeChanged() (err error) {
 	return nil
 }
 
+// charmUpgradeHeld returns whether the upgrade of the unit to the
+// service's charm is being held back. The answer is only fetched when
+// the service's charm has changed, or when the unit was held and may
+// since have been released.
+func (f *filter) charmUpgradeHeld() (bool, error) {
+	url := f.upgradeAvailable.url
+	if f.upgradeHeldFor != nil && *f.upgradeHeldFor == *url {
+		return f.upgradeHeld, nil
+	}
+	held, err := f.unit.CharmUpgradeHeld()
+	if err != nil {
+		return false, err
+	}
+	f.upgradeHeld, f.upgradeHeldFor = held, url
+	return held, nil
+}
+
 // relationsChanged responds to service relation changes.
 func (f *filter) relationsChanged(ids []int) {
 outer:
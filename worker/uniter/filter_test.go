@@ -289,6 +289,45 @@ func (s *FilterSuite) TestCharmUpgradeEvents(c *gc.C) {
 	assertNoChange()
 }
 
+func (s *FilterSuite) TestCharmUpgradeHeld(c *gc.C) {
+	oldCharm := s.AddTestingCharm(c, "upgrade1")
+	svc := s.AddTestingService(c, "upgradetest", oldCharm)
+	unit, err := svc.AddUnit()
+	c.Assert(err, gc.IsNil)
+	err = unit.AssignToNewMachine()
+	c.Assert(err, gc.IsNil)
+
+	s.APILogin(c, unit)
+
+	f, err := newFilter(s.uniter, unit.Tag().String())
+	c.Assert(err, gc.IsNil)
+	defer statetesting.AssertStop(c, f)
+	err = f.SetCharm(oldCharm.URL())
+	c.Assert(err, gc.IsNil)
+
+	// Change the service's charm while holding the unit; no event.
+	newCharm := s.AddTestingCharm(c, "upgrade2")
+	err = svc.SetCharmHoldingUnits(newCharm, false, []string{unit.Name()})
+	c.Assert(err, gc.IsNil)
+	s.BackingState.StartSync()
+	select {
+	case sch := <-f.UpgradeEvents():
+		c.Fatalf("unexpected %#v", sch)
+	case <-time.After(coretesting.ShortWait):
+	}
+
+	// Release the unit; the upgrade event is now delivered.
+	err = svc.ReleaseCharmUpgrades(unit.Name())
+	c.Assert(err, gc.IsNil)
+	s.BackingState.StartSync()
+	select {
+	case upgradeCharm := <-f.UpgradeEvents():
+		c.Assert(upgradeCharm, gc.DeepEquals, newCharm.URL())
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out")
+	}
+}
+
 func (s *FilterSuite) TestConfigEvents(c *gc.C) {
 	f, err := newFilter(s.uniter, s.unit.Tag().String())
 	c.Assert(err, gc.IsNil)
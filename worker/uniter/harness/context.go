@@ -0,0 +1,356 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package harness
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/juju/charm"
+	"github.com/juju/utils/set"
+
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/worker/uniter/jujuc"
+)
+
+// FakeContext is an in-memory implementation of jujuc.Context. Tests
+// set up the state a charm should see, run hooks against it, and then
+// inspect the changes the hooks made.
+type FakeContext struct {
+	mu             sync.Mutex
+	unitName       string
+	publicAddress  string
+	privateAddress string
+	endpoints      map[string]string
+	ownerTag       string
+	config         charm.Settings
+	actionParams   map[string]interface{}
+	ports          set.Strings
+	relations      map[int]*FakeRelation
+	nextRelationId int
+
+	// relationId and remoteUnitName identify the relation and remote
+	// unit of the relation hook being run, if any.
+	relationId     int
+	remoteUnitName string
+}
+
+var _ jujuc.Context = (*FakeContext)(nil)
+
+// NewFakeContext returns a context for the named unit, with no
+// relations and no opened ports.
+func NewFakeContext(unitName string) *FakeContext {
+	return &FakeContext{
+		unitName:       unitName,
+		privateAddress: "10.0.0.1",
+		endpoints:      make(map[string]string),
+		ownerTag:       "user-admin",
+		config:         charm.Settings{},
+		ports:          set.NewStrings(),
+		relations:      make(map[int]*FakeRelation),
+		relationId:     -1,
+	}
+}
+
+// SetAddresses sets the public and private addresses of the unit.
+func (ctx *FakeContext) SetAddresses(public, private string) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.publicAddress = public
+	ctx.privateAddress = private
+}
+
+// SetEndpointAddress sets the address of the unit to be used by the
+// named endpoint, as if the endpoint were bound to a space. Endpoints
+// without an address are not known to network-get.
+func (ctx *FakeContext) SetEndpointAddress(endpoint, addr string) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.endpoints[endpoint] = addr
+}
+
+// SetOwnerTag sets the tag of the owner of the unit's service.
+func (ctx *FakeContext) SetOwnerTag(tag string) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.ownerTag = tag
+}
+
+// UpdateConfig changes the service's config settings; settings with
+// nil values are removed.
+func (ctx *FakeContext) UpdateConfig(settings charm.Settings) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for name, value := range settings {
+		if value == nil {
+			delete(ctx.config, name)
+		} else {
+			ctx.config[name] = value
+		}
+	}
+}
+
+// AddRelation adds a relation with the given endpoint name to the
+// context and returns it.
+func (ctx *FakeContext) AddRelation(name string) *FakeRelation {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	r := &FakeRelation{
+		id:       ctx.nextRelationId,
+		name:     name,
+		settings: make(fakeSettings),
+		units:    make(map[string]params.RelationSettings),
+		ctx:      ctx,
+	}
+	ctx.relations[r.id] = r
+	ctx.nextRelationId++
+	return r
+}
+
+// RemoveRelation removes the relation with the given id.
+func (ctx *FakeContext) RemoveRelation(id int) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	delete(ctx.relations, id)
+}
+
+// FindRelation returns the first relation with the given endpoint
+// name, if there is one.
+func (ctx *FakeContext) FindRelation(name string) (*FakeRelation, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	var found *FakeRelation
+	for _, r := range ctx.relations {
+		if r.name == name && (found == nil || r.id < found.id) {
+			found = r
+		}
+	}
+	return found, found != nil
+}
+
+// OpenedPorts returns the ports opened by the unit, in the form
+// "80/tcp", sorted.
+func (ctx *FakeContext) OpenedPorts() []string {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.ports.SortedValues()
+}
+
+// setHook prepares the context for running a hook for the given
+// relation and remote unit, and with the given action parameters.
+func (ctx *FakeContext) setHook(relationId int, remoteUnitName string, actionParams map[string]interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.relationId = relationId
+	ctx.remoteUnitName = remoteUnitName
+	ctx.actionParams = actionParams
+}
+
+func (ctx *FakeContext) UnitName() string {
+	return ctx.unitName
+}
+
+func (ctx *FakeContext) PublicAddress() (string, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.publicAddress, ctx.publicAddress != ""
+}
+
+func (ctx *FakeContext) PrivateAddress() (string, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.privateAddress, ctx.privateAddress != ""
+}
+
+func (ctx *FakeContext) EndpointAddresses() map[string]string {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	addresses := make(map[string]string)
+	for endpoint, addr := range ctx.endpoints {
+		addresses[endpoint] = addr
+	}
+	return addresses
+}
+
+func (ctx *FakeContext) OpenPort(protocol string, port int) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.ports.Add(fmt.Sprintf("%d/%s", port, protocol))
+	return nil
+}
+
+func (ctx *FakeContext) ClosePort(protocol string, port int) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.ports.Remove(fmt.Sprintf("%d/%s", port, protocol))
+	return nil
+}
+
+func (ctx *FakeContext) ConfigSettings() (charm.Settings, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	result := charm.Settings{}
+	for name, value := range ctx.config {
+		result[name] = value
+	}
+	return result, nil
+}
+
+func (ctx *FakeContext) ActionParams() map[string]interface{} {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.actionParams
+}
+
+func (ctx *FakeContext) HookRelation() (jujuc.ContextRelation, bool) {
+	ctx.mu.Lock()
+	id := ctx.relationId
+	ctx.mu.Unlock()
+	return ctx.Relation(id)
+}
+
+func (ctx *FakeContext) RemoteUnitName() (string, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.remoteUnitName, ctx.remoteUnitName != ""
+}
+
+func (ctx *FakeContext) Relation(id int) (jujuc.ContextRelation, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	r, found := ctx.relations[id]
+	if !found {
+		return nil, false
+	}
+	return r, true
+}
+
+func (ctx *FakeContext) RelationIds() []int {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ids := []int{}
+	for id := range ctx.relations {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (ctx *FakeContext) OwnerTag() string {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.ownerTag
+}
+
+// FakeRelation is an in-memory implementation of jujuc.ContextRelation.
+type FakeRelation struct {
+	id       int
+	name     string
+	settings fakeSettings
+	units    map[string]params.RelationSettings
+	ctx      *FakeContext
+}
+
+var _ jujuc.ContextRelation = (*FakeRelation)(nil)
+
+// SetUnit adds the named remote unit to the relation with the given
+// settings, or replaces the settings of a unit already in it.
+func (r *FakeRelation) SetUnit(unitName string, settings params.RelationSettings) {
+	r.ctx.mu.Lock()
+	defer r.ctx.mu.Unlock()
+	r.units[unitName] = copySettings(settings)
+}
+
+// RemoveUnit removes the named remote unit from the relation.
+func (r *FakeRelation) RemoveUnit(unitName string) {
+	r.ctx.mu.Lock()
+	defer r.ctx.mu.Unlock()
+	delete(r.units, unitName)
+}
+
+// LocalSettings returns the local unit's settings in the relation, as
+// written by its hooks.
+func (r *FakeRelation) LocalSettings() params.RelationSettings {
+	r.ctx.mu.Lock()
+	defer r.ctx.mu.Unlock()
+	return copySettings(params.RelationSettings(r.settings))
+}
+
+func (r *FakeRelation) Id() int {
+	return r.id
+}
+
+func (r *FakeRelation) Name() string {
+	return r.name
+}
+
+func (r *FakeRelation) FakeId() string {
+	return fmt.Sprintf("%s:%d", r.name, r.id)
+}
+
+func (r *FakeRelation) Settings() (jujuc.Settings, error) {
+	return &lockedSettings{r.settings, &r.ctx.mu}, nil
+}
+
+func (r *FakeRelation) LocalSettings() (params.RelationSettings, error) {
+	r.ctx.mu.Lock()
+	defer r.ctx.mu.Unlock()
+	return copySettings(params.RelationSettings(r.settings)), nil
+}
+
+func (r *FakeRelation) UnitNames() []string {
+	r.ctx.mu.Lock()
+	defer r.ctx.mu.Unlock()
+	var unitNames []string
+	for unitName := range r.units {
+		unitNames = append(unitNames, unitName)
+	}
+	sort.Strings(unitNames)
+	return unitNames
+}
+
+func (r *FakeRelation) ReadSettings(unitName string) (params.RelationSettings, error) {
+	r.ctx.mu.Lock()
+	defer r.ctx.mu.Unlock()
+	settings, found := r.units[unitName]
+	if !found {
+		return nil, fmt.Errorf("unit %q is not in relation %s", unitName, r.FakeId())
+	}
+	return copySettings(settings), nil
+}
+
+func copySettings(settings params.RelationSettings) params.RelationSettings {
+	result := make(params.RelationSettings)
+	for k, v := range settings {
+		result[k] = v
+	}
+	return result
+}
+
+type fakeSettings params.RelationSettings
+
+// lockedSettings implements jujuc.Settings for a relation's local
+// settings, guarded by the context's mutex.
+type lockedSettings struct {
+	settings fakeSettings
+	mu       *sync.Mutex
+}
+
+func (s *lockedSettings) Map() params.RelationSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copySettings(params.RelationSettings(s.settings))
+}
+
+func (s *lockedSettings) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[key] = value
+}
+
+func (s *lockedSettings) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.settings, key)
+}
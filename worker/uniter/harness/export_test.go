@@ -0,0 +1,10 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package harness
+
+// ToolSocketPath returns the path of the socket the harness serves
+// hook tools on.
+func ToolSocketPath(h *Harness) string {
+	return h.tools.SocketPath()
+}
@@ -0,0 +1,290 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// The harness package runs the hooks of a charm outside a unit agent,
+// against an in-memory hook context, so that charms can be tested
+// quickly on a local machine. A test deploys the charm with New, sets
+// up the context the charm should see, runs a sequence of hooks, and
+// then inspects the ports, relation settings, status and logs that
+// resulted.
+//
+// The hook tools are provided by jujud, as they are for a unit agent.
+package harness
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/charm"
+	"github.com/juju/loggo"
+
+	"github.com/juju/juju/state/api/params"
+)
+
+// HookResult holds the outcome of running a hook.
+type HookResult struct {
+	// Hook holds the name of the hook or action.
+	Hook string
+
+	// Skipped is true if the charm does not implement the hook.
+	Skipped bool
+
+	// Code holds the hook's exit code, or -1 if it did not exit
+	// normally.
+	Code int
+
+	Stdout []byte
+	Stderr []byte
+}
+
+// LogEntry is a message logged by a hook with juju-log.
+type LogEntry struct {
+	Level   loggo.Level
+	Message string
+}
+
+// Harness holds a charm deployed into a temporary directory, and runs
+// its hooks against a FakeContext.
+type Harness struct {
+	// Context holds the context the hooks run in.
+	Context *FakeContext
+
+	dir      string
+	charmDir string
+	tools    *ToolServer
+	logs     *logCollector
+
+	status     params.Status
+	failedHook string
+}
+
+// New deploys the charm in the directory at charmPath and prepares to
+// run its hooks. The context starts with the charm's default config
+// settings and no relations. The hook tools are provided by the jujud
+// executable at jujudPath.
+func New(charmPath, jujudPath string) (_ *Harness, err error) {
+	ch, err := charm.ReadDir(charmPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read charm: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "juju-harness")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	charmDir := filepath.Join(dir, "charm")
+	if err := deployCharm(ch, dir, charmDir); err != nil {
+		return nil, fmt.Errorf("cannot deploy charm: %v", err)
+	}
+	unitName := ch.Meta().Name + "/0"
+	ctx := NewFakeContext(unitName)
+	ctx.UpdateConfig(ch.Config().DefaultSettings())
+	tools, err := NewToolServer(ctx, jujudPath)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := newLogCollector(unitName, "harness-"+dir)
+	if err != nil {
+		tools.Close()
+		return nil, err
+	}
+	return &Harness{
+		Context:  ctx,
+		dir:      dir,
+		charmDir: charmDir,
+		tools:    tools,
+		logs:     logs,
+		status:   params.StatusPending,
+	}, nil
+}
+
+// deployCharm bundles the charm and expands the bundle to charmDir,
+// as a unit agent would.
+func deployCharm(ch *charm.Dir, dir, charmDir string) error {
+	bundlePath := filepath.Join(dir, "charm.bundle")
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		return err
+	}
+	err = ch.BundleTo(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	bundle, err := charm.ReadBundle(bundlePath)
+	if err != nil {
+		return err
+	}
+	return bundle.ExpandTo(charmDir)
+}
+
+// CharmDir returns the directory the charm is deployed to.
+func (h *Harness) CharmDir() string {
+	return h.charmDir
+}
+
+// RunHook runs the named unit hook.
+func (h *Harness) RunHook(hookName string) (*HookResult, error) {
+	return h.run("hooks", hookName, -1, "", nil)
+}
+
+// RunRelationHook runs the named hook of the relation with the given
+// id, with the given remote unit. The remote unit should be empty for
+// relation-broken hooks.
+func (h *Harness) RunRelationHook(hookName string, relationId int, remoteUnitName string) (*HookResult, error) {
+	if _, found := h.Context.Relation(relationId); !found {
+		return nil, fmt.Errorf("relation %d not found", relationId)
+	}
+	return h.run("hooks", hookName, relationId, remoteUnitName, nil)
+}
+
+// RunAction runs the named action with the given parameters.
+func (h *Harness) RunAction(actionName string, actionParams map[string]interface{}) (*HookResult, error) {
+	return h.run("actions", actionName, -1, "", actionParams)
+}
+
+func (h *Harness) run(location, hookName string, relationId int, remoteUnitName string, actionParams map[string]interface{}) (*HookResult, error) {
+	result := &HookResult{Hook: hookName}
+	hookPath := filepath.Join(h.charmDir, location, hookName)
+	if _, err := os.Stat(hookPath); os.IsNotExist(err) {
+		result.Skipped = true
+		h.hookRan(result)
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	h.Context.setHook(relationId, remoteUnitName, actionParams)
+	defer h.Context.setHook(-1, "", nil)
+
+	var stdout, stderr bytes.Buffer
+	ps := exec.Command(hookPath)
+	ps.Env = MergeEnvironment(os.Environ(), h.hookVars())
+	ps.Dir = h.charmDir
+	ps.Stdout = &stdout
+	ps.Stderr = &stderr
+	err := ps.Run()
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.Code = -1
+		if status, ok := exitErr.Sys().(interface {
+			ExitStatus() int
+		}); ok {
+			result.Code = status.ExitStatus()
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	h.hookRan(result)
+	return result, nil
+}
+
+// hookVars returns the environment variables a unit agent would set
+// for the hook about to run.
+func (h *Harness) hookVars() map[string]string {
+	vars := h.tools.Environment()
+	vars["CHARM_DIR"] = h.charmDir
+	vars["JUJU_UNIT_NAME"] = h.Context.UnitName()
+	vars["JUJU_ENV_NAME"] = "harness"
+	vars["DEBIAN_FRONTEND"] = "noninteractive"
+	vars["APT_LISTCHANGES_FRONTEND"] = "none"
+	if r, found := h.Context.HookRelation(); found {
+		vars["JUJU_RELATION"] = r.Name()
+		vars["JUJU_RELATION_ID"] = r.FakeId()
+		vars["JUJU_REMOTE_UNIT"], _ = h.Context.RemoteUnitName()
+	}
+	return vars
+}
+
+// hookRan updates the unit's status after a hook has run. As with a
+// unit agent, a failed hook puts the unit into an error state, which
+// is cleared when a hook next succeeds.
+func (h *Harness) hookRan(result *HookResult) {
+	if result.Code != 0 {
+		h.failedHook = result.Hook
+		return
+	}
+	h.failedHook = ""
+	switch result.Hook {
+	case "install":
+		h.status = params.StatusInstalled
+	case "start":
+		h.status = params.StatusStarted
+	case "stop":
+		h.status = params.StatusStopped
+	}
+}
+
+// Status returns the status the unit would have, and any additional
+// information about it.
+func (h *Harness) Status() (params.Status, string) {
+	if h.failedHook != "" {
+		return params.StatusError, fmt.Sprintf("hook failed: %q", h.failedHook)
+	}
+	return h.status, ""
+}
+
+// Logs returns the messages logged by hooks so far.
+func (h *Harness) Logs() []LogEntry {
+	return h.logs.entries()
+}
+
+// Close stops serving the hook tools and removes the deployed charm.
+func (h *Harness) Close() error {
+	h.logs.close()
+	if err := h.tools.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(h.dir)
+}
+
+// logCollector collects the messages logged by a unit's hooks.
+type logCollector struct {
+	name   string
+	prefix string
+	mu     sync.Mutex
+	logs   []LogEntry
+}
+
+func newLogCollector(unitName, writerName string) (*logCollector, error) {
+	module := "unit." + unitName
+	w := &logCollector{
+		name:   writerName,
+		prefix: module + ".",
+	}
+	if err := loggo.RegisterWriter(writerName, w, loggo.TRACE); err != nil {
+		return nil, err
+	}
+	loggo.GetLogger(module).SetLogLevel(loggo.DEBUG)
+	return w, nil
+}
+
+func (w *logCollector) Write(level loggo.Level, module, filename string, line int, timestamp time.Time, message string) {
+	if !strings.HasPrefix(module, w.prefix) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.logs = append(w.logs, LogEntry{level, message})
+}
+
+func (w *logCollector) entries() []LogEntry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]LogEntry(nil), w.logs...)
+}
+
+func (w *logCollector) close() {
+	loggo.RemoveWriter(w.name)
+}
@@ -0,0 +1,267 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package harness_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/charm"
+	"github.com/juju/loggo"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils/exec"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/juju/sockets"
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/uniter/harness"
+	"github.com/juju/juju/worker/uniter/jujuc"
+)
+
+type HarnessSuite struct {
+	testing.BaseSuite
+	charmPath string
+	jujud     string
+}
+
+var _ = gc.Suite(&HarnessSuite{})
+
+const harnessMetadata = `
+name: mysql
+summary: "Database engine"
+description: "A pretty popular database"
+provides:
+  server: mysql
+`
+
+const harnessConfig = `
+options:
+  title:
+    type: string
+    default: My Title
+    description: A title.
+  password:
+    type: string
+    description: A password without a default.
+`
+
+func (s *HarnessSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	dir := c.MkDir()
+	s.charmPath = filepath.Join(dir, "mysql")
+	s.writeFile(c, "metadata.yaml", harnessMetadata, 0644)
+	s.writeFile(c, "config.yaml", harnessConfig, 0644)
+	s.writeFile(c, "revision", "1\n", 0644)
+
+	// The hooks run below do not call any hook tools, so jujud need
+	// only exist; the tools are exercised by calling the tool server
+	// directly, as jujud would.
+	s.jujud = filepath.Join(dir, "jujud")
+	err := ioutil.WriteFile(s.jujud, []byte("#!/bin/sh\nexit 1\n"), 0755)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *HarnessSuite) writeFile(c *gc.C, name, data string, mode os.FileMode) {
+	path := filepath.Join(s.charmPath, name)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	c.Assert(err, gc.IsNil)
+	err = ioutil.WriteFile(path, []byte(data), mode)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *HarnessSuite) writeHook(c *gc.C, name, script string) {
+	s.writeFile(c, filepath.Join("hooks", name), "#!/bin/sh\n"+script, 0755)
+}
+
+func (s *HarnessSuite) newHarness(c *gc.C) *harness.Harness {
+	h, err := harness.New(s.charmPath, s.jujud)
+	c.Assert(err, gc.IsNil)
+	s.AddCleanup(func(c *gc.C) {
+		c.Check(h.Close(), gc.IsNil)
+	})
+	return h
+}
+
+// runTool runs a hook tool against the harness's context, as jujud
+// would when called by a hook.
+func runTool(c *gc.C, h *harness.Harness, contextId, name string, args ...string) (*exec.ExecResponse, error) {
+	client, err := sockets.Dial(harness.ToolSocketPath(h))
+	c.Assert(err, gc.IsNil)
+	defer client.Close()
+	req := jujuc.Request{
+		ContextId:   contextId,
+		Dir:         h.CharmDir(),
+		CommandName: name,
+		Args:        args,
+	}
+	var resp exec.ExecResponse
+	err = client.Call("Jujuc.Main", req, &resp)
+	return &resp, err
+}
+
+func (s *HarnessSuite) TestNew(c *gc.C) {
+	s.writeHook(c, "install", "exit 0\n")
+	h := s.newHarness(c)
+	c.Assert(h.Context.UnitName(), gc.Equals, "mysql/0")
+	settings, err := h.Context.ConfigSettings()
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"title": "My Title"})
+
+	fi, err := os.Stat(filepath.Join(h.CharmDir(), "hooks", "install"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(fi.Mode()&0100, gc.Not(gc.Equals), os.FileMode(0))
+	status, info := h.Status()
+	c.Assert(status, gc.Equals, params.StatusPending)
+	c.Assert(info, gc.Equals, "")
+}
+
+func (s *HarnessSuite) TestNewBadCharm(c *gc.C) {
+	_, err := harness.New(c.MkDir(), s.jujud)
+	c.Assert(err, gc.ErrorMatches, "cannot read charm: .*")
+}
+
+func (s *HarnessSuite) TestRunHooks(c *gc.C) {
+	s.writeHook(c, "install", `
+echo $JUJU_UNIT_NAME $JUJU_CONTEXT_ID
+[ "$CHARM_DIR" = "$(pwd)" ] && echo in charm dir
+[ -S "$JUJU_AGENT_SOCKET" ] && echo socket exists
+readlink $(command -v config-get)
+echo oops >&2
+`)
+	s.writeHook(c, "start", "exit 0\n")
+	h := s.newHarness(c)
+
+	result, err := h.RunHook("install")
+	c.Assert(err, gc.IsNil)
+	c.Assert(result.Hook, gc.Equals, "install")
+	c.Assert(result.Skipped, jc.IsFalse)
+	c.Assert(result.Code, gc.Equals, 0)
+	c.Assert(string(result.Stdout), gc.Equals, ""+
+		"mysql/0 harness\n"+
+		"in charm dir\n"+
+		"socket exists\n"+
+		s.jujud+"\n")
+	c.Assert(string(result.Stderr), gc.Equals, "oops\n")
+	status, _ := h.Status()
+	c.Assert(status, gc.Equals, params.StatusInstalled)
+
+	result, err = h.RunHook("config-changed")
+	c.Assert(err, gc.IsNil)
+	c.Assert(result.Skipped, jc.IsTrue)
+
+	_, err = h.RunHook("start")
+	c.Assert(err, gc.IsNil)
+	status, _ = h.Status()
+	c.Assert(status, gc.Equals, params.StatusStarted)
+}
+
+func (s *HarnessSuite) TestRunHookFails(c *gc.C) {
+	s.writeHook(c, "start", "exit 2\n")
+	s.writeHook(c, "config-changed", "exit 0\n")
+	h := s.newHarness(c)
+
+	result, err := h.RunHook("start")
+	c.Assert(err, gc.IsNil)
+	c.Assert(result.Code, gc.Equals, 2)
+	status, info := h.Status()
+	c.Assert(status, gc.Equals, params.StatusError)
+	c.Assert(info, gc.Equals, `hook failed: "start"`)
+
+	_, err = h.RunHook("config-changed")
+	c.Assert(err, gc.IsNil)
+	status, info = h.Status()
+	c.Assert(status, gc.Equals, params.StatusPending)
+	c.Assert(info, gc.Equals, "")
+}
+
+func (s *HarnessSuite) TestRunRelationHook(c *gc.C) {
+	s.writeHook(c, "server-relation-joined", "echo $JUJU_RELATION $JUJU_RELATION_ID $JUJU_REMOTE_UNIT\n")
+	h := s.newHarness(c)
+	_, err := h.RunRelationHook("server-relation-joined", 0, "wordpress/0")
+	c.Assert(err, gc.ErrorMatches, "relation 0 not found")
+
+	r := h.Context.AddRelation("server")
+	r.SetUnit("wordpress/0", params.RelationSettings{"private-address": "10.0.0.2"})
+	result, err := h.RunRelationHook("server-relation-joined", r.Id(), "wordpress/0")
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(result.Stdout), gc.Equals, "server server:0 wordpress/0\n")
+
+	// The hook relation is only set while the hook runs.
+	_, found := h.Context.HookRelation()
+	c.Assert(found, jc.IsFalse)
+}
+
+func (s *HarnessSuite) TestRunAction(c *gc.C) {
+	s.writeFile(c, filepath.Join("actions", "snapshot"), "#!/bin/sh\necho snapped\n", 0755)
+	h := s.newHarness(c)
+	result, err := h.RunAction("snapshot", map[string]interface{}{"outfile": "/tmp/out"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(result.Stdout), gc.Equals, "snapped\n")
+	c.Assert(h.Context.ActionParams(), gc.IsNil)
+}
+
+func (s *HarnessSuite) TestTools(c *gc.C) {
+	h := s.newHarness(c)
+	r := h.Context.AddRelation("server")
+
+	resp, err := runTool(c, h, "harness", "open-port", "80")
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.Code, gc.Equals, 0)
+	c.Assert(h.Context.OpenedPorts(), jc.DeepEquals, []string{"80/tcp"})
+
+	resp, err = runTool(c, h, "harness", "relation-set", "-r", "server:0", "user=admin")
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.Code, gc.Equals, 0)
+	c.Assert(r.LocalSettings(), jc.DeepEquals, params.RelationSettings{"user": "admin"})
+
+	resp, err = runTool(c, h, "harness", "config-get", "title")
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(resp.Stdout), gc.Equals, "My Title\n")
+
+	_, err = runTool(c, h, "other", "config-get", "title")
+	c.Assert(err, gc.ErrorMatches, `bad request: expected context id "harness", got "other"`)
+}
+
+func (s *HarnessSuite) TestToolMain(c *gc.C) {
+	s.writeHook(c, "install", "open-port 80 && config-get title\n")
+	jujud, err := filepath.Abs(os.Args[0])
+	c.Assert(err, gc.IsNil)
+	h, err := harness.New(s.charmPath, jujud)
+	c.Assert(err, gc.IsNil)
+	defer h.Close()
+
+	result, err := h.RunHook("install")
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(result.Stderr), gc.Equals, "")
+	c.Assert(result.Code, gc.Equals, 0)
+	c.Assert(string(result.Stdout), gc.Equals, "My Title\n")
+	c.Assert(h.Context.OpenedPorts(), jc.DeepEquals, []string{"80/tcp"})
+}
+
+func (s *HarnessSuite) TestLogs(c *gc.C) {
+	h := s.newHarness(c)
+	_, err := runTool(c, h, "harness", "juju-log", "hello")
+	c.Assert(err, gc.IsNil)
+	_, err = runTool(c, h, "harness", "juju-log", "--debug", "details")
+	c.Assert(err, gc.IsNil)
+	c.Assert(h.Logs(), jc.DeepEquals, []harness.LogEntry{
+		{loggo.INFO, "hello"},
+		{loggo.DEBUG, "details"},
+	})
+}
+
+func (s *HarnessSuite) TestMergeEnvironment(c *gc.C) {
+	env := harness.MergeEnvironment(
+		[]string{"PATH=/bin", "HOME=/home/me", "EMPTY"},
+		map[string]string{"PATH": "/tools:/bin", "CHARM_DIR": "/charm"},
+	)
+	c.Assert(env, jc.SameContents, []string{
+		"HOME=/home/me",
+		"EMPTY",
+		"PATH=/tools:/bin",
+		"CHARM_DIR=/charm",
+	})
+}
@@ -0,0 +1,24 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package harness_test
+
+import (
+	"os"
+	stdtesting "testing"
+
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/worker/uniter/harness"
+)
+
+func init() {
+	// The test binary provides the hook tools for TestToolMain.
+	if code, ok := harness.ToolMain(os.Args); ok {
+		os.Exit(code)
+	}
+}
+
+func TestPackage(t *stdtesting.T) {
+	gc.TestingT(t)
+}
@@ -0,0 +1,183 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package harness
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/utils/exec"
+	"github.com/juju/utils/symlink"
+
+	"github.com/juju/juju/juju/names"
+	"github.com/juju/juju/juju/sockets"
+	"github.com/juju/juju/worker/uniter/jujuc"
+)
+
+// contextId is the context id given to hooks run outside a unit
+// agent; there is only ever one context per tool server.
+const contextId = "harness"
+
+// ToolServer serves the hook tools for a context outside a unit agent.
+// The tools are links to jujud, which forwards invocations to the
+// server exactly as it does for a unit agent.
+type ToolServer struct {
+	dir        string
+	toolsDir   string
+	socketPath string
+	srv        *jujuc.Server
+}
+
+// NewToolServer starts serving hook tools that run against ctx, using
+// the jujud executable at jujudPath.
+func NewToolServer(ctx jujuc.Context, jujudPath string) (_ *ToolServer, err error) {
+	dir, err := ioutil.TempDir("", "juju-hook-tools")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	s := &ToolServer{
+		dir:        dir,
+		toolsDir:   filepath.Join(dir, "tools"),
+		socketPath: filepath.Join(dir, "agent.socket"),
+	}
+	if err := os.Mkdir(s.toolsDir, 0755); err != nil {
+		return nil, err
+	}
+	for _, name := range jujuc.CommandNames() {
+		if err := symlink.New(jujudPath, filepath.Join(s.toolsDir, name)); err != nil {
+			return nil, fmt.Errorf("cannot create hook tools: %v", err)
+		}
+	}
+	getCmd := func(ctxId, cmdName string) (cmd.Command, error) {
+		if ctxId != contextId {
+			return nil, fmt.Errorf("expected context id %q, got %q", contextId, ctxId)
+		}
+		return jujuc.NewCommand(ctx, cmdName)
+	}
+	s.srv, err = jujuc.NewServer(getCmd, s.socketPath)
+	if err != nil {
+		return nil, err
+	}
+	go s.srv.Run()
+	return s, nil
+}
+
+// SocketPath returns the path of the socket the tools are served on.
+func (s *ToolServer) SocketPath() string {
+	return s.socketPath
+}
+
+// Environment returns the environment variables a hook needs to be
+// able to run the hook tools.
+func (s *ToolServer) Environment() map[string]string {
+	return map[string]string{
+		"JUJU_CONTEXT_ID":   contextId,
+		"JUJU_AGENT_SOCKET": s.socketPath,
+		"PATH":              s.toolsDir + string(os.PathListSeparator) + os.Getenv("PATH"),
+	}
+}
+
+// Close stops serving the hook tools and removes them.
+func (s *ToolServer) Close() error {
+	s.srv.Close()
+	return os.RemoveAll(s.dir)
+}
+
+// FindJujud returns the absolute path of the jujud executable found
+// in $PATH.
+func FindJujud() (string, error) {
+	path, err := osexec.LookPath(names.Jujud)
+	if err != nil {
+		return "", fmt.Errorf("cannot find %s to provide the hook tools", names.Jujud)
+	}
+	return filepath.Abs(path)
+}
+
+// ToolMain runs the hook tool named by args[0], as jujud does when it
+// is called through a link named for the tool: the tool runs on the
+// server at $JUJU_AGENT_SOCKET, in the context $JUJU_CONTEXT_ID. It
+// returns false if args[0] does not name a hook tool.
+//
+// A test binary that calls ToolMain when it starts can stand in for
+// jujud, so that hooks run by tests can use the hook tools without
+// jujud being built.
+func ToolMain(args []string) (code int, ok bool) {
+	commandName := filepath.Base(args[0])
+	isTool := false
+	for _, name := range jujuc.CommandNames() {
+		if name == commandName {
+			isTool = true
+			break
+		}
+	}
+	if !isTool {
+		return 0, false
+	}
+	code, err := runTool(commandName, args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+	return code, true
+}
+
+// runTool asks the tool server to run the named hook tool, and writes
+// its output to stdout and stderr.
+func runTool(commandName string, args []string) (int, error) {
+	contextId := os.Getenv("JUJU_CONTEXT_ID")
+	if contextId == "" {
+		return 1, fmt.Errorf("JUJU_CONTEXT_ID not set")
+	}
+	socketPath := os.Getenv("JUJU_AGENT_SOCKET")
+	if socketPath == "" {
+		return 1, fmt.Errorf("JUJU_AGENT_SOCKET not set")
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return 1, err
+	}
+	client, err := sockets.Dial(socketPath)
+	if err != nil {
+		return 1, err
+	}
+	defer client.Close()
+	req := jujuc.Request{
+		ContextId:   contextId,
+		Dir:         dir,
+		CommandName: commandName,
+		Args:        args,
+	}
+	var resp exec.ExecResponse
+	if err := client.Call("Jujuc.Main", req, &resp); err != nil {
+		return 1, err
+	}
+	os.Stdout.Write(resp.Stdout)
+	os.Stderr.Write(resp.Stderr)
+	return resp.Code, nil
+}
+
+// MergeEnvironment returns the os.Environ-style variables in env, with
+// the given variables added or replacing any already there.
+func MergeEnvironment(env []string, vars map[string]string) []string {
+	var result []string
+	for _, v := range env {
+		name := strings.SplitN(v, "=", 2)[0]
+		if _, ok := vars[name]; !ok {
+			result = append(result, v)
+		}
+	}
+	for name, value := range vars {
+		result = append(result, name+"="+value)
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// +build !windows
+
+package uniter
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setHookProcessGroup arranges for the hook's process to be started
+// in a process group of its own, so that any processes it starts can
+// be killed along with it.
+func setHookProcessGroup(ps *exec.Cmd) {
+	ps.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killHookProcess kills the hook's process group.
+func killHookProcess(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
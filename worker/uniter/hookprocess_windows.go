@@ -0,0 +1,18 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package uniter
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setHookProcessGroup does nothing on windows, where only the hook's
+// own process can be killed.
+func setHookProcessGroup(ps *exec.Cmd) {}
+
+// killHookProcess kills the hook's process.
+func killHookProcess(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,284 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package jujuc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/juju/charm"
+
+	"github.com/juju/juju/state/api/params"
+)
+
+// CapturedContext holds everything a hook can learn from its context,
+// as it was when the hook started, so that the hook can later be run
+// again against the same context without a unit agent.
+type CapturedContext struct {
+	// HookName is the name of the hook or action that ran.
+	HookName string `json:"hook-name"`
+
+	// Action is true if HookName names an action rather than a hook.
+	Action bool `json:"action,omitempty"`
+
+	// Environment holds the environment variables the hook ran with.
+	Environment []string `json:"environment"`
+
+	UnitName          string                 `json:"unit-name"`
+	PublicAddress     string                 `json:"public-address,omitempty"`
+	PrivateAddress    string                 `json:"private-address,omitempty"`
+	EndpointAddresses map[string]string      `json:"endpoint-addresses,omitempty"`
+	OwnerTag          string                 `json:"owner-tag"`
+	ConfigSettings    map[string]interface{} `json:"config-settings"`
+	ActionParams      map[string]interface{} `json:"action-params,omitempty"`
+
+	// HookRelationId holds the id of the relation the hook ran for,
+	// or -1 if it is not a relation hook.
+	HookRelationId int    `json:"hook-relation-id"`
+	RemoteUnitName string `json:"remote-unit-name,omitempty"`
+
+	Relations []CapturedRelation `json:"relations"`
+}
+
+// CapturedRelation holds the state of a relation as seen by a hook.
+type CapturedRelation struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+
+	// Settings holds the local unit's settings in the relation.
+	Settings params.RelationSettings `json:"settings"`
+
+	// Units holds the settings of each remote unit in the relation.
+	Units map[string]params.RelationSettings `json:"units"`
+}
+
+// CaptureContext records the current state of ctx. It does not change
+// ctx, except that reading the settings of remote units may cache them
+// exactly as if the hook had read them itself.
+func CaptureContext(ctx Context) (*CapturedContext, error) {
+	settings, err := ctx.ConfigSettings()
+	if err != nil {
+		return nil, fmt.Errorf("cannot capture config settings: %v", err)
+	}
+	captured := &CapturedContext{
+		UnitName:       ctx.UnitName(),
+		OwnerTag:       ctx.OwnerTag(),
+		ConfigSettings: settings,
+		ActionParams:   ctx.ActionParams(),
+		HookRelationId: -1,
+	}
+	captured.PublicAddress, _ = ctx.PublicAddress()
+	captured.PrivateAddress, _ = ctx.PrivateAddress()
+	captured.EndpointAddresses = ctx.EndpointAddresses()
+	if r, found := ctx.HookRelation(); found {
+		captured.HookRelationId = r.Id()
+	}
+	captured.RemoteUnitName, _ = ctx.RemoteUnitName()
+	ids := ctx.RelationIds()
+	sort.Ints(ids)
+	for _, id := range ids {
+		r, found := ctx.Relation(id)
+		if !found {
+			continue
+		}
+		rel, err := captureRelation(r)
+		if err != nil {
+			return nil, fmt.Errorf("cannot capture relation %s: %v", r.FakeId(), err)
+		}
+		captured.Relations = append(captured.Relations, rel)
+	}
+	return captured, nil
+}
+
+func captureRelation(r ContextRelation) (CapturedRelation, error) {
+	rel := CapturedRelation{
+		Id:    r.Id(),
+		Name:  r.Name(),
+		Units: make(map[string]params.RelationSettings),
+	}
+	settings, err := r.LocalSettings()
+	if err != nil {
+		return CapturedRelation{}, err
+	}
+	rel.Settings = settings
+	for _, unitName := range r.UnitNames() {
+		unitSettings, err := r.ReadSettings(unitName)
+		if err != nil {
+			return CapturedRelation{}, err
+		}
+		rel.Units[unitName] = copySettings(unitSettings)
+	}
+	return rel, nil
+}
+
+func copySettings(settings params.RelationSettings) params.RelationSettings {
+	result := make(params.RelationSettings)
+	for k, v := range settings {
+		result[k] = v
+	}
+	return result
+}
+
+func copyAddresses(addresses map[string]string) map[string]string {
+	result := make(map[string]string)
+	for endpoint, addr := range addresses {
+		result[endpoint] = addr
+	}
+	return result
+}
+
+// Context returns a Context that answers hook tools from the
+// captured state. Changes made through it, such as relation settings
+// or opened ports, are kept only in memory.
+func (c *CapturedContext) Context() Context {
+	ctx := &replayContext{
+		captured:  c,
+		relations: make(map[int]*replayRelation),
+	}
+	for _, rel := range c.Relations {
+		units := make(map[string]params.RelationSettings)
+		for unitName, settings := range rel.Units {
+			units[unitName] = copySettings(settings)
+		}
+		ctx.relations[rel.Id] = &replayRelation{
+			id:       rel.Id,
+			name:     rel.Name,
+			settings: replaySettings(copySettings(rel.Settings)),
+			units:    units,
+		}
+	}
+	return ctx
+}
+
+// replayContext implements Context for a captured hook context.
+type replayContext struct {
+	captured  *CapturedContext
+	relations map[int]*replayRelation
+}
+
+func (ctx *replayContext) UnitName() string {
+	return ctx.captured.UnitName
+}
+
+func (ctx *replayContext) PublicAddress() (string, bool) {
+	return ctx.captured.PublicAddress, ctx.captured.PublicAddress != ""
+}
+
+func (ctx *replayContext) PrivateAddress() (string, bool) {
+	return ctx.captured.PrivateAddress, ctx.captured.PrivateAddress != ""
+}
+
+func (ctx *replayContext) EndpointAddresses() map[string]string {
+	return copyAddresses(ctx.captured.EndpointAddresses)
+}
+
+func (ctx *replayContext) OpenPort(protocol string, port int) error {
+	logger.Infof("replay: not opening port %d/%s", port, protocol)
+	return nil
+}
+
+func (ctx *replayContext) ClosePort(protocol string, port int) error {
+	logger.Infof("replay: not closing port %d/%s", port, protocol)
+	return nil
+}
+
+func (ctx *replayContext) ConfigSettings() (charm.Settings, error) {
+	result := charm.Settings{}
+	for name, value := range ctx.captured.ConfigSettings {
+		result[name] = value
+	}
+	return result, nil
+}
+
+func (ctx *replayContext) ActionParams() map[string]interface{} {
+	return ctx.captured.ActionParams
+}
+
+func (ctx *replayContext) HookRelation() (ContextRelation, bool) {
+	return ctx.Relation(ctx.captured.HookRelationId)
+}
+
+func (ctx *replayContext) RemoteUnitName() (string, bool) {
+	return ctx.captured.RemoteUnitName, ctx.captured.RemoteUnitName != ""
+}
+
+func (ctx *replayContext) Relation(id int) (ContextRelation, bool) {
+	r, found := ctx.relations[id]
+	if !found {
+		return nil, false
+	}
+	return r, true
+}
+
+func (ctx *replayContext) RelationIds() []int {
+	ids := []int{}
+	for id := range ctx.relations {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (ctx *replayContext) OwnerTag() string {
+	return ctx.captured.OwnerTag
+}
+
+// replayRelation implements ContextRelation for a captured relation.
+type replayRelation struct {
+	id       int
+	name     string
+	settings replaySettings
+	units    map[string]params.RelationSettings
+}
+
+func (r *replayRelation) Id() int {
+	return r.id
+}
+
+func (r *replayRelation) Name() string {
+	return r.name
+}
+
+func (r *replayRelation) FakeId() string {
+	return fmt.Sprintf("%s:%d", r.name, r.id)
+}
+
+func (r *replayRelation) Settings() (Settings, error) {
+	return r.settings, nil
+}
+
+func (r *replayRelation) LocalSettings() (params.RelationSettings, error) {
+	return r.settings.Map(), nil
+}
+
+func (r *replayRelation) UnitNames() []string {
+	var unitNames []string
+	for unitName := range r.units {
+		unitNames = append(unitNames, unitName)
+	}
+	sort.Strings(unitNames)
+	return unitNames
+}
+
+func (r *replayRelation) ReadSettings(unitName string) (params.RelationSettings, error) {
+	settings, found := r.units[unitName]
+	if !found {
+		return nil, fmt.Errorf("settings of unit %q were not captured", unitName)
+	}
+	return copySettings(settings), nil
+}
+
+// replaySettings implements Settings for a captured relation.
+type replaySettings params.RelationSettings
+
+func (s replaySettings) Map() params.RelationSettings {
+	return copySettings(params.RelationSettings(s))
+}
+
+func (s replaySettings) Set(key, value string) {
+	s[key] = value
+}
+
+func (s replaySettings) Delete(key string) {
+	delete(s, key)
+}
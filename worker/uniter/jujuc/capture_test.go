@@ -0,0 +1,129 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package jujuc_test
+
+import (
+	"encoding/json"
+
+	"github.com/juju/cmd"
+	jc "github.com/juju/testing/checkers"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/state/api/params"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/uniter/jujuc"
+)
+
+type CaptureSuite struct {
+	ContextSuite
+}
+
+var _ = gc.Suite(&CaptureSuite{})
+
+func (s *CaptureSuite) capture(c *gc.C, relid int, remote string) *jujuc.CapturedContext {
+	hctx := s.GetHookContext(c, relid, remote)
+	hctx.actionParams = map[string]interface{}{"outfile": "/tmp/out"}
+	captured, err := jujuc.CaptureContext(hctx)
+	c.Assert(err, gc.IsNil)
+
+	// Check that the context survives being written to a file.
+	data, err := json.Marshal(captured)
+	c.Assert(err, gc.IsNil)
+	var loaded jujuc.CapturedContext
+	err = json.Unmarshal(data, &loaded)
+	c.Assert(err, gc.IsNil)
+	return &loaded
+}
+
+func (s *CaptureSuite) TestCaptureContext(c *gc.C) {
+	s.rels[1].units["u/1"] = Settings{"private-address": "u-1.testing.invalid"}
+	captured := s.capture(c, 1, "u/1")
+	c.Assert(captured.UnitName, gc.Equals, "u/0")
+	c.Assert(captured.PublicAddress, gc.Equals, "gimli.minecraft.testing.invalid")
+	c.Assert(captured.PrivateAddress, gc.Equals, "192.168.0.99")
+	c.Assert(captured.EndpointAddresses, jc.DeepEquals, map[string]string{
+		"peer0": "10.10.0.5",
+		"peer1": "192.168.0.99",
+	})
+	c.Assert(captured.OwnerTag, gc.Equals, "test-owner")
+	c.Assert(captured.ConfigSettings["title"], gc.Equals, "My Title")
+	c.Assert(captured.ActionParams, jc.DeepEquals, map[string]interface{}{"outfile": "/tmp/out"})
+	c.Assert(captured.HookRelationId, gc.Equals, 1)
+	c.Assert(captured.RemoteUnitName, gc.Equals, "u/1")
+	c.Assert(captured.Relations, jc.DeepEquals, []jujuc.CapturedRelation{{
+		Id:       0,
+		Name:     "peer0",
+		Settings: params.RelationSettings{"private-address": "u-0.testing.invalid"},
+		Units: map[string]params.RelationSettings{
+			"u/0": {"private-address": "u-0.testing.invalid"},
+		},
+	}, {
+		Id:       1,
+		Name:     "peer1",
+		Settings: params.RelationSettings{"private-address": "u-0.testing.invalid"},
+		Units: map[string]params.RelationSettings{
+			"u/0": {"private-address": "u-0.testing.invalid"},
+			"u/1": {"private-address": "u-1.testing.invalid"},
+		},
+	}})
+}
+
+func (s *CaptureSuite) TestCaptureNoRelation(c *gc.C) {
+	captured := s.capture(c, -1, "")
+	c.Assert(captured.HookRelationId, gc.Equals, -1)
+	c.Assert(captured.RemoteUnitName, gc.Equals, "")
+	hctx := captured.Context()
+	_, found := hctx.HookRelation()
+	c.Assert(found, jc.IsFalse)
+}
+
+func (s *CaptureSuite) run(c *gc.C, hctx jujuc.Context, name string, args ...string) string {
+	com, err := jujuc.NewCommand(hctx, name)
+	c.Assert(err, gc.IsNil)
+	ctx := testing.Context(c)
+	code := cmd.Main(com, ctx, args)
+	c.Assert(bufferString(ctx.Stderr), gc.Equals, "")
+	c.Assert(code, gc.Equals, 0)
+	return bufferString(ctx.Stdout)
+}
+
+func (s *CaptureSuite) TestReplayContext(c *gc.C) {
+	s.rels[1].units["u/1"] = Settings{"private-address": "u-1.testing.invalid"}
+	hctx := s.capture(c, 1, "u/1").Context()
+
+	c.Assert(s.run(c, hctx, "unit-get", "private-address"), gc.Equals, "192.168.0.99\n")
+	c.Assert(s.run(c, hctx, "network-get", "peer0"), gc.Equals, "10.10.0.5\n")
+	c.Assert(s.run(c, hctx, "config-get", "title"), gc.Equals, "My Title\n")
+	c.Assert(s.run(c, hctx, "action-get", "outfile"), gc.Equals, "/tmp/out\n")
+	c.Assert(s.run(c, hctx, "owner-get", "tag"), gc.Equals, "test-owner\n")
+	c.Assert(s.run(c, hctx, "relation-ids", "peer0"), gc.Equals, "peer0:0\n")
+	c.Assert(s.run(c, hctx, "relation-list"), gc.Equals, "u/0\nu/1\n")
+	c.Assert(s.run(c, hctx, "relation-get", "private-address"), gc.Equals, "u-1.testing.invalid\n")
+	c.Assert(s.run(c, hctx, "relation-get", "-r", "peer0:0", "private-address", "u/0"), gc.Equals, "u-0.testing.invalid\n")
+	c.Assert(s.run(c, hctx, "open-port", "80"), gc.Equals, "")
+}
+
+func (s *CaptureSuite) TestReplayChangesNotShared(c *gc.C) {
+	captured := s.capture(c, 1, "u/0")
+	hctx := captured.Context()
+	s.run(c, hctx, "relation-set", "foo=bar")
+	c.Assert(s.run(c, hctx, "relation-get", "foo", "u/0"), gc.Equals, "bar\n")
+
+	r, found := hctx.HookRelation()
+	c.Assert(found, jc.IsTrue)
+	settings, err := r.Settings()
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings.Map(), jc.DeepEquals, params.RelationSettings{
+		"private-address": "u-0.testing.invalid",
+		"foo":             "bar",
+	})
+
+	// A fresh replay starts again from the captured settings.
+	r, _ = captured.Context().HookRelation()
+	settings, err = r.Settings()
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings.Map(), jc.DeepEquals, params.RelationSettings{
+		"private-address": "u-0.testing.invalid",
+	})
+}
@@ -26,6 +26,12 @@ type Context interface {
 	// PrivateAddress returns the executing unit's private address.
 	PrivateAddress() (string, bool)
 
+	// EndpointAddresses returns the executing unit's address for each
+	// endpoint of its service, keyed by endpoint name: its address in
+	// the space the endpoint is bound to, or its private address if
+	// the endpoint is not bound.
+	EndpointAddresses() map[string]string
+
 	// OpenPort marks the supplied port for opening when the executing unit's
 	// service is exposed.
 	OpenPort(protocol string, port int) error
@@ -80,6 +86,11 @@ type ContextRelation interface {
 	// this relation.
 	Settings() (Settings, error)
 
+	// LocalSettings returns a copy of the local unit's settings in
+	// this relation, including any changes not yet written, without
+	// giving access to change them.
+	LocalSettings() (params.RelationSettings, error)
+
 	// UnitNames returns a list of the remote units in the relation.
 	UnitNames() []string
 
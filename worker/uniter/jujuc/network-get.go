@@ -0,0 +1,59 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package jujuc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
+)
+
+// NetworkGetCommand implements the network-get command.
+type NetworkGetCommand struct {
+	cmd.CommandBase
+	ctx     Context
+	Binding string
+	out     cmd.Output
+}
+
+func NewNetworkGetCommand(ctx Context) cmd.Command {
+	return &NetworkGetCommand{ctx: ctx}
+}
+
+func (c *NetworkGetCommand) Info() *cmd.Info {
+	doc := `
+network-get prints the address of the unit to be used by the named
+endpoint of its service. If the endpoint is bound to a space, this is
+the unit's address in that space; otherwise it is the unit's private
+address.
+`
+	return &cmd.Info{
+		Name:    "network-get",
+		Args:    "<binding>",
+		Purpose: "print the address for an endpoint binding",
+		Doc:     doc,
+	}
+}
+
+func (c *NetworkGetCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "smart", cmd.DefaultFormatters)
+}
+
+func (c *NetworkGetCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no binding specified")
+	}
+	c.Binding = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+func (c *NetworkGetCommand) Run(ctx *cmd.Context) error {
+	addr, ok := c.ctx.EndpointAddresses()[c.Binding]
+	if !ok {
+		return fmt.Errorf("no address for binding %q", c.Binding)
+	}
+	return c.out.Write(ctx, addr)
+}
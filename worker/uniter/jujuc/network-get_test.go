@@ -0,0 +1,64 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package jujuc_test
+
+import (
+	"github.com/juju/cmd"
+	gc "launchpad.net/gocheck"
+
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/uniter/jujuc"
+)
+
+type NetworkGetSuite struct {
+	ContextSuite
+}
+
+var _ = gc.Suite(&NetworkGetSuite{})
+
+var networkGetTests = []struct {
+	args []string
+	out  string
+}{
+	{[]string{"peer0"}, "10.10.0.5\n"},
+	{[]string{"peer0", "--format", "json"}, `"10.10.0.5"` + "\n"},
+	{[]string{"peer1"}, "192.168.0.99\n"},
+}
+
+func (s *NetworkGetSuite) createCommand(c *gc.C) cmd.Command {
+	hctx := s.GetHookContext(c, -1, "")
+	com, err := jujuc.NewCommand(hctx, "network-get")
+	c.Assert(err, gc.IsNil)
+	return com
+}
+
+func (s *NetworkGetSuite) TestOutputFormat(c *gc.C) {
+	for i, t := range networkGetTests {
+		c.Logf("test %d: %v", i, t.args)
+		com := s.createCommand(c)
+		ctx := testing.Context(c)
+		code := cmd.Main(com, ctx, t.args)
+		c.Assert(code, gc.Equals, 0)
+		c.Assert(bufferString(ctx.Stderr), gc.Equals, "")
+		c.Assert(bufferString(ctx.Stdout), gc.Equals, t.out)
+	}
+}
+
+func (s *NetworkGetSuite) TestUnknownBinding(c *gc.C) {
+	com := s.createCommand(c)
+	ctx := testing.Context(c)
+	code := cmd.Main(com, ctx, []string{"website"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(bufferString(ctx.Stderr), gc.Equals, "error: no address for binding \"website\"\n")
+}
+
+func (s *NetworkGetSuite) TestInit(c *gc.C) {
+	com := s.createCommand(c)
+	err := testing.InitCommand(com, nil)
+	c.Assert(err, gc.ErrorMatches, "no binding specified")
+
+	com = s.createCommand(c)
+	err = testing.InitCommand(com, []string{"peer0", "blah"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["blah"\]`)
+}
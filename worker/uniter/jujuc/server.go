@@ -28,6 +28,7 @@ var newCommands = map[string]func(Context) cmd.Command{
 	"close-port" + cmdSuffix:    NewClosePortCommand,
 	"config-get" + cmdSuffix:    NewConfigGetCommand,
 	"juju-log" + cmdSuffix:      NewJujuLogCommand,
+	"network-get" + cmdSuffix:   NewNetworkGetCommand,
 	"open-port" + cmdSuffix:     NewOpenPortCommand,
 	"relation-get" + cmdSuffix:  NewRelationGetCommand,
 	"action-get" + cmdSuffix:    NewActionGetCommand,
@@ -194,6 +194,7 @@ var newCommandTests = []struct {
 	{"close-port", ""},
 	{"config-get", ""},
 	{"juju-log", ""},
+	{"network-get", ""},
 	{"open-port", ""},
 	{"relation-get", ""},
 	{"relation-ids", ""},
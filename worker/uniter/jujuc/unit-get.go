@@ -50,6 +50,13 @@ func (c *UnitGetCommand) Run(ctx *cmd.Context) error {
 	value, ok := "", false
 	if c.Key == "private-address" {
 		value, ok = c.ctx.PrivateAddress()
+		// In a relation hook, the private address is the one the
+		// relation's endpoint is bound to.
+		if r, found := c.ctx.HookRelation(); found {
+			if addr, bound := c.ctx.EndpointAddresses()[r.Name()]; bound {
+				value, ok = addr, true
+			}
+		}
 	} else {
 		value, ok = c.ctx.PublicAddress()
 	}
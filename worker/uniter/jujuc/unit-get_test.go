@@ -79,6 +79,25 @@ func (s *UnitGetSuite) TestOutputPath(c *gc.C) {
 	c.Assert(string(content), gc.Equals, "192.168.0.99\n")
 }
 
+func (s *UnitGetSuite) TestPrivateAddressInRelationHook(c *gc.C) {
+	// The peer0 endpoint is bound to a space.
+	hctx := s.GetHookContext(c, 0, "")
+	com, err := jujuc.NewCommand(hctx, "unit-get")
+	c.Assert(err, gc.IsNil)
+	ctx := testing.Context(c)
+	code := cmd.Main(com, ctx, []string{"private-address"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(bufferString(ctx.Stdout), gc.Equals, "10.10.0.5\n")
+
+	// The public address does not depend on the binding.
+	com, err = jujuc.NewCommand(hctx, "unit-get")
+	c.Assert(err, gc.IsNil)
+	ctx = testing.Context(c)
+	code = cmd.Main(com, ctx, []string{"public-address"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(bufferString(ctx.Stdout), gc.Equals, "gimli.minecraft.testing.invalid\n")
+}
+
 func (s *UnitGetSuite) TestUnknownSetting(c *gc.C) {
 	com := s.createCommand(c)
 	err := testing.InitCommand(com, []string{"protected-address"})
@@ -98,6 +98,13 @@ func (c *Context) PrivateAddress() (string, bool) {
 	return "192.168.0.99", true
 }
 
+func (c *Context) EndpointAddresses() map[string]string {
+	return map[string]string{
+		"peer0": "10.10.0.5",
+		"peer1": "192.168.0.99",
+	}
+}
+
 func (c *Context) OpenPort(protocol string, port int) error {
 	c.ports.Add(fmt.Sprintf("%d/%s", port, protocol))
 	return nil
@@ -169,6 +176,10 @@ func (r *ContextRelation) Settings() (jujuc.Settings, error) {
 	return r.units["u/0"], nil
 }
 
+func (r *ContextRelation) LocalSettings() (params.RelationSettings, error) {
+	return r.units["u/0"].Map(), nil
+}
+
 func (r *ContextRelation) UnitNames() []string {
 	var s []string // initially nil to match the true context.
 	for name := range r.units {
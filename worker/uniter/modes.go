@@ -6,6 +6,7 @@ package uniter
 import (
 	stderrors "errors"
 	"fmt"
+	"time"
 
 	"github.com/juju/charm"
 	"github.com/juju/charm/hooks"
@@ -309,8 +310,31 @@ func modeAbideDyingLoop(u *Uniter) (next Mode, err error) {
 	}
 }
 
+// maxHookRetryDelay caps the delay between automatic retries of a
+// failed hook.
+const maxHookRetryDelay = 30 * time.Minute
+
+// hookRetryAfter is used to wait before automatically retrying a
+// failed hook.
+var hookRetryAfter = time.After
+
+// hookRetryDelay returns the delay to wait before making the given
+// (zero-based) automatic retry attempt; the delay doubles with every
+// attempt, up to maxHookRetryDelay.
+func hookRetryDelay(policy params.HookRetryPolicy, attempt int) time.Duration {
+	delay := policy.Delay
+	for i := 0; i < attempt && delay < maxHookRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxHookRetryDelay {
+		delay = maxHookRetryDelay
+	}
+	return delay
+}
+
 // ModeHookError is responsible for watching and responding to:
 // * user resolution of hook errors
+// * automatic retries of the failed hook
 // * forced charm upgrade requests
 func ModeHookError(u *Uniter) (next Mode, err error) {
 	defer modeContext("ModeHookError", &err)()
@@ -318,6 +342,9 @@ func ModeHookError(u *Uniter) (next Mode, err error) {
 		return nil, fmt.Errorf("insane uniter state: %#v", u.s)
 	}
 	msg := fmt.Sprintf("hook failed: %q", u.currentHookName())
+	if u.hookTimedOut > 0 {
+		msg += fmt.Sprintf(" (timed out after %v)", u.hookTimedOut)
+	}
 	// Create error information for status.
 	data := params.StatusData{"hook": u.currentHookName()}
 	if u.s.Hook.Kind.IsRelation() {
@@ -326,9 +353,42 @@ func ModeHookError(u *Uniter) (next Mode, err error) {
 			data["remote-unit"] = u.s.Hook.RemoteUnit
 		}
 	}
+	// Schedule an automatic retry of the hook, unless the retry policy
+	// is exhausted or an operator has already intervened.
+	// The retries made so far are kept in the uniter state, so that
+	// restarting the agent neither resets the backoff nor resumes
+	// retries an operator has stopped.
+	var retry <-chan time.Time
+	retried := HookRetry{}
+	if u.s.HookRetry != nil {
+		retried = *u.s.HookRetry
+	}
+	if !retried.Stopped {
+		policy, err := u.unit.HookRetryPolicy()
+		if err != nil {
+			logger.Warningf("cannot get hook retry policy, not retrying automatically: %v", err)
+		} else if policy.Attempts > 0 {
+			data["retry-max"] = policy.Attempts
+			if retried.Attempt < policy.Attempts {
+				delay := hookRetryDelay(policy, retried.Attempt)
+				data["retry-attempt"] = retried.Attempt + 1
+				data["next-retry"] = time.Now().Add(delay).UTC().Format(time.RFC3339)
+				logger.Infof("retrying hook %q in %v (attempt %d of %d)",
+					u.currentHookName(), delay, retried.Attempt+1, policy.Attempts)
+				retry = hookRetryAfter(delay)
+			} else {
+				data["retry-attempt"] = retried.Attempt
+			}
+		}
+	}
 	if err = u.unit.SetStatus(params.StatusError, msg, data); err != nil {
 		return nil, err
 	}
+	if u.s.Hook.Kind == hooks.UpgradeCharm && u.autoRollback() {
+		if err := u.rollbackCharm(); err != nil {
+			return nil, err
+		}
+	}
 	u.f.WantResolvedEvent()
 	u.f.WantUpgradeEvent(true)
 	for {
@@ -338,7 +398,22 @@ func ModeHookError(u *Uniter) (next Mode, err error) {
 			return nil, tomb.ErrDying
 		case info := <-u.f.ActionEvents():
 			hi = hook.Info{Kind: info.Kind, ActionId: info.ActionId}
+		case <-retry:
+			if err := u.setHookRetry(&HookRetry{Attempt: retried.Attempt + 1}); err != nil {
+				return nil, err
+			}
+			if err := u.runHook(*u.s.Hook); err == errHookFailed {
+				return ModeHookError, nil
+			} else if err != nil {
+				return nil, err
+			}
+			return ModeContinue, nil
 		case rm := <-u.f.ResolvedEvents():
+			// The operator has intervened, so leave any further
+			// retries to them.
+			if err := u.setHookRetry(&HookRetry{Attempt: retried.Attempt, Stopped: true}); err != nil {
+				return nil, err
+			}
 			switch rm {
 			case params.ResolvedRetryHooks:
 				err = u.runHook(*u.s.Hook)
@@ -351,12 +426,19 @@ func ModeHookError(u *Uniter) (next Mode, err error) {
 				return nil, e
 			}
 			if err == errHookFailed {
+				if _, ok := data["retry-max"]; ok {
+					// Refresh the status to drop the retry details.
+					return ModeHookError, nil
+				}
 				continue
 			} else if err != nil {
 				return nil, err
 			}
 			return ModeContinue, nil
 		case curl := <-u.f.UpgradeEvents():
+			if err := u.resetHookRetry(); err != nil {
+				return nil, err
+			}
 			return ModeUpgrading(curl), nil
 		}
 		if err := u.runHook(hi); err == errHookFailed {
@@ -369,7 +451,7 @@ func ModeHookError(u *Uniter) (next Mode, err error) {
 
 // ModeConflicted is responsible for watching and responding to:
 // * user resolution of charm upgrade conflicts
-// * forced charm upgrade requests
+// * forced charm upgrade requests, including rollbacks
 func ModeConflicted(curl *charm.URL) Mode {
 	return func(u *Uniter) (next Mode, err error) {
 		defer modeContext("ModeConflicted", &err)()
@@ -377,6 +459,11 @@ func ModeConflicted(curl *charm.URL) Mode {
 		if err = u.unit.SetStatus(params.StatusError, "upgrade failed", nil); err != nil {
 			return nil, err
 		}
+		if u.autoRollback() {
+			if err := u.rollbackCharm(); err != nil {
+				return nil, err
+			}
+		}
 		u.f.WantResolvedEvent()
 		u.f.WantUpgradeEvent(true)
 		select {
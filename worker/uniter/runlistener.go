@@ -7,21 +7,61 @@
 package uniter
 
 import (
+	"fmt"
+	"io"
 	"net"
 	"net/rpc"
 	"sync"
+	"time"
 
 	"github.com/juju/juju/juju/sockets"
 	"github.com/juju/utils/exec"
 )
 
-const JujuRunEndpoint = "JujuRunServer.RunCommands"
+const (
+	// JujuRunEndpoint runs commands and returns all of their output
+	// once they have finished.
+	JujuRunEndpoint = "JujuRunServer.RunCommands"
+
+	// JujuRunStartEndpoint starts running commands, and returns an id
+	// that JujuRunOutputEndpoint fetches their output with as it is
+	// written. Both must be called on the same connection.
+	JujuRunStartEndpoint  = "JujuRunServer.StartCommands"
+	JujuRunOutputEndpoint = "JujuRunServer.CommandsOutput"
+)
+
+// commandsOutputWait is how long a call to JujuRunOutputEndpoint waits
+// for more output before returning without any.
+var commandsOutputWait = 30 * time.Second
 
 // A CommandRunner is something that will actually execute the commands and
 // return the results of that execution in the exec.ExecResponse (which
-// contains stdout, stderr, and return code).
+// contains stdout, stderr, and return code). If stdout and stderr are not
+// nil, the output is also written to them as it arrives. If abort is
+// closed before the commands finish, they should be killed.
 type CommandRunner interface {
-	RunCommands(commands string) (results *exec.ExecResponse, err error)
+	RunCommands(commands string, stdout, stderr io.Writer, abort <-chan struct{}) (results *exec.ExecResponse, err error)
+}
+
+// CommandsOutputArgs identifies commands started with JujuRunStartEndpoint,
+// and how much of their output has been fetched already.
+type CommandsOutputArgs struct {
+	Id           int
+	StdoutOffset int
+	StderrOffset int
+}
+
+// CommandsOutput holds output written by commands started with
+// JujuRunStartEndpoint after the offsets asked for.
+type CommandsOutput struct {
+	Stdout []byte
+	Stderr []byte
+
+	// Done is true once the commands have finished and all of their
+	// output has been returned; Code and Error are then set.
+	Done  bool
+	Code  int
+	Error string
 }
 
 // RunListener is responsible for listening on the network connection and
@@ -29,34 +69,213 @@ type CommandRunner interface {
 // that listens and hands off the work.
 type RunListener struct {
 	listener net.Listener
-	server   *rpc.Server
+	runner   CommandRunner
 	closed   chan struct{}
 	closing  chan struct{}
 	wg       sync.WaitGroup
 }
 
 // The JujuRunServer is the entity that has the methods that are called over
-// the rpc connection.
+// the rpc connection. Each connection has a server of its own, and commands
+// started on a connection are killed when it is closed.
 type JujuRunServer struct {
 	runner CommandRunner
+
+	// abort is closed when the connection is closed, to kill any
+	// commands still running; wg tracks the commands started.
+	abort chan struct{}
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	nextId  int
+	running map[int]*runningCommands
+}
+
+func newRunServer(runner CommandRunner) (*JujuRunServer, *rpc.Server, error) {
+	runServer := &JujuRunServer{
+		runner:  runner,
+		abort:   make(chan struct{}),
+		running: make(map[int]*runningCommands),
+	}
+	server := rpc.NewServer()
+	if err := server.Register(runServer); err != nil {
+		return nil, nil, err
+	}
+	return runServer, server, nil
+}
+
+// abortCommands kills any commands started on the server's connection
+// that are still running, waits for them to exit, and discards their
+// output. It is called once the connection has been closed.
+func (r *JujuRunServer) abortCommands() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	close(r.abort)
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.running) > 0 {
+		logger.Debugf("discarding output of %d commands after connection closed", len(r.running))
+	}
+	r.running = nil
 }
 
 // RunCommands delegates the actual running to the runner and populates the
 // response structure.
 func (r *JujuRunServer) RunCommands(commands string, result *exec.ExecResponse) error {
 	logger.Debugf("RunCommands: %q", commands)
-	runResult, err := r.runner.RunCommands(commands)
-	*result = *runResult
+	runResult, err := r.runner.RunCommands(commands, nil, nil, r.abort)
+	if runResult != nil {
+		*result = *runResult
+	}
 	return err
 }
 
+// StartCommands starts the runner running the commands, and returns the id
+// that CommandsOutput fetches their output with.
+func (r *JujuRunServer) StartCommands(commands string, id *int) error {
+	logger.Debugf("StartCommands: %q", commands)
+	rc := &runningCommands{changed: make(chan struct{})}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("connection closed")
+	}
+	r.nextId++
+	*id = r.nextId
+	r.running[*id] = rc
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		result, err := r.runner.RunCommands(commands,
+			runningOutput{rc, false},
+			runningOutput{rc, true},
+			r.abort,
+		)
+		rc.finish(result, err)
+	}()
+	return nil
+}
+
+// CommandsOutput returns the output written by the commands with the given
+// id after the given offsets. It waits for a while for more output if there
+// is none yet, and returns without any if none is written.
+func (r *JujuRunServer) CommandsOutput(args CommandsOutputArgs, result *CommandsOutput) error {
+	r.mu.Lock()
+	rc := r.running[args.Id]
+	r.mu.Unlock()
+	if rc == nil {
+		return fmt.Errorf("no commands running with id %d", args.Id)
+	}
+	timeout := time.After(commandsOutputWait)
+	for {
+		changed, err := rc.output(args, result)
+		if err != nil {
+			return err
+		}
+		if result.Done {
+			r.mu.Lock()
+			delete(r.running, args.Id)
+			r.mu.Unlock()
+		}
+		if changed == nil {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+// runningCommands holds the output of commands started by StartCommands,
+// and their result once they have finished.
+type runningCommands struct {
+	mu      sync.Mutex
+	stdout  []byte
+	stderr  []byte
+	done    bool
+	code    int
+	err     error
+	changed chan struct{}
+}
+
+// notify wakes any callers waiting for the output to change. It must be
+// called with the lock held.
+func (rc *runningCommands) notify() {
+	close(rc.changed)
+	rc.changed = make(chan struct{})
+}
+
+func (rc *runningCommands) finish(result *exec.ExecResponse, err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.done = true
+	if result != nil {
+		rc.code = result.Code
+	}
+	rc.err = err
+	rc.notify()
+}
+
+// output fills in the result with any output after the offsets in args,
+// and the outcome of the commands if they have finished. If there is
+// nothing to return yet, it returns a channel that is closed when there
+// might be.
+func (rc *runningCommands) output(args CommandsOutputArgs, result *CommandsOutput) (<-chan struct{}, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if args.StdoutOffset < 0 || args.StdoutOffset > len(rc.stdout) ||
+		args.StderrOffset < 0 || args.StderrOffset > len(rc.stderr) {
+		return nil, fmt.Errorf("invalid output offsets %d, %d", args.StdoutOffset, args.StderrOffset)
+	}
+	stdout := rc.stdout[args.StdoutOffset:]
+	stderr := rc.stderr[args.StderrOffset:]
+	if len(stdout) == 0 && len(stderr) == 0 && !rc.done {
+		return rc.changed, nil
+	}
+	*result = CommandsOutput{
+		Stdout: append([]byte(nil), stdout...),
+		Stderr: append([]byte(nil), stderr...),
+		Done:   rc.done,
+		Code:   rc.code,
+	}
+	if rc.err != nil {
+		result.Error = rc.err.Error()
+	}
+	return nil, nil
+}
+
+// runningOutput records everything written to it as output of running
+// commands.
+type runningOutput struct {
+	rc     *runningCommands
+	stderr bool
+}
+
+func (w runningOutput) Write(data []byte) (int, error) {
+	w.rc.mu.Lock()
+	defer w.rc.mu.Unlock()
+	if w.stderr {
+		w.rc.stderr = append(w.rc.stderr, data...)
+	} else {
+		w.rc.stdout = append(w.rc.stdout, data...)
+	}
+	w.rc.notify()
+	return len(data), nil
+}
+
 // NewRunListener returns a new RunListener that is listening on given
 // socket or named pipe passed in. If a valid RunListener is returned, is
 // has the go routine running, and should be closed by the creator
 // when they are done with it.
 func NewRunListener(runner CommandRunner, socketPath string) (*RunListener, error) {
-	server := rpc.NewServer()
-	if err := server.Register(&JujuRunServer{runner}); err != nil {
+	// Check the server can be registered before listening; a server is
+	// registered for each connection as it is accepted.
+	if _, _, err := newRunServer(runner); err != nil {
 		return nil, err
 	}
 	listener, err := sockets.Listen(socketPath)
@@ -65,7 +284,7 @@ func NewRunListener(runner CommandRunner, socketPath string) (*RunListener, erro
 	}
 	runListener := &RunListener{
 		listener: listener,
-		server:   server,
+		runner:   runner,
 		closed:   make(chan struct{}),
 		closing:  make(chan struct{}),
 	}
@@ -74,7 +293,8 @@ func NewRunListener(runner CommandRunner, socketPath string) (*RunListener, erro
 }
 
 // Run accepts new connections until it encounters an error, or until Close is
-// called, and then blocks until all existing connections have been closed.
+// called, and then blocks until all existing connections have been closed
+// and the commands started on them have been killed.
 func (s *RunListener) Run() (err error) {
 	logger.Debugf("juju-run listener running")
 	var conn net.Conn
@@ -83,10 +303,16 @@ func (s *RunListener) Run() (err error) {
 		if err != nil {
 			break
 		}
+		runServer, server, err := newRunServer(s.runner)
+		if err != nil {
+			conn.Close()
+			break
+		}
 		s.wg.Add(1)
 		go func(conn net.Conn) {
-			s.server.ServeConn(conn)
-			s.wg.Done()
+			defer s.wg.Done()
+			server.ServeConn(conn)
+			runServer.abortCommands()
 		}(conn)
 	}
 	logger.Debugf("juju-run listener stopping")
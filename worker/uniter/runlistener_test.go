@@ -4,8 +4,11 @@
 package uniter_test
 
 import (
+	"fmt"
+	"io"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/juju/utils/exec"
 	gc "launchpad.net/gocheck"
@@ -18,6 +21,7 @@ import (
 type ListenerSuite struct {
 	testing.BaseSuite
 	socketPath string
+	runner     *mockRunner
 }
 
 var _ = gc.Suite(&ListenerSuite{})
@@ -32,7 +36,8 @@ func (s *ListenerSuite) sockPath(c *gc.C) string {
 // Mirror the params to uniter.NewRunListener, but add cleanup to close it.
 func (s *ListenerSuite) NewRunListener(c *gc.C) *uniter.RunListener {
 	s.socketPath = s.sockPath(c)
-	listener, err := uniter.NewRunListener(&mockRunner{c}, s.socketPath)
+	s.runner = &mockRunner{c: c, aborted: make(chan string, 1)}
+	listener, err := uniter.NewRunListener(s.runner, s.socketPath)
 	c.Assert(err, gc.IsNil)
 	c.Assert(listener, gc.NotNil)
 	s.AddCleanup(func(*gc.C) {
@@ -66,17 +71,87 @@ func (s *ListenerSuite) TestClientCall(c *gc.C) {
 	c.Assert(result.Code, gc.Equals, 42)
 }
 
+func (s *ListenerSuite) TestClientStreamOutput(c *gc.C) {
+	s.NewRunListener(c)
+
+	client, err := sockets.Dial(s.socketPath)
+	c.Assert(err, gc.IsNil)
+	defer client.Close()
+
+	var id int
+	err = client.Call(uniter.JujuRunStartEndpoint, "some-command", &id)
+	c.Assert(err, gc.IsNil)
+
+	var stdout, stderr []byte
+	args := uniter.CommandsOutputArgs{Id: id}
+	for {
+		var result uniter.CommandsOutput
+		err = client.Call(uniter.JujuRunOutputEndpoint, args, &result)
+		c.Assert(err, gc.IsNil)
+		stdout = append(stdout, result.Stdout...)
+		stderr = append(stderr, result.Stderr...)
+		args.StdoutOffset += len(result.Stdout)
+		args.StderrOffset += len(result.Stderr)
+		if result.Done {
+			c.Assert(result.Code, gc.Equals, 42)
+			c.Assert(result.Error, gc.Equals, "")
+			break
+		}
+	}
+	c.Assert(string(stdout), gc.Equals, "some-command stdout")
+	c.Assert(string(stderr), gc.Equals, "some-command stderr")
+
+	// Once all the output has been fetched, the commands are forgotten.
+	var result uniter.CommandsOutput
+	err = client.Call(uniter.JujuRunOutputEndpoint, args, &result)
+	c.Assert(err, gc.ErrorMatches, "no commands running with id 1")
+}
+
+func (s *ListenerSuite) TestClientCloseAbortsCommands(c *gc.C) {
+	s.NewRunListener(c)
+
+	client, err := sockets.Dial(s.socketPath)
+	c.Assert(err, gc.IsNil)
+
+	var id int
+	err = client.Call(uniter.JujuRunStartEndpoint, "wait-for-abort", &id)
+	c.Assert(err, gc.IsNil)
+	err = client.Close()
+	c.Assert(err, gc.IsNil)
+
+	// Closing the connection kills the commands started on it.
+	select {
+	case commands := <-s.runner.aborted:
+		c.Assert(commands, gc.Equals, "wait-for-abort")
+	case <-time.After(testing.LongWait):
+		c.Fatalf("commands not aborted")
+	}
+}
+
 type mockRunner struct {
-	c *gc.C
+	c       *gc.C
+	aborted chan string
 }
 
 var _ uniter.CommandRunner = (*mockRunner)(nil)
 
-func (r *mockRunner) RunCommands(commands string) (results *exec.ExecResponse, err error) {
+func (r *mockRunner) RunCommands(commands string, stdout, stderr io.Writer, abort <-chan struct{}) (results *exec.ExecResponse, err error) {
 	r.c.Log("mock runner: " + commands)
-	return &exec.ExecResponse{
+	if commands == "wait-for-abort" {
+		<-abort
+		r.aborted <- commands
+		return nil, fmt.Errorf("commands aborted")
+	}
+	result := &exec.ExecResponse{
 		Code:   42,
 		Stdout: []byte(commands + " stdout"),
 		Stderr: []byte(commands + " stderr"),
-	}, nil
+	}
+	if stdout != nil {
+		stdout.Write(result.Stdout)
+	}
+	if stderr != nil {
+		stderr.Write(result.Stderr)
+	}
+	return result, nil
 }
@@ -72,6 +72,27 @@ type State struct {
 	// Charm describes the charm being deployed by an Install or Upgrade
 	// operation, and is otherwise blank.
 	CharmURL *charm.URL `yaml:"charm,omitempty"`
+
+	// HookRetry records the automatic retries made of a failed hook. It
+	// may only be set when Op is RunHook, and is otherwise blank.
+	HookRetry *HookRetry `yaml:"hook-retry,omitempty"`
+
+	// RollbackRequested holds the URL of the charm whose failed upgrade
+	// the unit has asked to be rolled back, so that it asks only once
+	// however often the upgrade fails. It is kept until another charm
+	// is deployed.
+	RollbackRequested *charm.URL `yaml:"rollback-requested,omitempty"`
+}
+
+// HookRetry records the automatic retries made of a failed hook, so
+// that the backoff survives agent restarts.
+type HookRetry struct {
+	// Attempt holds the number of automatic retries already made.
+	Attempt int `yaml:"attempt"`
+
+	// Stopped records whether an operator has intervened since the
+	// hook failed, in which case no further automatic retries are made.
+	Stopped bool `yaml:"stopped,omitempty"`
 }
 
 // validate returns an error if the state violates expectations.
@@ -109,6 +130,9 @@ func (st State) validate() (err error) {
 	default:
 		return fmt.Errorf("unknown operation step %q", st.OpStep)
 	}
+	if st.HookRetry != nil && st.Op != RunHook {
+		return fmt.Errorf("unexpected hook retry")
+	}
 	if hasHook {
 		return st.Hook.Validate()
 	}
@@ -143,13 +167,17 @@ func (f *StateFile) Read() (*State, error) {
 }
 
 // Write stores the supplied state to the file.
-func (f *StateFile) Write(started bool, op Op, step OpStep, hi *uhook.Info, url *charm.URL) error {
+func (f *StateFile) Write(
+	started bool, op Op, step OpStep, hi *uhook.Info, url *charm.URL, retry *HookRetry, rollback *charm.URL,
+) error {
 	st := &State{
-		Started:  started,
-		Op:       op,
-		OpStep:   step,
-		Hook:     hi,
-		CharmURL: url,
+		Started:           started,
+		Op:                op,
+		OpStep:            step,
+		Hook:              hi,
+		CharmURL:          url,
+		HookRetry:         retry,
+		RollbackRequested: rollback,
 	}
 	if err := st.validate(); err != nil {
 		panic(err)
@@ -116,6 +116,20 @@ var stateTests = []struct {
 			},
 		},
 		err: `action id "foo" cannot be parsed as an action tag`,
+	}, {
+		st: uniter.State{
+			Op:        uniter.RunHook,
+			OpStep:    uniter.Pending,
+			Hook:      &hook.Info{Kind: hooks.ConfigChanged},
+			HookRetry: &uniter.HookRetry{Attempt: 2, Stopped: true},
+		},
+	}, {
+		st: uniter.State{
+			Op:                uniter.RunHook,
+			OpStep:            uniter.Pending,
+			Hook:              &hook.Info{Kind: hooks.UpgradeCharm},
+			RollbackRequested: stcurl,
+		},
 	},
 	// Upgrade operation.
 	{
@@ -153,6 +167,14 @@ var stateTests = []struct {
 			CharmURL: stcurl,
 		},
 		err: `unexpected charm URL`,
+	}, {
+		st: uniter.State{
+			Op:        uniter.Continue,
+			OpStep:    uniter.Pending,
+			Hook:      relhook,
+			HookRetry: &uniter.HookRetry{Attempt: 1},
+		},
+		err: `unexpected hook retry`,
 	}, {
 		st: uniter.State{
 			Op:     uniter.Continue,
@@ -170,7 +192,7 @@ func (s *StateFileSuite) TestStates(c *gc.C) {
 		_, err := file.Read()
 		c.Assert(err, gc.Equals, uniter.ErrNoStateFile)
 		write := func() {
-			err := file.Write(t.st.Started, t.st.Op, t.st.OpStep, t.st.Hook, t.st.CharmURL)
+			err := file.Write(t.st.Started, t.st.Op, t.st.OpStep, t.st.Hook, t.st.CharmURL, t.st.HookRetry, t.st.RollbackRequested)
 			c.Assert(err, gc.IsNil)
 		}
 		if t.err != "" {
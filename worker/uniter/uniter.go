@@ -5,11 +5,15 @@
 package uniter
 
 import (
+	"encoding/json"
 	stderrors "errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -85,7 +89,21 @@ type Uniter struct {
 	proxy      proxyutils.Settings
 	proxyMutex sync.Mutex
 
+	// envHookTimeout and envAutoRollback hold the environment's hook
+	// timeout and whether failed charm upgrades are rolled back
+	// automatically; envCaptureContexts holds whether the contexts
+	// of hooks are captured for replay.
+	envHookTimeout     time.Duration
+	envAutoRollback    bool
+	envCaptureContexts bool
+	envSettingsMutex   sync.Mutex
+
 	ranConfigChanged bool
+
+	// hookTimedOut holds the timeout of the currently failed hook,
+	// if it failed because it ran for too long.
+	hookTimedOut time.Duration
+
 	// The execution observer is only used in tests at this stage. Should this
 	// need to be extended, perhaps a list of observers would be needed.
 	observer UniterExecutionObserver
@@ -122,6 +140,14 @@ func (u *Uniter) loop(unitTag string) (err error) {
 		return err
 	}
 	defer watcher.Stop(environWatcher, &u.tomb)
+	// Apply the environment settings before any hook runs, rather
+	// than leaving the first hooks to race against the watcher.
+	environConfig, err := u.st.EnvironConfig()
+	if err != nil {
+		return err
+	}
+	u.updatePackageProxy(environConfig)
+	u.updateEnvironSettings(environConfig)
 	u.watchForProxyChanges(environWatcher)
 
 	// Start filtering state change events for consumption by modes.
@@ -268,6 +294,13 @@ func (u *Uniter) Dead() <-chan struct{} {
 // writeState saves uniter state with the supplied values, and infers the appropriate
 // value of Started.
 func (u *Uniter) writeState(op Op, step OpStep, hi *hook.Info, url *corecharm.URL) error {
+	if hi != nil && hi.Kind == hooks.ActionRequested && u.hookFailed() {
+		// An action is run while a failed hook waits to be retried or
+		// resolved. Keep the state of the failed hook, including its
+		// automatic retries, so that it is the one retried afterwards,
+		// even if the agent is restarted while the action runs.
+		return nil
+	}
 	s := State{
 		Started:  op == RunHook && hi.Kind == hooks.Start || u.s != nil && u.s.Started,
 		Op:       op,
@@ -275,13 +308,33 @@ func (u *Uniter) writeState(op Op, step OpStep, hi *hook.Info, url *corecharm.UR
 		Hook:     hi,
 		CharmURL: url,
 	}
-	if err := u.sf.Write(s.Started, s.Op, s.OpStep, s.Hook, s.CharmURL); err != nil {
+	if op == RunHook && step == Pending && u.s != nil && u.s.Op == RunHook && *u.s.Hook == *hi {
+		// The failed hook is being run again; keep track of the
+		// automatic retries made so far.
+		s.HookRetry = u.s.HookRetry
+	}
+	if u.s != nil && u.s.RollbackRequested != nil && (url == nil || *url == *u.s.RollbackRequested) {
+		s.RollbackRequested = u.s.RollbackRequested
+	}
+	return u.saveState(s)
+}
+
+// saveState saves the supplied uniter state as it is.
+func (u *Uniter) saveState(s State) error {
+	if err := u.sf.Write(s.Started, s.Op, s.OpStep, s.Hook, s.CharmURL, s.HookRetry, s.RollbackRequested); err != nil {
 		return err
 	}
 	u.s = &s
 	return nil
 }
 
+// hookFailed returns whether the uniter state records a failed hook,
+// other than an action, that is yet to be retried or resolved.
+func (u *Uniter) hookFailed() bool {
+	return u.s != nil && u.s.Op == RunHook && u.s.OpStep == Pending &&
+		u.s.Hook.Kind != hooks.ActionRequested
+}
+
 // deploy deploys the supplied charm URL, and sets follow-up hook operation state
 // as indicated by reason.
 func (u *Uniter) deploy(curl *corecharm.URL, reason Op) error {
@@ -332,8 +385,13 @@ func (u *Uniter) deploy(curl *corecharm.URL, reason Op) error {
 	logger.Infof("charm %q is deployed", curl)
 	status := Queued
 	if hi != nil {
-		// If a hook operation was interrupted, restore it.
-		status = Pending
+		// If a hook operation was interrupted, restore it; unless it was
+		// an upgrade-charm hook, which is superseded by the upgrade-charm
+		// hook of the newly deployed charm. This is what allows a failed
+		// upgrade to be rolled back.
+		if reason != Upgrade || hi.Kind != hooks.UpgradeCharm {
+			status = Pending
+		}
 	} else {
 		// Otherwise, queue the relevant post-deploy hook.
 		hi = &hook.Info{}
@@ -413,8 +471,11 @@ func (u *Uniter) startJujucServer(context *HookContext) (*jujuc.Server, string,
 	return srv, socketPath, nil
 }
 
-// RunCommands executes the supplied commands in a hook context.
-func (u *Uniter) RunCommands(commands string) (results *exec.ExecResponse, err error) {
+// RunCommands executes the supplied commands in a hook context. If stdout
+// and stderr are not nil, the output is written to them as it arrives.
+// If abort is closed before the commands finish, they are killed, or
+// not run at all if they have not yet started.
+func (u *Uniter) RunCommands(commands string, stdout, stderr io.Writer, abort <-chan struct{}) (results *exec.ExecResponse, err error) {
 	logger.Tracef("run commands: %s", commands)
 	hctxId := fmt.Sprintf("%s:run-commands:%d", u.unit.Name(), u.rand.Int63())
 	lockMessage := fmt.Sprintf("%s: running commands", u.unit.Name())
@@ -422,6 +483,11 @@ func (u *Uniter) RunCommands(commands string) (results *exec.ExecResponse, err e
 		return nil, err
 	}
 	defer u.hookLock.Unlock()
+	select {
+	case <-abort:
+		return nil, errCommandsAborted
+	default:
+	}
 
 	hctx, err := u.getHookContext(hctxId, -1, "", map[string]interface{}(nil))
 	if err != nil {
@@ -433,7 +499,7 @@ func (u *Uniter) RunCommands(commands string) (results *exec.ExecResponse, err e
 	}
 	defer srv.Close()
 
-	result, err := hctx.RunCommands(commands, u.charmPath, u.toolsDir, socketPath)
+	result, err := hctx.RunCommands(commands, u.charmPath, u.toolsDir, socketPath, stdout, stderr, abort)
 	if result != nil {
 		logger.Tracef("run commands: rc=%v\nstdout:\n%sstderr:\n%s", result.Code, result.Stdout, result.Stderr)
 	}
@@ -482,6 +548,84 @@ func (u *Uniter) validateAction(name string, params map[string]interface{}) (boo
 	return spec.ValidateParams(params)
 }
 
+// recordHookExecution records a run of a hook through the API, so it
+// can be inspected with "juju hook-history". Failing to record it is
+// not fatal to the uniter.
+func (u *Uniter) recordHookExecution(hi hook.Info, hookName string, hctx *HookContext, started time.Time, err error) {
+	execution := params.HookExecution{
+		Hook:       hookName,
+		RemoteUnit: hi.RemoteUnit,
+		Started:    started,
+		Duration:   time.Since(started),
+		ExitCode:   hookExitCode(err),
+		Output:     hctx.HookOutput(),
+	}
+	if r, ok := u.relationers[hi.RelationId]; ok && hi.Kind.IsRelation() {
+		execution.Relation = r.ru.Relation().String()
+	}
+	if err := u.unit.RecordHookExecution(execution); err != nil {
+		logger.Warningf("cannot record execution of %q hook: %v", hookName, err)
+	}
+}
+
+// maxCapturedContexts is the number of captured hook contexts kept
+// by each unit; older ones are removed as new ones are written.
+const maxCapturedContexts = 20
+
+// captureHookContext writes the context the named hook is about to run
+// in to a file, from which it can be downloaded and replayed with
+// "juju replay-hook". Failing to capture it is not fatal to the uniter.
+func (u *Uniter) captureHookContext(hookName string, action bool, hctx *HookContext, socketPath string) {
+	captured, err := jujuc.CaptureContext(hctx)
+	if err != nil {
+		logger.Warningf("cannot capture context of %q hook: %v", hookName, err)
+		return
+	}
+	captured.HookName = hookName
+	captured.Action = action
+	captured.Environment = hctx.hookVars(u.charmPath, u.toolsDir, socketPath)
+	data, err := json.MarshalIndent(captured, "", "  ")
+	if err != nil {
+		logger.Warningf("cannot capture context of %q hook: %v", hookName, err)
+		return
+	}
+	dir := filepath.Join(u.baseDir, "state", "hook-contexts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Warningf("cannot capture context of %q hook: %v", hookName, err)
+		return
+	}
+	// The time prefix keeps the files in the order they were written.
+	name := fmt.Sprintf("%s-%s.json", time.Now().UTC().Format("20060102-150405.000000000"), hookName)
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		logger.Warningf("cannot capture context of %q hook: %v", hookName, err)
+		return
+	}
+	logger.Infof("captured context of %q hook in %s", hookName, path)
+	if err := pruneCapturedContexts(dir); err != nil {
+		logger.Warningf("cannot remove old hook contexts: %v", err)
+	}
+}
+
+// pruneCapturedContexts removes all but the most recent captured hook
+// contexts from dir.
+func pruneCapturedContexts(dir string) error {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	if len(names) <= maxCapturedContexts {
+		return nil
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-maxCapturedContexts] {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // runHook executes the supplied hook.Info in an appropriate hook context. If
 // the hook itself fails to execute, it returns errHookFailed.
 func (u *Uniter) runHook(hi hook.Info) (err error) {
@@ -527,12 +671,18 @@ func (u *Uniter) runHook(hi hook.Info) (err error) {
 		return err
 	}
 
+	hctx.hookTimeout = u.hookTimeout(hctx)
+
 	srv, socketPath, err := u.startJujucServer(hctx)
 	if err != nil {
 		return err
 	}
 	defer srv.Close()
 
+	if u.captureContexts() {
+		u.captureHookContext(hookName, hi.Kind == hooks.ActionRequested, hctx, socketPath)
+	}
+
 	// Run the hook.
 	if err := u.writeState(RunHook, Pending, &hi, nil); err != nil {
 		return err
@@ -540,6 +690,7 @@ func (u *Uniter) runHook(hi hook.Info) (err error) {
 	logger.Infof("running %q hook", hookName)
 
 	ranHook := true
+	started := time.Now()
 	// The reason for the conditional at this point is that once inside
 	// RunHook, we don't know whether we're running an Action or a regular
 	// Hook.  RunAction simply calls the exact same method as RunHook, but
@@ -555,12 +706,22 @@ func (u *Uniter) runHook(hi hook.Info) (err error) {
 		err = hctx.RunHook(hookName, u.charmPath, u.toolsDir, socketPath)
 	}
 
+	if !IsMissingHookError(err) {
+		u.recordHookExecution(hi, hookName, hctx, started, err)
+	}
+
 	// Since the Action validation error was separated, regular error pathways
 	// will still occur correctly.
 	if IsMissingHookError(err) {
 		ranHook = false
 	} else if err != nil {
 		logger.Errorf("hook failed: %s", err)
+		if hi.Kind != hooks.ActionRequested {
+			u.hookTimedOut = 0
+			if IsHookTimeoutError(err) {
+				u.hookTimedOut = hctx.hookTimeout
+			}
+		}
 		u.notifyHookFailed(hookName, hctx)
 		return errHookFailed
 	}
@@ -598,6 +759,21 @@ func (u *Uniter) commitHook(hi hook.Info) error {
 	return nil
 }
 
+// setHookRetry records the automatic retries made of the failed hook.
+func (u *Uniter) setHookRetry(retry *HookRetry) error {
+	s := *u.s
+	s.HookRetry = retry
+	return u.saveState(s)
+}
+
+// resetHookRetry forgets any automatic retries made of a failed hook.
+func (u *Uniter) resetHookRetry() error {
+	if u.s.HookRetry == nil {
+		return nil
+	}
+	return u.setHookRetry(nil)
+}
+
 // currentHookName returns the current full hook name.
 func (u *Uniter) currentHookName() string {
 	hookInfo := u.s.Hook
@@ -824,8 +1000,110 @@ func (u *Uniter) updatePackageProxy(cfg *config.Config) {
 	}
 }
 
+// updateEnvironSettings updates the hook timeout, automatic rollback
+// and hook context capture settings from the environment.
+func (u *Uniter) updateEnvironSettings(cfg *config.Config) {
+	u.envSettingsMutex.Lock()
+	defer u.envSettingsMutex.Unlock()
+	u.envHookTimeout = cfg.HookTimeout()
+	u.envAutoRollback = cfg.CharmAutoRollback()
+	u.envCaptureContexts = cfg.HookContextCapture()
+}
+
+// captureContexts returns whether the contexts of hooks should be
+// captured for replay.
+func (u *Uniter) captureContexts() bool {
+	u.envSettingsMutex.Lock()
+	defer u.envSettingsMutex.Unlock()
+	return u.envCaptureContexts
+}
+
+// autoRollback returns whether failed charm upgrades should be rolled
+// back automatically.
+func (u *Uniter) autoRollback() bool {
+	u.envSettingsMutex.Lock()
+	defer u.envSettingsMutex.Unlock()
+	return u.envAutoRollback
+}
+
+// requestRollback asks for the charm of the unit's service to be
+// rolled back. It is a variable so that tests can count the requests.
+var requestRollback = (*uniter.Unit).RollbackCharm
+
+// rollbackCharm asks for the service's charm to be changed back to
+// the one used before the failed upgrade. The unit will then be
+// upgraded to it like any other forced upgrade. The request is made
+// once for each failed upgrade, however often the unit fails it, as
+// the rollback affects the whole service.
+func (u *Uniter) rollbackCharm() error {
+	curl, err := u.unit.CharmURL()
+	if err != nil {
+		return err
+	}
+	if u.s.RollbackRequested != nil && *u.s.RollbackRequested == *curl {
+		logger.Debugf("rollback of charm %q already requested", curl)
+		return nil
+	}
+	logger.Infof("rolling back failed upgrade to charm %q", curl)
+	err = requestRollback(u.unit)
+	if _, ok := err.(*params.Error); ok {
+		// The request was refused, for instance because the upgrade
+		// has already been rolled back; asking again will not help.
+		logger.Warningf("cannot roll back failed charm upgrade: %v", err)
+	} else if err != nil {
+		return err
+	}
+	s := *u.s
+	s.RollbackRequested = curl
+	return u.saveState(s)
+}
+
+// charmHookTimeoutOption names the charm config option that, when the
+// charm defines it, overrides the environment's hook-timeout setting.
+const charmHookTimeoutOption = "hook-timeout"
+
+// hookTimeout returns the maximum time a hook run in the given context
+// may run for. The charm's hook-timeout config option, if it defines
+// one, takes precedence over the environment's setting.
+func (u *Uniter) hookTimeout(hctx *HookContext) time.Duration {
+	settings, err := hctx.ConfigSettings()
+	if err != nil {
+		logger.Warningf("cannot read charm hook timeout: %v", err)
+	} else if timeout, ok, err := charmHookTimeout(settings); err != nil {
+		logger.Warningf("cannot read charm hook timeout: %v", err)
+	} else if ok {
+		return timeout
+	}
+	u.envSettingsMutex.Lock()
+	defer u.envSettingsMutex.Unlock()
+	return u.envHookTimeout
+}
+
+// charmHookTimeout returns the hook timeout set in the given charm
+// config settings, and whether one was set.
+func charmHookTimeout(settings corecharm.Settings) (time.Duration, bool, error) {
+	var seconds int64
+	switch value := settings[charmHookTimeoutOption].(type) {
+	case nil:
+		return 0, false, nil
+	case int64:
+		seconds = value
+	case int:
+		seconds = int64(value)
+	case float64:
+		// Numbers are decoded from the API as float64.
+		seconds = int64(value)
+	default:
+		return 0, false, fmt.Errorf("invalid %s %v: expected number", charmHookTimeoutOption, value)
+	}
+	if seconds < 0 {
+		return 0, false, fmt.Errorf("invalid %s %d: expected non-negative number", charmHookTimeoutOption, seconds)
+	}
+	return time.Duration(seconds) * time.Second, true, nil
+}
+
 // watchForProxyChanges kicks off a go routine to listen to the watcher and
-// update the proxy settings.
+// update the proxy and other environment settings.
 func (u *Uniter) watchForProxyChanges(environWatcher apiwatcher.NotifyWatcher) {
 	go func() {
 		for {
@@ -842,6 +1120,7 @@ func (u *Uniter) watchForProxyChanges(environWatcher apiwatcher.NotifyWatcher) {
 					logger.Errorf("cannot load environment configuration: %v", err)
 				} else {
 					u.updatePackageProxy(environConfig)
+					u.updateEnvironSettings(environConfig)
 				}
 			}
 		}
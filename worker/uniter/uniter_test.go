@@ -5,6 +5,7 @@ package uniter_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/rpc"
@@ -43,6 +44,7 @@ import (
 	"github.com/juju/juju/worker"
 	"github.com/juju/juju/worker/uniter"
 	"github.com/juju/juju/worker/uniter/charm"
+	"github.com/juju/juju/worker/uniter/jujuc"
 )
 
 // worstCase is used for timeouts when timing out
@@ -65,6 +67,8 @@ type UniterSuite struct {
 
 	st     *api.State
 	uniter *apiuniter.State
+
+	hookRetries *hookRetryTimer
 }
 
 var _ = gc.Suite(&UniterSuite{})
@@ -467,6 +471,261 @@ func (s *UniterSuite) TestUniterMultipleErrors(c *gc.C) {
 	s.runUniterTests(c, multipleErrorsTests)
 }
 
+var hookHistoryTests = []uniterTest{
+	ut(
+		"hook executions are recorded",
+		startupError{"config-changed"},
+		verifyWaiting{},
+		verifyHookHistory{
+			{"install", 0},
+			{"config-changed", 1},
+		},
+	),
+}
+
+func (s *UniterSuite) TestUniterHookHistory(c *gc.C) {
+	s.runUniterTests(c, hookHistoryTests)
+}
+
+var hookRetryTests = []uniterTest{
+	ut(
+		"failed hook is retried until attempts are exhausted",
+		setHookRetryPolicy{attempts: 2, delay: 3},
+		startupError{"start"},
+		verifyHookRetryStatus{attempt: 1, max: 2, scheduled: true},
+
+		fireHookRetry{},
+		waitHooks{"fail-start"},
+		verifyHookRetryStatus{attempt: 2, max: 2, scheduled: true},
+
+		fireHookRetry{},
+		waitHooks{"fail-start"},
+		verifyHookRetryStatus{attempt: 2, max: 2},
+		verifyHookRetryDelays{3 * time.Second, 6 * time.Second},
+
+		fixHook{"start"},
+		resolveError{state.ResolvedRetryHooks},
+		waitUnit{
+			status: params.StatusStarted,
+		},
+		waitHooks{"start", "config-changed"},
+		verifyRunning{},
+	), ut(
+		"retried hook succeeds",
+		setHookRetryPolicy{attempts: 3, delay: 1},
+		startupError{"start"},
+		verifyHookRetryStatus{attempt: 1, max: 3, scheduled: true},
+
+		fixHook{"start"},
+		fireHookRetry{},
+		waitUnit{
+			status: params.StatusStarted,
+		},
+		waitHooks{"start", "config-changed"},
+		verifyRunning{},
+	), ut(
+		"operator intervention stops automatic retries",
+		setHookRetryPolicy{attempts: 3, delay: 1},
+		startupError{"start"},
+		verifyHookRetryStatus{attempt: 1, max: 3, scheduled: true},
+
+		resolveError{state.ResolvedRetryHooks},
+		waitUnit{
+			status: params.StatusError,
+			info:   `hook failed: "start"`,
+			data: params.StatusData{
+				"hook": "start",
+			},
+		},
+		waitHooks{"fail-start"},
+		verifyHookRetryDelays{1 * time.Second},
+		verifyWaiting{},
+	), ut(
+		"actions run between retries keep the retry count",
+		setHookRetryPolicy{attempts: 3, delay: 1},
+		createCharm{
+			badHooks: []string{"start"},
+			customize: func(c *gc.C, ctx *context, path string) {
+				ctx.writeAction(c, path, "action-log")
+				ctx.writeActionsYaml(c, path, []string{"action-log"})
+			},
+		},
+		serveCharm{},
+		createUniter{},
+		waitHooks{"install", "config-changed", "fail-start"},
+		verifyHookRetryStatus{attempt: 1, max: 3, scheduled: true},
+
+		fireHookRetry{},
+		waitHooks{"fail-start"},
+		verifyHookRetryStatus{attempt: 2, max: 3, scheduled: true},
+
+		// The failed hook, not the action, is retried next, and the
+		// attempts made so far still count.
+		addAction{"action-log", nil},
+		waitHooks{"action-log"},
+		fireHookRetry{},
+		waitHooks{"fail-start"},
+		verifyHookRetryStatus{attempt: 3, max: 3},
+
+		// Nor does an action make a restarted agent forget them.
+		addAction{"action-log", nil},
+		waitHooks{"action-log"},
+		stopUniter{},
+		startUniter{},
+		verifyHookRetryStatus{attempt: 3, max: 3},
+		verifyHookRetryDelays{1 * time.Second, 2 * time.Second},
+	), ut(
+		"automatic retries survive a restart",
+		setHookRetryPolicy{attempts: 3, delay: 1},
+		startupError{"start"},
+		verifyHookRetryStatus{attempt: 1, max: 3, scheduled: true},
+
+		fireHookRetry{},
+		waitHooks{"fail-start"},
+		verifyHookRetryStatus{attempt: 2, max: 3, scheduled: true},
+
+		stopUniter{},
+		startUniter{},
+		fireHookRetry{},
+		waitHooks{"fail-start"},
+		verifyHookRetryStatus{attempt: 3, max: 3},
+		// The backoff carries on where it left off.
+		verifyHookRetryDelays{1 * time.Second, 2 * time.Second, 2 * time.Second},
+	), ut(
+		"stopped retries stay stopped after a restart",
+		setHookRetryPolicy{attempts: 3, delay: 1},
+		startupError{"start"},
+		verifyHookRetryStatus{attempt: 1, max: 3, scheduled: true},
+
+		resolveError{state.ResolvedRetryHooks},
+		waitHooks{"fail-start"},
+		stopUniter{},
+		startUniter{},
+		waitUnit{
+			status: params.StatusError,
+			info:   `hook failed: "start"`,
+			data: params.StatusData{
+				"hook": "start",
+			},
+		},
+		verifyHookRetryDelays{1 * time.Second},
+		verifyWaiting{},
+	),
+}
+
+func (s *UniterSuite) TestUniterHookRetry(c *gc.C) {
+	restore := gt.PatchValue(uniter.HookRetryAfter, func(d time.Duration) <-chan time.Time {
+		return s.hookRetries.after(d)
+	})
+	defer restore()
+	s.runUniterTests(c, hookRetryTests)
+}
+
+func (s *UniterSuite) TestHookRetryDelay(c *gc.C) {
+	policy := params.HookRetryPolicy{Attempts: 20, Delay: 10 * time.Second}
+	c.Check(uniter.HookRetryDelay(policy, 0), gc.Equals, 10*time.Second)
+	c.Check(uniter.HookRetryDelay(policy, 1), gc.Equals, 20*time.Second)
+	c.Check(uniter.HookRetryDelay(policy, 3), gc.Equals, 80*time.Second)
+	c.Check(uniter.HookRetryDelay(policy, 19), gc.Equals, 30*time.Minute)
+}
+
+var hookTimeoutTests = []uniterTest{
+	ut(
+		"hook killed after environment timeout",
+		setHookTimeout(1),
+		createCharm{
+			customize: func(c *gc.C, ctx *context, path string) {
+				appendHook(c, path, "start", "sleep 3600\n")
+			},
+		},
+		serveCharm{},
+		createUniter{},
+		waitUnit{
+			status: params.StatusError,
+			info:   `hook failed: "start" (timed out after 1s)`,
+		},
+		waitHooks{"install", "config-changed", "start"},
+		verifyHookHistory{
+			{"install", 0},
+			{"config-changed", 0},
+			{"start", -1},
+		},
+	), ut(
+		"charm hook timeout overrides environment",
+		setHookTimeout(3600),
+		createCharm{
+			customize: func(c *gc.C, ctx *context, path string) {
+				appendHook(c, path, "start", "sleep 3600\n")
+				addConfigOption(c, path, "hook-timeout", "int", 1)
+			},
+		},
+		serveCharm{},
+		createUniter{},
+		waitUnit{
+			status: params.StatusError,
+			info:   `hook failed: "start" (timed out after 1s)`,
+		},
+		waitHooks{"install", "config-changed", "start"},
+	),
+}
+
+func (s *UniterSuite) TestUniterHookTimeout(c *gc.C) {
+	s.runUniterTests(c, hookTimeoutTests)
+}
+
+func (s *UniterSuite) TestCharmHookTimeout(c *gc.C) {
+	for i, test := range []struct {
+		settings corecharm.Settings
+		timeout  time.Duration
+		ok       bool
+		err      string
+	}{{
+		settings: corecharm.Settings{"blog-title": "My Title"},
+	}, {
+		settings: corecharm.Settings{"hook-timeout": nil},
+	}, {
+		settings: corecharm.Settings{"hook-timeout": int64(90)},
+		timeout:  90 * time.Second,
+		ok:       true,
+	}, {
+		settings: corecharm.Settings{"hook-timeout": float64(0)},
+		ok:       true,
+	}, {
+		settings: corecharm.Settings{"hook-timeout": int64(-1)},
+		err:      "invalid hook-timeout -1: expected non-negative number",
+	}, {
+		settings: corecharm.Settings{"hook-timeout": "soon"},
+		err:      "invalid hook-timeout soon: expected number",
+	}} {
+		c.Logf("test %d: %v", i, test.settings)
+		timeout, ok, err := uniter.CharmHookTimeout(test.settings)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(timeout, gc.Equals, test.timeout)
+		c.Check(ok, gc.Equals, test.ok)
+	}
+}
+
+var hookContextCaptureTests = []uniterTest{
+	ut(
+		"hook contexts not captured by default",
+		quickStart{},
+		verifyCapturedContexts{},
+	), ut(
+		"hook contexts captured",
+		setHookContextCapture(true),
+		quickStart{},
+		verifyCapturedContexts{"install", "config-changed", "start"},
+	),
+}
+
+func (s *UniterSuite) TestUniterHookContextCapture(c *gc.C) {
+	s.runUniterTests(c, hookContextCaptureTests)
+}
+
 var configChangedHookTests = []uniterTest{
 	ut(
 		"config-changed hook fail and resolve",
@@ -1056,6 +1315,113 @@ func (s *UniterSuite) TestUniterUpgradeConflicts(c *gc.C) {
 	s.runUniterTests(c, upgradeConflictsTests)
 }
 
+var charmRollbackTests = []uniterTest{
+	ut(
+		"rollback after upgrade hook failure",
+		quickStart{},
+		createCharm{revision: 1, badHooks: []string{"upgrade-charm"}},
+		upgradeCharm{revision: 1},
+		waitUnit{
+			status: params.StatusError,
+			info:   `hook failed: "upgrade-charm"`,
+			data: params.StatusData{
+				"hook": "upgrade-charm",
+			},
+			charm: 1,
+		},
+		waitHooks{"fail-upgrade-charm"},
+		verifyCharm{revision: 1},
+
+		rollbackCharm{},
+		waitUnit{
+			status: params.StatusStarted,
+		},
+		waitHooks{"upgrade-charm", "config-changed"},
+		verifyCharm{},
+		verifyRunning{},
+	), ut(
+		"automatic rollback after upgrade hook failure",
+		setCharmAutoRollback(true),
+		quickStart{},
+		createCharm{revision: 1, badHooks: []string{"upgrade-charm"}},
+		upgradeCharm{revision: 1},
+		waitHooks{"fail-upgrade-charm", "upgrade-charm", "config-changed"},
+		waitUnit{
+			status: params.StatusStarted,
+		},
+		verifyCharm{},
+		verifyRunning{},
+	), ut(
+		"automatic rollback ignores other hook failures",
+		setCharmAutoRollback(true),
+		quickStart{},
+		createCharm{revision: 1, badHooks: []string{"config-changed"}},
+		upgradeCharm{revision: 1},
+		waitUnit{
+			status: params.StatusError,
+			info:   `hook failed: "config-changed"`,
+			data: params.StatusData{
+				"hook": "config-changed",
+			},
+			charm: 1,
+		},
+		waitHooks{"upgrade-charm", "fail-config-changed"},
+		verifyCharm{revision: 1},
+	),
+}
+
+func (s *UniterSuite) TestUniterCharmRollback(c *gc.C) {
+	s.runUniterTests(c, charmRollbackTests)
+}
+
+func (s *UniterSuite) TestUniterCharmRollbackRequestedOnce(c *gc.C) {
+	restore := gt.PatchValue(uniter.HookRetryAfter, func(d time.Duration) <-chan time.Time {
+		return s.hookRetries.after(d)
+	})
+	defer restore()
+	var mu sync.Mutex
+	requests := 0
+	restore = gt.PatchValue(uniter.RequestRollback, func(*apiuniter.Unit) error {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		// Refuse the rollback, so that the upgrade keeps failing.
+		return &params.Error{Message: "rollback refused"}
+	})
+	defer restore()
+	verifyRequests := custom{func(c *gc.C, ctx *context) {
+		mu.Lock()
+		defer mu.Unlock()
+		c.Assert(requests, gc.Equals, 1)
+	}}
+	s.runUniterTests(c, []uniterTest{
+		ut(
+			"rollback is requested once however often the upgrade hook fails",
+			setCharmAutoRollback(true),
+			setHookRetryPolicy{attempts: 2, delay: 1},
+			quickStart{},
+			createCharm{revision: 1, badHooks: []string{"upgrade-charm"}},
+			upgradeCharm{revision: 1},
+			waitHooks{"fail-upgrade-charm"},
+			verifyHookRetryStatus{attempt: 1, max: 2, scheduled: true},
+			verifyRequests,
+
+			fireHookRetry{},
+			waitHooks{"fail-upgrade-charm"},
+			verifyHookRetryStatus{attempt: 2, max: 2, scheduled: true},
+
+			// The request is remembered across restarts.
+			stopUniter{},
+			startUniter{},
+			fireHookRetry{},
+			waitHooks{"fail-upgrade-charm"},
+			verifyHookRetryStatus{attempt: 2, max: 2},
+			verifyCharm{revision: 1},
+			verifyRequests,
+		),
+	})
+}
+
 func (s *UniterSuite) TestRunCommand(c *gc.C) {
 	testDir := c.MkDir()
 	testFile := func(name string) string {
@@ -2027,6 +2393,22 @@ func (s upgradeCharm) step(c *gc.C, ctx *context) {
 	serveCharm{}.step(c, ctx)
 }
 
+type rollbackCharm struct{}
+
+func (s rollbackCharm) step(c *gc.C, ctx *context) {
+	err := ctx.svc.RollbackCharm()
+	c.Assert(err, gc.IsNil)
+	serveCharm{}.step(c, ctx)
+}
+
+type setCharmAutoRollback bool
+
+func (s setCharmAutoRollback) step(c *gc.C, ctx *context) {
+	attrs := map[string]interface{}{"charm-auto-rollback": bool(s)}
+	err := ctx.st.UpdateEnvironConfig(attrs, nil, nil)
+	c.Assert(err, gc.IsNil)
+}
+
 type verifyCharm struct {
 	revision          int
 	attemptedRevision int
@@ -2338,6 +2720,134 @@ func (s chmod) step(c *gc.C, ctx *context) {
 	c.Assert(err, gc.IsNil)
 }
 
+type verifyHookHistory []struct {
+	hook     string
+	exitCode int
+}
+
+func (s verifyHookHistory) step(c *gc.C, ctx *context) {
+	executions, err := ctx.unit.HookExecutions()
+	c.Assert(err, gc.IsNil)
+	c.Assert(executions, gc.HasLen, len(s))
+	for i, expect := range s {
+		c.Check(executions[i].Hook, gc.Equals, expect.hook)
+		c.Check(executions[i].ExitCode, gc.Equals, expect.exitCode)
+		c.Check(executions[i].Started.IsZero(), jc.IsFalse)
+	}
+}
+
+// hookRetryTimer stands in for the timer used by the uniter to
+// schedule automatic hook retries; it records the requested delays,
+// and only fires when told to.
+type hookRetryTimer struct {
+	mu     sync.Mutex
+	delays []time.Duration
+	ticks  chan time.Time
+}
+
+func (t *hookRetryTimer) after(d time.Duration) <-chan time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delays = append(t.delays, d)
+	return t.ticks
+}
+
+type setHookRetryPolicy struct {
+	attempts int
+	delay    int
+}
+
+func (s setHookRetryPolicy) step(c *gc.C, ctx *context) {
+	ctx.s.hookRetries = &hookRetryTimer{ticks: make(chan time.Time)}
+	attrs := map[string]interface{}{
+		"hook-retry-attempts": s.attempts,
+		"hook-retry-delay":    s.delay,
+	}
+	err := ctx.st.UpdateEnvironConfig(attrs, nil, nil)
+	c.Assert(err, gc.IsNil)
+}
+
+type setHookTimeout int
+
+func (s setHookTimeout) step(c *gc.C, ctx *context) {
+	attrs := map[string]interface{}{"hook-timeout": int(s)}
+	err := ctx.st.UpdateEnvironConfig(attrs, nil, nil)
+	c.Assert(err, gc.IsNil)
+}
+
+type setHookContextCapture bool
+
+func (s setHookContextCapture) step(c *gc.C, ctx *context) {
+	attrs := map[string]interface{}{"hook-context-capture": bool(s)}
+	err := ctx.st.UpdateEnvironConfig(attrs, nil, nil)
+	c.Assert(err, gc.IsNil)
+}
+
+// verifyCapturedContexts checks that the contexts of the given hooks,
+// and no others, have been captured in order.
+type verifyCapturedContexts []string
+
+func (s verifyCapturedContexts) step(c *gc.C, ctx *context) {
+	paths, err := filepath.Glob(filepath.Join(ctx.path, "state", "hook-contexts", "*.json"))
+	c.Assert(err, gc.IsNil)
+	var hookNames []string
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		c.Assert(err, gc.IsNil)
+		var captured jujuc.CapturedContext
+		err = json.Unmarshal(data, &captured)
+		c.Assert(err, gc.IsNil)
+		c.Check(captured.UnitName, gc.Equals, "u/0")
+		c.Check(strings.Join(captured.Environment, "\n"), jc.Contains, "JUJU_UNIT_NAME=u/0")
+		hookNames = append(hookNames, captured.HookName)
+	}
+	c.Assert(hookNames, jc.DeepEquals, []string(s))
+}
+
+type fireHookRetry struct{}
+
+func (s fireHookRetry) step(c *gc.C, ctx *context) {
+	select {
+	case ctx.s.hookRetries.ticks <- time.Now():
+	case <-time.After(worstCase):
+		c.Fatalf("hook retry never scheduled")
+	}
+}
+
+type verifyHookRetryDelays []time.Duration
+
+func (s verifyHookRetryDelays) step(c *gc.C, ctx *context) {
+	timer := ctx.s.hookRetries
+	timer.mu.Lock()
+	defer timer.mu.Unlock()
+	c.Assert(timer.delays, gc.DeepEquals, []time.Duration(s))
+}
+
+type verifyHookRetryStatus struct {
+	attempt   int
+	max       int
+	scheduled bool
+}
+
+func (s verifyHookRetryStatus) step(c *gc.C, ctx *context) {
+	for attempt := coretesting.LongAttempt.Start(); attempt.Next(); {
+		ctx.s.BackingState.StartSync()
+		status, _, data, err := ctx.unit.Status()
+		c.Assert(err, gc.IsNil)
+		c.Assert(status, gc.Equals, params.StatusError)
+		// Status data makes its way over the API as JSON.
+		if data["retry-attempt"] != float64(s.attempt) || data["retry-max"] != float64(s.max) {
+			c.Logf("want retry %d/%d, got %v/%v; still waiting",
+				s.attempt, s.max, data["retry-attempt"], data["retry-max"])
+			continue
+		}
+		_, scheduled := data["next-retry"]
+		c.Assert(scheduled, gc.Equals, s.scheduled)
+		return
+	}
+	c.Fatalf("never reached desired retry status")
+}
+
 type custom struct {
 	f func(*gc.C, *context)
 }
@@ -2379,6 +2889,32 @@ func appendHook(c *gc.C, charm, name, data string) {
 	c.Assert(err, gc.IsNil)
 }
 
+// addConfigOption adds an option with the given type and default value
+// to the config of the charm at the given path.
+func addConfigOption(c *gc.C, charmPath, name, optionType string, value interface{}) {
+	path := filepath.Join(charmPath, "config.yaml")
+	config := map[string]map[string]interface{}{}
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		err = goyaml.Unmarshal(data, &config)
+		c.Assert(err, gc.IsNil)
+	} else {
+		c.Assert(os.IsNotExist(err), jc.IsTrue)
+	}
+	if config["options"] == nil {
+		config["options"] = map[string]interface{}{}
+	}
+	config["options"][name] = map[string]interface{}{
+		"type":        optionType,
+		"description": name,
+		"default":     value,
+	}
+	data, err = goyaml.Marshal(config)
+	c.Assert(err, gc.IsNil)
+	err = ioutil.WriteFile(path, data, 0644)
+	c.Assert(err, gc.IsNil)
+}
+
 func renameRelation(c *gc.C, charmPath, oldName, newName string) {
 	path := filepath.Join(charmPath, "metadata.yaml")
 	f, err := os.Open(path)
@@ -2480,7 +3016,7 @@ type runCommands []string
 
 func (cmds runCommands) step(c *gc.C, ctx *context) {
 	commands := strings.Join(cmds, "\n")
-	result, err := ctx.uniter.RunCommands(commands)
+	result, err := ctx.uniter.RunCommands(commands, nil, nil, nil)
 	c.Assert(err, gc.IsNil)
 	c.Check(result.Code, gc.Equals, 0)
 	c.Check(string(result.Stdout), gc.Equals, "")